@@ -0,0 +1,177 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hakim/reconpipe/internal/report"
+	"github.com/hakim/reconpipe/internal/stats"
+	"github.com/hakim/reconpipe/internal/storage"
+	"github.com/hakim/reconpipe/pkg/models"
+	"github.com/spf13/cobra"
+)
+
+var aggregateCmd = &cobra.Command{
+	Use:   "aggregate",
+	Short: "Summarize the latest scan of every target in one report",
+	Long: `Build one cross-portfolio report from the latest scan of every target in the
+store, for leadership reporting across many programs: total assets, the
+findings by severity, the worst targets, and the subdomains and open ports that
+appeared since --since (default the last 7 days).
+
+Each target's latest complete scan is used. New assets are what it found that
+the target's last scan before the cut-off did not; targets first scanned after
+the cut-off are listed as new targets instead.
+
+Writes portfolio-<date>.md and portfolio-<date>.json to --output-dir (default
+{scan_dir}/aggregate) and prints the worst targets.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// Step 1: Get flags
+		allTargets, _ := cmd.Flags().GetBool("all-targets")
+		domains, _ := cmd.Flags().GetStringSlice("domain")
+		sinceFlag, _ := cmd.Flags().GetString("since")
+		top, _ := cmd.Flags().GetInt("top")
+		outputDir, _ := cmd.Flags().GetString("output-dir")
+
+		if !allTargets && len(domains) == 0 {
+			return fmt.Errorf("pass --all-targets, or --domain for each target to include")
+		}
+		window, err := parseWindow(sinceFlag)
+		if err != nil {
+			return fmt.Errorf("--since: %w", err)
+		}
+
+		// Step 2: Config check
+		if cfg == nil {
+			return fmt.Errorf("config not loaded. Run 'reconpipe init' first to create config")
+		}
+
+		// Step 3: Load every target's scan history (newest first)
+		store, err := storage.NewStore(cfg.DBPath)
+		if err != nil {
+			return fmt.Errorf("opening database: %w", err)
+		}
+		defer store.Close()
+		if allTargets {
+			if domains, err = store.ListTargets(); err != nil {
+				return fmt.Errorf("listing targets: %w", err)
+			}
+		}
+		history := make(map[string][]*models.ScanMeta, len(domains))
+		for _, domain := range domains {
+			scans, err := store.ListScans(domain)
+			if err != nil {
+				return fmt.Errorf("listing scans for %s: %w", domain, err)
+			}
+			if len(scans) > 0 {
+				history[domain] = scans
+			}
+		}
+
+		if len(history) == 0 {
+			fmt.Println("No scan history found")
+			return nil
+		}
+
+		// Step 4: Aggregate the latest scans
+		since := time.Now().Add(-window)
+		portfolio, err := stats.ComputePortfolio(store, history, since)
+		if err != nil {
+			return err
+		}
+
+		// Step 5: Write the report and JSON
+		if outputDir == "" {
+			outputDir = filepath.Join(cfg.ScanDir, "aggregate")
+		}
+		base, err := writePortfolio(portfolio, top, outputDir, "portfolio-"+portfolio.GeneratedAt.Format("20060102"))
+		if err != nil {
+			return err
+		}
+
+		// Step 6: Print the headline numbers
+		printPortfolio(portfolio, top)
+		fmt.Printf("[+] Portfolio report written to %s.md (JSON: %s.json)\n", base, base)
+		return nil
+	},
+}
+
+// writePortfolio writes p to name.md and name.json in outputDir, listing
+// the top worst targets (0 for all) in the report, and returns the path
+// without extension.
+func writePortfolio(p *stats.Portfolio, top int, outputDir, name string) (string, error) {
+	if err := storage.EnsureDir(outputDir); err != nil {
+		return "", fmt.Errorf("creating output directory: %w", err)
+	}
+	base := filepath.Join(outputDir, name)
+
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("marshaling portfolio: %w", err)
+	}
+	if err := os.WriteFile(base+".json", data, 0644); err != nil {
+		return "", fmt.Errorf("writing portfolio JSON: %w", err)
+	}
+	if err := report.WritePortfolioReport(p, top, base+".md"); err != nil {
+		return "", err
+	}
+	return base, nil
+}
+
+// printPortfolio prints the totals and the worst targets of p.
+func printPortfolio(p *stats.Portfolio, top int) {
+	const separator = "──────────────────────────────────────────────────────────────"
+
+	fmt.Printf("\nPortfolio of %d targets (new since %s)\n", len(p.Targets), p.Since.Local().Format("2006-01-02"))
+	fmt.Println(separator)
+	fmt.Printf("  Assets:         %d (%d subdomains, %d hosts)\n", p.Assets, p.Subdomains, p.Hosts)
+	fmt.Printf("  Open ports:     %d\n", p.OpenPorts)
+	fmt.Printf("  Criticals:      %d (high: %d)\n", p.Severities[models.SeverityCritical], p.Severities[models.SeverityHigh])
+	fmt.Printf("  New assets:     %d, plus %d new targets\n", len(p.NewAssets), len(p.NewTargets))
+
+	fmt.Printf("\n  Worst targets\n")
+	fmt.Println(separator)
+	fmt.Printf("  %-30s  %-8s  %-6s  %-6s  %s\n", "Target", "Critical", "High", "Medium", "New assets")
+	for i, t := range p.Targets {
+		if top > 0 && i == top {
+			break
+		}
+		fmt.Printf("  %-30s  %-8d  %-6d  %-6d  %d\n", t.Target, t.Severities[models.SeverityCritical],
+			t.Severities[models.SeverityHigh], t.Severities[models.SeverityMedium], t.NewAssets)
+	}
+	fmt.Println()
+}
+
+// parseWindow parses a look-back window: a Go duration ("36h") or a number
+// of days ("7d").
+func parseWindow(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid number of days %q", s)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, err
+	}
+	if d < 0 {
+		return 0, fmt.Errorf("%q is negative", s)
+	}
+	return d, nil
+}
+
+func init() {
+	aggregateCmd.Flags().Bool("all-targets", false, "Include every target in the store")
+	aggregateCmd.Flags().StringSliceP("domain", "d", nil, "Target to include (repeatable; instead of --all-targets)")
+	aggregateCmd.Flags().String("since", "7d", "Report assets that appeared within this window, e.g. 7d or 36h")
+	aggregateCmd.Flags().Int("top", 10, "Number of worst targets to list (0 for all)")
+	aggregateCmd.Flags().StringP("output-dir", "o", "", "Directory for the report and JSON (default {scan_dir}/aggregate)")
+	rootCmd.AddCommand(aggregateCmd)
+}
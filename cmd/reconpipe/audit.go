@@ -0,0 +1,118 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+
+	"github.com/hakim/reconpipe/internal/config"
+	"github.com/hakim/reconpipe/internal/storage"
+	"github.com/hakim/reconpipe/pkg/models"
+	"github.com/spf13/cobra"
+)
+
+var auditCmd = &cobra.Command{
+	Use:   "audit",
+	Short: "Show who ran which scans on this machine",
+	Long: `Display the audit log: one entry per scan started, resumed or finished and
+per standalone stage run, with the operator, host and scan ID.
+
+The operator is taken from --operator, $RECONPIPE_OPERATOR, the operator config
+key, or the login name, in that order.
+
+Entries are listed newest-first. Use --domain to show one target only.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// Step 1: Get flags
+		domain, _ := cmd.Flags().GetString("domain")
+		limit, _ := cmd.Flags().GetInt("limit")
+
+		// Step 2: Config check
+		if cfg == nil {
+			return fmt.Errorf("config not loaded. Run 'reconpipe init' first to create config")
+		}
+
+		// Step 3: Open bbolt store
+		store, err := storage.NewStore(cfg.DBPath)
+		if err != nil {
+			return fmt.Errorf("opening database: %w", err)
+		}
+		defer store.Close()
+
+		// Step 4: Read the log
+		entries, err := store.ListAudit(domain, limit)
+		if err != nil {
+			return fmt.Errorf("reading audit log: %w", err)
+		}
+		if len(entries) == 0 {
+			fmt.Println("No audit entries found")
+			return nil
+		}
+
+		// Step 5: Print formatted table
+		const separator = "──────────────────────────────────────────────────────────────────────────────────────"
+
+		fmt.Println()
+		fmt.Println(separator)
+		fmt.Printf("  %-20s  %-12s  %-14s  %-24s  %-12s  %s\n", "Time", "Operator", "Action", "Target", "Scan ID", "Detail")
+		fmt.Println(separator)
+		for _, e := range entries {
+			op := e.Operator
+			if e.Host != "" {
+				op += "@" + e.Host
+			}
+			scanID := "-"
+			if e.ScanID != "" {
+				scanID = shortScanID(e.ScanID)
+			}
+			fmt.Printf("  %-20s  %-12s  %-14s  %-24s  %-12s  %s\n",
+				e.Time.UTC().Format("2006-01-02 15:04:05"), op, e.Action, e.Target, scanID, e.Detail)
+		}
+		fmt.Println(separator)
+		fmt.Printf("Total: %d entry(ies)\n\n", len(entries))
+
+		return nil
+	},
+}
+
+// resolveOperator picks the name recorded for this invocation: the
+// --operator flag, then $RECONPIPE_OPERATOR, then the config file, then the
+// login name.
+func resolveOperator(flag string, c *config.Config) string {
+	if flag != "" {
+		return flag
+	}
+	if env := os.Getenv("RECONPIPE_OPERATOR"); env != "" {
+		return env
+	}
+	if c != nil && c.Operator != "" {
+		return c.Operator
+	}
+	if u, err := user.Current(); err == nil && u.Username != "" {
+		return u.Username
+	}
+	if name := os.Getenv("USER"); name != "" {
+		return name
+	}
+	return os.Getenv("USERNAME")
+}
+
+// recordAudit appends an entry for the current operator. Standalone stage
+// commands call it with their own store handle; a failed write only warns.
+func recordAudit(store storage.Store, action, target, scanID, detail string) {
+	err := store.AppendAudit(models.AuditEntry{
+		Operator: operator,
+		Action:   action,
+		Target:   target,
+		ScanID:   scanID,
+		Detail:   detail,
+	})
+	if err != nil {
+		fmt.Printf("[!] Warning: could not write audit log: %v\n", err)
+	}
+}
+
+func init() {
+	auditCmd.Flags().StringP("domain", "d", "", "Only show entries for this target")
+	auditCmd.Flags().Int("limit", 50, "Maximum number of entries to display")
+	rootCmd.AddCommand(auditCmd)
+}
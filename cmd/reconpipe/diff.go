@@ -1,15 +1,20 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
-	"os"
-	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/hakim/reconpipe/internal/diff"
-	"github.com/hakim/reconpipe/internal/models"
+	"github.com/hakim/reconpipe/internal/issues"
 	"github.com/hakim/reconpipe/internal/report"
+	"github.com/hakim/reconpipe/internal/riskpolicy"
+	"github.com/hakim/reconpipe/internal/stats"
 	"github.com/hakim/reconpipe/internal/storage"
+	"github.com/hakim/reconpipe/internal/takeover"
+	"github.com/hakim/reconpipe/pkg/models"
 	"github.com/spf13/cobra"
 )
 
@@ -26,8 +31,14 @@ Results are saved to:
   - {scan_dir}/reports/dangling-dns.md (dangling DNS report for current scan)
   - {scan_dir}/raw/diff.json           (structured diff JSON)
 
+With the signing section configured, diff.md and diff.json get detached
+signatures that 'reconpipe verify' checks.
+
 When no --compare directory is supplied the second-most-recent scan for the domain
-is located automatically via the scan database.`,
+is located automatically via the scan database.
+
+With the issues section configured, an issue is opened in GitHub or GitLab for
+each newly dangling subdomain and closed once the subdomain is resolved.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		// Step 1: Get flags
 		domain, _ := cmd.Flags().GetString("domain")
@@ -41,7 +52,7 @@ is located automatically via the scan database.`,
 
 		// Step 3: Resolve current scan directory
 		if scanDir == "" {
-			latestDir, err := findLatestScanDir(cfg.ScanDir, domain)
+			latestDir, err := storage.FindLatestScanDir(cfg.ScanDir, domain)
 			if err != nil {
 				return fmt.Errorf("finding latest scan directory: %w. Run 'reconpipe discover -d %s' first", err, domain)
 			}
@@ -51,8 +62,13 @@ is located automatically via the scan database.`,
 		fmt.Printf("[*] Current scan directory: %s\n", scanDir)
 
 		// Step 4: Resolve previous scan directory
+		store, err := storage.NewStore(cfg.DBPath)
+		if err != nil {
+			return fmt.Errorf("opening database: %w", err)
+		}
+		defer store.Close()
 		if compareDir == "" {
-			prevDir, err := findPreviousScanDir(domain, scanDir)
+			prevDir, err := findPreviousScanDir(store, domain, scanDir)
 			if err != nil {
 				return fmt.Errorf("looking up scan history: %w", err)
 			}
@@ -66,12 +82,12 @@ is located automatically via the scan database.`,
 		fmt.Printf("[*] Previous scan directory: %s\n", compareDir)
 
 		// Step 5: Load both snapshots
-		currentSnap, err := diff.LoadSnapshot(scanDir)
+		currentSnap, err := loadScanSnapshot(store, domain, scanDir)
 		if err != nil {
 			return fmt.Errorf("loading current snapshot: %w", err)
 		}
 
-		previousSnap, err := diff.LoadSnapshot(compareDir)
+		previousSnap, err := loadScanSnapshot(store, domain, compareDir)
 		if err != nil {
 			return fmt.Errorf("loading previous snapshot: %w", err)
 		}
@@ -81,44 +97,65 @@ is located automatically via the scan database.`,
 		fmt.Printf("[*] Previous: %d subdomains, %d hosts, %d vulns\n",
 			len(previousSnap.Subdomains), len(previousSnap.Hosts), len(previousSnap.Vulnerabilities))
 
-		// Step 6: Compute diff
+		// Step 6: Compute diff, dated from the earlier scans
 		result := diff.ComputeDiff(currentSnap, previousSnap)
+		dateDiff(store, domain, scanDir, result, "")
 
 		// Step 7: Write diff markdown report
-		diffReportPath := filepath.Join(scanDir, "reports", "diff.md")
+		diffReportPath := storage.ReportPath(scanDir, "diff.md")
 		if err := report.WriteDiffReport(result, diffReportPath); err != nil {
 			// Warn but do not abort — raw JSON is still persisted below
 			fmt.Printf("[!] Warning: failed to write diff report: %v\n", err)
 		} else {
 			fmt.Printf("[+] Diff report written to %s\n", diffReportPath)
 		}
+		htmlPath := storage.ReportPath(scanDir, "diff.html")
+		if err := report.WriteDiffHTMLReport(result, htmlPath); err != nil {
+			fmt.Printf("[!] Warning: failed to write HTML diff report: %v\n", err)
+		} else {
+			fmt.Printf("[+] HTML report written to %s\n", htmlPath)
+		}
 
 		// Step 8: Write dangling DNS report (current snapshot only)
-		danglingReportPath := filepath.Join(scanDir, "reports", "dangling-dns.md")
-		if err := report.WriteDanglingDNSReport(currentSnap.Subdomains, danglingReportPath); err != nil {
+		danglingReportPath := storage.ReportPath(scanDir, "dangling-dns.md")
+		if err := report.WriteDanglingDNSReport(currentSnap.Subdomains, currentSnap.CollectedAt["subdomains.json"], danglingReportPath); err != nil {
 			fmt.Printf("[!] Warning: failed to write dangling DNS report: %v\n", err)
 		} else {
 			fmt.Printf("[+] Dangling DNS report written to %s\n", danglingReportPath)
 		}
 
 		// Step 9: Save diff result as JSON
-		rawPath := filepath.Join(scanDir, "raw", "diff.json")
+		rawPath := storage.RawPath(scanDir, "diff.json")
 		rawData, err := json.MarshalIndent(result, "", "  ")
 		if err != nil {
 			return fmt.Errorf("marshaling diff result: %w", err)
 		}
-		if err := os.WriteFile(rawPath, rawData, 0644); err != nil {
+		if err := storage.WriteFileAtomic(rawPath, rawData, 0644); err != nil {
 			return fmt.Errorf("writing diff.json: %w", err)
 		}
 		fmt.Printf("[+] Diff JSON written to %s\n", rawPath)
 
-		// Step 10: Update bbolt — append "diff" to StagesRun
-		if err := appendDiffStage(domain, scanDir); err != nil {
+		// Step 10: Sign the diff reports when signing is configured
+		signDiffReports(context.Background(), scanDir, "")
+
+		// Step 11: Update bbolt — append "diff" to StagesRun
+		if readOnly {
+			fmt.Println("[*] Read-only mode: scan metadata not updated")
+		} else if err := appendDiffStage(store, domain, scanDir); err != nil {
 			// Non-fatal: metadata update failure should not fail the command
 			fmt.Printf("[!] Warning: failed to update scan metadata: %v\n", err)
 		}
 
-		// Step 11: Print summary
+		// Step 12: Open/close dangling DNS issues in the configured tracker
+		if cfg.Issues.Provider != "" {
+			if readOnly {
+				fmt.Println("[*] Read-only mode: issue tracker not updated")
+			} else {
+				syncDanglingIssues(context.Background(), store, domain, scanDir, result, "")
+			}
+		}
+
+		// Step 13: Print summary
 		fmt.Println()
 		fmt.Printf("[+] Diff complete!\n")
 		fmt.Printf("    Subdomains: +%d new, -%d removed\n",
@@ -128,32 +165,53 @@ is located automatically via the scan database.`,
 		fmt.Printf("    Vulns:      +%d new, -%d resolved\n",
 			len(result.NewVulns), len(result.ResolvedVulns))
 		if len(result.NewlyDangling) > 0 {
-			fmt.Printf("    Dangling:   %d newly dangling (takeover risk!)\n", len(result.NewlyDangling))
+			fmt.Printf("    Dangling:   %d newly dangling (%s)\n", len(result.NewlyDangling), danglingSeverities(result.NewlyDangling))
 		}
 
 		return nil
 	},
 }
 
+// danglingSeverities summarizes the takeover severity of dangling
+// subdomains, e.g. "1 critical, 2 high".
+func danglingSeverities(subs []models.Subdomain) string {
+	counts := map[models.Severity]int{}
+	for _, s := range subs {
+		counts[takeover.Assess(s).Severity]++
+	}
+	var parts []string
+	for _, sev := range stats.Severities {
+		if counts[sev] > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", counts[sev], sev))
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
 // findPreviousScanDir returns the ScanDir of the scan immediately preceding
 // currentScanDir in the sorted history for domain. Returns ("", nil) when there
 // is no prior scan — the caller interprets that as a graceful no-op.
-func findPreviousScanDir(domain, currentScanDir string) (string, error) {
-	store, err := storage.NewStore(cfg.DBPath)
-	if err != nil {
-		return "", fmt.Errorf("opening database: %w", err)
-	}
-	defer store.Close()
-
+func findPreviousScanDir(store storage.Store, domain, currentScanDir string) (string, error) {
 	scans, err := store.ListScans(domain)
 	if err != nil {
 		return "", fmt.Errorf("listing scans: %w", err)
 	}
 
-	// scans is sorted newest-first. Walk until we find one whose ScanDir
-	// differs from the current scan, then return that as the previous.
+	// A scan of a URL list (--targets-file) covers only the listed URLs, so
+	// it is compared with the previous URL-list scan, and a full scan with
+	// the previous full scan. Scans of selected ports or with UDP likewise.
+	kind := ""
+	for _, scan := range scans {
+		if scan.ScanDir == currentScanDir {
+			kind = scanKind(scan)
+		}
+	}
+
+	// scans is sorted newest-first. Walk until we find one of the same kind
+	// whose ScanDir differs from the current scan, then return that as the
+	// previous.
 	for _, scan := range scans {
-		if scan.ScanDir != currentScanDir {
+		if scan.ScanDir != currentScanDir && scanKind(scan) == kind {
 			return scan.ScanDir, nil
 		}
 	}
@@ -161,15 +219,154 @@ func findPreviousScanDir(domain, currentScanDir string) (string, error) {
 	return "", nil
 }
 
-// appendDiffStage opens bbolt, finds the scan record for scanDir, and appends
-// "diff" to its StagesRun list (idempotent).
-func appendDiffStage(domain, scanDir string) error {
-	store, err := storage.NewStore(cfg.DBPath)
+// scanForDir returns the scan of domain recorded for scanDir, or nil when
+// the database has none, e.g. for a copied directory.
+func scanForDir(store storage.Store, domain, scanDir string) (*models.ScanMeta, error) {
+	scans, err := store.ListScans(domain)
 	if err != nil {
-		return fmt.Errorf("opening database: %w", err)
+		return nil, fmt.Errorf("listing scans: %w", err)
+	}
+	for _, scan := range scans {
+		if scan.ScanDir == scanDir {
+			return scan, nil
+		}
 	}
-	defer store.Close()
+	return nil, nil
+}
+
+// loadScanSnapshot loads the snapshot of the scan in scanDir, taking the raw
+// files missing from it from the stage results saved in the database. A
+// directory the database has no scan for is read as it is.
+func loadScanSnapshot(store storage.Store, domain, scanDir string) (*diff.ScanSnapshot, error) {
+	scan, err := scanForDir(store, domain, scanDir)
+	if err != nil {
+		return nil, err
+	}
+	if scan == nil {
+		return diff.LoadSnapshot(scanDir)
+	}
+	return diff.LoadScanSnapshot(store, scan)
+}
+
+// dateDiff dates the new and removed entries of result from the scans of
+// domain that started before the one in scanDir, of the same kind as
+// findPreviousScanDir compares. Failures only warn: the diff stands
+// without the dates.
+func dateDiff(store storage.Store, domain, scanDir string, result *diff.DiffResult, indent string) {
+	scans, err := store.ListScans(domain)
+	if err != nil {
+		fmt.Printf("%s[!] Warning: scan history: %v\n", indent, err)
+		return
+	}
+
+	// A scan missing from the database, e.g. a copied directory, is dated
+	// by its data
+	at := result.CollectedAt
+	kind := ""
+	for _, scan := range scans {
+		if scan.ScanDir == scanDir {
+			at, kind = scan.StartedAt, scanKind(scan)
+		}
+	}
+	if at.IsZero() {
+		at = time.Now().UTC()
+	}
+
+	var earlier []*models.ScanMeta
+	for _, scan := range scans {
+		if scan.ScanDir != scanDir && scan.StartedAt.Before(at) && scanKind(scan) == kind {
+			earlier = append(earlier, scan)
+		}
+	}
+	history, err := diff.LoadHistory(store, earlier)
+	if err != nil {
+		fmt.Printf("%s[!] Warning: scan history: %v; diff entries are not dated\n", indent, err)
+		return
+	}
+	result.Date(history, at)
+}
+
+// scanKind groups scans that cover the same ground: "" for a full scan,
+// "urls" for a URL list, or the ports a scan narrowed by --ports or
+// --top-ports checked, plus "udp" when UDP was scanned too. Only scans of
+// one kind are compared.
+func scanKind(scan *models.ScanMeta) string {
+	rc := scan.RunConfig
+	if rc == nil {
+		return ""
+	}
+	if len(rc.TargetURLs) > 0 {
+		return "urls"
+	}
+	var kind []string
+	switch {
+	case rc.TopPorts > 0:
+		kind = append(kind, fmt.Sprintf("top-ports %d", rc.TopPorts))
+	case rc.Ports != "":
+		kind = append(kind, "ports "+rc.Ports)
+	}
+	if rc.UDP {
+		kind = append(kind, "udp")
+	}
+	return strings.Join(kind, " ")
+}
+
+// syncDanglingIssues opens and closes issue tracker issues for the dangling
+// DNS changes in result, when the issues integration is configured. scanDir
+// identifies the scan the issues refer to. Failures only warn: the diff
+// reports already hold the findings.
+func syncDanglingIssues(ctx context.Context, store storage.Store, domain, scanDir string, result *diff.DiffResult, indent string) {
+	if cfg.Issues.Provider == "" || len(result.NewlyDangling)+len(result.ResolvedDangling) == 0 {
+		return
+	}
+	tracker, err := issues.FromConfig(cfg.Issues)
+	if err != nil {
+		fmt.Printf("%s[!] Warning: issue tracker: %v\n", indent, err)
+		return
+	}
+
+	scan := &models.ScanMeta{Target: domain, StartedAt: time.Now()}
+	if scans, err := store.ListScans(domain); err == nil {
+		for _, m := range scans {
+			if m.ScanDir == scanDir {
+				scan = m
+				break
+			}
+		}
+	}
+
+	// Only the newly dangling subdomains risk_policy.ticket_on picks get an
+	// issue; resolved ones are closed whatever the policy
+	policy := riskPolicy()
+	tickets := *result
+	tickets.NewlyDangling = nil
+	for _, sub := range result.NewlyDangling {
+		if policy.Matches(riskpolicy.Ticket, riskpolicy.DanglingFinding(sub, true)) {
+			tickets.NewlyDangling = append(tickets.NewlyDangling, sub)
+		}
+	}
+	if n := len(result.NewlyDangling) - len(tickets.NewlyDangling); n > 0 {
+		fmt.Printf("%s[*] %d newly dangling subdomain(s) do not match risk_policy.ticket_on\n", indent, n)
+	}
+
+	synced, err := issues.SyncDangling(ctx, tracker, cfg.Issues.Labels, scan, &tickets)
+	for _, issue := range synced.Opened {
+		fmt.Printf("%s[+] Opened issue #%d %s\n", indent, issue.Number, issue.URL)
+	}
+	for _, issue := range synced.Closed {
+		fmt.Printf("%s[+] Closed issue #%d %s\n", indent, issue.Number, issue.URL)
+	}
+	if synced.Skipped > 0 {
+		fmt.Printf("%s[*] %d newly dangling subdomain(s) already have an open issue on %s\n", indent, synced.Skipped, tracker.Name())
+	}
+	if err != nil {
+		fmt.Printf("%s[!] Warning: issue tracker %s: %v\n", indent, tracker.Name(), err)
+	}
+}
 
+// appendDiffStage finds the scan record for scanDir and appends "diff" to
+// its StagesRun list (idempotent).
+func appendDiffStage(store storage.Store, domain, scanDir string) error {
 	scans, err := store.ListScans(domain)
 	if err != nil {
 		return fmt.Errorf("listing scans: %w", err)
@@ -5,14 +5,14 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
-	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/hakim/reconpipe/internal/discovery"
-	"github.com/hakim/reconpipe/internal/models"
 	"github.com/hakim/reconpipe/internal/report"
 	"github.com/hakim/reconpipe/internal/storage"
 	"github.com/hakim/reconpipe/internal/tools"
+	"github.com/hakim/reconpipe/pkg/models"
 	"github.com/spf13/cobra"
 )
 
@@ -35,11 +35,19 @@ Scan metadata is persisted to the configured database.`,
 		domain, _ := cmd.Flags().GetString("domain")
 		skipTlsx, _ := cmd.Flags().GetBool("skip-tlsx")
 		timeout, _ := cmd.Flags().GetDuration("timeout")
+		knownFile, _ := cmd.Flags().GetString("known-subdomains")
+		permutations, _ := cmd.Flags().GetBool("permutations")
+		bruteforce, _ := cmd.Flags().GetBool("bruteforce")
+		zoneTransfer, _ := cmd.Flags().GetBool("axfr")
+		dnsConsensus, _ := cmd.Flags().GetBool("dns-consensus")
 
 		// Step 1: Pre-flight check - verify required tools
+		runSubfinder, runAmass := engineTools()
 		requiredTools := []tools.ToolRequirement{
-			{Name: "subfinder", Binary: "subfinder", Required: true, InstallCmd: "go install -v github.com/projectdiscovery/subfinder/v2/cmd/subfinder@latest"},
-			{Name: "dig", Binary: "dig", Required: true, InstallCmd: "apt install dnsutils (or brew install bind on macOS)"},
+			{Name: "subfinder", Binary: "subfinder", Required: runSubfinder, InstallCmd: "go install -v github.com/projectdiscovery/subfinder/v2/cmd/subfinder@latest"},
+			{Name: "amass", Binary: "amass", Required: runAmass, InstallCmd: amassInstallCmd},
+			{Name: "dig", Binary: "dig", Required: !tools.NativeDNS(), InstallCmd: "apt install dnsutils (or brew install bind on macOS)"},
+			{Name: "dnsx", Binary: "dnsx", Required: tools.BulkDNS(), InstallCmd: dnsxInstallCmd},
 		}
 
 		tlsxTool := tools.ToolRequirement{Name: "tlsx", Binary: "tlsx", Required: false}
@@ -47,7 +55,7 @@ Scan metadata is persisted to the configured database.`,
 
 		for _, tool := range requiredTools {
 			result := tools.CheckTool(tool)
-			if !result.Found {
+			if tool.Required && !result.Found {
 				return fmt.Errorf("required tool '%s' not found. Install with: %s", tool.Name, tool.InstallCmd)
 			}
 		}
@@ -66,11 +74,42 @@ Scan metadata is persisted to the configured database.`,
 			return fmt.Errorf("config not loaded. Run 'reconpipe init' first to create config")
 		}
 
+		if !cmd.Flags().Changed("permutations") && cfg.Discovery.Permutations.Enabled {
+			permutations = true
+		}
+		if !cmd.Flags().Changed("bruteforce") && cfg.Discovery.Bruteforce.Enabled {
+			bruteforce = true
+		}
+		var words []string
+		if bruteforce {
+			var err error
+			if words, err = bruteforceWords(); err != nil {
+				return err
+			}
+		}
+		if !cmd.Flags().Changed("axfr") && cfg.Discovery.ZoneTransfer {
+			zoneTransfer = true
+		}
+		if !cmd.Flags().Changed("dns-consensus") && cfg.Discovery.DNSConsensus.Enabled {
+			dnsConsensus = true
+		}
+
+		// Client-supplied asset list, merged into discovery
+		var knownSubdomains []string
+		if knownFile != "" {
+			known, err := discovery.LoadKnownSubdomains(knownFile)
+			if err != nil {
+				return err
+			}
+			knownSubdomains = known
+			fmt.Printf("[*] Loaded %d known subdomains from %s\n", len(known), knownFile)
+		}
+
 		// Step 3: Create scan metadata
 		scan := models.NewScan(domain)
 
 		// Step 4: Create scan directory
-		scanDir, err := storage.CreateScanDir(cfg.ScanDir, domain, scan.StartedAt)
+		scanDir, err := storage.CreateScanDir(cfg.ScanDir, storage.DirVars{Target: domain, StartedAt: scan.StartedAt})
 		if err != nil {
 			return fmt.Errorf("creating scan directory: %w", err)
 		}
@@ -85,9 +124,11 @@ Scan metadata is persisted to the configured database.`,
 
 		// Step 6: Save scan metadata with StatusRunning
 		scan.Status = models.StatusRunning
+		scan.Operator = operator
 		if err := store.SaveScan(&scan.ScanMeta); err != nil {
 			return fmt.Errorf("saving scan metadata: %w", err)
 		}
+		recordAudit(store, "discover", domain, scan.ID, scanDir)
 
 		// Step 7: Print progress
 		fmt.Printf("[*] Starting subdomain discovery for %s\n", domain)
@@ -101,10 +142,30 @@ Scan metadata is persisted to the configured database.`,
 		discoveryCfg := discovery.DiscoveryConfig{
 			SubfinderThreads: cfg.RateLimits.SubfinderThreads,
 			SubfinderPath:    "", // Use binary from PATH
+			Engine:           cfg.Discovery.Engine,
+			AmassPath:        "", // Use binary from PATH
 			TlsxPath:         "", // Use binary from PATH
 			DigPath:          "", // Use binary from PATH
 			SkipTlsx:         skipTlsx || !tlsxAvailable,
+			KnownSubdomains:  knownSubdomains,
+
+			Permutations:        permutations,
+			PermutationPatterns: cfg.Discovery.Permutations.Patterns,
+			MaxPermutations:     cfg.Discovery.Permutations.MaxCandidates,
+			Bruteforce:          bruteforce,
+			BruteforceWords:     words,
+			BruteforceMutate:    cfg.Discovery.Bruteforce.Mutate,
+			MaxBruteforce:       cfg.Discovery.Bruteforce.MaxCandidates,
+			ZoneTransfer:        zoneTransfer,
+			WildcardFilter:      cfg.Discovery.WildcardFilter,
+			DNSHealth:           !cfg.Discovery.DNSHealth.Skip,
+			DNSHealthSubdomains: cfg.Discovery.DNSHealth.Subdomains,
+			DNSHealthResolver:   cfg.Discovery.DNSHealth.Resolver,
+			Consensus:           consensusConfig(dnsConsensus),
+			Resolve:             resolveOptions(),
+			Passive:             passiveOptions(),
 		}
+		skipDigOnlyChecks(&discoveryCfg, "")
 
 		// Step 10: Run discovery
 		result, err := discovery.RunDiscovery(ctx, domain, discoveryCfg)
@@ -119,21 +180,27 @@ Scan metadata is persisted to the configured database.`,
 			result.UniqueCount, result.ResolvedCount, result.DanglingCount)
 
 		// Step 12: Write markdown report
-		reportPath := filepath.Join(scanDir, "reports", "subdomains.md")
+		reportPath := storage.ReportPath(scanDir, "subdomains.md")
 		if err := report.WriteSubdomainReport(result, reportPath); err != nil {
 			// Warn but don't fail - raw data is still saved
 			fmt.Printf("[!] Warning: failed to write report: %v\n", err)
 		} else {
 			fmt.Printf("[+] Report written to %s\n", reportPath)
 		}
+		htmlPath := storage.ReportPath(scanDir, "subdomains.html")
+		if err := report.WriteSubdomainHTMLReport(result, htmlPath); err != nil {
+			fmt.Printf("[!] Warning: failed to write HTML report: %v\n", err)
+		} else {
+			fmt.Printf("[+] HTML report written to %s\n", htmlPath)
+		}
 
 		// Step 13: Save raw output as JSON
-		rawPath := filepath.Join(scanDir, "raw", "subdomains.json")
+		rawPath := storage.RawPath(scanDir, "subdomains.json")
 		rawData, err := json.MarshalIndent(result, "", "  ")
 		if err != nil {
 			return fmt.Errorf("marshaling raw output: %w", err)
 		}
-		if err := os.WriteFile(rawPath, rawData, 0644); err != nil {
+		if err := storage.WriteFileAtomic(rawPath, rawData, 0644); err != nil {
 			return fmt.Errorf("writing raw output: %w", err)
 		}
 
@@ -143,11 +210,17 @@ Scan metadata is persisted to the configured database.`,
 		if err := store.SaveScan(&scan.ScanMeta); err != nil {
 			return fmt.Errorf("updating scan metadata: %w", err)
 		}
+		if err := store.SaveSubdomains(scan.ID, result.Subdomains, result.CollectedAt); err != nil {
+			fmt.Printf("[!] Warning: failed to save subdomains to the database: %v\n", err)
+		}
 
 		// Step 15: Update status to complete
 		if err := store.UpdateScanStatus(scan.ID, models.StatusComplete); err != nil {
 			return fmt.Errorf("updating scan status: %w", err)
 		}
+		if err := storage.MarkLatestScan(cfg.ScanDir, domain, scanDir); err != nil {
+			fmt.Printf("[!] Warning: could not update latest scan pointer: %v\n", err)
+		}
 
 		// Step 16: Print final summary
 		fmt.Println()
@@ -155,6 +228,22 @@ Scan metadata is persisted to the configured database.`,
 		fmt.Printf("    Scan ID: %s\n", scan.ID)
 		fmt.Printf("    Total: %d | Unique: %d | Resolved: %d | Dangling: %d\n",
 			result.TotalFound, result.UniqueCount, result.ResolvedCount, result.DanglingCount)
+		if axfr := discovery.ZoneTransferFindings(result.Findings); len(axfr) > 0 {
+			fmt.Printf("    [!] Zone transfer allowed by %d nameserver(s)\n", len(axfr))
+		}
+		if n := len(result.Findings) - len(discovery.ZoneTransferFindings(result.Findings)); n > 0 {
+			fmt.Printf("    [!] DNSSEC/CAA problems: %d (see DNS Health in the report)\n", n)
+		}
+		if len(result.ProvidedNotDiscovered) > 0 {
+			fmt.Printf("    Provided but not discovered: %d\n", len(result.ProvidedNotDiscovered))
+		}
+		if w := result.Wildcard; w != nil {
+			fmt.Printf("    [!] Wildcard DNS: %d subdomain(s) resolve only to %s (see Wildcard DNS in the report)\n",
+				w.Flagged+len(w.Filtered), strings.Join(w.IPs, ", "))
+		}
+		if n := len(discovery.UnconfirmedSubdomains(result.Subdomains)); n > 0 {
+			fmt.Printf("    [!] Answers below the DNS consensus quorum: %d subdomain(s) (see DNS Consensus in the report)\n", n)
+		}
 		fmt.Printf("    Report: %s\n", reportPath)
 
 		return nil
@@ -166,6 +255,11 @@ func init() {
 	discoverCmd.Flags().StringP("domain", "d", "", "Target domain to discover subdomains for (required)")
 	discoverCmd.Flags().Bool("skip-tlsx", false, "Skip tlsx certificate discovery")
 	discoverCmd.Flags().Duration("timeout", 10*time.Minute, "Overall discovery timeout")
+	discoverCmd.Flags().Bool("permutations", false, "Generate and resolve permutations of discovered subdomains (api-dev, dev-api, api01...)")
+	discoverCmd.Flags().Bool("bruteforce", false, "Resolve a wordlist of labels under the domain (see discovery.bruteforce)")
+	discoverCmd.Flags().Bool("axfr", false, "Attempt a DNS zone transfer (AXFR) against each authoritative nameserver")
+	discoverCmd.Flags().Bool("dns-consensus", false, "Resolve each name through several resolvers and keep only answers a quorum agrees on (see discovery.dns_consensus)")
+	discoverCmd.Flags().String("known-subdomains", "", "File of client-supplied subdomains (.txt one per line, or .csv) to always include")
 
 	// Mark domain as required
 	discoverCmd.MarkFlagRequired("domain")
@@ -173,3 +267,84 @@ func init() {
 	// Add to root command
 	rootCmd.AddCommand(discoverCmd)
 }
+
+// consensusConfig returns the multi-resolver settings from the config when
+// enabled, or the zero Consensus (system resolver only) otherwise.
+func consensusConfig(enabled bool) discovery.Consensus {
+	if !enabled {
+		return discovery.Consensus{}
+	}
+	resolvers := cfg.Discovery.DNSConsensus.Resolvers
+	if len(resolvers) == 0 {
+		resolvers = discovery.DefaultConsensusResolvers
+	}
+	return discovery.Consensus{Resolvers: resolvers, Quorum: cfg.Discovery.DNSConsensus.Quorum}
+}
+
+// bruteforceWords returns the words of discovery.bruteforce.wordlist, or nil
+// for the built-in list when none is configured.
+func bruteforceWords() ([]string, error) {
+	path := cfg.Discovery.Bruteforce.Wordlist
+	if path == "" {
+		return nil, nil
+	}
+	words, err := discovery.LoadWordlist(path)
+	if err != nil {
+		return nil, fmt.Errorf("loading brute-force wordlist: %w", err)
+	}
+	return words, nil
+}
+
+// resolveOptions returns the DNS worker count and per-lookup timeout from
+// rate_limits; unset values leave discovery's defaults.
+func resolveOptions() discovery.ResolveOptions {
+	opts := discovery.ResolveOptions{Workers: cfg.RateLimits.DNSWorkers}
+	if cfg.RateLimits.DNSTimeout != "" {
+		opts.Timeout, _ = time.ParseDuration(cfg.RateLimits.DNSTimeout)
+	}
+	return opts
+}
+
+// amassInstallCmd is how amass is installed, for the tool checks.
+const amassInstallCmd = "go install -v github.com/owasp-amass/amass/v4/...@master"
+
+// dnsxInstallCmd is how dnsx is installed, for the tool checks.
+const dnsxInstallCmd = "go install -v github.com/projectdiscovery/dnsx/cmd/dnsx@latest"
+
+// engineTools returns whether discovery.engine runs subfinder and amass.
+// Without a config only subfinder runs.
+func engineTools() (subfinder, amass bool) {
+	if cfg == nil {
+		return tools.EngineTools("")
+	}
+	return tools.EngineTools(cfg.Discovery.Engine)
+}
+
+// passiveOptions returns the passive source settings of
+// discovery.passive_sources, with ${ENV} in the SecurityTrails key expanded.
+func passiveOptions() discovery.PassiveOptions {
+	p := cfg.Discovery.PassiveSources
+	opts := discovery.PassiveOptions{
+		Skip:              p.Skip,
+		SecurityTrailsKey: os.ExpandEnv(p.SecurityTrailsAPIKey),
+	}
+	if p.Timeout != "" {
+		opts.Timeout, _ = time.ParseDuration(p.Timeout)
+	}
+	return opts
+}
+
+// skipDigOnlyChecks turns off the zone transfers and DNS health checks of
+// discoveryCfg when dig is missing, which native resolution allows: both
+// still run dig.
+func skipDigOnlyChecks(discoveryCfg *discovery.DiscoveryConfig, indent string) {
+	if !discoveryCfg.ZoneTransfer && !discoveryCfg.DNSHealth {
+		return
+	}
+	if tools.CheckTool(tools.ToolRequirement{Name: "dig", Binary: "dig"}).Found {
+		return
+	}
+	fmt.Printf("%s[!] dig not found, skipping zone transfers and DNS health checks\n", indent)
+	discoveryCfg.ZoneTransfer = false
+	discoveryCfg.DNSHealth = false
+}
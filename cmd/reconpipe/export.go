@@ -0,0 +1,343 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/hakim/reconpipe/internal/diff"
+	"github.com/hakim/reconpipe/internal/export"
+	"github.com/hakim/reconpipe/internal/notes"
+	"github.com/hakim/reconpipe/internal/report"
+	"github.com/hakim/reconpipe/internal/storage"
+	"github.com/hakim/reconpipe/internal/vulnscan"
+	"github.com/hakim/reconpipe/pkg/models"
+	"github.com/spf13/cobra"
+)
+
+// exportFormats maps --format values to their default output file name.
+// A bundle is named after its scan directory and written outside it.
+var exportFormats = map[string]string{
+	"cyclonedx": "assets.cdx.json",
+	"stix":      "observations.stix.json",
+	"sarif":     "vulns.sarif",
+	"bundle":    "",
+}
+
+var exportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export scan results in an interchange format",
+	Long: `Convert a scan's structured output into a standard document for external tooling.
+
+Formats:
+  cyclonedx  CycloneDX 1.5 JSON. Open ports become services; software versions
+             identified by nmap and httpx become components linked to the
+             services they run behind.
+  stix       STIX 2.1 bundle for threat-intel platforms: domain-name, ipv4-addr,
+             x509-certificate and vulnerability objects, linked by
+             relationships to each other and to an infrastructure object
+             for the target.
+  sarif      SARIF 2.1.0 log of the vulnerability findings, for GitHub Code
+             Scanning and other SARIF dashboards: a rule per nuclei template,
+             a result per finding located at the URL it matched. Noise
+             findings are included as suppressed results.
+  bundle     The whole scan directory plus its database record, finished or
+             not, as a .tar.gz for 'reconpipe import' on another machine,
+             where the scan can be resumed. --with-baseline adds the scan
+             its diff stage compares against.
+
+The document is written to {scan_dir}/reports/ unless --output is given
+("-" writes to stdout). A bundle is written to the working directory as
+{scan_dir name}.bundle.tar.gz.
+
+With --sinks the scan is published to the configured output_sinks
+(Elasticsearch, OpenSearch, Postgres, Confluence) instead, e.g. to backfill with older
+scans or retry after a failed upload. --sinks is refused in read-only mode.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// Step 1: Get flags
+		domain, _ := cmd.Flags().GetString("domain")
+		scanDir, _ := cmd.Flags().GetString("scan-dir")
+		format, _ := cmd.Flags().GetString("format")
+		output, _ := cmd.Flags().GetString("output")
+		toSinks, _ := cmd.Flags().GetBool("sinks")
+		withBaseline, _ := cmd.Flags().GetBool("with-baseline")
+
+		if toSinks && readOnly {
+			return fmt.Errorf("--sinks is disabled in read-only mode")
+		}
+
+		defaultName, ok := exportFormats[format]
+		if !ok && !toSinks {
+			return fmt.Errorf("unknown export format %q (supported: cyclonedx, stix, sarif, bundle)", format)
+		}
+
+		// Step 2: Resolve scan directory
+		if scanDir == "" {
+			if domain == "" {
+				return fmt.Errorf("either --domain or --scan-dir is required")
+			}
+			if cfg == nil {
+				return fmt.Errorf("config not loaded. Run 'reconpipe init' first to create config")
+			}
+			latestDir, err := storage.FindLatestScanDir(cfg.ScanDir, domain)
+			if err != nil {
+				return fmt.Errorf("finding latest scan directory: %w", err)
+			}
+			scanDir = latestDir
+		}
+
+		if format == "bundle" && !toSinks {
+			return exportBundle(domain, scanDir, output, withBaseline)
+		}
+		if format == "sarif" && !toSinks {
+			return exportSARIF(scanDir, output)
+		}
+
+		// Step 3: Load snapshot
+		snap, err := diff.LoadSnapshot(scanDir)
+		if err != nil {
+			return fmt.Errorf("loading scan snapshot: %w", err)
+		}
+		if domain == "" && len(snap.Subdomains) > 0 {
+			domain = snap.Subdomains[0].Domain
+		}
+
+		if toSinks {
+			return publishToSinks(domain, scanDir)
+		}
+		scanNotes, err := notes.Load(scanDir)
+		if err != nil {
+			return fmt.Errorf("loading notes: %w", err)
+		}
+
+		// Step 4: Build document
+		var doc any
+		switch format {
+		case "cyclonedx":
+			bom := export.BuildCycloneDX(snap, domain, rootCmd.Version, scanNotes)
+			fmt.Fprintf(os.Stderr, "[*] CycloneDX: %d services, %d components\n", len(bom.Services), len(bom.Components))
+			doc = bom
+		case "stix":
+			bundle := export.BuildSTIX(snap, domain, scanNotes)
+			fmt.Fprintf(os.Stderr, "[*] STIX: %d objects\n", len(bundle.Objects))
+			doc = bundle
+		}
+
+		data, err := json.MarshalIndent(doc, "", "  ")
+		if err != nil {
+			return fmt.Errorf("marshaling %s document: %w", format, err)
+		}
+
+		// Step 5: Write
+		if output == "-" {
+			fmt.Println(string(data))
+			return nil
+		}
+		if output == "" {
+			output = storage.ReportPath(scanDir, defaultName)
+		}
+		if err := os.MkdirAll(filepath.Dir(output), 0755); err != nil {
+			return fmt.Errorf("creating output directory: %w", err)
+		}
+		if err := os.WriteFile(output, data, 0644); err != nil {
+			return fmt.Errorf("writing %s: %w", output, err)
+		}
+
+		fmt.Fprintf(os.Stderr, "[+] Export written to %s\n", output)
+		return nil
+	},
+}
+
+func init() {
+	exportCmd.Flags().StringP("domain", "d", "", "Target domain (uses the latest scan directory)")
+	exportCmd.Flags().String("scan-dir", "", "Scan directory to export (overrides --domain)")
+	exportCmd.Flags().StringP("format", "f", "cyclonedx", "Export format: cyclonedx, stix, sarif, bundle")
+	exportCmd.Flags().StringP("output", "o", "", "Output file (default {scan_dir}/reports/<format file>, '-' for stdout)")
+	exportCmd.Flags().Bool("sinks", false, "Publish the scan to the configured output_sinks instead of writing a document")
+	exportCmd.Flags().Bool("with-baseline", false, "With --format bundle, also bundle the previous scan the diff stage compares against")
+	rootCmd.AddCommand(exportCmd)
+}
+
+// publishToSinks sends an existing scan to every configured output sink.
+// The scan's database record supplies its ID and start time; scans without
+// one are published under their directory name.
+func publishToSinks(domain, scanDir string) error {
+	if cfg == nil || len(cfg.OutputSinks) == 0 {
+		return fmt.Errorf("no output_sinks configured")
+	}
+	sinks, err := export.OutputSinksFromConfig(cfg.OutputSinks)
+	if err != nil {
+		return err
+	}
+
+	store, err := storage.NewStore(cfg.DBPath)
+	if err != nil {
+		return fmt.Errorf("opening database: %w", err)
+	}
+	scans, err := store.ListScans(domain)
+	store.Close()
+	if err != nil {
+		return fmt.Errorf("listing scans: %w", err)
+	}
+
+	var meta *models.ScanMeta
+	for _, scan := range scans {
+		if scan.ScanDir == scanDir {
+			meta = scan
+			break
+		}
+	}
+	if meta == nil {
+		fmt.Println("[!] Warning: no scan record for this directory — publishing under its directory name")
+		meta = &models.ScanMeta{ID: filepath.Base(scanDir), Target: domain, ScanDir: scanDir}
+		if info, err := os.Stat(scanDir); err == nil {
+			meta.StartedAt = info.ModTime()
+		}
+	}
+
+	if err := export.PublishAll(context.Background(), sinks, meta); err != nil {
+		return err
+	}
+	fmt.Println("[+] Published to all output sinks")
+	return nil
+}
+
+// exportBundle writes scanDir and its database record, plus the baseline
+// scan when withBaseline is set, as a bundle for 'reconpipe import'.
+func exportBundle(domain, scanDir, output string, withBaseline bool) error {
+	if cfg == nil {
+		return fmt.Errorf("config not loaded. Run 'reconpipe init' first to create config")
+	}
+	store, err := storage.NewStore(cfg.DBPath)
+	if err != nil {
+		return fmt.Errorf("opening database: %w", err)
+	}
+	defer store.Close()
+
+	// Step 1: Find the scan's record; without it the scan cannot be resumed
+	meta, err := findScanByDir(store, domain, scanDir)
+	if err != nil {
+		return err
+	}
+	if meta == nil {
+		return fmt.Errorf("no scan record for %s; only scans run on this machine can be bundled", scanDir)
+	}
+	if meta.Status == models.StatusRunning {
+		fmt.Fprintln(os.Stderr, "[!] Warning: scan is marked running; stop it first or the bundle may catch a stage mid-write")
+	}
+	scans := []*models.ScanMeta{meta}
+
+	if withBaseline {
+		prevDir, err := findPreviousScanDir(store, meta.Target, meta.ScanDir)
+		if err != nil {
+			return err
+		}
+		if prevDir == "" {
+			fmt.Fprintln(os.Stderr, "[!] Warning: no previous scan to bundle as a baseline")
+		} else {
+			prev, err := findScanByDir(store, meta.Target, prevDir)
+			if err != nil {
+				return err
+			}
+			scans = append(scans, prev)
+		}
+	}
+
+	// Step 2: Write the bundle
+	if output == "-" {
+		if err := storage.WriteBundle(os.Stdout, rootCmd.Version, scans); err != nil {
+			return fmt.Errorf("writing bundle: %w", err)
+		}
+	} else {
+		if output == "" {
+			output = filepath.Base(meta.ScanDir) + ".bundle.tar.gz"
+		}
+		f, err := os.Create(output)
+		if err != nil {
+			return fmt.Errorf("creating %s: %w", output, err)
+		}
+		err = storage.WriteBundle(f, rootCmd.Version, scans)
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			os.Remove(output)
+			return fmt.Errorf("writing bundle: %w", err)
+		}
+	}
+
+	for _, s := range scans {
+		fmt.Fprintf(os.Stderr, "[*] Bundled %s (%s, %s)\n", s.ScanDir, s.ID, s.Status)
+	}
+	if output != "-" {
+		fmt.Fprintf(os.Stderr, "[+] Bundle written to %s\n", output)
+		fmt.Fprintf(os.Stderr, "[*] On the other machine: reconpipe import %s\n", filepath.Base(output))
+	}
+	return nil
+}
+
+// exportSARIF writes the vulnerability findings of scanDir as SARIF to
+// output, {scan_dir}/reports/vulns.sarif by default or stdout for "-".
+func exportSARIF(scanDir, output string) error {
+	var result vulnscan.VulnScanResult
+	data, err := os.ReadFile(storage.RawPath(scanDir, "vulns.json"))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("no vulns.json in %s; run the vulnscan stage first", scanDir)
+		}
+		return fmt.Errorf("reading vulns.json: %w", err)
+	}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return fmt.Errorf("parsing vulns.json: %w", err)
+	}
+
+	sarifLog := report.BuildSARIF(&result, rootCmd.Version)
+	fmt.Fprintf(os.Stderr, "[*] SARIF: %d rules, %d results\n", len(sarifLog.Runs[0].Tool.Driver.Rules), len(sarifLog.Runs[0].Results))
+	if output == "-" {
+		data, err := json.MarshalIndent(sarifLog, "", "  ")
+		if err != nil {
+			return fmt.Errorf("marshaling SARIF: %w", err)
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	if output == "" {
+		output = storage.ReportPath(scanDir, exportFormats["sarif"])
+	}
+	if err := os.MkdirAll(filepath.Dir(output), 0755); err != nil {
+		return fmt.Errorf("creating output directory: %w", err)
+	}
+	if err := report.WriteSARIF(&result, rootCmd.Version, output); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "[+] Export written to %s\n", output)
+	return nil
+}
+
+// findScanByDir returns the database record of the scan in scanDir, or nil
+// when there is none. Without a domain every target's scans are searched.
+func findScanByDir(store storage.Store, domain, scanDir string) (*models.ScanMeta, error) {
+	targets := []string{domain}
+	if domain == "" {
+		var err error
+		if targets, err = store.ListTargets(); err != nil {
+			return nil, fmt.Errorf("listing targets: %w", err)
+		}
+	}
+	for _, target := range targets {
+		scans, err := store.ListScans(target)
+		if err != nil {
+			return nil, fmt.Errorf("listing scans: %w", err)
+		}
+		for _, scan := range scans {
+			if filepath.Clean(scan.ScanDir) == filepath.Clean(scanDir) {
+				return scan, nil
+			}
+		}
+	}
+	return nil, nil
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/hakim/reconpipe/internal/storage"
+	"github.com/hakim/reconpipe/pkg/models"
+	"github.com/spf13/cobra"
+)
+
+var findingsCmd = &cobra.Command{
+	Use:   "findings",
+	Short: "List the finding inventory across targets",
+	Long: `List the finding inventory: the vulnerabilities and dangling subdomains tracked
+for alerting between scans (see --notify), across every target or one.
+
+Open findings are listed by default, most severe first. Use --all to include
+resolved ones, --severity for a minimum severity, --kind for vuln or dangling
+findings only and --since to skip findings not seen recently.
+
+Use --format json for machine-readable output.
+
+Examples:
+  reconpipe findings
+  reconpipe findings -d example.com --severity high
+  reconpipe findings --kind dangling --since 168h --format json`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// Step 1: Get flags
+		domain, _ := cmd.Flags().GetString("domain")
+		kind, _ := cmd.Flags().GetString("kind")
+		severity, _ := cmd.Flags().GetString("severity")
+		all, _ := cmd.Flags().GetBool("all")
+		since, _ := cmd.Flags().GetDuration("since")
+		format, _ := cmd.Flags().GetString("format")
+
+		if format != "table" && format != "json" {
+			return fmt.Errorf("unknown format %q (supported: table, json)", format)
+		}
+		if kind != "" && kind != models.FindingKindVuln && kind != models.FindingKindDangling {
+			return fmt.Errorf("unknown kind %q (supported: %s, %s)", kind, models.FindingKindVuln, models.FindingKindDangling)
+		}
+		switch models.Severity(severity) {
+		case "", models.SeverityCritical, models.SeverityHigh, models.SeverityMedium, models.SeverityLow, models.SeverityInfo:
+		default:
+			return fmt.Errorf("unknown severity %q (supported: critical, high, medium, low, info)", severity)
+		}
+
+		// Step 2: Config check
+		if cfg == nil {
+			return fmt.Errorf("config not loaded. Run 'reconpipe init' first to create config")
+		}
+
+		// Step 3: Open the store
+		store, err := storage.NewStore(cfg.DBPath)
+		if err != nil {
+			return fmt.Errorf("opening database: %w", err)
+		}
+		defer store.Close()
+
+		// Step 4: Query the inventory
+		q := storage.FindingQuery{
+			Target:      domain,
+			Kind:        kind,
+			MinSeverity: models.Severity(severity),
+			Open:        !all,
+		}
+		if since > 0 {
+			q.Since = time.Now().Add(-since)
+		}
+		findings, err := store.ListFindings(q)
+		if err != nil {
+			return fmt.Errorf("listing findings: %w", err)
+		}
+
+		// Step 5: Print
+		if format == "json" {
+			if findings == nil {
+				findings = []*models.NotificationState{}
+			}
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			return enc.Encode(findings)
+		}
+		if len(findings) == 0 {
+			fmt.Println("No findings found")
+			return nil
+		}
+
+		const separator = "──────────────────────────────────────────────────────────────────────────────────────"
+
+		fmt.Println()
+		fmt.Println(separator)
+		fmt.Printf("  %-8s  %-8s  %-24s  %-36s  %-28s  %-10s  %s\n", "Severity", "Kind", "Target", "Name", "Host", "Last seen", "State")
+		fmt.Println(separator)
+		for _, f := range findings {
+			state := "open"
+			switch {
+			case f.Expired:
+				state = "expired"
+			case f.ResolvedAt != nil:
+				state = "resolved"
+			}
+			fmt.Printf("  %-8s  %-8s  %-24s  %-36s  %-28s  %-10s  %s\n",
+				f.Severity, f.Kind, f.Target, f.Name, f.Host, f.LastSeen.Local().Format("2006-01-02"), state)
+		}
+		fmt.Println(separator)
+		fmt.Printf("Total: %d finding(s)\n\n", len(findings))
+
+		return nil
+	},
+}
+
+func init() {
+	findingsCmd.Flags().StringP("domain", "d", "", "Only list findings of this target")
+	findingsCmd.Flags().String("kind", "", "Only list findings of this kind: vuln or dangling")
+	findingsCmd.Flags().String("severity", "", "Only list findings at least this severe: critical, high, medium, low or info")
+	findingsCmd.Flags().Bool("all", false, "Include resolved findings")
+	findingsCmd.Flags().Duration("since", 0, "Only list findings seen within this long, e.g. 168h")
+	findingsCmd.Flags().String("format", "table", "Output format: table or json")
+	rootCmd.AddCommand(findingsCmd)
+}
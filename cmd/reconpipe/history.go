@@ -4,8 +4,8 @@ import (
 	"fmt"
 	"strings"
 
-	"github.com/hakim/reconpipe/internal/models"
 	"github.com/hakim/reconpipe/internal/storage"
+	"github.com/hakim/reconpipe/pkg/models"
 	"github.com/spf13/cobra"
 )
 
@@ -15,7 +15,7 @@ var historyCmd = &cobra.Command{
 	Long: `Display a formatted table of past scans for a target domain.
 
 Scans are listed newest-first. Each row shows the scan ID (truncated), start time,
-completion status, and which pipeline stages were run.
+who launched it, completion status, and which pipeline stages were run.
 
 Use --limit to cap the number of rows shown (default: 10).`,
 	RunE: func(cmd *cobra.Command, args []string) error {
@@ -52,11 +52,11 @@ Use --limit to cap the number of rows shown (default: 10).`,
 		}
 
 		// Step 6: Print formatted table
-		const separator = "────────────────────────────────────────────────────────────────────────"
+		const separator = "──────────────────────────────────────────────────────────────────────────────────────"
 
 		fmt.Printf("\nScan History for %s\n", domain)
 		fmt.Println(separator)
-		fmt.Printf("  %-3s  %-12s  %-20s  %-10s  %s\n", "#", "Scan ID", "Started", "Status", "Stages")
+		fmt.Printf("  %-3s  %-12s  %-20s  %-12s  %-10s  %s\n", "#", "Scan ID", "Started", "Operator", "Status", "Stages")
 		fmt.Println(separator)
 
 		for i, scan := range scans {
@@ -64,9 +64,13 @@ Use --limit to cap the number of rows shown (default: 10).`,
 			started := scan.StartedAt.UTC().Format("2006-01-02 15:04")
 			status := formatStatus(scan.Status)
 			stages := formatStages(scan.StagesRun)
+			op := scan.Operator
+			if op == "" {
+				op = "-"
+			}
 
-			fmt.Printf("  %-3d  %-12s  %-20s  %-10s  %s\n",
-				i+1, shortID, started, status, stages)
+			fmt.Printf("  %-3d  %-12s  %-20s  %-12s  %-10s  %s\n",
+				i+1, shortID, started, op, status, stages)
 		}
 
 		fmt.Println(separator)
@@ -96,6 +100,8 @@ func formatStatus(s models.ScanStatus) string {
 		return "running"
 	case models.StatusPending:
 		return "pending"
+	case models.StatusInterrupted:
+		return "interrupted"
 	default:
 		return string(s)
 	}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/hakim/reconpipe/internal/storage"
+	"github.com/hakim/reconpipe/pkg/models"
+	"github.com/spf13/cobra"
+)
+
+var importCmd = &cobra.Command{
+	Use:   "import <bundle>",
+	Short: "Import a scan bundle made with 'export --format bundle'",
+	Long: `Unpack a scan bundle into scan_dir and record its scans in this machine's
+database, so a scan started elsewhere can be resumed, diffed or reported on
+here. "-" reads the bundle from stdin.
+
+Scans already in the database, or whose directory already exists under
+scan_dir, are refused; nothing is imported in that case.
+
+Example:
+  reconpipe export -d example.com --format bundle --with-baseline
+  scp example.com_20260101_120000.bundle.tar.gz other-host:
+  reconpipe import example.com_20260101_120000.bundle.tar.gz        (on other-host)
+  reconpipe scan -d example.com --resume --scan-dir scans/example.com_20260101_120000`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// Step 1: Config check
+		if cfg == nil {
+			return fmt.Errorf("config not loaded. Run 'reconpipe init' first to create config")
+		}
+
+		// Step 2: Open the bundle
+		var r io.Reader = os.Stdin
+		if args[0] != "-" {
+			f, err := os.Open(args[0])
+			if err != nil {
+				return fmt.Errorf("opening bundle: %w", err)
+			}
+			defer f.Close()
+			r = f
+		}
+
+		// Step 3: Open bbolt store
+		store, err := storage.NewStore(cfg.DBPath)
+		if err != nil {
+			return fmt.Errorf("opening database: %w", err)
+		}
+		defer store.Close()
+
+		// Step 4: Unpack and record the scans
+		if err := storage.EnsureDir(cfg.ScanDir); err != nil {
+			return fmt.Errorf("creating scan directory: %w", err)
+		}
+		manifest, err := storage.ImportBundle(r, store, cfg.ScanDir)
+		if err != nil {
+			return fmt.Errorf("importing bundle: %w", err)
+		}
+		if manifest.ReconpipeVersion != rootCmd.Version {
+			fmt.Printf("[!] Warning: bundle was made by reconpipe %s, this is %s\n", manifest.ReconpipeVersion, rootCmd.Version)
+		}
+
+		for _, s := range manifest.Scans {
+			meta := s.Meta
+			recordAudit(store, "scan.import", meta.Target, meta.ID, fmt.Sprintf("%s from %s", s.Dir, manifest.Host))
+			fmt.Printf("[+] Imported %s (%s, %s)\n", meta.ScanDir, meta.ID, meta.Status)
+			if meta.Status == models.StatusComplete {
+				markImportedLatest(store, meta)
+			}
+		}
+
+		// Step 5: Say how to carry on with the scan that was moved
+		primary := manifest.Scans[0].Meta
+		if primary.Status == models.StatusComplete {
+			fmt.Printf("[*] Report with: reconpipe report --scan-dir %s\n", primary.ScanDir)
+		} else {
+			fmt.Printf("[*] Resume with: reconpipe scan -d %s --resume --scan-dir %s\n", primary.Target, primary.ScanDir)
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(importCmd)
+}
+
+// markImportedLatest points the target's latest entry at an imported
+// finished scan when no finished scan of the target here is newer.
+func markImportedLatest(store storage.Store, meta *models.ScanMeta) {
+	scans, err := store.ListScans(meta.Target)
+	if err != nil {
+		return
+	}
+	for _, scan := range scans {
+		if scan.Status == models.StatusComplete && scan.StartedAt.After(meta.StartedAt) {
+			return
+		}
+	}
+	if err := storage.MarkLatestScan(cfg.ScanDir, meta.Target, meta.ScanDir); err != nil {
+		fmt.Printf("[!] Warning: could not update latest scan pointer: %v\n", err)
+	}
+}
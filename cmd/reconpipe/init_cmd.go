@@ -31,6 +31,9 @@ This is typically the first command you run when setting up reconpipe.`,
 		if _, err := os.Stat(configPath); err == nil && !initForce {
 			return fmt.Errorf("config file already exists at %s. Use --force to overwrite", configPath)
 		}
+		if config.FileReadOnly(configPath) {
+			return fmt.Errorf("config file %s sets read_only; it cannot be overwritten", configPath)
+		}
 
 		// Create default config
 		if err := config.WriteDefault(configPath); err != nil {
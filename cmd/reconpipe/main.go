@@ -1,11 +1,18 @@
 package main
 
 import (
+	"errors"
 	"os"
+
+	"github.com/hakim/reconpipe/internal/riskpolicy"
 )
 
 func main() {
 	if err := Execute(); err != nil {
+		var pf *policyFailure
+		if errors.As(err, &pf) {
+			os.Exit(riskpolicy.ExitCode)
+		}
 		os.Exit(1)
 	}
 }
@@ -0,0 +1,412 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/hakim/reconpipe/internal/config"
+	"github.com/hakim/reconpipe/internal/diff"
+	"github.com/hakim/reconpipe/internal/pipeline"
+	"github.com/hakim/reconpipe/internal/schedule"
+	"github.com/hakim/reconpipe/internal/server"
+	"github.com/hakim/reconpipe/internal/storage"
+	"github.com/hakim/reconpipe/pkg/models"
+	"github.com/spf13/cobra"
+)
+
+var monitorCmd = &cobra.Command{
+	Use:   "monitor",
+	Short: "Run the configured schedules as a long-lived daemon",
+	Long: `Run the scans in the schedules section of the config, each on its cron-like
+schedule, until interrupted.
+
+A run scans the schedule's targets one after another with the same defaults
+as 'reconpipe scan' and the schedule's preset, stages, skip, severity and tag.
+Scans are recorded with the operator "monitor:<schedule name>" and follow the
+policy section of the config: a target in its cooldown, or already being
+scanned, is skipped until the next run. Runs never overlap; a run that is due
+while another is going starts when it finishes.
+
+Every run is recorded in the database with the outcome of each scan and the
+number of new subdomains, ports and vulns its diff found. The schedule's
+webhook is POSTed those new entries only when there are some, so a quiet
+night sends nothing. The first scan of a target has no previous scan to diff
+against and establishes the baseline.
+
+The run history is also how a restart picks up: a schedule that came due
+while the monitor was down runs once at startup, and otherwise at its next
+time. --run-now runs every schedule at startup regardless. With bbolt the
+database is opened only while the monitor reads or writes it, so history,
+show, diff and findings work between and during runs.
+
+While the monitor runs it watches the config file. Edits to schedules take
+effect at once; the other scan settings apply to scans started afterwards.
+db_path needs a restart.
+
+On SIGTERM or interrupt the running scan stops at its next stage boundary,
+is recorded as interrupted and the monitor exits. A second interrupt exits
+at once.
+
+Examples:
+  reconpipe monitor
+  reconpipe monitor --list
+  reconpipe monitor --run-now`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// Step 1: Get flags
+		list, _ := cmd.Flags().GetBool("list")
+		runNow, _ := cmd.Flags().GetBool("run-now")
+
+		// Step 2: Config check
+		if cfg == nil {
+			return fmt.Errorf("config not loaded. Run 'reconpipe init' first to create config")
+		}
+		if len(cfg.Schedules) == 0 {
+			return fmt.Errorf("no schedules configured; add a schedules section to %s", cfgFile)
+		}
+		if err := checkSchedules(cfg.Schedules); err != nil {
+			return err
+		}
+
+		// Step 3: Open the store, shared by every scheduled scan and,
+		// between their calls, by other reconpipe commands
+		store, err := storage.NewSharedStore(cfg.DBPath)
+		if err != nil {
+			return fmt.Errorf("opening database: %w", err)
+		}
+		defer store.Close()
+
+		plan, err := planSchedules(store, cfg.Schedules, time.Now(), runNow)
+		if err != nil {
+			return err
+		}
+		if list {
+			printSchedules(store, plan)
+			return nil
+		}
+
+		// Step 4: Run until interrupted
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		go func() {
+			<-ctx.Done()
+			stop() // a second signal kills the process instead of waiting for the stage
+		}()
+
+		// Step 5: Pick up config edits without a restart
+		var reloaded atomic.Pointer[config.Config]
+		wake := make(chan struct{}, 1)
+		err = config.Watch(ctx, cfgFile, func(next *config.Config) {
+			reloaded.Store(next)
+			select {
+			case wake <- struct{}{}:
+			default:
+			}
+		}, func(err error) {
+			fmt.Printf("[!] Config reload rejected, keeping the current config: %v\n", err)
+		})
+		if err != nil {
+			fmt.Printf("[!] Warning: not watching the config for changes: %v\n", err)
+		}
+
+		fmt.Printf("[*] Monitoring %d schedule(s)\n", len(plan))
+		for _, s := range plan {
+			fmt.Printf("    %s: next run %s\n", s.name, s.next.Format("2006-01-02 15:04:05"))
+		}
+
+		active := cfg
+		for {
+			if next := reloaded.Swap(nil); next != nil {
+				if p, ok := reloadMonitorConfig(store, active, next); ok {
+					active, plan = next, p
+				}
+			}
+
+			s := nextDue(plan)
+			if s == nil {
+				// Every schedule was removed from the config
+				select {
+				case <-ctx.Done():
+					return nil
+				case <-wake:
+					continue
+				}
+			}
+
+			timer := time.NewTimer(time.Until(s.next))
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				fmt.Println("[*] Monitor stopped")
+				return nil
+			case <-wake:
+				timer.Stop()
+				continue
+			case <-timer.C:
+			}
+
+			due := s.next
+			runSchedule(ctx, store, s, due)
+			if ctx.Err() != nil {
+				fmt.Println("[*] Monitor stopped")
+				return nil
+			}
+			s.next = s.spec.Next(laterOf(due, time.Now()))
+			fmt.Printf("[*] %s: next run %s\n", s.name, s.next.Format("2006-01-02 15:04:05"))
+		}
+	},
+}
+
+// monitorSchedule is a configured schedule and when it next runs.
+type monitorSchedule struct {
+	name string
+	cfg  config.ScheduleConfig
+	spec *schedule.Spec
+	next time.Time
+}
+
+// checkSchedules checks what config validation cannot: that the presets
+// exist.
+func checkSchedules(schedules []config.ScheduleConfig) error {
+	for _, sc := range schedules {
+		if sc.Preset == "" {
+			continue
+		}
+		if _, err := pipeline.GetPreset(sc.Preset); err != nil {
+			return fmt.Errorf("schedule %s: %w", sc.ScheduleName(), err)
+		}
+	}
+	return nil
+}
+
+// planSchedules works out when each schedule next runs: at its first time
+// after its last recorded run, which is now when that was missed while the
+// monitor was down, or after now for a schedule that has never run. runNow
+// makes every schedule due now.
+func planSchedules(store storage.Store, schedules []config.ScheduleConfig, now time.Time, runNow bool) ([]*monitorSchedule, error) {
+	var plan []*monitorSchedule
+	for _, sc := range schedules {
+		spec, err := schedule.Parse(sc.Cron) // checked by config validation
+		if err != nil {
+			return nil, err
+		}
+		s := &monitorSchedule{name: sc.ScheduleName(), cfg: sc, spec: spec}
+
+		last, err := store.LastMonitorRun(s.name)
+		if err != nil {
+			return nil, fmt.Errorf("reading the run history of %s: %w", s.name, err)
+		}
+		switch {
+		case runNow:
+			s.next = now
+		case last != nil:
+			s.next = spec.Next(last.DueAt.In(now.Location()))
+			if s.next.Before(now) {
+				s.next = now
+			}
+		default:
+			s.next = spec.Next(now)
+		}
+		plan = append(plan, s)
+	}
+	return plan, nil
+}
+
+// nextDue returns the schedule due first, or nil when there are none.
+func nextDue(plan []*monitorSchedule) *monitorSchedule {
+	var first *monitorSchedule
+	for _, s := range plan {
+		if first == nil || s.next.Before(first.next) {
+			first = s
+		}
+	}
+	return first
+}
+
+// laterOf returns the later of a and b.
+func laterOf(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
+
+// reloadMonitorConfig applies next, reloaded from the config file, to a
+// running monitor: the schedules are planned again at once, the other scan
+// settings wait in pendingConfig for the next scan. It returns the new plan,
+// and false when nothing changed or next cannot be used.
+func reloadMonitorConfig(store storage.Store, prev, next *config.Config) ([]*monitorSchedule, bool) {
+	if next.DBPath != prev.DBPath || next.DBDriver != prev.DBDriver {
+		fmt.Println("[!] Config: changes to db_path and db_driver need a restart; keeping the running values")
+		next.DBPath, next.DBDriver = prev.DBPath, prev.DBDriver
+	}
+	changed := config.ChangedKeys(prev, next)
+	if len(changed) == 0 {
+		return nil, false
+	}
+	if err := checkSchedules(next.Schedules); err != nil {
+		fmt.Printf("[!] Config reload rejected, keeping the current config: %v\n", err)
+		return nil, false
+	}
+	plan, err := planSchedules(store, next.Schedules, time.Now(), false)
+	if err != nil {
+		fmt.Printf("[!] Config reload rejected, keeping the current config: %v\n", err)
+		return nil, false
+	}
+
+	pendingConfig.Store(next)
+	fmt.Printf("[+] Config reloaded (%s); applies to scans started from now on\n", strings.Join(changed, ", "))
+	for _, s := range plan {
+		fmt.Printf("    %s: next run %s\n", s.name, s.next.Format("2006-01-02 15:04:05"))
+	}
+	return plan, true
+}
+
+// runSchedule scans the targets of s for its run due at due, recording the
+// run as it goes, and notifies the schedule's webhook of scans that found
+// something new. A signal on ctx stops the running scan at its next stage
+// boundary and skips the remaining targets.
+func runSchedule(ctx context.Context, store storage.Store, s *monitorSchedule, due time.Time) {
+	run := &models.MonitorRun{Schedule: s.name, DueAt: due, StartedAt: time.Now()}
+	saveRun := func() {
+		if err := store.SaveMonitorRun(run); err != nil {
+			fmt.Printf("[!] Warning: recording the run of %s: %v\n", s.name, err)
+		}
+	}
+	saveRun()
+	fmt.Printf("[*] %s: starting scheduled run of %s\n", s.name, strings.Join(s.cfg.Targets, ", "))
+
+	for _, target := range s.cfg.Targets {
+		if ctx.Err() != nil {
+			break
+		}
+		run.Targets = append(run.Targets, scanScheduledTarget(ctx, store, s, target))
+		saveRun()
+	}
+
+	run.FinishedAt = time.Now()
+	saveRun()
+	fmt.Printf("[+] %s: run finished in %s\n", s.name, run.FinishedAt.Sub(run.StartedAt).Round(time.Second))
+}
+
+// scanScheduledTarget runs one scan of a scheduled run and reports its
+// outcome. The scan runs to completion even when ctx is cancelled; the
+// cancellation only stops it at the next stage boundary.
+func scanScheduledTarget(ctx context.Context, store storage.Store, s *monitorSchedule, target string) models.MonitorTarget {
+	out := models.MonitorTarget{Target: target}
+	job := server.Job{
+		Request: server.ScanRequest{
+			Target:   target,
+			Preset:   s.cfg.Preset,
+			Stages:   s.cfg.Stages,
+			Skip:     s.cfg.Skip,
+			Severity: s.cfg.Severity,
+			Tag:      s.cfg.Tag,
+		},
+		Operator: "monitor:" + s.name,
+	}
+	result, err := runQueuedScan(context.Background(), store, job, ctx.Done())
+	var policyErr *pipeline.PolicyError
+	switch {
+	case errors.As(err, &policyErr):
+		out.Status, out.Error = "skipped", err.Error()
+		fmt.Printf("[!] %s: %s skipped: %v\n", s.name, target, err)
+		return out
+	case err != nil:
+		out.Status, out.Error = "failed", err.Error()
+		fmt.Printf("[!] %s: %s failed: %v\n", s.name, target, err)
+		if result == nil {
+			return out
+		}
+	default:
+		out.Status = result.Status
+	}
+	out.ScanID = result.ScanID
+
+	dr, err := loadScanDiff(result.ScanDir)
+	if err != nil {
+		fmt.Printf("[!] Warning: %s: %v\n", target, err)
+	}
+	if dr == nil {
+		fmt.Printf("[*] %s: %s has no diff (no previous scan); nothing to notify\n", s.name, target)
+		return out
+	}
+	out.NewSubdomains, out.NewPorts, out.NewVulns = len(dr.NewSubdomains), len(dr.NewPorts), len(dr.NewVulns)
+	fmt.Printf("[+] %s: %s %s: +%d subdomains, +%d ports, +%d vulns\n",
+		s.name, target, result.Status, out.NewSubdomains, out.NewPorts, out.NewVulns)
+
+	if s.cfg.Webhook == "" || !pipeline.HasNew(dr) {
+		return out
+	}
+	notifyCfg := pipeline.NotifyConfig{WebhookURL: os.ExpandEnv(s.cfg.Webhook)}
+	if err := notifyCfg.SendChanges(s.name, result, dr); err != nil {
+		fmt.Printf("[!] Warning: webhook notification failed: %v\n", err)
+	} else {
+		out.Notified = true
+		fmt.Printf("[+] %s: changes in %s sent to the webhook\n", s.name, target)
+	}
+	return out
+}
+
+// loadScanDiff reads the diff the diff stage wrote to scanDir, or returns
+// nil when it wrote none.
+func loadScanDiff(scanDir string) (*diff.DiffResult, error) {
+	if scanDir == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(storage.RawPath(scanDir, "diff.json"))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading diff.json: %w", err)
+	}
+	var dr diff.DiffResult
+	if err := json.Unmarshal(data, &dr); err != nil {
+		return nil, fmt.Errorf("parsing diff.json: %w", err)
+	}
+	return &dr, nil
+}
+
+// printSchedules prints each schedule with its last and next run.
+func printSchedules(store storage.Store, plan []*monitorSchedule) {
+	for _, s := range plan {
+		fmt.Printf("%s  (%s)\n", s.name, s.spec)
+		fmt.Printf("  targets:  %s\n", strings.Join(s.cfg.Targets, ", "))
+		last, err := store.LastMonitorRun(s.name)
+		switch {
+		case err != nil:
+			fmt.Printf("  last run: unknown (%v)\n", err)
+		case last == nil:
+			fmt.Println("  last run: never")
+		default:
+			fmt.Printf("  last run: %s\n", last.DueAt.Local().Format("2006-01-02 15:04:05"))
+			for _, t := range last.Targets {
+				line := fmt.Sprintf("    %s: %s", t.Target, t.Status)
+				if t.Error != "" {
+					line += " (" + t.Error + ")"
+				} else if t.ScanID != "" {
+					line += fmt.Sprintf(", +%d subdomains, +%d ports, +%d vulns", t.NewSubdomains, t.NewPorts, t.NewVulns)
+				}
+				if t.Notified {
+					line += ", notified"
+				}
+				fmt.Println(line)
+			}
+		}
+		fmt.Printf("  next run: %s\n", s.next.Format("2006-01-02 15:04:05"))
+	}
+}
+
+func init() {
+	monitorCmd.Flags().Bool("list", false, "Print the schedules with their last and next runs, and exit")
+	monitorCmd.Flags().Bool("run-now", false, "Run every schedule once at startup, then follow the schedules")
+	rootCmd.AddCommand(monitorCmd)
+}
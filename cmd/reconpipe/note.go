@@ -0,0 +1,222 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hakim/reconpipe/internal/diff"
+	"github.com/hakim/reconpipe/internal/notes"
+	"github.com/hakim/reconpipe/internal/pipeline"
+	"github.com/hakim/reconpipe/internal/storage"
+	"github.com/hakim/reconpipe/pkg/models"
+	"github.com/spf13/cobra"
+)
+
+// maxNoteWidth caps the note text shown per row by 'note list'.
+const maxNoteWidth = 48
+
+var noteCmd = &cobra.Command{
+	Use:   "note",
+	Short: "Attach analyst notes to findings and assets",
+	Long: `Add, list and delete analyst notes on a target's findings and assets, so the
+context behind a finding ("confirmed exploitable, see SEC-123") lives next to it.
+
+A finding is named by its key: template-id::host for a vulnerability, as in
+raw/vulns.json, or the subdomain for a dangling DNS record. An asset is a
+hostname or IP.
+
+Notes belong to the target, not to a scan. Every scan that reports the
+finding or asset gets a copy in raw/notes.json and reports/notes.md, and its
+exports carry them. Adding or deleting a note refreshes the latest scan;
+older scans keep the notes they had.`,
+}
+
+var noteAddCmd = &cobra.Command{
+	Use:   "add <text>",
+	Short: "Add a note to a finding or asset",
+	Long: `Add a note to a finding or asset of a target.
+
+Examples:
+  reconpipe note add -d example.com --finding "git-config::https://dev.example.com" "confirmed exploitable, see ticket SEC-123"
+  reconpipe note add -d example.com --finding old.example.com "CNAME owner contacted"
+  reconpipe note add -d example.com --asset 203.0.113.7 "shared hosting, out of scope for active testing"`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// Step 1: Get flags
+		domain, _ := cmd.Flags().GetString("domain")
+		finding, _ := cmd.Flags().GetString("finding")
+		asset, _ := cmd.Flags().GetString("asset")
+		text := strings.TrimSpace(args[0])
+
+		note := &models.Note{
+			ID:        uuid.New().String(),
+			Target:    domain,
+			Text:      text,
+			Author:    operator,
+			CreatedAt: time.Now().UTC(),
+		}
+		switch {
+		case finding != "" && asset != "":
+			return fmt.Errorf("use either --finding or --asset, not both")
+		case finding != "":
+			note.Subject, note.Key = models.NoteFinding, strings.TrimSpace(finding)
+		case asset != "":
+			note.Subject, note.Key = models.NoteAsset, strings.ToLower(strings.TrimSpace(asset))
+		default:
+			return fmt.Errorf("either --finding or --asset is required")
+		}
+		if text == "" {
+			return fmt.Errorf("note text is empty")
+		}
+
+		// Step 2: Config check
+		if cfg == nil {
+			return fmt.Errorf("config not loaded. Run 'reconpipe init' first to create config")
+		}
+
+		// Step 3: Warn when the latest scan does not report the key, which is
+		// usually a typo
+		latestDir, err := storage.FindLatestScanDir(cfg.ScanDir, domain)
+		if err == nil {
+			if snap, err := diff.LoadSnapshot(latestDir); err == nil && len(notes.Attach([]*models.Note{note}, snap)) == 0 {
+				fmt.Printf("[!] Warning: the latest scan of %s has no %s %q; the note will show once a scan reports it\n", domain, note.Subject, note.Key)
+			}
+		}
+
+		// Step 4: Store it
+		store, err := storage.NewStore(cfg.DBPath)
+		if err != nil {
+			return fmt.Errorf("opening database: %w", err)
+		}
+		defer store.Close()
+
+		if err := store.SaveNote(note); err != nil {
+			return fmt.Errorf("saving note: %w", err)
+		}
+		recordAudit(store, "note.add", domain, "", fmt.Sprintf("%s %s (%s)", note.Subject, note.Key, shortScanID(note.ID)))
+		fmt.Printf("[+] Added note on %s %s (ID: %s)\n", note.Subject, note.Key, shortScanID(note.ID))
+
+		// Step 5: Refresh the latest scan's copy
+		if latestDir != "" {
+			refreshScanNotes(store, domain, latestDir)
+		}
+		return nil
+	},
+}
+
+var noteListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List a target's notes",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// Step 1: Get flags
+		domain, _ := cmd.Flags().GetString("domain")
+
+		// Step 2: Config check
+		if cfg == nil {
+			return fmt.Errorf("config not loaded. Run 'reconpipe init' first to create config")
+		}
+
+		// Step 3: Open bbolt store
+		store, err := storage.NewStore(cfg.DBPath)
+		if err != nil {
+			return fmt.Errorf("opening database: %w", err)
+		}
+		defer store.Close()
+
+		// Step 4: List notes
+		list, err := store.ListNotes(domain)
+		if err != nil {
+			return fmt.Errorf("listing notes: %w", err)
+		}
+		if len(list) == 0 {
+			fmt.Printf("No notes found for %s\n", domain)
+			return nil
+		}
+
+		// Step 5: Print formatted table
+		const separator = "──────────────────────────────────────────────────────────────────────────────────────────────────────────"
+
+		fmt.Println()
+		fmt.Println(separator)
+		fmt.Printf("  %-12s  %-10s  %-12s  %-8s  %-40s  %s\n", "ID", "Added", "Author", "On", "Key", "Note")
+		fmt.Println(separator)
+		for _, n := range list {
+			text := strings.Join(strings.Fields(n.Text), " ")
+			if len(text) > maxNoteWidth {
+				text = text[:maxNoteWidth-3] + "..."
+			}
+			fmt.Printf("  %-12s  %-10s  %-12s  %-8s  %-40s  %s\n",
+				shortScanID(n.ID), n.CreatedAt.UTC().Format("2006-01-02"), n.Author, n.Subject, n.Key, text)
+		}
+		fmt.Println(separator)
+		fmt.Printf("Total: %d note(s)\n\n", len(list))
+		return nil
+	},
+}
+
+var noteDeleteCmd = &cobra.Command{
+	Use:   "delete <note-id>",
+	Short: "Delete a note",
+	Long: `Delete a note by its ID or the shortened ID shown by 'reconpipe note list'.
+The latest scan of its target is refreshed; older scans keep their copy.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// Step 1: Config check
+		if cfg == nil {
+			return fmt.Errorf("config not loaded. Run 'reconpipe init' first to create config")
+		}
+
+		// Step 2: Open bbolt store
+		store, err := storage.NewStore(cfg.DBPath)
+		if err != nil {
+			return fmt.Errorf("opening database: %w", err)
+		}
+		defer store.Close()
+
+		// Step 3: Find and delete
+		note, err := store.FindNote(args[0])
+		if err != nil {
+			return err
+		}
+		if note == nil {
+			return fmt.Errorf("no note with ID %s", args[0])
+		}
+		if err := store.DeleteNote(note.ID); err != nil {
+			return fmt.Errorf("deleting note: %w", err)
+		}
+		recordAudit(store, "note.delete", note.Target, "", fmt.Sprintf("%s %s (%s)", note.Subject, note.Key, shortScanID(note.ID)))
+		fmt.Printf("[+] Deleted note on %s %s (ID: %s)\n", note.Subject, note.Key, shortScanID(note.ID))
+
+		// Step 4: Refresh the latest scan's copy
+		if latestDir, err := storage.FindLatestScanDir(cfg.ScanDir, note.Target); err == nil {
+			refreshScanNotes(store, note.Target, latestDir)
+		}
+		return nil
+	},
+}
+
+// refreshScanNotes rewrites the notes of the scan in scanDir after a change.
+// A failure only warns: the note itself is saved.
+func refreshScanNotes(store storage.Store, target, scanDir string) {
+	n, err := pipeline.AttachNotes(store, target, scanDir)
+	if err != nil {
+		fmt.Printf("[!] Warning: could not refresh notes of %s: %v\n", scanDir, err)
+		return
+	}
+	fmt.Printf("[+] %s now carries %d note(s)\n", storage.ReportPath(scanDir, "notes.md"), n)
+}
+
+func init() {
+	noteAddCmd.Flags().StringP("domain", "d", "", "Target the note belongs to (required)")
+	noteAddCmd.Flags().String("finding", "", "Finding key: template-id::host, or a dangling subdomain")
+	noteAddCmd.Flags().String("asset", "", "Asset hostname or IP")
+	noteAddCmd.MarkFlagRequired("domain")
+
+	noteListCmd.Flags().StringP("domain", "d", "", "Target whose notes to list (required)")
+	noteListCmd.MarkFlagRequired("domain")
+
+	noteCmd.AddCommand(noteAddCmd, noteListCmd, noteDeleteCmd)
+	rootCmd.AddCommand(noteCmd)
+}
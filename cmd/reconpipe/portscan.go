@@ -5,17 +5,17 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
-	"path/filepath"
-	"sort"
+	"slices"
 	"strings"
 	"time"
 
 	"github.com/hakim/reconpipe/internal/discovery"
-	"github.com/hakim/reconpipe/internal/models"
+	"github.com/hakim/reconpipe/internal/netprobe"
 	"github.com/hakim/reconpipe/internal/portscan"
 	"github.com/hakim/reconpipe/internal/report"
 	"github.com/hakim/reconpipe/internal/storage"
 	"github.com/hakim/reconpipe/internal/tools"
+	"github.com/hakim/reconpipe/pkg/models"
 	"github.com/spf13/cobra"
 )
 
@@ -25,7 +25,8 @@ var portscanCmd = &cobra.Command{
 	Long: `Run the port scanning pipeline for a target domain.
 
 This command reads subdomain discovery results from a prior scan, filters CDN IPs
-via cdncheck, discovers open ports via masscan, and fingerprints services via nmap.
+via cdncheck, discovers open ports via masscan (or naabu, per portscan.scanner),
+and fingerprints services via nmap.
 
 Results are saved to:
   - {scan_dir}/reports/ports.md (report)
@@ -38,10 +39,15 @@ Scan metadata is updated in the configured database.`,
 		scanDir, _ := cmd.Flags().GetString("scan-dir")
 		skipCDNCheck, _ := cmd.Flags().GetBool("skip-cdncheck")
 		timeout, _ := cmd.Flags().GetDuration("timeout")
+		ports, _ := cmd.Flags().GetString("ports")
+		topPorts, _ := cmd.Flags().GetInt("top-ports")
+		udp, _ := cmd.Flags().GetBool("udp")
 
 		// Step 1: Pre-flight check - verify required tools
+		naabu := useNaabu()
 		requiredTools := []tools.ToolRequirement{
-			{Name: "masscan", Binary: "masscan", Required: true, InstallCmd: "apt install masscan (or brew install masscan on macOS)"},
+			{Name: "masscan", Binary: "masscan", Required: !naabu, InstallCmd: "apt install masscan (or brew install masscan on macOS)"},
+			{Name: "naabu", Binary: "naabu", Required: naabu, InstallCmd: naabuInstallCmd},
 			{Name: "nmap", Binary: "nmap", Required: true, InstallCmd: "apt install nmap (or brew install nmap on macOS)"},
 		}
 
@@ -50,7 +56,7 @@ Scan metadata is updated in the configured database.`,
 
 		for _, tool := range requiredTools {
 			result := tools.CheckTool(tool)
-			if !result.Found {
+			if tool.Required && !result.Found {
 				return fmt.Errorf("required tool '%s' not found. Install with: %s", tool.Name, tool.InstallCmd)
 			}
 		}
@@ -68,11 +74,18 @@ Scan metadata is updated in the configured database.`,
 		if cfg == nil {
 			return fmt.Errorf("config not loaded. Run 'reconpipe init' first to create config")
 		}
+		if err := checkPortSelection(ports, topPorts); err != nil {
+			return err
+		}
+		ports = strings.Join(strings.Fields(ports), "")
+		if !cmd.Flags().Changed("udp") && cfg.PortScan.UDP.Enabled {
+			udp = true
+		}
 
 		// Step 3: Determine scan directory
 		if scanDir == "" {
 			// Find latest scan dir for the domain
-			latestDir, err := findLatestScanDir(cfg.ScanDir, domain)
+			latestDir, err := storage.FindLatestScanDir(cfg.ScanDir, domain)
 			if err != nil {
 				return fmt.Errorf("finding latest scan directory: %w. Run 'reconpipe discover -d %s' first", err, domain)
 			}
@@ -82,7 +95,7 @@ Scan metadata is updated in the configured database.`,
 		fmt.Printf("[*] Using scan directory: %s\n", scanDir)
 
 		// Step 4: Read subdomains.json from prior discover scan
-		subdomainsPath := filepath.Join(scanDir, "raw", "subdomains.json")
+		subdomainsPath := storage.RawPath(scanDir, "subdomains.json")
 		subdomainsData, err := os.ReadFile(subdomainsPath)
 		if err != nil {
 			return fmt.Errorf("reading subdomains.json: %w. Run 'reconpipe discover' first", err)
@@ -113,13 +126,30 @@ Scan metadata is updated in the configured database.`,
 		defer cancel()
 
 		// Step 7: Build PortScanConfig
+		exclusions, err := cfg.Exclusions()
+		if err != nil {
+			return fmt.Errorf("loading exclusions: %w", err)
+		}
+
 		portScanCfg := portscan.PortScanConfig{
+			Target:          domain,
 			CdncheckPath:    "", // Use binary from PATH
 			MasscanPath:     "", // Use binary from PATH
+			NaabuPath:       "", // Use binary from PATH
 			NmapPath:        "", // Use binary from PATH
 			MasscanRate:     cfg.RateLimits.MasscanRate,
 			NmapMaxParallel: cfg.RateLimits.NmapMaxParallel,
 			SkipCDNCheck:    skipCDNCheck || !cdncheckAvailable,
+			Scanner:         cfg.PortScan.Scanner,
+			PortRange:       ports,
+			TopPorts:        topPorts,
+			UDP:             udp,
+			UDPPorts:        cfg.PortScan.UDP.Ports,
+			SkipMailChecks:  cfg.PortScan.MailChecks.Skip,
+			MailCheck:       mailCheckConfig(),
+			Exclude:         exclusions,
+			GeoIPPath:       cfg.PortScan.GeoIPDB,
+			Profiles:        cfg.ProviderProfiles.Set(),
 		}
 
 		// Step 8: Print progress
@@ -135,22 +165,32 @@ Scan metadata is updated in the configured database.`,
 		fmt.Printf("[+] Port scan complete: %d CDN hosts, %d scanned, %d open ports\n",
 			result.CDNCount, result.ScannedCount, result.TotalPorts)
 
-		// Step 11: Write markdown report
-		reportPath := filepath.Join(scanDir, "reports", "ports.md")
+		// Step 11: Write markdown reports
+		reportPath := storage.ReportPath(scanDir, "ports.md")
 		if err := report.WritePortReport(result, reportPath); err != nil {
 			// Warn but don't fail - raw data is still saved
 			fmt.Printf("[!] Warning: failed to write report: %v\n", err)
 		} else {
 			fmt.Printf("[+] Report written to %s\n", reportPath)
 		}
+		htmlPath := storage.ReportPath(scanDir, "ports.html")
+		if err := report.WritePortHTMLReport(result, htmlPath); err != nil {
+			fmt.Printf("[!] Warning: failed to write HTML report: %v\n", err)
+		} else {
+			fmt.Printf("[+] HTML report written to %s\n", htmlPath)
+		}
+		exposurePath := storage.ReportPath(scanDir, "exposure.md")
+		if err := report.WriteExposureReport(result, nil, exposurePath); err != nil {
+			fmt.Printf("[!] Warning: failed to write exposure report: %v\n", err)
+		}
 
 		// Step 12: Save raw output as JSON
-		rawPath := filepath.Join(scanDir, "raw", "ports.json")
+		rawPath := storage.RawPath(scanDir, "ports.json")
 		rawData, err := json.MarshalIndent(result, "", "  ")
 		if err != nil {
 			return fmt.Errorf("marshaling raw output: %w", err)
 		}
-		if err := os.WriteFile(rawPath, rawData, 0644); err != nil {
+		if err := storage.WriteFileAtomic(rawPath, rawData, 0644); err != nil {
 			return fmt.Errorf("writing raw output: %w", err)
 		}
 
@@ -200,16 +240,39 @@ Scan metadata is updated in the configured database.`,
 				fullScan.StagesRun = append(fullScan.StagesRun, "portscan")
 			}
 
+			// Record the ports scanned and whether UDP was, as scan does, so
+			// diff only compares this scan with scans of the same kind
+			rc := fullScan.RunConfig
+			if rc == nil && (ports != "" || topPorts > 0 || udp) {
+				rc = &models.RunConfig{}
+			}
+			if rc != nil {
+				rc.Ports, rc.TopPorts, rc.UDP = ports, topPorts, udp
+				fullScan.RunConfig = rc
+				if err := storage.WriteRunConfig(scanDir, rc); err != nil {
+					fmt.Printf("[!] Warning: failed to write run config: %v\n", err)
+				}
+			}
+
 			// Save updated scan
 			if err := store.SaveScan(&fullScan.ScanMeta); err != nil {
 				return fmt.Errorf("updating scan metadata: %w", err)
 			}
+			if err := store.SaveHosts(targetScan.ID, result.Hosts, result.CollectedAt); err != nil {
+				fmt.Printf("[!] Warning: failed to save port scan results to the database: %v\n", err)
+			}
 
 			fmt.Printf("[+] Scan metadata updated (ID: %s)\n", targetScan.ID)
 		} else {
 			fmt.Println("[!] Warning: Could not find scan record to update in database")
 		}
 
+		scanID := ""
+		if targetScan != nil {
+			scanID = targetScan.ID
+		}
+		recordAudit(store, "portscan", domain, scanID, scanDir)
+
 		// Step 15: Print final summary
 		fmt.Println()
 		fmt.Printf("[+] Port scan complete!\n")
@@ -228,6 +291,9 @@ func init() {
 	portscanCmd.Flags().String("scan-dir", "", "Path to existing scan directory (auto-detects latest if empty)")
 	portscanCmd.Flags().Bool("skip-cdncheck", false, "Skip CDN detection")
 	portscanCmd.Flags().Duration("timeout", 30*time.Minute, "Overall timeout")
+	portscanCmd.Flags().String("ports", "", portsFlagUsage)
+	portscanCmd.Flags().Int("top-ports", 0, topPortsFlagUsage)
+	portscanCmd.Flags().Bool("udp", false, udpFlagUsage)
 
 	// Mark domain as required
 	portscanCmd.MarkFlagRequired("domain")
@@ -236,33 +302,46 @@ func init() {
 	rootCmd.AddCommand(portscanCmd)
 }
 
-// findLatestScanDir finds the most recent scan directory for a domain.
-// It looks for directories matching {domain}_* pattern and returns the newest.
-func findLatestScanDir(baseDir, domain string) (string, error) {
-	entries, err := os.ReadDir(baseDir)
-	if err != nil {
-		return "", fmt.Errorf("reading scan directory: %w", err)
-	}
-
-	// Sanitize domain to match directory naming convention
-	sanitized := storage.SanitizeTarget(domain)
-	prefix := sanitized + "_"
+// Usage of the --ports, --top-ports and --udp flags of portscan and scan.
+const (
+	portsFlagUsage    = "Ports to scan instead of all 65535, e.g. 22,80,443,8000-8100"
+	topPortsFlagUsage = "Scan only the N most common ports (naabu: 100 or 1000)"
+	udpFlagUsage      = "Also scan common UDP services (DNS, SNMP, NTP...) with nmap -sU; needs root (see portscan.udp)"
+)
 
-	// Collect matching directories
-	var matchingDirs []string
-	for _, entry := range entries {
-		if entry.IsDir() && strings.HasPrefix(entry.Name(), prefix) {
-			matchingDirs = append(matchingDirs, entry.Name())
+// checkPortSelection validates the --ports and --top-ports values, which
+// cannot be combined.
+func checkPortSelection(ports string, topPorts int) error {
+	switch {
+	case ports != "" && topPorts != 0:
+		return fmt.Errorf("--ports and --top-ports cannot be combined")
+	case topPorts < 0:
+		return fmt.Errorf("--top-ports must be positive")
+	case topPorts > 0 && useNaabu() && !slices.Contains(tools.NaabuTopPorts, topPorts):
+		return fmt.Errorf("--top-ports %d: naabu only supports 100 or 1000", topPorts)
+	case ports != "":
+		if err := tools.CheckPortRange(ports); err != nil {
+			return fmt.Errorf("--ports: %w", err)
 		}
 	}
+	return nil
+}
 
-	if len(matchingDirs) == 0 {
-		return "", fmt.Errorf("no scan directories found for domain %s", domain)
-	}
+// naabuInstallCmd is how naabu is installed, for the tool checks.
+const naabuInstallCmd = "go install -v github.com/projectdiscovery/naabu/v2/cmd/naabu@latest"
 
-	// Sort descending (newest first due to timestamp format)
-	sort.Sort(sort.Reverse(sort.StringSlice(matchingDirs)))
+// useNaabu reports whether portscan.scanner selects naabu instead of
+// masscan.
+func useNaabu() bool {
+	return cfg != nil && cfg.PortScan.Scanner == tools.ScannerNaabu
+}
 
-	// Return full path of the latest directory
-	return filepath.Join(baseDir, matchingDirs[0]), nil
+// mailCheckConfig converts the portscan.mail_checks settings. The timeout was
+// validated at config load, so a parse failure cannot happen here.
+func mailCheckConfig() netprobe.MailCheckConfig {
+	mc := netprobe.MailCheckConfig{SkipRelayTest: cfg.PortScan.MailChecks.SkipRelayTest}
+	if cfg.PortScan.MailChecks.Timeout != "" {
+		mc.Timeout, _ = time.ParseDuration(cfg.PortScan.MailChecks.Timeout)
+	}
+	return mc
 }
@@ -5,15 +5,19 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
-	"path/filepath"
+	"slices"
 	"time"
 
+	"github.com/hakim/reconpipe/internal/diff"
+	"github.com/hakim/reconpipe/internal/filter"
 	"github.com/hakim/reconpipe/internal/httpprobe"
-	"github.com/hakim/reconpipe/internal/models"
+	"github.com/hakim/reconpipe/internal/netprobe"
+	"github.com/hakim/reconpipe/internal/origins"
 	"github.com/hakim/reconpipe/internal/portscan"
 	"github.com/hakim/reconpipe/internal/report"
 	"github.com/hakim/reconpipe/internal/storage"
 	"github.com/hakim/reconpipe/internal/tools"
+	"github.com/hakim/reconpipe/pkg/models"
 	"github.com/spf13/cobra"
 )
 
@@ -58,19 +62,27 @@ Scan metadata is updated in the configured database.`,
 		}
 
 		gowitnessResult := tools.CheckTool(gowinessTool)
-		if !gowitnessResult.Found && !skipScreenshots {
-			fmt.Println("[!] Warning: gowitness not found, screenshots will be skipped")
-			skipScreenshots = true
-		}
 
 		// Step 3: Verify config was loaded
 		if cfg == nil {
 			return fmt.Errorf("config not loaded. Run 'reconpipe init' first to create config")
 		}
 
+		_, probeFilter, err := stageFilter(cmd, "filter", cfg.Probe.Filter, filter.TargetFields)
+		if err != nil {
+			return err
+		}
+
+		// Screenshots need gowitness or a Chrome install for chromedp
+		engine := screenshotEngine(gowitnessResult.Found)
+		if engine == "" && !skipScreenshots {
+			fmt.Println("[!] Warning: no screenshot engine available (install gowitness or Chrome/Chromium), screenshots will be skipped")
+			skipScreenshots = true
+		}
+
 		// Step 4: Determine scan directory
 		if scanDir == "" {
-			latestDir, err := findLatestScanDir(cfg.ScanDir, domain)
+			latestDir, err := storage.FindLatestScanDir(cfg.ScanDir, domain)
 			if err != nil {
 				return fmt.Errorf("finding latest scan directory: %w. Run 'reconpipe portscan -d %s' first", err, domain)
 			}
@@ -80,7 +92,7 @@ Scan metadata is updated in the configured database.`,
 		fmt.Printf("[*] Using scan directory: %s\n", scanDir)
 
 		// Step 5: Read ports.json from prior portscan
-		portsPath := filepath.Join(scanDir, "raw", "ports.json")
+		portsPath := storage.RawPath(scanDir, "ports.json")
 		portsData, err := os.ReadFile(portsPath)
 		if err != nil {
 			return fmt.Errorf("reading ports.json: %w. Run 'reconpipe portscan -d %s' first", err, domain)
@@ -91,7 +103,7 @@ Scan metadata is updated in the configured database.`,
 			return fmt.Errorf("parsing ports.json: %w", err)
 		}
 
-		// Step 6: Extract hosts that have open ports (skip CDN hosts with no ports)
+		// Step 6: Extract hosts that have open ports, plus CDN hosts
 		hosts := hostsWithOpenPorts(portResult.Hosts)
 		if len(hosts) == 0 {
 			fmt.Println("[!] No hosts with open ports found. Nothing to probe.")
@@ -104,15 +116,47 @@ Scan metadata is updated in the configured database.`,
 		ctx, cancel := context.WithTimeout(context.Background(), timeout)
 		defer cancel()
 
-		// Step 8: Build HTTPProbeConfig
-		screenshotDir := filepath.Join(scanDir, "screenshots")
+		// Step 8: Build HTTPProbeConfig. Earlier scans in the database give
+		// origin discovery its DNS history.
+		exclusions, err := cfg.Exclusions()
+		if err != nil {
+			return fmt.Errorf("loading exclusions: %w", err)
+		}
+
+		store, err := storage.NewStore(cfg.DBPath)
+		if err != nil {
+			return fmt.Errorf("opening database: %w", err)
+		}
+		defer store.Close()
+
+		screenshotDir := storage.ScreenshotsDir(scanDir)
 		probeCfg := httpprobe.HTTPProbeConfig{
+			Target:           domain,
 			HttpxPath:        "",
 			GowitnessPath:    "",
 			HttpxThreads:     cfg.RateLimits.HttpxThreads,
 			GowitnessThreads: 6,
 			ScreenshotDir:    screenshotDir,
 			SkipScreenshots:  skipScreenshots,
+			Screenshots:      screenshotOptions(engine),
+			BodyScan:         bodyScanOptions(),
+			LiveStatus:       cfg.Probe.LiveStatus,
+			Exclude:          exclusions,
+			SkipProtocols:    cfg.Probe.Protocols.Skip,
+			Protocols:        protocolCheckConfig(),
+			CheckMethods:     cfg.Probe.MethodChecks.Enabled,
+			Methods:          methodCheckConfig(),
+			SkipRetry:        cfg.Probe.Retry.Skip,
+			RetryThreads:     cfg.Probe.Retry.Threads,
+			RetryRateLimit:   cfg.Probe.Retry.RateLimit,
+			SkipClones:       cfg.Probe.Clones.Skip,
+			SkipOriginCheck:  cfg.Probe.OriginCheck.Skip,
+			Filter:           probeFilter,
+			ChunkSize:        cfg.Memory.ProbeChunkSize(),
+			Profiles:         cfg.ProviderProfiles.Set(),
+		}
+		if !probeCfg.SkipOriginCheck {
+			probeCfg.OriginHistory = originHistory(store, domain, scanDir)
 		}
 
 		// Step 9: Create screenshot directory
@@ -129,39 +173,43 @@ Scan metadata is updated in the configured database.`,
 			return fmt.Errorf("HTTP probe pipeline failed: %w", err)
 		}
 
-		// Ensure the target field is set to the requested domain
-		if probeResult.Target == "" {
-			probeResult.Target = domain
-		}
-
 		fmt.Printf("[+] HTTP probe complete: %d live services\n", probeResult.LiveCount)
 
-		// Step 11: Write markdown report
-		reportPath := filepath.Join(scanDir, "reports", "http-probes.md")
+		// Step 11: Write markdown reports
+		reportPath := storage.ReportPath(scanDir, "http-probes.md")
 		if err := report.WriteHTTPProbeReport(probeResult, reportPath); err != nil {
 			// Warn but do not fail — raw data is still saved below
 			fmt.Printf("[!] Warning: failed to write report: %v\n", err)
 		} else {
 			fmt.Printf("[+] Report written to %s\n", reportPath)
 		}
+		htmlPath := storage.ReportPath(scanDir, "http-probes.html")
+		if err := report.WriteHTTPProbeHTMLReport(probeResult, htmlPath); err != nil {
+			fmt.Printf("[!] Warning: failed to write HTML report: %v\n", err)
+		} else {
+			fmt.Printf("[+] HTML report written to %s\n", htmlPath)
+		}
+		exposurePath := storage.ReportPath(scanDir, "exposure.md")
+		if err := report.WriteExposureReport(&portResult, probeResult, exposurePath); err != nil {
+			fmt.Printf("[!] Warning: failed to write exposure report: %v\n", err)
+		}
 
 		// Step 12: Save raw JSON
-		rawPath := filepath.Join(scanDir, "raw", "http-probes.json")
+		rawPath := storage.RawPath(scanDir, "http-probes.json")
 		rawData, err := json.MarshalIndent(probeResult, "", "  ")
 		if err != nil {
 			return fmt.Errorf("marshaling raw output: %w", err)
 		}
-		if err := os.WriteFile(rawPath, rawData, 0644); err != nil {
+		if err := storage.WriteFileAtomic(rawPath, rawData, 0644); err != nil {
 			return fmt.Errorf("writing raw output: %w", err)
 		}
-
-		// Step 13: Update scan metadata in bbolt
-		store, err := storage.NewStore(cfg.DBPath)
-		if err != nil {
-			return fmt.Errorf("opening database: %w", err)
+		if len(probeResult.Origins) > 0 {
+			if err := recordOrigins(scanDir, &portResult, probeResult.Origins); err != nil {
+				return fmt.Errorf("recording origin candidates: %w", err)
+			}
 		}
-		defer store.Close()
 
+		// Step 13: Update scan metadata in bbolt
 		scans, err := store.ListScans(domain)
 		if err != nil {
 			return fmt.Errorf("listing scans: %w", err)
@@ -191,16 +239,31 @@ Scan metadata is updated in the configured database.`,
 			if err := store.SaveScan(targetScan); err != nil {
 				return fmt.Errorf("updating scan metadata: %w", err)
 			}
+			if err := store.SaveProbes(targetScan.ID, probeResult.Probes, probeResult.CollectedAt); err != nil {
+				fmt.Printf("[!] Warning: failed to save HTTP probe results to the database: %v\n", err)
+			}
+			if len(probeResult.Origins) > 0 {
+				if err := store.SaveHosts(targetScan.ID, portResult.Hosts, portResult.CollectedAt); err != nil {
+					fmt.Printf("[!] Warning: failed to save origin candidates to the database: %v\n", err)
+				}
+			}
 
 			fmt.Printf("[+] Scan metadata updated (ID: %s)\n", targetScan.ID)
 		} else {
 			fmt.Println("[!] Warning: Could not find scan record to update in database")
 		}
 
+		scanID := ""
+		if targetScan != nil {
+			scanID = targetScan.ID
+		}
+		recordAudit(store, "probe", domain, scanID, scanDir)
+
 		// Step 14: Print final summary
 		fmt.Println()
 		fmt.Printf("[+] HTTP probe complete!\n")
 		fmt.Printf("    Live services: %d\n", probeResult.LiveCount)
+		fmt.Printf("    Unique applications: %d\n", probeResult.UniqueApps)
 		fmt.Printf("    Report: %s\n", reportPath)
 		if !skipScreenshots {
 			fmt.Printf("    Screenshots: %s\n", screenshotDir)
@@ -215,20 +278,205 @@ func init() {
 	probeCmd.Flags().String("scan-dir", "", "Path to existing scan directory")
 	probeCmd.Flags().Bool("skip-screenshots", false, "Skip gowitness screenshots")
 	probeCmd.Flags().Duration("timeout", 30*time.Minute, "Overall timeout")
+	probeCmd.Flags().String("filter", "", `Probe only targets matching this expression, e.g. 'port in (80,443) && !is_cdn' (overrides probe.filter)`)
 	probeCmd.MarkFlagRequired("domain")
 	rootCmd.AddCommand(probeCmd)
 }
 
+// screenshotEngine resolves probe.screenshots.engine against what is
+// installed. With no engine configured gowitness is preferred, falling back
+// to the built-in chromedp engine. "" means no engine is usable.
+func screenshotEngine(gowitnessAvailable bool) string {
+	switch cfg.Probe.Screenshots.Engine {
+	case httpprobe.EngineGowitness:
+		if gowitnessAvailable {
+			return httpprobe.EngineGowitness
+		}
+	case httpprobe.EngineChromedp:
+		if tools.ChromeAvailable() {
+			return httpprobe.EngineChromedp
+		}
+	default:
+		if gowitnessAvailable {
+			return httpprobe.EngineGowitness
+		}
+		if tools.ChromeAvailable() {
+			return httpprobe.EngineChromedp
+		}
+	}
+	return ""
+}
+
+// bodyScanOptions converts the probe.body_scan settings. Custom rules were
+// already compiled once by config validation.
+func bodyScanOptions() httpprobe.BodyScanOptions {
+	rules, _ := cfg.Probe.BodyScan.BodyRules()
+	return httpprobe.BodyScanOptions{
+		Enabled: cfg.Probe.BodyScan.Enabled,
+		Rules:   rules,
+	}
+}
+
+// stageFilter resolves a stage's filter expression: the flag flagName when
+// given, even empty to lift a configured filter, otherwise configured. It
+// returns the expression's source and its parsed form, nil when empty.
+func stageFilter(cmd *cobra.Command, flagName, configured string, fields filter.Fields) (string, *filter.Expr, error) {
+	src := configured
+	if cmd.Flags().Changed(flagName) {
+		src, _ = cmd.Flags().GetString(flagName)
+	}
+	expr, err := parseFilter(src, fields)
+	if err != nil {
+		return "", nil, fmt.Errorf("--%s: %w", flagName, err)
+	}
+	return src, expr, nil
+}
+
+// parseFilter parses src against fields; empty means no filter.
+func parseFilter(src string, fields filter.Fields) (*filter.Expr, error) {
+	if src == "" {
+		return nil, nil
+	}
+	return filter.Parse(src, fields)
+}
+
+// configuredFilters parses the probe.filter and vulnscan.filter settings,
+// for runs without --filter flags. Config validation already parsed them.
+func configuredFilters() (probe, vuln *filter.Expr) {
+	probe, _ = parseFilter(cfg.Probe.Filter, filter.TargetFields)
+	vuln, _ = parseFilter(cfg.Vulnscan.Filter, filter.ProbeFields)
+	return probe, vuln
+}
+
+// protocolCheckConfig converts the probe.protocols settings. The timeout was
+// validated at config load.
+func protocolCheckConfig() netprobe.ProtocolCheckConfig {
+	var pc netprobe.ProtocolCheckConfig
+	if cfg.Probe.Protocols.Timeout != "" {
+		pc.Timeout, _ = time.ParseDuration(cfg.Probe.Protocols.Timeout)
+	}
+	return pc
+}
+
+// methodCheckConfig converts the probe.method_checks settings. The timeout
+// was validated at config load.
+func methodCheckConfig() netprobe.MethodCheckConfig {
+	var mc netprobe.MethodCheckConfig
+	if cfg.Probe.MethodChecks.Timeout != "" {
+		mc.Timeout, _ = time.ParseDuration(cfg.Probe.MethodChecks.Timeout)
+	}
+	return mc
+}
+
+// screenshotOptions converts the probe.screenshots settings for engine. The
+// delay was validated at config load.
+func screenshotOptions(engine string) httpprobe.ScreenshotOptions {
+	s := cfg.Probe.Screenshots
+	opts := httpprobe.ScreenshotOptions{
+		Engine:      engine,
+		StatusCodes: s.StatusCodes,
+		FullPage:    s.FullPage,
+		Width:       s.Width,
+		Height:      s.Height,
+		SaveDOM:     s.SaveDOM,
+		BatchSize:   s.BatchSize,
+		Parallel:    s.ParallelBatches,
+	}
+	if s.Delay != "" {
+		opts.Delay, _ = time.ParseDuration(s.Delay)
+	}
+	// Rules were compiled once already by config validation
+	rules, _ := s.Triage.TriageRules()
+	opts.Triage = httpprobe.TriageOptions{
+		Skip:    s.Triage.Skip,
+		SkipOCR: s.Triage.SkipOCR,
+		Rules:   rules,
+	}
+	return opts
+}
+
 // hostsWithOpenPorts returns all non-CDN hosts that have at least one open port,
 // plus any CDN host that we still want to probe for HTTP services.
 // The probe command is interested in all hosts — CDN or not — since HTTP
-// services may run behind CDN endpoints too.
+// services may run behind CDN endpoints too. CDN hosts are kept, even with
+// no ports scanned, so the origin check knows which names the CDN fronts, and
+// excluded hosts so the probe can tell which hostnames lead into excluded
+// networks.
 func hostsWithOpenPorts(hosts []models.Host) []models.Host {
 	var result []models.Host
 	for _, h := range hosts {
-		if len(h.Ports) > 0 {
+		if len(h.Ports) > 0 || h.IsCDN || h.Excluded {
 			result = append(result, h)
 		}
 	}
 	return result
 }
+
+// originHistory returns the addresses the target's names resolved to,
+// outside any CDN, in scans other than scanDir, newest sighting first. Scans
+// whose output can't be read are skipped.
+func originHistory(store storage.Store, domain, scanDir string) []origins.Sighting {
+	scans, err := store.ListScans(domain)
+	if err != nil {
+		fmt.Printf("[!] Warning: no DNS history for origin discovery: %v\n", err)
+		return nil
+	}
+
+	var sightings []origins.Sighting
+	seen := make(map[string]bool)
+	for _, scan := range scans {
+		if scan.ScanDir == scanDir {
+			continue
+		}
+		snap, err := diff.LoadSnapshot(scan.ScanDir)
+		if err != nil {
+			fmt.Printf("[!] Warning: skipping scan %s in DNS history: %v\n", scan.ID, err)
+			continue
+		}
+		cdnIPs := make(map[string]bool)
+		for _, host := range snap.Hosts {
+			if host.IsCDN {
+				cdnIPs[host.IP] = true
+			}
+		}
+		for _, sub := range snap.Subdomains {
+			for _, ip := range sub.IPs {
+				if cdnIPs[ip] || seen[sub.Name+"|"+ip] {
+					continue
+				}
+				seen[sub.Name+"|"+ip] = true
+				sightings = append(sightings, origins.Sighting{Name: sub.Name, IP: ip, Seen: scan.StartedAt})
+			}
+		}
+	}
+	return sightings
+}
+
+// recordOrigins stores the origin candidates on the CDN hosts whose names
+// they were found for, then rewrites ports.json and ports.md to match.
+func recordOrigins(scanDir string, portResult *portscan.PortScanResult, candidates []models.OriginCandidate) error {
+	for i := range portResult.Hosts {
+		host := &portResult.Hosts[i]
+		if !host.IsCDN {
+			continue
+		}
+		host.Origins = nil
+		for _, c := range candidates {
+			if slices.Contains(host.Subdomains, c.Hostname) {
+				host.Origins = append(host.Origins, c)
+			}
+		}
+	}
+
+	if err := report.WritePortReport(portResult, storage.ReportPath(scanDir, "ports.md")); err != nil {
+		fmt.Printf("[!] Warning: failed to write port report: %v\n", err)
+	}
+	if err := report.WritePortHTMLReport(portResult, storage.ReportPath(scanDir, "ports.html")); err != nil {
+		fmt.Printf("[!] Warning: failed to write HTML port report: %v\n", err)
+	}
+	data, err := json.MarshalIndent(portResult, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshaling port scan result: %w", err)
+	}
+	return storage.WriteFileAtomic(storage.RawPath(scanDir, "ports.json"), data, 0644)
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/hakim/reconpipe/internal/pipeline"
+	"github.com/hakim/reconpipe/internal/report"
+	"github.com/hakim/reconpipe/internal/storage"
+	"github.com/hakim/reconpipe/pkg/models"
+	"github.com/spf13/cobra"
+)
+
+var pruneFindingsCmd = &cobra.Command{
+	Use:   "prune-findings",
+	Short: "Close findings that recent scans no longer report",
+	Long: `Expire stale findings from a target's finding inventory: the vulnerabilities
+and dangling subdomains tracked for alerting between scans.
+
+A scan closes a finding itself when it looks for it and finds it gone. A finding
+whose stage stops running (a preset without vulnscan, a vulnscan always cut
+short by the deadline) is never looked for again and stays open. This command
+closes every open finding that the last --after completed scans did not report.
+
+Each closure is recorded on the finding (marked expired, with the number of
+scans it was missing from) and in the audit log. The latest scan gets
+raw/expired-findings.json and reports/expired-findings.md listing the
+auto-closed findings. A finding that turns up again is reported as new.
+
+Use --dry-run to list what would be closed without changing anything.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// Step 1: Get flags
+		domain, _ := cmd.Flags().GetString("domain")
+		after, _ := cmd.Flags().GetInt("after")
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+		if after < 1 {
+			return fmt.Errorf("--after must be at least 1")
+		}
+
+		// Step 2: Config check
+		if cfg == nil {
+			return fmt.Errorf("config not loaded. Run 'reconpipe init' first to create config")
+		}
+
+		// Step 3: Open bbolt store
+		store, err := storage.NewStore(cfg.DBPath)
+		if err != nil {
+			return fmt.Errorf("opening database: %w", err)
+		}
+		defer store.Close()
+
+		// Step 4: List scans (newest first)
+		scans, err := store.ListScans(domain)
+		if err != nil {
+			return fmt.Errorf("listing scans for %s: %w", domain, err)
+		}
+		if len(scans) == 0 {
+			fmt.Printf("No scan history found for %s\n", domain)
+			return nil
+		}
+
+		// Step 5: Expire stale findings
+		expired, err := pipeline.ExpireFindings(store, domain, scans, after, dryRun)
+		if err != nil {
+			return err
+		}
+		if len(expired) == 0 {
+			fmt.Printf("[*] No open findings missing from the last %d completed scans of %s\n", after, domain)
+			return nil
+		}
+
+		verb := "Closed"
+		if dryRun {
+			verb = "Would close"
+		}
+		fmt.Printf("[+] %s %d finding(s) not seen in %d or more completed scans:\n", verb, len(expired), after)
+		for _, st := range expired {
+			fmt.Printf("    %-8s  %-8s  %-40s  %s (last seen %s, missed %d)\n", st.Severity, st.Kind, st.Name, st.Host,
+				st.LastSeen.Local().Format("2006-01-02"), st.ScansMissed)
+		}
+		if dryRun {
+			fmt.Println("[*] Dry run: finding inventory not changed")
+			return nil
+		}
+
+		// Step 6: Record the closures
+		recordAudit(store, "findings.prune", domain, "", fmt.Sprintf("%d expired after %d scans", len(expired), after))
+
+		scanDir := scans[0].ScanDir
+		if err := writeExpiredFindings(scanDir, domain, after, expired); err != nil {
+			fmt.Printf("[!] Warning: failed to write expired findings report: %v\n", err)
+		} else {
+			fmt.Printf("[+] Expired findings report written to %s\n", storage.ReportPath(scanDir, "expired-findings.md"))
+		}
+		return nil
+	},
+}
+
+// writeExpiredFindings appends a record of this run to the scan's
+// raw/expired-findings.json and rewrites reports/expired-findings.md from
+// every record in it.
+func writeExpiredFindings(scanDir, target string, after int, expired []*models.NotificationState) error {
+	for _, dir := range []string{storage.RawDir(scanDir), storage.ReportsDir(scanDir)} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+	}
+
+	rawPath := storage.RawPath(scanDir, "expired-findings.json")
+
+	var runs []models.FindingExpiry
+	data, err := os.ReadFile(rawPath)
+	switch {
+	case err == nil:
+		if err := json.Unmarshal(data, &runs); err != nil {
+			return fmt.Errorf("parsing %s: %w", rawPath, err)
+		}
+	case !errors.Is(err, os.ErrNotExist):
+		return err
+	}
+
+	run := models.FindingExpiry{Target: target, Time: time.Now().UTC(), Operator: operator, After: after}
+	for _, st := range expired {
+		run.Findings = append(run.Findings, *st)
+	}
+	runs = append(runs, run)
+
+	data, err = json.MarshalIndent(runs, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshaling expired findings: %w", err)
+	}
+	if err := storage.WriteFileAtomic(rawPath, data, 0644); err != nil {
+		return fmt.Errorf("writing expired-findings.json: %w", err)
+	}
+
+	return report.WriteExpiredFindingsReport(runs, storage.ReportPath(scanDir, "expired-findings.md"))
+}
+
+func init() {
+	pruneFindingsCmd.Flags().StringP("domain", "d", "", "Target domain (required)")
+	pruneFindingsCmd.Flags().Int("after", 3, "Close findings missing from this many consecutive completed scans")
+	pruneFindingsCmd.Flags().Bool("dry-run", false, "List the findings that would be closed without closing them")
+	pruneFindingsCmd.MarkFlagRequired("domain")
+	rootCmd.AddCommand(pruneFindingsCmd)
+}
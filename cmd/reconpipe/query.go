@@ -0,0 +1,204 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"github.com/hakim/reconpipe/internal/diff"
+	"github.com/hakim/reconpipe/internal/notes"
+	"github.com/hakim/reconpipe/internal/pipeline"
+	"github.com/hakim/reconpipe/internal/storage"
+	"github.com/itchyny/gojq"
+	"github.com/spf13/cobra"
+)
+
+var queryCmd = &cobra.Command{
+	Use:   "query [flags] <jq filter>",
+	Short: "Query a scan's raw results with a jq filter",
+	Long: `Run a jq filter over a scan's raw results, without installing jq or
+remembering where each file lives. The filter runs against one document:
+
+  .subdomains   subdomains.json subdomains
+  .hosts        ports.json hosts, with their ports
+  .probes       http-probes.json probes
+  .vulns        vulns.json vulnerabilities
+  .notes        notes.json analyst notes attached to the scan
+  .diff         diff.json, null when the scan has no diff
+  .summary      summary.json, null when the scan has none
+  .raw          every raw JSON file whole, by file name: .raw["ports.json"]
+
+A filter may start with one of these names without the dot, so
+'probes[] | select(.status_code == 403) | .url' is '.probes[] | ...'.
+
+Examples:
+  reconpipe query --latest -d example.com 'probes[] | select(.status_code == 403) | .url'
+  reconpipe query --scan-dir scans/example.com_20260101_120000 -r '.hosts[].ip'
+  reconpipe query --latest -d example.com '[.vulns[] | .severity] | group_by(.) | map({(.[0]): length}) | add'`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// Step 1: Get flags
+		domain, _ := cmd.Flags().GetString("domain")
+		scanDir, _ := cmd.Flags().GetString("scan-dir")
+		latest, _ := cmd.Flags().GetBool("latest")
+		rawOutput, _ := cmd.Flags().GetBool("raw-output")
+		compact, _ := cmd.Flags().GetBool("compact")
+
+		// Step 2: Parse the filter before touching any file
+		query, err := gojq.Parse(expandQueryShorthand(args[0]))
+		if err != nil {
+			return fmt.Errorf("parsing filter: %w", err)
+		}
+		code, err := gojq.Compile(query)
+		if err != nil {
+			return fmt.Errorf("compiling filter: %w", err)
+		}
+
+		// Step 3: Resolve scan directory
+		if scanDir != "" && latest {
+			return fmt.Errorf("--latest and --scan-dir cannot be combined")
+		}
+		if scanDir == "" {
+			if domain == "" {
+				return fmt.Errorf("either --scan-dir or --latest -d <domain> is required")
+			}
+			if cfg == nil {
+				return fmt.Errorf("config not loaded. Run 'reconpipe init' first to create config")
+			}
+			latestDir, err := storage.FindLatestScanDir(cfg.ScanDir, domain)
+			if err != nil {
+				return fmt.Errorf("finding latest scan directory: %w", err)
+			}
+			scanDir = latestDir
+		}
+
+		// Step 4: Load the raw results
+		doc, err := loadQueryDocument(scanDir)
+		if err != nil {
+			return err
+		}
+
+		// Step 5: Run the filter and print each result
+		iter := code.RunWithContext(context.Background(), doc)
+		for {
+			v, ok := iter.Next()
+			if !ok {
+				return nil
+			}
+			if err, ok := v.(error); ok {
+				var halt *gojq.HaltError
+				if errors.As(err, &halt) && halt.Value() == nil {
+					return nil
+				}
+				return fmt.Errorf("running filter: %w", err)
+			}
+			if s, ok := v.(string); ok && rawOutput {
+				fmt.Println(s)
+				continue
+			}
+			var out []byte
+			if compact {
+				out, err = json.Marshal(v)
+			} else {
+				out, err = json.MarshalIndent(v, "", "  ")
+			}
+			if err != nil {
+				return fmt.Errorf("encoding result: %w", err)
+			}
+			fmt.Println(string(out))
+		}
+	},
+}
+
+func init() {
+	queryCmd.Flags().StringP("domain", "d", "", "Target domain (uses the latest scan directory)")
+	queryCmd.Flags().Bool("latest", false, "Query the latest scan of --domain (the default without --scan-dir)")
+	queryCmd.Flags().String("scan-dir", "", "Scan directory to query")
+	queryCmd.Flags().BoolP("raw-output", "r", false, "Print string results without JSON quotes, like jq -r")
+	queryCmd.Flags().BoolP("compact", "c", false, "Print each result on one line, like jq -c")
+	rootCmd.AddCommand(queryCmd)
+}
+
+// queryLists are the document's record lists: the key, the raw file it
+// comes from, and the file's field holding the list.
+var queryLists = []struct {
+	key, file, field string
+}{
+	{"subdomains", "subdomains.json", "subdomains"},
+	{"hosts", "ports.json", "hosts"},
+	{"probes", "http-probes.json", "probes"},
+	{"vulns", "vulns.json", "vulnerabilities"},
+	{"notes", notes.File, "notes"},
+}
+
+// queryShorthand matches a filter that starts with a document key without
+// its leading dot.
+var queryShorthand = regexp.MustCompile(`^\s*(subdomains|hosts|probes|vulns|notes|diff|summary|raw)\b`)
+
+// expandQueryShorthand turns "probes[] | ..." into ".probes[] | ...".
+func expandQueryShorthand(q string) string {
+	if loc := queryShorthand.FindStringSubmatchIndex(q); loc != nil {
+		return q[:loc[2]] + "." + q[loc[2]:]
+	}
+	return q
+}
+
+// loadQueryDocument reads every raw JSON file of scanDir into the document
+// query filters run against. The record lists are empty, never null, when
+// their file is missing, so ".probes[]" yields nothing instead of failing.
+func loadQueryDocument(scanDir string) (map[string]any, error) {
+	rawDir := storage.RawDir(scanDir)
+	paths, err := filepath.Glob(filepath.Join(rawDir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no raw results in %s", rawDir)
+	}
+
+	raw := make(map[string]any, len(paths))
+	for _, path := range paths {
+		v, err := readQueryFile(path)
+		if err != nil {
+			return nil, err
+		}
+		raw[filepath.Base(path)] = v
+	}
+
+	doc := map[string]any{"raw": raw}
+	for _, list := range queryLists {
+		records := []any{}
+		if file, ok := raw[list.file].(map[string]any); ok {
+			if v, ok := file[list.field].([]any); ok {
+				records = v
+			}
+		}
+		doc[list.key] = records
+	}
+	doc["diff"] = raw["diff.json"]
+
+	summary, err := readQueryFile(filepath.Join(scanDir, pipeline.SummaryFile))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	doc["summary"] = summary
+	return doc, nil
+}
+
+// readQueryFile decodes the JSON file at path into the plain values gojq
+// works on.
+func readQueryFile(path string) (any, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var v any
+	if err := json.Unmarshal(data, &v); err != nil {
+		return nil, &diff.CorruptFileError{Path: path, Err: err}
+	}
+	return v, nil
+}
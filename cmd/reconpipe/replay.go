@@ -0,0 +1,105 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+
+	"github.com/hakim/reconpipe/internal/storage"
+	"github.com/spf13/cobra"
+)
+
+// replayOverrideFlags are the replay flags passed through to scan. None of
+// them change what is scanned or how.
+var replayOverrideFlags = []string{"scan-dir", "tag", "skip-pdf", "pdf-engine", "notify", "notify-webhook"}
+
+var replayCmd = &cobra.Command{
+	Use:   "replay <scan-id>",
+	Short: "Rerun a past scan with its exact recorded configuration",
+	Long: `Rerun a historical scan with the settings it was recorded with.
+
+The target, preset, stages, severity, timeout, scope, discovery options and the
+config snapshot (rate limits, tool arguments, probe settings) are loaded from
+the scan's run configuration, so the new run is made under the same conditions
+as the original — for example to verify remediation.
+
+Results go to a new scan directory named by scan_layout, or to --scan-dir.
+The original scan is never written to, and the diff stage compares the new run
+against it rather than against the most recent scan.
+
+The scan ID may be the full ID or the shortened ID shown by 'reconpipe history'.
+
+Examples:
+  reconpipe replay 3f2a9c1e
+  reconpipe replay 3f2a9c1e --tag retest
+  reconpipe replay 3f2a9c1e --scan-dir scans/acme-retest
+  reconpipe replay 3f2a9c1e --show`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// Step 1: Get flags
+		scanID := args[0]
+		show, _ := cmd.Flags().GetBool("show")
+		scanDir, _ := cmd.Flags().GetString("scan-dir")
+
+		// Step 2: Config check
+		if cfg == nil {
+			return fmt.Errorf("config not loaded. Run 'reconpipe init' first to create config")
+		}
+
+		// Step 3: Look up the recorded run
+		prior, rc, err := loadRunConfig(scanID)
+		if err != nil {
+			return err
+		}
+
+		if show {
+			data, err := json.MarshalIndent(rc, "", "  ")
+			if err != nil {
+				return fmt.Errorf("marshaling run config: %w", err)
+			}
+			fmt.Printf("[*] Scan %s of %s (started %s)\n",
+				prior.ID, prior.Target, prior.StartedAt.Format("2006-01-02 15:04"))
+			fmt.Println(string(data))
+			return nil
+		}
+
+		// Step 4: Prepare an explicit target directory
+		if scanDir != "" {
+			if filepath.Clean(scanDir) == filepath.Clean(prior.ScanDir) {
+				return fmt.Errorf("--scan-dir is the original scan's directory; replay results must go elsewhere")
+			}
+			for _, dir := range []string{storage.RawDir(scanDir), storage.ReportsDir(scanDir)} {
+				if err := storage.EnsureDir(dir); err != nil {
+					return fmt.Errorf("creating scan directory: %w", err)
+				}
+			}
+		}
+
+		// Step 5: Hand over to scan with the recorded settings
+		if err := scanCmd.Flags().Set("replay", prior.ID); err != nil {
+			return err
+		}
+		for _, name := range replayOverrideFlags {
+			if !cmd.Flags().Changed(name) {
+				continue
+			}
+			if err := scanCmd.Flags().Set(name, cmd.Flags().Lookup(name).Value.String()); err != nil {
+				return err
+			}
+		}
+
+		return scanCmd.RunE(scanCmd, nil)
+	},
+}
+
+func init() {
+	replayCmd.Flags().String("scan-dir", "", "Write the replay into this directory instead of a new one named by scan_layout")
+	replayCmd.Flags().String("tag", "", "Label for the replay run (default: the original run's tag)")
+	replayCmd.Flags().Bool("skip-pdf", false, "Skip PDF report generation")
+	replayCmd.Flags().String("pdf-engine", pdfEngineGo, "PDF generator: go (built in) or python (the Nuc-pdf Python tool)")
+	replayCmd.Flags().String("notify", "", "Send a completion summary and finding alerts to an http(s) webhook, slack://hooks.slack.com/services/... or discord://discord.com/api/webhooks/...")
+	replayCmd.Flags().String("notify-webhook", "", "HTTP webhook URL to POST a completion summary and finding alerts to (same as --notify)")
+	replayCmd.Flags().Bool("show", false, "Print the recorded run configuration and exit")
+
+	rootCmd.AddCommand(replayCmd)
+}
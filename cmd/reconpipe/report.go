@@ -0,0 +1,169 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/hakim/reconpipe/internal/config"
+	"github.com/hakim/reconpipe/internal/report"
+	"github.com/hakim/reconpipe/internal/storage"
+	"github.com/spf13/cobra"
+)
+
+var reportCmd = &cobra.Command{
+	Use:   "report",
+	Short: "Regenerate markdown reports from a scan's raw output",
+	Long: `Rebuild every markdown report in {scan_dir}/reports/ from the structured JSON
+in {scan_dir}/raw/. Useful after upgrading reconpipe or editing raw output by hand.
+
+Reports whose raw input is missing are skipped, as are reports whose raw input
+has not changed since they were written (per raw/checksums.json) unless --force
+is given. The PDF report is not rebuilt.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// Step 1: Get flags
+		domain, _ := cmd.Flags().GetString("domain")
+		scanDir, _ := cmd.Flags().GetString("scan-dir")
+		force, _ := cmd.Flags().GetBool("force")
+
+		// Step 2: Resolve scan directory
+		if scanDir == "" {
+			if domain == "" {
+				return fmt.Errorf("either --domain or --scan-dir is required")
+			}
+			if cfg == nil {
+				return fmt.Errorf("config not loaded. Run 'reconpipe init' first to create config")
+			}
+			latestDir, err := storage.FindLatestScanDir(cfg.ScanDir, domain)
+			if err != nil {
+				return fmt.Errorf("finding latest scan directory: %w", err)
+			}
+			scanDir = latestDir
+		}
+
+		fmt.Printf("[*] Regenerating reports in %s\n", scanDir)
+		warnStaleData(scanDir)
+
+		// Step 3: Regenerate, skipping reports whose raw input is unchanged
+		var paths, unchanged []string
+		var err error
+		if force {
+			paths, err = report.RegenerateReports(scanDir)
+		} else {
+			paths, unchanged, err = report.RegenerateChanged(scanDir, rootCmd.Version)
+		}
+		for _, p := range paths {
+			fmt.Printf("[+] %s\n", p)
+		}
+		if err != nil {
+			return fmt.Errorf("regenerating reports: %w", err)
+		}
+
+		// Step 4: Re-sign diff.md, whose date stamp changed
+		if slices.Contains(paths, storage.ReportPath(scanDir, "diff.md")) {
+			resignDiffReport(cmd.Context(), scanDir)
+		}
+
+		fmt.Printf("[+] %d reports regenerated", len(paths))
+		if len(unchanged) > 0 {
+			fmt.Printf(", %d unchanged (raw input as last written; --force rebuilds them)", len(unchanged))
+		}
+		fmt.Println()
+		return nil
+	},
+}
+
+// warnStaleData warns about each stage of scanDir whose data is older than
+// the configured stale_after, so regenerated reports are not taken as current.
+func warnStaleData(scanDir string) {
+	maxAge := config.DefaultStaleAfter
+	if cfg != nil {
+		maxAge = cfg.StaleAfterDuration()
+	}
+	if maxAge == 0 {
+		return
+	}
+	stale, err := report.StaleData(scanDir, maxAge)
+	if err != nil {
+		fmt.Printf("[!] Warning: checking data age: %v\n", err)
+		return
+	}
+	for _, s := range stale {
+		when := "collected"
+		if s.Estimated {
+			when = "last written"
+		}
+		fmt.Printf("[!] Warning: %s was %s %s ago (%s); its reports describe the target as it was then\n",
+			s.File, when, formatAge(time.Since(s.CollectedAt)), s.CollectedAt.UTC().Format("2006-01-02 15:04 UTC"))
+	}
+}
+
+// formatAge renders a data age in days, or hours under two days.
+func formatAge(d time.Duration) string {
+	if d < 48*time.Hour {
+		return fmt.Sprintf("%d hours", int(d.Hours()))
+	}
+	return fmt.Sprintf("%d days", int(d.Hours()/24))
+}
+
+var reportGoldenCmd = &cobra.Command{
+	Use:   "golden",
+	Short: "Diff regenerated reports against stored golden files",
+	Long: `Regenerate all reports from a fixture scan directory (with a fixed clock) and
+compare them byte-for-byte against stored golden files. Any difference is printed
+as a line diff and the command exits non-zero, so report-format changes are always
+deliberate.
+
+After an intentional format change, rerun with --update to rewrite the golden files
+and commit them alongside the code change.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		fixtureDir, _ := cmd.Flags().GetString("fixture")
+		goldenDir, _ := cmd.Flags().GetString("golden")
+		update, _ := cmd.Flags().GetBool("update")
+
+		if update && readOnly {
+			return fmt.Errorf("--update is disabled in read-only mode")
+		}
+
+		mismatches, err := report.CompareGolden(fixtureDir, goldenDir, update)
+		if err != nil {
+			return err
+		}
+
+		if update {
+			fmt.Printf("[+] Golden files in %s updated from %s\n", goldenDir, fixtureDir)
+			return nil
+		}
+
+		if len(mismatches) == 0 {
+			fmt.Printf("[+] All reports match golden files in %s\n", goldenDir)
+			return nil
+		}
+
+		for _, m := range mismatches {
+			switch {
+			case m.Missing:
+				fmt.Printf("[!] %s: no golden file (run with --update to create it)\n", m.Name)
+			case m.Stale:
+				fmt.Printf("[!] %s: golden file has no matching report\n", m.Name)
+			default:
+				fmt.Printf("[!] %s differs from golden:\n%s\n", m.Name, m.Diff)
+			}
+		}
+
+		return fmt.Errorf("%d report(s) differ from golden files", len(mismatches))
+	},
+}
+
+func init() {
+	reportCmd.Flags().StringP("domain", "d", "", "Target domain (uses the latest scan directory)")
+	reportCmd.Flags().String("scan-dir", "", "Scan directory to regenerate (overrides --domain)")
+	reportCmd.Flags().Bool("force", false, "Regenerate every report, even those whose raw input is unchanged")
+
+	reportGoldenCmd.Flags().String("fixture", "testdata/golden/scan", "Fixture scan directory containing raw/")
+	reportGoldenCmd.Flags().String("golden", "testdata/golden/expected", "Directory of golden report files")
+	reportGoldenCmd.Flags().Bool("update", false, "Rewrite golden files from the current output")
+
+	reportCmd.AddCommand(reportGoldenCmd)
+	rootCmd.AddCommand(reportCmd)
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/hakim/reconpipe/internal/pipeline"
+	"github.com/hakim/reconpipe/internal/riskpolicy"
+	"github.com/hakim/reconpipe/internal/storage"
+)
+
+// maxListedFailures caps the fail_on matches printed per scan.
+const maxListedFailures = 10
+
+// policyFailure is returned by scan, wizard and vulnscan when findings of
+// the run match risk_policy.fail_on. main exits with riskpolicy.ExitCode
+// for it instead of 1.
+type policyFailure struct {
+	matched int
+}
+
+func (e *policyFailure) Error() string {
+	return fmt.Sprintf("risk policy: %d finding(s) match risk_policy.fail_on", e.matched)
+}
+
+// policyError returns the error for matched fail_on matches, or nil for
+// none. Usage is not printed for it: the command was used correctly.
+func policyError(matched int) error {
+	if matched == 0 {
+		return nil
+	}
+	rootCmd.SilenceUsage = true
+	return &policyFailure{matched: matched}
+}
+
+// riskPolicy returns the configured risk policy.
+func riskPolicy() *riskpolicy.Policy {
+	return cfg.RiskPolicy.Policy(cfg.Vulnscan.Noise.Alert)
+}
+
+// checkFailPolicy lists the findings of the scan in scanDir that match
+// risk_policy.fail_on and returns how many there are. Findings that cannot
+// be loaded only warn: the run itself finished.
+func checkFailPolicy(scanDir string) int {
+	policy := riskPolicy()
+	if !policy.Configured(riskpolicy.Fail) {
+		return 0
+	}
+	findings, err := riskpolicy.ScanFindings(scanDir)
+	if err != nil {
+		fmt.Printf("[!] Warning: risk policy: %v\n", err)
+		return 0
+	}
+	matched := policy.Filter(riskpolicy.Fail, findings)
+	if len(matched) == 0 {
+		fmt.Println("[+] Risk policy: no finding matches fail_on")
+		return 0
+	}
+
+	fmt.Printf("[!] Risk policy: %d finding(s) match fail_on\n", len(matched))
+	for i, f := range matched {
+		if i == maxListedFailures {
+			fmt.Printf("    ... and %d more\n", len(matched)-i)
+			break
+		}
+		age := "known"
+		if f.New {
+			age = "new"
+		}
+		fmt.Printf("    %-8s %-5s %s on %s\n", f.Severity, age, f.Name, f.Host)
+	}
+	return len(matched)
+}
+
+// sendNotifications posts the completion notification for result, unless
+// risk_policy.notify_on rules it out, and then the finding alerts.
+func sendNotifications(store storage.Store, notifyURL string, result *pipeline.PipelineResult) {
+	notifyCfg := pipeline.NotifyConfig{WebhookURL: notifyURL, Policy: riskPolicy()}
+	due, err := notifyCfg.CompletionDue(result)
+	if err != nil {
+		fmt.Printf("[!] Warning: risk policy: %v\n", err)
+	}
+	switch {
+	case !due:
+		fmt.Println("[*] No finding matches risk_policy.notify_on; completion notification not sent")
+	default:
+		if notifyErr := notifyCfg.SendCompletion(result); notifyErr != nil {
+			fmt.Printf("[!] Warning: webhook notification failed: %v\n", notifyErr)
+		} else {
+			fmt.Printf("[+] Completion notification sent to %s\n", notifyURL)
+		}
+	}
+	sendFindingAlerts(store, &notifyCfg, result)
+}
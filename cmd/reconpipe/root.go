@@ -2,17 +2,34 @@ package main
 
 import (
 	"fmt"
+	"maps"
+	"runtime/debug"
+	"slices"
+	"strings"
 
 	"github.com/hakim/reconpipe/internal/config"
+	"github.com/hakim/reconpipe/internal/report"
+	"github.com/hakim/reconpipe/internal/storage"
+	"github.com/hakim/reconpipe/internal/tools"
 	"github.com/spf13/cobra"
 )
 
 var (
-	cfgFile string
-	verbose bool
-	cfg     *config.Config
+	cfgFile        string
+	verbose        bool
+	fakeToolsMode  bool
+	fakeFixtureDir string
+	operatorFlag   string
+	operator       string // resolved by resolveOperator once config is loaded
+	readOnlyFlag   bool
+	readOnly       bool // --read-only or read_only in config
+	cfg            *config.Config
 )
 
+// version is the release this binary was built from, set by release builds
+// with -ldflags "-X main.version=1.2.0".
+var version = "0.1.0-dev"
+
 var rootCmd = &cobra.Command{
 	Use:   "reconpipe",
 	Short: "Subdomain-to-vulnerability reconnaissance pipeline",
@@ -24,16 +41,26 @@ It orchestrates external tools like subfinder, masscan, nmap, httpx, and nuclei
 into a streamlined pipeline that generates structured reports and tracks changes
 over time.`,
 	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
-		// Skip config loading for commands that don't need it
+		// Fake-tools mode applies to every command, including check
+		if fakeToolsMode {
+			tools.EnableFakeTools(fakeFixtureDir)
+			fmt.Println("[!] Fake-tools mode: external tools are replaced by fixture output")
+		}
+
+		// Skip config loading for commands that don't need it. read_only
+		// is still read from the config file, so that init --force cannot
+		// rewrite it and leave read-only mode.
 		skipConfig := map[string]bool{
-			"check":   true,
-			"init":    true,
-			"help":    true,
-			"version": true,
+			"check":            true,
+			"init":             true,
+			"help":             true,
+			"version":          true,
+			"golden":           true,
+			"release-manifest": true,
 		}
 
 		if skipConfig[cmd.Name()] {
-			return nil
+			return enforceReadOnly(cmd, readOnlyFlag || config.FileReadOnly(cfgFile))
 		}
 
 		// Load config if file exists
@@ -43,19 +70,92 @@ over time.`,
 			if err != nil {
 				return fmt.Errorf("failed to load config: %w", err)
 			}
+			if err := applyConfig(cfg); err != nil {
+				return err
+			}
 		}
+		operator = resolveOperator(operatorFlag, cfg)
 
-		return nil
+		return enforceReadOnly(cmd, readOnlyFlag || (cfg != nil && cfg.ReadOnly))
 	},
 }
 
+// readOnlyCommands are the top-level commands allowed in read-only mode:
+// those that only read scan history and results, or render them. Flags of
+// these commands that write elsewhere or publish are refused by the
+// commands themselves, e.g. validate-scan only checks: --repair is refused.
+var readOnlyCommands = map[string]bool{
+	"history":       true,
+	"show":          true,
+	"search":        true,
+	"diff":          true,
+	"report":        true,
+	"export":        true,
+	"validate-scan": true,
+	"help":          true,
+	"version":       true,
+	"completion":    true,
+}
+
+// readOnlyAllowed lists readOnlyCommands for help and error messages.
+func readOnlyAllowed() string {
+	return strings.Join(slices.Sorted(maps.Keys(readOnlyCommands)), ", ")
+}
+
+// enforceReadOnly refuses commands that launch scans or modify the database
+// when ro is set, opens every store read-only for the rest, and drops the
+// report sinks so regenerated reports are not published.
+func enforceReadOnly(cmd *cobra.Command, ro bool) error {
+	readOnly = ro
+	storage.SetReadOnly(ro)
+	if !ro {
+		return nil
+	}
+	report.SetSinks(nil)
+
+	top := cmd
+	for top.HasParent() && top.Parent().HasParent() {
+		top = top.Parent()
+	}
+	if !readOnlyCommands[top.Name()] {
+		return fmt.Errorf("'%s' is disabled in read-only mode (allowed: %s)", top.Name(), readOnlyAllowed())
+	}
+	return nil
+}
+
+// applyConfig installs the process-wide settings derived from c: report
+// sinks, the database driver, the scan directory layout and the DNS
+// resolver.
+func applyConfig(c *config.Config) error {
+	sinks, err := report.SinksFromConfig(c.ReportSinks)
+	if err != nil {
+		return fmt.Errorf("configuring report sinks: %w", err)
+	}
+	report.SetSinks(sinks)
+	if err := storage.SetDriver(c.DBDriver); err != nil {
+		return fmt.Errorf("configuring database: %w", err)
+	}
+	storage.SetLayout(c.ScanLayout.Layout())
+	if err := tools.SetDNSResolver(c.Discovery.DNSResolver.Mode, c.Discovery.DNSResolver.Nameservers); err != nil {
+		return fmt.Errorf("configuring DNS resolver: %w", err)
+	}
+	if c.Memory.BudgetMB > 0 {
+		debug.SetMemoryLimit(int64(c.Memory.BudgetMB) << 20)
+	}
+	return nil
+}
+
 func init() {
 	// Global flags
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "reconpipe.yaml", "config file path")
 	rootCmd.PersistentFlags().BoolVar(&verbose, "verbose", false, "verbose output")
+	rootCmd.PersistentFlags().BoolVar(&fakeToolsMode, "fake-tools", false, "replace external tools with canned fixture output (CI and demos)")
+	rootCmd.PersistentFlags().StringVar(&operatorFlag, "operator", "", "name recorded on scans and in the audit log (default: $RECONPIPE_OPERATOR, config operator, or login name)")
+	rootCmd.PersistentFlags().BoolVar(&readOnlyFlag, "read-only", false, "only allow commands that read results ("+readOnlyAllowed()+"); the database is opened read-only")
+	rootCmd.PersistentFlags().StringVar(&fakeFixtureDir, "fixtures-dir", "", "directory of {tool}.fixture files overriding the built-in fixtures (with --fake-tools)")
 
 	// Version flag
-	rootCmd.Version = "0.1.0-dev"
+	rootCmd.Version = version
 }
 
 // Execute runs the root command
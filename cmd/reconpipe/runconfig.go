@@ -0,0 +1,84 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/hakim/reconpipe/internal/config"
+	"github.com/hakim/reconpipe/internal/storage"
+	"github.com/hakim/reconpipe/pkg/models"
+)
+
+// snapshotConfig serialises the loaded configuration for a run config.
+// Credentials are left out (see config.Redacted): the snapshot is stored in
+// the scan record and raw/run-config.json, which export bundle ships and
+// read-only users can read. ${ENV} references are kept.
+func snapshotConfig(c *config.Config) json.RawMessage {
+	data, err := json.Marshal(c.Redacted())
+	if err != nil {
+		fmt.Printf("[!] Warning: could not snapshot config: %v\n", err)
+		return nil
+	}
+	return data
+}
+
+// loadRunConfig looks up a past scan by ID (or unique ID prefix) and returns
+// it together with the settings it was run with. The scan record is checked
+// first, then raw/run-config.json in its scan directory.
+func loadRunConfig(scanID string) (*models.ScanMeta, *models.RunConfig, error) {
+	store, err := storage.NewStore(cfg.DBPath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("opening database: %w", err)
+	}
+	defer store.Close()
+
+	meta, err := store.FindScan(scanID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if meta == nil {
+		return nil, nil, fmt.Errorf("no scan found with ID %q", scanID)
+	}
+
+	if meta.RunConfig != nil {
+		return meta, meta.RunConfig, nil
+	}
+	rc, err := storage.ReadRunConfig(meta.ScanDir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil, fmt.Errorf("scan %s has no recorded run configuration (it predates run-config.json)", meta.ID)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return meta, rc, nil
+}
+
+// restoreConfig makes the config snapshot in rc the active configuration.
+// scan_dir and db_path keep their current values: they describe where this
+// installation stores results, not how the scan behaved. Credentials are
+// taken from the current configuration, as the snapshot does not hold them.
+func restoreConfig(rc *models.RunConfig) error {
+	if len(rc.Config) == 0 {
+		return fmt.Errorf("run configuration has no config snapshot")
+	}
+
+	restored := &config.Config{}
+	if err := json.Unmarshal(rc.Config, restored); err != nil {
+		return fmt.Errorf("parsing config snapshot: %w", err)
+	}
+	restored.ScanDir = cfg.ScanDir
+	restored.DBPath = cfg.DBPath
+	restored.DBDriver = cfg.DBDriver
+	restored.RestoreCredentials(cfg)
+	if err := restored.Validate(); err != nil {
+		return fmt.Errorf("config snapshot: %w", err)
+	}
+	if err := applyConfig(restored); err != nil {
+		return err
+	}
+
+	cfg = restored
+	return nil
+}
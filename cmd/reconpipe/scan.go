@@ -2,13 +2,25 @@ package main
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
 	"strings"
 	"time"
 
+	"github.com/hakim/reconpipe/internal/config"
+	"github.com/hakim/reconpipe/internal/discovery"
+	"github.com/hakim/reconpipe/internal/filter"
 	"github.com/hakim/reconpipe/internal/pipeline"
+	"github.com/hakim/reconpipe/internal/portscan"
+	"github.com/hakim/reconpipe/internal/report"
+	"github.com/hakim/reconpipe/internal/stats"
 	"github.com/hakim/reconpipe/internal/storage"
 	"github.com/hakim/reconpipe/internal/tools"
+	"github.com/hakim/reconpipe/internal/urllist"
+	"github.com/hakim/reconpipe/pkg/models"
 	"github.com/spf13/cobra"
 )
 
@@ -26,17 +38,25 @@ Results are saved to:
   {scan_dir}/{target}_{timestamp}/reports/      (markdown and optional PDF)
 
 Scan metadata is persisted to the configured database so history and diff work
-across runs.
+across runs.  The resolved settings and a snapshot of the config are recorded in
+raw/run-config.json; --replay reruns a past scan with exactly those settings.
 
 Examples:
   reconpipe scan -d example.com
   reconpipe scan -d example.com --preset bug-bounty
   reconpipe scan -d example.com --stages discover,portscan
   reconpipe scan -d example.com --resume
-  reconpipe scan -d example.com --scope-domains "example.com,*.example.com"`,
+  reconpipe scan -d example.com --scope-domains "example.com,*.example.com"
+  reconpipe scan -d example.com --known-subdomains client-assets.csv
+  reconpipe scan -d example.com,example.net,example-corp.io
+  reconpipe scan -d example.com -d example.net
+  reconpipe scan --domains-file org-domains.txt
+  reconpipe scan --replay 3f2a9c1e
+  reconpipe scan -d example.com --ignore-policy`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		// ── 1. Read all flags ──────────────────────────────────────────────────
-		domain, _ := cmd.Flags().GetString("domain")
+		domains, _ := cmd.Flags().GetStringSlice("domain")
+		domainsFile, _ := cmd.Flags().GetString("domains-file")
 		scanDir, _ := cmd.Flags().GetString("scan-dir")
 		stagesFlag, _ := cmd.Flags().GetString("stages")
 		skipFlag, _ := cmd.Flags().GetString("skip")
@@ -44,20 +64,152 @@ Examples:
 		presetName, _ := cmd.Flags().GetString("preset")
 		severity, _ := cmd.Flags().GetString("severity")
 		timeout, _ := cmd.Flags().GetDuration("timeout")
-		webhookURL, _ := cmd.Flags().GetString("notify-webhook")
+		notifyURL, _ := cmd.Flags().GetString("notify")
+		if notifyURL == "" {
+			notifyURL, _ = cmd.Flags().GetString("notify-webhook")
+		}
 		scopeDomainsFlag, _ := cmd.Flags().GetString("scope-domains")
 		skipPDF, _ := cmd.Flags().GetBool("skip-pdf")
+		pdfEngine, _ := cmd.Flags().GetString("pdf-engine")
+		knownFile, _ := cmd.Flags().GetString("known-subdomains")
+		targetsFile, _ := cmd.Flags().GetString("targets-file")
+		permutations, _ := cmd.Flags().GetBool("permutations")
+		bruteforce, _ := cmd.Flags().GetBool("bruteforce")
+		zoneTransfer, _ := cmd.Flags().GetBool("axfr")
+		dnsConsensus, _ := cmd.Flags().GetBool("dns-consensus")
+		ports, _ := cmd.Flags().GetString("ports")
+		topPorts, _ := cmd.Flags().GetInt("top-ports")
+		udp, _ := cmd.Flags().GetBool("udp")
+		tag, _ := cmd.Flags().GetString("tag")
+		replayID, _ := cmd.Flags().GetString("replay")
+		ignorePolicy, _ := cmd.Flags().GetBool("ignore-policy")
+		keepToolOutput, _ := cmd.Flags().GetBool("keep-tool-output")
 
 		// ── 2. Config check ────────────────────────────────────────────────────
 		if cfg == nil {
 			return fmt.Errorf("config not loaded. Run 'reconpipe init' first to create config")
 		}
+		if domainsFile != "" {
+			listed, err := loadDomainsFile(domainsFile)
+			if err != nil {
+				return err
+			}
+			domains = append(domains, listed...)
+			fmt.Printf("[*] Loaded %d domains from %s\n", len(listed), domainsFile)
+		}
+		domains = uniqueDomains(domains)
+		if len(domains) == 0 && replayID == "" {
+			return fmt.Errorf("required flag \"domain\" not set (or pass --domains-file)")
+		}
+		if notifyURL != "" {
+			if _, _, err := pipeline.ParseNotifyURL(notifyURL); err != nil {
+				return fmt.Errorf("--notify: %w", err)
+			}
+		}
+		if replayID != "" && resume {
+			return fmt.Errorf("--replay starts a new scan and cannot be combined with --resume")
+		}
+		if len(domains) > 1 && (replayID != "" || resume || scanDir != "" || targetsFile != "") {
+			return fmt.Errorf("several domains cannot be combined with --replay, --resume, --scan-dir or --targets-file")
+		}
 
-		// ── 3. Apply preset (flags override preset values) ────────────────────
+		// ── 3. Apply replayed run or preset (flags override either) ───────────
+		probeFilterSrc, probeFilter, err := stageFilter(cmd, "probe-filter", cfg.Probe.Filter, filter.TargetFields)
+		if err != nil {
+			return err
+		}
+		vulnFilterSrc, vulnFilter, err := stageFilter(cmd, "vulnscan-filter", cfg.Vulnscan.Filter, filter.ProbeFields)
+		if err != nil {
+			return err
+		}
 		var stageList []string
 		var skipList []string
+		var knownSubdomains []string
+		var targetURLs []string
+		scopeDomains := splitCSV(scopeDomainsFlag)
+		replayOf, replayDir := "", ""
 
-		if presetName != "" {
+		if replayID != "" {
+			prior, rc, err := loadRunConfig(replayID)
+			if err != nil {
+				return fmt.Errorf("replay: %w", err)
+			}
+			if err := restoreConfig(rc); err != nil {
+				return fmt.Errorf("replay: %w", err)
+			}
+			replayOf, replayDir = prior.ID, prior.ScanDir
+			fmt.Printf("[*] Replaying scan %s of %s (started %s)\n",
+				prior.ID, prior.Target, prior.StartedAt.Format("2006-01-02 15:04"))
+			if rc.FakeTools && !fakeToolsMode {
+				fmt.Println("[!] Warning: the replayed scan ran with --fake-tools; this run uses the real tools")
+			}
+
+			// The recorded values are already resolved, so the preset is
+			// only re-applied below if --preset is given explicitly.
+			flags := cmd.Flags()
+			if len(domains) == 0 {
+				domains = []string{prior.Target}
+			}
+			if !flags.Changed("preset") {
+				presetName = rc.Preset
+			}
+			if !flags.Changed("tag") {
+				tag = rc.Tag
+			}
+			if !flags.Changed("severity") {
+				severity = rc.Severity
+			}
+			if !flags.Changed("timeout") && rc.Timeout != "" {
+				if timeout, err = time.ParseDuration(rc.Timeout); err != nil {
+					return fmt.Errorf("replay: recorded timeout: %w", err)
+				}
+			}
+			if !flags.Changed("scope-domains") {
+				scopeDomains = rc.ScopeDomains
+			}
+			if !flags.Changed("skip-pdf") {
+				skipPDF = rc.SkipPDF
+			}
+			if !flags.Changed("pdf-engine") && rc.PDFEngine != "" {
+				pdfEngine = rc.PDFEngine
+			}
+			if !flags.Changed("permutations") {
+				permutations = rc.Permutations
+			}
+			if !flags.Changed("bruteforce") {
+				bruteforce = rc.Bruteforce
+			}
+			if !flags.Changed("axfr") {
+				zoneTransfer = rc.ZoneTransfer
+			}
+			if !flags.Changed("dns-consensus") {
+				dnsConsensus = rc.DNSConsensus
+			}
+			if !flags.Changed("ports") && !flags.Changed("top-ports") {
+				ports, topPorts = rc.Ports, rc.TopPorts
+			}
+			if !flags.Changed("udp") {
+				udp = rc.UDP
+			}
+			if !flags.Changed("probe-filter") {
+				probeFilterSrc = rc.ProbeFilter
+				if probeFilter, err = parseFilter(probeFilterSrc, filter.TargetFields); err != nil {
+					return fmt.Errorf("replay: recorded probe filter: %w", err)
+				}
+			}
+			if !flags.Changed("vulnscan-filter") {
+				vulnFilterSrc = rc.VulnscanFilter
+				if vulnFilter, err = parseFilter(vulnFilterSrc, filter.ProbeFields); err != nil {
+					return fmt.Errorf("replay: recorded vulnscan filter: %w", err)
+				}
+			}
+			stageList = rc.Stages
+			skipList = rc.Skip
+			knownSubdomains = rc.KnownSubdomains
+			targetURLs = rc.TargetURLs
+		}
+
+		if presetName != "" && cmd.Flags().Changed("preset") {
 			preset, err := pipeline.GetPreset(presetName)
 			if err != nil {
 				return err
@@ -73,7 +225,29 @@ Examples:
 			if !cmd.Flags().Changed("skip-pdf") {
 				skipPDF = preset.SkipPDF
 			}
+			if !cmd.Flags().Changed("permutations") && preset.Permutations {
+				permutations = true
+			}
+		}
+		if !cmd.Flags().Changed("permutations") && cfg.Discovery.Permutations.Enabled {
+			permutations = true
+		}
+		if !cmd.Flags().Changed("bruteforce") && cfg.Discovery.Bruteforce.Enabled {
+			bruteforce = true
+		}
+		if !cmd.Flags().Changed("axfr") && cfg.Discovery.ZoneTransfer {
+			zoneTransfer = true
 		}
+		if !cmd.Flags().Changed("dns-consensus") && cfg.Discovery.DNSConsensus.Enabled {
+			dnsConsensus = true
+		}
+		if !cmd.Flags().Changed("udp") && cfg.PortScan.UDP.Enabled {
+			udp = true
+		}
+		if err := checkPortSelection(ports, topPorts); err != nil {
+			return err
+		}
+		ports = strings.Join(strings.Fields(ports), "")
 
 		// Parse --stages and --skip flags, overriding any preset values.
 		if stagesFlag != "" {
@@ -83,20 +257,86 @@ Examples:
 			skipList = splitCSV(skipFlag)
 		}
 
+		// Client-supplied URL list, scanned in place of discovery and portscan
+		if targetsFile != "" {
+			urls, err := urllist.Load(targetsFile)
+			if err != nil {
+				return err
+			}
+			targetURLs = urls
+			fmt.Printf("[*] Loaded %d URLs from %s\n", len(targetURLs), targetsFile)
+		}
+
 		// ── 4. Scope validation ────────────────────────────────────────────────
-		if scopeDomainsFlag != "" {
+		if len(scopeDomains) == 0 && cfg != nil {
+			scopeDomains = cfg.ScopeDomains
+		}
+		targets := domains
+		if len(scopeDomains) > 0 {
 			scopeCfg := pipeline.ScopeConfig{
-				AllowedDomains: splitCSV(scopeDomainsFlag),
+				AllowedDomains: scopeDomains,
+			}
+			for _, target := range targets {
+				if err := scopeCfg.ValidateTarget(target); err != nil {
+					return fmt.Errorf("scope check failed: %w", err)
+				}
+			}
+			if err := validateURLScope(&scopeCfg, targetURLs); err != nil {
+				return err
 			}
-			if err := scopeCfg.ValidateTarget(domain); err != nil {
-				return fmt.Errorf("scope check failed: %w", err)
+			fmt.Printf("[*] Scope validated: %s in scope\n", strings.Join(targets, ", "))
+		}
+
+		// Client-supplied asset list, merged into discovery
+		if knownFile != "" {
+			known, err := discovery.LoadKnownSubdomains(knownFile)
+			if err != nil {
+				return err
 			}
-			fmt.Printf("[*] Scope validated: %s is in scope\n", domain)
+			knownSubdomains = known
+			fmt.Printf("[*] Loaded %d known subdomains from %s\n", len(known), knownFile)
+		}
+
+		// Record the resolved settings so the run can be reproduced later.
+		runCfg := &models.RunConfig{
+			Version:         rootCmd.Version,
+			Preset:          presetName,
+			Tag:             tag,
+			Stages:          stageList,
+			Skip:            skipList,
+			Severity:        severity,
+			Timeout:         timeout.String(),
+			ScopeDomains:    scopeDomains,
+			SkipPDF:         skipPDF,
+			PDFEngine:       pdfEngine,
+			Permutations:    permutations,
+			Bruteforce:      bruteforce,
+			ZoneTransfer:    zoneTransfer,
+			DNSConsensus:    dnsConsensus,
+			Ports:           ports,
+			TopPorts:        topPorts,
+			UDP:             udp,
+			ProbeFilter:     probeFilterSrc,
+			VulnscanFilter:  vulnFilterSrc,
+			KnownSubdomains: knownSubdomains,
+			TargetURLs:      targetURLs,
+			FakeTools:       fakeToolsMode,
+			ReplayOf:        replayOf,
+			Config:          snapshotConfig(cfg),
 		}
 
 		// ── 5. Pre-flight tool checks ──────────────────────────────────────────
 		// Check all tools upfront so we fail fast before creating any directories.
 		toolCheckResults := checkAllScanTools()
+		if len(targetURLs) > 0 {
+			// A URL list replaces subdomain discovery and port scanning
+			for _, name := range []string{"subfinder", "amass", "masscan", "naabu", "nmap"} {
+				if r, ok := toolCheckResults[name]; ok {
+					r.required = false
+					toolCheckResults[name] = r
+				}
+			}
+		}
 		printToolCheckSummary(toolCheckResults)
 
 		// Hard-fail if any required tool is missing.
@@ -112,13 +352,10 @@ Examples:
 		gowitnessAvailable := toolCheckResults["gowitness"].found
 		nucleiAvailable := toolCheckResults["nuclei"].found
 
-		// Python is needed only for PDF generation.
-		python3Available, pythonBinary := false, ""
-		if !skipPDF {
-			python3Available, pythonBinary = detectPython()
-			if !python3Available {
-				fmt.Println("[!] Warning: python3/python not found — PDF generation will be skipped")
-			}
+		// Python is needed only by the python PDF engine.
+		pythonBinary, err := resolvePDFEngine(pdfEngine, skipPDF)
+		if err != nil {
+			return err
 		}
 
 		// ── 6. Open bbolt store ────────────────────────────────────────────────
@@ -128,86 +365,271 @@ Examples:
 		}
 		defer store.Close()
 
-		// ── 7. Build stage closures ────────────────────────────────────────────
-		// Stage closures are constructed by the shared helper in stages.go so
-		// that wizard.go can reuse them without duplicating code.
-		allStages := buildScanStages(
-			domain,
-			severity,
-			skipPDF,
-			python3Available,
-			pythonBinary,
-			tlsxAvailable,
-			cdncheckAvailable,
-			gowitnessAvailable,
-			nucleiAvailable,
-		)
-
-		// ── 8. Build PipelineConfig ────────────────────────────────────────────
-		pipelineCfg := pipeline.PipelineConfig{
-			Target:  domain,
-			ScanDir: scanDir,
-			Stages:  stageList,
-			Skip:    skipList,
-			Resume:  resume,
-			Timeout: timeout,
-			OnStageStart: func(name string, index, total int) {
-				fmt.Printf("[*] Stage %d/%d: %s...\n", index+1, total, name)
-			},
-			OnStageDone: func(name string, index, total int, err error, elapsed time.Duration) {
-				if err != nil {
-					fmt.Printf("[!] Stage %d/%d: %s FAILED (%s)\n",
-						index+1, total, name, elapsed.Round(time.Millisecond))
-				} else {
-					fmt.Printf("[+] Stage %d/%d: %s complete (%s)\n",
-						index+1, total, name, elapsed.Round(time.Millisecond))
+		// Several domains run one after another; IPs they share are port
+		// scanned for the first only and attributed to all of them at the end.
+		var shared *portscan.SharedScan
+		if len(targets) > 1 {
+			shared = portscan.NewSharedScan()
+			fmt.Printf("[*] Scanning %d targets: %s\n", len(targets), strings.Join(targets, ", "))
+		}
+		runStarted := time.Now()
+		var scanDirs []string
+		var scanned []string
+		var failed []string
+		policyMatches := 0
+		for _, domain := range targets {
+			// ── 7. Build stage closures ────────────────────────────────────────
+			// Stage closures are constructed by the shared helper in stages.go so
+			// that wizard.go can reuse them without duplicating code.
+			allStages := buildScanStages(store, scanStageOptions{
+				domain:             domain,
+				severity:           severity,
+				skipPDF:            skipPDF,
+				pdfEngine:          pdfEngine,
+				pythonBinary:       pythonBinary,
+				tlsxAvailable:      tlsxAvailable,
+				cdncheckAvailable:  cdncheckAvailable,
+				gowitnessAvailable: gowitnessAvailable,
+				nucleiAvailable:    nucleiAvailable,
+				knownSubdomains:    knownSubdomains,
+				permutations:       permutations,
+				bruteforce:         bruteforce,
+				zoneTransfer:       zoneTransfer,
+				dnsConsensus:       dnsConsensus,
+				ports:              ports,
+				topPorts:           topPorts,
+				udp:                udp,
+				probeFilter:        probeFilter,
+				vulnscanFilter:     vulnFilter,
+				sharedPorts:        shared,
+				compareDir:         replayDir,
+				targetURLs:         targetURLs,
+			})
+
+			// ── 8. Build PipelineConfig ────────────────────────────────────────
+			pipelineCfg := pipeline.PipelineConfig{
+				Target:    domain,
+				ScanDir:   scanDir,
+				Preset:    presetName,
+				Tag:       tag,
+				Stages:    stageList,
+				Skip:      skipList,
+				Resume:    resume,
+				Timeout:   timeout,
+				Operator:  operator,
+				RunConfig: runCfg,
+				Policy:    targetPolicy(cfg, ignorePolicy),
+
+				KeepToolOutput: keepToolOutput,
+				OnStageStart: func(name string, index, total int) {
+					fmt.Printf("[*] Stage %d/%d: %s...\n", index+1, total, name)
+				},
+				OnStageDone: func(name string, index, total int, err error, elapsed time.Duration) {
+					if err != nil {
+						fmt.Printf("[!] Stage %d/%d: %s FAILED (%s)\n",
+							index+1, total, name, elapsed.Round(time.Millisecond))
+					} else {
+						fmt.Printf("[+] Stage %d/%d: %s complete (%s)\n",
+							index+1, total, name, elapsed.Round(time.Millisecond))
+					}
+				},
+			}
+
+			// ── 9. Run the pipeline ────────────────────────────────────────────
+			fmt.Printf("[*] Starting full pipeline scan for %s\n", domain)
+
+			// Use a background context — the orchestrator applies its own timeout.
+			result, err := pipeline.RunPipeline(context.Background(), pipelineCfg, allStages, store, cfg)
+			if err != nil {
+				err = policyHint(fmt.Errorf("pipeline failed: %w", err))
+				if len(targets) == 1 {
+					return err
 				}
-			},
+				fmt.Printf("[!] %s: %v\n", domain, err)
+				failed = append(failed, domain)
+				continue
+			}
+
+			// ── 10. Webhook notification (non-fatal) ───────────────────────────
+			if notifyURL != "" {
+				sendNotifications(store, notifyURL, result)
+			}
+
+			// ── 11. Print final summary ────────────────────────────────────────
+			fmt.Println()
+			fmt.Printf("[+] Scan complete!\n")
+			fmt.Printf("    Target:    %s\n", result.Target)
+			fmt.Printf("    Scan ID:   %s\n", result.ScanID)
+			fmt.Printf("    Scan dir:  %s\n", result.ScanDir)
+			fmt.Printf("    Status:    %s\n", result.Status)
+			fmt.Printf("    Elapsed:   %s\n", result.Elapsed.Round(time.Second))
+			fmt.Printf("    Stages:    %s\n", strings.Join(result.StagesRun, " -> "))
+
+			if len(result.StageErrors) > 0 {
+				fmt.Println()
+				fmt.Println("[!] Stage errors:")
+				for stage, errMsg := range result.StageErrors {
+					fmt.Printf("    %-12s %s\n", stage+":", errMsg)
+				}
+			}
+
+			// ── 12. Apply risk_policy.fail_on ──────────────────────────────────
+			policyMatches += checkFailPolicy(result.ScanDir)
+
+			scanDirs = append(scanDirs, result.ScanDir)
+			scanned = append(scanned, domain)
+		}
+
+		// ── 13. Attribute shared IPs to every owning target ────────────────────
+		if shared != nil {
+			attributeSharedHosts(store, shared, scanDirs)
+		}
+
+		// ── 14. Roll up a multi-target run into one report ─────────────────────
+		if len(targets) > 1 && len(scanned) > 0 {
+			writeRunRollup(store, scanned, runStarted)
+		}
+		if len(failed) > 0 {
+			return fmt.Errorf("pipeline failed for %d of %d targets: %s", len(failed), len(targets), strings.Join(failed, ", "))
 		}
 
-		// ── 9. Run the pipeline ────────────────────────────────────────────────
-		fmt.Printf("[*] Starting full pipeline scan for %s\n", domain)
+		return policyError(policyMatches)
+	},
+}
 
-		// Use a background context — the orchestrator applies its own timeout.
-		result, err := pipeline.RunPipeline(context.Background(), pipelineCfg, allStages, store, cfg)
+// attributeSharedHosts records, in the ports.json and ports.md of each scan
+// of a multi-target run, and in the results saved in the database, which
+// other targets resolved to the same IPs. Failures only warn: the scans
+// themselves are complete.
+func attributeSharedHosts(store storage.Store, shared *portscan.SharedScan, scanDirs []string) {
+	for _, scanDir := range scanDirs {
+		portsPath := storage.RawPath(scanDir, "ports.json")
+		data, err := os.ReadFile(portsPath)
 		if err != nil {
-			return fmt.Errorf("pipeline failed: %w", err)
+			continue // portscan did not run for this target
+		}
+		var result portscan.PortScanResult
+		if err := json.Unmarshal(data, &result); err != nil {
+			fmt.Printf("[!] Warning: parsing %s: %v\n", portsPath, err)
+			continue
 		}
 
-		// ── 10. Webhook notification (non-fatal) ───────────────────────────────
-		if webhookURL != "" {
-			notifyCfg := pipeline.NotifyConfig{WebhookURL: webhookURL}
-			if notifyErr := notifyCfg.SendCompletion(result); notifyErr != nil {
-				fmt.Printf("[!] Warning: webhook notification failed: %v\n", notifyErr)
-			} else {
-				fmt.Printf("[+] Completion notification sent to %s\n", webhookURL)
-			}
+		n := shared.Attribute(&result)
+		if n == 0 {
+			continue
+		}
+		if err := report.WritePortReport(&result, storage.ReportPath(scanDir, "ports.md")); err != nil {
+			fmt.Printf("[!] Warning: failed to write port report: %v\n", err)
 		}
+		if err := report.WritePortHTMLReport(&result, storage.ReportPath(scanDir, "ports.html")); err != nil {
+			fmt.Printf("[!] Warning: failed to write HTML port report: %v\n", err)
+		}
+		data, err = json.MarshalIndent(&result, "", "  ")
+		if err != nil {
+			fmt.Printf("[!] Warning: marshaling port scan result: %v\n", err)
+			continue
+		}
+		if err := storage.WriteFileAtomic(portsPath, data, 0644); err != nil {
+			fmt.Printf("[!] Warning: writing %s: %v\n", portsPath, err)
+			continue
+		}
+		saveStageResults(store, result.Target, scanDir, "", func(scanID string) error {
+			return store.SaveHosts(scanID, result.Hosts, result.CollectedAt)
+		})
+		fmt.Printf("[*] %s: %d hosts shared with other targets of this run\n", result.Target, n)
+	}
+}
+
+// loadDomainsFile reads the root domains of a multi-target run from path:
+// one per line (or several comma-separated), with # comments.
+func loadDomainsFile(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading domains file: %w", err)
+	}
+	var domains []string
+	for _, line := range strings.Split(string(data), "\n") {
+		line, _, _ = strings.Cut(line, "#")
+		domains = append(domains, splitCSV(line)...)
+	}
+	if len(domains) == 0 {
+		return nil, fmt.Errorf("domains file %s lists no domains", path)
+	}
+	return domains, nil
+}
 
-		// ── 11. Print final summary ────────────────────────────────────────────
-		fmt.Println()
-		fmt.Printf("[+] Scan complete!\n")
-		fmt.Printf("    Target:    %s\n", result.Target)
-		fmt.Printf("    Scan ID:   %s\n", result.ScanID)
-		fmt.Printf("    Scan dir:  %s\n", result.ScanDir)
-		fmt.Printf("    Status:    %s\n", result.Status)
-		fmt.Printf("    Elapsed:   %s\n", result.Elapsed.Round(time.Second))
-		fmt.Printf("    Stages:    %s\n", strings.Join(result.StagesRun, " -> "))
+// uniqueDomains trims domains and drops empty and repeated ones, keeping
+// the order they were given in.
+func uniqueDomains(domains []string) []string {
+	seen := make(map[string]bool, len(domains))
+	var out []string
+	for _, d := range domains {
+		d = strings.TrimSpace(d)
+		if d == "" || seen[strings.ToLower(d)] {
+			continue
+		}
+		seen[strings.ToLower(d)] = true
+		out = append(out, d)
+	}
+	return out
+}
 
-		if len(result.StageErrors) > 0 {
-			fmt.Println()
-			fmt.Println("[!] Stage errors:")
-			for stage, errMsg := range result.StageErrors {
-				fmt.Printf("    %-12s %s\n", stage+":", errMsg)
-			}
+// writeRunRollup writes one portfolio report over the targets a
+// multi-target run scanned, to {scan_dir}/aggregate/run-<time>.md and
+// .json, and prints its headline numbers. New assets are those each
+// target's scan found that its previous scan did not. Failures only warn:
+// the scans themselves are complete.
+func writeRunRollup(store storage.Store, targets []string, started time.Time) {
+	history := make(map[string][]*models.ScanMeta, len(targets))
+	for _, target := range targets {
+		scans, err := store.ListScans(target)
+		if err != nil {
+			fmt.Printf("[!] Warning: roll-up report: listing scans for %s: %v\n", target, err)
+			return
 		}
+		history[target] = scans
+	}
+	portfolio, err := stats.ComputePortfolio(store, history, started)
+	if err != nil {
+		fmt.Printf("[!] Warning: roll-up report: %v\n", err)
+		return
+	}
+	base, err := writePortfolio(portfolio, 0, filepath.Join(cfg.ScanDir, "aggregate"), "run-"+started.Format("20060102-150405"))
+	if err != nil {
+		fmt.Printf("[!] Warning: roll-up report: %v\n", err)
+		return
+	}
+	printPortfolio(portfolio, 0)
+	fmt.Printf("[+] Roll-up report for %d targets written to %s.md (JSON: %s.json)\n", len(targets), base, base)
+}
 
-		return nil
-	},
+// sendFindingAlerts posts alerts for findings that are new, escalated or
+// resolved since earlier scans of the target, deduplicated through the
+// notification state in the database.
+func sendFindingAlerts(store storage.Store, notifyCfg *pipeline.NotifyConfig, result *pipeline.PipelineResult) {
+	alerts, err := pipeline.TrackFindings(store, result, notifyCfg.Policy, func(alerts []pipeline.FindingAlert) error {
+		return notifyCfg.SendFindingAlerts(result, alerts)
+	})
+	if err != nil {
+		fmt.Printf("[!] Warning: finding alerts: %v\n", err)
+		return
+	}
+	if len(alerts) == 0 {
+		fmt.Println("[*] No new, escalated or resolved findings to alert on")
+		return
+	}
+
+	counts := map[string]int{}
+	for _, a := range alerts {
+		counts[a.Event]++
+	}
+	fmt.Printf("[+] Finding alerts sent: %d new, %d escalated, %d resolved\n",
+		counts[pipeline.AlertNew], counts[pipeline.AlertEscalated], counts[pipeline.AlertResolved])
 }
 
 func init() {
-	scanCmd.Flags().StringP("domain", "d", "", "Target domain to scan (required)")
+	scanCmd.Flags().StringSliceP("domain", "d", nil, "Target domain to scan (required unless --replay or --domains-file is given); repeat or comma-separate to scan several in one run")
+	scanCmd.Flags().String("domains-file", "", "File of root domains to scan in one run, one per line with # comments (adds to --domain)")
 	scanCmd.Flags().String("scan-dir", "", "Use an existing scan directory (auto-creates new one if empty)")
 	scanCmd.Flags().String("stages", "", "Comma-separated stage names to run (e.g. discover,portscan)")
 	scanCmd.Flags().String("skip", "", "Comma-separated stage names to skip")
@@ -215,11 +637,26 @@ func init() {
 	scanCmd.Flags().String("preset", "", "Named preset: bug-bounty, quick-recon, internal-pentest")
 	scanCmd.Flags().String("severity", "critical,high,medium", "Nuclei severity filter (comma-separated)")
 	scanCmd.Flags().Duration("timeout", 2*time.Hour, "Total pipeline timeout")
-	scanCmd.Flags().String("notify-webhook", "", "HTTP webhook URL to POST a completion summary to")
-	scanCmd.Flags().String("scope-domains", "", "Comma-separated allowed domain patterns (e.g. example.com,*.example.com)")
+	scanCmd.Flags().String("notify", "", "Send a completion summary and finding alerts to an http(s) webhook, slack://hooks.slack.com/services/... or discord://discord.com/api/webhooks/...")
+	scanCmd.Flags().String("notify-webhook", "", "HTTP webhook URL to POST a completion summary and finding alerts to (same as --notify)")
+	scanCmd.Flags().String("scope-domains", "", "Comma-separated allowed domain patterns (e.g. example.com,*.example.com; default: config scope_domains)")
 	scanCmd.Flags().Bool("skip-pdf", false, "Skip PDF report generation")
-
-	scanCmd.MarkFlagRequired("domain")
+	scanCmd.Flags().String("pdf-engine", pdfEngineGo, "PDF generator: go (built in) or python (the Nuc-pdf Python tool)")
+	scanCmd.Flags().Bool("permutations", false, "Generate and resolve permutations of discovered subdomains (api-dev, dev-api, api01...)")
+	scanCmd.Flags().Bool("bruteforce", false, "Resolve a wordlist of labels under the domain (see discovery.bruteforce)")
+	scanCmd.Flags().Bool("axfr", false, "Attempt a DNS zone transfer (AXFR) against each authoritative nameserver")
+	scanCmd.Flags().Bool("dns-consensus", false, "Resolve each name through several resolvers and keep only answers a quorum agrees on (see discovery.dns_consensus)")
+	scanCmd.Flags().String("ports", "", portsFlagUsage)
+	scanCmd.Flags().Int("top-ports", 0, topPortsFlagUsage)
+	scanCmd.Flags().Bool("udp", false, udpFlagUsage)
+	scanCmd.Flags().String("probe-filter", "", `Probe only targets matching this expression, e.g. 'port in (80,443) && !is_cdn' (overrides probe.filter)`)
+	scanCmd.Flags().String("vulnscan-filter", "", `Scan only targets matching this expression, e.g. 'status_code == 200' (overrides vulnscan.filter)`)
+	scanCmd.Flags().String("tag", "", "Label for this run, used by the {tag} placeholder of scan_layout.dir_template")
+	scanCmd.Flags().String("known-subdomains", "", "File of client-supplied subdomains (.txt one per line, or .csv) to always include")
+	scanCmd.Flags().String("targets-file", "", "File of URLs (one per line) to probe and scan instead of discovering subdomains and scanning ports")
+	scanCmd.Flags().String("replay", "", "Rerun a past scan (ID or ID prefix) with its recorded settings and config; other flags override them")
+	scanCmd.Flags().Bool("ignore-policy", false, "Scan even if the target's cooldown has not passed or another scan of it is running")
+	scanCmd.Flags().Bool("keep-tool-output", false, "Keep each tool run's raw stdout/stderr (gzipped, capped at 16 MiB per stream) under raw/tool-logs/ for debugging")
 
 	rootCmd.AddCommand(scanCmd)
 }
@@ -241,6 +678,39 @@ func splitCSV(s string) []string {
 	return out
 }
 
+// validateURLScope checks that every hostname in a URL list is in scope.
+// IP addresses are left to the exclusions list.
+func validateURLScope(scope *pipeline.ScopeConfig, urls []string) error {
+	for _, host := range urllist.Hostnames(urls) {
+		if err := scope.ValidateTarget(host); err != nil {
+			return fmt.Errorf("scope check failed for targets file: %w", err)
+		}
+	}
+	return nil
+}
+
+// targetPolicy builds the per-target scan policy from the config, or returns
+// nil when the operator chose to ignore it.
+func targetPolicy(c *config.Config, ignore bool) *pipeline.TargetPolicy {
+	if ignore || c == nil {
+		return nil
+	}
+	return &pipeline.TargetPolicy{
+		Cooldown:        c.Policy.CooldownDuration(),
+		AllowConcurrent: c.Policy.AllowConcurrent,
+	}
+}
+
+// policyHint points the operator at --ignore-policy when err is a refusal by
+// the target policy.
+func policyHint(err error) error {
+	var pe *pipeline.PolicyError
+	if errors.As(err, &pe) {
+		return fmt.Errorf("%w (use --ignore-policy to scan anyway)", err)
+	}
+	return err
+}
+
 // toolCheckEntry carries the result of a single pre-flight tool check.
 type toolCheckEntry struct {
 	name       string
@@ -250,16 +720,23 @@ type toolCheckEntry struct {
 }
 
 // checkAllScanTools probes every tool the scan pipeline may need and returns a
-// map keyed by tool name so callers can look up individual results.
+// map keyed by tool name so callers can look up individual results. amass and
+// dnsx are only checked when discovery.engine and discovery.dns_resolver use
+// them, and only the port scanner portscan.scanner selects is checked.
 func checkAllScanTools() map[string]toolCheckEntry {
+	runSubfinder, runAmass := engineTools()
+	naabu := useNaabu()
 	checks := []struct {
 		name       string
 		required   bool
 		installCmd string
 	}{
-		{"subfinder", true, "go install -v github.com/projectdiscovery/subfinder/v2/cmd/subfinder@latest"},
-		{"dig", true, "apt install dnsutils (or brew install bind on macOS)"},
-		{"masscan", true, "apt install masscan (or brew install masscan on macOS)"},
+		{"subfinder", runSubfinder, "go install -v github.com/projectdiscovery/subfinder/v2/cmd/subfinder@latest"},
+		{"amass", runAmass, amassInstallCmd},
+		{"dig", !tools.NativeDNS(), "apt install dnsutils (or brew install bind on macOS)"},
+		{"dnsx", tools.BulkDNS(), dnsxInstallCmd},
+		{"masscan", !naabu, "apt install masscan (or brew install masscan on macOS)"},
+		{"naabu", naabu, naabuInstallCmd},
 		{"nmap", true, "apt install nmap (or brew install nmap on macOS)"},
 		{"httpx", true, "go install -v github.com/projectdiscovery/httpx/cmd/httpx@latest"},
 		{"tlsx", false, "go install -v github.com/projectdiscovery/tlsx/cmd/tlsx@latest"},
@@ -270,6 +747,9 @@ func checkAllScanTools() map[string]toolCheckEntry {
 
 	results := make(map[string]toolCheckEntry, len(checks))
 	for _, c := range checks {
+		if (c.name == "amass" && !runAmass) || (c.name == "dnsx" && !tools.BulkDNS()) || (c.name == "masscan" && naabu) || (c.name == "naabu" && !naabu) {
+			continue
+		}
 		r := tools.CheckTool(tools.ToolRequirement{
 			Name:       c.name,
 			Binary:     c.name,
@@ -288,10 +768,13 @@ func checkAllScanTools() map[string]toolCheckEntry {
 
 // printToolCheckSummary prints a compact pre-flight report to stdout.
 func printToolCheckSummary(results map[string]toolCheckEntry) {
-	order := []string{"subfinder", "dig", "masscan", "nmap", "httpx", "tlsx", "cdncheck", "gowitness", "nuclei"}
+	order := []string{"subfinder", "amass", "dig", "dnsx", "masscan", "naabu", "nmap", "httpx", "tlsx", "cdncheck", "gowitness", "nuclei"}
 	fmt.Println("[*] Pre-flight tool check:")
 	for _, name := range order {
-		r := results[name]
+		r, ok := results[name]
+		if !ok {
+			continue
+		}
 		status := "ok"
 		if !r.found {
 			if r.required {
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/hakim/reconpipe/internal/diff"
+	"github.com/hakim/reconpipe/internal/storage"
+	"github.com/hakim/reconpipe/pkg/models"
+	"github.com/spf13/cobra"
+)
+
+// searchHit is one result of a scan that matched the search term.
+type searchHit struct {
+	Target string `json:"target"`
+	ScanID string `json:"scan_id"`
+	Kind   string `json:"kind"`  // subdomain, port, service or vuln
+	Item   string `json:"item"`  // the subdomain, host:port, URL or finding
+	Match  string `json:"match"` // the value that contains the term
+}
+
+var searchCmd = &cobra.Command{
+	Use:   "search <term>",
+	Short: "Find a hostname, IP, technology or finding across scans",
+	Long: `Search the results of past scans for a term, matched case-insensitively as a
+substring of:
+
+  subdomains      name, IPs and DNS record values
+  open ports      IP, hostnames, service and version
+  live services   URL, title, web server and technologies
+  vulnerabilities template ID, name, host and where it matched
+
+The latest scan of every target is searched (the newest complete one, as
+'show -d' picks it), or of one target with -d. --all-scans searches every
+scan instead, to find when something was last seen.
+
+Use --format json for machine-readable output.
+
+Examples:
+  reconpipe search 10.0.3.17
+  reconpipe search jenkins
+  reconpipe search -d example.com --all-scans staging`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// Step 1: Get flags
+		term := strings.ToLower(strings.TrimSpace(args[0]))
+		domain, _ := cmd.Flags().GetString("domain")
+		allScans, _ := cmd.Flags().GetBool("all-scans")
+		format, _ := cmd.Flags().GetString("format")
+
+		if term == "" {
+			return fmt.Errorf("search term cannot be empty")
+		}
+		if format != "table" && format != "json" {
+			return fmt.Errorf("unknown format %q (supported: table, json)", format)
+		}
+
+		// Step 2: Config check
+		if cfg == nil {
+			return fmt.Errorf("config not loaded. Run 'reconpipe init' first to create config")
+		}
+
+		// Step 3: Open the store and pick the scans
+		store, err := storage.NewStore(cfg.DBPath)
+		if err != nil {
+			return fmt.Errorf("opening database: %w", err)
+		}
+		defer store.Close()
+
+		targets := []string{domain}
+		if domain == "" {
+			if targets, err = store.ListTargets(); err != nil {
+				return fmt.Errorf("listing targets: %w", err)
+			}
+		}
+		var scans []*models.ScanMeta
+		for _, target := range targets {
+			list, err := store.ListScans(target)
+			if err != nil {
+				return fmt.Errorf("listing scans for %s: %w", target, err)
+			}
+			if allScans {
+				scans = append(scans, list...)
+			} else if scan := latestCompleteScan(list); scan != nil {
+				scans = append(scans, scan)
+			}
+		}
+
+		// Step 4: Search each scan's results
+		hits := []searchHit{}
+		for _, scan := range scans {
+			snap, err := diff.LoadScanSnapshot(store, scan)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "[!] Warning: skipping scan %s: %v\n", scan.ID, err)
+				continue
+			}
+			hits = append(hits, searchSnapshot(scan, snap, term)...)
+		}
+
+		// Step 5: Print
+		if format == "json" {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			return enc.Encode(hits)
+		}
+		if len(hits) == 0 {
+			fmt.Printf("No matches for %q in %d scan(s)\n", args[0], len(scans))
+			return nil
+		}
+		var rows [][]any
+		for _, h := range hits {
+			rows = append(rows, []any{truncate(h.Target, 24), shortScanID(h.ScanID), h.Kind, truncate(h.Item, 40), truncate(h.Match, 32)})
+		}
+		printTable("Matches", "  %-24s  %-11s  %-9s  %-40s  %s\n", []any{"Target", "Scan", "Kind", "Item", "Match"}, rows)
+		fmt.Printf("\n%d match(es) in %d scan(s)\n\n", len(hits), len(scans))
+		return nil
+	},
+}
+
+// searchSnapshot returns the results of snap with a value containing term,
+// which is lowercase. Each result is reported once, with the first value
+// that matched.
+func searchSnapshot(scan *models.ScanMeta, snap *diff.ScanSnapshot, term string) []searchHit {
+	var hits []searchHit
+	add := func(kind, item string, values ...string) {
+		for _, v := range values {
+			if v != "" && strings.Contains(strings.ToLower(v), term) {
+				hits = append(hits, searchHit{Target: scan.Target, ScanID: scan.ID, Kind: kind, Item: item, Match: v})
+				return
+			}
+		}
+	}
+
+	for _, s := range snap.Subdomains {
+		values := append([]string{s.Name}, s.IPs...)
+		for _, r := range s.DNSRecords {
+			values = append(values, r.Value)
+		}
+		add("subdomain", s.Name, values...)
+	}
+	for _, h := range snap.Hosts {
+		for _, p := range h.Ports {
+			values := append([]string{h.IP}, h.Subdomains...)
+			add("port", h.IP+":"+strconv.Itoa(p.Number), append(values, p.Service, p.Version)...)
+		}
+	}
+	for _, p := range snap.Probes {
+		add("service", p.URL, append([]string{p.URL, p.IP, p.Title, p.WebServer}, p.Technologies...)...)
+	}
+	for _, v := range snap.Vulnerabilities {
+		where := v.MatchedAt
+		if where == "" {
+			where = v.Host
+		}
+		add("vuln", v.Name+" @ "+where, v.TemplateID, v.Name, v.Host, v.MatchedAt)
+	}
+	return hits
+}
+
+func init() {
+	searchCmd.Flags().StringP("domain", "d", "", "Only search scans of this target")
+	searchCmd.Flags().Bool("all-scans", false, "Search every scan, not just the latest of each target")
+	searchCmd.Flags().String("format", "table", "Output format: table or json")
+	rootCmd.AddCommand(searchCmd)
+}
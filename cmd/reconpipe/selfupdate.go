@@ -0,0 +1,224 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/hakim/reconpipe/internal/config"
+	"github.com/hakim/reconpipe/internal/selfupdate"
+	"github.com/hakim/reconpipe/internal/signing"
+	"github.com/spf13/cobra"
+)
+
+var selfUpdateCmd = &cobra.Command{
+	Use:   "self-update",
+	Short: "Replace this binary with the latest signed release",
+	Long: `Download the latest release on the configured channel and replace the running
+binary with it. stable follows releases only; beta also takes prereleases.
+
+The release must carry a binary for this platform (reconpipe_<os>_<arch>) and
+reconpipe.manifest with its ed25519 signature (.sig), as written by
+release-manifest. The manifest is checked against update.public_key (or
+--public-key), must name the release's tag and list the binary's SHA-256, and
+the binary must report the release's version before anything is replaced. The
+previous binary is kept next to the new one with an .old suffix.
+
+--channel defaults to update.channel, or stable. --force installs the latest
+release even when it is not newer, e.g. to leave the beta channel.
+
+Exits non-zero when the update fails, so fleet automation can alert on it.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// Step 1: Get flags
+		channel, _ := cmd.Flags().GetString("channel")
+		publicKey, _ := cmd.Flags().GetString("public-key")
+		checkOnly, _ := cmd.Flags().GetBool("check")
+		force, _ := cmd.Flags().GetBool("force")
+
+		var updateCfg config.UpdateConfig
+		if cfg != nil {
+			updateCfg = cfg.Update
+		}
+		if channel == "" {
+			channel = updateCfg.ChannelOrDefault()
+		}
+		if publicKey == "" {
+			publicKey = updateCfg.PublicKey
+		}
+
+		// Step 2: Find the latest release
+		rel, err := latestRelease(cmd.Context(), updateCfg, channel)
+		if err != nil {
+			return err
+		}
+		if rel == nil {
+			fmt.Printf("[*] No %s release published yet\n", channel)
+			return nil
+		}
+		newer := selfupdate.Compare(rel.Version(), rootCmd.Version) > 0
+		if !newer && !force {
+			fmt.Printf("[+] reconpipe %s is up to date (latest %s release: %s)\n", rootCmd.Version, channel, rel.Tag)
+			return nil
+		}
+		fmt.Printf("[*] reconpipe %s -> %s (%s channel)\n", rootCmd.Version, rel.Tag, channel)
+		if checkOnly {
+			return nil
+		}
+
+		// Step 3: Load the release key
+		if publicKey == "" {
+			return fmt.Errorf("no release key: set update.public_key in the config or pass --public-key")
+		}
+		verifier, err := signing.New(signing.MethodEd25519, "", publicKey, "")
+		if err != nil {
+			return fmt.Errorf("release key: %w", err)
+		}
+
+		// Step 4: Download, verify and install
+		exe, err := selfupdate.Executable()
+		if err != nil {
+			return fmt.Errorf("locating the running binary: %w", err)
+		}
+		client := updateClient(updateCfg)
+		if err := client.Install(cmd.Context(), rel, exe, verifier); err != nil {
+			if errors.Is(err, os.ErrPermission) {
+				return fmt.Errorf("self-update: %w (run as the user owning %s)", err, exe)
+			}
+			return fmt.Errorf("self-update: %w", err)
+		}
+		fmt.Printf("[+] Installed reconpipe %s to %s (previous binary kept as %s.old)\n", rel.Tag, exe, exe)
+		return nil
+	},
+}
+
+var versionCmd = &cobra.Command{
+	Use:   "version",
+	Short: "Print the version, and whether a newer release exists",
+	Long: `Print the version of this binary. With --check the release channel is asked for
+its latest release too, and a newer one is reported with the command that
+installs it. The config is read for the update settings when it exists.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		channel, _ := cmd.Flags().GetString("channel")
+		check, _ := cmd.Flags().GetBool("check")
+
+		fmt.Printf("reconpipe %s\n", rootCmd.Version)
+		if !check {
+			return nil
+		}
+
+		// version skips config loading; the update settings are optional
+		var updateCfg config.UpdateConfig
+		if _, err := os.Stat(cfgFile); err == nil {
+			c, err := config.Load(cfgFile)
+			if err != nil {
+				return fmt.Errorf("failed to load config: %w", err)
+			}
+			updateCfg = c.Update
+		}
+		if channel == "" {
+			channel = updateCfg.ChannelOrDefault()
+		}
+
+		rel, err := latestRelease(cmd.Context(), updateCfg, channel)
+		if err != nil {
+			return err
+		}
+		switch {
+		case rel == nil:
+			fmt.Printf("[*] No %s release published yet\n", channel)
+		case selfupdate.Compare(rel.Version(), rootCmd.Version) > 0:
+			fmt.Printf("[!] A newer %s release is available: %s (published %s)\n",
+				channel, rel.Tag, rel.PublishedAt.Format("2006-01-02"))
+			if rel.URL != "" {
+				fmt.Printf("    %s\n", rel.URL)
+			}
+			fmt.Printf("    Run 'reconpipe self-update' to install it\n")
+		default:
+			fmt.Printf("[+] Up to date (latest %s release: %s)\n", channel, rel.Tag)
+		}
+		return nil
+	},
+}
+
+var releaseManifestCmd = &cobra.Command{
+	Use:   "release-manifest binary...",
+	Short: "Write the signed manifest self-update checks a release against",
+	Long: `Write reconpipe.manifest, listing the release tag and the SHA-256 digest of
+each binary, and sign it with the ed25519 release key. Attach both files to the
+release next to the binaries:
+
+  reconpipe release-manifest --tag v1.4.0 --key release.key dist/reconpipe_*
+
+self-update installs a binary only when the manifest verifies against its public
+key, names the release being installed and lists the binary's digest.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// Step 1: Get flags
+		tag, _ := cmd.Flags().GetString("tag")
+		key, _ := cmd.Flags().GetString("key")
+		outDir, _ := cmd.Flags().GetString("output")
+
+		if tag == "" {
+			return fmt.Errorf("--tag is required")
+		}
+		if key == "" {
+			return fmt.Errorf("--key is required")
+		}
+		signer, err := signing.New(signing.MethodEd25519, key, "", "")
+		if err != nil {
+			return fmt.Errorf("release key: %w", err)
+		}
+
+		// Step 2: Hash the binaries
+		manifest, err := selfupdate.NewManifest(tag, args)
+		if err != nil {
+			return err
+		}
+
+		// Step 3: Write and sign the manifest
+		path := filepath.Join(outDir, selfupdate.ManifestName)
+		if err := manifest.Write(path); err != nil {
+			return fmt.Errorf("writing manifest: %w", err)
+		}
+		sigPath, err := signer.Sign(cmd.Context(), path)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("[+] Wrote %s (%d binaries, %s) and %s\n", path, len(manifest.SHA256), tag, sigPath)
+		return nil
+	},
+}
+
+// updateClient returns the release client for c.
+func updateClient(c config.UpdateConfig) *selfupdate.Client {
+	return &selfupdate.Client{URL: c.URL, Repo: c.Repo}
+}
+
+// latestRelease returns the latest release on channel, or nil when there
+// is none.
+func latestRelease(ctx context.Context, c config.UpdateConfig, channel string) (*selfupdate.Release, error) {
+	rel, err := updateClient(c).Latest(ctx, channel)
+	if err != nil {
+		return nil, fmt.Errorf("checking for releases: %w", err)
+	}
+	return rel, nil
+}
+
+func init() {
+	selfUpdateCmd.Flags().String("channel", "", "Release channel, stable or beta (default update.channel)")
+	selfUpdateCmd.Flags().String("public-key", "", "ed25519 PEM release key (default update.public_key)")
+	selfUpdateCmd.Flags().Bool("check", false, "Only report whether a newer release exists")
+	selfUpdateCmd.Flags().Bool("force", false, "Install the latest release even if it is not newer")
+	rootCmd.AddCommand(selfUpdateCmd)
+
+	releaseManifestCmd.Flags().String("tag", "", "Release tag the binaries belong to, e.g. v1.4.0 (required)")
+	releaseManifestCmd.Flags().String("key", "", "ed25519 PEM private key releases are signed with (required)")
+	releaseManifestCmd.Flags().String("output", ".", "Directory to write the manifest and its signature to")
+	rootCmd.AddCommand(releaseManifestCmd)
+
+	versionCmd.Flags().String("channel", "", "Release channel, stable or beta (default update.channel)")
+	versionCmd.Flags().Bool("check", false, "Report whether a newer release exists")
+	rootCmd.AddCommand(versionCmd)
+}
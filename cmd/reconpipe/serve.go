@@ -0,0 +1,350 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"reflect"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/hakim/reconpipe/internal/config"
+	"github.com/hakim/reconpipe/internal/pipeline"
+	"github.com/hakim/reconpipe/internal/server"
+	"github.com/hakim/reconpipe/internal/storage"
+	"github.com/hakim/reconpipe/pkg/models"
+	"github.com/spf13/cobra"
+)
+
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serve scan results over a JSON API and web UI, and accept scan requests",
+	Long: `Run an HTTP API over the scan database.
+
+Every request needs an API token in an "Authorization: Bearer" header. Create
+tokens with 'reconpipe token create'; a read token can list targets, scans,
+results and jobs, a scan token can also queue scans. Each token is rate
+limited (server.rate_limit requests per minute unless the token sets its own).
+
+Endpoints:
+  GET  /api/v1/targets
+  GET  /api/v1/scans?target=example.com&limit=20
+  GET  /api/v1/scans/{id}
+  GET  /api/v1/scans/{id}/results
+  GET  /api/v1/scans/{id}/diff?against={id}   (default: the previous scan)
+  GET  /api/v1/scans/{id}/reports
+  GET  /api/v1/scans/{id}/reports/{name}
+  GET  /api/v1/scans/{id}/screenshots/{name}
+  POST /api/v1/scans            {"target": "example.com", "preset": "quick-recon", "priority": "high"}
+  GET  /api/v1/jobs
+  GET  /api/v1/jobs/{id}
+
+GET /healthz and GET /readyz need no token, for load balancers and
+orchestrators. /healthz checks that the database is readable; /readyz also
+fails while the server is shutting down or a required tool is missing, and
+reports the worker state, queue depth and missing optional tools. Both
+answer 200 when healthy and 503 otherwise.
+
+The same address serves a web UI at / for browsing targets, scan history,
+results, screenshots, reports and changes since the previous scan. It signs
+in with an API token, which is kept in the browser's local storage.
+
+Queued scans run one at a time with the same defaults as 'reconpipe scan' and
+are recorded with the operator "api:<token name>". A scan's priority is high,
+normal (the default) or low; higher priorities run first. With
+server.preemption on, queueing a scan pauses a running scan of lower priority
+at its next stage boundary; the paused scan resumes where it stopped once the
+queue gets back to it.
+
+Set server.tls in the config to serve HTTPS with your certificate or a
+generated self-signed one, and server.tls.client_ca_file to require client
+certificates (mutual TLS) in addition to tokens.
+
+While the server runs it watches the config file. Edits to rate limits,
+scope_domains, policy, server.rate_limit and the other scan settings take
+effect for scans started afterwards; a running scan keeps the settings it
+started with. A config that fails to load or validate is rejected and logged, and
+the server carries on with the previous one. db_path, server.listen,
+server.queue_size, server.shutdown_grace, server.preemption and server.tls
+need a restart.
+
+On SIGTERM or interrupt the server refuses new scans, lets the running scan
+finish its current stage (up to server.shutdown_grace, default 1m, before
+cancelling it), saves the queued and interrupted scans to the database and
+exits. They are queued again on the next start, the interrupted scan
+resuming where it stopped. A second interrupt exits at once.
+
+Queued scans follow the policy section of the config: a target scanned within
+policy.cooldown is refused with 409 Conflict and a Retry-After header, and
+unless policy.allow_concurrent is set a target that already has a queued or
+running job is refused with 409 too. Set "ignore_policy": true in the request
+to override both.
+
+With bbolt the server opens the database only while it reads or writes it,
+so other reconpipe commands, such as token revoke, can use it meanwhile.
+
+Examples:
+  reconpipe serve
+  reconpipe serve --listen 0.0.0.0:8443`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// Step 1: Get flags
+		listen, _ := cmd.Flags().GetString("listen")
+
+		// Step 2: Config check
+		if cfg == nil {
+			return fmt.Errorf("config not loaded. Run 'reconpipe init' first to create config")
+		}
+		if listen == "" {
+			listen = cfg.Server.Listen
+		}
+
+		// Step 3: Open the store, shared by the API and queued scans and,
+		// between their calls, by other reconpipe commands
+		store, err := storage.NewSharedStore(cfg.DBPath)
+		if err != nil {
+			return fmt.Errorf("opening database: %w", err)
+		}
+		defer store.Close()
+
+		tokens, err := store.ListTokens()
+		if err != nil {
+			return fmt.Errorf("listing API tokens: %w", err)
+		}
+		if len(tokens) == 0 {
+			fmt.Println("[!] Warning: no API tokens exist yet — create one with 'reconpipe token create'")
+		}
+
+		// Step 4: Serve until interrupted
+		var grace time.Duration
+		if cfg.Server.ShutdownGrace != "" {
+			grace, _ = time.ParseDuration(cfg.Server.ShutdownGrace) // checked by config validation
+		}
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		go func() {
+			<-ctx.Done()
+			stop() // a second signal kills the process instead of waiting out the drain
+		}()
+
+		srv := server.New(server.Options{
+			Store:     store,
+			RateLimit: cfg.Server.RateLimit,
+			QueueSize: cfg.Server.QueueSize,
+			Tools:     serveToolStatus,
+			Scope:     cfg.ScopeDomains,
+			Policy:    *targetPolicy(cfg, false),
+			Grace:     grace,
+			Preempt:   cfg.Server.Preemption,
+			TLS: &server.TLSOptions{
+				CertFile:     cfg.Server.TLS.CertFile,
+				KeyFile:      cfg.Server.TLS.KeyFile,
+				SelfSigned:   cfg.Server.TLS.SelfSigned,
+				Hosts:        cfg.Server.TLS.Hosts,
+				ClientCAFile: cfg.Server.TLS.ClientCAFile,
+			},
+			Launch: func(ctx context.Context, job server.Job, stop <-chan struct{}) (*pipeline.PipelineResult, error) {
+				return runQueuedScan(ctx, store, job, stop)
+			},
+		})
+
+		// Step 5: Pick up config edits without a restart
+		active := cfg
+		err = config.Watch(ctx, cfgFile, func(next *config.Config) {
+			if reloadServeConfig(srv, active, next) {
+				active = next
+			}
+		}, func(err error) {
+			fmt.Printf("[!] Config reload rejected, keeping the current config: %v\n", err)
+		})
+		if err != nil {
+			fmt.Printf("[!] Warning: not watching the config for changes: %v\n", err)
+		}
+
+		return srv.Run(ctx, listen)
+	},
+}
+
+// serveToolStatus reports the scan tools' availability for /readyz.
+func serveToolStatus() []server.ToolStatus {
+	var out []server.ToolStatus
+	for _, r := range checkAllScanTools() {
+		out = append(out, server.ToolStatus{Name: r.name, Found: r.found, Required: r.required})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// pendingConfig holds a reloaded config until the next queued scan starts,
+// so a running scan never sees its settings change halfway through.
+var pendingConfig atomic.Pointer[config.Config]
+
+// reloadServeConfig applies next, reloaded from the config file, to a running
+// server. The API rate limit and scope change at once; everything else waits
+// in pendingConfig for the next scan. Settings bound at startup keep their
+// running values. Reports whether anything changed.
+func reloadServeConfig(srv *server.Server, prev, next *config.Config) bool {
+	var restart []string
+	if next.DBPath != prev.DBPath {
+		restart = append(restart, "db_path")
+		next.DBPath = prev.DBPath
+	}
+	if next.DBDriver != prev.DBDriver {
+		restart = append(restart, "db_driver")
+		next.DBDriver = prev.DBDriver
+	}
+	if next.Server.Listen != prev.Server.Listen {
+		restart = append(restart, "server.listen")
+		next.Server.Listen = prev.Server.Listen
+	}
+	if next.Server.QueueSize != prev.Server.QueueSize {
+		restart = append(restart, "server.queue_size")
+		next.Server.QueueSize = prev.Server.QueueSize
+	}
+	if next.Server.Preemption != prev.Server.Preemption {
+		restart = append(restart, "server.preemption")
+		next.Server.Preemption = prev.Server.Preemption
+	}
+	if next.Server.ShutdownGrace != prev.Server.ShutdownGrace {
+		restart = append(restart, "server.shutdown_grace")
+		next.Server.ShutdownGrace = prev.Server.ShutdownGrace
+	}
+	if !reflect.DeepEqual(next.Server.TLS, prev.Server.TLS) {
+		restart = append(restart, "server.tls")
+		next.Server.TLS = prev.Server.TLS
+	}
+	if len(restart) > 0 {
+		fmt.Printf("[!] Config: changes to %s need a restart; keeping the running values\n", strings.Join(restart, ", "))
+	}
+
+	changed := config.ChangedKeys(prev, next)
+	if len(changed) == 0 {
+		return false
+	}
+	srv.SetLimits(next.Server.RateLimit, next.ScopeDomains, *targetPolicy(next, false))
+	pendingConfig.Store(next)
+	fmt.Printf("[+] Config reloaded (%s); applies to scans started from now on\n", strings.Join(changed, ", "))
+	return true
+}
+
+// runQueuedScan runs a scan requested through the API, resolving the request
+// the way the scan command resolves its flags. A job with a scan directory
+// was interrupted by a shutdown and resumes there.
+func runQueuedScan(ctx context.Context, store storage.Store, job server.Job, stop <-chan struct{}) (*pipeline.PipelineResult, error) {
+	req, op := job.Request, job.Operator
+	if next := pendingConfig.Swap(nil); next != nil {
+		if err := applyConfig(next); err != nil {
+			fmt.Printf("[!] Reloaded config not applied, keeping the previous one: %v\n", err)
+		} else {
+			cfg = next
+		}
+	}
+	// Queued scans restored after a restart, and monitor's, did not pass
+	// the API's check
+	if err := pipeline.ValidateDomain(req.Target); err != nil {
+		return nil, err
+	}
+	// The scope may have narrowed since the scan was queued
+	scope := pipeline.ScopeConfig{AllowedDomains: cfg.ScopeDomains}
+	if err := scope.ValidateTarget(req.Target); err != nil {
+		return nil, err
+	}
+
+	severity := "critical,high,medium"
+	timeout := 2 * time.Hour
+	stageList := req.Stages
+	skipPDF := false
+	permutations := cfg.Discovery.Permutations.Enabled
+
+	if req.Preset != "" {
+		preset, err := pipeline.GetPreset(req.Preset)
+		if err != nil {
+			return nil, err
+		}
+		if len(stageList) == 0 {
+			stageList = preset.Stages
+		}
+		severity = preset.Severity
+		skipPDF = preset.SkipPDF
+		permutations = permutations || preset.Permutations
+	}
+	if req.Severity != "" {
+		severity = req.Severity
+	}
+
+	toolCheckResults := checkAllScanTools()
+	for _, r := range toolCheckResults {
+		if r.required && !r.found {
+			return nil, fmt.Errorf("required tool %q not found — install with: %s", r.name, r.installCmd)
+		}
+	}
+
+	probeFilter, vulnscanFilter := configuredFilters()
+	allStages := buildScanStages(store, scanStageOptions{
+		domain:             req.Target,
+		severity:           severity,
+		skipPDF:            skipPDF,
+		pdfEngine:          pdfEngineGo,
+		tlsxAvailable:      toolCheckResults["tlsx"].found,
+		cdncheckAvailable:  toolCheckResults["cdncheck"].found,
+		gowitnessAvailable: toolCheckResults["gowitness"].found,
+		nucleiAvailable:    toolCheckResults["nuclei"].found,
+		permutations:       permutations,
+		bruteforce:         cfg.Discovery.Bruteforce.Enabled,
+		zoneTransfer:       cfg.Discovery.ZoneTransfer,
+		dnsConsensus:       cfg.Discovery.DNSConsensus.Enabled,
+		probeFilter:        probeFilter,
+		vulnscanFilter:     vulnscanFilter,
+	})
+
+	pipelineCfg := pipeline.PipelineConfig{
+		Target:   req.Target,
+		Preset:   req.Preset,
+		Tag:      req.Tag,
+		Stages:   stageList,
+		Skip:     req.Skip,
+		ScanDir:  job.ScanDir,
+		Resume:   job.ScanDir != "",
+		Timeout:  timeout,
+		Operator: op,
+		Stop:     stop,
+		Policy:   targetPolicy(cfg, req.IgnorePolicy),
+		RunConfig: &models.RunConfig{
+			Version:        rootCmd.Version,
+			Preset:         req.Preset,
+			Tag:            req.Tag,
+			Stages:         stageList,
+			Skip:           req.Skip,
+			Severity:       severity,
+			Timeout:        timeout.String(),
+			SkipPDF:        skipPDF,
+			Permutations:   permutations,
+			Bruteforce:     cfg.Discovery.Bruteforce.Enabled,
+			ZoneTransfer:   cfg.Discovery.ZoneTransfer,
+			DNSConsensus:   cfg.Discovery.DNSConsensus.Enabled,
+			ProbeFilter:    cfg.Probe.Filter,
+			VulnscanFilter: cfg.Vulnscan.Filter,
+			FakeTools:      fakeToolsMode,
+			Config:         snapshotConfig(cfg),
+		},
+		OnStageDone: func(name string, index, total int, err error, elapsed time.Duration) {
+			if err != nil {
+				fmt.Printf("[!] %s: stage %d/%d %s FAILED (%s)\n",
+					req.Target, index+1, total, name, elapsed.Round(time.Millisecond))
+			} else {
+				fmt.Printf("[+] %s: stage %d/%d %s complete (%s)\n",
+					req.Target, index+1, total, name, elapsed.Round(time.Millisecond))
+			}
+		},
+	}
+
+	return pipeline.RunPipeline(ctx, pipelineCfg, allStages, store, cfg)
+}
+
+func init() {
+	serveCmd.Flags().String("listen", "", "Address to listen on (default: server.listen, or "+server.DefaultListen+")")
+	rootCmd.AddCommand(serveCmd)
+}
@@ -0,0 +1,275 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/hakim/reconpipe/internal/diff"
+	"github.com/hakim/reconpipe/internal/httpprobe"
+	"github.com/hakim/reconpipe/internal/stats"
+	"github.com/hakim/reconpipe/internal/storage"
+	"github.com/hakim/reconpipe/pkg/models"
+	"github.com/spf13/cobra"
+)
+
+var showCmd = &cobra.Command{
+	Use:   "show [scan ID]",
+	Short: "Print the results of a past scan",
+	Long: `Print what a scan found: its subdomains, open ports, live HTTP services and
+vulnerabilities, as tables in the terminal.
+
+Pick the scan by its ID, or a unique prefix of it as 'history' shows it, or
+with -d for the latest scan of a domain: the newest complete one, or the
+newest of any status when none completed.
+
+Results are read from the scan directory. Files missing from it, or the whole
+directory once it was deleted, are read from the copy the stages saved in
+the database.
+
+Use --json for the scan record and every result in one JSON document, in the
+shape of the API's /api/v1/scans/{id}/results.
+
+Examples:
+  reconpipe show 3f2a9c1e
+  reconpipe show -d example.com --latest
+  reconpipe show -d example.com --json | jq '.vulnerabilities[].name'`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// Step 1: Get flags
+		domain, _ := cmd.Flags().GetString("domain")
+		latest, _ := cmd.Flags().GetBool("latest")
+		asJSON, _ := cmd.Flags().GetBool("json")
+
+		switch {
+		case len(args) == 1 && (domain != "" || latest):
+			return fmt.Errorf("give either a scan ID or -d <domain>, not both")
+		case len(args) == 0 && domain == "":
+			return fmt.Errorf("either a scan ID or -d <domain> --latest is required")
+		}
+
+		// Step 2: Config check
+		if cfg == nil {
+			return fmt.Errorf("config not loaded. Run 'reconpipe init' first to create config")
+		}
+
+		// Step 3: Open the store and find the scan
+		store, err := storage.NewStore(cfg.DBPath)
+		if err != nil {
+			return fmt.Errorf("opening database: %w", err)
+		}
+		defer store.Close()
+
+		var scan *models.ScanMeta
+		if len(args) == 1 {
+			if scan, err = store.FindScan(args[0]); err != nil {
+				return fmt.Errorf("looking up scan: %w", err)
+			}
+			if scan == nil {
+				return fmt.Errorf("scan %q not found", args[0])
+			}
+		} else {
+			scans, err := store.ListScans(domain)
+			if err != nil {
+				return fmt.Errorf("listing scans for %s: %w", domain, err)
+			}
+			if scan = latestCompleteScan(scans); scan == nil {
+				return fmt.Errorf("no scans found for %s", domain)
+			}
+		}
+
+		// Step 4: Load the results, from the database where files are gone
+		snap, err := diff.LoadScanSnapshot(store, scan)
+		if err != nil {
+			return fmt.Errorf("loading results of scan %s: %w", scan.ID, err)
+		}
+
+		// Step 5: Print
+		if asJSON {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			return enc.Encode(map[string]any{
+				"scan":            scan,
+				"subdomains":      nonNil(snap.Subdomains),
+				"hosts":           nonNil(snap.Hosts),
+				"http_probes":     nonNil(snap.Probes),
+				"vulnerabilities": nonNil(snap.Vulnerabilities),
+			})
+		}
+		printScanHeader(scan)
+		printSubdomainTable(snap.Subdomains)
+		printPortTable(snap.Hosts)
+		printServiceTable(snap.Probes)
+		printVulnTable(snap.Vulnerabilities)
+		return nil
+	},
+}
+
+const showSeparator = "──────────────────────────────────────────────────────────────────────────────────────"
+
+// latestCompleteScan returns the newest complete scan of scans, which are
+// newest first, or the newest scan when none completed.
+func latestCompleteScan(scans []*models.ScanMeta) *models.ScanMeta {
+	for _, scan := range scans {
+		if scan.Status == models.StatusComplete {
+			return scan
+		}
+	}
+	if len(scans) > 0 {
+		return scans[0]
+	}
+	return nil
+}
+
+// nonNil returns s, or an empty slice for nil so JSON shows [] not null.
+func nonNil[T any](s []T) []T {
+	if s == nil {
+		return []T{}
+	}
+	return s
+}
+
+func printScanHeader(scan *models.ScanMeta) {
+	fmt.Printf("\nScan %s of %s\n", scan.ID, scan.Target)
+	fmt.Println(showSeparator)
+	started := scan.StartedAt.UTC().Format("2006-01-02 15:04 UTC")
+	if scan.CompletedAt != nil {
+		started += fmt.Sprintf(" (took %s)", scan.CompletedAt.Sub(scan.StartedAt).Round(time.Second))
+	}
+	op := scan.Operator
+	if op == "" {
+		op = "-"
+	}
+	dir := scan.ScanDir
+	if _, err := os.Stat(dir); err != nil {
+		dir += " (missing; results read from the database)"
+	}
+	fmt.Printf("  Status:    %s\n", formatStatus(scan.Status))
+	fmt.Printf("  Started:   %s\n", started)
+	fmt.Printf("  Operator:  %s\n", op)
+	fmt.Printf("  Stages:    %s\n", formatStages(scan.StagesRun))
+	fmt.Printf("  Scan dir:  %s\n", dir)
+}
+
+// printTable prints a titled table; rows line up under the header by
+// format. A table without rows prints "none".
+func printTable(title, format string, header []any, rows [][]any) {
+	fmt.Printf("\n%s (%d)\n", title, len(rows))
+	fmt.Println(showSeparator)
+	if len(rows) == 0 {
+		fmt.Println("  none")
+		return
+	}
+	fmt.Printf(format, header...)
+	fmt.Println(showSeparator)
+	for _, row := range rows {
+		fmt.Printf(format, row...)
+	}
+}
+
+func printSubdomainTable(subs []models.Subdomain) {
+	var rows [][]any
+	for _, s := range subs {
+		state := "resolved"
+		switch {
+		case s.IsDangling:
+			state = "dangling"
+		case !s.Resolved:
+			state = "unresolved"
+		case s.Wildcard:
+			state = "wildcard"
+		case s.IsCDN:
+			state = "cdn"
+			if s.CDNProvider != "" {
+				state += " (" + s.CDNProvider + ")"
+			}
+		}
+		rows = append(rows, []any{truncate(s.Name, 40), truncate(strings.Join(s.IPs, ", "), 32), s.Source, state})
+	}
+	printTable("Subdomains", "  %-40s  %-32s  %-14s  %s\n", []any{"Name", "IPs", "Source", "State"}, rows)
+}
+
+func printPortTable(hosts []models.Host) {
+	var rows [][]any
+	for _, h := range hosts {
+		name := "-"
+		if len(h.Subdomains) > 0 {
+			name = h.Subdomains[0]
+			if len(h.Subdomains) > 1 {
+				name += fmt.Sprintf(" (+%d)", len(h.Subdomains)-1)
+			}
+		}
+		for _, p := range h.Ports {
+			rows = append(rows, []any{truncate(name, 36), h.IP, fmt.Sprintf("%d/%s", p.Number, p.Protocol), p.Service, truncate(p.Version, 30)})
+		}
+	}
+	printTable("Open ports", "  %-36s  %-16s  %-9s  %-12s  %s\n", []any{"Host", "IP", "Port", "Service", "Version"}, rows)
+}
+
+// printServiceTable lists the probes that count as live under
+// probe.live_status, as the probe stage and its report count them, and
+// how many others answered.
+func printServiceTable(probes []models.HTTPProbe) {
+	var rows [][]any
+	for _, p := range probes {
+		if !httpprobe.IsLive(p, cfg.Probe.LiveStatus) {
+			continue
+		}
+		rows = append(rows, []any{truncate(p.URL, 44), p.StatusCode, truncate(p.Title, 30), truncate(strings.Join(p.Technologies, ", "), 40)})
+	}
+	printTable("Live services", "  %-44s  %-6v  %-30s  %s\n", []any{"URL", "Status", "Title", "Technologies"}, rows)
+	if others := len(probes) - len(rows); others > 0 {
+		fmt.Printf("  (%d other HTTP response(s) outside probe.live_status; see --json)\n", others)
+	}
+}
+
+// printVulnTable lists vulns most severe first.
+func printVulnTable(vulns []models.Vulnerability) {
+	sorted := slices.Clone(vulns)
+	slices.SortStableFunc(sorted, func(a, b models.Vulnerability) int {
+		return severityIndex(a.Severity) - severityIndex(b.Severity)
+	})
+	var rows [][]any
+	for _, v := range sorted {
+		where := v.MatchedAt
+		if where == "" {
+			where = v.Host
+		}
+		name := v.Name
+		if v.Noise {
+			name += " (noise)"
+		}
+		rows = append(rows, []any{v.Severity, truncate(name, 40), where})
+	}
+	printTable("Vulnerabilities", "  %-8s  %-40s  %s\n", []any{"Severity", "Name", "Matched at"}, rows)
+	fmt.Println()
+}
+
+// severityIndex is sev's position in stats.Severities, most severe first;
+// unknown severities sort last.
+func severityIndex(sev models.Severity) int {
+	if i := slices.Index(stats.Severities, sev); i >= 0 {
+		return i
+	}
+	return len(stats.Severities)
+}
+
+// truncate shortens s to at most max runes for a table cell, ending it with
+// an ellipsis.
+func truncate(s string, max int) string {
+	r := []rune(s)
+	if len(r) <= max {
+		return s
+	}
+	return string(r[:max-1]) + "…"
+}
+
+func init() {
+	showCmd.Flags().StringP("domain", "d", "", "Show the latest scan of this domain")
+	showCmd.Flags().Bool("latest", false, "Show the latest scan of --domain (the default with -d)")
+	showCmd.Flags().Bool("json", false, "Print the scan record and results as JSON")
+	rootCmd.AddCommand(showCmd)
+}
@@ -9,82 +9,179 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
-	"path/filepath"
+	"strings"
 
 	"github.com/hakim/reconpipe/internal/diff"
 	"github.com/hakim/reconpipe/internal/discovery"
+	"github.com/hakim/reconpipe/internal/filter"
 	"github.com/hakim/reconpipe/internal/httpprobe"
-	"github.com/hakim/reconpipe/internal/models"
 	"github.com/hakim/reconpipe/internal/pipeline"
 	"github.com/hakim/reconpipe/internal/portscan"
 	"github.com/hakim/reconpipe/internal/report"
 	"github.com/hakim/reconpipe/internal/storage"
+	"github.com/hakim/reconpipe/internal/urllist"
 	"github.com/hakim/reconpipe/internal/vulnscan"
+	"github.com/hakim/reconpipe/pkg/models"
 )
 
+// scanStageOptions carries the flag values and tool-check results the stage
+// closures need. scan.go and wizard.go fill it from their own flags/prompts so
+// neither has to re-run tool checks.
+type scanStageOptions struct {
+	domain             string
+	severity           string
+	skipPDF            bool
+	pdfEngine          string // pdfEngineGo or pdfEnginePython
+	pythonBinary       string // set when the python PDF engine found python
+	tlsxAvailable      bool
+	cdncheckAvailable  bool
+	gowitnessAvailable bool
+	nucleiAvailable    bool
+
+	// knownSubdomains are client-supplied hostnames merged into discovery
+	// with source "provided" (--known-subdomains).
+	knownSubdomains []string
+
+	// permutations enables altdns-style subdomain permutation in discovery.
+	permutations bool
+
+	// bruteforce resolves the configured wordlist under the target in
+	// discovery.
+	bruteforce bool
+
+	// zoneTransfer attempts AXFR against the target's nameservers.
+	zoneTransfer bool
+
+	// dnsConsensus resolves names through the configured resolvers and
+	// keeps only answers a quorum agrees on.
+	dnsConsensus bool
+
+	// ports and topPorts narrow the port scan (--ports, --top-ports); both
+	// unset scans every port.
+	ports    string
+	topPorts int
+
+	// udp also scans the configured UDP services (--udp).
+	udp bool
+
+	// probeFilter and vulnscanFilter select the targets of those stages
+	// (--probe-filter, --vulnscan-filter). Nil keeps every target.
+	probeFilter    *filter.Expr
+	vulnscanFilter *filter.Expr
+
+	// sharedPorts, set for multi-target runs, reuses the ports of IPs
+	// another target of the run already scanned.
+	sharedPorts *portscan.SharedScan
+
+	// compareDir, when set, is the scan the diff stage compares against
+	// instead of the previous scan for the domain. Replays set it to the
+	// scan being replayed.
+	compareDir string
+
+	// targetURLs is a client-supplied URL list (--targets-file). When set,
+	// discover and portscan only record the listed hosts and ports, probe
+	// takes the URLs as given, and vulnscan scans only what they serve.
+	targetURLs []string
+}
+
 // buildScanStages constructs the five canonical pipeline stages as closures
 // that capture all the runtime parameters they need.  The returned slice is
 // in canonical execution order: discover, portscan, probe, vulnscan, diff.
 //
-// Parameters mirror the local variables that scan.go computed from flags and
-// tool-check results so the wizard can pass the same values without re-running
-// tool checks.
-func buildScanStages(
-	domain string,
-	severity string,
-	skipPDF bool,
-	python3Available bool,
-	pythonBinary string,
-	tlsxAvailable bool,
-	cdncheckAvailable bool,
-	gowitnessAvailable bool,
-	nucleiAvailable bool,
-) []pipeline.Stage {
-
+// store is the caller's open scan database; bbolt holds an exclusive lock, so
+// stages must share it rather than open their own.
+func buildScanStages(store storage.Store, opts scanStageOptions) []pipeline.Stage {
 	discoverStage := pipeline.Stage{
 		Name: "discover",
 		Run: func(ctx context.Context, scanDir string) error {
-			if err := storage.EnsureDir(filepath.Join(scanDir, "raw")); err != nil {
+			if err := storage.EnsureDir(storage.RawDir(scanDir)); err != nil {
 				return fmt.Errorf("ensuring raw dir: %w", err)
 			}
-			if err := storage.EnsureDir(filepath.Join(scanDir, "reports")); err != nil {
+			if err := storage.EnsureDir(storage.ReportsDir(scanDir)); err != nil {
 				return fmt.Errorf("ensuring reports dir: %w", err)
 			}
 
+			var words []string
+			if opts.bruteforce {
+				var err error
+				if words, err = bruteforceWords(); err != nil {
+					return err
+				}
+			}
+
 			discoveryCfg := discovery.DiscoveryConfig{
 				SubfinderThreads: cfg.RateLimits.SubfinderThreads,
 				SubfinderPath:    "",
+				Engine:           cfg.Discovery.Engine,
+				AmassPath:        "",
 				TlsxPath:         "",
 				DigPath:          "",
-				SkipTlsx:         !tlsxAvailable,
+				SkipTlsx:         !opts.tlsxAvailable,
+				KnownSubdomains:  opts.knownSubdomains,
+
+				Permutations:        opts.permutations,
+				PermutationPatterns: cfg.Discovery.Permutations.Patterns,
+				MaxPermutations:     cfg.Discovery.Permutations.MaxCandidates,
+				Bruteforce:          opts.bruteforce,
+				BruteforceWords:     words,
+				BruteforceMutate:    cfg.Discovery.Bruteforce.Mutate,
+				MaxBruteforce:       cfg.Discovery.Bruteforce.MaxCandidates,
+				ZoneTransfer:        opts.zoneTransfer,
+				WildcardFilter:      cfg.Discovery.WildcardFilter,
+				DNSHealth:           !cfg.Discovery.DNSHealth.Skip,
+				DNSHealthSubdomains: cfg.Discovery.DNSHealth.Subdomains,
+				DNSHealthResolver:   cfg.Discovery.DNSHealth.Resolver,
+				Consensus:           consensusConfig(opts.dnsConsensus),
+				Resolve:             resolveOptions(),
+				Passive:             passiveOptions(),
+			}
+			skipDigOnlyChecks(&discoveryCfg, "    ")
+
+			var result *discovery.DiscoveryResult
+			var err error
+			if len(opts.targetURLs) > 0 {
+				fmt.Println("    [>] Targets file given — resolving its hosts instead of discovering subdomains")
+				result, err = urllist.Discovery(ctx, opts.domain, opts.targetURLs, "", discoveryCfg.Consensus, discoveryCfg.Resolve)
+			} else {
+				result, err = discovery.RunDiscovery(ctx, opts.domain, discoveryCfg)
 			}
-
-			result, err := discovery.RunDiscovery(ctx, domain, discoveryCfg)
 			if err != nil {
 				return fmt.Errorf("discovery pipeline: %w", err)
 			}
 
 			fmt.Printf("    [>] Found %d unique subdomains (%d resolved, %d dangling)\n",
 				result.UniqueCount, result.ResolvedCount, result.DanglingCount)
+			if n := len(discovery.UnconfirmedSubdomains(result.Subdomains)); n > 0 {
+				fmt.Printf("    [!] %d subdomain(s) had answers below the DNS consensus quorum\n", n)
+			}
 
-			reportPath := filepath.Join(scanDir, "reports", "subdomains.md")
+			reportPath := storage.ReportPath(scanDir, "subdomains.md")
 			if err := report.WriteSubdomainReport(result, reportPath); err != nil {
 				fmt.Printf("    [!] Warning: failed to write subdomain report: %v\n", err)
 			}
+			if err := report.WriteSubdomainHTMLReport(result, storage.ReportPath(scanDir, "subdomains.html")); err != nil {
+				fmt.Printf("    [!] Warning: failed to write HTML subdomain report: %v\n", err)
+			}
 
-			rawPath := filepath.Join(scanDir, "raw", "subdomains.json")
+			rawPath := storage.RawPath(scanDir, "subdomains.json")
 			rawData, err := json.MarshalIndent(result, "", "  ")
 			if err != nil {
 				return fmt.Errorf("marshaling subdomains: %w", err)
 			}
-			return os.WriteFile(rawPath, rawData, 0644)
+			if err := storage.WriteFileAtomic(rawPath, rawData, 0644); err != nil {
+				return err
+			}
+			saveStageResults(store, opts.domain, scanDir, "    ", func(scanID string) error {
+				return store.SaveSubdomains(scanID, result.Subdomains, result.CollectedAt)
+			})
+			return nil
 		},
 	}
 
 	portscanStage := pipeline.Stage{
 		Name: "portscan",
 		Run: func(ctx context.Context, scanDir string) error {
-			subdomainsPath := filepath.Join(scanDir, "raw", "subdomains.json")
+			subdomainsPath := storage.RawPath(scanDir, "subdomains.json")
 			subData, err := os.ReadFile(subdomainsPath)
 			if err != nil {
 				return fmt.Errorf("reading subdomains.json (run discover first): %w", err)
@@ -102,51 +199,87 @@ func buildScanStages(
 				}
 			}
 
-			if len(resolved) == 0 {
+			if len(resolved) == 0 && len(opts.targetURLs) == 0 {
 				fmt.Println("    [!] No resolved subdomains with IPs — skipping port scan")
-				empty := portscan.PortScanResult{Target: domain, Hosts: []models.Host{}}
+				empty := portscan.PortScanResult{Target: opts.domain, Hosts: []models.Host{}}
 				rawData, _ := json.MarshalIndent(empty, "", "  ")
-				rawPath := filepath.Join(scanDir, "raw", "ports.json")
-				return os.WriteFile(rawPath, rawData, 0644)
+				rawPath := storage.RawPath(scanDir, "ports.json")
+				return storage.WriteFileAtomic(rawPath, rawData, 0644)
 			}
 
-			fmt.Printf("    [>] Scanning %d resolved subdomains\n", len(resolved))
+			exclusions, err := cfg.Exclusions()
+			if err != nil {
+				return fmt.Errorf("loading exclusions: %w", err)
+			}
 
 			portScanCfg := portscan.PortScanConfig{
+				Target:          opts.domain,
 				CdncheckPath:    "",
 				MasscanPath:     "",
+				NaabuPath:       "",
 				NmapPath:        "",
 				MasscanRate:     cfg.RateLimits.MasscanRate,
 				NmapMaxParallel: cfg.RateLimits.NmapMaxParallel,
-				SkipCDNCheck:    !cdncheckAvailable,
-			}
-
-			result, err := portscan.RunPortScan(ctx, resolved, portScanCfg)
-			if err != nil {
-				return fmt.Errorf("port scan pipeline: %w", err)
+				SkipCDNCheck:    !opts.cdncheckAvailable,
+				Scanner:         cfg.PortScan.Scanner,
+				PortRange:       opts.ports,
+				TopPorts:        opts.topPorts,
+				UDP:             opts.udp,
+				UDPPorts:        cfg.PortScan.UDP.Ports,
+				SkipMailChecks:  cfg.PortScan.MailChecks.Skip,
+				MailCheck:       mailCheckConfig(),
+				Exclude:         exclusions,
+				GeoIPPath:       cfg.PortScan.GeoIPDB,
+				Shared:          opts.sharedPorts,
+				Profiles:        cfg.ProviderProfiles.Set(),
+			}
+
+			var result *portscan.PortScanResult
+			if len(opts.targetURLs) > 0 {
+				// The list names the ports; nothing else is in scope
+				fmt.Println("    [>] Targets file given — recording its ports instead of scanning")
+				result = urllist.PortScan(opts.domain, opts.targetURLs, discoveryResult.Subdomains, exclusions)
+			} else {
+				fmt.Printf("    [>] Scanning %d resolved subdomains\n", len(resolved))
+				if result, err = portscan.RunPortScan(ctx, resolved, portScanCfg); err != nil {
+					return fmt.Errorf("port scan pipeline: %w", err)
+				}
 			}
 
 			fmt.Printf("    [>] CDN: %d filtered, scanned: %d, open ports: %d\n",
 				result.CDNCount, result.ScannedCount, result.TotalPorts)
 
-			reportPath := filepath.Join(scanDir, "reports", "ports.md")
+			reportPath := storage.ReportPath(scanDir, "ports.md")
 			if err := report.WritePortReport(result, reportPath); err != nil {
 				fmt.Printf("    [!] Warning: failed to write port report: %v\n", err)
 			}
+			if err := report.WritePortHTMLReport(result, storage.ReportPath(scanDir, "ports.html")); err != nil {
+				fmt.Printf("    [!] Warning: failed to write HTML port report: %v\n", err)
+			}
+			exposurePath := storage.ReportPath(scanDir, "exposure.md")
+			if err := report.WriteExposureReport(result, nil, exposurePath); err != nil {
+				fmt.Printf("    [!] Warning: failed to write exposure report: %v\n", err)
+			}
 
-			rawPath := filepath.Join(scanDir, "raw", "ports.json")
+			rawPath := storage.RawPath(scanDir, "ports.json")
 			rawData, err := json.MarshalIndent(result, "", "  ")
 			if err != nil {
 				return fmt.Errorf("marshaling port scan result: %w", err)
 			}
-			return os.WriteFile(rawPath, rawData, 0644)
+			if err := storage.WriteFileAtomic(rawPath, rawData, 0644); err != nil {
+				return err
+			}
+			saveStageResults(store, opts.domain, scanDir, "    ", func(scanID string) error {
+				return store.SaveHosts(scanID, result.Hosts, result.CollectedAt)
+			})
+			return nil
 		},
 	}
 
 	probeStage := pipeline.Stage{
 		Name: "probe",
 		Run: func(ctx context.Context, scanDir string) error {
-			portsPath := filepath.Join(scanDir, "raw", "ports.json")
+			portsPath := storage.RawPath(scanDir, "ports.json")
 			portsData, err := os.ReadFile(portsPath)
 			if err != nil {
 				return fmt.Errorf("reading ports.json (run portscan first): %w", err)
@@ -160,16 +293,17 @@ func buildScanStages(
 			hosts := hostsWithOpenPorts(portResult.Hosts)
 			if len(hosts) == 0 {
 				fmt.Println("    [!] No hosts with open ports — skipping HTTP probe")
-				empty := httpprobe.HTTPProbeResult{Target: domain, Probes: []models.HTTPProbe{}}
+				empty := httpprobe.HTTPProbeResult{Target: opts.domain, Probes: []models.HTTPProbe{}}
 				rawData, _ := json.MarshalIndent(empty, "", "  ")
-				rawPath := filepath.Join(scanDir, "raw", "http-probes.json")
-				return os.WriteFile(rawPath, rawData, 0644)
+				rawPath := storage.RawPath(scanDir, "http-probes.json")
+				return storage.WriteFileAtomic(rawPath, rawData, 0644)
 			}
 
 			fmt.Printf("    [>] Probing %d hosts\n", len(hosts))
 
-			screenshotDir := filepath.Join(scanDir, "screenshots")
-			skipScreenshots := !gowitnessAvailable
+			screenshotDir := storage.ScreenshotsDir(scanDir)
+			engine := screenshotEngine(opts.gowitnessAvailable)
+			skipScreenshots := engine == ""
 			if !skipScreenshots {
 				if err := storage.EnsureDir(screenshotDir); err != nil {
 					fmt.Printf("    [!] Warning: could not create screenshot dir: %v\n", err)
@@ -177,48 +311,93 @@ func buildScanStages(
 				}
 			}
 
+			exclusions, err := cfg.Exclusions()
+			if err != nil {
+				return fmt.Errorf("loading exclusions: %w", err)
+			}
+
 			probeCfg := httpprobe.HTTPProbeConfig{
+				Target:           opts.domain,
 				HttpxPath:        "",
 				GowitnessPath:    "",
 				HttpxThreads:     cfg.RateLimits.HttpxThreads,
 				GowitnessThreads: 6,
 				ScreenshotDir:    screenshotDir,
 				SkipScreenshots:  skipScreenshots,
+				Screenshots:      screenshotOptions(engine),
+				BodyScan:         bodyScanOptions(),
+				LiveStatus:       cfg.Probe.LiveStatus,
+				Exclude:          exclusions,
+				SkipProtocols:    cfg.Probe.Protocols.Skip,
+				Protocols:        protocolCheckConfig(),
+				CheckMethods:     cfg.Probe.MethodChecks.Enabled,
+				Methods:          methodCheckConfig(),
+				SkipRetry:        cfg.Probe.Retry.Skip,
+				RetryThreads:     cfg.Probe.Retry.Threads,
+				RetryRateLimit:   cfg.Probe.Retry.RateLimit,
+				SkipClones:       cfg.Probe.Clones.Skip,
+				SkipOriginCheck:  cfg.Probe.OriginCheck.Skip,
+				Filter:           opts.probeFilter,
+				ChunkSize:        cfg.Memory.ProbeChunkSize(),
+				URLs:             opts.targetURLs,
+				Profiles:         cfg.ProviderProfiles.Set(),
+			}
+			if !probeCfg.SkipOriginCheck {
+				probeCfg.OriginHistory = originHistory(store, opts.domain, scanDir)
 			}
 
 			probeResult, err := httpprobe.RunHTTPProbe(ctx, hosts, probeCfg)
 			if err != nil {
 				return fmt.Errorf("HTTP probe pipeline: %w", err)
 			}
-			if probeResult.Target == "" {
-				probeResult.Target = domain
-			}
 
-			fmt.Printf("    [>] Live services: %d\n", probeResult.LiveCount)
+			fmt.Printf("    [>] Live services: %d (%d unique applications)\n", probeResult.LiveCount, probeResult.UniqueApps)
 
-			reportPath := filepath.Join(scanDir, "reports", "http-probes.md")
+			reportPath := storage.ReportPath(scanDir, "http-probes.md")
 			if err := report.WriteHTTPProbeReport(probeResult, reportPath); err != nil {
 				fmt.Printf("    [!] Warning: failed to write HTTP probe report: %v\n", err)
 			}
+			if err := report.WriteHTTPProbeHTMLReport(probeResult, storage.ReportPath(scanDir, "http-probes.html")); err != nil {
+				fmt.Printf("    [!] Warning: failed to write HTML HTTP probe report: %v\n", err)
+			}
+			exposurePath := storage.ReportPath(scanDir, "exposure.md")
+			if err := report.WriteExposureReport(&portResult, probeResult, exposurePath); err != nil {
+				fmt.Printf("    [!] Warning: failed to write exposure report: %v\n", err)
+			}
+
+			if len(probeResult.Origins) > 0 {
+				if err := recordOrigins(scanDir, &portResult, probeResult.Origins); err != nil {
+					return fmt.Errorf("recording origin candidates: %w", err)
+				}
+				saveStageResults(store, opts.domain, scanDir, "    ", func(scanID string) error {
+					return store.SaveHosts(scanID, portResult.Hosts, portResult.CollectedAt)
+				})
+			}
 
-			rawPath := filepath.Join(scanDir, "raw", "http-probes.json")
+			rawPath := storage.RawPath(scanDir, "http-probes.json")
 			rawData, err := json.MarshalIndent(probeResult, "", "  ")
 			if err != nil {
 				return fmt.Errorf("marshaling HTTP probe result: %w", err)
 			}
-			return os.WriteFile(rawPath, rawData, 0644)
+			if err := storage.WriteFileAtomic(rawPath, rawData, 0644); err != nil {
+				return err
+			}
+			saveStageResults(store, opts.domain, scanDir, "    ", func(scanID string) error {
+				return store.SaveProbes(scanID, probeResult.Probes, probeResult.CollectedAt)
+			})
+			return nil
 		},
 	}
 
 	vulnscanStage := pipeline.Stage{
 		Name: "vulnscan",
 		Run: func(ctx context.Context, scanDir string) error {
-			if !nucleiAvailable {
+			if !opts.nucleiAvailable {
 				fmt.Println("    [!] nuclei not found — skipping vulnerability scan")
 				return nil
 			}
 
-			portsPath := filepath.Join(scanDir, "raw", "ports.json")
+			portsPath := storage.RawPath(scanDir, "ports.json")
 			portsData, err := os.ReadFile(portsPath)
 			if err != nil {
 				return fmt.Errorf("reading ports.json (run portscan first): %w", err)
@@ -228,7 +407,7 @@ func buildScanStages(
 				return fmt.Errorf("parsing ports.json: %w", err)
 			}
 
-			probesPath := filepath.Join(scanDir, "raw", "http-probes.json")
+			probesPath := storage.RawPath(scanDir, "http-probes.json")
 			probesData, err := os.ReadFile(probesPath)
 			if err != nil {
 				return fmt.Errorf("reading http-probes.json (run probe first): %w", err)
@@ -238,48 +417,85 @@ func buildScanStages(
 				return fmt.Errorf("parsing http-probes.json: %w", err)
 			}
 
+			// A URL list limits the scan to the listed URLs' responses, not
+			// every name and address behind them
+			hosts := portResult.Hosts
+			if len(opts.targetURLs) > 0 {
+				hosts = nil
+			}
+
 			fmt.Printf("    [>] Scanning %d hosts, %d HTTP probes (severity: %s)\n",
-				len(portResult.Hosts), len(probeResult.Probes), severity)
+				len(hosts), len(probeResult.Probes), opts.severity)
 
-			vulnCfg := vulnscan.VulnScanConfig{
-				NucleiPath: "",
-				Severity:   severity,
-				Threads:    cfg.RateLimits.NucleiThreads,
-				RateLimit:  cfg.RateLimits.NucleiRateLimit,
+			exclusions, err := cfg.Exclusions()
+			if err != nil {
+				return fmt.Errorf("loading exclusions: %w", err)
 			}
 
-			result, err := vulnscan.RunVulnScan(ctx, portResult.Hosts, probeResult.Probes, vulnCfg)
+			vulnCfg := vulnscan.VulnScanConfig{
+				Target:            opts.domain,
+				NucleiPath:        "",
+				Severity:          opts.severity,
+				Threads:           cfg.RateLimits.NucleiThreads,
+				RateLimit:         cfg.RateLimits.NucleiRateLimit,
+				Nuclei:            nucleiOptions(),
+				Exclude:           exclusions,
+				BatchSize:         cfg.Vulnscan.BatchSize,
+				ChunkSize:         cfg.Vulnscan.ChunkSize,
+				ListFile:          cfg.Vulnscan.ListFile,
+				Filter:            opts.vulnscanFilter,
+				SeverityOverrides: cfg.Vulnscan.Overrides(),
+				Profiles:          cfg.ProviderProfiles.Set(),
+			}
+
+			result, err := vulnscan.RunVulnScan(ctx, hosts, probeResult.Probes, vulnCfg)
 			if err != nil {
 				return fmt.Errorf("vulnerability scan pipeline: %w", err)
 			}
-			if result.Target == "" {
-				result.Target = domain
+			result.AddFindings(loadStageFindings(scanDir))
+			mapping, err := cfg.ComplianceMapping()
+			if err != nil {
+				return fmt.Errorf("loading compliance mapping: %w", err)
+			}
+			if n := mapping.Tag(result.Vulnerabilities); n > 0 {
+				fmt.Printf("    [>] %d findings mapped to compliance requirements\n", n)
+			}
+			if n := cfg.Vulnscan.Noise.Classifier().Classify(result.Vulnerabilities); n > 0 {
+				fmt.Printf("    [>] %d findings classified as noise\n", n)
 			}
 
 			fmt.Printf("    [>] Total findings: %d\n", result.TotalCount)
+			if result.Partial {
+				fmt.Printf("    [!] Partial: deadline reached (%d targets skipped)\n", len(result.SkippedTargets))
+			}
 
-			reportPath := filepath.Join(scanDir, "reports", "vulns.md")
+			reportPath := storage.ReportPath(scanDir, "vulns.md")
 			if err := report.WriteVulnReport(result, reportPath); err != nil {
 				fmt.Printf("    [!] Warning: failed to write vuln report: %v\n", err)
 			}
+			if err := report.WriteVulnHTMLReport(result, storage.ReportPath(scanDir, "vulns.html")); err != nil {
+				fmt.Printf("    [!] Warning: failed to write HTML vuln report: %v\n", err)
+			}
 
-			rawPath := filepath.Join(scanDir, "raw", "vulns.json")
+			rawPath := storage.RawPath(scanDir, "vulns.json")
 			rawData, err := json.MarshalIndent(result, "", "  ")
 			if err != nil {
 				return fmt.Errorf("marshaling vuln result: %w", err)
 			}
-			if err := os.WriteFile(rawPath, rawData, 0644); err != nil {
+			if err := storage.WriteFileAtomic(rawPath, rawData, 0644); err != nil {
 				return fmt.Errorf("writing vulns.json: %w", err)
 			}
+			saveStageResults(store, opts.domain, scanDir, "    ", func(scanID string) error {
+				return store.SaveVulns(scanID, result.Vulnerabilities, result.CollectedAt)
+			})
 
-			jsonlPath := filepath.Join(scanDir, "raw", "nuclei-output.jsonl")
+			jsonlPath := storage.RawPath(scanDir, "nuclei-output.jsonl")
 			if err := writeNucleiJSONL(result.Vulnerabilities, jsonlPath); err != nil {
 				fmt.Printf("    [!] Warning: failed to write nuclei JSONL: %v\n", err)
 			}
 
-			if !skipPDF && python3Available {
-				pdfPath := filepath.Join(scanDir, "reports", "vulns.pdf")
-				generateNucPDF(ctx, pythonBinary, jsonlPath, pdfPath, domain)
+			if !opts.skipPDF {
+				writeVulnPDF(ctx, opts.pdfEngine, opts.pythonBinary, result, jsonlPath, storage.ReportPath(scanDir, "vulns.pdf"), opts.domain)
 			}
 
 			return nil
@@ -289,21 +505,18 @@ func buildScanStages(
 	diffStage := pipeline.Stage{
 		Name: "diff",
 		Run: func(ctx context.Context, scanDir string) error {
-			currentSnap, err := diff.LoadSnapshot(scanDir)
+			currentSnap, err := loadScanSnapshot(store, opts.domain, scanDir)
 			if err != nil {
 				return fmt.Errorf("loading current snapshot: %w", err)
 			}
 
-			diffStore, err := storage.NewStore(cfg.DBPath)
-			if err != nil {
-				return fmt.Errorf("opening database for diff: %w", err)
-			}
-			defer diffStore.Close()
-
-			prevDir, err := findPreviousScanDir(domain, scanDir)
-			if err != nil {
-				fmt.Printf("    [!] Warning: could not find previous scan: %v\n", err)
-				return nil
+			prevDir := opts.compareDir
+			if prevDir == "" {
+				prevDir, err = findPreviousScanDir(store, opts.domain, scanDir)
+				if err != nil {
+					fmt.Printf("    [!] Warning: could not find previous scan: %v\n", err)
+					return nil
+				}
 			}
 			if prevDir == "" {
 				fmt.Println("    [>] No previous scan found — skipping diff")
@@ -312,46 +525,117 @@ func buildScanStages(
 
 			fmt.Printf("    [>] Comparing against %s\n", prevDir)
 
-			previousSnap, err := diff.LoadSnapshot(prevDir)
+			previousSnap, err := loadScanSnapshot(store, opts.domain, prevDir)
 			if err != nil {
 				return fmt.Errorf("loading previous snapshot: %w", err)
 			}
 
-			result := diff.ComputeDiff(currentSnap, previousSnap)
+			// Sections whose raw files are unchanged since the last diff of
+			// this scan, e.g. when only vulnscan was rerun, are reused
+			sums, err := storage.LoadChecksums(scanDir, rootCmd.Version)
+			if err != nil {
+				fmt.Printf("    [!] Warning: %v\n", err)
+			}
+			var prior *diff.DiffResult
+			if data, err := os.ReadFile(storage.RawPath(scanDir, "diff.json")); err == nil {
+				prior = new(diff.DiffResult)
+				if err := json.Unmarshal(data, prior); err != nil {
+					fmt.Printf("    [!] Warning: previous diff.json is corrupt, recomputing every section: %v\n", err)
+					prior = nil
+				}
+			}
+			result, reused, err := diff.ComputeDiffCached(currentSnap, previousSnap, prior, sums)
+			if err != nil {
+				return fmt.Errorf("computing diff: %w", err)
+			}
+			if len(reused) > 0 {
+				fmt.Printf("    [>] Reusing the diff of unchanged %s\n", strings.Join(reused, ", "))
+			}
+			dateDiff(store, opts.domain, scanDir, result, "    ")
 
-			diffReportPath := filepath.Join(scanDir, "reports", "diff.md")
+			diffReportPath := storage.ReportPath(scanDir, "diff.md")
 			if err := report.WriteDiffReport(result, diffReportPath); err != nil {
 				fmt.Printf("    [!] Warning: failed to write diff report: %v\n", err)
 			}
+			if err := report.WriteDiffHTMLReport(result, storage.ReportPath(scanDir, "diff.html")); err != nil {
+				fmt.Printf("    [!] Warning: failed to write HTML diff report: %v\n", err)
+			}
 
-			danglingReportPath := filepath.Join(scanDir, "reports", "dangling-dns.md")
-			if err := report.WriteDanglingDNSReport(currentSnap.Subdomains, danglingReportPath); err != nil {
+			danglingReportPath := storage.ReportPath(scanDir, "dangling-dns.md")
+			if err := report.WriteDanglingDNSReport(currentSnap.Subdomains, currentSnap.CollectedAt["subdomains.json"], danglingReportPath); err != nil {
 				fmt.Printf("    [!] Warning: failed to write dangling DNS report: %v\n", err)
 			}
 
-			rawPath := filepath.Join(scanDir, "raw", "diff.json")
+			rawPath := storage.RawPath(scanDir, "diff.json")
 			rawData, err := json.MarshalIndent(result, "", "  ")
 			if err != nil {
 				return fmt.Errorf("marshaling diff result: %w", err)
 			}
-			if err := os.WriteFile(rawPath, rawData, 0644); err != nil {
+			if err := storage.WriteFileAtomic(rawPath, rawData, 0644); err != nil {
 				return fmt.Errorf("writing diff.json: %w", err)
 			}
+			if err := sums.Save(scanDir); err != nil {
+				fmt.Printf("    [!] Warning: %v\n", err)
+			}
+			signDiffReports(ctx, scanDir, "    ")
 
 			fmt.Printf("    [>] Subdomains: +%d new, -%d removed | Ports: +%d new, -%d closed | Vulns: +%d new, -%d resolved\n",
 				len(result.NewSubdomains), len(result.RemovedSubdomains),
 				len(result.NewPorts), len(result.ClosedPorts),
 				len(result.NewVulns), len(result.ResolvedVulns))
 
+			syncDanglingIssues(ctx, store, opts.domain, scanDir, result, "    ")
+
 			return nil
 		},
 	}
 
-	return []pipeline.Stage{
+	stages := []pipeline.Stage{
 		discoverStage,
 		portscanStage,
 		probeStage,
 		vulnscanStage,
 		diffStage,
 	}
+	for i := range stages {
+		stages[i].Run = recordStageReports(stages[i].Name, stages[i].Run)
+	}
+	return stages
+}
+
+// stageReports are the reports each stage writes from its raw output.
+var stageReports = map[string][]string{
+	"discover": {"subdomains.md", "subdomains.html"},
+	"portscan": {"ports.md", "ports.html", "exposure.md"},
+	"probe":    {"http-probes.md", "http-probes.html", "exposure.md", "ports.md", "ports.html"},
+	"vulnscan": {"vulns.md", "vulns.html"},
+	"diff":     {"diff.md", "diff.html", "dangling-dns.md"},
+}
+
+// recordStageReports wraps a stage so that, once it succeeds, the checksums
+// of the raw files behind its reports are recorded and `reconpipe report`
+// leaves those reports alone until the files change.
+func recordStageReports(name string, run pipeline.StageFunc) pipeline.StageFunc {
+	return func(ctx context.Context, scanDir string) error {
+		if err := run(ctx, scanDir); err != nil {
+			return err
+		}
+		if err := report.RecordReports(scanDir, rootCmd.Version, stageReports[name]...); err != nil {
+			fmt.Printf("    [!] Warning: recording report checksums: %v\n", err)
+		}
+		return nil
+	}
+}
+
+// saveStageResults saves a stage's results in the scan database under the
+// scan recorded for scanDir, so diffs and history still have them once the
+// scan directory is deleted. Failures only warn: the raw file holds them.
+func saveStageResults(store storage.Store, domain, scanDir, indent string, save func(scanID string) error) {
+	scan, err := scanForDir(store, domain, scanDir)
+	if err == nil && scan != nil {
+		err = save(scan.ID)
+	}
+	if err != nil {
+		fmt.Printf("%s[!] Warning: saving results to the database: %v\n", indent, err)
+	}
 }
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/hakim/reconpipe/internal/stats"
+	"github.com/hakim/reconpipe/internal/storage"
+	"github.com/spf13/cobra"
+)
+
+var statsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Show aggregate statistics for a domain",
+	Long: `Summarize a target's scan results for management reporting: top technologies,
+top open ports, vulnerability severity distribution, how many subdomains each
+discovery source found (and how many of those resolved), and the average scan
+duration.
+
+By default only the latest scan is summarized. With --all every scan in the
+history is aggregated; a subdomain, open port, technology or finding seen by
+several scans is counted once.
+
+Use --format json for machine-readable output.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// Step 1: Get flags
+		domain, _ := cmd.Flags().GetString("domain")
+		all, _ := cmd.Flags().GetBool("all")
+		top, _ := cmd.Flags().GetInt("top")
+		format, _ := cmd.Flags().GetString("format")
+
+		if format != "table" && format != "json" {
+			return fmt.Errorf("unknown format %q (supported: table, json)", format)
+		}
+
+		// Step 2: Config check
+		if cfg == nil {
+			return fmt.Errorf("config not loaded. Run 'reconpipe init' first to create config")
+		}
+
+		// Step 3: List scans (newest first)
+		store, err := storage.NewStore(cfg.DBPath)
+		if err != nil {
+			return fmt.Errorf("opening database: %w", err)
+		}
+		defer store.Close()
+		scans, err := store.ListScans(domain)
+		if err != nil {
+			return fmt.Errorf("listing scans for %s: %w", domain, err)
+		}
+
+		if len(scans) == 0 {
+			fmt.Printf("No scan history found for %s\n", domain)
+			return nil
+		}
+		if !all {
+			scans = scans[:1]
+		}
+
+		// Step 4: Aggregate results
+		st, err := stats.Compute(store, domain, scans, top)
+		if err != nil {
+			return err
+		}
+
+		// Step 5: Print
+		if format == "json" {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			return enc.Encode(st)
+		}
+		printStats(st, all)
+		return nil
+	},
+}
+
+// printStats renders st as the human-readable tables.
+func printStats(st *stats.Stats, all bool) {
+	const separator = "──────────────────────────────────────────────────────────────"
+
+	if all {
+		fmt.Printf("\nStatistics for %s (%d scans, %s to %s)\n", st.Target, st.Scans,
+			st.FirstScan.UTC().Format("2006-01-02"), st.LastScan.UTC().Format("2006-01-02"))
+	} else {
+		fmt.Printf("\nStatistics for %s (latest scan, %s)\n", st.Target, st.LastScan.UTC().Format("2006-01-02 15:04"))
+	}
+	fmt.Println(separator)
+	fmt.Printf("  Subdomains:     %d\n", st.Subdomains)
+	fmt.Printf("  Hosts:          %d with open ports\n", st.Hosts)
+	if st.CompletedScans > 0 {
+		fmt.Printf("  Avg duration:   %s (%d completed)\n", st.AvgDuration, st.CompletedScans)
+	} else {
+		fmt.Printf("  Avg duration:   - (no completed scans)\n")
+	}
+
+	fmt.Printf("\n  Severity distribution\n")
+	fmt.Println(separator)
+	for _, sev := range stats.Severities {
+		fmt.Printf("  %-10s  %d\n", sev, st.Severities[sev])
+	}
+
+	fmt.Printf("\n  Top technologies\n")
+	fmt.Println(separator)
+	if len(st.Technologies) == 0 {
+		fmt.Println("  -")
+	}
+	for _, t := range st.Technologies {
+		fmt.Printf("  %-40s  %d\n", t.Name, t.Count)
+	}
+
+	fmt.Printf("\n  Top open ports\n")
+	fmt.Println(separator)
+	if len(st.Ports) == 0 {
+		fmt.Println("  -")
+	} else {
+		fmt.Printf("  %-6s  %-20s  %s\n", "Port", "Service", "Hosts")
+	}
+	for _, p := range st.Ports {
+		service := p.Service
+		if service == "" {
+			service = "-"
+		}
+		fmt.Printf("  %-6d  %-20s  %d\n", p.Port, service, p.Hosts)
+	}
+
+	fmt.Printf("\n  Discovery sources\n")
+	fmt.Println(separator)
+	if len(st.Sources) == 0 {
+		fmt.Println("  -")
+	} else {
+		fmt.Printf("  %-20s  %-10s  %-10s  %s\n", "Source", "Subdomains", "Resolved", "Rate")
+	}
+	for _, s := range st.Sources {
+		fmt.Printf("  %-20s  %-10d  %-10d  %.0f%%\n", s.Source, s.Subdomains, s.Resolved, s.ResolveRate())
+	}
+	fmt.Println()
+}
+
+func init() {
+	statsCmd.Flags().StringP("domain", "d", "", "Target domain (required)")
+	statsCmd.Flags().Bool("all", false, "Aggregate every scan instead of only the latest")
+	statsCmd.Flags().Int("top", 10, "Number of technologies and ports to list (0 for all)")
+	statsCmd.Flags().StringP("format", "f", "table", "Output format: table, json")
+	statsCmd.MarkFlagRequired("domain")
+	rootCmd.AddCommand(statsCmd)
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/hakim/reconpipe/internal/server"
+	"github.com/hakim/reconpipe/internal/storage"
+	"github.com/hakim/reconpipe/pkg/models"
+	"github.com/spf13/cobra"
+)
+
+var tokenCmd = &cobra.Command{
+	Use:   "token",
+	Short: "Manage API tokens for 'reconpipe serve'",
+	Long: `Create, list and revoke the tokens that authenticate API requests.
+
+Scopes:
+  read   list targets, scans, results and jobs
+  scan   everything read allows, plus queueing scans
+
+Only a hash of each token is stored, so the token is printed once, when it is
+created. A revoked token is rejected from the next request; the server can
+keep running.`,
+}
+
+var tokenCreateCmd = &cobra.Command{
+	Use:   "create",
+	Short: "Create an API token and print it",
+	Long: `Create an API token and print it. The token cannot be shown again.
+
+Examples:
+  reconpipe token create --name dashboard
+  reconpipe token create --name ci --scope scan --rate-limit 10`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// Step 1: Get flags
+		name, _ := cmd.Flags().GetString("name")
+		scope, _ := cmd.Flags().GetString("scope")
+		rateLimit, _ := cmd.Flags().GetInt("rate-limit")
+
+		// Step 2: Config check
+		if cfg == nil {
+			return fmt.Errorf("config not loaded. Run 'reconpipe init' first to create config")
+		}
+
+		// Step 3: Generate the token
+		secret, token, err := server.GenerateToken(name, scope, rateLimit, operator)
+		if err != nil {
+			return err
+		}
+
+		// Step 4: Store it
+		store, err := storage.NewStore(cfg.DBPath)
+		if err != nil {
+			return fmt.Errorf("opening database: %w", err)
+		}
+		defer store.Close()
+
+		if err := store.SaveToken(token); err != nil {
+			return fmt.Errorf("saving token: %w", err)
+		}
+		recordAudit(store, "token.create", "", "", fmt.Sprintf("%s (%s, %s)", token.Name, shortScanID(token.ID), token.Scope))
+
+		fmt.Printf("[+] Created %s token %q (ID: %s)\n", token.Scope, token.Name, shortScanID(token.ID))
+		fmt.Println("[!] Store it now — it will not be shown again:")
+		fmt.Println()
+		fmt.Printf("    %s\n", secret)
+		fmt.Println()
+		return nil
+	},
+}
+
+var tokenListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List API tokens",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// Step 1: Config check
+		if cfg == nil {
+			return fmt.Errorf("config not loaded. Run 'reconpipe init' first to create config")
+		}
+
+		// Step 2: Open bbolt store
+		store, err := storage.NewStore(cfg.DBPath)
+		if err != nil {
+			return fmt.Errorf("opening database: %w", err)
+		}
+		defer store.Close()
+
+		// Step 3: List tokens
+		tokens, err := store.ListTokens()
+		if err != nil {
+			return fmt.Errorf("listing tokens: %w", err)
+		}
+		if len(tokens) == 0 {
+			fmt.Println("No API tokens found")
+			return nil
+		}
+
+		// Step 4: Print formatted table
+		const separator = "──────────────────────────────────────────────────────────────────────────────────────"
+
+		fmt.Println()
+		fmt.Println(separator)
+		fmt.Printf("  %-12s  %-16s  %-6s  %-10s  %-17s  %-17s  %s\n", "ID", "Name", "Scope", "Rate/min", "Created", "Last used", "Status")
+		fmt.Println(separator)
+		for _, t := range tokens {
+			rate := "default"
+			if t.RateLimit > 0 {
+				rate = fmt.Sprint(t.RateLimit)
+			}
+			status := "active"
+			if t.RevokedAt != nil {
+				status = "revoked " + t.RevokedAt.UTC().Format("2006-01-02")
+			}
+			fmt.Printf("  %-12s  %-16s  %-6s  %-10s  %-17s  %-17s  %s\n",
+				shortScanID(t.ID), t.Name, t.Scope, rate,
+				t.CreatedAt.UTC().Format("2006-01-02 15:04"), formatOptionalTime(t.LastUsedAt), status)
+		}
+		fmt.Println(separator)
+		fmt.Printf("Total: %d token(s)\n\n", len(tokens))
+		return nil
+	},
+}
+
+var tokenRevokeCmd = &cobra.Command{
+	Use:   "revoke <token-id>",
+	Short: "Revoke an API token",
+	Long: `Revoke an API token by its ID or the shortened ID shown by 'reconpipe token list'.
+The record is kept, marked revoked, so 'token list' still shows who had access.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// Step 1: Config check
+		if cfg == nil {
+			return fmt.Errorf("config not loaded. Run 'reconpipe init' first to create config")
+		}
+
+		// Step 2: Open bbolt store
+		store, err := storage.NewStore(cfg.DBPath)
+		if err != nil {
+			return fmt.Errorf("opening database: %w", err)
+		}
+		defer store.Close()
+
+		// Step 3: Find and revoke
+		token, err := store.FindToken(args[0])
+		if err != nil {
+			return err
+		}
+		if token == nil {
+			return fmt.Errorf("no token with ID %s", args[0])
+		}
+		if token.RevokedAt != nil {
+			fmt.Printf("[*] Token %q was already revoked\n", token.Name)
+			return nil
+		}
+
+		now := time.Now()
+		token.RevokedAt = &now
+		if err := store.SaveToken(token); err != nil {
+			return fmt.Errorf("saving token: %w", err)
+		}
+		recordAudit(store, "token.revoke", "", "", fmt.Sprintf("%s (%s)", token.Name, shortScanID(token.ID)))
+
+		fmt.Printf("[+] Revoked token %q (ID: %s)\n", token.Name, shortScanID(token.ID))
+		return nil
+	},
+}
+
+// formatOptionalTime formats t for a table cell, or "-" when unset.
+func formatOptionalTime(t *time.Time) string {
+	if t == nil {
+		return "-"
+	}
+	return t.UTC().Format("2006-01-02 15:04")
+}
+
+func init() {
+	tokenCreateCmd.Flags().String("name", "", "What the token is for, e.g. the client using it (required)")
+	tokenCreateCmd.Flags().String("scope", models.TokenScopeRead, "Token scope: read or scan")
+	tokenCreateCmd.Flags().Int("rate-limit", 0, "Requests per minute (default: server.rate_limit)")
+	tokenCreateCmd.MarkFlagRequired("name")
+
+	tokenCmd.AddCommand(tokenCreateCmd, tokenListCmd, tokenRevokeCmd)
+	rootCmd.AddCommand(tokenCmd)
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/hakim/reconpipe/internal/integrity"
+	"github.com/hakim/reconpipe/internal/storage"
+	"github.com/hakim/reconpipe/pkg/models"
+	"github.com/spf13/cobra"
+)
+
+var validateScanCmd = &cobra.Command{
+	Use:   "validate-scan",
+	Short: "Check a scan directory for missing, corrupt or inconsistent files",
+	Long: `Check a scan directory for completeness and consistency:
+
+  raw         each stage recorded as run left its raw output, and every raw
+              JSON file parses
+  report      every report its raw data supports exists, and its headline
+              counts match the raw data
+  screenshot  screenshots referenced by http-probes.json or linked from a
+              report are on disk
+  record      the database record lists the stages whose output is present,
+              and a scan is not left marked running
+  temp        no temporary files of interrupted writes are left behind
+
+With --repair the fixable issues are fixed: reports are regenerated, references
+to missing screenshots dropped, temporary files removed and the record brought
+in step with the directory. A stage whose output is missing or corrupt is
+marked not run, so 'reconpipe scan --resume' reruns it. --repair is refused in
+read-only mode; checking is allowed.
+
+Exits non-zero when issues remain.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// Step 1: Get flags
+		domain, _ := cmd.Flags().GetString("domain")
+		scanDir, _ := cmd.Flags().GetString("scan-dir")
+		repair, _ := cmd.Flags().GetBool("repair")
+
+		if cfg == nil {
+			return fmt.Errorf("config not loaded. Run 'reconpipe init' first to create config")
+		}
+		if repair && readOnly {
+			return fmt.Errorf("--repair is disabled in read-only mode")
+		}
+
+		// Step 2: Resolve scan directory
+		if scanDir == "" {
+			if domain == "" {
+				return fmt.Errorf("either --domain or --scan-dir is required")
+			}
+			latestDir, err := storage.FindLatestScanDir(cfg.ScanDir, domain)
+			if err != nil {
+				return fmt.Errorf("finding latest scan directory: %w", err)
+			}
+			scanDir = latestDir
+		}
+
+		// Step 3: Open bbolt store and find the scan's record
+		store, err := storage.NewStore(cfg.DBPath)
+		if err != nil {
+			return fmt.Errorf("opening database: %w", err)
+		}
+		defer store.Close()
+
+		meta, err := findScanByDir(store, domain, scanDir)
+		if err != nil {
+			return err
+		}
+
+		// Step 4: Check
+		fmt.Printf("[*] Validating %s\n", scanDir)
+		issues, err := integrity.Check(scanDir, meta)
+		if err != nil {
+			return fmt.Errorf("checking scan directory: %w", err)
+		}
+		if len(issues) == 0 {
+			fmt.Println("[+] No issues found")
+			return nil
+		}
+		printIssues(issues, repair)
+
+		if !repair {
+			fixable := 0
+			for _, issue := range issues {
+				if repairable(issue) {
+					fixable++
+				}
+			}
+			if fixable > 0 {
+				fmt.Printf("[*] %d of %d issue(s) can be fixed with --repair\n", fixable, len(issues))
+			}
+			return fmt.Errorf("%d issue(s) found", len(issues))
+		}
+
+		// Step 5: Repair and check again. Setting a corrupt raw file aside
+		// can make reports regenerable, so repairs run until none is left.
+		var fixed []integrity.Issue
+		remaining := issues
+		for pass := 0; pass < 3 && slices.ContainsFunc(remaining, repairable); pass++ {
+			var passFixed []integrity.Issue
+			meta, passFixed, err = integrity.Repair(scanDir, meta, remaining, store, rootCmd.Version)
+			for _, issue := range passFixed {
+				fmt.Printf("[+] Repaired %s: %s\n", issueSubject(issue), issue.Repair)
+			}
+			fixed = append(fixed, passFixed...)
+			if err != nil {
+				return fmt.Errorf("repairing: %w", err)
+			}
+			if remaining, err = integrity.Check(scanDir, meta); err != nil {
+				return fmt.Errorf("checking scan directory: %w", err)
+			}
+		}
+		if meta != nil && len(fixed) > 0 {
+			recordAudit(store, "scan.repair", meta.Target, meta.ID, fmt.Sprintf("%d issue(s) repaired in %s", len(fixed), scanDir))
+		}
+		if repairedAny(fixed, integrity.KindReport, integrity.KindScreenshot) {
+			resignDiffReport(cmd.Context(), scanDir)
+		}
+
+		if len(remaining) > 0 {
+			fmt.Println("[!] Remaining issues:")
+			printIssues(remaining, false)
+			return fmt.Errorf("%d issue(s) remain after repair", len(remaining))
+		}
+		if meta != nil && meta.Status == models.StatusInterrupted {
+			fmt.Printf("[*] Resume the scan with: reconpipe scan -d %s --resume --scan-dir %s\n", meta.Target, scanDir)
+		}
+		fmt.Println("[+] Scan directory is consistent")
+		return nil
+	},
+}
+
+// printIssues lists issues, with the repair each would get unless repairing
+// is already underway.
+func printIssues(issues []integrity.Issue, repairing bool) {
+	for _, issue := range issues {
+		fmt.Printf("[!] %s: %s\n", issueSubject(issue), issue.Problem)
+		if issue.Repair != "" && !repairing {
+			fmt.Printf("    [>] --repair: %s\n", issue.Repair)
+		}
+	}
+}
+
+// repairable reports whether --repair can fix issue.
+func repairable(issue integrity.Issue) bool {
+	return issue.Repair != ""
+}
+
+// repairedAny reports whether an issue of one of kinds was repaired.
+func repairedAny(fixed []integrity.Issue, kinds ...integrity.Kind) bool {
+	return slices.ContainsFunc(fixed, func(issue integrity.Issue) bool {
+		return slices.Contains(kinds, issue.Kind)
+	})
+}
+
+// issueSubject names what an issue is about: its file, or the record.
+func issueSubject(issue integrity.Issue) string {
+	if issue.Path == "" {
+		return "database record"
+	}
+	return issue.Path
+}
+
+func init() {
+	validateScanCmd.Flags().StringP("domain", "d", "", "Target domain (uses the latest scan directory)")
+	validateScanCmd.Flags().String("scan-dir", "", "Scan directory to validate (overrides --domain)")
+	validateScanCmd.Flags().Bool("repair", false, "Fix the issues that can be fixed")
+	rootCmd.AddCommand(validateScanCmd)
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/hakim/reconpipe/internal/signing"
+	"github.com/hakim/reconpipe/internal/storage"
+	"github.com/spf13/cobra"
+)
+
+var verifyCmd = &cobra.Command{
+	Use:   "verify [file...]",
+	Short: "Check the detached signatures of diff reports",
+	Long: `Verify the detached signatures written next to a scan's diff.md and diff.json
+when signing is configured. Without file arguments the reports of the scan given
+by --scan-dir (or the latest scan of --domain) are checked.
+
+The signing method and key come from the signing section of the config. A host
+that only verifies needs the public key: set signing.public_key, or pass
+--public-key to check ed25519 signatures without a config.
+
+Exits non-zero when any file is unsigned, modified after signing, or signed by
+another key.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// Step 1: Get flags
+		domain, _ := cmd.Flags().GetString("domain")
+		scanDir, _ := cmd.Flags().GetString("scan-dir")
+		publicKey, _ := cmd.Flags().GetString("public-key")
+
+		// Step 2: Build the verifier
+		var signer *signing.Signer
+		var err error
+		switch {
+		case publicKey != "":
+			signer, err = signing.New(signing.MethodEd25519, "", publicKey, "")
+		case cfg != nil:
+			signer, err = cfg.Signing.Signer()
+		}
+		if err != nil {
+			return fmt.Errorf("signing: %w", err)
+		}
+		if signer == nil {
+			return fmt.Errorf("signing is not configured: set signing.method in the config or pass --public-key")
+		}
+
+		// Step 3: Resolve the files to check
+		files := args
+		if len(files) == 0 {
+			if scanDir == "" {
+				if domain == "" {
+					return fmt.Errorf("either --domain, --scan-dir or file arguments are required")
+				}
+				if cfg == nil {
+					return fmt.Errorf("config not loaded. Run 'reconpipe init' first to create config")
+				}
+				latestDir, err := storage.FindLatestScanDir(cfg.ScanDir, domain)
+				if err != nil {
+					return fmt.Errorf("finding latest scan directory: %w", err)
+				}
+				scanDir = latestDir
+			}
+			for _, path := range signedReports(scanDir) {
+				if _, err := os.Stat(path); err == nil {
+					files = append(files, path)
+				}
+			}
+			if len(files) == 0 {
+				return fmt.Errorf("no diff reports in %s", scanDir)
+			}
+		}
+
+		// Step 4: Verify each file
+		failed := 0
+		for _, path := range files {
+			who, err := signer.Verify(cmd.Context(), path)
+			switch {
+			case errors.Is(err, signing.ErrNoSignature):
+				fmt.Printf("[!] %s: not signed (no %s)\n", path, signer.SignaturePath(path))
+				failed++
+			case err != nil:
+				fmt.Printf("[!] %s: %v\n", path, err)
+				failed++
+			default:
+				fmt.Printf("[+] %s: good signature (%s)\n", path, who)
+			}
+		}
+
+		if failed > 0 {
+			return fmt.Errorf("%d of %d file(s) failed verification", failed, len(files))
+		}
+		fmt.Printf("[+] %d file(s) verified\n", len(files))
+		return nil
+	},
+}
+
+// signedReports lists the files that are signed when signing is configured.
+func signedReports(scanDir string) []string {
+	return []string{
+		storage.ReportPath(scanDir, "diff.md"),
+		storage.RawPath(scanDir, "diff.json"),
+	}
+}
+
+// signDiffReports writes detached signatures for the diff reports of scanDir
+// that exist. It does nothing unless signing is configured; failures are
+// printed as warnings, since the reports themselves are already written.
+func signDiffReports(ctx context.Context, scanDir, indent string) {
+	if cfg == nil {
+		return
+	}
+	signer, err := cfg.Signing.Signer()
+	if err != nil {
+		fmt.Printf("%s[!] Warning: signing: %v\n", indent, err)
+		return
+	}
+	if signer == nil {
+		return
+	}
+	for _, path := range signedReports(scanDir) {
+		if _, err := os.Stat(path); err != nil {
+			continue
+		}
+		if sigPath, err := signer.Sign(ctx, path); err != nil {
+			fmt.Printf("%s[!] Warning: failed to sign %s: %v\n", indent, path, err)
+		} else {
+			fmt.Printf("%s[+] Signed %s\n", indent, sigPath)
+		}
+	}
+}
+
+// resignDiffReport signs a regenerated diff.md, but only when diff.json,
+// which it was rebuilt from, still carries a good signature: a hand-edited
+// diff.json must not launder its changes into a freshly signed report.
+func resignDiffReport(ctx context.Context, scanDir string) {
+	if cfg == nil {
+		return
+	}
+	signer, err := cfg.Signing.Signer()
+	if err != nil || signer == nil {
+		return
+	}
+	reports := signedReports(scanDir)
+	mdPath, jsonPath := reports[0], reports[1]
+	if _, err := os.Stat(mdPath); err != nil {
+		return
+	}
+	if _, err := signer.Verify(ctx, jsonPath); err != nil {
+		fmt.Printf("[!] Warning: not re-signing %s: %s: %v\n", mdPath, jsonPath, err)
+		return
+	}
+	if sigPath, err := signer.Sign(ctx, mdPath); err != nil {
+		fmt.Printf("[!] Warning: failed to sign %s: %v\n", mdPath, err)
+	} else {
+		fmt.Printf("[+] Signed %s\n", sigPath)
+	}
+}
+
+func init() {
+	verifyCmd.Flags().StringP("domain", "d", "", "Target domain (uses the latest scan directory)")
+	verifyCmd.Flags().String("scan-dir", "", "Scan directory whose diff reports to check (overrides --domain)")
+	verifyCmd.Flags().String("public-key", "", "ed25519 PEM public key to verify with (overrides the config)")
+	rootCmd.AddCommand(verifyCmd)
+}
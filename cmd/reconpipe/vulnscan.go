@@ -1,22 +1,26 @@
 package main
 
 import (
-	"bufio"
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
 	"os"
 	"os/exec"
-	"path/filepath"
 	"time"
 
+	"github.com/hakim/reconpipe/internal/discovery"
+	"github.com/hakim/reconpipe/internal/filter"
 	"github.com/hakim/reconpipe/internal/httpprobe"
-	"github.com/hakim/reconpipe/internal/models"
+	"github.com/hakim/reconpipe/internal/netprobe"
+	"github.com/hakim/reconpipe/internal/pipeline"
 	"github.com/hakim/reconpipe/internal/portscan"
 	"github.com/hakim/reconpipe/internal/report"
 	"github.com/hakim/reconpipe/internal/storage"
 	"github.com/hakim/reconpipe/internal/tools"
+	"github.com/hakim/reconpipe/internal/urllist"
 	"github.com/hakim/reconpipe/internal/vulnscan"
+	"github.com/hakim/reconpipe/pkg/models"
 	"github.com/spf13/cobra"
 )
 
@@ -33,16 +37,33 @@ Results are saved to:
   - {scan_dir}/reports/vulns.md        (markdown report)
   - {scan_dir}/raw/vulns.json          (structured JSON)
   - {scan_dir}/raw/nuclei-output.jsonl (raw nuclei JSONL for tooling)
-  - {scan_dir}/reports/vulns.pdf       (PDF report, if python3 available)
+  - {scan_dir}/reports/vulns.pdf       (PDF report, unless --skip-pdf)
 
-Scan metadata is updated in the configured database.`,
+The PDF is rendered in-process. --pdf-engine python hands it to the Nuc-pdf
+Python tool instead, as older versions did; it is skipped when python3 is not
+installed.
+
+Scan metadata is updated in the configured database.
+
+With --targets-file, a client-supplied list of URLs is scanned instead, as a
+new scan of --domain: its hosts and ports are recorded without discovery or
+port scanning, the URLs are probed as given, and nuclei runs against what they
+serve. The scan directory holds the usual raw JSON and reports, so report,
+diff and history work as for any other scan.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		// Step 1: Get flags
 		domain, _ := cmd.Flags().GetString("domain")
 		scanDir, _ := cmd.Flags().GetString("scan-dir")
 		severity, _ := cmd.Flags().GetString("severity")
 		skipPDF, _ := cmd.Flags().GetBool("skip-pdf")
+		pdfEngine, _ := cmd.Flags().GetString("pdf-engine")
 		timeout, _ := cmd.Flags().GetDuration("timeout")
+		targetsFile, _ := cmd.Flags().GetString("targets-file")
+		ignorePolicy, _ := cmd.Flags().GetBool("ignore-policy")
+
+		if targetsFile != "" && scanDir != "" {
+			return fmt.Errorf("--targets-file starts a new scan and cannot be combined with --scan-dir")
+		}
 
 		// Step 2: Pre-flight checks
 		// nuclei is required — hard error if missing
@@ -57,14 +78,10 @@ Scan metadata is updated in the configured database.`,
 			return fmt.Errorf("required tool 'nuclei' not found. Install with: %s", nucleiTool.InstallCmd)
 		}
 
-		// python3 is optional — only needed for PDF generation
-		python3Available := false
-		pythonBinary := ""
-		if !skipPDF {
-			python3Available, pythonBinary = detectPython()
-			if !python3Available {
-				fmt.Println("[!] Warning: python3/python not found, PDF report generation will be skipped")
-			}
+		// python3 is optional — only needed by the python PDF engine
+		pythonBinary, err := resolvePDFEngine(pdfEngine, skipPDF)
+		if err != nil {
+			return err
 		}
 
 		// Step 3: Verify config was loaded
@@ -72,9 +89,18 @@ Scan metadata is updated in the configured database.`,
 			return fmt.Errorf("config not loaded. Run 'reconpipe init' first to create config")
 		}
 
+		filterSrc, vulnFilter, err := stageFilter(cmd, "filter", cfg.Vulnscan.Filter, filter.ProbeFields)
+		if err != nil {
+			return err
+		}
+
+		if targetsFile != "" {
+			return vulnscanTargetsFile(domain, targetsFile, severity, skipPDF, pdfEngine, pythonBinary, timeout, ignorePolicy, filterSrc, vulnFilter)
+		}
+
 		// Step 4: Determine scan directory
 		if scanDir == "" {
-			latestDir, err := findLatestScanDir(cfg.ScanDir, domain)
+			latestDir, err := storage.FindLatestScanDir(cfg.ScanDir, domain)
 			if err != nil {
 				return fmt.Errorf("finding latest scan directory: %w. Run 'reconpipe probe -d %s' first", err, domain)
 			}
@@ -84,7 +110,7 @@ Scan metadata is updated in the configured database.`,
 		fmt.Printf("[*] Using scan directory: %s\n", scanDir)
 
 		// Step 5: Read http-probes.json from prior probe scan
-		probesPath := filepath.Join(scanDir, "raw", "http-probes.json")
+		probesPath := storage.RawPath(scanDir, "http-probes.json")
 		probesData, err := os.ReadFile(probesPath)
 		if err != nil {
 			return fmt.Errorf("reading http-probes.json: %w. Run 'reconpipe probe -d %s' first", err, domain)
@@ -96,7 +122,7 @@ Scan metadata is updated in the configured database.`,
 		}
 
 		// Step 6: Read ports.json for host data
-		portsPath := filepath.Join(scanDir, "raw", "ports.json")
+		portsPath := storage.RawPath(scanDir, "ports.json")
 		portsData, err := os.ReadFile(portsPath)
 		if err != nil {
 			return fmt.Errorf("reading ports.json: %w. Run 'reconpipe portscan -d %s' first", err, domain)
@@ -114,11 +140,25 @@ Scan metadata is updated in the configured database.`,
 		defer cancel()
 
 		// Step 8: Build VulnScanConfig
+		exclusions, err := cfg.Exclusions()
+		if err != nil {
+			return fmt.Errorf("loading exclusions: %w", err)
+		}
+
 		vulnCfg := vulnscan.VulnScanConfig{
-			NucleiPath: "", // resolve from PATH
-			Severity:   severity,
-			Threads:    cfg.RateLimits.NucleiThreads,
-			RateLimit:  cfg.RateLimits.NucleiRateLimit,
+			Target:            domain,
+			NucleiPath:        "", // resolve from PATH
+			Severity:          severity,
+			Threads:           cfg.RateLimits.NucleiThreads,
+			RateLimit:         cfg.RateLimits.NucleiRateLimit,
+			Nuclei:            nucleiOptions(),
+			Exclude:           exclusions,
+			BatchSize:         cfg.Vulnscan.BatchSize,
+			ChunkSize:         cfg.Vulnscan.ChunkSize,
+			ListFile:          cfg.Vulnscan.ListFile,
+			Filter:            vulnFilter,
+			SeverityOverrides: cfg.Vulnscan.Overrides(),
+			Profiles:          cfg.ProviderProfiles.Set(),
 		}
 
 		fmt.Printf("[*] Starting vulnerability scan for %s (severity: %s)\n", domain, severity)
@@ -129,41 +169,58 @@ Scan metadata is updated in the configured database.`,
 			return fmt.Errorf("vulnerability scan pipeline failed: %w", err)
 		}
 
-		// Ensure target is set to the requested domain
-		if result.Target == "" {
-			result.Target = domain
+		// Carry over findings from discovery (AXFR) and portscan (mail checks)
+		result.AddFindings(loadStageFindings(scanDir))
+
+		// Tag findings with the framework requirements they bear on
+		mapping, err := cfg.ComplianceMapping()
+		if err != nil {
+			return fmt.Errorf("loading compliance mapping: %w", err)
+		}
+		if n := mapping.Tag(result.Vulnerabilities); n > 0 {
+			fmt.Printf("[*] %d findings mapped to compliance requirements\n", n)
+		}
+
+		// Set informational noise apart from the findings to act on
+		if n := cfg.Vulnscan.Noise.Classifier().Classify(result.Vulnerabilities); n > 0 {
+			fmt.Printf("[*] %d findings classified as noise\n", n)
 		}
 
 		// Step 10: Write markdown report
-		reportPath := filepath.Join(scanDir, "reports", "vulns.md")
+		reportPath := storage.ReportPath(scanDir, "vulns.md")
 		if err := report.WriteVulnReport(result, reportPath); err != nil {
 			// Warn but do not fail — raw data is still saved below
 			fmt.Printf("[!] Warning: failed to write markdown report: %v\n", err)
 		} else {
 			fmt.Printf("[+] Report written to %s\n", reportPath)
 		}
+		htmlPath := storage.ReportPath(scanDir, "vulns.html")
+		if err := report.WriteVulnHTMLReport(result, htmlPath); err != nil {
+			fmt.Printf("[!] Warning: failed to write HTML report: %v\n", err)
+		} else {
+			fmt.Printf("[+] HTML report written to %s\n", htmlPath)
+		}
 
 		// Step 11: Save structured JSON
-		rawPath := filepath.Join(scanDir, "raw", "vulns.json")
+		rawPath := storage.RawPath(scanDir, "vulns.json")
 		rawData, err := json.MarshalIndent(result, "", "  ")
 		if err != nil {
 			return fmt.Errorf("marshaling raw output: %w", err)
 		}
-		if err := os.WriteFile(rawPath, rawData, 0644); err != nil {
+		if err := storage.WriteFileAtomic(rawPath, rawData, 0644); err != nil {
 			return fmt.Errorf("writing raw output: %w", err)
 		}
 
 		// Step 12: Save nuclei-compatible JSONL for downstream tooling (e.g. Nuc-pdf)
-		jsonlPath := filepath.Join(scanDir, "raw", "nuclei-output.jsonl")
+		jsonlPath := storage.RawPath(scanDir, "nuclei-output.jsonl")
 		if err := writeNucleiJSONL(result.Vulnerabilities, jsonlPath); err != nil {
 			// Non-fatal — PDF generation will fail gracefully if file is missing
 			fmt.Printf("[!] Warning: failed to write nuclei JSONL: %v\n", err)
 		}
 
-		// Step 13: Generate PDF report via Nuc-pdf Python tool
-		if !skipPDF && python3Available {
-			pdfPath := filepath.Join(scanDir, "reports", "vulns.pdf")
-			generateNucPDF(ctx, pythonBinary, jsonlPath, pdfPath, domain)
+		// Step 13: Generate PDF report
+		if !skipPDF {
+			writeVulnPDF(ctx, pdfEngine, pythonBinary, result, jsonlPath, storage.ReportPath(scanDir, "vulns.pdf"), domain)
 		}
 
 		// Step 14: Update scan metadata in bbolt
@@ -202,12 +259,26 @@ Scan metadata is updated in the configured database.`,
 			if err := store.SaveScan(targetScan); err != nil {
 				return fmt.Errorf("updating scan metadata: %w", err)
 			}
+			if err := store.SaveVulns(targetScan.ID, result.Vulnerabilities, result.CollectedAt); err != nil {
+				fmt.Printf("[!] Warning: failed to save findings to the database: %v\n", err)
+			}
 
 			fmt.Printf("[+] Scan metadata updated (ID: %s)\n", targetScan.ID)
 		} else {
 			fmt.Println("[!] Warning: Could not find scan record to update in database")
 		}
 
+		scanID := ""
+		if targetScan != nil {
+			scanID = targetScan.ID
+		}
+		recordAudit(store, "vulnscan", domain, scanID, scanDir)
+		if n, err := pipeline.AttachNotes(store, domain, scanDir); err != nil {
+			fmt.Printf("[!] Warning: could not attach analyst notes: %v\n", err)
+		} else if n > 0 {
+			fmt.Printf("[+] %d analyst note(s) attached to this scan\n", n)
+		}
+
 		// Step 15: Print final summary with per-severity counts
 		fmt.Println()
 		fmt.Printf("[+] Vulnerability scan complete!\n")
@@ -217,10 +288,13 @@ Scan metadata is updated in the configured database.`,
 				fmt.Printf("    %-10s %d\n", sev+":", count)
 			}
 		}
+		if result.Partial {
+			fmt.Printf("    [!] Partial: the --timeout deadline cut the scan short (%d targets skipped)\n", len(result.SkippedTargets))
+		}
 		fmt.Printf("    Report: %s\n", reportPath)
 		fmt.Printf("    Raw JSON: %s\n", rawPath)
 
-		return nil
+		return policyError(checkFailPolicy(scanDir))
 	},
 }
 
@@ -229,11 +303,114 @@ func init() {
 	vulnscanCmd.Flags().String("scan-dir", "", "Path to existing scan directory (auto-detects latest if empty)")
 	vulnscanCmd.Flags().String("severity", "critical,high,medium", "Nuclei severity filter (comma-separated)")
 	vulnscanCmd.Flags().Bool("skip-pdf", false, "Skip PDF report generation")
+	vulnscanCmd.Flags().String("pdf-engine", pdfEngineGo, "PDF generator: go (built in) or python (the Nuc-pdf Python tool)")
 	vulnscanCmd.Flags().Duration("timeout", 60*time.Minute, "Overall timeout")
+	vulnscanCmd.Flags().String("targets-file", "", "File of URLs (one per line) to probe and scan as a new scan, without discovery or port scanning")
+	vulnscanCmd.Flags().String("filter", "", `Scan only targets matching this expression, e.g. 'status_code == 200 && tech contains "wordpress"' (overrides vulnscan.filter)`)
+	vulnscanCmd.Flags().Bool("ignore-policy", false, "With --targets-file: scan even if the target's cooldown has not passed or another scan of it is running")
 	vulnscanCmd.MarkFlagRequired("domain")
 	rootCmd.AddCommand(vulnscanCmd)
 }
 
+// targetsFileStages are the stages run for a URL list. discover and portscan
+// only record the listed hosts and ports.
+var targetsFileStages = []string{"discover", "portscan", "probe", "vulnscan", "diff"}
+
+// vulnscanTargetsFile scans the URLs listed in path as a new scan of domain.
+// pythonBinary is empty unless the python PDF engine runs. vulnFilter, parsed
+// from filterSrc, selects the targets nuclei gets.
+func vulnscanTargetsFile(domain, path, severity string, skipPDF bool, pdfEngine, pythonBinary string, timeout time.Duration, ignorePolicy bool, filterSrc string, vulnFilter *filter.Expr) error {
+	urls, err := urllist.Load(path)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("[*] Loaded %d URLs from %s\n", len(urls), path)
+
+	if len(cfg.ScopeDomains) > 0 {
+		scopeCfg := pipeline.ScopeConfig{AllowedDomains: cfg.ScopeDomains}
+		if err := scopeCfg.ValidateTarget(domain); err != nil {
+			return fmt.Errorf("scope check failed: %w", err)
+		}
+		if err := validateURLScope(&scopeCfg, urls); err != nil {
+			return err
+		}
+	}
+
+	// httpx probes the URLs and dig resolves their hosts, unless resolution
+	// is native; nuclei was checked by the caller
+	for _, req := range []tools.ToolRequirement{
+		{Name: "httpx", Binary: "httpx", Required: true, InstallCmd: "go install -v github.com/projectdiscovery/httpx/cmd/httpx@latest"},
+		{Name: "dig", Binary: "dig", Required: !tools.NativeDNS(), InstallCmd: "apt install dnsutils (or brew install bind on macOS)"},
+	} {
+		if req.Required && !tools.CheckTool(req).Found {
+			return fmt.Errorf("required tool %q not found. Install with: %s", req.Name, req.InstallCmd)
+		}
+	}
+	gowitnessAvailable := tools.CheckTool(tools.ToolRequirement{Name: "gowitness", Binary: "gowitness"}).Found
+
+	store, err := storage.NewStore(cfg.DBPath)
+	if err != nil {
+		return fmt.Errorf("opening database: %w", err)
+	}
+	defer store.Close()
+
+	probeFilter, _ := configuredFilters()
+	allStages := buildScanStages(store, scanStageOptions{
+		domain:             domain,
+		severity:           severity,
+		skipPDF:            skipPDF,
+		pdfEngine:          pdfEngine,
+		pythonBinary:       pythonBinary,
+		gowitnessAvailable: gowitnessAvailable,
+		nucleiAvailable:    true,
+		targetURLs:         urls,
+		probeFilter:        probeFilter,
+		vulnscanFilter:     vulnFilter,
+	})
+
+	pipelineCfg := pipeline.PipelineConfig{
+		Target:   domain,
+		Stages:   targetsFileStages,
+		Timeout:  timeout,
+		Operator: operator,
+		RunConfig: &models.RunConfig{
+			Version:        rootCmd.Version,
+			Stages:         targetsFileStages,
+			Severity:       severity,
+			Timeout:        timeout.String(),
+			ScopeDomains:   cfg.ScopeDomains,
+			SkipPDF:        skipPDF,
+			PDFEngine:      pdfEngine,
+			TargetURLs:     urls,
+			ProbeFilter:    cfg.Probe.Filter,
+			VulnscanFilter: filterSrc,
+			FakeTools:      fakeToolsMode,
+			Config:         snapshotConfig(cfg),
+		},
+		Policy: targetPolicy(cfg, ignorePolicy),
+		OnStageStart: func(name string, index, total int) {
+			fmt.Printf("[*] Stage %d/%d: %s...\n", index+1, total, name)
+		},
+	}
+
+	fmt.Printf("[*] Scanning %d listed URLs for %s (severity: %s)\n", len(urls), domain, severity)
+	result, err := pipeline.RunPipeline(context.Background(), pipelineCfg, allStages, store, cfg)
+	if err != nil {
+		return policyHint(fmt.Errorf("pipeline failed: %w", err))
+	}
+
+	fmt.Println()
+	fmt.Printf("[+] Vulnerability scan complete!\n")
+	fmt.Printf("    Scan ID:   %s\n", result.ScanID)
+	fmt.Printf("    Scan dir:  %s\n", result.ScanDir)
+	fmt.Printf("    Status:    %s\n", result.Status)
+	fmt.Printf("    Report:    %s\n", storage.ReportPath(result.ScanDir, "vulns.md"))
+	for stage, errMsg := range result.StageErrors {
+		fmt.Printf("    [!] %s: %s\n", stage, errMsg)
+	}
+	return policyError(checkFailPolicy(result.ScanDir))
+}
+
 // nucleiJSONLRecord mirrors nuclei's JSONL output format.
 // Field names use hyphens to match what Nuc-pdf and other nuclei tooling expect.
 type nucleiJSONLRecord struct {
@@ -257,17 +434,51 @@ type nucleiJSONLClassify struct {
 	CVSSScore float64 `json:"cvss-score,omitempty"`
 }
 
+// loadStageFindings collects findings raised by earlier stages outside of
+// nuclei: discovery misconfigurations (raw/subdomains.json), mail service
+// checks (raw/ports.json) and response body matches and HTTP method checks
+// (raw/http-probes.json).
+// Missing files yield nothing.
+func loadStageFindings(scanDir string) []models.Vulnerability {
+	var findings []models.Vulnerability
+
+	if data, err := os.ReadFile(storage.RawPath(scanDir, "subdomains.json")); err == nil {
+		var result discovery.DiscoveryResult
+		if err := json.Unmarshal(data, &result); err != nil {
+			fmt.Printf("[!] Warning: parsing subdomains.json: %v\n", err)
+		} else {
+			findings = append(findings, result.Findings...)
+		}
+	}
+
+	if data, err := os.ReadFile(storage.RawPath(scanDir, "ports.json")); err == nil {
+		var result portscan.PortScanResult
+		if err := json.Unmarshal(data, &result); err != nil {
+			fmt.Printf("[!] Warning: parsing ports.json: %v\n", err)
+		} else {
+			findings = append(findings, netprobe.MailFindings(result.MailChecks)...)
+		}
+	}
+
+	if data, err := os.ReadFile(storage.RawPath(scanDir, "http-probes.json")); err == nil {
+		var result httpprobe.HTTPProbeResult
+		if err := json.Unmarshal(data, &result); err != nil {
+			fmt.Printf("[!] Warning: parsing http-probes.json: %v\n", err)
+		} else {
+			findings = append(findings, httpprobe.BodyFindings(result.BodyMatches)...)
+			findings = append(findings, httpprobe.MethodFindings(result.Probes)...)
+		}
+	}
+
+	return findings
+}
+
 // writeNucleiJSONL serialises vulnerabilities as nuclei-compatible JSONL so
 // downstream tools (e.g. Nuc-pdf) can parse the file without modification.
 // One JSON object is written per line; no trailing comma or array wrapper.
+// The file is replaced atomically, so a crash never leaves it truncated.
 func writeNucleiJSONL(vulns []models.Vulnerability, outputPath string) error {
-	f, err := os.Create(outputPath)
-	if err != nil {
-		return fmt.Errorf("creating JSONL file: %w", err)
-	}
-	defer f.Close()
-
-	w := bufio.NewWriter(f)
+	var buf bytes.Buffer
 	now := time.Now().UTC().Format(time.RFC3339Nano)
 
 	for _, v := range vulns {
@@ -299,15 +510,59 @@ func writeNucleiJSONL(vulns []models.Vulnerability, outputPath string) error {
 			continue
 		}
 
-		if _, err := w.Write(line); err != nil {
-			return fmt.Errorf("writing JSONL record: %w", err)
-		}
-		if err := w.WriteByte('\n'); err != nil {
-			return fmt.Errorf("writing JSONL newline: %w", err)
-		}
+		buf.Write(line)
+		buf.WriteByte('\n')
+	}
+
+	if err := storage.WriteFileAtomic(outputPath, buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("writing JSONL file: %w", err)
 	}
+	return nil
+}
+
+// PDF engines for --pdf-engine.
+const (
+	pdfEngineGo     = "go"     // rendered in-process by internal/report/pdf
+	pdfEnginePython = "python" // the Nuc-pdf Python tool
+)
+
+// resolvePDFEngine checks engine and, for the python engine, looks up the
+// interpreter. The binary returned is empty unless a PDF will be generated
+// with it.
+func resolvePDFEngine(engine string, skipPDF bool) (string, error) {
+	switch engine {
+	case pdfEngineGo:
+		return "", nil
+	case pdfEnginePython:
+	default:
+		return "", fmt.Errorf("invalid --pdf-engine %q: must be %s or %s", engine, pdfEngineGo, pdfEnginePython)
+	}
+	if skipPDF {
+		return "", nil
+	}
+	found, binary := detectPython()
+	if !found {
+		fmt.Println("[!] Warning: python3/python not found — PDF generation will be skipped")
+	}
+	return binary, nil
+}
 
-	return w.Flush()
+// writeVulnPDF writes the PDF vulnerability report with engine: from result
+// for the go engine, from the nuclei JSONL at jsonlPath through Nuc-pdf for
+// the python one, when pythonBinary was found. Failures are treated as
+// warnings — the pipeline continues without a PDF.
+func writeVulnPDF(ctx context.Context, engine, pythonBinary string, result *vulnscan.VulnScanResult, jsonlPath, pdfPath, domain string) {
+	if engine == pdfEnginePython {
+		if pythonBinary != "" {
+			generateNucPDF(ctx, pythonBinary, jsonlPath, pdfPath, domain)
+		}
+		return
+	}
+	if err := report.WriteVulnPDFReport(result, fmt.Sprintf("%s Vulnerability Assessment", domain), pdfPath); err != nil {
+		fmt.Printf("[!] Warning: PDF report generation failed: %v\n", err)
+		return
+	}
+	fmt.Printf("[+] PDF report written to %s\n", pdfPath)
 }
 
 // detectPython checks for python3 first (preferred), then python as a fallback.
@@ -342,3 +597,16 @@ func generateNucPDF(ctx context.Context, pythonBinary, jsonlPath, pdfPath, domai
 
 	fmt.Printf("[+] PDF report written to %s\n", pdfPath)
 }
+
+// nucleiOptions converts the vulnscan settings for nuclei. The timeout was
+// validated at config load.
+func nucleiOptions() tools.NucleiOptions {
+	opts := tools.NucleiOptions{
+		MaxHostError: cfg.Vulnscan.MaxHostError,
+		ScanStrategy: cfg.Vulnscan.ScanStrategy,
+	}
+	if cfg.Vulnscan.Timeout != "" {
+		opts.Timeout, _ = time.ParseDuration(cfg.Vulnscan.Timeout)
+	}
+	return opts
+}
@@ -10,6 +10,7 @@ import (
 
 	"github.com/hakim/reconpipe/internal/pipeline"
 	"github.com/hakim/reconpipe/internal/storage"
+	"github.com/hakim/reconpipe/pkg/models"
 	"github.com/spf13/cobra"
 )
 
@@ -20,11 +21,13 @@ var wizardCmd = &cobra.Command{
 
 The wizard asks for a target domain, preset, severity, timeout, and optional
 webhook URL.  It then prints a summary and asks for confirmation before
-launching the full recon pipeline with the same logic as 'reconpipe scan'.`,
+launching the full recon pipeline with the same logic as 'reconpipe scan',
+including the per-target policy (see --ignore-policy).`,
 	RunE: runWizard,
 }
 
 func init() {
+	wizardCmd.Flags().Bool("ignore-policy", false, "Scan even if the target's cooldown has not passed or another scan of it is running")
 	rootCmd.AddCommand(wizardCmd)
 }
 
@@ -34,6 +37,7 @@ func runWizard(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("config not loaded. Run 'reconpipe init' first to create config")
 	}
 
+	ignorePolicy, _ := cmd.Flags().GetBool("ignore-policy")
 	reader := bufio.NewReader(os.Stdin)
 
 	fmt.Println("[*] ReconPipe Interactive Wizard")
@@ -44,10 +48,16 @@ func runWizard(cmd *cobra.Command, args []string) error {
 	var domain string
 	for {
 		domain = wizardPrompt(reader, "[?] Target domain (required): ", "")
-		if domain != "" {
-			break
+		if domain == "" {
+			fmt.Println("[!] Target domain is required — please enter a domain name.")
+			continue
+		}
+		scope := pipeline.ScopeConfig{AllowedDomains: cfg.ScopeDomains}
+		if err := scope.ValidateTarget(domain); err != nil {
+			fmt.Printf("[!] %v\n", err)
+			continue
 		}
-		fmt.Println("[!] Target domain is required — please enter a domain name.")
+		break
 	}
 
 	// ── 2. Preset ─────────────────────────────────────────────────────────────
@@ -147,7 +157,13 @@ func runWizard(cmd *cobra.Command, args []string) error {
 
 	// ── 5. Webhook URL ────────────────────────────────────────────────────────
 	fmt.Println()
-	webhookURL := wizardPrompt(reader, "[?] Webhook URL (optional, press Enter to skip): ", "")
+	webhookURL := wizardPrompt(reader, "[?] Webhook URL — http(s), slack:// or discord:// (optional, press Enter to skip): ", "")
+	if webhookURL != "" {
+		if _, _, err := pipeline.ParseNotifyURL(webhookURL); err != nil {
+			fmt.Printf("[!] %v — no notification will be sent\n", err)
+			webhookURL = ""
+		}
+	}
 
 	// ── Summary + confirmation ─────────────────────────────────────────────────
 	fmt.Println()
@@ -191,13 +207,6 @@ func runWizard(cmd *cobra.Command, args []string) error {
 	nucleiAvailable := toolCheckResults["nuclei"].found
 
 	skipPDF := resolvedPreset.SkipPDF
-	python3Available, pythonBinary := false, ""
-	if !skipPDF {
-		python3Available, pythonBinary = detectPython()
-		if !python3Available {
-			fmt.Println("[!] Warning: python3/python not found — PDF generation will be skipped")
-		}
-	}
 
 	// Open bbolt store.
 	store, err := storage.NewStore(cfg.DBPath)
@@ -208,25 +217,50 @@ func runWizard(cmd *cobra.Command, args []string) error {
 
 	// Build stage closures — delegate to the shared builder so we never
 	// duplicate the per-stage closure code from scan.go.
-	allStages := buildScanStages(
-		domain,
-		severity,
-		skipPDF,
-		python3Available,
-		pythonBinary,
-		tlsxAvailable,
-		cdncheckAvailable,
-		gowitnessAvailable,
-		nucleiAvailable,
-	)
+	probeFilter, vulnscanFilter := configuredFilters()
+	allStages := buildScanStages(store, scanStageOptions{
+		domain:             domain,
+		severity:           severity,
+		skipPDF:            skipPDF,
+		pdfEngine:          pdfEngineGo,
+		tlsxAvailable:      tlsxAvailable,
+		cdncheckAvailable:  cdncheckAvailable,
+		gowitnessAvailable: gowitnessAvailable,
+		nucleiAvailable:    nucleiAvailable,
+		permutations:       resolvedPreset.Permutations || cfg.Discovery.Permutations.Enabled,
+		bruteforce:         cfg.Discovery.Bruteforce.Enabled,
+		zoneTransfer:       cfg.Discovery.ZoneTransfer,
+		dnsConsensus:       cfg.Discovery.DNSConsensus.Enabled,
+		probeFilter:        probeFilter,
+		vulnscanFilter:     vulnscanFilter,
+	})
 
 	pipelineCfg := pipeline.PipelineConfig{
-		Target:  domain,
-		ScanDir: "",
-		Stages:  stageList,
-		Skip:    nil,
-		Resume:  false,
-		Timeout: timeout,
+		Target:   domain,
+		ScanDir:  "",
+		Preset:   presetName,
+		Stages:   stageList,
+		Skip:     nil,
+		Resume:   false,
+		Timeout:  timeout,
+		Operator: operator,
+		RunConfig: &models.RunConfig{
+			Version:        rootCmd.Version,
+			Preset:         presetName,
+			Stages:         stageList,
+			Severity:       severity,
+			Timeout:        timeout.String(),
+			SkipPDF:        skipPDF,
+			Permutations:   resolvedPreset.Permutations || cfg.Discovery.Permutations.Enabled,
+			Bruteforce:     cfg.Discovery.Bruteforce.Enabled,
+			ZoneTransfer:   cfg.Discovery.ZoneTransfer,
+			DNSConsensus:   cfg.Discovery.DNSConsensus.Enabled,
+			ProbeFilter:    cfg.Probe.Filter,
+			VulnscanFilter: cfg.Vulnscan.Filter,
+			FakeTools:      fakeToolsMode,
+			Config:         snapshotConfig(cfg),
+		},
+		Policy: targetPolicy(cfg, ignorePolicy),
 		OnStageStart: func(name string, index, total int) {
 			fmt.Printf("[*] Stage %d/%d: %s...\n", index+1, total, name)
 		},
@@ -243,17 +277,12 @@ func runWizard(cmd *cobra.Command, args []string) error {
 
 	result, err := pipeline.RunPipeline(context.Background(), pipelineCfg, allStages, store, cfg)
 	if err != nil {
-		return fmt.Errorf("pipeline failed: %w", err)
+		return policyHint(fmt.Errorf("pipeline failed: %w", err))
 	}
 
 	// Webhook notification (non-fatal).
 	if webhookURL != "" {
-		notifyCfg := pipeline.NotifyConfig{WebhookURL: webhookURL}
-		if notifyErr := notifyCfg.SendCompletion(result); notifyErr != nil {
-			fmt.Printf("[!] Warning: webhook notification failed: %v\n", notifyErr)
-		} else {
-			fmt.Printf("[+] Completion notification sent to %s\n", webhookURL)
-		}
+		sendNotifications(store, webhookURL, result)
 	}
 
 	// Final summary.
@@ -274,7 +303,7 @@ func runWizard(cmd *cobra.Command, args []string) error {
 		}
 	}
 
-	return nil
+	return policyError(checkFailPolicy(result.ScanDir))
 }
 
 // wizardPrompt prints a prompt, reads a line, trims whitespace, and returns
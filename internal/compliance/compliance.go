@@ -0,0 +1,165 @@
+// Package compliance tags findings with the framework requirements they
+// bear on (OWASP Top 10 categories, CIS controls, PCI DSS requirement
+// numbers, ...), from a mapping file named by compliance.mapping_file:
+//
+//	mappings:
+//	  - templates: ["*-default-login"]
+//	    refs:
+//	      OWASP Top 10: ["A07:2021"]
+//	      PCI DSS: ["2.2.2", "8.3.1"]
+//	  - templates: [dns-zone-transfer, dns-caa-missing]
+//	    refs:
+//	      CIS: ["4.1"]
+//
+// Each entry applies to the findings whose template ID matches one of its
+// patterns (a path.Match glob, compared case-insensitively). Framework names
+// are free-form and appear in reports as written; a finding matched by
+// several entries gets the union of their references.
+package compliance
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/hakim/reconpipe/pkg/models"
+	"go.yaml.in/yaml/v3"
+)
+
+// Mapping is a loaded mapping file. A nil *Mapping tags nothing.
+type Mapping struct {
+	// Path is the file the mapping was loaded from.
+	Path    string
+	entries []entry
+}
+
+type entry struct {
+	templates []string // lowercased patterns
+	refs      map[string][]string
+}
+
+// mappingFile is the layout of a mapping file.
+type mappingFile struct {
+	Mappings []struct {
+		Templates []string            `yaml:"templates"`
+		Refs      map[string][]string `yaml:"refs"`
+	} `yaml:"mappings"`
+}
+
+// Load reads and checks a mapping file.
+func Load(filePath string) (*Mapping, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	var f mappingFile
+	if err := yaml.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("%s: %w", filePath, err)
+	}
+
+	m := &Mapping{Path: filePath}
+	for i, spec := range f.Mappings {
+		if len(spec.Templates) == 0 {
+			return nil, fmt.Errorf("%s: mapping %d: no templates", filePath, i+1)
+		}
+		if len(spec.Refs) == 0 {
+			return nil, fmt.Errorf("%s: mapping %d: no refs", filePath, i+1)
+		}
+		e := entry{refs: spec.Refs}
+		for _, pattern := range spec.Templates {
+			pattern = strings.ToLower(strings.TrimSpace(pattern))
+			if _, err := path.Match(pattern, ""); err != nil || pattern == "" {
+				return nil, fmt.Errorf("%s: mapping %d: bad template pattern %q", filePath, i+1, pattern)
+			}
+			e.templates = append(e.templates, pattern)
+		}
+		m.entries = append(m.entries, e)
+	}
+	return m, nil
+}
+
+// Refs returns the framework references for templateID, each list sorted,
+// or nil when no entry matches.
+func (m *Mapping) Refs(templateID string) map[string][]string {
+	if m == nil {
+		return nil
+	}
+	id := strings.ToLower(templateID)
+	var refs map[string][]string
+	for _, e := range m.entries {
+		if !slices.ContainsFunc(e.templates, func(p string) bool {
+			ok, _ := path.Match(p, id)
+			return ok
+		}) {
+			continue
+		}
+		if refs == nil {
+			refs = make(map[string][]string)
+		}
+		for framework, ids := range e.refs {
+			for _, ref := range ids {
+				if !slices.Contains(refs[framework], ref) {
+					refs[framework] = append(refs[framework], ref)
+				}
+			}
+		}
+	}
+	for _, ids := range refs {
+		slices.SortFunc(ids, CompareRefs)
+	}
+	return refs
+}
+
+// CompareRefs orders requirement references with their numbers compared
+// by value, so "3.10" follows "3.2" and "12.2" follows both.
+func CompareRefs(a, b string) int {
+	for a != "" && b != "" {
+		na, restA := leadingNumber(a)
+		nb, restB := leadingNumber(b)
+		switch {
+		case na >= 0 && nb >= 0:
+			if na != nb {
+				return na - nb
+			}
+			a, b = restA, restB
+		case a[0] != b[0]:
+			return int(a[0]) - int(b[0])
+		default:
+			a, b = a[1:], b[1:]
+		}
+	}
+	return len(a) - len(b)
+}
+
+// leadingNumber returns the number s starts with and the rest of s, or -1
+// when s does not start with a digit.
+func leadingNumber(s string) (int, string) {
+	i := 0
+	for i < len(s) && s[i] >= '0' && s[i] <= '9' {
+		i++
+	}
+	if i == 0 {
+		return -1, s
+	}
+	n, err := strconv.Atoi(s[:i])
+	if err != nil {
+		return -1, s
+	}
+	return n, s[i:]
+}
+
+// Tag sets the compliance references of every finding in vulns, replacing
+// any it had, and returns how many findings matched an entry.
+func (m *Mapping) Tag(vulns []models.Vulnerability) int {
+	tagged := 0
+	for i := range vulns {
+		vulns[i].Compliance = m.Refs(vulns[i].TemplateID)
+		if vulns[i].Compliance != nil {
+			tagged++
+		}
+	}
+	return tagged
+}
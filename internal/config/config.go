@@ -3,9 +3,32 @@ package config
 import (
 	"errors"
 	"fmt"
+	"net/url"
 	"os"
 	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
 
+	"github.com/hakim/reconpipe/internal/compliance"
+	"github.com/hakim/reconpipe/internal/discovery"
+	"github.com/hakim/reconpipe/internal/exclude"
+	"github.com/hakim/reconpipe/internal/filter"
+	"github.com/hakim/reconpipe/internal/geoip"
+	"github.com/hakim/reconpipe/internal/hooks"
+	"github.com/hakim/reconpipe/internal/httpprobe"
+	"github.com/hakim/reconpipe/internal/noise"
+	"github.com/hakim/reconpipe/internal/provider"
+	"github.com/hakim/reconpipe/internal/riskpolicy"
+	"github.com/hakim/reconpipe/internal/schedule"
+	"github.com/hakim/reconpipe/internal/selfupdate"
+	"github.com/hakim/reconpipe/internal/signing"
+	"github.com/hakim/reconpipe/internal/storage"
+	"github.com/hakim/reconpipe/internal/tools"
+	"github.com/hakim/reconpipe/internal/triage"
+	"github.com/hakim/reconpipe/internal/vulnscan"
+	"github.com/hakim/reconpipe/pkg/models"
 	"github.com/spf13/viper"
 )
 
@@ -13,9 +36,303 @@ import (
 type Config struct {
 	ScanDir    string          `mapstructure:"scan_dir"`
 	DBPath     string          `mapstructure:"db_path"`
+	DBDriver   string          `mapstructure:"db_driver"` // bbolt (default) or sqlite
+	Operator   string          `mapstructure:"operator"`  // who runs scans from this config; see --operator
+	ReadOnly   bool            `mapstructure:"read_only"` // same as --read-only: no scanning, database opened read-only
 	Tools      ToolsConfig     `mapstructure:"tools"`
 	RateLimits RateLimitConfig `mapstructure:"rate_limits"`
 	Stages     StagesConfig    `mapstructure:"stages"`
+	Discovery  DiscoveryConfig `mapstructure:"discovery"`
+	PortScan   PortScanConfig  `mapstructure:"portscan"`
+	Probe      ProbeConfig     `mapstructure:"probe"`
+	Vulnscan   VulnscanConfig  `mapstructure:"vulnscan"`
+	ScanLayout ScanLayout      `mapstructure:"scan_layout"`
+
+	// ProviderProfiles adjusts the port scan, httpx and nuclei for hosts by
+	// the CDN, WAF or cloud provider cdncheck identifies them with.
+	ProviderProfiles ProviderProfilesConfig `mapstructure:"provider_profiles"`
+
+	ReportSinks []ReportSinkConfig `mapstructure:"report_sinks"`
+	OutputSinks []OutputSinkConfig `mapstructure:"output_sinks"`
+
+	// Hooks maps pre_/post_ plus a stage name (or "scan") to a shell command
+	// template run by the orchestrator, e.g. post_vulnscan: "./notify.sh {{.ScanDir}}".
+	Hooks map[string]string `mapstructure:"hooks"`
+
+	Server ServerConfig `mapstructure:"server"`
+
+	Issues IssuesConfig `mapstructure:"issues"`
+
+	Signing SigningConfig `mapstructure:"signing"`
+
+	Update UpdateConfig `mapstructure:"update"`
+
+	// ScopeDomains limits which targets may be scanned, using the patterns
+	// of --scope-domains (which overrides it). Empty allows any target.
+	ScopeDomains []string `mapstructure:"scope_domains"`
+
+	// ExcludeFile names a file of IPs and CIDRs that are never scanned, one
+	// per line. masscan gets it as --excludefile and naabu as -exclude-file;
+	// nmap, httpx and nuclei targets inside those networks are dropped.
+	ExcludeFile string `mapstructure:"exclude_file"`
+
+	// ComplianceFile names a mapping from template IDs to framework
+	// requirements (see package compliance). Findings are tagged with them
+	// and vulns.md gets a compliance summary. Empty tags nothing.
+	ComplianceFile string `mapstructure:"compliance_file"`
+
+	// StaleAfter is the age of a scan's data past which 'reconpipe report'
+	// warns that it is rendering old results, e.g. 168h. Empty uses 30
+	// days; 0 never warns.
+	StaleAfter string `mapstructure:"stale_after"`
+
+	Policy PolicyConfig `mapstructure:"policy"`
+
+	// Schedules are the scans 'reconpipe monitor' runs unattended.
+	Schedules []ScheduleConfig `mapstructure:"schedules"`
+
+	RiskPolicy RiskPolicyConfig `mapstructure:"risk_policy"`
+
+	Memory MemoryConfig `mapstructure:"memory"`
+}
+
+// PolicyConfig limits how often and how concurrently each target is scanned.
+// scan and wizard enforce it unless --ignore-policy is given; serve enforces
+// it unless a request sets ignore_policy.
+type PolicyConfig struct {
+	Cooldown        string `mapstructure:"cooldown"`         // minimum time between scans of a target, e.g. 24h; empty for none
+	AllowConcurrent bool   `mapstructure:"allow_concurrent"` // let several scans of one target run at once
+}
+
+// ScheduleConfig is one schedule of 'reconpipe monitor': the targets it
+// scans and when. The scan settings mean what the scan flags of the same
+// name do.
+type ScheduleConfig struct {
+	Name     string   `mapstructure:"name"` // identifies the schedule's run history; default the first target
+	Cron     string   `mapstructure:"cron"` // e.g. "0 3 * * *", @daily or "@every 6h", in local time
+	Targets  []string `mapstructure:"targets"`
+	Preset   string   `mapstructure:"preset"`
+	Stages   []string `mapstructure:"stages"`
+	Skip     []string `mapstructure:"skip"`
+	Severity string   `mapstructure:"severity"`
+	Tag      string   `mapstructure:"tag"`
+
+	// Webhook is POSTed a JSON summary when a scan's diff shows new
+	// subdomains, ports or vulns; supports ${ENV}. slack:// and discord://
+	// URLs get a chat message instead. Scans that change nothing, and the
+	// first scan of a target, send nothing.
+	Webhook string `mapstructure:"webhook"`
+}
+
+// ScheduleName returns the schedule's name, the first target when unset.
+func (c ScheduleConfig) ScheduleName() string {
+	if c.Name == "" && len(c.Targets) > 0 {
+		return c.Targets[0]
+	}
+	return c.Name
+}
+
+// RiskPolicyConfig decides which findings fail scan and vulnscan runs,
+// raise finding alerts, warrant the completion notification and open
+// issues; see package riskpolicy. Actions without rules keep their default.
+type RiskPolicyConfig struct {
+	Assets   map[string][]string `mapstructure:"assets"` // asset tag -> hostname globs
+	FailOn   []riskpolicy.Rule   `mapstructure:"fail_on"`
+	AlertOn  []riskpolicy.Rule   `mapstructure:"alert_on"`
+	NotifyOn []riskpolicy.Rule   `mapstructure:"notify_on"`
+	TicketOn []riskpolicy.Rule   `mapstructure:"ticket_on"`
+}
+
+// Spec returns the policy as riskpolicy.New takes it. alertNoise is
+// vulnscan.noise.alert.
+func (c RiskPolicyConfig) Spec(alertNoise bool) riskpolicy.Spec {
+	return riskpolicy.Spec{
+		Assets: c.Assets,
+		Rules: map[riskpolicy.Action][]riskpolicy.Rule{
+			riskpolicy.Fail:   c.FailOn,
+			riskpolicy.Alert:  c.AlertOn,
+			riskpolicy.Notify: c.NotifyOn,
+			riskpolicy.Ticket: c.TicketOn,
+		},
+		AlertNoise: alertNoise,
+	}
+}
+
+// Policy returns the risk policy. alertNoise is vulnscan.noise.alert.
+func (c RiskPolicyConfig) Policy(alertNoise bool) *riskpolicy.Policy {
+	policy, _ := riskpolicy.New(c.Spec(alertNoise)) // checked by Validate
+	return policy
+}
+
+// MemoryConfig bounds memory use on very large programs, where holding every
+// target and result of the heavy stages at once can exhaust the machine.
+type MemoryConfig struct {
+	// BudgetMB is a soft limit on the Go heap: the garbage collector works
+	// harder as it is approached, and it sizes the probe stage's httpx
+	// batches. External tools are not counted. 0 = no limit.
+	BudgetMB int `mapstructure:"budget_mb"`
+	// ProbeBatchSize caps the targets of one httpx run. 0 = derived from
+	// BudgetMB, or a single run when no budget is set.
+	ProbeBatchSize int `mapstructure:"probe_batch_size"`
+}
+
+// probeTargetsPerMB is how many httpx targets one MB of budget allows per
+// batch, leaving room for the response bodies in flight at a time.
+const probeTargetsPerMB = 8
+
+// ProbeChunkSize returns the number of targets to hand each httpx run, or 0
+// for a single run over all of them.
+func (m MemoryConfig) ProbeChunkSize() int {
+	if m.ProbeBatchSize > 0 {
+		return m.ProbeBatchSize
+	}
+	if m.BudgetMB > 0 {
+		return max(m.BudgetMB*probeTargetsPerMB, 500)
+	}
+	return 0
+}
+
+// Exclusions loads the exclude_file list, or returns nil when none is set.
+// The file is read on every call so edits apply to the next scan.
+func (c *Config) Exclusions() (*exclude.List, error) {
+	if c.ExcludeFile == "" {
+		return nil, nil
+	}
+	return exclude.Load(c.ExcludeFile)
+}
+
+// ComplianceMapping loads the compliance mapping file, or returns nil when
+// none is configured.
+func (c *Config) ComplianceMapping() (*compliance.Mapping, error) {
+	if c.ComplianceFile == "" {
+		return nil, nil
+	}
+	return compliance.Load(c.ComplianceFile)
+}
+
+// DefaultStaleAfter is the data age report warns past when stale_after is
+// not set.
+const DefaultStaleAfter = 30 * 24 * time.Hour
+
+// StaleAfterDuration returns the data age past which reports warn, or zero
+// for never.
+func (c *Config) StaleAfterDuration() time.Duration {
+	if c.StaleAfter == "" {
+		return DefaultStaleAfter
+	}
+	d, _ := time.ParseDuration(c.StaleAfter) // checked by Validate
+	return d
+}
+
+// CooldownDuration returns the parsed cooldown, or zero when none is set.
+func (p PolicyConfig) CooldownDuration() time.Duration {
+	d, _ := time.ParseDuration(p.Cooldown) // checked by Validate
+	return d
+}
+
+// IssuesConfig opens an issue in a GitHub or GitLab project for every newly
+// dangling subdomain the diff stage finds, and closes it once the subdomain
+// is no longer dangling. Disabled while provider is empty.
+type IssuesConfig struct {
+	Provider string   `mapstructure:"provider"` // github or gitlab
+	Repo     string   `mapstructure:"repo"`     // owner/name, or the GitLab project path
+	URL      string   `mapstructure:"url"`      // API base for GitHub Enterprise / self-hosted GitLab
+	Token    string   `mapstructure:"token"`    // supports ${ENV}
+	Labels   []string `mapstructure:"labels"`   // default [reconpipe, dangling-dns]; also used to find open issues
+	Timeout  string   `mapstructure:"timeout"`  // per request, default 30s
+}
+
+// SigningConfig writes a detached signature next to diff.md and diff.json
+// whenever they are written, for 'reconpipe verify' to check later.
+// Disabled while method is empty.
+type SigningConfig struct {
+	Method string `mapstructure:"method"` // gpg or ed25519
+
+	// Key is the gpg key ID, fingerprint or email (empty = gpg's default
+	// key), or the path of an ed25519 PEM private key.
+	Key string `mapstructure:"key"`
+
+	// PublicKey is the path of the ed25519 PEM public key. Hosts that only
+	// verify need just this; when key is set it is derived.
+	PublicKey string `mapstructure:"public_key"`
+
+	GPGPath string `mapstructure:"gpg_path"` // default gpg from $PATH
+}
+
+// Signer returns the configured signer, or nil when signing is disabled.
+func (c SigningConfig) Signer() (*signing.Signer, error) {
+	if c.Method == "" {
+		return nil, nil
+	}
+	return signing.New(c.Method, c.Key, c.PublicKey, c.GPGPath)
+}
+
+// UpdateConfig configures 'reconpipe self-update' and 'reconpipe version
+// --check'.
+type UpdateConfig struct {
+	Channel string `mapstructure:"channel"` // stable (default) or beta
+	Repo    string `mapstructure:"repo"`    // owner/name, default Wakiki93/recon-pipeline
+	URL     string `mapstructure:"url"`     // API base for GitHub Enterprise or a mirror
+
+	// PublicKey is the path of the ed25519 PEM public key release manifests
+	// are signed with. self-update refuses to install without it.
+	PublicKey string `mapstructure:"public_key"`
+}
+
+// ChannelOrDefault returns the release channel, stable when unset.
+func (c UpdateConfig) ChannelOrDefault() string {
+	if c.Channel == "" {
+		return selfupdate.ChannelStable
+	}
+	return c.Channel
+}
+
+// ServerConfig configures 'reconpipe serve'. Every API request needs a token
+// created with 'reconpipe token create'.
+type ServerConfig struct {
+	Listen    string `mapstructure:"listen"`     // default 127.0.0.1:8080
+	RateLimit int    `mapstructure:"rate_limit"` // requests per minute per token, default 60; tokens may override
+	QueueSize int    `mapstructure:"queue_size"` // scans waiting to run, default 16
+
+	// ShutdownGrace is how long a shutdown waits for the running scan to
+	// reach a stage boundary before cancelling it, default 1m
+	ShutdownGrace string `mapstructure:"shutdown_grace"`
+
+	// Preemption lets a queued scan of higher priority pause a running
+	// lower-priority one at its next stage boundary
+	Preemption bool `mapstructure:"preemption"`
+
+	TLS ServerTLSConfig `mapstructure:"tls"`
+}
+
+// ServerTLSConfig serves the API over HTTPS. TLS is on when cert_file is set
+// or self_signed is true.
+type ServerTLSConfig struct {
+	CertFile     string   `mapstructure:"cert_file"`
+	KeyFile      string   `mapstructure:"key_file"`
+	SelfSigned   bool     `mapstructure:"self_signed"`    // generate a certificate; kept in cert_file/key_file when set
+	Hosts        []string `mapstructure:"hosts"`          // extra names/IPs for the generated certificate
+	ClientCAFile string   `mapstructure:"client_ca_file"` // require client certificates signed by this CA (mTLS)
+}
+
+// ScanLayout controls scan directory naming and the subdirectories inside
+// each scan. Empty fields keep the defaults ({target}_{timestamp}, raw/,
+// reports/, screenshots/).
+type ScanLayout struct {
+	DirTemplate    string `mapstructure:"dir_template"` // {target} {timestamp} {date} {time} {preset} {tag}
+	RawDir         string `mapstructure:"raw_dir"`
+	ReportsDir     string `mapstructure:"reports_dir"`
+	ScreenshotsDir string `mapstructure:"screenshots_dir"`
+}
+
+// Layout converts the settings for the storage package
+func (l ScanLayout) Layout() storage.Layout {
+	return storage.Layout{
+		DirTemplate:    l.DirTemplate,
+		RawDir:         l.RawDir,
+		ReportsDir:     l.ReportsDir,
+		ScreenshotsDir: l.ScreenshotsDir,
+	}
 }
 
 // ToolConfig represents configuration for a single tool
@@ -28,9 +345,11 @@ type ToolConfig struct {
 // ToolsConfig contains configuration for all external tools
 type ToolsConfig struct {
 	Subfinder ToolConfig `mapstructure:"subfinder"`
+	Amass     ToolConfig `mapstructure:"amass"`
 	Tlsx      ToolConfig `mapstructure:"tlsx"`
 	Dig       ToolConfig `mapstructure:"dig"`
 	Masscan   ToolConfig `mapstructure:"masscan"`
+	Naabu     ToolConfig `mapstructure:"naabu"`
 	Nmap      ToolConfig `mapstructure:"nmap"`
 	Httpx     ToolConfig `mapstructure:"httpx"`
 	Gowitness ToolConfig `mapstructure:"gowitness"`
@@ -46,6 +365,25 @@ type RateLimitConfig struct {
 	HttpxThreads     int `mapstructure:"httpx_threads"`
 	NucleiThreads    int `mapstructure:"nuclei_threads"`
 	NucleiRateLimit  int `mapstructure:"nuclei_rate_limit"`
+	// DNSWorkers is how many subdomains discovery resolves at once and
+	// DNSTimeout how long the lookups of one may take; 0 and "" take 20
+	// and 10s.
+	DNSWorkers int    `mapstructure:"dns_workers"`
+	DNSTimeout string `mapstructure:"dns_timeout"`
+}
+
+// ProviderProfilesConfig overrides and extends the built-in provider
+// profiles (see package provider), keyed by the provider names cdncheck
+// reports.
+type ProviderProfilesConfig struct {
+	SkipBuiltin bool                        `mapstructure:"skip_builtin"` // apply only the profiles configured here
+	Profiles    map[string]provider.Profile `mapstructure:"profiles"`
+}
+
+// Set returns the profiles in effect.
+func (c ProviderProfilesConfig) Set() *provider.Profiles {
+	profiles, _ := provider.New(!c.SkipBuiltin, c.Profiles) // checked by Validate
+	return profiles
 }
 
 // StagesConfig controls which pipeline stages to run
@@ -54,6 +392,342 @@ type StagesConfig struct {
 	Skip   []string `mapstructure:"skip"`
 }
 
+// DiscoveryConfig tunes optional discovery enhancements
+type DiscoveryConfig struct {
+	// Engine selects the subdomain enumeration tools: subfinder (the
+	// default when empty), amass, or both with their results merged.
+	Engine string `mapstructure:"engine"`
+
+	Permutations PermutationConfig `mapstructure:"permutations"`
+
+	Bruteforce BruteforceConfig `mapstructure:"bruteforce"`
+
+	// ZoneTransfer attempts AXFR against each authoritative nameserver.
+	// Also enabled by --axfr.
+	ZoneTransfer bool `mapstructure:"zone_transfer"`
+
+	// WildcardFilter drops subdomains that resolve only to the target's
+	// wildcard DNS addresses instead of flagging them.
+	WildcardFilter bool `mapstructure:"wildcard_filter"`
+
+	DNSHealth DNSHealthConfig `mapstructure:"dns_health"`
+
+	DNSConsensus DNSConsensusConfig `mapstructure:"dns_consensus"`
+
+	DNSResolver DNSResolverConfig `mapstructure:"dns_resolver"`
+
+	PassiveSources PassiveSourcesConfig `mapstructure:"passive_sources"`
+}
+
+// PassiveSourcesConfig controls the passive sources discovery queries
+// itself over HTTP alongside subfinder: crt.sh and the Wayback Machine run
+// unless skipped, SecurityTrails once an API key is set.
+type PassiveSourcesConfig struct {
+	Skip                 []string `mapstructure:"skip"`                   // crtsh, wayback, securitytrails
+	SecurityTrailsAPIKey string   `mapstructure:"securitytrails_api_key"` // supports ${ENV}
+	Timeout              string   `mapstructure:"timeout"`                // per source; empty = 60s
+}
+
+// DNSResolverConfig selects how names are resolved: by running dig (the
+// default), with Go's own resolver, which needs no dnsutils, or with dnsx
+// resolving the discovered names in bulk. Zone transfers and the DNS health
+// checks still run dig, and are skipped when it is not installed.
+type DNSResolverConfig struct {
+	Mode string `mapstructure:"mode"` // dig, native or dnsx; empty = dig
+	// Nameservers answer native lookups instead of the system's
+	// resolv.conf, in turn, or are dnsx's resolvers instead of its
+	// built-in list; host or IP, optionally with a port.
+	Nameservers []string `mapstructure:"nameservers"`
+}
+
+// DNSConsensusConfig resolves every discovered name through several
+// resolvers and keeps only the answers a quorum of them returned, so one
+// filtering or poisoned resolver cannot add or hide hosts. It is off unless
+// enabled here or by --dns-consensus.
+type DNSConsensusConfig struct {
+	Enabled   bool     `mapstructure:"enabled"`
+	Resolvers []string `mapstructure:"resolvers"` // empty = 1.1.1.1, 8.8.8.8, 9.9.9.9
+	Quorum    int      `mapstructure:"quorum"`    // resolvers that must agree; 0 = a majority
+}
+
+// DNSHealthConfig controls the DNSSEC and CAA checks run on the apex and key
+// subdomains at the end of discovery. They run by default.
+type DNSHealthConfig struct {
+	Skip       bool     `mapstructure:"skip"`
+	Subdomains []string `mapstructure:"subdomains"` // labels or names; empty = www, mail, api, app, login, auth, portal, vpn
+	// Resolver answers the DNSSEC queries. Only a validating resolver
+	// (e.g. 1.1.1.1) tells a valid zone from one that is merely signed.
+	// Empty = system resolver.
+	Resolver string `mapstructure:"resolver"`
+}
+
+// PermutationConfig controls altdns-style subdomain permutation. It is off
+// unless enabled here, by --permutations, or by a thorough preset.
+type PermutationConfig struct {
+	Enabled       bool     `mapstructure:"enabled"`
+	Patterns      []string `mapstructure:"patterns"`       // empty = built-in list
+	MaxCandidates int      `mapstructure:"max_candidates"` // 0 = 2000
+}
+
+// BruteforceConfig controls wordlist subdomain brute-forcing. It is off
+// unless enabled here or by --bruteforce.
+type BruteforceConfig struct {
+	Enabled       bool   `mapstructure:"enabled"`
+	Wordlist      string `mapstructure:"wordlist"`       // one label per line; empty = built-in list
+	Mutate        bool   `mapstructure:"mutate"`         // also combine the words with resolved names (dev.api, dev-api, api-dev)
+	MaxCandidates int    `mapstructure:"max_candidates"` // 0 = 5000
+}
+
+// PortScanConfig tunes checks run after port fingerprinting
+type PortScanConfig struct {
+	// Scanner selects the tool finding open ports: masscan (the default
+	// when empty), or naabu, which runs without root or raw sockets.
+	Scanner string `mapstructure:"scanner"`
+
+	UDP        UDPScanConfig    `mapstructure:"udp"`
+	MailChecks MailChecksConfig `mapstructure:"mail_checks"`
+
+	// GeoIPDB is the path to a MaxMind-format database (GeoLite2-City or
+	// -Country .mmdb) used to add country and city to scanned IPs. Empty
+	// disables the lookup.
+	GeoIPDB string `mapstructure:"geoip_db"`
+}
+
+// UDPScanConfig controls the nmap UDP scan of curated services (DNS, SNMP,
+// NTP...) that runs alongside the TCP port scan.
+type UDPScanConfig struct {
+	Enabled bool  `mapstructure:"enabled"` // also enabled by --udp
+	Ports   []int `mapstructure:"ports"`   // empty = portscan.DefaultUDPPorts
+}
+
+// MailChecksConfig controls the SMTP/IMAP/POP3 checks. They run by default;
+// the relay test never sends DATA.
+type MailChecksConfig struct {
+	Skip          bool   `mapstructure:"skip"`
+	SkipRelayTest bool   `mapstructure:"skip_relay_test"`
+	Timeout       string `mapstructure:"timeout"` // per service, default 10s
+}
+
+// ProbeConfig tunes the HTTP probe stage
+type ProbeConfig struct {
+	LiveStatus   []string           `mapstructure:"live_status"` // responses counted as live; empty = 2xx, 3xx, 401, 403
+	Screenshots  ScreenshotConfig   `mapstructure:"screenshots"`
+	BodyScan     BodyScanConfig     `mapstructure:"body_scan"`
+	Protocols    ProtocolsConfig    `mapstructure:"protocols"`
+	MethodChecks MethodChecksConfig `mapstructure:"method_checks"`
+	Retry        ProbeRetryConfig   `mapstructure:"retry"`
+	Clones       ClonesConfig       `mapstructure:"clones"`
+	OriginCheck  OriginCheckConfig  `mapstructure:"origin_check"`
+
+	// Filter selects the host:port targets that are probed, e.g.
+	// "port in (80, 443) && !is_cdn"; see package filter. --filter overrides
+	// it. Empty probes every target.
+	Filter string `mapstructure:"filter"`
+}
+
+// MethodChecksConfig controls the OPTIONS, TRACE and random-path requests
+// sent to every responding service. They are off by default.
+type MethodChecksConfig struct {
+	Enabled bool   `mapstructure:"enabled"`
+	Timeout string `mapstructure:"timeout"` // per request, default 5s
+}
+
+// OriginCheckConfig controls origin discovery for CDN-fronted hostnames and
+// requesting them directly from the candidates found. It runs by default.
+type OriginCheckConfig struct {
+	Skip bool `mapstructure:"skip"`
+}
+
+// ClonesConfig controls content hashing of response bodies to find copies
+// of one application on different hosts. It runs by default.
+type ClonesConfig struct {
+	Skip bool `mapstructure:"skip"`
+}
+
+// ProbeRetryConfig throttles the second httpx pass over targets the first
+// one got no response from. The retry runs by default.
+type ProbeRetryConfig struct {
+	Skip      bool `mapstructure:"skip"`
+	Threads   int  `mapstructure:"threads"`    // 0 = a quarter of rate_limits.httpx_threads
+	RateLimit int  `mapstructure:"rate_limit"` // requests per second, 0 = httpx default
+}
+
+// ProtocolsConfig controls the HTTP/2, HTTP/3, gRPC and WebSocket checks run
+// on every responding service. They run by default.
+type ProtocolsConfig struct {
+	Skip    bool   `mapstructure:"skip"`
+	Timeout string `mapstructure:"timeout"` // per service, default 5s
+}
+
+// VulnscanConfig tunes how nuclei treats slow and failing hosts, so one bad
+// host is skipped instead of holding the stage until its timeout. Empty
+// fields keep nuclei's defaults.
+type VulnscanConfig struct {
+	Timeout      string `mapstructure:"timeout"`        // per request, nuclei default 10s
+	MaxHostError int    `mapstructure:"max_host_error"` // errors before a host is skipped, nuclei default 30
+	ScanStrategy string `mapstructure:"scan_strategy"`  // auto, host-spray or template-spray
+
+	// BatchSize is how many targets each nuclei run gets when the scan has
+	// a deadline, default 50. Each batch gets a share of the time left.
+	BatchSize int `mapstructure:"batch_size"`
+
+	// ChunkSize caps the targets of one nuclei run, with or without a
+	// deadline. 0 = no cap: a single run, or batches of batch_size.
+	ChunkSize int `mapstructure:"chunk_size"`
+
+	// ListFile passes nuclei its targets in a temporary file (-list)
+	// instead of on stdin.
+	ListFile bool `mapstructure:"list_file"`
+
+	// Filter selects the targets nuclei gets, e.g. "status_code == 200";
+	// see package filter. --filter overrides it. Empty scans every target.
+	Filter string `mapstructure:"filter"`
+
+	// SeverityOverrides maps a template ID to the severity its findings
+	// are counted, reported and alerted at, e.g. tech-detect: info.
+	SeverityOverrides map[string]string `mapstructure:"severity_overrides"`
+
+	// Noise classes findings as informational noise; see package noise.
+	Noise NoiseConfig `mapstructure:"noise"`
+}
+
+// NoiseConfig holds the noise rules and whether noise is alerted on.
+type NoiseConfig struct {
+	Rules []noise.Rule `mapstructure:"rules"`
+	Alert bool         `mapstructure:"alert"` // send finding alerts for noise too
+}
+
+// Classifier returns the classifier for the noise rules, or nil when none
+// are set.
+func (c NoiseConfig) Classifier() *noise.Classifier {
+	classifier, _ := noise.New(c.Rules) // checked by Validate
+	return classifier
+}
+
+// Overrides returns the parsed severity overrides, keyed by lowercased
+// template ID.
+func (c VulnscanConfig) Overrides() map[string]models.Severity {
+	overrides, _ := vulnscan.ParseSeverityOverrides(c.SeverityOverrides) // checked by Validate
+	return overrides
+}
+
+// BodyScanConfig enables regex scanning of HTTP response bodies
+type BodyScanConfig struct {
+	Enabled bool           `mapstructure:"enabled"`
+	Rules   []BodyRuleSpec `mapstructure:"rules"` // added to the built-in rules
+}
+
+// BodyRuleSpec is a custom body scan rule
+type BodyRuleSpec struct {
+	ID       string `mapstructure:"id"`
+	Name     string `mapstructure:"name"`
+	Severity string `mapstructure:"severity"` // critical, high, medium, low, info; empty = medium
+	Secret   bool   `mapstructure:"secret"`   // redact matched values in reports
+	Pattern  string `mapstructure:"pattern"`
+}
+
+// BodyRules compiles the configured custom body scan rules
+func (c BodyScanConfig) BodyRules() ([]httpprobe.BodyRule, error) {
+	specs := make([]httpprobe.BodyRuleSpec, len(c.Rules))
+	for i, r := range c.Rules {
+		specs[i] = httpprobe.BodyRuleSpec{ID: r.ID, Name: r.Name, Severity: r.Severity, Secret: r.Secret, Pattern: r.Pattern}
+	}
+	return httpprobe.CompileBodyRules(specs)
+}
+
+// ScreenshotConfig controls which responses are screenshotted and how
+type ScreenshotConfig struct {
+	Engine      string       `mapstructure:"engine"`       // gowitness, chromedp; empty = gowitness if installed, else chromedp
+	StatusCodes []string     `mapstructure:"status_codes"` // "200", "4xx", "300-399"; empty = 2xx
+	FullPage    bool         `mapstructure:"full_page"`
+	Width       int          `mapstructure:"width"`    // 0 = engine default
+	Height      int          `mapstructure:"height"`   // 0 = engine default
+	Delay       string       `mapstructure:"delay"`    // wait after load, e.g. "2s"
+	SaveDOM     bool         `mapstructure:"save_dom"` // chromedp only: keep rendered HTML
+	Triage      TriageConfig `mapstructure:"triage"`
+
+	// gowitness only: URLs per gowitness run (0 = 25) and runs at once
+	// (0 = 2). A page that hangs gowitness only costs its batch.
+	BatchSize       int `mapstructure:"batch_size"`
+	ParallelBatches int `mapstructure:"parallel_batches"`
+}
+
+// TriageConfig controls keyword triage of captured screenshots
+type TriageConfig struct {
+	Skip    bool             `mapstructure:"skip"`
+	SkipOCR bool             `mapstructure:"skip_ocr"` // never run tesseract on text-less captures
+	Rules   []TriageRuleSpec `mapstructure:"rules"`    // added to the built-in rules
+}
+
+// TriageRuleSpec is a custom triage rule: pages whose title or visible text
+// match Pattern are tagged Tag and ranked up by Score (default 25).
+type TriageRuleSpec struct {
+	Tag     string `mapstructure:"tag"`
+	Pattern string `mapstructure:"pattern"`
+	Score   int    `mapstructure:"score"`
+}
+
+// TriageRules compiles the configured custom triage rules
+func (c TriageConfig) TriageRules() ([]triage.Rule, error) {
+	specs := make([]triage.RuleSpec, len(c.Rules))
+	for i, r := range c.Rules {
+		specs[i] = triage.RuleSpec{Tag: r.Tag, Pattern: r.Pattern, Score: r.Score}
+	}
+	return triage.CompileRules(specs)
+}
+
+// ReportSinkConfig configures an additional destination for generated
+// reports. Reports are always written to the scan directory; each sink
+// receives a copy.
+type ReportSinkConfig struct {
+	Type string `mapstructure:"type"` // stdout, file, webhook, s3
+
+	// file
+	Dir string `mapstructure:"dir"`
+
+	// webhook
+	URL     string            `mapstructure:"url"`
+	Headers map[string]string `mapstructure:"headers"` // values support ${ENV} expansion
+
+	// s3 (credentials come from AWS_ACCESS_KEY_ID / AWS_SECRET_ACCESS_KEY)
+	Bucket   string `mapstructure:"bucket"`
+	Region   string `mapstructure:"region"`
+	Prefix   string `mapstructure:"prefix"`
+	Endpoint string `mapstructure:"endpoint"` // S3-compatible endpoint, path-style
+
+	Timeout string `mapstructure:"timeout"` // webhook and s3, default 10s
+}
+
+// OutputSinkConfig configures a destination for a scan's structured results
+// (subdomains, HTTP probes, vulnerabilities) or, for confluence, its reports.
+// Results are published once the pipeline finishes; the scan directory stays
+// the source of truth.
+type OutputSinkConfig struct {
+	Type string `mapstructure:"type"` // elasticsearch, opensearch, postgres, confluence
+
+	// elasticsearch / opensearch; confluence uses url as the site base URL
+	URL   string `mapstructure:"url"`
+	Index string `mapstructure:"index"` // placeholders {target} {date} {kind} {scan_id}; default reconpipe-{kind}-{target}-{date}
+
+	// Credentials support ${ENV} expansion. Set either username and password
+	// or api_key.
+	Username string `mapstructure:"username"`
+	Password string `mapstructure:"password"`
+	APIKey   string `mapstructure:"api_key"`
+
+	InsecureSkipVerify bool `mapstructure:"insecure_skip_verify"` // self-signed cluster certificates
+
+	// postgres: connection string in URL or key=value form; supports ${ENV}
+	DSN string `mapstructure:"dsn"`
+
+	// confluence: reports are published under parent_page (a page ID or
+	// title) in this space, one page per target and a child page per scan
+	Space      string `mapstructure:"space"`
+	ParentPage string `mapstructure:"parent_page"`
+
+	Timeout string `mapstructure:"timeout"` // elasticsearch, confluence: per request, default 30s; postgres: whole publish, default none
+}
+
 // Load reads and parses configuration from a YAML file
 // If path is empty, searches for reconpipe.yaml in current directory and ~/.config/reconpipe/
 func Load(path string) (*Config, error) {
@@ -79,6 +753,11 @@ func Load(path string) (*Config, error) {
 		return nil, fmt.Errorf("failed to read config: %w", err)
 	}
 
+	// Unknown keys and mistyped values, with their positions in the file
+	if err := checkKeys(v.ConfigFileUsed()); err != nil {
+		return nil, fmt.Errorf("invalid config:\n%w", err)
+	}
+
 	var cfg Config
 	if err := v.Unmarshal(&cfg); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal config: %w", err)
@@ -99,6 +778,41 @@ func (c *Config) Validate() error {
 		errs = append(errs, errors.New("scan_dir cannot be empty"))
 	}
 
+	switch c.DBDriver {
+	case "", storage.DriverBbolt, storage.DriverSQLite:
+	default:
+		errs = append(errs, fmt.Errorf("db_driver %q must be bbolt or sqlite", c.DBDriver))
+	}
+
+	for _, p := range c.ScopeDomains {
+		if strings.TrimSpace(p) == "" || strings.Contains(strings.TrimPrefix(p, "*."), "*") {
+			errs = append(errs, fmt.Errorf("scope_domains: invalid pattern %q (want example.com or *.example.com)", p))
+		}
+	}
+
+	if _, err := c.Exclusions(); err != nil {
+		errs = append(errs, fmt.Errorf("exclude_file: %w", err))
+	}
+	if _, err := c.ComplianceMapping(); err != nil {
+		errs = append(errs, fmt.Errorf("compliance_file: %w", err))
+	}
+
+	if d := c.StaleAfter; d != "" {
+		if v, err := time.ParseDuration(d); err != nil {
+			errs = append(errs, fmt.Errorf("stale_after %q: %w", d, err))
+		} else if v < 0 {
+			errs = append(errs, fmt.Errorf("stale_after %q must not be negative", d))
+		}
+	}
+
+	if d := c.Policy.Cooldown; d != "" {
+		if v, err := time.ParseDuration(d); err != nil {
+			errs = append(errs, fmt.Errorf("policy.cooldown %q: %w", d, err))
+		} else if v < 0 {
+			errs = append(errs, fmt.Errorf("policy.cooldown %q must not be negative", d))
+		}
+	}
+
 	if c.RateLimits.SubfinderThreads <= 0 {
 		errs = append(errs, errors.New("subfinder_threads must be positive"))
 	}
@@ -123,9 +837,442 @@ func (c *Config) Validate() error {
 		errs = append(errs, errors.New("nuclei_rate_limit must be positive"))
 	}
 
+	if c.RateLimits.DNSWorkers < 0 {
+		errs = append(errs, errors.New("dns_workers must not be negative"))
+	}
+
+	if t := c.RateLimits.DNSTimeout; t != "" {
+		if v, err := time.ParseDuration(t); err != nil {
+			errs = append(errs, fmt.Errorf("dns_timeout %q: %w", t, err))
+		} else if v <= 0 {
+			errs = append(errs, fmt.Errorf("dns_timeout %q must be positive", t))
+		}
+	}
+
+	if r := c.Discovery.DNSHealth.Resolver; r != "" && !validResolver(r) {
+		errs = append(errs, fmt.Errorf("discovery.dns_health.resolver: invalid resolver %q (want a host name or IP)", r))
+	}
+
+	switch c.Discovery.DNSResolver.Mode {
+	case "", tools.ResolverDig, tools.ResolverNative, tools.ResolverDnsx:
+	default:
+		errs = append(errs, fmt.Errorf("discovery.dns_resolver.mode: unknown mode %q (want dig, native or dnsx)", c.Discovery.DNSResolver.Mode))
+	}
+	for _, r := range c.Discovery.DNSResolver.Nameservers {
+		if !validResolver(r) {
+			errs = append(errs, fmt.Errorf("discovery.dns_resolver.nameservers: invalid nameserver %q (want a host name or IP)", r))
+		}
+	}
+
+	consensus := c.Discovery.DNSConsensus
+	for _, r := range consensus.Resolvers {
+		if !validResolver(r) {
+			errs = append(errs, fmt.Errorf("discovery.dns_consensus.resolvers: invalid resolver %q (want a host name or IP)", r))
+		}
+	}
+	if len(consensus.Resolvers) == 1 {
+		errs = append(errs, errors.New("discovery.dns_consensus.resolvers must list at least two resolvers"))
+	}
+	resolverCount := len(consensus.Resolvers)
+	if resolverCount == 0 {
+		resolverCount = len(discovery.DefaultConsensusResolvers)
+	}
+	if consensus.Quorum < 0 {
+		errs = append(errs, errors.New("discovery.dns_consensus.quorum must not be negative"))
+	} else if consensus.Quorum > resolverCount {
+		errs = append(errs, errors.New("discovery.dns_consensus.quorum must not exceed the number of resolvers"))
+	}
+
+	if e := c.Discovery.Engine; e != "" && !slices.Contains(tools.Engines, e) {
+		errs = append(errs, fmt.Errorf("discovery.engine: unknown engine %q (want %s)", e, strings.Join(tools.Engines, ", ")))
+	}
+
+	passive := c.Discovery.PassiveSources
+	for _, source := range passive.Skip {
+		if !slices.Contains(discovery.PassiveSources, source) {
+			errs = append(errs, fmt.Errorf("discovery.passive_sources.skip: unknown source %q (want %s)", source, strings.Join(discovery.PassiveSources, ", ")))
+		}
+	}
+	if t := passive.Timeout; t != "" {
+		if d, err := time.ParseDuration(t); err != nil {
+			errs = append(errs, fmt.Errorf("discovery.passive_sources.timeout %q: %w", t, err))
+		} else if d <= 0 {
+			errs = append(errs, fmt.Errorf("discovery.passive_sources.timeout %q must be positive", t))
+		}
+	}
+
+	if p := c.Discovery.Bruteforce.Wordlist; p != "" {
+		if _, err := discovery.LoadWordlist(p); err != nil {
+			errs = append(errs, fmt.Errorf("discovery.bruteforce.wordlist: %w", err))
+		}
+	}
+
+	if s := c.PortScan.Scanner; s != "" && !slices.Contains(tools.Scanners, s) {
+		errs = append(errs, fmt.Errorf("portscan.scanner: unknown scanner %q (want %s)", s, strings.Join(tools.Scanners, ", ")))
+	}
+
+	for _, port := range c.PortScan.UDP.Ports {
+		if port < 1 || port > 65535 {
+			errs = append(errs, fmt.Errorf("portscan.udp.ports: invalid port %d (want 1-65535)", port))
+		}
+	}
+
+	if t := c.PortScan.MailChecks.Timeout; t != "" {
+		if _, err := time.ParseDuration(t); err != nil {
+			errs = append(errs, fmt.Errorf("portscan.mail_checks.timeout %q: %w", t, err))
+		}
+	}
+
+	if c.Probe.Filter != "" {
+		if _, err := filter.Parse(c.Probe.Filter, filter.TargetFields); err != nil {
+			errs = append(errs, fmt.Errorf("probe.filter: %w", err))
+		}
+	}
+	if c.Vulnscan.Filter != "" {
+		if _, err := filter.Parse(c.Vulnscan.Filter, filter.ProbeFields); err != nil {
+			errs = append(errs, fmt.Errorf("vulnscan.filter: %w", err))
+		}
+	}
+
+	if c.Memory.BudgetMB < 0 {
+		errs = append(errs, errors.New("memory.budget_mb must not be negative"))
+	}
+	if c.Memory.ProbeBatchSize < 0 {
+		errs = append(errs, errors.New("memory.probe_batch_size must not be negative"))
+	}
+
+	if c.Probe.Retry.Threads < 0 {
+		errs = append(errs, errors.New("probe.retry.threads must not be negative"))
+	}
+	if c.Probe.Retry.RateLimit < 0 {
+		errs = append(errs, errors.New("probe.retry.rate_limit must not be negative"))
+	}
+
+	if t := c.Probe.Protocols.Timeout; t != "" {
+		if _, err := time.ParseDuration(t); err != nil {
+			errs = append(errs, fmt.Errorf("probe.protocols.timeout %q: %w", t, err))
+		}
+	}
+	if t := c.Probe.MethodChecks.Timeout; t != "" {
+		if _, err := time.ParseDuration(t); err != nil {
+			errs = append(errs, fmt.Errorf("probe.method_checks.timeout %q: %w", t, err))
+		}
+	}
+
+	if t := c.Vulnscan.Timeout; t != "" {
+		if d, err := time.ParseDuration(t); err != nil {
+			errs = append(errs, fmt.Errorf("vulnscan.timeout %q: %w", t, err))
+		} else if d < time.Second {
+			errs = append(errs, fmt.Errorf("vulnscan.timeout %q: must be at least 1s", t))
+		}
+	}
+	if c.Vulnscan.MaxHostError < 0 {
+		errs = append(errs, errors.New("vulnscan.max_host_error must not be negative"))
+	}
+	if c.Vulnscan.BatchSize < 0 {
+		errs = append(errs, errors.New("vulnscan.batch_size must not be negative"))
+	}
+	if c.Vulnscan.ChunkSize < 0 {
+		errs = append(errs, errors.New("vulnscan.chunk_size must not be negative"))
+	}
+	if _, err := vulnscan.ParseSeverityOverrides(c.Vulnscan.SeverityOverrides); err != nil {
+		errs = append(errs, fmt.Errorf("vulnscan.severity_overrides: %w", err))
+	}
+	if _, err := noise.New(c.Vulnscan.Noise.Rules); err != nil {
+		errs = append(errs, fmt.Errorf("vulnscan.noise.rules: %w", err))
+	}
+	if _, err := provider.New(!c.ProviderProfiles.SkipBuiltin, c.ProviderProfiles.Profiles); err != nil {
+		errs = append(errs, fmt.Errorf("provider_profiles: %w", err))
+	}
+	if _, err := riskpolicy.New(c.RiskPolicy.Spec(false)); err != nil {
+		errs = append(errs, fmt.Errorf("risk_policy: %w", err))
+	}
+	switch c.Vulnscan.ScanStrategy {
+	case "", "auto", "host-spray", "template-spray":
+	default:
+		errs = append(errs, fmt.Errorf("vulnscan.scan_strategy: unknown strategy %q (want auto, host-spray or template-spray)", c.Vulnscan.ScanStrategy))
+	}
+
+	if p := c.PortScan.GeoIPDB; p != "" {
+		if db, err := geoip.Open(p); err != nil {
+			errs = append(errs, fmt.Errorf("portscan.geoip_db: %w", err))
+		} else {
+			db.Close()
+		}
+	}
+
+	if err := c.ScanLayout.Layout().Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("scan_layout: %w", err))
+	}
+
+	for _, p := range c.Probe.LiveStatus {
+		if _, _, ok := httpprobe.ParseStatusPattern(p); !ok {
+			errs = append(errs, fmt.Errorf("probe.live_status: invalid pattern %q (want 403, 4xx or 400-403)", p))
+		}
+	}
+	for _, p := range c.Probe.Screenshots.StatusCodes {
+		if _, _, ok := httpprobe.ParseStatusPattern(p); !ok {
+			errs = append(errs, fmt.Errorf("probe.screenshots.status_codes: invalid pattern %q (want 403, 4xx or 400-403)", p))
+		}
+	}
+	switch c.Probe.Screenshots.Engine {
+	case "", "gowitness", "chromedp":
+	default:
+		errs = append(errs, fmt.Errorf("probe.screenshots.engine: unknown engine %q (want gowitness or chromedp)", c.Probe.Screenshots.Engine))
+	}
+	if s := c.Probe.Screenshots; s.Width < 0 || s.Height < 0 {
+		errs = append(errs, errors.New("probe.screenshots width and height must not be negative"))
+	}
+	if s := c.Probe.Screenshots; s.BatchSize < 0 || s.ParallelBatches < 0 {
+		errs = append(errs, errors.New("probe.screenshots batch_size and parallel_batches must not be negative"))
+	}
+	if d := c.Probe.Screenshots.Delay; d != "" {
+		if _, err := time.ParseDuration(d); err != nil {
+			errs = append(errs, fmt.Errorf("probe.screenshots.delay %q: %w", d, err))
+		}
+	}
+	for i, r := range c.Probe.Screenshots.Triage.Rules {
+		if r.Tag == "" || r.Pattern == "" {
+			errs = append(errs, fmt.Errorf("probe.screenshots.triage.rules[%d]: tag and pattern are required", i))
+		}
+	}
+	if _, err := c.Probe.Screenshots.Triage.TriageRules(); err != nil {
+		errs = append(errs, fmt.Errorf("probe.screenshots.triage: %w", err))
+	}
+	for i, r := range c.Probe.BodyScan.Rules {
+		if r.ID == "" || r.Pattern == "" {
+			errs = append(errs, fmt.Errorf("probe.body_scan.rules[%d]: id and pattern are required", i))
+		}
+	}
+	if _, err := c.Probe.BodyScan.BodyRules(); err != nil {
+		errs = append(errs, fmt.Errorf("probe.body_scan: %w", err))
+	}
+
+	for i, sink := range c.ReportSinks {
+		if err := sink.validate(); err != nil {
+			errs = append(errs, fmt.Errorf("report_sinks[%d]: %w", i, err))
+		}
+	}
+
+	for i, sink := range c.OutputSinks {
+		if err := sink.validate(); err != nil {
+			errs = append(errs, fmt.Errorf("output_sinks[%d]: %w", i, err))
+		}
+	}
+
+	if err := c.Issues.validate(); err != nil {
+		errs = append(errs, fmt.Errorf("issues: %w", err))
+	}
+
+	if _, err := c.Signing.Signer(); err != nil {
+		errs = append(errs, fmt.Errorf("signing: %w", err))
+	}
+
+	if err := c.Update.validate(); err != nil {
+		errs = append(errs, fmt.Errorf("update: %w", err))
+	}
+
+	seen := make(map[string]bool)
+	for i, sc := range c.Schedules {
+		if err := sc.validate(); err != nil {
+			errs = append(errs, fmt.Errorf("schedules[%d]: %w", i, err))
+			continue
+		}
+		if name := sc.ScheduleName(); seen[name] {
+			errs = append(errs, fmt.Errorf("schedules[%d]: duplicate name %q", i, name))
+		} else {
+			seen[name] = true
+		}
+	}
+
+	for name, command := range c.Hooks {
+		if !hooks.ValidName(name) {
+			errs = append(errs, fmt.Errorf("hooks.%s: unknown hook (want pre_ or post_ followed by scan, discover, portscan, probe, vulnscan or diff)", name))
+			continue
+		}
+		if _, err := hooks.Parse(name, command); err != nil {
+			errs = append(errs, fmt.Errorf("hooks.%s: %w", name, err))
+		}
+	}
+
+	if c.Server.RateLimit < 0 {
+		errs = append(errs, errors.New("server.rate_limit must not be negative"))
+	}
+	if c.Server.QueueSize < 0 {
+		errs = append(errs, errors.New("server.queue_size must not be negative"))
+	}
+	if g := c.Server.ShutdownGrace; g != "" {
+		if _, err := time.ParseDuration(g); err != nil {
+			errs = append(errs, fmt.Errorf("server.shutdown_grace %q: %w", g, err))
+		}
+	}
+	if t := c.Server.TLS; (t.CertFile == "") != (t.KeyFile == "") {
+		errs = append(errs, errors.New("server.tls: cert_file and key_file must be set together"))
+	} else if t.ClientCAFile != "" && t.CertFile == "" && !t.SelfSigned {
+		errs = append(errs, errors.New("server.tls.client_ca_file requires cert_file/key_file or self_signed"))
+	}
+
 	if len(errs) > 0 {
 		return errors.Join(errs...)
 	}
 
 	return nil
 }
+
+// validate checks the channel and repository
+func (c UpdateConfig) validate() error {
+	switch c.Channel {
+	case "", selfupdate.ChannelStable, selfupdate.ChannelBeta:
+	default:
+		return fmt.Errorf("unknown channel %q (want stable or beta)", c.Channel)
+	}
+	if c.Repo != "" && strings.Count(c.Repo, "/") != 1 {
+		return fmt.Errorf("repo %q must be owner/name", c.Repo)
+	}
+	return nil
+}
+
+// validate checks the expression, targets and webhook
+func (c ScheduleConfig) validate() error {
+	if c.Cron == "" {
+		return errors.New("cron is required")
+	}
+	if _, err := schedule.Parse(c.Cron); err != nil {
+		return err
+	}
+	if len(c.Targets) == 0 {
+		return errors.New("targets cannot be empty")
+	}
+	for _, t := range c.Targets {
+		if strings.TrimSpace(t) == "" {
+			return errors.New("targets cannot contain an empty entry")
+		}
+	}
+	// A URL built from ${ENV} is only known when it is sent
+	if c.Webhook != "" && !strings.Contains(c.Webhook, "${") {
+		u, err := url.Parse(c.Webhook)
+		if err != nil || u.Host == "" {
+			return fmt.Errorf("webhook %q must be an http(s), slack:// or discord:// URL", c.Webhook)
+		}
+		switch u.Scheme {
+		case "http", "https", "slack", "discord":
+		default:
+			return fmt.Errorf("webhook %q must be an http(s), slack:// or discord:// URL", c.Webhook)
+		}
+	}
+	return nil
+}
+
+// validate checks the issue tracker settings when a provider is set
+func (c IssuesConfig) validate() error {
+	switch c.Provider {
+	case "":
+		return nil
+	case "github", "gitlab":
+	default:
+		return fmt.Errorf("unknown provider %q (want github or gitlab)", c.Provider)
+	}
+	if c.Repo == "" || c.Token == "" {
+		return fmt.Errorf("%s requires repo and token", c.Provider)
+	}
+	if c.Provider == "github" && strings.Count(c.Repo, "/") != 1 {
+		return fmt.Errorf("github repo %q must be owner/name", c.Repo)
+	}
+	if c.Timeout != "" {
+		if _, err := time.ParseDuration(c.Timeout); err != nil {
+			return fmt.Errorf("invalid timeout %q: %w", c.Timeout, err)
+		}
+	}
+	return nil
+}
+
+// validate checks that an output sink has the fields its type requires
+func (s OutputSinkConfig) validate() error {
+	switch s.Type {
+	case "elasticsearch", "opensearch":
+		if s.URL == "" {
+			return fmt.Errorf("%s sink requires url", s.Type)
+		}
+		if s.APIKey != "" && (s.Username != "" || s.Password != "") {
+			return fmt.Errorf("%s sink: set either username/password or api_key, not both", s.Type)
+		}
+		if err := validIndexTemplate(s.Index); err != nil {
+			return err
+		}
+	case "postgres":
+		if s.DSN == "" {
+			return errors.New("postgres sink requires dsn")
+		}
+	case "confluence":
+		if s.URL == "" || s.Space == "" {
+			return errors.New("confluence sink requires url and space")
+		}
+		if s.APIKey != "" && (s.Username != "" || s.Password != "") {
+			return errors.New("confluence sink: set either username/password or api_key, not both")
+		}
+	default:
+		return fmt.Errorf("unknown sink type %q (want elasticsearch, opensearch, postgres or confluence)", s.Type)
+	}
+
+	if s.Timeout != "" {
+		if _, err := time.ParseDuration(s.Timeout); err != nil {
+			return fmt.Errorf("invalid timeout %q: %w", s.Timeout, err)
+		}
+	}
+
+	return nil
+}
+
+// indexPlaceholder matches {name} placeholders in an output sink index.
+var indexPlaceholder = regexp.MustCompile(`\{[^}]*\}`)
+
+// validIndexTemplate rejects unknown placeholders and characters that
+// Elasticsearch does not allow in index names.
+func validIndexTemplate(index string) error {
+	for _, m := range indexPlaceholder.FindAllString(index, -1) {
+		switch m {
+		case "{target}", "{date}", "{kind}", "{scan_id}":
+		default:
+			return fmt.Errorf("index %q: unknown placeholder %s", index, m)
+		}
+	}
+	if strings.ContainsAny(index, ` \/*?"<>|,#:`) {
+		return fmt.Errorf("index %q contains characters not allowed in index names", index)
+	}
+	return nil
+}
+
+// validResolver reports whether r can be passed to dig as @server: a host
+// name or IP, not something dig would read as an option.
+func validResolver(r string) bool {
+	return r != "" && !strings.ContainsAny(r, " @/") && !strings.HasPrefix(r, "-")
+}
+
+// validate checks that a report sink has the fields its type requires
+func (s ReportSinkConfig) validate() error {
+	switch s.Type {
+	case "stdout":
+	case "file":
+		if s.Dir == "" {
+			return errors.New("file sink requires dir")
+		}
+	case "webhook":
+		if s.URL == "" {
+			return errors.New("webhook sink requires url")
+		}
+	case "s3":
+		if s.Bucket == "" {
+			return errors.New("s3 sink requires bucket")
+		}
+	default:
+		return fmt.Errorf("unknown sink type %q (want stdout, file, webhook, or s3)", s.Type)
+	}
+
+	if s.Timeout != "" {
+		if _, err := time.ParseDuration(s.Timeout); err != nil {
+			return fmt.Errorf("invalid timeout %q: %w", s.Timeout, err)
+		}
+	}
+
+	return nil
+}
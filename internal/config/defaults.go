@@ -8,14 +8,20 @@ import (
 // DefaultConfig returns a Config with sensible default values
 func DefaultConfig() *Config {
 	return &Config{
-		ScanDir: "scans",
-		DBPath:  "reconpipe.db",
+		ScanDir:  "scans",
+		DBPath:   "reconpipe.db",
+		DBDriver: "bbolt",
 		Tools: ToolsConfig{
 			Subfinder: ToolConfig{
 				Path:    "subfinder",
 				Args:    []string{"-silent"},
 				Timeout: "5m",
 			},
+			Amass: ToolConfig{
+				Path:    "amass",
+				Args:    []string{"enum"},
+				Timeout: "30m",
+			},
 			Tlsx: ToolConfig{
 				Path:    "tlsx",
 				Args:    []string{"-silent"},
@@ -31,6 +37,11 @@ func DefaultConfig() *Config {
 				Args:    []string{"-p1-65535", "--rate=1000"},
 				Timeout: "5m",
 			},
+			Naabu: ToolConfig{
+				Path:    "naabu",
+				Args:    []string{"-silent"},
+				Timeout: "5m",
+			},
 			Nmap: ToolConfig{
 				Path:    "nmap",
 				Args:    []string{"-sV", "-Pn"},
@@ -64,6 +75,8 @@ func DefaultConfig() *Config {
 			HttpxThreads:     25,
 			NucleiThreads:    10,
 			NucleiRateLimit:  150,
+			DNSWorkers:       20,
+			DNSTimeout:       "10s",
 		},
 		Stages: StagesConfig{
 			Enable: []string{},
@@ -81,9 +94,70 @@ func WriteDefault(path string) error {
 # Directory where scan results will be stored
 scan_dir: scans
 
-# Path to bbolt database for scan metadata
+# Scan folder naming: {target} {timestamp} {date} {time} {preset} {tag}
+scan_layout:
+  dir_template: "{target}_{timestamp}"
+  raw_dir: raw
+  reports_dir: reports
+  screenshots_dir: screenshots
+
+# Path to the database for scan metadata
 db_path: reconpipe.db
 
+# Database backend: bbolt, or sqlite to query the history with SQL and let
+# several reconpipe processes share the database
+db_driver: bbolt
+
+# Name recorded on scans and in the audit log. Empty uses --operator,
+# $RECONPIPE_OPERATOR or the login name, in that order.
+operator: ""
+
+# Analyst mode: only history, show, search, diff, report, export and
+# validate-scan (check only, no --repair) run, and the database is opened
+# read-only. Same as --read-only.
+read_only: false
+
+# Allowed targets, e.g. [example.com, "*.example.com"]. Empty allows any.
+# --scope-domains overrides this.
+scope_domains: []
+
+# File of IPs/CIDRs that are never scanned (masscan --excludefile or naabu
+# -exclude-file, and dropped from nmap, httpx and nuclei targets)
+exclude_file: ""
+
+# Mapping of template IDs to framework requirements (OWASP, CIS, PCI DSS, ...)
+compliance_file: ""
+
+# Warn when 'reconpipe report' renders data older than this (0 = never)
+stale_after: 720h
+
+# Per-target limits; --ignore-policy (or ignore_policy in the API) skips them.
+policy:
+  cooldown: ""              # minimum time between scans of one target, e.g. 24h
+  allow_concurrent: false   # allow more than one scan of a target at a time
+
+# Scans 'reconpipe monitor' runs on a schedule; webhook fires on new findings
+schedules: []
+  # - name: nightly
+  #   cron: "0 3 * * *"       # or @daily, "@every 6h"
+  #   targets: [example.com]
+  #   preset: quick-recon
+  #   webhook: https://hooks.example.com/recon
+
+# Which findings fail a run (exit 2), raise alerts, send the completion
+# notification and open issues; empty keeps each default
+risk_policy:
+  assets: {}                # asset tag -> hostname globs, e.g. prod: ["www.*", "api.*"]
+  fail_on: []               # e.g. [{min_severity: high, new: true}]
+  alert_on: []              # default: every finding but noise
+  notify_on: []             # default: every scan
+  ticket_on: []             # default: every newly dangling subdomain
+
+# Memory limits for very large programs (100k+ subdomains)
+memory:
+  budget_mb: 0              # soft heap limit in MB; 0 = none
+  probe_batch_size: 0       # targets per httpx run; 0 = from budget_mb, or all at once
+
 # External tool configurations
 tools:
   subfinder:
@@ -91,6 +165,11 @@ tools:
     args:
       - -silent
     timeout: 5m
+  amass:
+    path: amass
+    args:
+      - enum
+    timeout: 30m
   tlsx:
     path: tlsx
     args:
@@ -107,6 +186,11 @@ tools:
       - -p1-65535
       - --rate=1000
     timeout: 5m
+  naabu:
+    path: naabu
+    args:
+      - -silent
+    timeout: 5m
   nmap:
     path: nmap
     args:
@@ -137,16 +221,142 @@ tools:
 # Rate limiting settings for tools
 rate_limits:
   subfinder_threads: 10
-  masscan_rate: 1000
-  nmap_max_parallel: 5
+  masscan_rate: 1000   # packets/second, for naabu too
+  nmap_max_parallel: 5  # hosts fingerprinted at once
   httpx_threads: 25
   nuclei_threads: 10
   nuclei_rate_limit: 150
+  dns_workers: 20      # subdomains resolved at once
+  dns_timeout: 10s     # per subdomain, across all its lookups
+
+# Profiles per CDN/WAF provider (built-in: cloudflare, akamai, fastly,
+# cloudfront, incapsula, sucuri — not port scanned, slower httpx/nuclei)
+provider_profiles:
+  skip_builtin: false  # true = only the profiles below
+  profiles: {}         # e.g. cloudflare: {nuclei_rate_limit: 10, timeout: 30s}
 
 # Pipeline stage control
 stages:
   enable: []  # Enable only specific stages (empty = all enabled)
   skip: []    # Skip specific stages
+
+# Discovery enhancements
+discovery:
+  engine: subfinder    # subfinder, amass, or both (results merged)
+  permutations:
+    enabled: false     # also enabled by --permutations or the bug-bounty/internal-pentest presets
+    patterns: []       # empty = built-in list (-dev, dev-, 01..09, ...)
+    max_candidates: 0  # 0 = 2000
+  bruteforce:
+    enabled: false     # also enabled by --bruteforce
+    wordlist: ""       # one label per line; empty = built-in list
+    mutate: false      # also combine the words with resolved names (dev.api, dev-api, api-dev)
+    max_candidates: 0  # 0 = 5000
+  zone_transfer: false # attempt AXFR against each authoritative NS (also --axfr)
+  wildcard_filter: false # drop names resolving only to wildcard DNS addresses instead of flagging them
+  dns_health:
+    skip: false        # DNSSEC and CAA checks on the apex and key subdomains
+    subdomains: []     # empty = www, mail, api, app, login, auth, portal, vpn
+    resolver: ""       # validating resolver for DNSSEC, e.g. 1.1.1.1; empty = system
+  dns_consensus:
+    enabled: false     # keep only answers a quorum of resolvers agree on (also --dns-consensus)
+    resolvers: []      # empty = 1.1.1.1, 8.8.8.8, 9.9.9.9
+    quorum: 0          # resolvers that must return an answer; 0 = a majority
+  dns_resolver:
+    mode: dig          # dig, native for Go's resolver (no dnsutils; AXFR and DNS health still need dig), or dnsx for bulk resolution
+    nameservers: []    # native and dnsx modes; empty = system resolvers, e.g. ["1.1.1.1", "8.8.8.8:53"]
+  passive_sources:
+    skip: []           # crtsh, wayback, securitytrails (queried directly, besides subfinder)
+    securitytrails_api_key: "" # SecurityTrails runs only with a key; supports ${ENV}
+    timeout: ""        # per source, default 60s
+
+# Post-fingerprint checks
+portscan:
+  scanner: masscan         # masscan (needs root), or naabu for unprivileged and CI runs
+  udp:
+    enabled: false         # also scan UDP services with nmap -sU, needs root (also --udp)
+    ports: []              # empty = 53, 69, 111, 123, 137, 161, 500, 623, 1434, 1900, 5060, 5353, 11211
+  mail_checks:
+    skip: false            # STARTTLS, certificate and open-relay checks on mail ports
+    skip_relay_test: false # the relay test stops at RCPT TO and never sends DATA
+    timeout: ""            # per service, default 10s
+  geoip_db: ""             # GeoLite2-City/-Country .mmdb adding country and city to hosts
+
+# HTTP probe stage
+probe:
+  live_status: []      # responses counted as live; empty = 2xx, 3xx, 401, 403
+  filter: ""           # probe only matching targets, e.g. "port in (80, 443) && !is_cdn"
+  screenshots:
+    engine: ""         # gowitness or chromedp; empty = gowitness if installed, else chromedp
+    status_codes: []   # e.g. ["2xx", "401", "403"]; empty = 2xx only
+    full_page: false   # capture the full scrollable page instead of the viewport
+    width: 0           # browser window size in pixels, 0 = engine default
+    height: 0
+    delay: ""          # wait after page load before capturing, e.g. "2s"
+    save_dom: false    # chromedp only: save the rendered HTML next to each screenshot
+    batch_size: 0      # gowitness only: URLs per gowitness run, 0 = 25
+    parallel_batches: 0 # gowitness only: runs at once, 0 = 2
+    triage:
+      skip: false
+      skip_ocr: false  # don't OCR captures that have no DOM text
+      rules: []        # extra keyword rules: {tag, pattern, score}
+  body_scan:
+    enabled: false     # scan response bodies for secrets and leaks
+    rules: []          # extra rules: {id, name, pattern, severity, secret}
+  protocols:
+    skip: false        # HTTP/2 (ALPN), HTTP/3 (Alt-Svc + QUIC), gRPC and WebSocket detection
+    timeout: ""        # per service, default 5s
+  method_checks:
+    enabled: false     # OPTIONS, TRACE and a random path per service (allowed methods, soft 404)
+    timeout: ""        # per request, default 5s
+  retry:
+    skip: false        # retry targets httpx got no response from, once
+    threads: 0         # 0 = a quarter of httpx_threads
+    rate_limit: 0      # requests per second, 0 = httpx default
+  clones:
+    skip: false        # hash response bodies to find the same app on different hosts
+  origin_check:
+    skip: false        # find the origins behind CDN-fronted names and test them for a CDN bypass
+
+# Vulnerability scan stage: skip slow or failing hosts instead of waiting
+vulnscan:
+  timeout: ""          # nuclei per-request timeout, default 10s
+  max_host_error: 0    # errors before a host is skipped, 0 = nuclei default (30)
+  scan_strategy: ""    # auto, host-spray or template-spray
+  batch_size: 0        # targets per nuclei run under a deadline, 0 = 50
+  chunk_size: 0        # max targets per nuclei run, 0 = no cap
+  list_file: false     # pass targets as a -list file instead of stdin
+  filter: ""           # scan only matching targets, e.g. "status_code == 200"
+  severity_overrides: {} # template ID -> severity, e.g. tech-detect: info
+  noise:
+    rules: []          # findings to report in an appendix, e.g. - severities: [info]
+    alert: false       # send finding alerts for noise too
+
+# Extra report destinations (reports are always written to the scan directory)
+report_sinks: []
+
+# Scan result destinations (elasticsearch, opensearch, postgres, confluence)
+output_sinks: []
+
+# Commands run before/after the scan or a stage (pre_scan, post_vulnscan, ...)
+hooks: {}
+
+# 'reconpipe serve' API; create tokens with 'reconpipe token create'
+server:
+  listen: 127.0.0.1:8080
+  rate_limit: 60       # requests per minute per token
+  queue_size: 16
+  shutdown_grace: 1m   # wait for a running scan's stage on SIGTERM
+  preemption: false    # high-priority scans pause running lower-priority ones
+
+# GitHub/GitLab issues for newly dangling subdomains (provider, repo, token)
+issues: {}
+
+# Detached signatures for diff reports (method: gpg or ed25519, key, public_key)
+signing: {}
+
+# Release channel and key for self-update (channel: stable or beta, public_key)
+update: {}
 `
 
 	if err := os.WriteFile(path, []byte(yamlContent), 0644); err != nil {
@@ -0,0 +1,106 @@
+package config
+
+import (
+	"net/url"
+	"os"
+)
+
+// Redacted returns a copy of c without its credentials, for recording with
+// a scan: API keys, tokens, passwords, DSNs, sink header values and
+// schedule webhooks keep only values made entirely of ${ENV} references,
+// and URLs lose the password in their userinfo. RestoreCredentials puts
+// them back from a live config.
+func (c *Config) Redacted() *Config {
+	out := *c
+
+	out.Discovery.PassiveSources.SecurityTrailsAPIKey = redactSecret(c.Discovery.PassiveSources.SecurityTrailsAPIKey)
+	out.Issues.Token = redactSecret(c.Issues.Token)
+
+	out.ReportSinks = make([]ReportSinkConfig, len(c.ReportSinks))
+	for i, s := range c.ReportSinks {
+		s.URL = redactURL(s.URL)
+		if s.Headers != nil {
+			headers := make(map[string]string, len(s.Headers))
+			for k, v := range s.Headers {
+				headers[k] = redactSecret(v)
+			}
+			s.Headers = headers
+		}
+		out.ReportSinks[i] = s
+	}
+
+	out.OutputSinks = make([]OutputSinkConfig, len(c.OutputSinks))
+	for i, s := range c.OutputSinks {
+		s.URL = redactURL(s.URL)
+		s.Password = redactSecret(s.Password)
+		s.APIKey = redactSecret(s.APIKey)
+		s.DSN = redactSecret(s.DSN)
+		out.OutputSinks[i] = s
+	}
+
+	out.Schedules = make([]ScheduleConfig, len(c.Schedules))
+	for i, s := range c.Schedules {
+		s.Webhook = redactSecret(s.Webhook)
+		out.Schedules[i] = s
+	}
+
+	return &out
+}
+
+// RestoreCredentials copies the credentials Redacted removes from live into
+// c, a config restored from a scan record. Sinks are matched by position
+// and type, schedules by name, and the issue tracker by provider, repo and
+// URL; anything without a counterpart in live is left without credentials.
+func (c *Config) RestoreCredentials(live *Config) {
+	c.Discovery.PassiveSources.SecurityTrailsAPIKey = live.Discovery.PassiveSources.SecurityTrailsAPIKey
+	if c.Issues.Provider == live.Issues.Provider && c.Issues.Repo == live.Issues.Repo && c.Issues.URL == live.Issues.URL {
+		c.Issues.Token = live.Issues.Token
+	}
+
+	for i := range c.ReportSinks {
+		if i >= len(live.ReportSinks) || live.ReportSinks[i].Type != c.ReportSinks[i].Type {
+			continue
+		}
+		c.ReportSinks[i].URL = live.ReportSinks[i].URL
+		c.ReportSinks[i].Headers = live.ReportSinks[i].Headers
+	}
+
+	for i := range c.OutputSinks {
+		if i >= len(live.OutputSinks) || live.OutputSinks[i].Type != c.OutputSinks[i].Type {
+			continue
+		}
+		s, l := &c.OutputSinks[i], live.OutputSinks[i]
+		s.URL, s.Username, s.Password, s.APIKey, s.DSN = l.URL, l.Username, l.Password, l.APIKey, l.DSN
+	}
+
+	for i := range c.Schedules {
+		c.Schedules[i].Webhook = ""
+		for _, l := range live.Schedules {
+			if l.ScheduleName() == c.Schedules[i].ScheduleName() {
+				c.Schedules[i].Webhook = l.Webhook
+				break
+			}
+		}
+	}
+}
+
+// redactSecret keeps v only when it holds nothing but ${ENV} references.
+func redactSecret(v string) string {
+	if os.Expand(v, func(string) string { return "" }) == "" {
+		return v
+	}
+	return ""
+}
+
+// redactURL drops the password from a URL's userinfo.
+func redactURL(v string) string {
+	u, err := url.Parse(v)
+	if err != nil || u.User == nil {
+		return v
+	}
+	if _, ok := u.User.Password(); !ok {
+		return v
+	}
+	u.User = url.User(u.User.Username())
+	return u.String()
+}
@@ -0,0 +1,256 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// checkKeys reads the YAML config at path and checks it against the Config
+// schema before viper decodes it. viper drops keys it does not know, so a
+// typo such as rate_limtis would otherwise leave the defaults in place and
+// only show up later as a confusing validation error, if at all. Every
+// unknown key and every value of the wrong shape is reported with its
+// file:line:col, plus the closest valid key when a misspelling is likely.
+func checkKeys(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("%s: %w", filepath.Base(path), err)
+	}
+	if len(doc.Content) == 0 {
+		return nil
+	}
+
+	c := &schemaChecker{file: filepath.Base(path)}
+	c.check(doc.Content[0], reflect.TypeOf(Config{}), "")
+	return errors.Join(c.errs...)
+}
+
+// schemaChecker walks a YAML node tree alongside the Go type it decodes into.
+type schemaChecker struct {
+	file string
+	errs []error
+}
+
+func (c *schemaChecker) errorf(n *yaml.Node, format string, args ...any) {
+	c.errs = append(c.errs, fmt.Errorf("%s:%d:%d: %s", c.file, n.Line, n.Column, fmt.Sprintf(format, args...)))
+}
+
+// check reports where n does not fit t. path is the dotted key of n, used
+// in messages; it is empty for the document root.
+func (c *schemaChecker) check(n *yaml.Node, t reflect.Type, path string) {
+	for n.Kind == yaml.AliasNode {
+		n = n.Alias
+	}
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if isNull(n) {
+		return
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		if n.Kind != yaml.MappingNode {
+			c.errorf(n, "%s must be a mapping of settings", describe(path))
+			return
+		}
+		fields := structFields(t)
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			key, value := n.Content[i], n.Content[i+1]
+			if key.Value == "<<" {
+				// Merge key: the merged mapping holds settings of this section
+				c.check(value, t, path)
+				continue
+			}
+			field, ok := fields[strings.ToLower(key.Value)]
+			if !ok {
+				msg := fmt.Sprintf("unknown key %q in %s", key.Value, describe(path))
+				if s := closestKey(key.Value, fields); s != "" {
+					msg += fmt.Sprintf(" (did you mean %q?)", s)
+				}
+				c.errorf(key, "%s", msg)
+				continue
+			}
+			c.check(value, field, joinPath(path, key.Value))
+		}
+
+	case reflect.Map:
+		if n.Kind != yaml.MappingNode {
+			c.errorf(n, "%s must be a mapping", describe(path))
+			return
+		}
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			c.check(n.Content[i+1], t.Elem(), joinPath(path, n.Content[i].Value))
+		}
+
+	case reflect.Slice:
+		switch n.Kind {
+		case yaml.SequenceNode:
+			for i, item := range n.Content {
+				c.check(item, t.Elem(), fmt.Sprintf("%s[%d]", path, i))
+			}
+		case yaml.ScalarNode:
+			// viper splits a comma-separated string into a string list
+			if t.Elem().Kind() != reflect.String {
+				c.errorf(n, "%s must be a list", describe(path))
+			}
+		default:
+			c.errorf(n, "%s must be a list", describe(path))
+		}
+
+	case reflect.Interface:
+		// Free-form value
+
+	default:
+		if n.Kind != yaml.ScalarNode {
+			c.errorf(n, "%s must be a single value, not a %s", describe(path), kindName(n))
+			return
+		}
+		c.checkScalar(n, t, path)
+	}
+}
+
+// checkScalar reports scalars that cannot be converted to t. viper decodes
+// weakly, so "1" is a valid bool and 10 a valid string; only values that
+// would fail to decode are reported.
+func (c *schemaChecker) checkScalar(n *yaml.Node, t reflect.Type, path string) {
+	v := n.Value
+	switch t.Kind() {
+	case reflect.Bool:
+		if n.Tag == "!!bool" {
+			return
+		}
+		if _, err := strconv.ParseBool(v); err != nil && n.Tag != "!!int" {
+			c.errorf(n, "%s must be true or false, got %q", describe(path), v)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if _, err := strconv.ParseInt(v, 0, 64); err != nil && n.Tag != "!!bool" {
+			c.errorf(n, "%s must be a whole number, got %q", describe(path), v)
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if _, err := strconv.ParseUint(v, 0, 64); err != nil && n.Tag != "!!bool" {
+			c.errorf(n, "%s must be a non-negative whole number, got %q", describe(path), v)
+		}
+	case reflect.Float32, reflect.Float64:
+		if _, err := strconv.ParseFloat(v, 64); err != nil && n.Tag != "!!bool" {
+			c.errorf(n, "%s must be a number, got %q", describe(path), v)
+		}
+	}
+}
+
+// structFields maps the lowercased key of each field of t to its type,
+// following mapstructure: the tag name, else the field name, with
+// ",squash" fields flattened into t.
+func structFields(t reflect.Type) map[string]reflect.Type {
+	fields := make(map[string]reflect.Type)
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name, opts, _ := strings.Cut(f.Tag.Get("mapstructure"), ",")
+		if name == "-" {
+			continue
+		}
+		if strings.Contains(opts, "squash") {
+			for k, v := range structFields(f.Type) {
+				fields[k] = v
+			}
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		fields[strings.ToLower(name)] = f.Type
+	}
+	return fields
+}
+
+// closestKey returns the valid key nearest to key, or "" when none is close
+// enough to be a plausible misspelling.
+func closestKey(key string, fields map[string]reflect.Type) string {
+	key = strings.ToLower(key)
+	best, bestDist := "", len(key)/3+1
+	for name := range fields {
+		if d := editDistance(key, name); d < bestDist || (d == bestDist && best != "" && name < best) {
+			best, bestDist = name, d
+		}
+	}
+	return best
+}
+
+// editDistance is the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+func isNull(n *yaml.Node) bool {
+	return n.Kind == yaml.ScalarNode && n.Tag == "!!null"
+}
+
+func kindName(n *yaml.Node) string {
+	if n.Kind == yaml.SequenceNode {
+		return "list"
+	}
+	return "mapping"
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// describe names the section at path for messages.
+func describe(path string) string {
+	if path == "" {
+		return "the top level"
+	}
+	return path
+}
+
+// FileReadOnly reports whether the config file at path sets read_only. Only
+// that key is decoded, so commands that do not load the config, or are
+// about to rewrite it, can still honour it. A missing or unparseable file
+// is not read-only.
+func FileReadOnly(path string) bool {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return false
+	}
+	var c struct {
+		ReadOnly bool `yaml:"read_only"`
+	}
+	if err := yaml.Unmarshal(data, &c); err != nil {
+		return false
+	}
+	return c.ReadOnly
+}
@@ -0,0 +1,110 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// watchDebounce is how long Watch waits after the last change to the file
+// before reloading it; editors often write a file in several steps.
+const watchDebounce = 500 * time.Millisecond
+
+// Watch reloads the config file at path whenever it changes, until ctx is
+// cancelled. Each successfully loaded and validated config is passed to
+// onChange; a file that fails to load or validate is passed to onError
+// instead and the watch continues. Saves that leave the content unchanged
+// are ignored.
+//
+// The file's directory is watched rather than the file itself, so a save
+// that replaces the file (write to a temp file, then rename) is seen too.
+func Watch(ctx context.Context, path string, onChange func(*Config), onError func(error)) error {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return fmt.Errorf("resolving %s: %w", path, err)
+	}
+	last, err := os.ReadFile(abs)
+	if err != nil {
+		return fmt.Errorf("reading %s: %w", path, err)
+	}
+
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("watching %s: %w", path, err)
+	}
+	if err := w.Add(filepath.Dir(abs)); err != nil {
+		w.Close()
+		return fmt.Errorf("watching %s: %w", path, err)
+	}
+
+	go func() {
+		defer w.Close()
+
+		timer := time.NewTimer(watchDebounce)
+		timer.Stop()
+		defer timer.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+
+			case ev, ok := <-w.Events:
+				if !ok {
+					return
+				}
+				if filepath.Clean(ev.Name) != abs || !ev.Has(fsnotify.Write|fsnotify.Create|fsnotify.Rename) {
+					continue
+				}
+				timer.Reset(watchDebounce)
+
+			case err, ok := <-w.Errors:
+				if !ok {
+					return
+				}
+				onError(fmt.Errorf("watching %s: %w", path, err))
+
+			case <-timer.C:
+				data, err := os.ReadFile(abs)
+				if err != nil {
+					if !os.IsNotExist(err) { // mid-rename; the create event follows
+						onError(fmt.Errorf("reading %s: %w", path, err))
+					}
+					continue
+				}
+				if bytes.Equal(data, last) {
+					continue
+				}
+				last = data
+
+				c, err := Load(abs)
+				if err != nil {
+					onError(err)
+					continue
+				}
+				onChange(c)
+			}
+		}
+	}()
+
+	return nil
+}
+
+// ChangedKeys lists the top-level config keys (such as rate_limits or
+// server) whose settings differ between a and b.
+func ChangedKeys(a, b *Config) []string {
+	av, bv := reflect.ValueOf(a).Elem(), reflect.ValueOf(b).Elem()
+	var keys []string
+	for i := 0; i < av.NumField(); i++ {
+		if !reflect.DeepEqual(av.Field(i).Interface(), bv.Field(i).Interface()) {
+			keys = append(keys, av.Type().Field(i).Tag.Get("mapstructure"))
+		}
+	}
+	return keys
+}
@@ -0,0 +1,105 @@
+package diff
+
+import (
+	"github.com/hakim/reconpipe/internal/storage"
+)
+
+// diffSection is the part of a DiffResult computed from one raw file of
+// each snapshot.
+type diffSection struct {
+	file    string
+	compute func(dr *DiffResult, current, previous *ScanSnapshot)
+	reuse   func(dr, prior *DiffResult)
+}
+
+var diffSections = []diffSection{
+	{
+		file: "subdomains.json",
+		compute: func(dr *DiffResult, current, previous *ScanSnapshot) {
+			diffSubdomains(dr, current.Subdomains, previous.Subdomains)
+		},
+		reuse: func(dr, prior *DiffResult) {
+			dr.NewSubdomains = nonNil(prior.NewSubdomains)
+			dr.RemovedSubdomains = nonNil(prior.RemovedSubdomains)
+			dr.NewlyDangling = nonNil(prior.NewlyDangling)
+			dr.PersistentlyDangling = nonNil(prior.PersistentlyDangling)
+			dr.ResolvedDangling = nonNil(prior.ResolvedDangling)
+		},
+	},
+	{
+		file: "ports.json",
+		compute: func(dr *DiffResult, current, previous *ScanSnapshot) {
+			diffPorts(dr, current.Hosts, previous.Hosts)
+		},
+		reuse: func(dr, prior *DiffResult) {
+			dr.NewPorts = nonNil(prior.NewPorts)
+			dr.ClosedPorts = nonNil(prior.ClosedPorts)
+		},
+	},
+	{
+		file: "vulns.json",
+		compute: func(dr *DiffResult, current, previous *ScanSnapshot) {
+			diffVulns(dr, current.Vulnerabilities, previous.Vulnerabilities)
+		},
+		reuse: func(dr, prior *DiffResult) {
+			dr.NewVulns = nonNil(prior.NewVulns)
+			dr.ResolvedVulns = nonNil(prior.ResolvedVulns)
+		},
+	},
+}
+
+// ComputeDiffCached is ComputeDiff for two snapshots loaded from disk. A
+// section is copied from prior, the scan's last diff, when sums shows it was
+// computed against the same previous scan and neither scan's raw file for
+// it has changed since. The inputs of every section are recorded in sums.
+// prior may be nil. Returns the raw files whose sections were reused.
+func ComputeDiffCached(current, previous *ScanSnapshot, prior *DiffResult, sums *storage.Checksums) (*DiffResult, []string, error) {
+	// Start from the empty diff, whose slices are all non-nil
+	dr := ComputeDiff(&ScanSnapshot{}, &ScanSnapshot{})
+
+	var reused []string
+	for _, section := range diffSections {
+		cur, err := storage.HashRawFiles(current.ScanDir, section.file)
+		if err != nil {
+			return nil, nil, err
+		}
+		prev, err := storage.HashRawFiles(previous.ScanDir, section.file)
+		if err != nil {
+			return nil, nil, err
+		}
+		inputs := map[string]string{
+			"current":       cur[section.file],
+			"previous":      previous.ScanDir,
+			"previous_hash": prev[section.file],
+		}
+
+		output := "diff.json:" + section.file
+		if prior != nil && sums.Unchanged(output, inputs) {
+			section.reuse(dr, prior)
+			reused = append(reused, section.file)
+		} else {
+			section.compute(dr, current, previous)
+		}
+		sums.Record(output, inputs)
+	}
+
+	dr.CurrentSubdomainCount = len(current.Subdomains)
+	dr.PreviousSubdomainCount = len(previous.Subdomains)
+	dr.CurrentPortCount = totalPortCount(current.Hosts)
+	dr.PreviousPortCount = totalPortCount(previous.Hosts)
+	dr.CurrentVulnCount = len(current.Vulnerabilities)
+	dr.PreviousVulnCount = len(previous.Vulnerabilities)
+	dr.CollectedAt = current.Collected()
+	dr.PreviousCollectedAt = previous.Collected()
+
+	return dr, reused, nil
+}
+
+// nonNil returns s, or an empty slice when s is nil, keeping the DiffResult
+// guarantee that slice fields can be ranged over.
+func nonNil[T any](s []T) []T {
+	if s == nil {
+		return []T{}
+	}
+	return s
+}
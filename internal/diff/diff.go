@@ -10,26 +10,36 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"time"
 
-	"github.com/hakim/reconpipe/internal/models"
+	"github.com/hakim/reconpipe/internal/storage"
+	"github.com/hakim/reconpipe/pkg/models"
 )
 
 // ---------------------------------------------------------------------------
 // Local wrapper types for JSON unmarshaling.
-// These mirror the wrapper structs in the discovery, portscan, and vulnscan
-// packages without importing those packages (avoids circular imports).
+// These mirror the wrapper structs in the discovery, portscan, vulnscan, and
+// httpprobe packages without importing them (avoids circular imports).
 // ---------------------------------------------------------------------------
 
 type discoveryResult struct {
-	Subdomains []models.Subdomain `json:"subdomains"`
+	Subdomains  []models.Subdomain `json:"subdomains"`
+	CollectedAt time.Time          `json:"collected_at"`
 }
 
 type portScanResult struct {
-	Hosts []models.Host `json:"hosts"`
+	Hosts       []models.Host `json:"hosts"`
+	CollectedAt time.Time     `json:"collected_at"`
 }
 
 type vulnScanResult struct {
 	Vulnerabilities []models.Vulnerability `json:"vulnerabilities"`
+	CollectedAt     time.Time              `json:"collected_at"`
+}
+
+type httpProbeResult struct {
+	Probes      []models.HTTPProbe `json:"probes"`
+	CollectedAt time.Time          `json:"collected_at"`
 }
 
 // ---------------------------------------------------------------------------
@@ -37,21 +47,32 @@ type vulnScanResult struct {
 // ---------------------------------------------------------------------------
 
 // ScanSnapshot holds all structured data loaded from a single scan's raw output
-// directory. Fields are empty (nil) when the corresponding JSON file is absent.
-type ScanSnapshot struct {
-	ScanDir         string
-	Subdomains      []models.Subdomain
-	Hosts           []models.Host
-	Vulnerabilities []models.Vulnerability
+// directory. It is defined in pkg/models so that code outside this module can
+// build one.
+type ScanSnapshot = models.ScanSnapshot
+
+// CorruptFileError reports a raw JSON file that exists but cannot be parsed,
+// typically one truncated by a crash in a version that did not write raw
+// files atomically. Rerunning the stage that writes it replaces the file.
+type CorruptFileError struct {
+	Path string
+	Err  error
 }
 
-// LoadSnapshot reads the three canonical JSON files from {scanDir}/raw/ and
+func (e *CorruptFileError) Error() string {
+	return fmt.Sprintf("%s is corrupt (rerun the stage that writes it): %v", e.Path, e.Err)
+}
+
+func (e *CorruptFileError) Unwrap() error { return e.Err }
+
+// LoadSnapshot reads the canonical JSON files from {scanDir}/raw/ and
 // populates a ScanSnapshot. Missing files are treated as empty — they are not
-// an error condition because early-stage scans may not have all files.
+// an error condition because early-stage scans may not have all files. A file
+// that is present but unparsable is reported as a *CorruptFileError.
 func LoadSnapshot(scanDir string) (*ScanSnapshot, error) {
 	snap := &ScanSnapshot{ScanDir: scanDir}
 
-	rawDir := filepath.Join(scanDir, "raw")
+	rawDir := storage.RawDir(scanDir)
 
 	if err := loadSubdomains(rawDir, snap); err != nil {
 		return nil, fmt.Errorf("loading subdomains.json: %w", err)
@@ -65,51 +86,122 @@ func LoadSnapshot(scanDir string) (*ScanSnapshot, error) {
 		return nil, fmt.Errorf("loading vulns.json: %w", err)
 	}
 
+	if err := loadProbes(rawDir, snap); err != nil {
+		return nil, fmt.Errorf("loading http-probes.json: %w", err)
+	}
+
+	return snap, nil
+}
+
+// ResultStore reads the stage results saved in the scan database.
+type ResultStore interface {
+	GetScanResults(scanID string) (*storage.ScanResults, error)
+}
+
+// LoadScanSnapshot loads the snapshot of scan from its directory like
+// LoadSnapshot, taking each raw file missing from disk, or all of them when
+// the directory was deleted, from the stage results saved in store. A nil
+// store reads the directory only.
+func LoadScanSnapshot(store ResultStore, scan *models.ScanMeta) (*ScanSnapshot, error) {
+	snap, err := LoadSnapshot(scan.ScanDir)
+	if err != nil || store == nil {
+		return snap, err
+	}
+	results, err := store.GetScanResults(scan.ID)
+	if err != nil {
+		return nil, fmt.Errorf("loading saved results of scan %s: %w", scan.ID, err)
+	}
+	if results == nil {
+		return snap, nil
+	}
+
+	rawDir := storage.RawDir(scan.ScanDir)
+	for name := range results.Saved {
+		if scan.ScanDir != "" {
+			if _, err := os.Stat(filepath.Join(rawDir, name)); !errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+		}
+		switch name {
+		case storage.ResultSubdomains:
+			snap.Subdomains = results.Subdomains
+		case storage.ResultHosts:
+			snap.Hosts = results.Hosts
+		case storage.ResultProbes:
+			snap.Probes = results.Probes
+		case storage.ResultVulns:
+			snap.Vulnerabilities = results.Vulnerabilities
+		}
+		snap.SetCollected(name, results.CollectedAt[name])
+	}
 	return snap, nil
 }
 
 func loadSubdomains(rawDir string, snap *ScanSnapshot) error {
-	data, err := readOptionalFile(filepath.Join(rawDir, "subdomains.json"))
+	path := filepath.Join(rawDir, "subdomains.json")
+	data, err := readOptionalFile(path)
 	if err != nil || data == nil {
 		return err
 	}
 
 	var wrapper discoveryResult
 	if err := json.Unmarshal(data, &wrapper); err != nil {
-		return err
+		return &CorruptFileError{Path: path, Err: err}
 	}
 
 	snap.Subdomains = wrapper.Subdomains
+	snap.SetCollected("subdomains.json", wrapper.CollectedAt)
 	return nil
 }
 
 func loadHosts(rawDir string, snap *ScanSnapshot) error {
-	data, err := readOptionalFile(filepath.Join(rawDir, "ports.json"))
+	path := filepath.Join(rawDir, "ports.json")
+	data, err := readOptionalFile(path)
 	if err != nil || data == nil {
 		return err
 	}
 
 	var wrapper portScanResult
 	if err := json.Unmarshal(data, &wrapper); err != nil {
-		return err
+		return &CorruptFileError{Path: path, Err: err}
 	}
 
 	snap.Hosts = wrapper.Hosts
+	snap.SetCollected("ports.json", wrapper.CollectedAt)
 	return nil
 }
 
 func loadVulns(rawDir string, snap *ScanSnapshot) error {
-	data, err := readOptionalFile(filepath.Join(rawDir, "vulns.json"))
+	path := filepath.Join(rawDir, "vulns.json")
+	data, err := readOptionalFile(path)
 	if err != nil || data == nil {
 		return err
 	}
 
 	var wrapper vulnScanResult
 	if err := json.Unmarshal(data, &wrapper); err != nil {
-		return err
+		return &CorruptFileError{Path: path, Err: err}
 	}
 
 	snap.Vulnerabilities = wrapper.Vulnerabilities
+	snap.SetCollected("vulns.json", wrapper.CollectedAt)
+	return nil
+}
+
+func loadProbes(rawDir string, snap *ScanSnapshot) error {
+	path := filepath.Join(rawDir, "http-probes.json")
+	data, err := readOptionalFile(path)
+	if err != nil || data == nil {
+		return err
+	}
+
+	var wrapper httpProbeResult
+	if err := json.Unmarshal(data, &wrapper); err != nil {
+		return &CorruptFileError{Path: path, Err: err}
+	}
+
+	snap.Probes = wrapper.Probes
+	snap.SetCollected("http-probes.json", wrapper.CollectedAt)
 	return nil
 }
 
@@ -165,6 +257,19 @@ type DiffResult struct {
 	PreviousPortCount      int
 	CurrentVulnCount       int
 	PreviousVulnCount      int
+
+	// CollectedAt and PreviousCollectedAt are when the newest data of each
+	// snapshot was collected; zero for scans that predate the record.
+	CollectedAt         time.Time `json:",omitzero"`
+	PreviousCollectedAt time.Time `json:",omitzero"`
+
+	// SubdomainSeen, PortSeen and VulnSeen date the new and removed entries
+	// above from the target's earlier scans (see Date), keyed by subdomain
+	// name, PortChange.Key and VulnKey. Nil for a diff computed without the
+	// scan history.
+	SubdomainSeen map[string]Sighting `json:",omitempty"`
+	PortSeen      map[string]Sighting `json:",omitempty"`
+	VulnSeen      map[string]Sighting `json:",omitempty"`
 }
 
 // ---------------------------------------------------------------------------
@@ -191,6 +296,9 @@ func ComputeDiff(current, previous *ScanSnapshot) *DiffResult {
 	diffPorts(dr, current.Hosts, previous.Hosts)
 	diffVulns(dr, current.Vulnerabilities, previous.Vulnerabilities)
 
+	dr.CollectedAt = current.Collected()
+	dr.PreviousCollectedAt = previous.Collected()
+
 	// Summary counts
 	dr.CurrentSubdomainCount = len(current.Subdomains)
 	dr.PreviousSubdomainCount = len(previous.Subdomains)
@@ -334,9 +442,9 @@ func totalPortCount(hosts []models.Host) int {
 // Vulnerability diff
 // ---------------------------------------------------------------------------
 
-// vulnKey uniquely identifies a vulnerability finding.
+// VulnKey uniquely identifies a vulnerability finding across scans.
 // Format: "templateID::host"
-func vulnKey(v models.Vulnerability) string {
+func VulnKey(v models.Vulnerability) string {
 	return fmt.Sprintf("%s::%s", v.TemplateID, v.Host)
 }
 
@@ -344,12 +452,12 @@ func vulnKey(v models.Vulnerability) string {
 func diffVulns(dr *DiffResult, current, previous []models.Vulnerability) {
 	prevVulns := make(map[string]models.Vulnerability, len(previous))
 	for _, v := range previous {
-		prevVulns[vulnKey(v)] = v
+		prevVulns[VulnKey(v)] = v
 	}
 
 	currVulns := make(map[string]models.Vulnerability, len(current))
 	for _, v := range current {
-		currVulns[vulnKey(v)] = v
+		currVulns[VulnKey(v)] = v
 	}
 
 	// New: in current but not in previous
@@ -0,0 +1,119 @@
+package diff
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/hakim/reconpipe/pkg/models"
+)
+
+// Sighting dates a diff entry from the target's earlier scans.
+type Sighting struct {
+	// FirstSeen is when the entry was first observed: the earliest scan
+	// that had it, or the current scan for one never seen before.
+	FirstSeen time.Time `json:"first_seen"`
+	// LastSeenPrevious is the last earlier scan that had the entry, and
+	// ScansAgo how many scans back it is (1 = the previous scan). Zero for
+	// an entry never seen before.
+	LastSeenPrevious time.Time `json:"last_seen_previous,omitzero"`
+	ScansAgo         int       `json:"scans_ago,omitempty"`
+}
+
+// HistoryScan is one earlier scan of the target.
+type HistoryScan struct {
+	At       time.Time
+	Snapshot *ScanSnapshot
+}
+
+// LoadHistory loads the snapshots of scans, the target's scans that came
+// before the one being diffed, in any order, and returns them oldest
+// first. A scan whose directory is gone contributes the stage results saved
+// in store, or an empty snapshot when there are none.
+func LoadHistory(store ResultStore, scans []*models.ScanMeta) ([]HistoryScan, error) {
+	var history []HistoryScan
+	for _, scan := range scans {
+		snap, err := LoadScanSnapshot(store, scan)
+		if err != nil {
+			return nil, fmt.Errorf("loading %s: %w", scan.ScanDir, err)
+		}
+		history = append(history, HistoryScan{At: scan.StartedAt, Snapshot: snap})
+	}
+	sort.SliceStable(history, func(i, j int) bool { return history[i].At.Before(history[j].At) })
+	return history, nil
+}
+
+// Key returns the key PortSeen dates the port change under: its IP, port
+// number and protocol.
+func (pc PortChange) Key() string {
+	return portKey(pc.IP, pc.Port)
+}
+
+// Date fills SubdomainSeen, PortSeen and VulnSeen for the new and removed
+// entries of dr from history, the target's earlier scans oldest first. at
+// is when the current scan ran: the first sighting of an entry no earlier
+// scan had.
+func (dr *DiffResult) Date(history []HistoryScan, at time.Time) {
+	subdomains := make(sightings)
+	ports := make(sightings)
+	vulns := make(sightings)
+	for i, h := range history {
+		ago := len(history) - i
+		for _, s := range h.Snapshot.Subdomains {
+			subdomains.observe(s.Name, h.At, ago)
+		}
+		for _, host := range h.Snapshot.Hosts {
+			for _, p := range host.Ports {
+				ports.observe(portKey(host.IP, p), h.At, ago)
+			}
+		}
+		for _, v := range h.Snapshot.Vulnerabilities {
+			vulns.observe(VulnKey(v), h.At, ago)
+		}
+	}
+
+	dr.SubdomainSeen = make(map[string]Sighting)
+	for _, s := range dr.NewSubdomains {
+		dr.SubdomainSeen[s.Name] = subdomains.sighting(s.Name, at)
+	}
+	for _, s := range dr.RemovedSubdomains {
+		dr.SubdomainSeen[s.Name] = subdomains.sighting(s.Name, at)
+	}
+	dr.PortSeen = make(map[string]Sighting)
+	for _, pc := range dr.NewPorts {
+		dr.PortSeen[pc.Key()] = ports.sighting(pc.Key(), at)
+	}
+	for _, pc := range dr.ClosedPorts {
+		dr.PortSeen[pc.Key()] = ports.sighting(pc.Key(), at)
+	}
+	dr.VulnSeen = make(map[string]Sighting)
+	for _, v := range dr.NewVulns {
+		dr.VulnSeen[VulnKey(v)] = vulns.sighting(VulnKey(v), at)
+	}
+	for _, v := range dr.ResolvedVulns {
+		dr.VulnSeen[VulnKey(v)] = vulns.sighting(VulnKey(v), at)
+	}
+}
+
+// sightings collects the sightings of one kind of entry across scans
+// visited oldest first.
+type sightings map[string]*Sighting
+
+// observe records that the scan at, ago scans back, had key.
+func (s sightings) observe(key string, at time.Time, ago int) {
+	seen, ok := s[key]
+	if !ok {
+		seen = &Sighting{FirstSeen: at}
+		s[key] = seen
+	}
+	seen.LastSeenPrevious, seen.ScansAgo = at, ago
+}
+
+// sighting returns the sighting of key, first seen at now when no scan had
+// it.
+func (s sightings) sighting(key string, now time.Time) Sighting {
+	if seen, ok := s[key]; ok {
+		return *seen
+	}
+	return Sighting{FirstSeen: now}
+}
@@ -0,0 +1,87 @@
+package discovery
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hakim/reconpipe/internal/tools"
+	"github.com/hakim/reconpipe/pkg/models"
+)
+
+// ZoneTransferSource is the Source recorded for subdomains that were only
+// learned from a successful AXFR.
+const ZoneTransferSource = "axfr"
+
+// ZoneTransferTemplateID identifies the misconfiguration finding raised when
+// a nameserver allows a zone transfer.
+const ZoneTransferTemplateID = "dns-zone-transfer"
+
+// attemptZoneTransfers tries an AXFR of domain against each of its
+// authoritative nameservers. It returns the in-scope host names from every
+// transfer that succeeded, and one high-severity finding per nameserver that
+// allowed it.
+func attemptZoneTransfers(ctx context.Context, domain, digPath string) ([]string, []models.Vulnerability, error) {
+	nameservers, err := tools.LookupNS(ctx, domain, digPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(nameservers) == 0 {
+		return nil, nil, fmt.Errorf("no NS records for %s", domain)
+	}
+
+	names := make(map[string]bool)
+	var findings []models.Vulnerability
+	for _, ns := range nameservers {
+		fmt.Printf("Attempting zone transfer of %s from %s...\n", domain, ns)
+		records, err := tools.ZoneTransfer(ctx, domain, ns, digPath)
+		if err != nil {
+			fmt.Printf("Warning: %v\n", err)
+			continue
+		}
+		if len(records) == 0 {
+			continue
+		}
+
+		for _, rec := range records {
+			// Service labels (_sip._tcp) are not hosts worth probing
+			if rec.Name == domain || strings.HasPrefix(rec.Name, "_") || !inDomain(rec.Name, domain) {
+				continue
+			}
+			if normalized := normalizeSubdomain(rec.Name); normalized != "" {
+				names[normalized] = true
+			}
+		}
+
+		findings = append(findings, models.Vulnerability{
+			TemplateID: ZoneTransferTemplateID,
+			Name:       "DNS Zone Transfer (AXFR) Allowed",
+			Severity:   models.SeverityHigh,
+			Host:       ns,
+			Port:       53,
+			MatchedAt:  fmt.Sprintf("%s @%s", domain, ns),
+			Description: fmt.Sprintf("Nameserver %s allowed an unauthenticated zone transfer of %s, disclosing %d records. Restrict AXFR to secondary nameservers.",
+				ns, domain, len(records)),
+		})
+	}
+
+	hosts := make([]string, 0, len(names))
+	for name := range names {
+		hosts = append(hosts, name)
+	}
+	sort.Strings(hosts)
+	return hosts, findings, nil
+}
+
+// ZoneTransferFindings returns the zone transfer findings among a discovery
+// result's findings.
+func ZoneTransferFindings(findings []models.Vulnerability) []models.Vulnerability {
+	var axfr []models.Vulnerability
+	for _, f := range findings {
+		if f.TemplateID == ZoneTransferTemplateID {
+			axfr = append(axfr, f)
+		}
+	}
+	return axfr
+}
@@ -0,0 +1,154 @@
+package discovery
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/hakim/reconpipe/pkg/models"
+)
+
+// BruteforceSource is the Source recorded for subdomains found by resolving
+// wordlist candidates rather than a passive source.
+const BruteforceSource = "bruteforce"
+
+// DefaultBruteforceWords is used when no wordlist is configured: labels
+// common enough to be worth a lookup on any target.
+var DefaultBruteforceWords = []string{
+	"www", "www1", "www2", "mail", "webmail", "smtp", "mx", "imap", "pop", "autodiscover",
+	"ns1", "ns2", "dns", "vpn", "remote", "gateway", "proxy", "fw", "firewall", "router",
+	"api", "api1", "api2", "graphql", "app", "apps", "mobile", "m", "web", "portal",
+	"admin", "administrator", "panel", "cpanel", "dashboard", "console", "manage", "manager", "internal", "intranet",
+	"dev", "develop", "development", "test", "testing", "qa", "uat", "stage", "staging", "preprod",
+	"prod", "production", "demo", "beta", "alpha", "sandbox", "lab", "old", "new", "legacy",
+	"auth", "login", "sso", "id", "identity", "accounts", "account", "secure", "oauth", "adfs",
+	"git", "gitlab", "github", "jenkins", "ci", "build", "jira", "confluence", "wiki", "docs",
+	"cdn", "static", "assets", "img", "images", "media", "files", "upload", "download", "s3",
+	"db", "sql", "mysql", "postgres", "redis", "elastic", "kibana", "grafana", "monitor", "status",
+	"shop", "store", "pay", "payment", "billing", "support", "help", "blog", "news", "forum",
+	"crm", "erp", "hr", "vault", "backup", "owa", "exchange", "citrix", "rdp", "ftp",
+}
+
+// defaultMaxBruteforce caps how many candidates are resolved per run.
+const defaultMaxBruteforce = 5000
+
+// LoadWordlist reads a wordlist, one label per line. Blank lines and lines
+// starting with '#' are ignored.
+func LoadWordlist(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("opening wordlist: %w", err)
+	}
+	defer f.Close()
+
+	var words []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		words = append(words, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading wordlist: %w", err)
+	}
+	return words, nil
+}
+
+// GenerateBruteforce builds candidate names from words: each word as a
+// label under domain and, with mutate, each word combined altdns-style with
+// the leftmost label of each of names (dev.api, dev-api, api-dev). Words
+// that are not a valid label, candidates already in names, and candidates
+// outside domain are omitted. The result is sorted and capped at limit (0 = no cap).
+func GenerateBruteforce(words, names []string, domain string, mutate bool, limit int) []string {
+	known := make(map[string]bool, len(names))
+	for _, n := range names {
+		known[n] = true
+	}
+
+	seen := make(map[string]bool)
+	var labels, out []string
+	for _, w := range words {
+		w = strings.Trim(strings.ToLower(strings.TrimSpace(w)), ".")
+		if w == "" || strings.ContainsAny(w, " *\t/@") || seen[w] {
+			continue
+		}
+		seen[w] = true
+		labels = append(labels, w)
+		if name := w + "." + domain; !known[name] {
+			out = append(out, name)
+		}
+	}
+
+	if mutate {
+		// Bare-word patterns; GeneratePermutations skips known names
+		out = append(out, GeneratePermutations(names, domain, labels, 0)...)
+	}
+
+	// A word with dots (e.g. "dev.eu") must still land inside domain
+	filtered := out[:0]
+	dedup := make(map[string]bool, len(out))
+	for _, name := range out {
+		if !dedup[name] && inDomain(name, domain) {
+			dedup[name] = true
+			filtered = append(filtered, name)
+		}
+	}
+	out = filtered
+
+	sort.Strings(out)
+	if limit > 0 && len(out) > limit {
+		out = out[:limit]
+	}
+	return out
+}
+
+// resolveBruteforce resolves the wordlist candidates for domain and returns
+// those that resolve, fully populated via ResolveBatch. Candidates resolving
+// only to wildcardIPs are discarded.
+func resolveBruteforce(ctx context.Context, domain string, subdomains []models.Subdomain, wildcardIPs map[string]bool, cfg DiscoveryConfig) ([]models.Subdomain, error) {
+	// Mutate resolved names only, but never re-test a known name
+	var seeds []string
+	known := make(map[string]bool, len(subdomains))
+	for _, sub := range subdomains {
+		known[sub.Name] = true
+		if sub.Resolved {
+			seeds = append(seeds, sub.Name)
+		}
+	}
+
+	words := cfg.BruteforceWords
+	if len(words) == 0 {
+		words = DefaultBruteforceWords
+	}
+	limit := cfg.MaxBruteforce
+	if limit <= 0 {
+		limit = defaultMaxBruteforce
+	}
+
+	candidates := GenerateBruteforce(words, seeds, domain, cfg.BruteforceMutate, 0)
+	filtered := candidates[:0]
+	for _, c := range candidates {
+		if !known[c] {
+			filtered = append(filtered, c)
+		}
+	}
+	candidates = filtered
+	if len(candidates) > limit {
+		fmt.Printf("Warning: %d brute-force candidates, testing the first %d\n", len(candidates), limit)
+		candidates = candidates[:limit]
+	}
+	if len(candidates) == 0 {
+		return nil, nil
+	}
+
+	if len(wildcardIPs) > 0 {
+		fmt.Printf("Ignoring brute-force names that resolve only to the wildcard addresses of %s\n", domain)
+	}
+	fmt.Printf("Brute-forcing %d candidates from %d words...\n", len(candidates), len(words))
+	return resolveCandidates(ctx, domain, candidates, BruteforceSource, wildcardIPs, cfg)
+}
@@ -2,75 +2,298 @@ package discovery
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"slices"
 	"strings"
+	"sync"
+	"time"
 
-	"github.com/hakim/reconpipe/internal/models"
 	"github.com/hakim/reconpipe/internal/tools"
+	"github.com/hakim/reconpipe/pkg/models"
 )
 
+// SystemResolver is the provenance recorded for answers from the host's own
+// resolver.
+const SystemResolver = "system"
+
+// DefaultConsensusResolvers are queried under --dns-consensus when the
+// config lists none: three public resolvers run by different operators.
+var DefaultConsensusResolvers = []string{"1.1.1.1", "8.8.8.8", "9.9.9.9"}
+
+// Consensus configures multi-resolver resolution. The zero value resolves
+// each name once through the system resolver.
+type Consensus struct {
+	Resolvers []string
+	Quorum    int // resolvers that must return an answer; 0 = a majority
+}
+
+// ConsensusSummary records the consensus a discovery ran with.
+type ConsensusSummary struct {
+	Resolvers []string `json:"resolvers"`
+	Quorum    int      `json:"quorum"`
+}
+
+// Summary returns the resolvers and effective quorum of c, or nil when c
+// resolves through the system resolver.
+func (c Consensus) Summary() *ConsensusSummary {
+	if len(c.Resolvers) == 0 {
+		return nil
+	}
+	return &ConsensusSummary{Resolvers: c.Resolvers, Quorum: c.quorum()}
+}
+
+// quorum returns how many resolvers must return an answer for it to count.
+func (c Consensus) quorum() int {
+	if c.Quorum > 0 {
+		return c.Quorum
+	}
+	return len(c.Resolvers)/2 + 1
+}
+
+// DefaultDNSWorkers is how many names ResolveBatch resolves at once when
+// ResolveOptions sets no count.
+const DefaultDNSWorkers = 20
+
+// DefaultDNSTimeout bounds the lookups of one name when ResolveOptions sets
+// no timeout.
+const DefaultDNSTimeout = 10 * time.Second
+
+// ResolveOptions tunes ResolveBatch. Zero fields take the defaults.
+type ResolveOptions struct {
+	Workers int           // names resolved at once
+	Timeout time.Duration // per name: its A/AAAA lookups on every resolver and the CNAME check
+}
+
 // ResolveBatch resolves DNS for a batch of subdomains and classifies dangling entries.
 // For unresolved subdomains, it checks for CNAME records to identify potential takeover candidates.
+// Each A/AAAA record notes the resolvers that returned it. With consensus
+// resolvers set, only answers a quorum of them returned are used; the rest
+// are kept in Unconfirmed, and a name with only unconfirmed answers is
+// neither resolved nor dangling.
+// Names are resolved by a pool of opts.Workers workers, each lookup bounded
+// by opts.Timeout; a name that times out is left unresolved and not
+// dangling. Results are written in place, so the order of subdomains is kept.
+// When ctx ends before every name was resolved, ctx.Err() is returned.
+// In dnsx mode (tools.BulkDNS) the batch is handed to dnsx instead; see
+// resolveBulk.
 // Returns updated subdomains slice with resolution data and dangling classification.
-func ResolveBatch(ctx context.Context, subdomains []models.Subdomain, digPath string) ([]models.Subdomain, error) {
-	// Process each subdomain sequentially
-	// (Concurrent resolution can be added later for performance optimization)
+func ResolveBatch(ctx context.Context, subdomains []models.Subdomain, digPath string, consensus Consensus, opts ResolveOptions) ([]models.Subdomain, error) {
+	if tools.BulkDNS() {
+		return resolveBulk(ctx, subdomains, consensus, opts)
+	}
+
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = DefaultDNSWorkers
+	}
+	workers = min(workers, len(subdomains))
+	timeout := opts.Timeout
+	if timeout <= 0 {
+		timeout = DefaultDNSTimeout
+	}
+
+	var (
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+		firstErr error
+	)
+	failed := func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return firstErr != nil
+	}
+
+	queue := make(chan int)
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range queue {
+				if err := resolveName(ctx, &subdomains[i], digPath, consensus, timeout); err != nil {
+					mu.Lock()
+					if firstErr == nil {
+						firstErr = err
+					}
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+
+	// Stop handing out names after the first failure or once ctx is done
+feed:
 	for i := range subdomains {
-		// Resolve A/AAAA records
-		dnsResults, err := tools.ResolveSubdomains(ctx, []string{subdomains[i].Name}, digPath)
-		if err != nil {
-			return nil, fmt.Errorf("DNS resolution failed for %s: %w", subdomains[i].Name, err)
+		if failed() {
+			break
 		}
-
-		if len(dnsResults) == 0 {
-			continue
+		select {
+		case queue <- i:
+		case <-ctx.Done():
+			break feed
 		}
+	}
+	close(queue)
+	wg.Wait()
 
-		dnsResult := dnsResults[0]
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	// Names never handed out are unresolved only because ctx ended
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return subdomains, nil
+}
 
-		if dnsResult.Resolved {
-			// Subdomain resolves - mark as resolved and store IPs
-			subdomains[i].Resolved = true
-			subdomains[i].IPs = dnsResult.IPs
+// resolveName resolves one subdomain in place, giving its lookups at most
+// timeout.
+func resolveName(ctx context.Context, sub *models.Subdomain, digPath string, consensus Consensus, timeout time.Duration) error {
+	lookupCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
 
-			// Populate DNSRecords with A/AAAA records for report generation
-			// (markdown.go checks DNSRecords to identify resolved subdomains)
-			for _, ip := range dnsResult.IPs {
-				recordType := models.DNSRecordA
-				if strings.Contains(ip, ":") {
-					// IPv6 addresses contain colons
-					recordType = models.DNSRecordAAAA
-				}
-				subdomains[i].DNSRecords = append(subdomains[i].DNSRecords, models.DNSRecord{
-					Type:  recordType,
-					Value: ip,
-				})
-			}
+	// Resolve A/AAAA records
+	var records, unconfirmed []models.DNSRecord
+	var err error
+	if len(consensus.Resolvers) > 0 {
+		records, unconfirmed, err = resolveConsensus(lookupCtx, sub.Name, digPath, consensus)
+	} else {
+		records, err = resolveSystem(lookupCtx, sub.Name, digPath)
+	}
+	if err != nil {
+		return fmt.Errorf("DNS resolution failed for %s: %w", sub.Name, err)
+	}
+	sub.Unconfirmed = unconfirmed
+
+	if len(records) > 0 {
+		// Subdomain resolves - mark as resolved and store IPs.
+		// DNSRecords carries the A/AAAA records for report generation
+		// (markdown.go checks DNSRecords to identify resolved subdomains)
+		sub.Resolved = true
+		for _, r := range records {
+			sub.IPs = append(sub.IPs, r.Value)
+		}
+		sub.DNSRecords = append(sub.DNSRecords, records...)
+		return nil
+	}
+	if len(unconfirmed) > 0 {
+		// Resolvers answered but disagree - not evidence of dangling DNS
+		return nil
+	}
+	if timedOut(ctx, lookupCtx) {
+		// No answer in time is not evidence of dangling DNS either
+		fmt.Printf("Warning: DNS lookup of %s timed out after %s\n", sub.Name, timeout)
+		return nil
+	}
+
+	// Subdomain does not resolve - check for CNAME (dangling DNS candidate)
+	cname, err := tools.CheckCNAME(lookupCtx, sub.Name, digPath)
+	if err != nil {
+		// Log warning but continue - CNAME check failure shouldn't stop processing
+		if timedOut(ctx, lookupCtx) {
+			fmt.Printf("Warning: CNAME check of %s timed out after %s\n", sub.Name, timeout)
 		} else {
-			// Subdomain does not resolve - check for CNAME (dangling DNS candidate)
-			cname, err := tools.CheckCNAME(ctx, subdomains[i].Name, digPath)
-			if err != nil {
-				// Log warning but continue - CNAME check failure shouldn't stop processing
-				fmt.Printf("Warning: CNAME check failed for %s: %v\n", subdomains[i].Name, err)
-				continue
-			}
+			fmt.Printf("Warning: CNAME check failed for %s: %v\n", sub.Name, err)
+		}
+		return nil
+	}
 
-			// Mark as dangling DNS
-			subdomains[i].IsDangling = true
+	// Mark as dangling DNS
+	sub.IsDangling = true
+
+	if cname != "" {
+		// High priority: has CNAME (subdomain takeover candidate)
+		sub.DNSRecords = append(sub.DNSRecords, models.DNSRecord{
+			Type:      models.DNSRecordCNAME,
+			Value:     cname,
+			Resolvers: []string{SystemResolver},
+		})
+	}
+	// Low priority: no CNAME (stale DNS cleanup candidate)
+	// No additional marking needed - IsDangling=true is sufficient
+	return nil
+}
+
+// timedOut reports whether lookupCtx ran out of time while its parent ctx
+// is still live.
+func timedOut(ctx, lookupCtx context.Context) bool {
+	return ctx.Err() == nil && errors.Is(lookupCtx.Err(), context.DeadlineExceeded)
+}
+
+// resolveSystem returns the A/AAAA records of name from the system resolver.
+func resolveSystem(ctx context.Context, name, digPath string) ([]models.DNSRecord, error) {
+	dnsResults, err := tools.ResolveSubdomains(ctx, []string{name}, digPath)
+	if err != nil || len(dnsResults) == 0 {
+		return nil, err
+	}
+
+	var records []models.DNSRecord
+	for _, ip := range dnsResults[0].IPs {
+		records = append(records, models.DNSRecord{
+			Type:      addressRecordType(ip),
+			Value:     ip,
+			Resolvers: []string{SystemResolver},
+		})
+	}
+	return records, nil
+}
 
-			if cname != "" {
-				// High priority: has CNAME (subdomain takeover candidate)
-				subdomains[i].DNSRecords = append(subdomains[i].DNSRecords, models.DNSRecord{
-					Type:  models.DNSRecordCNAME,
-					Value: cname,
-				})
+// resolveConsensus queries name on every consensus resolver and splits the
+// answers into those at least a quorum returned and the rest, each noting
+// which resolvers returned it. A resolver that fails counts as returning
+// nothing.
+func resolveConsensus(ctx context.Context, name, digPath string, consensus Consensus) (accepted, unconfirmed []models.DNSRecord, err error) {
+	returnedBy := make(map[string][]string)
+	var answers []string
+	for _, server := range consensus.Resolvers {
+		dnsResults, err := tools.ResolveSubdomainsVia(ctx, []string{name}, server, digPath)
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(dnsResults) == 0 || dnsResults[0].Error != "" {
+			continue
+		}
+		for _, ip := range dnsResults[0].IPs {
+			if slices.Contains(returnedBy[ip], server) {
+				continue
+			}
+			if _, ok := returnedBy[ip]; !ok {
+				answers = append(answers, ip)
 			}
-			// Low priority: no CNAME (stale DNS cleanup candidate)
-			// No additional marking needed - IsDangling=true is sufficient
+			returnedBy[ip] = append(returnedBy[ip], server)
 		}
 	}
 
-	return subdomains, nil
+	for _, ip := range answers {
+		record := models.DNSRecord{Type: addressRecordType(ip), Value: ip, Resolvers: returnedBy[ip]}
+		if len(returnedBy[ip]) >= consensus.quorum() {
+			accepted = append(accepted, record)
+		} else {
+			unconfirmed = append(unconfirmed, record)
+		}
+	}
+	return accepted, unconfirmed, nil
+}
+
+// addressRecordType returns AAAA for an IPv6 address and A otherwise.
+func addressRecordType(ip string) models.DNSRecordType {
+	if strings.Contains(ip, ":") {
+		// IPv6 addresses contain colons
+		return models.DNSRecordAAAA
+	}
+	return models.DNSRecordA
+}
+
+// UnconfirmedSubdomains returns the subdomains with answers that fell short
+// of the consensus quorum.
+func UnconfirmedSubdomains(subdomains []models.Subdomain) []models.Subdomain {
+	var out []models.Subdomain
+	for _, sub := range subdomains {
+		if len(sub.Unconfirmed) > 0 {
+			out = append(out, sub)
+		}
+	}
+	return out
 }
 
 // ClassifyDangling separates dangling DNS entries into high and low priority.
@@ -0,0 +1,314 @@
+package discovery
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/hakim/reconpipe/internal/tools"
+	"github.com/hakim/reconpipe/pkg/models"
+)
+
+// DNSSEC states recorded for each name checked by the DNS health checks.
+const (
+	DNSSECValid      = "valid"      // the resolver validated the answer
+	DNSSECSigned     = "signed"     // DS and DNSKEY published; the resolver does not validate
+	DNSSECUnsigned   = "unsigned"   // no chain of trust reaches the name
+	DNSSECIncomplete = "incomplete" // DNSKEY published but no DS at the parent
+	DNSSECBroken     = "broken"     // validation fails, so validating resolvers return SERVFAIL
+)
+
+// Template IDs of the findings raised by the DNS health checks.
+const (
+	DNSSECMissingTemplateID    = "dnssec-missing"
+	DNSSECIncompleteTemplateID = "dnssec-incomplete"
+	DNSSECBrokenTemplateID     = "dnssec-broken"
+	CAAMissingTemplateID       = "dns-caa-missing"
+	CAAInvalidTemplateID       = "dns-caa-invalid"
+)
+
+// IsDNSHealthFinding reports whether templateID was raised by the DNS health
+// checks.
+func IsDNSHealthFinding(templateID string) bool {
+	switch templateID {
+	case DNSSECMissingTemplateID, DNSSECIncompleteTemplateID, DNSSECBrokenTemplateID,
+		CAAMissingTemplateID, CAAInvalidTemplateID:
+		return true
+	}
+	return false
+}
+
+// DefaultDNSHealthSubdomains are the labels checked besides the apex when no
+// list is configured. Only those that resolved during discovery are checked.
+var DefaultDNSHealthSubdomains = []string{"www", "mail", "api", "app", "login", "auth", "portal", "vpn"}
+
+// DNSHealthCheck is the DNSSEC and CAA state of one name.
+type DNSHealthCheck struct {
+	Name   string `json:"name"`
+	DNSSEC string `json:"dnssec"`
+	// CAA holds the records in effect for the name and CAASource the name
+	// they were published at: the name itself, a parent up to the apex, or
+	// empty when no CAA record applies.
+	CAA       []string `json:"caa,omitempty"`
+	CAASource string   `json:"caa_source,omitempty"`
+	// CAAProblems lists what is wrong with CAA records published at this
+	// name itself; inherited records are judged at the name that owns them.
+	CAAProblems []string `json:"caa_problems,omitempty"`
+	Error       string   `json:"error,omitempty"`
+}
+
+// caaTags are the property tags CAs understand (RFC 8659, RFC 9495 and the
+// CA/Browser Forum S/MIME and VMC extensions).
+var caaTags = map[string]bool{
+	"issue":        true,
+	"issuewild":    true,
+	"iodef":        true,
+	"issuemail":    true,
+	"issuevmc":     true,
+	"contactemail": true,
+	"contactphone": true,
+}
+
+// caaCriticalFlag marks a property a CA must understand before issuing.
+const caaCriticalFlag = 128
+
+// checkDNSHealth checks DNSSEC and CAA for domain and for the key subdomains
+// among subdomains that resolved. names are labels ("www") or full names;
+// empty means DefaultDNSHealthSubdomains. server is the resolver queried, or
+// empty for the system one.
+func checkDNSHealth(ctx context.Context, domain string, subdomains []models.Subdomain, names []string, server, digPath string) []DNSHealthCheck {
+	if len(names) == 0 {
+		names = DefaultDNSHealthSubdomains
+	}
+	resolved := make(map[string]bool, len(subdomains))
+	for _, sub := range subdomains {
+		if sub.Resolved {
+			resolved[sub.Name] = true
+		}
+	}
+
+	targets := []string{domain}
+	seen := map[string]bool{domain: true}
+	for _, n := range names {
+		name := normalizeSubdomain(n)
+		if !strings.Contains(name, ".") {
+			name += "." + domain
+		}
+		if seen[name] || !resolved[name] || !inDomain(name, domain) {
+			continue
+		}
+		seen[name] = true
+		targets = append(targets, name)
+	}
+
+	fmt.Printf("Checking DNSSEC and CAA for %d name(s)...\n", len(targets))
+
+	caaCache := make(map[string][]string)
+	lookupCAA := func(name string) ([]string, error) {
+		if records, ok := caaCache[name]; ok {
+			return records, nil
+		}
+		records, err := tools.LookupRecords(ctx, name, "CAA", server, true, digPath)
+		if err != nil {
+			return nil, err
+		}
+		caaCache[name] = records
+		return records, nil
+	}
+
+	checks := make([]DNSHealthCheck, 0, len(targets))
+	apexDNSSEC := ""
+	for _, name := range targets {
+		check := DNSHealthCheck{Name: name}
+
+		var err error
+		if name == domain {
+			check.DNSSEC, err = apexDNSSECStatus(ctx, domain, server, digPath)
+			apexDNSSEC = check.DNSSEC
+		} else {
+			check.DNSSEC, err = subdomainDNSSECStatus(ctx, name, apexDNSSEC, server, digPath)
+		}
+		if err != nil {
+			check.Error = err.Error()
+		}
+
+		// CAA is inherited: CAs climb from the name towards the apex and use
+		// the first record set they find.
+		for owner := name; ; {
+			records, err := lookupCAA(owner)
+			if err != nil {
+				check.Error = err.Error()
+				break
+			}
+			if len(records) > 0 {
+				check.CAA, check.CAASource = records, owner
+				if owner == name {
+					check.CAAProblems = caaProblems(records)
+				}
+				break
+			}
+			if owner == domain {
+				break
+			}
+			_, owner, _ = strings.Cut(owner, ".")
+		}
+
+		checks = append(checks, check)
+	}
+
+	return checks
+}
+
+// apexDNSSECStatus works out the DNSSEC state of the zone apex from its DS
+// records at the parent, its DNSKEY set and whether the resolver validates
+// its SOA.
+func apexDNSSECStatus(ctx context.Context, domain, server, digPath string) (string, error) {
+	ds, err := tools.LookupRecords(ctx, domain, "DS", server, true, digPath)
+	if err != nil {
+		return DNSSECUnsigned, err
+	}
+	keys, err := tools.LookupRecords(ctx, domain, "DNSKEY", server, true, digPath)
+	if err != nil {
+		return DNSSECUnsigned, err
+	}
+	status, err := tools.QueryStatus(ctx, domain, "SOA", server, digPath)
+	if err != nil {
+		return DNSSECUnsigned, err
+	}
+
+	switch {
+	case len(ds) > 0 && (len(keys) == 0 || status.Rcode == "SERVFAIL"):
+		return DNSSECBroken, nil
+	case len(ds) > 0 && status.Authenticated:
+		return DNSSECValid, nil
+	case len(ds) > 0:
+		return DNSSECSigned, nil
+	case len(keys) > 0:
+		return DNSSECIncomplete, nil
+	default:
+		return DNSSECUnsigned, nil
+	}
+}
+
+// subdomainDNSSECStatus works out the DNSSEC state of a name below the apex.
+// In a signed zone a SERVFAIL means the name's signatures (or those of a
+// zone it is delegated or aliased to) do not validate.
+func subdomainDNSSECStatus(ctx context.Context, name, apex, server, digPath string) (string, error) {
+	status, err := tools.QueryStatus(ctx, name, "SOA", server, digPath)
+	if err != nil {
+		return apex, err
+	}
+
+	signedApex := apex == DNSSECValid || apex == DNSSECSigned || apex == DNSSECBroken
+	switch {
+	case status.Authenticated:
+		return DNSSECValid, nil
+	case signedApex && status.Rcode == "SERVFAIL":
+		return DNSSECBroken, nil
+	case apex == DNSSECValid:
+		// The resolver validates, yet not this answer: the name leaves the
+		// chain of trust, e.g. through a CNAME to an unsigned provider.
+		return DNSSECUnsigned, nil
+	case apex == DNSSECSigned:
+		return DNSSECSigned, nil
+	default:
+		return DNSSECUnsigned, nil
+	}
+}
+
+// caaProblems returns why a name's own CAA record set fails to restrict
+// issuance as intended. Records are dig's rdata: `<flags> <tag> "<value>"`.
+func caaProblems(records []string) []string {
+	var problems []string
+	restricts := false
+	for _, rec := range records {
+		fields := strings.SplitN(rec, " ", 3)
+		if len(fields) != 3 {
+			problems = append(problems, fmt.Sprintf("malformed record %q", rec))
+			continue
+		}
+		flags, err := strconv.Atoi(fields[0])
+		if err != nil || flags < 0 || flags > 255 {
+			problems = append(problems, fmt.Sprintf("bad flags in %q", rec))
+			continue
+		}
+		tag := strings.ToLower(fields[1])
+		if !caaTags[tag] {
+			if flags&caaCriticalFlag != 0 {
+				problems = append(problems, fmt.Sprintf("unknown critical tag %q: CAs will refuse to issue", fields[1]))
+			} else {
+				problems = append(problems, fmt.Sprintf("unknown tag %q is ignored by CAs", fields[1]))
+			}
+			continue
+		}
+		if tag == "issue" || tag == "issuewild" {
+			restricts = true
+		}
+	}
+	if !restricts {
+		problems = append(problems, "no issue or issuewild property, so any CA may issue")
+	}
+	return problems
+}
+
+// DNSHealthFindings converts DNS health checks into findings, each with a
+// remediation hint. Names without their own DNSSEC or CAA configuration are
+// covered by the apex finding rather than reported again, and so are the
+// names below a broken apex.
+func DNSHealthFindings(domain string, checks []DNSHealthCheck) []models.Vulnerability {
+	var findings []models.Vulnerability
+	add := func(templateID, name string, severity models.Severity, host, description string) {
+		findings = append(findings, models.Vulnerability{
+			TemplateID:  templateID,
+			Name:        name,
+			Severity:    severity,
+			Host:        host,
+			Port:        53,
+			MatchedAt:   host,
+			Description: description,
+		})
+	}
+
+	apexBroken := false
+	for _, c := range checks {
+		if c.Name == domain && c.DNSSEC == DNSSECBroken {
+			apexBroken = true
+		}
+	}
+
+	for _, c := range checks {
+		if c.Error != "" {
+			// A failed lookup is not a missing record
+			continue
+		}
+
+		if c.DNSSEC == DNSSECBroken && (c.Name == domain || !apexBroken) {
+			add(DNSSECBrokenTemplateID, "DNSSEC Validation Failure", models.SeverityHigh, c.Name,
+				fmt.Sprintf("Validating resolvers cannot verify %s and answer SERVFAIL, so it is unreachable for their users. Re-sign the zone, or make the DS record at the registrar match the current DNSKEY (remove the DS if DNSSEC is being turned off).", c.Name))
+		} else if c.Name == domain && c.DNSSEC == DNSSECIncomplete {
+			add(DNSSECIncompleteTemplateID, "DNSSEC Chain of Trust Incomplete", models.SeverityLow, c.Name,
+				fmt.Sprintf("%s publishes DNSKEY records but its parent has no DS record, so resolvers treat the zone as unsigned. Publish the DS record at the registrar.", c.Name))
+		} else if c.Name == domain && c.DNSSEC == DNSSECUnsigned {
+			add(DNSSECMissingTemplateID, "DNSSEC Not Enabled", models.SeverityLow, c.Name,
+				fmt.Sprintf("%s is not signed, so DNS answers for it can be spoofed. Enable DNSSEC signing at the DNS provider and publish the DS record at the registrar.", c.Name))
+		}
+
+		switch {
+		case c.CAASource == "" && c.Name == domain:
+			add(CAAMissingTemplateID, "CAA Records Missing", models.SeverityLow, c.Name,
+				fmt.Sprintf("No CAA record restricts which certificate authorities may issue for %s. Publish CAA records naming the CAs in use, e.g. `%s. CAA 0 issue \"letsencrypt.org\"`, and an iodef contact.", c.Name, c.Name))
+		case len(c.CAAProblems) > 0:
+			severity := models.SeverityLow
+			for _, p := range c.CAAProblems {
+				if strings.Contains(p, "critical") {
+					severity = models.SeverityMedium
+				}
+			}
+			add(CAAInvalidTemplateID, "CAA Records Misconfigured", severity, c.Name,
+				fmt.Sprintf("CAA records at %s are misconfigured: %s. Fix the records so each has flags 0, a known tag and a quoted value, with at least one issue property.", c.Name, strings.Join(c.CAAProblems, "; ")))
+		}
+	}
+
+	return findings
+}
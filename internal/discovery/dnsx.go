@@ -0,0 +1,176 @@
+package discovery
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/hakim/reconpipe/internal/tools"
+	"github.com/hakim/reconpipe/pkg/models"
+)
+
+// resolveBulk is ResolveBatch through dnsx (discovery.dns_resolver.mode:
+// dnsx): one dnsx run resolves every name, or one run per resolver under
+// consensus, with opts.Workers as its concurrency and opts.Timeout bounding
+// each query. Besides A/AAAA records the CNAME, MX and TXT records dnsx
+// returns are kept. A name is dangling, with its CNAME when it has one,
+// only when it is known to have no address (see noAddress); as with dig,
+// a name that got no answer is left unresolved and not dangling.
+func resolveBulk(ctx context.Context, subdomains []models.Subdomain, consensus Consensus, opts ResolveOptions) ([]models.Subdomain, error) {
+	names := make([]string, len(subdomains))
+	for i, sub := range subdomains {
+		names[i] = sub.Name
+	}
+
+	if len(consensus.Resolvers) == 0 {
+		results, err := tools.RunDnsx(ctx, names, "", opts.Workers, opts.Timeout, tools.DNSNameservers())
+		if err != nil {
+			return nil, fmt.Errorf("DNS resolution failed: %w", err)
+		}
+		records := make(map[string][]models.DNSRecord, len(results))
+		answered := make(map[string]bool, len(results))
+		absent := make(map[string]bool)
+		for _, r := range results {
+			records[r.Host] = append(records[r.Host], r.Records()...)
+			answered[r.Host] = true
+			absent[r.Host] = noAddress(r)
+		}
+		for i := range subdomains {
+			applyBulkRecords(&subdomains[i], records[subdomains[i].Name], nil, absent[subdomains[i].Name])
+		}
+		warnUnanswered(names, answered)
+		return subdomains, nil
+	}
+
+	// Count the resolvers returning each answer of each name
+	type answer struct {
+		typ   models.DNSRecordType
+		value string
+	}
+	returnedBy := make(map[string]map[answer][]string)
+	answers := make(map[string][]answer) // per name, in the order first seen
+	answered := make(map[string]bool)
+	absentOn := make(map[string]int) // resolvers saying a name has no address
+	for _, server := range consensus.Resolvers {
+		results, err := tools.RunDnsx(ctx, names, "", opts.Workers, opts.Timeout, []string{server})
+		if err != nil {
+			return nil, fmt.Errorf("DNS resolution via %s failed: %w", server, err)
+		}
+		for _, r := range results {
+			answered[r.Host] = true
+			if noAddress(r) {
+				absentOn[r.Host]++
+			}
+			if returnedBy[r.Host] == nil {
+				returnedBy[r.Host] = make(map[answer][]string)
+			}
+			for _, rec := range r.Records() {
+				a := answer{rec.Type, rec.Value}
+				if slices.Contains(returnedBy[r.Host][a], server) {
+					continue
+				}
+				if _, ok := returnedBy[r.Host][a]; !ok {
+					answers[r.Host] = append(answers[r.Host], a)
+				}
+				returnedBy[r.Host][a] = append(returnedBy[r.Host][a], server)
+			}
+		}
+	}
+
+	for i := range subdomains {
+		name := subdomains[i].Name
+		var accepted, unconfirmed []models.DNSRecord
+		for _, a := range answers[name] {
+			record := models.DNSRecord{Type: a.typ, Value: a.value, Resolvers: returnedBy[name][a]}
+			if len(record.Resolvers) >= consensus.quorum() {
+				accepted = append(accepted, record)
+			} else {
+				unconfirmed = append(unconfirmed, record)
+			}
+		}
+		applyBulkRecords(&subdomains[i], accepted, unconfirmed, absentOn[name] >= consensus.quorum())
+	}
+	warnUnanswered(names, answered)
+	return subdomains, nil
+}
+
+// noAddress reports whether r says its host has no address: NXDOMAIN, or
+// NOERROR with nothing but a CNAME chain. A name with MX or TXT records
+// exists, and SERVFAIL or REFUSED say nothing about the name.
+func noAddress(r tools.DnsxResult) bool {
+	switch r.StatusCode {
+	case "NXDOMAIN":
+		return true
+	case "NOERROR":
+		return len(r.A) == 0 && len(r.AAAA) == 0 && len(r.MX) == 0 && len(r.TXT) == 0
+	}
+	return false
+}
+
+// warnUnanswered notes the names dnsx got no answer for, which are left
+// unresolved and not dangling.
+func warnUnanswered(names []string, answered map[string]bool) {
+	missing := 0
+	for _, name := range names {
+		if !answered[name] {
+			missing++
+		}
+	}
+	if missing > 0 {
+		fmt.Printf("Warning: dnsx got no answer for %d of %d names; they are left unresolved and not dangling\n", missing, len(names))
+	}
+}
+
+// applyBulkRecords records the answers dnsx returned for sub, classifying
+// it the way resolveName does. absent is whether the resolvers said the
+// name has no address; without that, a name with no addresses is neither
+// resolved nor dangling.
+func applyBulkRecords(sub *models.Subdomain, records, unconfirmed []models.DNSRecord, absent bool) {
+	sub.Unconfirmed = unconfirmed
+	for _, r := range records {
+		if r.Type == models.DNSRecordA || r.Type == models.DNSRecordAAAA {
+			sub.IPs = append(sub.IPs, r.Value)
+		}
+	}
+	sub.DNSRecords = append(sub.DNSRecords, records...)
+	if len(sub.IPs) > 0 {
+		sub.Resolved = true
+		return
+	}
+	for _, r := range unconfirmed {
+		if r.Type == models.DNSRecordA || r.Type == models.DNSRecordAAAA {
+			// Resolvers answered but disagree - not evidence of dangling DNS
+			return
+		}
+	}
+	if !absent {
+		// No answer, an error or only MX/TXT records - not evidence of
+		// dangling DNS either
+		return
+	}
+	sub.IsDangling = true
+}
+
+// resolveCandidatesBulk is resolveCandidates through dnsx.
+func resolveCandidatesBulk(ctx context.Context, domain string, candidates []string, source string, wildcardIPs map[string]bool, cfg DiscoveryConfig) ([]models.Subdomain, error) {
+	results, err := tools.RunDnsx(ctx, candidates, "", cfg.Resolve.Workers, cfg.Resolve.Timeout, tools.DNSNameservers())
+	if err != nil {
+		return nil, err
+	}
+
+	var found []models.Subdomain
+	for _, r := range results {
+		ips := append(slices.Clone(r.A), r.AAAA...)
+		if len(ips) == 0 || onlyWildcard(ips, wildcardIPs) || !inDomain(r.Host, domain) {
+			continue
+		}
+		found = append(found, models.Subdomain{Name: r.Host, Domain: domain, Source: source})
+	}
+	if len(found) == 0 {
+		return nil, nil
+	}
+
+	slices.SortFunc(found, func(a, b models.Subdomain) int { return strings.Compare(a.Name, b.Name) })
+	return ResolveBatch(ctx, found, cfg.DigPath, cfg.Consensus, cfg.Resolve)
+}
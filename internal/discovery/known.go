@@ -0,0 +1,108 @@
+package discovery
+
+import (
+	"bufio"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ProvidedSource is the Source recorded for subdomains that came only from a
+// client-supplied asset list rather than a passive source.
+const ProvidedSource = "provided"
+
+// knownHostColumns are CSV header names recognised as the hostname column.
+var knownHostColumns = map[string]bool{
+	"subdomain": true,
+	"hostname":  true,
+	"host":      true,
+	"domain":    true,
+	"fqdn":      true,
+	"name":      true,
+}
+
+// LoadKnownSubdomains reads a client-supplied list of hostnames.
+//
+// Plain text files hold one hostname per line; blank lines and lines starting
+// with '#' are ignored. Files ending in .csv are parsed as CSV: if the first
+// row has a recognised header (subdomain, hostname, host, domain, fqdn, name)
+// that column is used, otherwise the first column of every row.
+func LoadKnownSubdomains(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("opening known subdomains file: %w", err)
+	}
+	defer f.Close()
+
+	if strings.EqualFold(filepath.Ext(path), ".csv") {
+		return readKnownCSV(f)
+	}
+
+	var names []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		names = append(names, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading known subdomains file: %w", err)
+	}
+	return names, nil
+}
+
+// readKnownCSV extracts hostnames from a CSV asset list.
+func readKnownCSV(r io.Reader) ([]string, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.Comment = '#'
+	reader.TrimLeadingSpace = true
+
+	var names []string
+	column := 0
+	first := true
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("parsing known subdomains CSV: %w", err)
+		}
+
+		if first {
+			first = false
+			header := false
+			for i, field := range record {
+				if knownHostColumns[strings.ToLower(strings.TrimSpace(field))] {
+					column = i
+					header = true
+					break
+				}
+			}
+			if header {
+				continue
+			}
+		}
+
+		if column >= len(record) {
+			continue
+		}
+		if name := strings.TrimSpace(record[column]); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names, nil
+}
+
+// inDomain reports whether name is domain itself or one of its subdomains.
+func inDomain(name, domain string) bool {
+	domain = strings.ToLower(strings.TrimSuffix(domain, "."))
+	return name == domain || strings.HasSuffix(name, "."+domain)
+}
@@ -0,0 +1,251 @@
+package discovery
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hakim/reconpipe/internal/tools"
+)
+
+// Passive sources discovery queries itself over HTTP, independently of how
+// subfinder is configured. Each is also the Source of the names it finds
+// first.
+const (
+	CrtshSource          = "crtsh"          // certificate transparency logs via crt.sh
+	WaybackSource        = "wayback"        // Wayback Machine CDX API
+	SecurityTrailsSource = "securitytrails" // SecurityTrails API; needs an API key
+)
+
+// PassiveSources lists the built-in passive sources in the order their
+// results are merged.
+var PassiveSources = []string{CrtshSource, WaybackSource, SecurityTrailsSource}
+
+// DefaultPassiveTimeout bounds each passive source query. crt.sh in
+// particular can take tens of seconds for a large domain.
+const DefaultPassiveTimeout = 60 * time.Second
+
+// maxPassiveResponse caps how much of a source's response is read.
+const maxPassiveResponse = 64 << 20
+
+// Endpoints of the passive sources.
+const (
+	crtshEndpoint          = "https://crt.sh/"
+	waybackEndpoint        = "https://web.archive.org/cdx/search/cdx"
+	securityTrailsEndpoint = "https://api.securitytrails.com/v1/domain/"
+)
+
+// PassiveOptions controls the built-in passive sources.
+type PassiveOptions struct {
+	Skip []string // source names not to query
+	// SecurityTrailsKey is the API key for SecurityTrails, which is only
+	// queried when it is set.
+	SecurityTrailsKey string
+	Timeout           time.Duration // per source; 0 = DefaultPassiveTimeout
+}
+
+// enabled returns the sources to query, in PassiveSources order.
+func (o PassiveOptions) enabled() []string {
+	var sources []string
+	for _, source := range PassiveSources {
+		if source == SecurityTrailsSource && o.SecurityTrailsKey == "" {
+			continue
+		}
+		skipped := false
+		for _, s := range o.Skip {
+			if strings.EqualFold(s, source) {
+				skipped = true
+			}
+		}
+		if !skipped {
+			sources = append(sources, source)
+		}
+	}
+	return sources
+}
+
+// passiveResult is what one passive source returned.
+type passiveResult struct {
+	source string
+	names  []string
+	err    error
+}
+
+// queryPassiveSources queries the enabled passive sources for domain at
+// once and returns their results in PassiveSources order. Names are
+// normalized, deduplicated per source and limited to names under domain.
+func queryPassiveSources(ctx context.Context, domain string, opts PassiveOptions) []passiveResult {
+	timeout := opts.Timeout
+	if timeout <= 0 {
+		timeout = DefaultPassiveTimeout
+	}
+
+	sources := opts.enabled()
+	results := make([]passiveResult, len(sources))
+	var wg sync.WaitGroup
+	for i, source := range sources {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			queryCtx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+
+			names, err := queryPassiveSource(queryCtx, source, domain, opts)
+			if err != nil && queryCtx.Err() == context.DeadlineExceeded && ctx.Err() == nil {
+				err = fmt.Errorf("no answer within %s", timeout)
+			}
+			results[i] = passiveResult{source: source, names: filterPassiveNames(names, domain), err: err}
+		}()
+	}
+	wg.Wait()
+	return results
+}
+
+// queryPassiveSource fetches and parses the response of one source.
+func queryPassiveSource(ctx context.Context, source, domain string, opts PassiveOptions) ([]string, error) {
+	var req *http.Request
+	var parse func(io.Reader) ([]string, error)
+	var err error
+	switch source {
+	case CrtshSource:
+		q := url.Values{"q": {"%." + domain}, "output": {"json"}}
+		req, err = http.NewRequestWithContext(ctx, http.MethodGet, crtshEndpoint+"?"+q.Encode(), nil)
+		parse = parseCrtsh
+	case WaybackSource:
+		q := url.Values{"url": {domain}, "matchType": {"domain"}, "fl": {"original"}, "collapse": {"urlkey"}}
+		req, err = http.NewRequestWithContext(ctx, http.MethodGet, waybackEndpoint+"?"+q.Encode(), nil)
+		parse = parseWayback
+	case SecurityTrailsSource:
+		req, err = http.NewRequestWithContext(ctx, http.MethodGet, securityTrailsEndpoint+url.PathEscape(domain)+"/subdomains", nil)
+		if req != nil {
+			req.Header.Set("APIKEY", opts.SecurityTrailsKey)
+		}
+		parse = func(r io.Reader) ([]string, error) { return parseSecurityTrails(r, domain) }
+	default:
+		return nil, fmt.Errorf("unknown passive source %q", source)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("building request: %w", err)
+	}
+
+	if tools.FakeToolsEnabled() {
+		// The fixture holds the response body, keyed by "<source> <domain>"
+		lines, err := tools.FakeFixture("passive", source+" "+domain)
+		if err != nil {
+			return nil, err
+		}
+		return parse(strings.NewReader(strings.Join(lines, "\n")))
+	}
+
+	req.Header.Set("Accept", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%s %s: %w", req.Method, req.URL.Redacted(), err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("%s %s returned %s: %s", req.Method, req.URL.Host+req.URL.Path, resp.Status, strings.TrimSpace(string(data)))
+	}
+	return parse(io.LimitReader(resp.Body, maxPassiveResponse))
+}
+
+// parseCrtsh reads crt.sh's JSON output: one entry per certificate, whose
+// name_value holds its matching names one per line.
+func parseCrtsh(r io.Reader) ([]string, error) {
+	var entries []struct {
+		NameValue string `json:"name_value"`
+	}
+	if err := json.NewDecoder(r).Decode(&entries); err != nil {
+		if err == io.EOF {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("parsing crt.sh response: %w", err)
+	}
+	var names []string
+	for _, e := range entries {
+		names = append(names, strings.Split(e.NameValue, "\n")...)
+	}
+	return names, nil
+}
+
+// parseWayback reads the CDX API's plain-text output: one archived URL per
+// line, of which only the host is kept.
+func parseWayback(r io.Reader) ([]string, error) {
+	var names []string
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		if !strings.Contains(line, "://") {
+			line = "http://" + line
+		}
+		if u, err := url.Parse(line); err == nil {
+			names = append(names, u.Hostname())
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading Wayback response: %w", err)
+	}
+	return names, nil
+}
+
+// parseSecurityTrails reads the subdomains endpoint's response, which lists
+// labels relative to domain.
+func parseSecurityTrails(r io.Reader, domain string) ([]string, error) {
+	var body struct {
+		Subdomains []string `json:"subdomains"`
+	}
+	if err := json.NewDecoder(r).Decode(&body); err != nil {
+		if err == io.EOF {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("parsing SecurityTrails response: %w", err)
+	}
+	names := make([]string, 0, len(body.Subdomains))
+	for _, label := range body.Subdomains {
+		names = append(names, label+"."+domain)
+	}
+	return names, nil
+}
+
+// filterPassiveNames normalizes names and keeps, once each, those that are
+// hostnames under domain. Sources return wildcards, e-mail addresses and
+// names of other domains sharing a certificate, which are all dropped.
+func filterPassiveNames(names []string, domain string) []string {
+	seen := make(map[string]bool)
+	var out []string
+	for _, name := range names {
+		n := normalizeSubdomain(name)
+		if n == "" || n == domain || seen[n] || !inDomain(n, domain) || !isHostname(n) {
+			continue
+		}
+		seen[n] = true
+		out = append(out, n)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// isHostname reports whether name consists only of characters valid in a
+// DNS name.
+func isHostname(name string) bool {
+	for _, c := range name {
+		if !(c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '-' || c == '.' || c == '_') {
+			return false
+		}
+	}
+	return !strings.Contains(name, "..")
+}
@@ -0,0 +1,236 @@
+package discovery
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/hakim/reconpipe/internal/tools"
+	"github.com/hakim/reconpipe/pkg/models"
+)
+
+// PermutationSource is the Source recorded for subdomains found by
+// permutation rather than a passive source.
+const PermutationSource = "permutation"
+
+// DefaultPermutationPatterns is used when no pattern list is configured.
+//
+// Pattern syntax, applied to the leftmost label of each resolved name
+// (api.example.com → "api"):
+//
+//	-dev     suffix      → api-dev.example.com
+//	dev-     prefix      → dev-api.example.com
+//	01..09   number range appended → api01 … api09
+//	dev      bare word   → dev.api.example.com, dev-api, api-dev
+var DefaultPermutationPatterns = []string{
+	"-dev", "-staging", "-test", "-qa", "-uat", "-prod", "-old", "-new", "-internal", "-admin",
+	"dev-", "staging-", "test-", "api-", "admin-", "internal-",
+	"1..3", "01..09",
+}
+
+// defaultMaxPermutations caps how many candidates are resolved per run.
+const defaultMaxPermutations = 2000
+
+// GeneratePermutations builds candidate names from the leftmost label of each
+// name using patterns. Candidates already in names, or outside domain, are
+// omitted. The result is sorted and capped at limit (0 = no cap).
+func GeneratePermutations(names []string, domain string, patterns []string, limit int) []string {
+	known := make(map[string]bool, len(names))
+	for _, n := range names {
+		known[n] = true
+	}
+
+	seen := make(map[string]bool)
+	var out []string
+	add := func(candidate string) {
+		if known[candidate] || seen[candidate] || !inDomain(candidate, domain) {
+			return
+		}
+		seen[candidate] = true
+		out = append(out, candidate)
+	}
+
+	for _, name := range names {
+		if name == domain || !inDomain(name, domain) {
+			continue
+		}
+		label, rest, _ := strings.Cut(name, ".")
+		if label == "" || rest == "" {
+			continue
+		}
+
+		for _, pattern := range patterns {
+			pattern = strings.TrimSpace(strings.ToLower(pattern))
+			switch {
+			case pattern == "":
+			case strings.Contains(pattern, ".."):
+				for _, n := range expandRange(pattern) {
+					add(label + n + "." + rest)
+				}
+			case strings.HasPrefix(pattern, "-"):
+				add(label + pattern + "." + rest)
+			case strings.HasSuffix(pattern, "-"):
+				add(pattern + label + "." + rest)
+			default:
+				add(pattern + "." + name)
+				add(pattern + "-" + label + "." + rest)
+				add(label + "-" + pattern + "." + rest)
+			}
+		}
+	}
+
+	sort.Strings(out)
+	if limit > 0 && len(out) > limit {
+		out = out[:limit]
+	}
+	return out
+}
+
+// expandRange turns "01..09" into ["01", …, "09"], keeping the zero padding
+// of the start value. Malformed or oversized ranges expand to nothing.
+func expandRange(pattern string) []string {
+	lo, hi, _ := strings.Cut(pattern, "..")
+	start, err1 := strconv.Atoi(lo)
+	end, err2 := strconv.Atoi(hi)
+	if err1 != nil || err2 != nil || start > end || end-start > 100 {
+		return nil
+	}
+	width := len(lo)
+	var out []string
+	for i := start; i <= end; i++ {
+		out = append(out, fmt.Sprintf("%0*d", width, i))
+	}
+	return out
+}
+
+// resolvePermutations generates candidates from the resolved subdomains and
+// returns those that actually resolve, fully populated via ResolveBatch.
+// When the domain has wildcard DNS, candidates pointing only at the wildcard
+// addresses, wildcardIPs, are discarded.
+func resolvePermutations(ctx context.Context, domain string, subdomains []models.Subdomain, wildcardIPs map[string]bool, cfg DiscoveryConfig) ([]models.Subdomain, error) {
+	var seeds, all []string
+	for _, sub := range subdomains {
+		all = append(all, sub.Name)
+		if sub.Resolved {
+			seeds = append(seeds, sub.Name)
+		}
+	}
+
+	patterns := cfg.PermutationPatterns
+	if len(patterns) == 0 {
+		patterns = DefaultPermutationPatterns
+	}
+	limit := cfg.MaxPermutations
+	if limit <= 0 {
+		limit = defaultMaxPermutations
+	}
+
+	// Generate from resolved names only, but never re-test a known name
+	candidates := GeneratePermutations(seeds, domain, patterns, 0)
+	known := make(map[string]bool, len(all))
+	for _, n := range all {
+		known[n] = true
+	}
+	filtered := candidates[:0]
+	for _, c := range candidates {
+		if !known[c] {
+			filtered = append(filtered, c)
+		}
+	}
+	candidates = filtered
+	if len(candidates) > limit {
+		fmt.Printf("Warning: %d permutation candidates, testing the first %d\n", len(candidates), limit)
+		candidates = candidates[:limit]
+	}
+	if len(candidates) == 0 {
+		return nil, nil
+	}
+
+	if len(wildcardIPs) > 0 {
+		fmt.Printf("Ignoring permutations that resolve only to the wildcard addresses of %s\n", domain)
+	}
+
+	fmt.Printf("Resolving %d permutation candidates...\n", len(candidates))
+	return resolveCandidates(ctx, domain, candidates, PermutationSource, wildcardIPs, cfg)
+}
+
+// resolveCandidates looks up generated candidate names with cfg.Resolve's
+// workers and per-name timeout, and returns those that resolve, fully
+// populated via ResolveBatch and recorded with source. Candidates that
+// resolve only to wildcardIPs are discarded. In dnsx mode one dnsx run
+// looks up every candidate.
+func resolveCandidates(ctx context.Context, domain string, candidates []string, source string, wildcardIPs map[string]bool, cfg DiscoveryConfig) ([]models.Subdomain, error) {
+	if tools.BulkDNS() {
+		return resolveCandidatesBulk(ctx, domain, candidates, source, wildcardIPs, cfg)
+	}
+
+	workers := cfg.Resolve.Workers
+	if workers <= 0 {
+		workers = DefaultDNSWorkers
+	}
+	timeout := cfg.Resolve.Timeout
+	if timeout <= 0 {
+		timeout = DefaultDNSTimeout
+	}
+
+	var (
+		mu    sync.Mutex
+		hits  []string
+		wg    sync.WaitGroup
+		queue = make(chan string)
+	)
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for name := range queue {
+				lookupCtx, cancel := context.WithTimeout(ctx, timeout)
+				results, err := tools.ResolveSubdomains(lookupCtx, []string{name}, cfg.DigPath)
+				cancel()
+				if err != nil || len(results) == 0 || !results[0].Resolved {
+					continue
+				}
+				if onlyWildcard(results[0].IPs, wildcardIPs) {
+					continue
+				}
+				mu.Lock()
+				hits = append(hits, name)
+				mu.Unlock()
+			}
+		}()
+	}
+	for _, c := range candidates {
+		if ctx.Err() != nil {
+			break
+		}
+		queue <- c
+	}
+	close(queue)
+	wg.Wait()
+
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	if len(hits) == 0 {
+		return nil, nil
+	}
+
+	sort.Strings(hits)
+	found := make([]models.Subdomain, len(hits))
+	for i, name := range hits {
+		found[i] = models.Subdomain{Name: name, Domain: domain, Source: source}
+	}
+	return ResolveBatch(ctx, found, cfg.DigPath, cfg.Consensus, cfg.Resolve)
+}
+
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -2,11 +2,14 @@ package discovery
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sort"
 	"strings"
+	"time"
 
-	"github.com/hakim/reconpipe/internal/models"
 	"github.com/hakim/reconpipe/internal/tools"
+	"github.com/hakim/reconpipe/pkg/models"
 )
 
 // DiscoveryResult contains the complete results of subdomain discovery
@@ -18,51 +21,158 @@ type DiscoveryResult struct {
 	ResolvedCount int                 `json:"resolved_count"`
 	DanglingCount int                 `json:"dangling_count"`
 	Sources       map[string]int      `json:"sources"`
+
+	// ProvidedNotDiscovered lists known subdomains (--known-subdomains) that
+	// no passive source found. They are still resolved and scanned.
+	ProvidedNotDiscovered []string `json:"provided_not_discovered,omitempty"`
+
+	// Findings are misconfigurations observed during discovery itself, such
+	// as a nameserver allowing zone transfers.
+	Findings []models.Vulnerability `json:"findings,omitempty"`
+
+	// DNSHealth is the DNSSEC and CAA state of the apex and key subdomains.
+	DNSHealth []DNSHealthCheck `json:"dns_health,omitempty"`
+
+	// Consensus is set when names were resolved through several resolvers
+	// (--dns-consensus).
+	Consensus *ConsensusSummary `json:"dns_consensus,omitempty"`
+
+	// Wildcard is set when random names under the target resolve, i.e. it
+	// has wildcard DNS.
+	Wildcard *WildcardSummary `json:"wildcard,omitempty"`
+
+	// CollectedAt is when discovery started querying sources: the time the
+	// results describe, however much later a report is rendered from them.
+	CollectedAt time.Time `json:"collected_at,omitzero"`
 }
 
+// AmassSource is the Source recorded for subdomains amass found first; its
+// output does not attribute names to its own data sources.
+const AmassSource = "amass"
+
 // DiscoveryConfig contains configuration for the discovery pipeline
 type DiscoveryConfig struct {
 	SubfinderThreads int
 	SubfinderPath    string
-	TlsxPath         string
-	DigPath          string
-	SkipTlsx         bool
+	// Engine selects the enumeration tools: tools.EngineSubfinder (the
+	// default when empty), tools.EngineAmass or tools.EngineBoth.
+	Engine    string
+	AmassPath string
+	TlsxPath  string
+	DigPath   string
+	SkipTlsx  bool
+	// KnownSubdomains are client-supplied hostnames merged into the dedup
+	// set with source "provided". Entries outside the target domain are
+	// ignored.
+	KnownSubdomains []string
+	// Permutations enables altdns-style name generation from resolved
+	// subdomains; only candidates that resolve are kept.
+	Permutations        bool
+	PermutationPatterns []string // empty = DefaultPermutationPatterns
+	MaxPermutations     int      // 0 = 2000
+	// Bruteforce resolves a wordlist of labels under the domain; only
+	// candidates that resolve are kept.
+	Bruteforce       bool
+	BruteforceWords  []string // empty = DefaultBruteforceWords
+	BruteforceMutate bool     // also combine the words with resolved names, altdns-style
+	MaxBruteforce    int      // 0 = 5000
+	// ZoneTransfer attempts AXFR against each authoritative nameserver.
+	ZoneTransfer bool
+	// WildcardFilter drops subdomains that resolve only to the target's
+	// wildcard DNS addresses instead of flagging them.
+	WildcardFilter bool
+	// DNSHealth checks DNSSEC and CAA for the apex and key subdomains.
+	DNSHealth           bool
+	DNSHealthSubdomains []string // empty = DefaultDNSHealthSubdomains
+	DNSHealthResolver   string   // empty = system resolver
+	// Consensus resolves each name through several resolvers and keeps only
+	// answers a quorum of them returned (--dns-consensus).
+	Consensus Consensus
+	// Resolve sets how many names are resolved at once and how long each
+	// may take.
+	Resolve ResolveOptions
+	// Passive controls the crt.sh, Wayback and SecurityTrails queries made
+	// alongside subfinder.
+	Passive PassiveOptions
 }
 
 // RunDiscovery orchestrates the full subdomain discovery pipeline.
-// It runs subfinder and tlsx (if enabled), normalizes and deduplicates results,
+// It runs subfinder and/or amass and tlsx (if enabled), queries the
+// built-in passive sources, normalizes and deduplicates results,
 // resolves DNS, and classifies dangling entries.
 func RunDiscovery(ctx context.Context, domain string, cfg DiscoveryConfig) (*DiscoveryResult, error) {
 	result := &DiscoveryResult{
-		Target:  domain,
-		Sources: make(map[string]int),
+		Target:      domain,
+		Sources:     make(map[string]int),
+		CollectedAt: time.Now().UTC(),
 	}
 
 	// Map for deduplication: key=normalized subdomain, value=source
 	subdomainMap := make(map[string]string)
 
-	// Step 1: Run subfinder
-	fmt.Printf("Running subfinder for %s...\n", domain)
-	subfinderResults, err := tools.RunSubfinder(ctx, domain, cfg.SubfinderThreads, cfg.SubfinderPath)
-	if err != nil {
-		return nil, fmt.Errorf("subfinder execution failed: %w", err)
-	}
+	// Step 1: Run the enumeration engine(s): subfinder, amass, or both
+	runSubfinder, runAmass := tools.EngineTools(cfg.Engine)
+	var subfinderErr, amassErr error
+	if runSubfinder {
+		fmt.Printf("Running subfinder for %s...\n", domain)
+		subfinderResults, err := tools.RunSubfinder(ctx, domain, cfg.SubfinderThreads, cfg.SubfinderPath)
+		if err != nil {
+			subfinderErr = fmt.Errorf("subfinder execution failed: %w", err)
+		} else {
+			// Collect subfinder results
+			for _, sf := range subfinderResults {
+				normalized := normalizeSubdomain(sf.Host)
+				if normalized == "" {
+					continue
+				}
 
-	// Collect subfinder results
-	for _, sf := range subfinderResults {
-		normalized := normalizeSubdomain(sf.Host)
-		if normalized == "" {
-			continue
+				result.TotalFound++
+
+				// First source wins for dedup
+				if _, exists := subdomainMap[normalized]; !exists {
+					subdomainMap[normalized] = sf.Source
+				}
+			}
+			result.Sources["subfinder"] = len(subfinderResults)
 		}
+	}
+	if runAmass {
+		fmt.Printf("Running amass for %s...\n", domain)
+		amassResults, err := tools.RunAmass(ctx, domain, cfg.AmassPath)
+		if err != nil {
+			amassErr = fmt.Errorf("amass execution failed: %w", err)
+		} else {
+			// Collect amass results
+			for _, name := range amassResults {
+				normalized := normalizeSubdomain(name)
+				if normalized == "" {
+					continue
+				}
 
-		result.TotalFound++
+				result.TotalFound++
 
-		// First source wins for dedup
-		if _, exists := subdomainMap[normalized]; !exists {
-			subdomainMap[normalized] = sf.Source
+				// First source wins for dedup
+				if _, exists := subdomainMap[normalized]; !exists {
+					subdomainMap[normalized] = AmassSource
+				}
+			}
+			result.Sources[AmassSource] = len(amassResults)
 		}
 	}
-	result.Sources["subfinder"] = len(subfinderResults)
+
+	// With both engines, either one's results are enough to go on
+	switch {
+	case subfinderErr != nil && amassErr != nil:
+		return nil, errors.Join(subfinderErr, amassErr)
+	case subfinderErr != nil && !runAmass:
+		return nil, subfinderErr
+	case amassErr != nil && !runSubfinder:
+		return nil, amassErr
+	case subfinderErr != nil:
+		fmt.Printf("Warning: %v\n", subfinderErr)
+	case amassErr != nil:
+		fmt.Printf("Warning: %v\n", amassErr)
+	}
 
 	// Step 2: Run tlsx (if not skipped)
 	if !cfg.SkipTlsx {
@@ -90,7 +200,78 @@ func RunDiscovery(ctx context.Context, domain string, cfg DiscoveryConfig) (*Dis
 		}
 	}
 
-	// Step 3: Build Subdomain slice from deduplicated map
+	// Step 3: Query crt.sh, Wayback and SecurityTrails directly
+	for _, pr := range queryPassiveSources(ctx, domain, cfg.Passive) {
+		if pr.err != nil {
+			// Passive sources are supplementary - a failing API must not stop discovery
+			fmt.Printf("Warning: %s query failed: %v\n", pr.source, pr.err)
+			continue
+		}
+		for _, name := range pr.names {
+			result.TotalFound++
+
+			// First source wins for dedup
+			if _, exists := subdomainMap[name]; !exists {
+				subdomainMap[name] = pr.source
+			}
+		}
+		result.Sources[pr.source] = len(pr.names)
+		fmt.Printf("%s returned %d subdomains\n", pr.source, len(pr.names))
+	}
+
+	// Step 4: Zone transfer against each authoritative NS (opt-in)
+	if cfg.ZoneTransfer {
+		hosts, findings, err := attemptZoneTransfers(ctx, domain, cfg.DigPath)
+		if err != nil {
+			// AXFR is opportunistic - a lookup failure must not stop discovery
+			fmt.Printf("Warning: zone transfer attempt failed: %v\n", err)
+		} else {
+			result.Findings = append(result.Findings, findings...)
+			for _, host := range hosts {
+				result.TotalFound++
+				if _, exists := subdomainMap[host]; !exists {
+					subdomainMap[host] = ZoneTransferSource
+				}
+			}
+			result.Sources[ZoneTransferSource] = len(hosts)
+			if len(findings) > 0 {
+				fmt.Printf("Zone transfer allowed by %d nameserver(s), %d hosts recovered\n", len(findings), len(hosts))
+			} else {
+				fmt.Println("Zone transfer refused by all nameservers")
+			}
+		}
+	}
+
+	// Step 5: Merge client-supplied subdomains so they are always covered
+	if len(cfg.KnownSubdomains) > 0 {
+		provided, outOfScope := 0, 0
+		for _, known := range cfg.KnownSubdomains {
+			normalized := normalizeSubdomain(known)
+			if normalized == "" {
+				continue
+			}
+			if !inDomain(normalized, domain) {
+				outOfScope++
+				continue
+			}
+
+			provided++
+			if _, exists := subdomainMap[normalized]; !exists {
+				subdomainMap[normalized] = ProvidedSource
+				result.ProvidedNotDiscovered = append(result.ProvidedNotDiscovered, normalized)
+			}
+		}
+		result.Sources[ProvidedSource] = provided
+		sort.Strings(result.ProvidedNotDiscovered)
+
+		fmt.Printf("Merged %d provided subdomains (%d not found by passive sources)\n",
+			provided, len(result.ProvidedNotDiscovered))
+		if outOfScope > 0 {
+			fmt.Printf("Warning: ignored %d provided entries outside %s\n", outOfScope, domain)
+		}
+	}
+
+	// Step 6: Build Subdomain slice from deduplicated map
 	subdomains := make([]models.Subdomain, 0, len(subdomainMap))
 	for subdomain, source := range subdomainMap {
 		subdomains = append(subdomains, models.Subdomain{
@@ -103,15 +284,35 @@ func RunDiscovery(ctx context.Context, domain string, cfg DiscoveryConfig) (*Dis
 
 	fmt.Printf("Found %d unique subdomains (total: %d)\n", result.UniqueCount, result.TotalFound)
 
-	// Step 4: Resolve DNS and classify dangling entries
+	// Step 7: Resolve DNS, flag wildcard answers and classify dangling entries.
+	// Names resolving only to what random labels resolve to may exist only
+	// because of wildcard DNS.
+	wildcardIPs, wildcardErr := detectWildcard(ctx, domain, cfg.DigPath)
+	if wildcardErr != nil {
+		// Wildcard detection is an enhancement - keep the names unflagged
+		fmt.Printf("Warning: %v\n", wildcardErr)
+	}
 	if len(subdomains) > 0 {
 		fmt.Printf("Resolving DNS for %d subdomains...\n", len(subdomains))
-		resolvedSubdomains, err := ResolveBatch(ctx, subdomains, cfg.DigPath)
+		if c := cfg.Consensus.Summary(); c != nil {
+			fmt.Printf("DNS consensus: %d of %d resolvers must agree (%s)\n", c.Quorum, len(c.Resolvers), strings.Join(c.Resolvers, ", "))
+		}
+		resolvedSubdomains, err := ResolveBatch(ctx, subdomains, cfg.DigPath, cfg.Consensus, cfg.Resolve)
 		if err != nil {
 			return nil, fmt.Errorf("DNS resolution failed: %w", err)
 		}
 		result.Subdomains = resolvedSubdomains
 
+		if len(wildcardIPs) > 0 {
+			result.Subdomains, result.Wildcard = applyWildcard(result.Subdomains, wildcardIPs, cfg.WildcardFilter)
+			result.UniqueCount -= len(result.Wildcard.Filtered)
+			fmt.Printf("Wildcard DNS detected for %s (%s): %d subdomain(s) resolve only to it\n",
+				domain, strings.Join(result.Wildcard.IPs, ", "), result.Wildcard.Flagged+len(result.Wildcard.Filtered))
+			if len(result.Wildcard.Filtered) > 0 {
+				fmt.Printf("Dropped %d of them (wildcard_filter)\n", len(result.Wildcard.Filtered))
+			}
+		}
+
 		// Calculate counts
 		for _, sub := range result.Subdomains {
 			if sub.Resolved {
@@ -124,6 +325,74 @@ func RunDiscovery(ctx context.Context, domain string, cfg DiscoveryConfig) (*Dis
 	}
 
 	fmt.Printf("Resolution complete: %d resolved, %d dangling\n", result.ResolvedCount, result.DanglingCount)
+	result.Consensus = cfg.Consensus.Summary()
+	if result.Consensus != nil {
+		if n := len(UnconfirmedSubdomains(result.Subdomains)); n > 0 {
+			fmt.Printf("DNS consensus: %d subdomain(s) had answers below quorum\n", n)
+		}
+	}
+
+	// Step 8: Permutations of resolved names (opt-in)
+	if cfg.Permutations && result.ResolvedCount > 0 {
+		// Without the wildcard addresses every candidate could look real
+		var found []models.Subdomain
+		err := wildcardErr
+		if err == nil {
+			found, err = resolvePermutations(ctx, domain, result.Subdomains, wildcardIPs, cfg)
+		}
+		if err != nil {
+			// Permutations are an enhancement - keep what passive sources found
+			fmt.Printf("Warning: permutation discovery failed: %v\n", err)
+		} else {
+			result.Subdomains = append(result.Subdomains, found...)
+			result.UniqueCount += len(found)
+			for _, sub := range found {
+				if sub.Resolved {
+					result.ResolvedCount++
+				}
+				if sub.IsDangling {
+					result.DanglingCount++
+				}
+			}
+			result.Sources[PermutationSource] = len(found)
+			fmt.Printf("Permutations found %d new subdomains\n", len(found))
+		}
+	}
+
+	// Step 9: Wordlist brute-forcing (opt-in)
+	if cfg.Bruteforce {
+		// Without the wildcard addresses every candidate could look real
+		var found []models.Subdomain
+		err := wildcardErr
+		if err == nil {
+			found, err = resolveBruteforce(ctx, domain, result.Subdomains, wildcardIPs, cfg)
+		}
+		if err != nil {
+			// Brute-forcing is an enhancement - keep what other sources found
+			fmt.Printf("Warning: brute-force discovery failed: %v\n", err)
+		} else {
+			result.Subdomains = append(result.Subdomains, found...)
+			result.UniqueCount += len(found)
+			for _, sub := range found {
+				if sub.Resolved {
+					result.ResolvedCount++
+				}
+				if sub.IsDangling {
+					result.DanglingCount++
+				}
+			}
+			result.Sources[BruteforceSource] = len(found)
+			fmt.Printf("Brute-forcing found %d new subdomains\n", len(found))
+		}
+	}
+
+	// Step 10: DNSSEC and CAA checks for the apex and key subdomains
+	if cfg.DNSHealth {
+		result.DNSHealth = checkDNSHealth(ctx, domain, result.Subdomains, cfg.DNSHealthSubdomains, cfg.DNSHealthResolver, cfg.DigPath)
+		findings := DNSHealthFindings(domain, result.DNSHealth)
+		result.Findings = append(result.Findings, findings...)
+		fmt.Printf("DNS health: %d issue(s) across %d name(s)\n", len(findings), len(result.DNSHealth))
+	}
 
 	return result, nil
 }
@@ -0,0 +1,100 @@
+package discovery
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sort"
+
+	"github.com/hakim/reconpipe/internal/tools"
+	"github.com/hakim/reconpipe/pkg/models"
+)
+
+// wildcardProbes is how many random labels detectWildcard resolves. A
+// wildcard answered by a load balancer may rotate through several
+// addresses, so one probe can miss some of them.
+const wildcardProbes = 3
+
+// WildcardSummary records the wildcard DNS of a target: the addresses
+// names that do not exist resolve to, and the subdomains that resolve only
+// to them and so may exist only because of the wildcard.
+type WildcardSummary struct {
+	IPs []string `json:"ips"`
+	// Flagged counts the subdomains kept with Wildcard set.
+	Flagged int `json:"flagged"`
+	// Filtered lists the subdomains dropped for resolving only to the
+	// wildcard addresses (discovery.wildcard_filter).
+	Filtered []string `json:"filtered,omitempty"`
+}
+
+// detectWildcard resolves several random labels under domain. Any addresses
+// they return are the wildcard answer set; an empty set means no wildcard.
+func detectWildcard(ctx context.Context, domain, digPath string) (map[string]bool, error) {
+	ips := make(map[string]bool)
+	for range wildcardProbes {
+		buf := make([]byte, 8)
+		if _, err := rand.Read(buf); err != nil {
+			return nil, fmt.Errorf("generating wildcard probe label: %w", err)
+		}
+		probe := "rp-" + hex.EncodeToString(buf) + "." + domain
+
+		results, err := tools.ResolveSubdomains(ctx, []string{probe}, digPath)
+		if err != nil {
+			return nil, fmt.Errorf("wildcard check failed: %w", err)
+		}
+		if len(results) > 0 && results[0].Resolved {
+			for _, ip := range results[0].IPs {
+				ips[ip] = true
+			}
+		}
+	}
+	return ips, nil
+}
+
+// onlyWildcard reports whether every address in ips belongs to the wildcard set.
+func onlyWildcard(ips []string, wildcard map[string]bool) bool {
+	if len(wildcard) == 0 {
+		return false
+	}
+	for _, ip := range ips {
+		if !wildcard[ip] {
+			return false
+		}
+	}
+	return true
+}
+
+// applyWildcard flags the resolved subdomains whose addresses are all
+// wildcard addresses. With filter set they are dropped instead, except the
+// provided ones, which the client asked to have scanned. It returns the
+// subdomains kept and the summary.
+func applyWildcard(subdomains []models.Subdomain, wildcard map[string]bool, filter bool) ([]models.Subdomain, *WildcardSummary) {
+	summary := &WildcardSummary{IPs: sortedKeys(wildcard)}
+	kept := subdomains[:0]
+	for _, sub := range subdomains {
+		if sub.Resolved && onlyWildcard(sub.IPs, wildcard) {
+			if filter && sub.Source != ProvidedSource {
+				summary.Filtered = append(summary.Filtered, sub.Name)
+				continue
+			}
+			sub.Wildcard = true
+			summary.Flagged++
+		}
+		kept = append(kept, sub)
+	}
+	sort.Strings(summary.Filtered)
+	return kept, summary
+}
+
+// WildcardSubdomains returns the subdomains that resolve only to wildcard
+// addresses.
+func WildcardSubdomains(subdomains []models.Subdomain) []models.Subdomain {
+	var out []models.Subdomain
+	for _, sub := range subdomains {
+		if sub.Wildcard {
+			out = append(out, sub)
+		}
+	}
+	return out
+}
@@ -0,0 +1,117 @@
+// Package exclude holds the never-scan list: networks such as corporate
+// ranges, government CIDRs or a client's do-not-touch list that no stage may
+// send traffic to. The list is loaded from the file named by exclude_file,
+// which is also handed to masscan as its --excludefile.
+package exclude
+
+import (
+	"bufio"
+	"fmt"
+	"net/netip"
+	"os"
+	"strings"
+
+	"github.com/hakim/reconpipe/pkg/models"
+)
+
+// List is a loaded exclusions file. A nil *List excludes nothing.
+type List struct {
+	// Path is the file the list was loaded from.
+	Path     string
+	prefixes []netip.Prefix
+}
+
+// Load reads an exclusions file: one IP address or CIDR per line, with
+// blank lines and #-comments ignored. Entries may carry a trailing comment,
+// e.g. "10.0.0.0/8  # corporate".
+func Load(path string) (*List, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	l := &List{Path: path}
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		entry, _, _ := strings.Cut(scanner.Text(), "#")
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		p, err := parseEntry(entry)
+		if err != nil {
+			return nil, fmt.Errorf("%s line %d: %q is not an IP address or CIDR", path, n, entry)
+		}
+		l.prefixes = append(l.prefixes, p)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading %s: %w", path, err)
+	}
+	return l, nil
+}
+
+// parseEntry parses an address as a single-address prefix, or a CIDR.
+func parseEntry(s string) (netip.Prefix, error) {
+	if !strings.Contains(s, "/") {
+		addr, err := netip.ParseAddr(s)
+		if err != nil {
+			return netip.Prefix{}, err
+		}
+		return netip.PrefixFrom(addr, addr.BitLen()), nil
+	}
+	p, err := netip.ParsePrefix(s)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	return p.Masked(), nil
+}
+
+// Len returns the number of entries in the list.
+func (l *List) Len() int {
+	if l == nil {
+		return 0
+	}
+	return len(l.prefixes)
+}
+
+// Contains reports whether ip falls within an excluded network. Values that
+// are not IP addresses are never excluded.
+func (l *List) Contains(ip string) bool {
+	if l == nil {
+		return false
+	}
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, p := range l.prefixes {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// Allows reports whether host may be contacted: it was not marked excluded
+// by the port scan and its address is not on the list.
+func (l *List) Allows(host models.Host) bool {
+	return !host.Excluded && !l.Contains(host.IP)
+}
+
+// Names returns the hostnames that resolve to at least one excluded host.
+// Probing such a name may reach the excluded address, so callers skip it
+// even when it also resolves elsewhere.
+func (l *List) Names(hosts []models.Host) map[string]bool {
+	names := make(map[string]bool)
+	for _, host := range hosts {
+		if l.Allows(host) {
+			continue
+		}
+		for _, sub := range host.Subdomains {
+			names[sub] = true
+		}
+	}
+	return names
+}
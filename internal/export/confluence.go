@@ -0,0 +1,431 @@
+package export
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/hakim/reconpipe/internal/diff"
+	"github.com/hakim/reconpipe/internal/storage"
+	"github.com/hakim/reconpipe/pkg/models"
+)
+
+// confluenceReportOrder lists the reports in the order they appear on a scan
+// page. Other Markdown reports follow alphabetically.
+var confluenceReportOrder = []string{
+	"subdomains.md", "dangling-dns.md", "ports.md", "http-probes.md", "vulns.md", "diff.md",
+}
+
+// ConfluenceSink publishes a scan's Markdown reports as a Confluence page.
+// Pages are arranged as {parent page} > "Recon: {target}" > one page per
+// scan, and publishing the same scan again updates its page in place. It uses
+// the v1 content REST API, which Confluence Cloud and Data Center share.
+type ConfluenceSink struct {
+	URL    string // base URL, e.g. https://acme.atlassian.net/wiki
+	Space  string // space key
+	Parent string // page ID or title the target pages live under; empty for the space root
+
+	// Credentials are expanded with os.ExpandEnv. Cloud uses username (the
+	// account email) and password (an API token); Data Center can use a
+	// personal access token in APIKey instead.
+	Username string
+	Password string
+	APIKey   string
+
+	Client *http.Client
+}
+
+// Name implements OutputSink.
+func (s *ConfluenceSink) Name() string { return "confluence:" + s.URL + " (" + s.Space + ")" }
+
+// TargetPageTitle is the title of the page that collects a target's scans.
+func TargetPageTitle(target string) string { return "Recon: " + target }
+
+// ScanPageTitle is the title of a scan's page. Titles are unique within a
+// space, so it includes the start time.
+func ScanPageTitle(scan *models.ScanMeta) string {
+	return fmt.Sprintf("Recon: %s %s", scan.Target, scan.StartedAt.UTC().Format("2006-01-02 15:04:05 UTC"))
+}
+
+// Publish implements OutputSink.
+func (s *ConfluenceSink) Publish(ctx context.Context, scan *models.ScanMeta, snap *diff.ScanSnapshot) error {
+	reports, err := scanReports(scan.ScanDir)
+	if err != nil {
+		return err
+	}
+	if len(reports) == 0 {
+		return fmt.Errorf("no Markdown reports in %s", storage.ReportsDir(scan.ScanDir))
+	}
+
+	parentID := ""
+	if s.Parent != "" {
+		if parentID, err = s.resolvePage(ctx, s.Parent); err != nil {
+			return err
+		}
+	}
+
+	targetID, err := s.ensurePage(ctx, TargetPageTitle(scan.Target), parentID,
+		"<p>Reconnaissance scans of <strong>"+html.EscapeString(scan.Target)+"</strong>, one child page per scan, published by reconpipe.</p>"+
+			`<ac:structured-macro ac:name="children"><ac:parameter ac:name="sort">creation</ac:parameter><ac:parameter ac:name="reverse">true</ac:parameter></ac:structured-macro>`,
+		false)
+	if err != nil {
+		return err
+	}
+
+	title := ScanPageTitle(scan)
+	if _, err := s.ensurePage(ctx, title, targetID, scanPageBody(scan, snap, reports), true); err != nil {
+		return err
+	}
+
+	fmt.Printf("[+] Published %d reports to Confluence page %q\n", len(reports), title)
+	return nil
+}
+
+// confluenceReport is one Markdown report read from the scan directory.
+type confluenceReport struct {
+	Name    string
+	Content string
+}
+
+// scanReports reads the scan's Markdown reports in confluenceReportOrder.
+func scanReports(scanDir string) ([]confluenceReport, error) {
+	dir := storage.ReportsDir(scanDir)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("reading reports: %w", err)
+	}
+
+	var names []string
+	for _, e := range entries {
+		if !e.IsDir() && strings.EqualFold(filepath.Ext(e.Name()), ".md") {
+			names = append(names, e.Name())
+		}
+	}
+	rank := func(name string) int {
+		if i := slices.Index(confluenceReportOrder, name); i >= 0 {
+			return i
+		}
+		return len(confluenceReportOrder)
+	}
+	slices.SortStableFunc(names, func(a, b string) int {
+		if ra, rb := rank(a), rank(b); ra != rb {
+			return ra - rb
+		}
+		return strings.Compare(a, b)
+	})
+
+	var out []confluenceReport
+	for _, name := range names {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			return nil, fmt.Errorf("reading %s: %w", name, err)
+		}
+		out = append(out, confluenceReport{Name: name, Content: string(data)})
+	}
+	return out, nil
+}
+
+// scanPageBody renders the scan page: a summary table, a table of contents
+// and every report converted to storage format.
+func scanPageBody(scan *models.ScanMeta, snap *diff.ScanSnapshot, reports []confluenceReport) string {
+	ports := 0
+	for _, h := range snap.Hosts {
+		ports += len(h.Ports)
+	}
+
+	var b strings.Builder
+	b.WriteString("<table><tbody>")
+	row := func(k, v string) {
+		fmt.Fprintf(&b, "<tr><th>%s</th><td>%s</td></tr>", k, html.EscapeString(v))
+	}
+	row("Target", scan.Target)
+	row("Scan ID", scan.ID)
+	row("Started", scan.StartedAt.UTC().Format("2006-01-02 15:04:05 UTC"))
+	if scan.CompletedAt != nil {
+		row("Completed", scan.CompletedAt.UTC().Format("2006-01-02 15:04:05 UTC"))
+	}
+	row("Status", string(scan.Status))
+	if scan.Operator != "" {
+		row("Operator", scan.Operator)
+	}
+	row("Stages", strings.Join(scan.StagesRun, ", "))
+	row("Results", fmt.Sprintf("%d subdomains, %d open ports, %d HTTP services, %d vulnerabilities",
+		len(snap.Subdomains), ports, len(snap.Probes), len(snap.Vulnerabilities)))
+	b.WriteString("</tbody></table>")
+	b.WriteString(`<ac:structured-macro ac:name="toc"><ac:parameter ac:name="maxLevel">2</ac:parameter></ac:structured-macro>`)
+
+	for _, r := range reports {
+		b.WriteString("<hr />")
+		b.WriteString(markdownToStorage(r.Content))
+	}
+	return b.String()
+}
+
+// confluencePage is the part of a content API page this sink reads.
+type confluencePage struct {
+	ID      string `json:"id"`
+	Version struct {
+		Number int `json:"number"`
+	} `json:"version"`
+}
+
+// resolvePage returns the ID of the page named by ref: a numeric page ID as
+// is, otherwise the page with that title in the space.
+func (s *ConfluenceSink) resolvePage(ctx context.Context, ref string) (string, error) {
+	if strings.Trim(ref, "0123456789") == "" {
+		return ref, nil
+	}
+	page, err := s.findPage(ctx, ref)
+	if err != nil {
+		return "", err
+	}
+	if page == nil {
+		return "", fmt.Errorf("parent page %q not found in space %s", ref, s.Space)
+	}
+	return page.ID, nil
+}
+
+// ensurePage creates the page titled title under parentID, or returns the
+// existing one, replacing its body when update is set.
+func (s *ConfluenceSink) ensurePage(ctx context.Context, title, parentID, body string, update bool) (string, error) {
+	page, err := s.findPage(ctx, title)
+	if err != nil {
+		return "", err
+	}
+
+	content := map[string]any{
+		"type":  "page",
+		"title": title,
+		"space": map[string]string{"key": s.Space},
+		"body": map[string]any{
+			"storage": map[string]string{"value": body, "representation": "storage"},
+		},
+	}
+	if parentID != "" {
+		content["ancestors"] = []map[string]string{{"id": parentID}}
+	}
+
+	if page == nil {
+		var created confluencePage
+		if err := s.do(ctx, http.MethodPost, "/rest/api/content", content, &created); err != nil {
+			return "", fmt.Errorf("creating page %q: %w", title, err)
+		}
+		return created.ID, nil
+	}
+	if !update {
+		return page.ID, nil
+	}
+
+	content["id"] = page.ID
+	content["version"] = map[string]any{"number": page.Version.Number + 1, "message": "Updated by reconpipe"}
+	if err := s.do(ctx, http.MethodPut, "/rest/api/content/"+url.PathEscape(page.ID), content, nil); err != nil {
+		return "", fmt.Errorf("updating page %q: %w", title, err)
+	}
+	return page.ID, nil
+}
+
+// findPage looks a page up by title in the space, returning nil if there is
+// none.
+func (s *ConfluenceSink) findPage(ctx context.Context, title string) (*confluencePage, error) {
+	q := url.Values{
+		"spaceKey": {s.Space},
+		"title":    {title},
+		"type":     {"page"},
+		"expand":   {"version"},
+	}
+	var result struct {
+		Results []confluencePage `json:"results"`
+	}
+	if err := s.do(ctx, http.MethodGet, "/rest/api/content?"+q.Encode(), nil, &result); err != nil {
+		return nil, fmt.Errorf("looking up page %q: %w", title, err)
+	}
+	if len(result.Results) == 0 {
+		return nil, nil
+	}
+	return &result.Results[0], nil
+}
+
+// do sends one content API request, encoding in as the JSON body and
+// decoding the response into out when they are non-nil.
+func (s *ConfluenceSink) do(ctx context.Context, method, path string, in, out any) error {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return fmt.Errorf("encoding request: %w", err)
+		}
+		body = bytes.NewReader(data)
+	}
+
+	endpoint := strings.TrimRight(s.URL, "/") + path
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, body)
+	if err != nil {
+		return fmt.Errorf("building request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	switch {
+	case s.APIKey != "":
+		req.Header.Set("Authorization", "Bearer "+os.ExpandEnv(s.APIKey))
+	case s.Username != "":
+		req.SetBasicAuth(os.ExpandEnv(s.Username), os.ExpandEnv(s.Password))
+	}
+
+	client := s.Client
+	if client == nil {
+		client = &http.Client{Timeout: 30 * time.Second}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s %s: %w", method, endpoint, err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("reading response: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("%s returned %s: %s", endpoint, resp.Status, truncate(string(data), 512))
+	}
+	if out != nil {
+		if err := json.Unmarshal(data, out); err != nil {
+			return fmt.Errorf("parsing response: %w", err)
+		}
+	}
+	return nil
+}
+
+// Markdown patterns for markdownToStorage.
+var (
+	mdHeading   = regexp.MustCompile(`^(#{1,6})\s+(.*)$`)
+	mdListItem  = regexp.MustCompile(`^\s*(?:[-*+]|(\d+)\.)\s+(.*)$`)
+	mdTableRule = regexp.MustCompile(`^\|?[\s:|-]+\|?$`)
+	mdInline    = regexp.MustCompile("`([^`]+)`" + `|!\[([^\]]*)\]\([^)]*\)|\[([^\]]+)\]\(([^)\s]+)\)|\*\*([^*]+)\*\*`)
+)
+
+// markdownToStorage converts the Markdown subset the report generators
+// write (headings, pipe tables, lists, fenced code, bold, code spans and
+// links) to Confluence storage format. Consecutive text lines become one
+// paragraph with line breaks, matching how the reports lay out their
+// key: value headers. Images would need attachments, so only their alt text
+// is kept.
+func markdownToStorage(src string) string {
+	var b strings.Builder
+	lines := strings.Split(strings.ReplaceAll(src, "\r\n", "\n"), "\n")
+
+	for i := 0; i < len(lines); {
+		line := lines[i]
+		trimmed := strings.TrimSpace(line)
+
+		switch {
+		case trimmed == "":
+			i++
+
+		case strings.HasPrefix(trimmed, "```"):
+			var code []string
+			for i++; i < len(lines) && !strings.HasPrefix(strings.TrimSpace(lines[i]), "```"); i++ {
+				code = append(code, lines[i])
+			}
+			i++
+			b.WriteString(`<ac:structured-macro ac:name="code"><ac:plain-text-body><![CDATA[`)
+			b.WriteString(strings.ReplaceAll(strings.Join(code, "\n"), "]]>", "]]]]><![CDATA[>"))
+			b.WriteString(`]]></ac:plain-text-body></ac:structured-macro>`)
+
+		case mdHeading.MatchString(line):
+			m := mdHeading.FindStringSubmatch(line)
+			fmt.Fprintf(&b, "<h%d>%s</h%d>", len(m[1]), mdInlineToStorage(m[2]), len(m[1]))
+			i++
+
+		case trimmed == "---" || trimmed == "***":
+			b.WriteString("<hr />")
+			i++
+
+		case strings.HasPrefix(trimmed, "|"):
+			b.WriteString("<table><tbody>")
+			for row := 0; i < len(lines) && strings.HasPrefix(strings.TrimSpace(lines[i]), "|"); i, row = i+1, row+1 {
+				cellLine := strings.TrimSpace(lines[i])
+				if row == 1 && mdTableRule.MatchString(cellLine) {
+					continue
+				}
+				tag := "td"
+				if row == 0 {
+					tag = "th"
+				}
+				b.WriteString("<tr>")
+				for _, cell := range strings.Split(strings.Trim(cellLine, "|"), "|") {
+					fmt.Fprintf(&b, "<%s>%s</%s>", tag, mdInlineToStorage(strings.TrimSpace(cell)), tag)
+				}
+				b.WriteString("</tr>")
+			}
+			b.WriteString("</tbody></table>")
+
+		case mdListItem.MatchString(line):
+			tag := "ul"
+			if mdListItem.FindStringSubmatch(line)[1] != "" {
+				tag = "ol"
+			}
+			b.WriteString("<" + tag + ">")
+			for ; i < len(lines) && mdListItem.MatchString(lines[i]); i++ {
+				b.WriteString("<li>" + mdInlineToStorage(mdListItem.FindStringSubmatch(lines[i])[2]) + "</li>")
+			}
+			b.WriteString("</" + tag + ">")
+
+		default:
+			var para []string
+			for ; i < len(lines); i++ {
+				t := strings.TrimSpace(lines[i])
+				if t == "" || strings.HasPrefix(t, "|") || strings.HasPrefix(t, "```") ||
+					mdHeading.MatchString(lines[i]) || mdListItem.MatchString(lines[i]) {
+					break
+				}
+				para = append(para, mdInlineToStorage(t))
+			}
+			b.WriteString("<p>" + strings.Join(para, "<br />") + "</p>")
+		}
+	}
+	return b.String()
+}
+
+// mdInlineToStorage escapes text and converts code spans, bold and links.
+// Only http(s) links are kept; relative links point into the scan directory,
+// which Confluence readers can't reach.
+func mdInlineToStorage(text string) string {
+	var b strings.Builder
+	last := 0
+	for _, m := range mdInline.FindAllStringSubmatchIndex(text, -1) {
+		b.WriteString(html.EscapeString(text[last:m[0]]))
+		group := func(n int) string { return text[m[2*n]:m[2*n+1]] }
+		switch {
+		case m[2] >= 0:
+			b.WriteString("<code>" + html.EscapeString(group(1)) + "</code>")
+		case m[4] >= 0:
+			b.WriteString("<em>" + html.EscapeString(group(2)) + "</em>")
+		case m[6] >= 0:
+			label, href := html.EscapeString(group(3)), group(4)
+			if strings.HasPrefix(href, "https://") || strings.HasPrefix(href, "http://") {
+				fmt.Fprintf(&b, `<a href="%s">%s</a>`, html.EscapeString(href), label)
+			} else {
+				b.WriteString(label)
+			}
+		case m[10] >= 0:
+			b.WriteString("<strong>" + html.EscapeString(group(5)) + "</strong>")
+		}
+		last = m[1]
+	}
+	b.WriteString(html.EscapeString(text[last:]))
+	return b.String()
+}
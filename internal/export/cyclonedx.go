@@ -0,0 +1,273 @@
+// Package export converts a scan snapshot into interchange formats consumed
+// by external tooling (SBOM platforms, threat-intel platforms) and publishes
+// it to output sinks such as Elasticsearch.
+package export
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hakim/reconpipe/internal/diff"
+	"github.com/hakim/reconpipe/internal/notes"
+	"github.com/hakim/reconpipe/pkg/models"
+)
+
+// CycloneDX JSON document types (spec 1.5). Only the fields reconpipe
+// populates are modelled.
+
+// CycloneDXBOM is the top-level CycloneDX document.
+type CycloneDXBOM struct {
+	BOMFormat    string                `json:"bomFormat"`
+	SpecVersion  string                `json:"specVersion"`
+	SerialNumber string                `json:"serialNumber"`
+	Version      int                   `json:"version"`
+	Metadata     CycloneDXMetadata     `json:"metadata"`
+	Components   []CycloneDXComponent  `json:"components"`
+	Services     []CycloneDXService    `json:"services"`
+	Dependencies []CycloneDXDependency `json:"dependencies,omitempty"`
+}
+
+// CycloneDXMetadata describes when and by what the BOM was produced, and the
+// scan target it describes.
+type CycloneDXMetadata struct {
+	Timestamp string             `json:"timestamp"`
+	Tools     CycloneDXTools     `json:"tools"`
+	Component CycloneDXComponent `json:"component"`
+}
+
+// CycloneDXTools lists the tools that produced the BOM.
+type CycloneDXTools struct {
+	Components []CycloneDXComponent `json:"components"`
+}
+
+// CycloneDXComponent is a piece of software observed on the attack surface.
+type CycloneDXComponent struct {
+	BOMRef     string              `json:"bom-ref,omitempty"`
+	Type       string              `json:"type"`
+	Name       string              `json:"name"`
+	Version    string              `json:"version,omitempty"`
+	Properties []CycloneDXProperty `json:"properties,omitempty"`
+}
+
+// CycloneDXService is an exposed network service.
+type CycloneDXService struct {
+	BOMRef     string              `json:"bom-ref"`
+	Name       string              `json:"name"`
+	Endpoints  []string            `json:"endpoints,omitempty"`
+	Properties []CycloneDXProperty `json:"properties,omitempty"`
+}
+
+// CycloneDXDependency links a service to the components running behind it.
+type CycloneDXDependency struct {
+	Ref       string   `json:"ref"`
+	DependsOn []string `json:"dependsOn"`
+}
+
+// CycloneDXProperty is a free-form name/value pair.
+type CycloneDXProperty struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// BuildCycloneDX turns a scan snapshot into a CycloneDX BOM. Every open port
+// becomes a service; software identified by nmap (-sV product/version) or by
+// httpx technology detection becomes a component, and each service depends on
+// the components observed behind it. Analyst notes on an asset become
+// reconpipe:note properties of its services; the BOM has no findings for
+// finding notes to attach to.
+func BuildCycloneDX(snap *diff.ScanSnapshot, target, toolVersion string, scanNotes []notes.AttachedNote) *CycloneDXBOM {
+	bom := &CycloneDXBOM{
+		BOMFormat:    "CycloneDX",
+		SpecVersion:  "1.5",
+		SerialNumber: "urn:uuid:" + uuid.New().String(),
+		Version:      1,
+		Metadata: CycloneDXMetadata{
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+			Tools: CycloneDXTools{
+				Components: []CycloneDXComponent{
+					{Type: "application", Name: "reconpipe", Version: toolVersion},
+				},
+			},
+			Component: CycloneDXComponent{
+				BOMRef: "target:" + target,
+				Type:   "platform",
+				Name:   target,
+			},
+		},
+		Components: []CycloneDXComponent{},
+		Services:   []CycloneDXService{},
+	}
+
+	components := make(map[string]*CycloneDXComponent)
+	deps := make(map[string]map[string]bool)
+
+	// addComponent registers a component once per name@version and records
+	// which tool saw it. Returns its bom-ref.
+	addComponent := func(name, version, source string) string {
+		ref := "component:" + strings.ToLower(name)
+		if version != "" {
+			ref += "@" + version
+		}
+		c, ok := components[ref]
+		if !ok {
+			c = &CycloneDXComponent{BOMRef: ref, Type: "application", Name: name, Version: version}
+			components[ref] = c
+		}
+		prop := CycloneDXProperty{Name: "reconpipe:source", Value: source}
+		for _, p := range c.Properties {
+			if p == prop {
+				return ref
+			}
+		}
+		c.Properties = append(c.Properties, prop)
+		return ref
+	}
+
+	assetNotes := make(map[string][]string) // hostname or IP → note texts
+	for _, n := range scanNotes {
+		if n.Subject == models.NoteAsset {
+			assetNotes[strings.ToLower(n.Key)] = append(assetNotes[strings.ToLower(n.Key)], n.Text)
+		}
+	}
+
+	// Index HTTP probes by ip:port so their technologies attach to the
+	// matching service.
+	probesByEndpoint := make(map[string][]int)
+	for i, p := range snap.Probes {
+		key := fmt.Sprintf("%s:%d", p.IP, p.Port)
+		probesByEndpoint[key] = append(probesByEndpoint[key], i)
+	}
+
+	for _, host := range snap.Hosts {
+		for _, port := range host.Ports {
+			if port.State != "" && port.State != "open" {
+				continue
+			}
+
+			protocol := port.Protocol
+			if protocol == "" {
+				protocol = "tcp"
+			}
+			name := port.Service
+			if name == "" {
+				name = "unknown"
+			}
+
+			svc := CycloneDXService{
+				BOMRef: fmt.Sprintf("service:%s:%d/%s", host.IP, port.Number, protocol),
+				Name:   name,
+			}
+			svc.Endpoints = append(svc.Endpoints, fmt.Sprintf("%s://%s:%d", protocol, host.IP, port.Number))
+			for _, sub := range host.Subdomains {
+				svc.Endpoints = append(svc.Endpoints, fmt.Sprintf("%s://%s:%d", protocol, sub, port.Number))
+			}
+
+			svc.Properties = append(svc.Properties, CycloneDXProperty{Name: "reconpipe:ip", Value: host.IP})
+			for _, sub := range host.Subdomains {
+				svc.Properties = append(svc.Properties, CycloneDXProperty{Name: "reconpipe:hostname", Value: sub})
+			}
+			for _, asset := range append([]string{host.IP}, host.Subdomains...) {
+				for _, text := range assetNotes[strings.ToLower(asset)] {
+					svc.Properties = append(svc.Properties, CycloneDXProperty{Name: "reconpipe:note", Value: text})
+				}
+			}
+			if host.IsCDN {
+				svc.Properties = append(svc.Properties, CycloneDXProperty{Name: "reconpipe:cdn", Value: host.CDNProvider})
+			}
+			if host.Geo != nil {
+				svc.Properties = append(svc.Properties, CycloneDXProperty{Name: "reconpipe:country", Value: host.Geo.CountryCode})
+				if host.Geo.City != "" {
+					svc.Properties = append(svc.Properties, CycloneDXProperty{Name: "reconpipe:city", Value: host.Geo.City})
+				}
+			}
+
+			refs := make(map[string]bool)
+			if port.Version != "" {
+				product, version := splitProductVersion(port.Version)
+				refs[addComponent(product, version, "nmap")] = true
+			}
+
+			var h2, h3, grpc, ws bool
+			var probed []int // HTTP probes reach TCP ports only
+			if protocol == "tcp" {
+				probed = probesByEndpoint[fmt.Sprintf("%s:%d", host.IP, port.Number)]
+			}
+			for _, i := range probed {
+				probe := snap.Probes[i]
+				svc.Endpoints = appendUnique(svc.Endpoints, probe.URL)
+				for _, tech := range probe.Technologies {
+					name, version, _ := strings.Cut(tech, ":")
+					refs[addComponent(name, version, "httpx")] = true
+				}
+				h2, h3 = h2 || probe.HTTP2, h3 || probe.HTTP3
+				grpc, ws = grpc || probe.GRPC, ws || probe.WebSocket != ""
+			}
+			if h2 {
+				svc.Properties = append(svc.Properties, CycloneDXProperty{Name: "reconpipe:protocol", Value: "h2"})
+			}
+			if h3 {
+				svc.Properties = append(svc.Properties, CycloneDXProperty{Name: "reconpipe:protocol", Value: "h3"})
+			}
+			if grpc {
+				svc.Properties = append(svc.Properties, CycloneDXProperty{Name: "reconpipe:protocol", Value: "grpc"})
+			}
+			if ws {
+				svc.Properties = append(svc.Properties, CycloneDXProperty{Name: "reconpipe:protocol", Value: "websocket"})
+			}
+
+			if len(refs) > 0 {
+				deps[svc.BOMRef] = refs
+			}
+			bom.Services = append(bom.Services, svc)
+		}
+	}
+
+	for _, c := range components {
+		bom.Components = append(bom.Components, *c)
+	}
+	sort.Slice(bom.Components, func(i, j int) bool {
+		return bom.Components[i].BOMRef < bom.Components[j].BOMRef
+	})
+
+	for _, svc := range bom.Services {
+		refs, ok := deps[svc.BOMRef]
+		if !ok {
+			continue
+		}
+		dep := CycloneDXDependency{Ref: svc.BOMRef}
+		for ref := range refs {
+			dep.DependsOn = append(dep.DependsOn, ref)
+		}
+		sort.Strings(dep.DependsOn)
+		bom.Dependencies = append(bom.Dependencies, dep)
+	}
+
+	return bom
+}
+
+// splitProductVersion splits an nmap service string such as
+// "OpenSSH 8.9p1 Ubuntu 3ubuntu0.6" into product ("OpenSSH") and version
+// ("8.9p1 Ubuntu 3ubuntu0.6") at the first token starting with a digit.
+// Strings without such a token are returned whole as the product.
+func splitProductVersion(s string) (product, version string) {
+	fields := strings.Fields(s)
+	for i, f := range fields {
+		if i > 0 && f[0] >= '0' && f[0] <= '9' {
+			return strings.Join(fields[:i], " "), strings.Join(fields[i:], " ")
+		}
+	}
+	return strings.TrimSpace(s), ""
+}
+
+// appendUnique appends s to list unless it is already present.
+func appendUnique(list []string, s string) []string {
+	for _, existing := range list {
+		if existing == s {
+			return list
+		}
+	}
+	return append(list, s)
+}
@@ -0,0 +1,239 @@
+package export
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/hakim/reconpipe/internal/diff"
+	"github.com/hakim/reconpipe/pkg/models"
+)
+
+// DefaultIndexTemplate names indices when an output sink sets no index.
+const DefaultIndexTemplate = "reconpipe-{kind}-{target}-{date}"
+
+// bulkBatchSize is the number of documents sent per _bulk request.
+const bulkBatchSize = 500
+
+// ElasticsearchSink indexes a scan's subdomains, HTTP probes and
+// vulnerabilities through the _bulk API of Elasticsearch or OpenSearch.
+// Document IDs are derived from the scan ID and each record's identity, so
+// publishing the same scan again overwrites its documents rather than
+// duplicating them.
+type ElasticsearchSink struct {
+	Kind  string // "elasticsearch" or "opensearch"; only used in Name
+	URL   string
+	Index string // index template, see IndexName
+
+	// Credentials are expanded with os.ExpandEnv. APIKey takes precedence.
+	Username string
+	Password string
+	APIKey   string
+
+	Client *http.Client
+}
+
+// Name implements OutputSink.
+func (s *ElasticsearchSink) Name() string { return s.Kind + ":" + s.URL }
+
+// IndexName renders the index template for one kind of record
+// ("subdomains", "probes" or "vulns"). {date} is the scan's start date as
+// YYYY.MM.DD. Index names must be lowercase, so the result is lowercased.
+func (s *ElasticsearchSink) IndexName(scan *models.ScanMeta, kind string) string {
+	tmpl := s.Index
+	if tmpl == "" {
+		tmpl = DefaultIndexTemplate
+	}
+	r := strings.NewReplacer(
+		"{target}", scan.Target,
+		"{date}", scan.StartedAt.Format("2006.01.02"),
+		"{kind}", kind,
+		"{scan_id}", scan.ID,
+	)
+	return strings.ToLower(r.Replace(tmpl))
+}
+
+// esDocument is one document queued for the _bulk API.
+type esDocument struct {
+	Index string
+	ID    string
+	Body  map[string]any
+}
+
+// Publish implements OutputSink.
+func (s *ElasticsearchSink) Publish(ctx context.Context, scan *models.ScanMeta, snap *diff.ScanSnapshot) error {
+	var docs []esDocument
+	add := func(kind, key string, record any) error {
+		body, err := esBody(scan, kind, record)
+		if err != nil {
+			return err
+		}
+		docs = append(docs, esDocument{
+			Index: s.IndexName(scan, kind),
+			ID:    esDocumentID(scan.ID, kind, key),
+			Body:  body,
+		})
+		return nil
+	}
+
+	for _, sub := range snap.Subdomains {
+		if err := add("subdomains", sub.Name, sub); err != nil {
+			return err
+		}
+	}
+	geo := make(map[string]*models.GeoLocation)
+	for _, h := range snap.Hosts {
+		if h.Geo != nil {
+			geo[h.IP] = h.Geo
+		}
+	}
+	for _, p := range snap.Probes {
+		if err := add("probes", p.URL, esProbe{p, geo[p.IP]}); err != nil {
+			return err
+		}
+	}
+	for _, v := range snap.Vulnerabilities {
+		key := strings.Join([]string{v.TemplateID, v.Host, fmt.Sprint(v.Port), v.MatchedAt}, "|")
+		if err := add("vulns", key, v); err != nil {
+			return err
+		}
+	}
+
+	for start := 0; start < len(docs); start += bulkBatchSize {
+		end := min(start+bulkBatchSize, len(docs))
+		if err := s.bulk(ctx, docs[start:end]); err != nil {
+			return err
+		}
+	}
+
+	fmt.Printf("[+] Indexed %d documents (%d subdomains, %d probes, %d vulns)\n",
+		len(docs), len(snap.Subdomains), len(snap.Probes), len(snap.Vulnerabilities))
+	return nil
+}
+
+// esProbe is a probe document, carrying the GeoIP location of the address
+// it was answered from so services can be filtered by country.
+type esProbe struct {
+	models.HTTPProbe
+	Geo *models.GeoLocation `json:"geo,omitempty"`
+}
+
+// esBody flattens a record's JSON form and adds the fields shared by every
+// document so results can be filtered by scan and target in Kibana.
+func esBody(scan *models.ScanMeta, kind string, record any) (map[string]any, error) {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return nil, fmt.Errorf("marshaling %s record: %w", kind, err)
+	}
+	body := map[string]any{}
+	if err := json.Unmarshal(data, &body); err != nil {
+		return nil, fmt.Errorf("flattening %s record: %w", kind, err)
+	}
+
+	body["@timestamp"] = scan.StartedAt.UTC().Format(time.RFC3339)
+	body["scan_id"] = scan.ID
+	body["scan_target"] = scan.Target
+	body["record_kind"] = kind
+	if scan.Operator != "" {
+		body["scan_operator"] = scan.Operator
+	}
+	return body, nil
+}
+
+// esDocumentID is a stable ID for a record within a scan. Keys such as URLs
+// can exceed the 512 byte ID limit, so they are hashed.
+func esDocumentID(scanID, kind, key string) string {
+	sum := sha1.Sum([]byte(scanID + "\x00" + kind + "\x00" + key))
+	return hex.EncodeToString(sum[:])
+}
+
+// bulk sends one batch to the _bulk endpoint and checks per-document results,
+// since the API answers 200 even when individual documents are rejected.
+func (s *ElasticsearchSink) bulk(ctx context.Context, docs []esDocument) error {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, d := range docs {
+		action := map[string]any{"index": map[string]string{"_index": d.Index, "_id": d.ID}}
+		if err := enc.Encode(action); err != nil {
+			return fmt.Errorf("encoding bulk action: %w", err)
+		}
+		if err := enc.Encode(d.Body); err != nil {
+			return fmt.Errorf("encoding document: %w", err)
+		}
+	}
+
+	endpoint := strings.TrimRight(s.URL, "/") + "/_bulk"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, &buf)
+	if err != nil {
+		return fmt.Errorf("building request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	switch {
+	case s.APIKey != "":
+		req.Header.Set("Authorization", "ApiKey "+os.ExpandEnv(s.APIKey))
+	case s.Username != "":
+		req.SetBasicAuth(os.ExpandEnv(s.Username), os.ExpandEnv(s.Password))
+	}
+
+	client := s.Client
+	if client == nil {
+		client = &http.Client{Timeout: 30 * time.Second}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("posting to %s: %w", endpoint, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("reading bulk response: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("%s returned %s: %s", endpoint, resp.Status, truncate(string(body), 512))
+	}
+
+	var result struct {
+		Errors bool `json:"errors"`
+		Items  []map[string]struct {
+			Status int             `json:"status"`
+			Error  json.RawMessage `json:"error"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return fmt.Errorf("parsing bulk response: %w", err)
+	}
+	if !result.Errors {
+		return nil
+	}
+
+	failed, firstErr := 0, ""
+	for _, item := range result.Items {
+		for _, r := range item {
+			if len(r.Error) == 0 {
+				continue
+			}
+			failed++
+			if firstErr == "" {
+				firstErr = string(r.Error)
+			}
+		}
+	}
+	return fmt.Errorf("%d of %d documents rejected, first error: %s", failed, len(docs), truncate(firstErr, 512))
+}
+
+// truncate shortens s to at most n bytes for error messages.
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "..."
+}
@@ -0,0 +1,93 @@
+-- Scans, assets and findings mirrored from each analyst's local store.
+-- Every child row belongs to one scan; republishing a scan replaces its rows.
+
+CREATE TABLE scans (
+    id           TEXT PRIMARY KEY,
+    target       TEXT        NOT NULL,
+    started_at   TIMESTAMPTZ NOT NULL,
+    completed_at TIMESTAMPTZ,
+    status       TEXT        NOT NULL,
+    scan_dir     TEXT        NOT NULL,
+    stages_run   TEXT[]      NOT NULL DEFAULT '{}',
+    published_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    published_by TEXT        NOT NULL DEFAULT ''
+);
+CREATE INDEX scans_target_started_idx ON scans (target, started_at DESC);
+
+CREATE TABLE subdomains (
+    scan_id      TEXT    NOT NULL REFERENCES scans (id) ON DELETE CASCADE,
+    name         TEXT    NOT NULL,
+    domain       TEXT    NOT NULL,
+    source       TEXT    NOT NULL DEFAULT '',
+    resolved     BOOLEAN NOT NULL DEFAULT false,
+    ips          TEXT[]  NOT NULL DEFAULT '{}',
+    dns_records  JSONB,
+    is_cdn       BOOLEAN NOT NULL DEFAULT false,
+    cdn_provider TEXT    NOT NULL DEFAULT '',
+    is_dangling  BOOLEAN NOT NULL DEFAULT false,
+    PRIMARY KEY (scan_id, name)
+);
+CREATE INDEX subdomains_name_idx ON subdomains (name);
+
+CREATE TABLE hosts (
+    scan_id      TEXT    NOT NULL REFERENCES scans (id) ON DELETE CASCADE,
+    ip           TEXT    NOT NULL,
+    subdomains   TEXT[]  NOT NULL DEFAULT '{}',
+    is_cdn       BOOLEAN NOT NULL DEFAULT false,
+    cdn_provider TEXT    NOT NULL DEFAULT '',
+    PRIMARY KEY (scan_id, ip)
+);
+
+CREATE TABLE ports (
+    scan_id  TEXT    NOT NULL,
+    ip       TEXT    NOT NULL,
+    port     INTEGER NOT NULL,
+    protocol TEXT    NOT NULL,
+    service  TEXT    NOT NULL DEFAULT '',
+    version  TEXT    NOT NULL DEFAULT '',
+    state    TEXT    NOT NULL DEFAULT '',
+    PRIMARY KEY (scan_id, ip, port, protocol),
+    FOREIGN KEY (scan_id, ip) REFERENCES hosts (scan_id, ip) ON DELETE CASCADE
+);
+CREATE INDEX ports_port_idx ON ports (port);
+
+CREATE TABLE http_probes (
+    scan_id        TEXT    NOT NULL REFERENCES scans (id) ON DELETE CASCADE,
+    url            TEXT    NOT NULL,
+    status_code    INTEGER NOT NULL,
+    title          TEXT    NOT NULL DEFAULT '',
+    content_length BIGINT  NOT NULL DEFAULT 0,
+    technologies   TEXT[]  NOT NULL DEFAULT '{}',
+    host           TEXT    NOT NULL DEFAULT '',
+    ip             TEXT    NOT NULL DEFAULT '',
+    port           INTEGER NOT NULL DEFAULT 0,
+    webserver      TEXT    NOT NULL DEFAULT '',
+    is_cdn         BOOLEAN NOT NULL DEFAULT false,
+    cdn_provider   TEXT    NOT NULL DEFAULT '',
+    tls            JSONB,
+    location       TEXT    NOT NULL DEFAULT '',
+    redirects_to   TEXT    NOT NULL DEFAULT '',
+    PRIMARY KEY (scan_id, url)
+);
+
+CREATE TABLE findings (
+    id          BIGSERIAL PRIMARY KEY,
+    scan_id     TEXT    NOT NULL REFERENCES scans (id) ON DELETE CASCADE,
+    template_id TEXT    NOT NULL,
+    name        TEXT    NOT NULL DEFAULT '',
+    severity    TEXT    NOT NULL,
+    host        TEXT    NOT NULL DEFAULT '',
+    port        INTEGER NOT NULL DEFAULT 0,
+    url         TEXT    NOT NULL DEFAULT '',
+    description TEXT    NOT NULL DEFAULT '',
+    matched_at  TEXT    NOT NULL DEFAULT ''
+);
+CREATE INDEX findings_scan_severity_idx ON findings (scan_id, severity);
+CREATE INDEX findings_template_idx ON findings (template_id);
+
+-- Latest scan per target, for dashboards that only care about current state.
+CREATE VIEW latest_scans AS
+SELECT DISTINCT ON (target) *
+FROM scans
+WHERE status = 'complete'
+ORDER BY target, started_at DESC;
@@ -0,0 +1,12 @@
+-- Who launched each scan, from ScanMeta.Operator. published_by remains the
+-- account that copied the scan into this database.
+ALTER TABLE scans ADD COLUMN IF NOT EXISTS operator TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS scans_operator_idx ON scans (operator);
+
+-- A view's * is expanded when it is created; redefine it to pick up operator.
+CREATE OR REPLACE VIEW latest_scans AS
+SELECT DISTINCT ON (target) *
+FROM scans
+WHERE status = 'complete'
+ORDER BY target, started_at DESC;
@@ -0,0 +1,7 @@
+-- Where each host is located, from portscan.geoip_db. Empty when the scan
+-- ran without a GeoIP database or the address could not be placed.
+ALTER TABLE hosts ADD COLUMN IF NOT EXISTS country_code TEXT NOT NULL DEFAULT '';
+ALTER TABLE hosts ADD COLUMN IF NOT EXISTS country      TEXT NOT NULL DEFAULT '';
+ALTER TABLE hosts ADD COLUMN IF NOT EXISTS city         TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS hosts_country_code_idx ON hosts (country_code);
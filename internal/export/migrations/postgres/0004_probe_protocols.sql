@@ -0,0 +1,6 @@
+-- Protocols each HTTP service speaks beyond HTTP/1.1, from the probe stage's
+-- protocol check. http3 is only true once the advertised QUIC port answered.
+ALTER TABLE http_probes ADD COLUMN IF NOT EXISTS http2         BOOLEAN NOT NULL DEFAULT false;
+ALTER TABLE http_probes ADD COLUMN IF NOT EXISTS http3         BOOLEAN NOT NULL DEFAULT false;
+ALTER TABLE http_probes ADD COLUMN IF NOT EXISTS alt_svc       TEXT    NOT NULL DEFAULT '';
+ALTER TABLE http_probes ADD COLUMN IF NOT EXISTS quic_versions TEXT[]  NOT NULL DEFAULT '{}';
@@ -0,0 +1,4 @@
+-- API endpoints found by the protocol check: services that answered a gRPC
+-- call, and the URL that accepted a WebSocket upgrade ('' when none did).
+ALTER TABLE http_probes ADD COLUMN IF NOT EXISTS grpc      BOOLEAN NOT NULL DEFAULT false;
+ALTER TABLE http_probes ADD COLUMN IF NOT EXISTS websocket TEXT    NOT NULL DEFAULT '';
@@ -0,0 +1,3 @@
+-- SHA-256 of each probe's normalized response body. Probes on different
+-- hosts sharing a hash serve the same application ('' when not hashed).
+ALTER TABLE http_probes ADD COLUMN IF NOT EXISTS content_hash TEXT NOT NULL DEFAULT '';
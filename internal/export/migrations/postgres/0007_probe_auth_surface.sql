@@ -0,0 +1,4 @@
+-- Authentication surface classification of each probe: login-form, sso,
+-- vpn-portal or http-auth ('' for none), and what gave it away.
+ALTER TABLE http_probes ADD COLUMN IF NOT EXISTS auth_surface  TEXT NOT NULL DEFAULT '';
+ALTER TABLE http_probes ADD COLUMN IF NOT EXISTS auth_evidence TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,96 @@
+package export
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/hakim/reconpipe/internal/config"
+	"github.com/hakim/reconpipe/internal/diff"
+	"github.com/hakim/reconpipe/pkg/models"
+)
+
+// OutputSink stores a scan's structured results outside the scan directory,
+// e.g. in a search cluster or a shared database.
+type OutputSink interface {
+	Name() string
+	Publish(ctx context.Context, scan *models.ScanMeta, snap *diff.ScanSnapshot) error
+}
+
+// OutputSinksFromConfig builds sinks from the output_sinks config block.
+func OutputSinksFromConfig(cfgs []config.OutputSinkConfig) ([]OutputSink, error) {
+	var out []OutputSink
+	for i, c := range cfgs {
+		timeout := 30 * time.Second
+		if c.Timeout != "" {
+			d, err := time.ParseDuration(c.Timeout)
+			if err != nil {
+				return nil, fmt.Errorf("output_sinks[%d]: invalid timeout %q: %w", i, c.Timeout, err)
+			}
+			timeout = d
+		}
+
+		switch c.Type {
+		case "elasticsearch", "opensearch":
+			client := &http.Client{Timeout: timeout}
+			if c.InsecureSkipVerify {
+				client.Transport = &http.Transport{
+					TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+				}
+			}
+			out = append(out, &ElasticsearchSink{
+				Kind:     c.Type,
+				URL:      c.URL,
+				Index:    c.Index,
+				Username: c.Username,
+				Password: c.Password,
+				APIKey:   c.APIKey,
+				Client:   client,
+			})
+		case "postgres":
+			sink := &PostgresSink{DSN: c.DSN}
+			if c.Timeout != "" {
+				sink.Timeout = timeout
+			}
+			out = append(out, sink)
+		case "confluence":
+			out = append(out, &ConfluenceSink{
+				URL:      c.URL,
+				Space:    c.Space,
+				Parent:   c.ParentPage,
+				Username: c.Username,
+				Password: c.Password,
+				APIKey:   c.APIKey,
+				Client:   &http.Client{Timeout: timeout},
+			})
+		default:
+			return nil, fmt.Errorf("output_sinks[%d]: unknown sink type %q", i, c.Type)
+		}
+	}
+	return out, nil
+}
+
+// PublishAll loads the scan's snapshot and sends it to every sink, trying
+// all of them even if one fails. The returned error joins the failures.
+func PublishAll(ctx context.Context, sinks []OutputSink, scan *models.ScanMeta) error {
+	if len(sinks) == 0 {
+		return nil
+	}
+
+	snap, err := diff.LoadSnapshot(scan.ScanDir)
+	if err != nil {
+		return fmt.Errorf("loading results: %w", err)
+	}
+
+	var errs []error
+	for _, s := range sinks {
+		fmt.Printf("[*] Publishing results to %s\n", s.Name())
+		if err := s.Publish(ctx, scan, snap); err != nil {
+			errs = append(errs, fmt.Errorf("output sink %s: %w", s.Name(), err))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,369 @@
+package export
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hakim/reconpipe/internal/diff"
+	"github.com/hakim/reconpipe/internal/notes"
+	"github.com/hakim/reconpipe/pkg/models"
+)
+
+// stixSCONamespace is the UUIDv5 namespace STIX 2.1 mandates for
+// deterministic cyber-observable IDs, so the same domain or IP gets the same
+// ID across scans and tools.
+var stixSCONamespace = uuid.MustParse("00abedb4-aa42-466c-9c01-fed23315a9b7")
+
+// STIXBundle is a STIX 2.1 bundle. Objects are kept as generic maps since
+// each STIX type carries a different property set.
+type STIXBundle struct {
+	Type    string           `json:"type"`
+	ID      string           `json:"id"`
+	Objects []map[string]any `json:"objects"`
+}
+
+// BuildSTIX turns a scan snapshot into a STIX 2.1 bundle:
+//   - infrastructure: one object for the target, consisting of everything below
+//   - domain-name / ipv4-addr: subdomains and the IPs they resolve to
+//   - x509-certificate: leaf certificates seen by httpx, related to their hosts
+//   - vulnerability: one per nuclei template, with a "has" relationship from
+//     each affected domain-name or ipv4-addr
+//   - note: one per analyst note, referring to the vulnerability and host of
+//     a finding note, or to the domain-name or ipv4-addr of an asset note
+func BuildSTIX(snap *diff.ScanSnapshot, target string, scanNotes []notes.AttachedNote) *STIXBundle {
+	b := &stixBuilder{
+		now:     time.Now().UTC().Format("2006-01-02T15:04:05.000Z"),
+		objects: make(map[string]map[string]any),
+		domains: make(map[string]string),
+		ips:     make(map[string]string),
+
+		locations: make(map[string]string),
+	}
+
+	infra := b.sdo("infrastructure", map[string]any{
+		"name":                 target,
+		"description":          "External attack surface of " + target + " observed by reconpipe",
+		"infrastructure_types": []string{"hosting-target-lists"},
+	})
+
+	// Domains and the addresses they resolve to
+	for _, sub := range snap.Subdomains {
+		domainID := b.domain(sub.Name)
+		var refs []string
+		for _, ip := range sub.IPs {
+			if ipID := b.ip(ip); ipID != "" {
+				refs = append(refs, ipID)
+			}
+		}
+		if len(refs) > 0 {
+			b.objects[domainID]["resolves_to_refs"] = refs
+		}
+	}
+	for _, host := range snap.Hosts {
+		ipID := b.ip(host.IP)
+		if ipID != "" && host.Geo != nil {
+			b.relationship(ipID, "located-at", b.location(host.Geo), "GeoIP")
+		}
+	}
+
+	// Certificates
+	for _, probe := range snap.Probes {
+		if probe.TLS == nil || probe.TLS.SHA256 == "" {
+			continue
+		}
+		certID := b.cert(probe.TLS)
+		hostID := b.hostRef(probe.URL)
+		if hostID == "" {
+			continue
+		}
+		b.relationship(hostID, "related-to", certID,
+			fmt.Sprintf("Certificate presented on port %d", probe.Port))
+	}
+
+	// Vulnerabilities, one object per template
+	vulnIDs := make(map[string]string)
+	findingRefs := make(map[string][]string) // finding key → vulnerability and host IDs
+	for _, v := range snap.Vulnerabilities {
+		vulnID, ok := vulnIDs[v.TemplateID]
+		if !ok {
+			vulnID = b.vulnerability(v)
+			vulnIDs[v.TemplateID] = vulnID
+		}
+		key := strings.ToLower(notes.FindingKey(v))
+		findingRefs[key] = []string{vulnID}
+		if hostID := b.hostRef(v.Host); hostID != "" {
+			b.relationship(hostID, "has", vulnID, v.MatchedAt)
+			findingRefs[key] = append(findingRefs[key], hostID)
+		}
+	}
+
+	// Analyst notes
+	for _, n := range scanNotes {
+		var refs []string
+		switch n.Subject {
+		case models.NoteFinding:
+			refs = findingRefs[strings.ToLower(n.Key)]
+			if refs == nil { // a dangling subdomain
+				refs = []string{b.hostRef(n.Key)}
+			}
+		case models.NoteAsset:
+			refs = []string{b.hostRef(n.Key)}
+		}
+		if len(refs) == 0 || refs[0] == "" {
+			continue
+		}
+		b.note(n.Note, refs)
+	}
+
+	// Everything observed belongs to the target's infrastructure
+	var observed []string
+	for id, obj := range b.objects {
+		switch obj["type"] {
+		case "domain-name", "ipv4-addr", "x509-certificate":
+			observed = append(observed, id)
+		}
+	}
+	for _, id := range observed {
+		b.relationship(infra, "consists-of", id, "")
+	}
+
+	bundle := &STIXBundle{
+		Type:    "bundle",
+		ID:      "bundle--" + uuid.New().String(),
+		Objects: make([]map[string]any, 0, len(b.objects)),
+	}
+	for _, obj := range b.objects {
+		bundle.Objects = append(bundle.Objects, obj)
+	}
+	sort.Slice(bundle.Objects, func(i, j int) bool {
+		ti, tj := stixTypeOrder(bundle.Objects[i]), stixTypeOrder(bundle.Objects[j])
+		if ti != tj {
+			return ti < tj
+		}
+		return bundle.Objects[i]["id"].(string) < bundle.Objects[j]["id"].(string)
+	})
+
+	return bundle
+}
+
+// stixBuilder accumulates STIX objects keyed by ID, deduplicating
+// observables by value.
+type stixBuilder struct {
+	now     string
+	objects map[string]map[string]any
+	domains map[string]string // name → id
+	ips     map[string]string // address → id
+
+	locations map[string]string // country code + city → id
+}
+
+// sdo adds a STIX domain object with a random ID and returns the ID.
+func (b *stixBuilder) sdo(typ string, props map[string]any) string {
+	id := typ + "--" + uuid.New().String()
+	obj := map[string]any{
+		"type":         typ,
+		"spec_version": "2.1",
+		"id":           id,
+		"created":      b.now,
+		"modified":     b.now,
+	}
+	for k, v := range props {
+		obj[k] = v
+	}
+	b.objects[id] = obj
+	return id
+}
+
+// sco adds a cyber-observable with a deterministic UUIDv5 ID derived from
+// its ID-contributing properties, and returns the ID.
+func (b *stixBuilder) sco(typ string, idProps map[string]any, props map[string]any) string {
+	canonical, _ := json.Marshal(idProps) // map keys marshal sorted
+	id := typ + "--" + uuid.NewSHA1(stixSCONamespace, canonical).String()
+	if _, ok := b.objects[id]; ok {
+		return id
+	}
+	obj := map[string]any{
+		"type":         typ,
+		"spec_version": "2.1",
+		"id":           id,
+	}
+	for k, v := range idProps {
+		obj[k] = v
+	}
+	for k, v := range props {
+		obj[k] = v
+	}
+	b.objects[id] = obj
+	return id
+}
+
+func (b *stixBuilder) domain(name string) string {
+	name = strings.ToLower(name)
+	if id, ok := b.domains[name]; ok {
+		return id
+	}
+	id := b.sco("domain-name", map[string]any{"value": name}, nil)
+	b.domains[name] = id
+	return id
+}
+
+// ip adds an ipv4-addr observable. IPv6 and unparsable addresses are skipped
+// and return "".
+func (b *stixBuilder) ip(addr string) string {
+	if id, ok := b.ips[addr]; ok {
+		return id
+	}
+	parsed := net.ParseIP(addr)
+	if parsed == nil || parsed.To4() == nil {
+		return ""
+	}
+	id := b.sco("ipv4-addr", map[string]any{"value": addr}, nil)
+	b.ips[addr] = id
+	return id
+}
+
+// location adds a location SDO for a GeoIP result, one per country and city.
+func (b *stixBuilder) location(g *models.GeoLocation) string {
+	key := g.CountryCode + "|" + g.City
+	if id, ok := b.locations[key]; ok {
+		return id
+	}
+	props := map[string]any{"name": g.String(), "country": g.CountryCode}
+	if g.City != "" {
+		props["city"] = g.City
+	}
+	id := b.sdo("location", props)
+	b.locations[key] = id
+	return id
+}
+
+func (b *stixBuilder) cert(c *models.TLSCert) string {
+	props := map[string]any{}
+	if c.Serial != "" {
+		props["serial_number"] = c.Serial
+	}
+	if c.SubjectDN != "" {
+		props["subject"] = c.SubjectDN
+	}
+	if c.IssuerDN != "" {
+		props["issuer"] = c.IssuerDN
+	}
+	if c.NotBefore != "" {
+		props["validity_not_before"] = c.NotBefore
+	}
+	if c.NotAfter != "" {
+		props["validity_not_after"] = c.NotAfter
+	}
+	if len(c.SANs) > 0 {
+		sans := make([]string, len(c.SANs))
+		for i, san := range c.SANs {
+			sans[i] = "DNS:" + san
+		}
+		props["x509_v3_extensions"] = map[string]any{
+			"subject_alternative_name": strings.Join(sans, ", "),
+		}
+	}
+	return b.sco("x509-certificate",
+		map[string]any{"hashes": map[string]string{"SHA-256": c.SHA256}}, props)
+}
+
+func (b *stixBuilder) vulnerability(v models.Vulnerability) string {
+	refs := []map[string]string{
+		{"source_name": "nuclei", "external_id": v.TemplateID},
+	}
+	if strings.HasPrefix(strings.ToUpper(v.TemplateID), "CVE-") {
+		refs = append(refs, map[string]string{
+			"source_name": "cve",
+			"external_id": strings.ToUpper(v.TemplateID),
+		})
+	}
+
+	props := map[string]any{
+		"name":                v.Name,
+		"external_references": refs,
+		"labels":              []string{"severity:" + string(v.Severity)},
+	}
+	if v.Description != "" {
+		props["description"] = v.Description
+	}
+	return b.sdo("vulnerability", props)
+}
+
+// note adds a note SDO for an analyst note on the objects in refs.
+func (b *stixBuilder) note(n models.Note, refs []string) {
+	props := map[string]any{
+		"abstract":    n.Subject + " " + n.Key,
+		"content":     n.Text,
+		"object_refs": refs,
+	}
+	if n.Author != "" {
+		props["authors"] = []string{n.Author}
+	}
+	id := b.sdo("note", props)
+	created := n.CreatedAt.UTC().Format("2006-01-02T15:04:05.000Z")
+	b.objects[id]["created"], b.objects[id]["modified"] = created, created
+}
+
+// relationship adds an SRO between two objects.
+func (b *stixBuilder) relationship(source, relType, target, description string) {
+	props := map[string]any{
+		"relationship_type": relType,
+		"source_ref":        source,
+		"target_ref":        target,
+	}
+	if description != "" {
+		props["description"] = description
+	}
+	b.sdo("relationship", props)
+}
+
+// hostRef resolves a nuclei host or probe URL ("https://api.example.com:8443",
+// "203.0.113.30", "mail.example.com:25") to a domain-name or ipv4-addr ID.
+func (b *stixBuilder) hostRef(raw string) string {
+	host := raw
+	if strings.Contains(raw, "://") {
+		u, err := url.Parse(raw)
+		if err != nil {
+			return ""
+		}
+		host = u.Hostname()
+	} else if h, _, err := net.SplitHostPort(raw); err == nil {
+		host = h
+	}
+	if host == "" {
+		return ""
+	}
+	if net.ParseIP(host) != nil {
+		return b.ip(host)
+	}
+	return b.domain(host)
+}
+
+// stixTypeOrder groups bundle objects so readers see the target first,
+// then observables, vulnerabilities, notes, and finally relationships.
+func stixTypeOrder(obj map[string]any) int {
+	switch obj["type"] {
+	case "infrastructure":
+		return 0
+	case "domain-name":
+		return 1
+	case "ipv4-addr":
+		return 2
+	case "x509-certificate":
+		return 3
+	case "vulnerability":
+		return 4
+	case "location":
+		return 5
+	case "note":
+		return 6
+	default:
+		return 7
+	}
+}
@@ -0,0 +1,454 @@
+// Package filter parses and evaluates the expressions given to --filter,
+// which select the records a stage builds its targets from, e.g.
+//
+//	port in (80, 443, 8443) && !is_cdn
+//	status_code == 200 && tech contains "wordpress"
+//	hostname =~ "^(api|admin)\\." || service == "http-proxy"
+//
+// An expression compares fields with literals: == and != on any field,
+// < <= > >= on numbers, in (...) against a list of literals, contains for a
+// case-insensitive substring of a string (or of any element of a list), and
+// =~ for an RE2 regular expression. A bool field stands on its own. Terms
+// combine with &&, || and !, grouped with parentheses. Fields are checked
+// when the expression is parsed, so a typo fails before any scanning.
+package filter
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Kind is the type of a field.
+type Kind int
+
+const (
+	String Kind = iota
+	Number
+	Bool
+	List // of strings
+)
+
+func (k Kind) String() string {
+	switch k {
+	case Number:
+		return "number"
+	case Bool:
+		return "bool"
+	case List:
+		return "list"
+	default:
+		return "string"
+	}
+}
+
+// Fields names the fields an expression may use and their kinds.
+type Fields map[string]Kind
+
+// Record holds the field values of one record: string, int64, bool or
+// []string according to the field's Kind. A field missing from the record
+// makes every comparison on it false.
+type Record map[string]any
+
+// Expr is a parsed filter expression. A nil *Expr matches every record.
+type Expr struct {
+	src  string
+	root node
+}
+
+// Parse parses src, checking every field against fields.
+func Parse(src string, fields Fields) (*Expr, error) {
+	toks, err := lex(src)
+	if err != nil {
+		return nil, fmt.Errorf("filter %q: %w", src, err)
+	}
+	p := &parser{toks: toks, fields: fields}
+	root, err := p.parseOr()
+	if err == nil && p.peek().kind != tokEOF {
+		err = p.errorf("unexpected %s", p.peek())
+	}
+	if err != nil {
+		return nil, fmt.Errorf("filter %q: %w", src, err)
+	}
+	return &Expr{src: src, root: root}, nil
+}
+
+// String returns the expression as given.
+func (e *Expr) String() string {
+	if e == nil {
+		return ""
+	}
+	return e.src
+}
+
+// Match reports whether r satisfies the expression.
+func (e *Expr) Match(r Record) bool {
+	return e == nil || e.root.eval(r)
+}
+
+// Names returns the field names of fields, sorted, for error messages and
+// help text.
+func (f Fields) Names() []string {
+	names := make([]string, 0, len(f))
+	for name := range f {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ---------------------------------------------------------------------------
+// Evaluation
+// ---------------------------------------------------------------------------
+
+type node interface {
+	eval(r Record) bool
+}
+
+type andNode struct{ left, right node }
+type orNode struct{ left, right node }
+type notNode struct{ operand node }
+
+func (n andNode) eval(r Record) bool { return n.left.eval(r) && n.right.eval(r) }
+func (n orNode) eval(r Record) bool  { return n.left.eval(r) || n.right.eval(r) }
+func (n notNode) eval(r Record) bool { return !n.operand.eval(r) }
+
+// boolField is a bool field standing on its own.
+type boolField struct{ field string }
+
+func (n boolField) eval(r Record) bool {
+	v, _ := r[n.field].(bool)
+	return v
+}
+
+// compare is field <op> values. values holds one literal except for "in".
+type compare struct {
+	field  string
+	op     string
+	values []any
+	re     *regexp.Regexp
+}
+
+func (n compare) eval(r Record) bool {
+	v, ok := r[n.field]
+	if !ok {
+		return false
+	}
+	switch n.op {
+	case "=~":
+		return anyString(v, n.re.MatchString)
+	case "contains":
+		sub := strings.ToLower(n.values[0].(string))
+		return anyString(v, func(s string) bool { return strings.Contains(strings.ToLower(s), sub) })
+	case "in":
+		if list, ok := v.([]string); ok {
+			return slices.ContainsFunc(list, func(s string) bool { return slices.Contains(n.values, any(s)) })
+		}
+		return slices.Contains(n.values, v)
+	case "==":
+		return v == n.values[0]
+	case "!=":
+		return v != n.values[0]
+	}
+
+	a, _ := v.(int64)
+	b := n.values[0].(int64)
+	switch n.op {
+	case "<":
+		return a < b
+	case "<=":
+		return a <= b
+	case ">":
+		return a > b
+	default:
+		return a >= b
+	}
+}
+
+// anyString applies match to v, or to each element when v is a list.
+func anyString(v any, match func(string) bool) bool {
+	switch v := v.(type) {
+	case string:
+		return match(v)
+	case []string:
+		return slices.ContainsFunc(v, match)
+	}
+	return false
+}
+
+// ---------------------------------------------------------------------------
+// Parsing
+// ---------------------------------------------------------------------------
+
+type parser struct {
+	toks   []token
+	pos    int
+	fields Fields
+}
+
+func (p *parser) peek() token { return p.toks[p.pos] }
+
+func (p *parser) next() token {
+	t := p.toks[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) errorf(format string, args ...any) error {
+	return fmt.Errorf("column %d: %s", p.peek().col, fmt.Sprintf(format, args...))
+}
+
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	for err == nil && p.peek().is(tokOp, "||") {
+		p.next()
+		var right node
+		if right, err = p.parseAnd(); err == nil {
+			left = orNode{left, right}
+		}
+	}
+	return left, err
+}
+
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseUnary()
+	for err == nil && p.peek().is(tokOp, "&&") {
+		p.next()
+		var right node
+		if right, err = p.parseUnary(); err == nil {
+			left = andNode{left, right}
+		}
+	}
+	return left, err
+}
+
+func (p *parser) parseUnary() (node, error) {
+	if p.peek().is(tokOp, "!") {
+		p.next()
+		operand, err := p.parseUnary()
+		return notNode{operand}, err
+	}
+	if p.peek().is(tokOp, "(") {
+		p.next()
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if !p.peek().is(tokOp, ")") {
+			return nil, p.errorf("expected ) but found %s", p.peek())
+		}
+		p.next()
+		return inner, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *parser) parseComparison() (node, error) {
+	t := p.peek()
+	if t.kind != tokIdent {
+		return nil, p.errorf("expected a field name but found %s", t)
+	}
+	kind, ok := p.fields[t.text]
+	if !ok {
+		return nil, p.errorf("unknown field %q (fields: %s)", t.text, strings.Join(p.fields.Names(), ", "))
+	}
+	p.next()
+
+	op := p.peek()
+	switch {
+	case op.kind == tokOp && slices.Contains([]string{"==", "!=", "<", "<=", ">", ">=", "=~"}, op.text),
+		op.kind == tokIdent && (op.text == "in" || op.text == "contains"):
+	default:
+		if kind != Bool {
+			return nil, p.errorf("%s field %q needs a comparison", kind, t.text)
+		}
+		return boolField{t.text}, nil
+	}
+	p.next()
+
+	n := compare{field: t.text, op: op.text}
+	switch n.op {
+	case "in":
+		values, err := p.parseList(kind)
+		if err != nil {
+			return nil, err
+		}
+		n.values = values
+	case "contains", "=~":
+		if kind != String && kind != List {
+			return nil, p.errorf("%s needs a string or list field, not %s field %q", n.op, kind, t.text)
+		}
+		lit := p.peek()
+		if lit.kind != tokString {
+			return nil, p.errorf("%s needs a string but found %s", n.op, lit)
+		}
+		p.next()
+		n.values = []any{lit.text}
+		if n.op == "=~" {
+			re, err := regexp.Compile(lit.text)
+			if err != nil {
+				return nil, fmt.Errorf("column %d: %w", lit.col, err)
+			}
+			n.re = re
+		}
+	default:
+		if kind == List {
+			return nil, p.errorf("list field %q takes contains, in or =~, not %s", t.text, n.op)
+		}
+		if n.op != "==" && n.op != "!=" && kind != Number {
+			return nil, p.errorf("%s needs a number field, not %s field %q", n.op, kind, t.text)
+		}
+		v, err := p.parseLiteral(kind)
+		if err != nil {
+			return nil, err
+		}
+		n.values = []any{v}
+	}
+	return n, nil
+}
+
+// parseList parses "(lit, lit, ...)" of literals for a field of kind.
+func (p *parser) parseList(kind Kind) ([]any, error) {
+	if !p.peek().is(tokOp, "(") {
+		return nil, p.errorf("in needs a list such as (80, 443) but found %s", p.peek())
+	}
+	p.next()
+	var values []any
+	for {
+		v, err := p.parseLiteral(kind)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+		if p.peek().is(tokOp, ",") {
+			p.next()
+			continue
+		}
+		if !p.peek().is(tokOp, ")") {
+			return nil, p.errorf("expected , or ) but found %s", p.peek())
+		}
+		p.next()
+		return values, nil
+	}
+}
+
+// parseLiteral parses one literal of the type a field of kind holds; a
+// list holds strings.
+func (p *parser) parseLiteral(kind Kind) (any, error) {
+	t := p.peek()
+	switch {
+	case kind == Number && t.kind == tokNumber:
+		n, err := strconv.ParseInt(t.text, 10, 64)
+		if err != nil {
+			return nil, p.errorf("bad number %s", t.text)
+		}
+		p.next()
+		return n, nil
+	case (kind == String || kind == List) && t.kind == tokString:
+		p.next()
+		return t.text, nil
+	case kind == Bool && (t.is(tokIdent, "true") || t.is(tokIdent, "false")):
+		p.next()
+		return t.text == "true", nil
+	}
+	if kind == List {
+		kind = String
+	}
+	return nil, p.errorf("expected a %s but found %s", kind, t)
+}
+
+// ---------------------------------------------------------------------------
+// Lexing
+// ---------------------------------------------------------------------------
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokIdent
+	tokNumber
+	tokString
+	tokOp
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	col  int
+}
+
+func (t token) is(kind tokenKind, text string) bool { return t.kind == kind && t.text == text }
+
+func (t token) String() string {
+	switch t.kind {
+	case tokEOF:
+		return "end of filter"
+	case tokString:
+		return strconv.Quote(t.text)
+	}
+	return fmt.Sprintf("%q", t.text)
+}
+
+// twoCharOps are tried before the single-character ones.
+var twoCharOps = []string{"&&", "||", "==", "!=", "<=", ">=", "=~"}
+
+func lex(src string) ([]token, error) {
+	var toks []token
+	for i := 0; i < len(src); {
+		c := src[i]
+		col := i + 1
+		switch {
+		case c == ' ' || c == '\t' || c == '\n':
+			i++
+		case c == '"' || c == '\'':
+			var sb strings.Builder
+			j := i + 1
+			for ; j < len(src) && src[j] != c; j++ {
+				if src[j] == '\\' && j+1 < len(src) {
+					j++
+				}
+				sb.WriteByte(src[j])
+			}
+			if j >= len(src) {
+				return nil, fmt.Errorf("column %d: unterminated string", col)
+			}
+			toks = append(toks, token{tokString, sb.String(), col})
+			i = j + 1
+		case c >= '0' && c <= '9':
+			j := i
+			for j < len(src) && src[j] >= '0' && src[j] <= '9' {
+				j++
+			}
+			toks = append(toks, token{tokNumber, src[i:j], col})
+			i = j
+		case c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z':
+			j := i
+			for j < len(src) && (src[j] == '_' || src[j] >= 'a' && src[j] <= 'z' || src[j] >= 'A' && src[j] <= 'Z' || src[j] >= '0' && src[j] <= '9') {
+				j++
+			}
+			toks = append(toks, token{tokIdent, src[i:j], col})
+			i = j
+		default:
+			op := ""
+			for _, two := range twoCharOps {
+				if strings.HasPrefix(src[i:], two) {
+					op = two
+				}
+			}
+			if op == "" && strings.ContainsRune("!<>(),", rune(c)) {
+				op = string(c)
+			}
+			if op == "" {
+				return nil, fmt.Errorf("column %d: unexpected %q", col, c)
+			}
+			toks = append(toks, token{tokOp, op, col})
+			i += len(op)
+		}
+	}
+	return append(toks, token{kind: tokEOF, col: len(src) + 1}), nil
+}
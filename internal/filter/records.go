@@ -0,0 +1,97 @@
+package filter
+
+import (
+	"net/url"
+
+	"github.com/hakim/reconpipe/pkg/models"
+)
+
+// TargetFields are the fields of a port on a host: what the probe stage
+// filters on, and what the vulnscan stage knows of subdomain and IP targets.
+var TargetFields = Fields{
+	"hostname":     String, // the subdomain, or the IP for an IP target
+	"ip":           String,
+	"port":         Number,
+	"service":      String, // as nmap named it
+	"version":      String,
+	"is_cdn":       Bool,
+	"cdn_provider": String,
+}
+
+// ProbeFields are TargetFields plus what httpx found, for filtering the
+// vulnscan stage's URL targets.
+var ProbeFields = withFields(TargetFields, Fields{
+	"url":            String,
+	"status_code":    Number,
+	"title":          String,
+	"webserver":      String,
+	"tech":           List,
+	"content_length": Number,
+	"auth_surface":   String,
+	"http2":          Bool,
+	"grpc":           Bool,
+	"methods":        List,
+	"trace_enabled":  Bool,
+	"soft_404":       Bool,
+})
+
+func withFields(base, extra Fields) Fields {
+	all := make(Fields, len(base)+len(extra))
+	for name, kind := range base {
+		all[name] = kind
+	}
+	for name, kind := range extra {
+		all[name] = kind
+	}
+	return all
+}
+
+// TargetRecord returns the record of port on host, reached as hostname.
+// A zero port (a host with no open ports) leaves the port fields out.
+func TargetRecord(hostname string, host models.Host, port models.Port) Record {
+	r := Record{
+		"hostname":     hostname,
+		"ip":           host.IP,
+		"is_cdn":       host.IsCDN,
+		"cdn_provider": host.CDNProvider,
+	}
+	if port.Number != 0 {
+		r["port"] = int64(port.Number)
+		r["service"] = port.Service
+		r["version"] = port.Version
+	}
+	return r
+}
+
+// ProbeRecord returns the record of probe. port is the nmap result for the
+// probe's IP and port, or a zero Port when there is none.
+func ProbeRecord(probe models.HTTPProbe, port models.Port) Record {
+	hostname := probe.IP
+	if u, err := url.Parse(probe.URL); err == nil && u.Hostname() != "" {
+		hostname = u.Hostname()
+	}
+	r := Record{
+		"hostname":       hostname,
+		"ip":             probe.IP,
+		"port":           int64(probe.Port),
+		"is_cdn":         probe.IsCDN,
+		"cdn_provider":   probe.CDNProvider,
+		"url":            probe.URL,
+		"status_code":    int64(probe.StatusCode),
+		"title":          probe.Title,
+		"webserver":      probe.WebServer,
+		"tech":           probe.Technologies,
+		"content_length": probe.ContentLength,
+		"auth_surface":   probe.AuthSurface,
+		"http2":          probe.HTTP2,
+		"grpc":           probe.GRPC,
+		"methods":        probe.AllowedMethods,
+		"trace_enabled":  probe.TraceEnabled,
+		"soft_404":       probe.Soft404,
+	}
+	if port.Number != 0 {
+		r["service"] = port.Service
+		r["version"] = port.Version
+	}
+	return r
+}
@@ -0,0 +1,74 @@
+// Package geoip looks up where scanned IP addresses are hosted using a
+// MaxMind-format (MMDB) database such as GeoLite2-City or GeoLite2-Country,
+// so assets in unexpected jurisdictions stand out in the reports.
+package geoip
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/hakim/reconpipe/pkg/models"
+	"github.com/oschwald/maxminddb-golang"
+)
+
+// DB is an open GeoIP database.
+type DB struct {
+	reader *maxminddb.Reader
+}
+
+// record is the part of a GeoIP2/GeoLite2 City or Country record we read.
+// Country databases have no city; the city name is then left empty.
+type record struct {
+	Country struct {
+		ISOCode string            `maxminddb:"iso_code"`
+		Names   map[string]string `maxminddb:"names"`
+	} `maxminddb:"country"`
+	City struct {
+		Names map[string]string `maxminddb:"names"`
+	} `maxminddb:"city"`
+}
+
+// Open opens the MMDB file at path.
+func Open(path string) (*DB, error) {
+	r, err := maxminddb.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("opening GeoIP database %s: %w", path, err)
+	}
+	return &DB{reader: r}, nil
+}
+
+// Close releases the database.
+func (db *DB) Close() error {
+	return db.reader.Close()
+}
+
+// Lookup returns the location of ip, or nil when ip is not an address or
+// the database has no country for it.
+func (db *DB) Lookup(ip string) *models.GeoLocation {
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return nil
+	}
+	var rec record
+	if err := db.reader.Lookup(addr, &rec); err != nil || rec.Country.ISOCode == "" {
+		return nil
+	}
+	return &models.GeoLocation{
+		CountryCode: rec.Country.ISOCode,
+		Country:     rec.Country.Names["en"],
+		City:        rec.City.Names["en"],
+	}
+}
+
+// Enrich sets Geo on every host the database can place and returns how
+// many it located.
+func (db *DB) Enrich(hosts []models.Host) int {
+	located := 0
+	for i := range hosts {
+		if loc := db.Lookup(hosts[i].IP); loc != nil {
+			hosts[i].Geo = loc
+			located++
+		}
+	}
+	return located
+}
@@ -0,0 +1,304 @@
+// Package hooks runs user-configured shell commands before and after pipeline
+// stages. Commands are Go templates rendered with the scan's paths and a
+// summary of its results, so small automations (notifications, uploads,
+// ticket creation) don't need a plugin or a fork. Every value a template
+// prints is shell-quoted, so a target or stage error cannot inject commands.
+// cmd.exe has no quoting that makes that safe, so on Windows templates may
+// not print values and hooks read them from the environment instead.
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"text/template"
+	"text/template/parse"
+	"time"
+
+	"github.com/hakim/reconpipe/internal/diff"
+	"github.com/hakim/reconpipe/internal/storage"
+)
+
+// Timeout caps how long a single hook command may run.
+const Timeout = 5 * time.Minute
+
+// Points are the places a hook can attach: the whole scan and each stage.
+var Points = []string{"scan", "discover", "portscan", "probe", "vulnscan", "diff"}
+
+// Name returns the config key of a hook, e.g. Name("post", "vulnscan") is
+// "post_vulnscan".
+func Name(when, point string) string {
+	return when + "_" + point
+}
+
+// ValidName reports whether name is pre_ or post_ followed by a hook point.
+func ValidName(name string) bool {
+	for _, p := range Points {
+		if name == Name("pre", p) || name == Name("post", p) {
+			return true
+		}
+	}
+	return false
+}
+
+// funcs are available in hook commands in addition to the template builtins.
+var funcs = template.FuncMap{
+	// quote wraps a value in single quotes for the shell. Parse applies it
+	// to every action, so writing {{quote .ScanDir}} is allowed but not
+	// needed.
+	"quote": quote,
+	// quoteInDouble and quoteInSingle are what Parse uses instead of quote
+	// for actions inside the command's own double or single quotes: they
+	// close those quotes around the quoted value, so "{{.ScanDir}}/x"
+	// still forms the word /path/x, without quote characters in it.
+	"quoteInDouble": func(v any) string { return `"` + quote(v) + `"` },
+	"quoteInSingle": func(v any) string { return "'" + quote(v) + "'" },
+	"join":          strings.Join,
+}
+
+// quote wraps a value in single quotes for the shell.
+func quote(v any) string {
+	return "'" + strings.ReplaceAll(fmt.Sprint(v), "'", `'\''`) + "'"
+}
+
+// Parse compiles a hook command template, making every action that prints
+// a value print it shell-quoted, whether or not the command already puts
+// it in quotes. On Windows a template that prints a value is an error:
+// hooks there get values through %RECONPIPE_*% variables.
+func Parse(name, command string) (*template.Template, error) {
+	tmpl, err := template.New(name).Funcs(funcs).Option("missingkey=zero").Parse(command)
+	if err != nil {
+		return nil, err
+	}
+	prints := false
+	for _, t := range tmpl.Templates() {
+		var state shellQuote
+		if t.Tree != nil && quoteList(t.Tree.Root, &state) {
+			prints = true
+		}
+	}
+	if prints && runtime.GOOS == "windows" {
+		return nil, fmt.Errorf("hook templates cannot print values on Windows, where cmd.exe cannot quote them; use !RECONPIPE_TARGET! and the other RECONPIPE_* variables instead")
+	}
+	return tmpl, nil
+}
+
+// shellQuote is the quoting in effect at a point of a shell command line.
+type shellQuote int
+
+const (
+	unquoted shellQuote = iota
+	inSingle
+	inDouble
+)
+
+// advance moves q past text as sh reads it.
+func (q *shellQuote) advance(text string) {
+	for i := 0; i < len(text); i++ {
+		switch c := text[i]; {
+		case *q == inSingle:
+			if c == '\'' {
+				*q = unquoted
+			}
+		case c == '\\':
+			i++ // escapes the next byte, unquoted or in double quotes
+		case *q == inDouble:
+			if c == '"' {
+				*q = unquoted
+			}
+		case c == '\'':
+			*q = inSingle
+		case c == '"':
+			*q = inDouble
+		}
+	}
+}
+
+// quoteFunc is the function that quotes a value printed under q.
+func (q shellQuote) quoteFunc() string {
+	switch q {
+	case inSingle:
+		return "quoteInSingle"
+	case inDouble:
+		return "quoteInDouble"
+	}
+	return "quote"
+}
+
+// quoteList ends the pipeline of every action in list, nested ones included,
+// with the quote function for the quoting the surrounding text leaves it
+// in, and reports whether any of them prints a value. Branches are assumed
+// to close the quotes they open.
+func quoteList(list *parse.ListNode, state *shellQuote) bool {
+	if list == nil {
+		return false
+	}
+	prints := false
+	branches := func(lists ...*parse.ListNode) {
+		start := *state
+		for _, l := range lists {
+			*state = start
+			prints = quoteList(l, state) || prints
+		}
+		*state = start
+	}
+	for _, node := range list.Nodes {
+		switch n := node.(type) {
+		case *parse.TextNode:
+			state.advance(string(n.Text))
+		case *parse.ActionNode:
+			if quotePipe(n.Pipe, *state) {
+				prints = true
+			}
+		case *parse.IfNode:
+			branches(n.List, n.ElseList)
+		case *parse.RangeNode:
+			branches(n.List, n.ElseList)
+		case *parse.WithNode:
+			branches(n.List, n.ElseList)
+		}
+	}
+	return prints
+}
+
+// quotePipe ends a pipeline that prints with the quote function for state,
+// replacing an explicit quote, and reports whether it prints. Variable
+// declarations print nothing and are left alone.
+func quotePipe(pipe *parse.PipeNode, state shellQuote) bool {
+	if len(pipe.Decl) > 0 || len(pipe.Cmds) == 0 {
+		return false
+	}
+	fn := state.quoteFunc()
+	last := pipe.Cmds[len(pipe.Cmds)-1]
+	if id, ok := last.Args[0].(*parse.IdentifierNode); ok && id.Ident == "quote" {
+		id.Ident = fn
+		return true
+	}
+	pipe.Cmds = append(pipe.Cmds, &parse.CommandNode{
+		NodeType: parse.NodeCommand,
+		Args:     []parse.Node{parse.NewIdentifier(fn).SetTree(nil).SetPos(pipe.Position())},
+	})
+	return true
+}
+
+// Data is what a hook command template can refer to, e.g. {{.ScanDir}} or
+// {{.Summary.Vulns}}.
+type Data struct {
+	Hook       string // e.g. post_vulnscan
+	Stage      string // stage name, or "scan" for pre_scan/post_scan
+	Target     string
+	ScanID     string
+	ScanDir    string
+	RawDir     string
+	ReportsDir string
+
+	StagesRun []string // stages run so far in this invocation
+	Summary   Summary
+
+	// Set for post hooks only.
+	Status string // stage: complete or failed; scan: the pipeline result status
+	Error  string // stage error, if any
+}
+
+// Summary counts what the scan has found so far, read from its raw output.
+type Summary struct {
+	Subdomains int
+	Dangling   int
+	Hosts      int
+	OpenPorts  int
+	HTTP       int
+	Vulns      int
+	Severity   map[string]int // vulnerability count by severity; {{.Summary.Severity.critical}}
+}
+
+// LoadSummary reads the raw JSON in scanDir. Files not yet written count as
+// zero.
+func LoadSummary(scanDir string) (Summary, error) {
+	snap, err := diff.LoadSnapshot(scanDir)
+	if err != nil {
+		return Summary{}, err
+	}
+
+	s := Summary{
+		Subdomains: len(snap.Subdomains),
+		Hosts:      len(snap.Hosts),
+		HTTP:       len(snap.Probes),
+		Vulns:      len(snap.Vulnerabilities),
+		Severity:   make(map[string]int),
+	}
+	for _, sub := range snap.Subdomains {
+		if sub.IsDangling {
+			s.Dangling++
+		}
+	}
+	for _, h := range snap.Hosts {
+		s.OpenPorts += len(h.Ports)
+	}
+	for _, v := range snap.Vulnerabilities {
+		s.Severity[string(v.Severity)]++
+	}
+	return s, nil
+}
+
+// NewData fills in the paths of a hook's template data.
+func NewData(hook, stage, target, scanID, scanDir string) Data {
+	return Data{
+		Hook:       hook,
+		Stage:      stage,
+		Target:     target,
+		ScanID:     scanID,
+		ScanDir:    scanDir,
+		RawDir:     storage.RawDir(scanDir),
+		ReportsDir: storage.ReportsDir(scanDir),
+	}
+}
+
+// Run renders command with data and executes it through the shell, with the
+// hook's output passed through. Values are shell-quoted as Parse describes.
+// The main fields are also exported as RECONPIPE_* environment variables
+// for scripts that prefer them.
+func Run(ctx context.Context, command string, data Data) error {
+	tmpl, err := Parse(data.Hook, command)
+	if err != nil {
+		return fmt.Errorf("parsing hook %s: %w", data.Hook, err)
+	}
+	var rendered bytes.Buffer
+	if err := tmpl.Execute(&rendered, data); err != nil {
+		return fmt.Errorf("rendering hook %s: %w", data.Hook, err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, Timeout)
+	defer cancel()
+
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		// Delayed expansion: !RECONPIPE_TARGET! is expanded after the
+		// line is parsed, so & | ^ in a value stay literal. %VAR% is not.
+		cmd = exec.CommandContext(ctx, "cmd", "/V:ON", "/C", rendered.String())
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", rendered.String())
+	}
+	cmd.WaitDelay = 5 * time.Second
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.Env = append(os.Environ(),
+		"RECONPIPE_HOOK="+data.Hook,
+		"RECONPIPE_STAGE="+data.Stage,
+		"RECONPIPE_TARGET="+data.Target,
+		"RECONPIPE_SCAN_ID="+data.ScanID,
+		"RECONPIPE_SCAN_DIR="+data.ScanDir,
+		"RECONPIPE_STATUS="+data.Status,
+	)
+
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return fmt.Errorf("hook %s: %w", data.Hook, ctx.Err())
+		}
+		return fmt.Errorf("hook %s: %w", data.Hook, err)
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package httpprobe
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Kinds of authentication surface a probe can be classified as.
+const (
+	AuthVPN       = "vpn-portal" // remote access gateway login
+	AuthSSO       = "sso"        // identity provider, OAuth/OIDC or SAML endpoint
+	AuthLoginForm = "login-form" // application login page
+	AuthHTTP      = "http-auth"  // HTTP authentication challenge (401)
+)
+
+// authRule marks a probe as Kind when any of its patterns matches. Title,
+// Body and Location are matched case-insensitively against the page title,
+// the response body and the redirect target.
+type authRule struct {
+	Kind     string
+	Evidence string
+	Title    *regexp.Regexp
+	Body     *regexp.Regexp
+	Location *regexp.Regexp
+}
+
+// authRules are checked in order; the first match wins, so the specific
+// products come before the generic login signals.
+var authRules = []authRule{
+	{Kind: AuthVPN, Evidence: "GlobalProtect portal",
+		Title: regexp.MustCompile(`(?i)globalprotect`),
+		Body:  regexp.MustCompile(`(?i)/global-protect/(?:login|portal)`)},
+	{Kind: AuthVPN, Evidence: "Fortinet SSL VPN",
+		Title: regexp.MustCompile(`(?i)fortinet|fortigate|ssl-?vpn`),
+		Body:  regexp.MustCompile(`(?i)/remote/(?:login|logincheck)`)},
+	{Kind: AuthVPN, Evidence: "Pulse/Ivanti Connect Secure",
+		Title: regexp.MustCompile(`(?i)pulse (?:connect )?secure|ivanti connect`),
+		Body:  regexp.MustCompile(`(?i)/dana-na/`)},
+	{Kind: AuthVPN, Evidence: "Cisco AnyConnect",
+		Title: regexp.MustCompile(`(?i)anyconnect|ssl vpn service`),
+		Body:  regexp.MustCompile(`(?i)\+cscoe\+`)},
+	{Kind: AuthVPN, Evidence: "Citrix Gateway",
+		Title: regexp.MustCompile(`(?i)citrix gateway|netscaler gateway`),
+		Body:  regexp.MustCompile(`(?i)/vpn/index\.html|/logon/logonpoint/`)},
+	{Kind: AuthVPN, Evidence: "VPN portal",
+		Title: regexp.MustCompile(`(?i)\bvpn\b`)},
+	{Kind: AuthSSO, Evidence: "AD FS",
+		Title:    regexp.MustCompile(`(?i)\bad ?fs\b`),
+		Body:     regexp.MustCompile(`(?i)/adfs/ls`),
+		Location: regexp.MustCompile(`(?i)/adfs/ls`)},
+	{Kind: AuthSSO, Evidence: "SAML",
+		Body:     regexp.MustCompile(`(?i)name=["']?saml(?:request|response)\b`),
+		Location: regexp.MustCompile(`(?i)[?&]samlrequest=`)},
+	{Kind: AuthSSO, Evidence: "OAuth/OpenID Connect",
+		Body:     regexp.MustCompile(`(?i)/\.well-known/openid-configuration|/oauth2?/(?:v2\.0/)?authorize`),
+		Location: regexp.MustCompile(`(?i)/oauth2?/(?:v2\.0/)?authorize|/protocol/openid-connect/auth|[?&]response_type=(?:code|token|id_token)`)},
+	{Kind: AuthSSO, Evidence: "identity provider",
+		Title:    regexp.MustCompile(`(?i)\b(?:keycloak|okta|auth0|onelogin|ping ?federate|shibboleth|cas)\b|single sign[- ]on|\bsso\b`),
+		Location: regexp.MustCompile(`(?i)\.okta\.com|\.auth0\.com|\.onelogin\.com|login\.microsoftonline\.com|accounts\.google\.com|/cas/login`)},
+	{Kind: AuthLoginForm, Evidence: "password field",
+		Body: regexp.MustCompile(`(?i)<input[^>]+type=["']?password`)},
+	{Kind: AuthLoginForm, Evidence: "login page",
+		Title:    regexp.MustCompile(`(?i)\blog ?in\b|\bsign ?in\b|\blogon\b|authenticat|webmail|outlook web app`),
+		Location: regexp.MustCompile(`(?i)/(?:log ?in|sign ?in|logon|auth|account/login|users/sign_in)\b`)},
+}
+
+// ClassifyAuth reports whether a response looks like an authentication
+// surface: a VPN portal, an SSO endpoint, a login page or an HTTP
+// authentication challenge. It returns the kind and a short note on what
+// gave it away, or two empty strings. body may be empty when httpx did not
+// return it; the title, redirect target and status are still checked.
+func ClassifyAuth(title, body, location string, status int) (kind, evidence string) {
+	for _, rule := range authRules {
+		switch {
+		case rule.Title != nil && rule.Title.MatchString(title):
+			return rule.Kind, rule.Evidence + " (title)"
+		case rule.Body != nil && body != "" && rule.Body.MatchString(body):
+			return rule.Kind, rule.Evidence + " (body)"
+		case rule.Location != nil && location != "" && rule.Location.MatchString(location):
+			return rule.Kind, rule.Evidence + " (redirect to " + strings.TrimSpace(location) + ")"
+		}
+	}
+	if status == 401 {
+		return AuthHTTP, "401 challenge"
+	}
+	return "", ""
+}
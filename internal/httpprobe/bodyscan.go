@@ -0,0 +1,160 @@
+package httpprobe
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/hakim/reconpipe/pkg/models"
+)
+
+// BodyRule flags response bodies matching Pattern. Secret matches are
+// redacted before they are stored or reported.
+type BodyRule struct {
+	ID       string
+	Name     string
+	Severity models.Severity
+	Secret   bool
+	Pattern  *regexp.Regexp
+}
+
+// DefaultBodyRules catch credentials and information leaks that are cheap to
+// spot in a page body.
+var DefaultBodyRules = []BodyRule{
+	{"private-key", "Private Key", models.SeverityCritical, true,
+		regexp.MustCompile(`-----BEGIN (?:RSA |EC |DSA |OPENSSH |PGP |ENCRYPTED )?PRIVATE KEY(?: BLOCK)?-----`)},
+	{"aws-access-key-id", "AWS Access Key ID", models.SeverityHigh, true,
+		regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`)},
+	{"aws-secret-access-key", "AWS Secret Access Key", models.SeverityHigh, true,
+		regexp.MustCompile(`(?i)aws.{0,20}secret.{0,20}['":=]\s*['"]?[0-9a-zA-Z/+]{40}\b`)},
+	{"github-token", "GitHub Token", models.SeverityHigh, true,
+		regexp.MustCompile(`\bgh[pousr]_[A-Za-z0-9]{36}\b`)},
+	{"slack-token", "Slack Token", models.SeverityHigh, true,
+		regexp.MustCompile(`\bxox[abposr]-[0-9A-Za-z-]{10,}`)},
+	{"google-api-key", "Google API Key", models.SeverityMedium, true,
+		regexp.MustCompile(`\bAIza[0-9A-Za-z_-]{35}\b`)},
+	{"jwt", "JSON Web Token", models.SeverityMedium, true,
+		regexp.MustCompile(`\beyJ[A-Za-z0-9_-]{10,}\.eyJ[A-Za-z0-9_-]{10,}\.[A-Za-z0-9_-]{10,}`)},
+	{"debug-banner", "Debug Output", models.SeverityMedium, false,
+		regexp.MustCompile(`(?i)werkzeug debugger|django debug|you're seeing this error because you have debug = true|whoops, looks like something went wrong|phpinfo\(\)|xdebug|laravel debugbar`)},
+	{"stack-trace", "Stack Trace", models.SeverityLow, false,
+		regexp.MustCompile(`(?i)traceback \(most recent call last\)|\bat [a-z0-9_.$]+\([a-z0-9_]+\.java:\d+\)|fatal error: [^<\n]{0,200} in /[^\s<]+|uncaught [a-z]*exception`)},
+	{"internal-ip", "Internal IP Address", models.SeverityLow, false,
+		regexp.MustCompile(`\b(?:10\.\d{1,3}\.\d{1,3}\.\d{1,3}|172\.(?:1[6-9]|2\d|3[01])\.\d{1,3}\.\d{1,3}|192\.168\.\d{1,3}\.\d{1,3})\b`)},
+}
+
+// BodyRuleSpec is an uncompiled rule, as written in config. An empty
+// Severity defaults to medium.
+type BodyRuleSpec struct {
+	ID       string
+	Name     string
+	Severity string
+	Secret   bool
+	Pattern  string
+}
+
+// CompileBodyRules compiles configured body rules.
+func CompileBodyRules(specs []BodyRuleSpec) ([]BodyRule, error) {
+	rules := make([]BodyRule, 0, len(specs))
+	for _, spec := range specs {
+		re, err := regexp.Compile(spec.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("body rule %q: %w", spec.ID, err)
+		}
+		severity := models.Severity(strings.ToLower(spec.Severity))
+		switch severity {
+		case "":
+			severity = models.SeverityMedium
+		case models.SeverityCritical, models.SeverityHigh, models.SeverityMedium, models.SeverityLow, models.SeverityInfo:
+		default:
+			return nil, fmt.Errorf("body rule %q: unknown severity %q", spec.ID, spec.Severity)
+		}
+		name := spec.Name
+		if name == "" {
+			name = spec.ID
+		}
+		rules = append(rules, BodyRule{ID: spec.ID, Name: name, Severity: severity, Secret: spec.Secret, Pattern: re})
+	}
+	return rules, nil
+}
+
+// BodyScanOptions controls response body scanning. Bodies are only fetched
+// from httpx when Enabled is set, since they make its output much larger.
+type BodyScanOptions struct {
+	Enabled bool
+	// Rules are matched in addition to DefaultBodyRules.
+	Rules []BodyRule
+}
+
+// BodyMatch is one rule that matched a response body.
+type BodyMatch struct {
+	URL      string          `json:"url"`
+	RuleID   string          `json:"rule_id"`
+	Name     string          `json:"name"`
+	Severity models.Severity `json:"severity"`
+	// Matches holds up to maxBodyMatchValues distinct matched strings,
+	// redacted for secret rules.
+	Matches []string `json:"matches"`
+}
+
+// maxBodyMatchValues caps the values kept per rule and URL; the first few
+// are enough to confirm a hit.
+const maxBodyMatchValues = 3
+
+// maxBodyMatchLen truncates long non-secret matches such as stack traces.
+const maxBodyMatchLen = 120
+
+// ScanBody applies rules to one response body.
+func ScanBody(url, body string, rules []BodyRule) []BodyMatch {
+	var matches []BodyMatch
+	for _, r := range rules {
+		found := r.Pattern.FindAllString(body, -1)
+		if len(found) == 0 {
+			continue
+		}
+		m := BodyMatch{URL: url, RuleID: r.ID, Name: r.Name, Severity: r.Severity}
+		seen := make(map[string]bool)
+		for _, v := range found {
+			if r.Secret {
+				v = redact(v)
+			} else if len(v) > maxBodyMatchLen {
+				v = strings.ToValidUTF8(v[:maxBodyMatchLen], "") + "…"
+			}
+			if seen[v] {
+				continue
+			}
+			seen[v] = true
+			if len(m.Matches) < maxBodyMatchValues {
+				m.Matches = append(m.Matches, v)
+			}
+		}
+		matches = append(matches, m)
+	}
+	return matches
+}
+
+// redact keeps enough of a secret to recognise it without reproducing it.
+func redact(s string) string {
+	if len(s) <= 12 {
+		return strings.Repeat("*", len(s))
+	}
+	return s[:6] + "…" + s[len(s)-4:]
+}
+
+// BodyFindings converts body matches into findings for the vulnerability
+// report, one per rule and URL.
+func BodyFindings(matches []BodyMatch) []models.Vulnerability {
+	findings := make([]models.Vulnerability, 0, len(matches))
+	for _, m := range matches {
+		findings = append(findings, models.Vulnerability{
+			TemplateID:  "body-" + m.RuleID,
+			Name:        m.Name + " in Response Body",
+			Severity:    m.Severity,
+			Host:        m.URL,
+			URL:         m.URL,
+			MatchedAt:   m.URL,
+			Description: "Matched: " + strings.Join(m.Matches, ", "),
+		})
+	}
+	return findings
+}
@@ -0,0 +1,118 @@
+package httpprobe
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/hakim/reconpipe/pkg/models"
+)
+
+// minCloneBody is the shortest normalized body worth fingerprinting. Below
+// it, unrelated services share stock error pages and tiny JSON replies.
+const minCloneBody = 256
+
+// CloneCluster is a page served identically, after normalization, by
+// services on different hosts.
+type CloneCluster struct {
+	Hash  string   `json:"hash"`
+	Title string   `json:"title,omitempty"`
+	URLs  []string `json:"urls"`
+}
+
+var (
+	// Timestamps and Unix epochs (seconds or milliseconds) that change
+	// between responses
+	timestampPattern = regexp.MustCompile(`(?i)\d{4}-\d{2}-\d{2}[T ]\d{2}:\d{2}(?::\d{2}(?:\.\d+)?)?(?:Z|[+-]\d{2}:?\d{2})?|\b1\d{9}(?:\d{3})?\b`)
+	// Long runs of token characters: CSRF tokens, nonces, session and cache
+	// busting IDs. Only runs mixing letters and digits are replaced.
+	tokenPattern      = regexp.MustCompile(`[A-Za-z0-9+/_-]{16,}={0,2}`)
+	whitespacePattern = regexp.MustCompile(`\s+`)
+)
+
+// ContentHash returns the SHA-256 of body after normalization, or "" when
+// the normalized body is too short to identify an application. The host and
+// IP of rawURL are masked so a clone on another name hashes the same, as
+// are timestamps and per-response tokens.
+func ContentHash(rawURL, ip, body string) string {
+	normalized := normalizeBody(rawURL, ip, body)
+	if len(normalized) < minCloneBody {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
+
+// normalizeBody strips what differs between copies of one application.
+func normalizeBody(rawURL, ip, body string) string {
+	s := strings.ToLower(body)
+	if u, err := url.Parse(rawURL); err == nil && u.Hostname() != "" {
+		s = strings.ReplaceAll(s, strings.ToLower(u.Hostname()), "{host}")
+	}
+	if ip != "" {
+		s = strings.ReplaceAll(s, ip, "{host}")
+	}
+	s = timestampPattern.ReplaceAllString(s, "{time}")
+	s = tokenPattern.ReplaceAllStringFunc(s, func(m string) string {
+		if strings.ContainsAny(m, "0123456789") && strings.ContainsAny(m, "abcdefghijklmnopqrstuvwxyz") {
+			return "{token}"
+		}
+		return m
+	})
+	return strings.TrimSpace(whitespacePattern.ReplaceAllString(s, " "))
+}
+
+// findClones groups the 2xx probes by ContentHash and returns the groups
+// that span more than one hostname or more than one IP. A hostname and the
+// IP it resolves to serving the same page are one service, not a clone.
+// Clusters are ordered by size, largest first.
+func findClones(probes []models.HTTPProbe) []CloneCluster {
+	byHash := make(map[string][]models.HTTPProbe)
+	var order []string
+	for _, p := range probes {
+		if p.ContentHash == "" || p.StatusCode < 200 || p.StatusCode > 299 {
+			continue
+		}
+		if _, ok := byHash[p.ContentHash]; !ok {
+			order = append(order, p.ContentHash)
+		}
+		byHash[p.ContentHash] = append(byHash[p.ContentHash], p)
+	}
+
+	var clusters []CloneCluster
+	for _, hash := range order {
+		group := byHash[hash]
+		names := make(map[string]bool)
+		ips := make(map[string]bool)
+		for _, p := range group {
+			if u, err := url.Parse(p.URL); err == nil && net.ParseIP(u.Hostname()) == nil {
+				names[strings.ToLower(u.Hostname())] = true
+			}
+			if p.IP != "" {
+				ips[p.IP] = true
+			}
+		}
+		if len(names) < 2 && len(ips) < 2 {
+			continue
+		}
+
+		c := CloneCluster{Hash: hash}
+		for _, p := range group {
+			c.URLs = append(c.URLs, p.URL)
+			if c.Title == "" {
+				c.Title = p.Title
+			}
+		}
+		sort.Strings(c.URLs)
+		clusters = append(clusters, c)
+	}
+
+	sort.SliceStable(clusters, func(i, j int) bool {
+		return len(clusters[i].URLs) > len(clusters[j].URLs)
+	})
+	return clusters
+}
@@ -0,0 +1,82 @@
+package httpprobe
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/hakim/reconpipe/pkg/models"
+)
+
+// consolidateRedirects links each redirecting probe to the probe its
+// Location points at, so http://host:80 → https://host:443 is recognised as
+// one application. Probes are kept as-is apart from RedirectsTo.
+//
+// Targets are matched by origin (scheme, host, port) — a redirect to
+// https://host/login still lands on the https://host probe. Chains are
+// followed to their final probe; cycles leave the probes unlinked.
+func consolidateRedirects(probes []models.HTTPProbe) {
+	byOrigin := make(map[string]int, len(probes))
+	for i, p := range probes {
+		if o := origin(p.URL, ""); o != "" {
+			if _, dup := byOrigin[o]; !dup {
+				byOrigin[o] = i
+			}
+		}
+	}
+
+	// next[i] is the probe that probe i redirects to, or -1
+	next := make([]int, len(probes))
+	for i, p := range probes {
+		next[i] = -1
+		if p.StatusCode < 300 || p.StatusCode > 399 || p.Location == "" {
+			continue
+		}
+		if j, ok := byOrigin[origin(p.Location, p.URL)]; ok && j != i {
+			next[i] = j
+		}
+	}
+
+	for i := range probes {
+		probes[i].RedirectsTo = ""
+		j, hops := i, 0
+		for next[j] >= 0 && hops <= len(probes) {
+			j = next[j]
+			hops++
+		}
+		if j != i && next[j] < 0 {
+			probes[i].RedirectsTo = probes[j].URL
+		}
+	}
+}
+
+// origin returns "scheme://host:port" for rawURL, resolved against base when
+// rawURL is relative. Default ports are made explicit so http://host and
+// http://host:80 compare equal. It returns "" for unparseable URLs.
+func origin(rawURL, base string) string {
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil {
+		return ""
+	}
+	if base != "" {
+		b, err := url.Parse(base)
+		if err != nil {
+			return ""
+		}
+		u = b.ResolveReference(u)
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return ""
+	}
+
+	scheme := strings.ToLower(u.Scheme)
+	port := u.Port()
+	if port == "" {
+		switch scheme {
+		case "http":
+			port = "80"
+		case "https":
+			port = "443"
+		}
+	}
+	return scheme + "://" + strings.ToLower(u.Hostname()) + ":" + port
+}
@@ -0,0 +1,95 @@
+package httpprobe
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/hakim/reconpipe/internal/netprobe"
+	"github.com/hakim/reconpipe/pkg/models"
+)
+
+// checkMethods records each probe's allowed methods, TRACE support and
+// answer to a random path (Step 12). API endpoints without a page are
+// left out: a GET-based 404 check says nothing about them.
+func checkMethods(ctx context.Context, probes []models.HTTPProbe, cfg netprobe.MethodCheckConfig) {
+	var urls []string
+	for _, p := range probes {
+		if p.GRPC && p.ContentLength == 0 {
+			continue
+		}
+		urls = append(urls, p.URL)
+	}
+	if len(urls) == 0 {
+		return
+	}
+
+	checks := netprobe.CheckMethods(ctx, urls, cfg)
+	trace, risky, soft404 := 0, 0, 0
+	for i := range probes {
+		c, ok := checks[probes[i].URL]
+		if !ok {
+			continue
+		}
+		probes[i].AllowedMethods = c.AllowedMethods
+		probes[i].TraceEnabled = c.TraceEnabled
+		probes[i].NotFoundStatus = c.NotFoundStatus
+		probes[i].NotFoundLength = c.NotFoundLength
+		probes[i].Soft404 = c.Soft404
+		if c.TraceEnabled {
+			trace++
+		}
+		if len(RiskyMethods(probes[i])) > 0 {
+			risky++
+		}
+		if c.Soft404 {
+			soft404++
+		}
+	}
+	fmt.Printf("[*] Method check: %d services echo TRACE, %d allow risky methods, %d answer any path with a 2xx\n", trace, risky, soft404)
+}
+
+// RiskyMethods returns the methods in netprobe.RiskyMethods the probe's
+// OPTIONS answer allowed.
+func RiskyMethods(probe models.HTTPProbe) []string {
+	var risky []string
+	for _, m := range probe.AllowedMethods {
+		if slices.Contains(netprobe.RiskyMethods, m) {
+			risky = append(risky, m)
+		}
+	}
+	return risky
+}
+
+// MethodFindings converts the method checks into findings for the
+// vulnerability report: one per service echoing TRACE, and one per service
+// allowing risky methods.
+func MethodFindings(probes []models.HTTPProbe) []models.Vulnerability {
+	var findings []models.Vulnerability
+	for _, p := range probes {
+		if p.TraceEnabled {
+			findings = append(findings, models.Vulnerability{
+				TemplateID:  "http-trace-enabled",
+				Name:        "HTTP TRACE Method Enabled",
+				Severity:    models.SeverityLow,
+				Host:        p.URL,
+				URL:         p.URL,
+				MatchedAt:   p.URL,
+				Description: "TRACE echoes the request back, headers included (cross-site tracing).",
+			})
+		}
+		if risky := RiskyMethods(p); len(risky) > 0 {
+			findings = append(findings, models.Vulnerability{
+				TemplateID:  "http-risky-methods",
+				Name:        "Risky HTTP Methods Allowed",
+				Severity:    models.SeverityInfo,
+				Host:        p.URL,
+				URL:         p.URL,
+				MatchedAt:   p.URL,
+				Description: "OPTIONS lists " + strings.Join(risky, ", ") + "; check whether they are really accepted.",
+			})
+		}
+	}
+	return findings
+}
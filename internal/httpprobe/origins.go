@@ -0,0 +1,206 @@
+package httpprobe
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/hakim/reconpipe/internal/origins"
+	"github.com/hakim/reconpipe/internal/provider"
+	"github.com/hakim/reconpipe/internal/tools"
+	"github.com/hakim/reconpipe/pkg/models"
+)
+
+// cdnPorts are the ports a CDN-fronted hostname is requested on.
+var cdnPorts = []int{443, 80}
+
+// OriginBypass is an origin server that serves a CDN-fronted hostname when
+// asked for it directly, so the CDN's WAF, rate limits and access rules can
+// be skipped by connecting to the origin IP.
+type OriginBypass struct {
+	Hostname    string `json:"hostname"`
+	CDNProvider string `json:"cdn_provider,omitempty"`
+	CDNURL      string `json:"cdn_url"`
+	// OriginURL is the origin IP and port, requested with Host: Hostname
+	OriginURL  string `json:"origin_url"`
+	OriginIP   string `json:"origin_ip"`
+	StatusCode int    `json:"status_code"` // the origin's answer
+	Title      string `json:"title,omitempty"`
+	// Match is "identical" when both bodies hash the same, "similar" when
+	// only the status and title agree.
+	Match string `json:"match"`
+	// Evidence is what tied the origin to the hostname, e.g. the
+	// certificate name it presented.
+	Evidence string `json:"evidence"`
+}
+
+// checkOrigins looks for the origins of CDN-fronted hostnames and for those
+// reachable around the CDN. Each hostname is requested through the CDN,
+// origin candidates are gathered by the techniques of package origins, and
+// every candidate the scan found serving HTTP is requested on those ports
+// with the hostname as Host header and SNI. A candidate that answers like
+// the CDN is verified and reported as a bypass. Failures only warn: the
+// check adds to the probe results.
+func checkOrigins(ctx context.Context, probes []models.HTTPProbe, hosts []models.Host, cfg HTTPProbeConfig) ([]models.OriginCandidate, []OriginBypass) {
+	excludedNames := cfg.Exclude.Names(hosts)
+	providers := make(map[string]string)
+	var names []string
+	for _, host := range hosts {
+		if !host.IsCDN || host.Excluded {
+			continue
+		}
+		for _, sub := range host.Subdomains {
+			if _, ok := providers[sub]; !ok && !excludedNames[sub] {
+				providers[sub] = host.CDNProvider
+				names = append(names, sub)
+			}
+		}
+	}
+	if len(names) == 0 {
+		return nil, nil
+	}
+	sort.Strings(names)
+
+	// Step 1: Request each hostname through the CDN
+	var cdnTargets []string
+	for _, name := range names {
+		for _, port := range cdnPorts {
+			cdnTargets = append(cdnTargets, net.JoinHostPort(name, strconv.Itoa(port)))
+		}
+	}
+	fmt.Printf("[*] Origin discovery: requesting %d CDN-fronted hostnames through the CDN...\n", len(names))
+	opts := tools.HttpxOptions{Threads: cfg.HttpxThreads, IncludeBody: true, Favicon: true}
+	var cdnResults []tools.HttpxResult
+	err := streamByProvider(ctx, cdnTargets, opts, cfg, provider.Index(hosts), func(r tools.HttpxResult) {
+		cdnResults = append(cdnResults, r)
+	})
+	if err != nil {
+		fmt.Printf("[!] Warning: origin discovery failed: %v\n", err)
+		return nil, nil
+	}
+	viaCDN := make(map[string]tools.HttpxResult)
+	var cdnProbes []models.HTTPProbe
+	for _, r := range cdnResults {
+		viaCDN[r.Input] = r
+		cdnProbes = append(cdnProbes, models.HTTPProbe{
+			URL: r.URL, StatusCode: r.StatusCode, Title: r.Title, IP: r.HostIP, Favicon: r.Favicon, IsCDN: true,
+		})
+	}
+
+	// Step 2: Gather candidates from every technique
+	mailHosts, err := origins.LookupMailHosts(ctx, cfg.Target, cfg.DigPath)
+	if err != nil {
+		fmt.Printf("[!] Warning: %v\n", err)
+	}
+	candidates := origins.Find(origins.Inputs{
+		Hosts:     hosts,
+		Probes:    probes,
+		CDNProbes: cdnProbes,
+		History:   cfg.OriginHistory,
+		MailHosts: mailHosts,
+		Exclude:   cfg.Exclude,
+	})
+	fmt.Printf("[*] Origin discovery: %d candidate origins for %d CDN-fronted hostnames\n", len(candidates), len(names))
+
+	// Step 3: Request each hostname from its candidates on the ports they
+	// answered HTTP on, one httpx run per hostname
+	originPorts := make(map[string][]int)
+	for _, p := range probes {
+		if isDirectProbe(p) && p.Port > 0 && !slices.Contains(originPorts[p.IP], p.Port) {
+			originPorts[p.IP] = append(originPorts[p.IP], p.Port)
+		}
+	}
+
+	var bypasses []OriginBypass
+	opts.Favicon = false
+	for _, name := range names {
+		var targets []string
+		index := make(map[string]int)
+		for i, c := range candidates {
+			if c.Hostname != name {
+				continue
+			}
+			for _, port := range originPorts[c.IP] {
+				target := net.JoinHostPort(c.IP, strconv.Itoa(port))
+				targets = append(targets, target)
+				index[target] = i
+			}
+		}
+		if len(targets) == 0 {
+			continue
+		}
+
+		opts.Host = name
+		results, err := tools.RunHttpx(ctx, targets, opts, cfg.HttpxPath)
+		if err != nil {
+			fmt.Printf("[!] Warning: origin check for %s failed: %v\n", name, err)
+			continue
+		}
+		for _, r := range results {
+			i, ok := index[r.Input]
+			if !ok {
+				continue
+			}
+			_, port, _ := net.SplitHostPort(r.Input)
+			cdn, ok := viaCDN[net.JoinHostPort(name, port)]
+			if !ok {
+				continue
+			}
+			match := compareResponses(cdn, r)
+			if match == "" {
+				continue
+			}
+			candidates[i].Verified = true
+			bypasses = append(bypasses, OriginBypass{
+				Hostname:    name,
+				CDNProvider: providers[name],
+				CDNURL:      cdn.URL,
+				OriginURL:   r.URL,
+				OriginIP:    candidates[i].IP,
+				StatusCode:  r.StatusCode,
+				Title:       r.Title,
+				Match:       match,
+				Evidence:    strings.Join(candidates[i].Evidence, "; "),
+			})
+		}
+	}
+
+	sort.Slice(bypasses, func(i, j int) bool {
+		if bypasses[i].Hostname != bypasses[j].Hostname {
+			return bypasses[i].Hostname < bypasses[j].Hostname
+		}
+		return bypasses[i].OriginURL < bypasses[j].OriginURL
+	})
+	return candidates, bypasses
+}
+
+// isDirectProbe reports whether p requested an IP address rather than a name.
+func isDirectProbe(p models.HTTPProbe) bool {
+	u, err := url.Parse(p.URL)
+	return err == nil && p.IP != "" && u.Hostname() == p.IP
+}
+
+// compareResponses returns how closely the origin's direct answer matches
+// the CDN's: "identical" when the normalized bodies hash the same,
+// "similar" when both give the same non-error status and page title, else
+// "". The direct body is normalized against the CDN URL, so the hostname
+// is masked in both.
+func compareResponses(cdn, direct tools.HttpxResult) string {
+	if direct.StatusCode == 0 {
+		return ""
+	}
+	cdnHash := ContentHash(cdn.URL, cdn.HostIP, cdn.Body)
+	if cdnHash != "" && cdnHash == ContentHash(cdn.URL, direct.HostIP, direct.Body) {
+		return "identical"
+	}
+	if direct.StatusCode == cdn.StatusCode && direct.StatusCode < 400 &&
+		cdn.Title != "" && strings.EqualFold(strings.TrimSpace(direct.Title), strings.TrimSpace(cdn.Title)) {
+		return "similar"
+	}
+	return ""
+}
@@ -3,34 +3,124 @@ package httpprobe
 import (
 	"context"
 	"fmt"
+	"slices"
 	"strconv"
+	"strings"
+	"time"
 
-	"github.com/hakim/reconpipe/internal/models"
+	"github.com/hakim/reconpipe/internal/exclude"
+	"github.com/hakim/reconpipe/internal/filter"
+	"github.com/hakim/reconpipe/internal/netprobe"
+	"github.com/hakim/reconpipe/internal/origins"
+	"github.com/hakim/reconpipe/internal/provider"
 	"github.com/hakim/reconpipe/internal/tools"
+	"github.com/hakim/reconpipe/internal/triage"
+	"github.com/hakim/reconpipe/internal/urllist"
+	"github.com/hakim/reconpipe/pkg/models"
 )
 
 // HTTPProbeConfig holds all configuration for the HTTP probing pipeline.
 type HTTPProbeConfig struct {
+	// Target is the engagement's root domain; it labels the result.
+	Target string
 	// HttpxPath is the path to the httpx binary. Empty means resolve from PATH.
 	HttpxPath string
 	// GowitnessPath is the path to the gowitness binary. Empty means resolve from PATH.
 	GowitnessPath string
 	// HttpxThreads controls the concurrency level for httpx.
 	HttpxThreads int
-	// GowitnessThreads controls the concurrency level for gowitness screenshot captures.
+	// GowitnessThreads controls screenshot concurrency (gowitness threads or chromedp tabs).
 	GowitnessThreads int
 	// ScreenshotDir is the directory where screenshots will be saved.
 	ScreenshotDir string
-	// SkipScreenshots disables gowitness when true.
+	// SkipScreenshots disables screenshot capture when true.
 	SkipScreenshots bool
+	// Screenshots selects which responses are captured and how.
+	Screenshots ScreenshotOptions
+	// BodyScan matches response bodies against secret and leak rules.
+	BodyScan BodyScanOptions
+	// LiveStatus lists the status patterns ("2xx", "401", "500-503") that
+	// count towards LiveCount. Empty = DefaultLiveStatus.
+	LiveStatus []string
+	// Exclude lists networks that are never probed, directly or through a
+	// hostname resolving into them. Nil excludes nothing.
+	Exclude *exclude.List
+	// SkipProtocols disables the HTTP/2, HTTP/3, gRPC and WebSocket checks
+	// run on every responding service.
+	SkipProtocols bool
+	Protocols     netprobe.ProtocolCheckConfig
+	// CheckMethods sends every responding service an OPTIONS and a TRACE
+	// request and a GET for a random path, to record its allowed methods
+	// and soft-404 behavior.
+	CheckMethods bool
+	Methods      netprobe.MethodCheckConfig
+	// SkipRetry disables the second, slower httpx pass over the targets the
+	// first pass got no response from.
+	SkipRetry bool
+	// RetryThreads and RetryRateLimit throttle that pass. Zero threads means
+	// a quarter of HttpxThreads; a zero rate limit keeps httpx's default.
+	RetryThreads   int
+	RetryRateLimit int
+	// SkipClones disables content hashing of response bodies, which finds
+	// copies of one application served on different hosts.
+	SkipClones bool
+	// SkipOriginCheck disables origin discovery for CDN-fronted hostnames
+	// and requesting them from the candidates directly.
+	SkipOriginCheck bool
+	// DigPath is the dig binary for the MX lookup of origin discovery.
+	// Empty means resolve from PATH.
+	DigPath string
+	// OriginHistory lists where the scanned names resolved in earlier
+	// scans, for origin discovery.
+	OriginHistory []origins.Sighting
+	// URLs, when set, are probed as given instead of the host:port targets
+	// derived from hosts, for a client-supplied URL list. hosts still supply
+	// the addresses and CDN data of the probes.
+	URLs []string
+	// Filter selects the host:port targets (or listed URLs) that are probed,
+	// from filter.TargetFields. Nil probes every target.
+	Filter *filter.Expr
+	// Profiles lowers the httpx rate and lengthens its timeout for the
+	// targets of hosts whose provider has a profile. Nil probes every
+	// target alike.
+	Profiles *provider.Profiles
+	// ChunkSize caps the targets handed to one httpx run. Results are
+	// reduced to probes as they stream in, response bodies dropped, and each
+	// run finishes before the next starts. 0 = one run over every target.
+	ChunkSize int
 }
 
 // HTTPProbeResult contains the aggregated output of the HTTP probing pipeline.
 type HTTPProbeResult struct {
 	Target        string             `json:"target"`
 	Probes        []models.HTTPProbe `json:"probes"`
-	LiveCount     int                `json:"live_count"`
+	LiveCount     int                `json:"live_count"`  // probes matching the live status patterns
+	UniqueApps    int                `json:"unique_apps"` // live services less redirects to another probe
+	StatusCounts  map[string]int     `json:"status_counts,omitempty"`
 	ScreenshotDir string             `json:"screenshot_dir,omitempty"`
+	// Filter is the --filter expression the targets were selected with;
+	// FilteredOut counts the targets it dropped.
+	Filter      string `json:"filter,omitempty"`
+	FilteredOut int    `json:"filtered_out,omitempty"`
+	// Triage orders this run's screenshots by how interesting they look.
+	Triage []triage.Entry `json:"screenshot_triage,omitempty"`
+	// BodyMatches lists body scan hits, when body scanning is enabled.
+	BodyMatches []BodyMatch `json:"body_matches,omitempty"`
+	// Clones groups the services that serve the same page on different
+	// hosts, e.g. a staging copy of production.
+	Clones []CloneCluster `json:"clones,omitempty"`
+	// OriginBypass lists origins that serve a CDN-fronted hostname when
+	// asked directly, bypassing the CDN.
+	OriginBypass []OriginBypass `json:"origin_bypass,omitempty"`
+	// Origins lists candidate origin servers of CDN-fronted hostnames; the
+	// probe stage also records them on the CDN hosts in ports.json.
+	Origins []models.OriginCandidate `json:"origin_candidates,omitempty"`
+	// Unreachable lists the targets on ports that may serve HTTP which
+	// answered neither httpx pass nor the gRPC check. Empty when the retry
+	// is skipped.
+	Unreachable []string `json:"unreachable,omitempty"`
+	// CollectedAt is when the stage started probing.
+	CollectedAt time.Time `json:"collected_at,omitzero"`
 }
 
 // RunHTTPProbe orchestrates httpx probing and optional gowitness screenshots
@@ -41,31 +131,42 @@ type HTTPProbeResult struct {
 //   - All hosts: "{subdomain}:{port}" for every subdomain+port combination
 //
 // CDN IPs are excluded from direct IP:port probing but their subdomains are
-// still probed by name so CDN-fronted services appear in results.
+// still probed by name so CDN-fronted services appear in results. Hosts on
+// the exclusions list, and every name resolving to one, are not probed.
 func RunHTTPProbe(ctx context.Context, hosts []models.Host, cfg HTTPProbeConfig) (*HTTPProbeResult, error) {
 	result := &HTTPProbeResult{
-		Probes: []models.HTTPProbe{},
-	}
-
-	// Derive target from first host's first subdomain (best-effort)
-	if len(hosts) > 0 && len(hosts[0].Subdomains) > 0 {
-		result.Target = hosts[0].Subdomains[0]
+		Target:      cfg.Target,
+		Probes:      []models.HTTPProbe{},
+		Filter:      cfg.Filter.String(),
+		CollectedAt: time.Now().UTC(),
 	}
+	excludedNames := cfg.Exclude.Names(hosts)
 
 	// Step 1: Build IP:port targets for non-CDN hosts only.
 	// CDN IPs should not be port-probed directly — we reach them via subdomains.
 	ipPortSeen := make(map[string]bool)
 	var ipPortTargets []string
+	// The service nmap identified behind each target, if any
+	services := make(map[string]string)
 
 	for _, host := range hosts {
-		if host.IsCDN {
+		if host.IsCDN || !cfg.Exclude.Allows(host) {
 			continue
 		}
 		for _, port := range host.Ports {
+			if port.Protocol == "udp" {
+				continue // not HTTP
+			}
 			target := fmt.Sprintf("%s:%d", host.IP, port.Number)
+			if !ipPortSeen[target] && !cfg.Filter.Match(filter.TargetRecord(host.IP, host, port)) {
+				ipPortSeen[target] = true
+				result.FilteredOut++
+				continue
+			}
 			if !ipPortSeen[target] {
 				ipPortSeen[target] = true
 				ipPortTargets = append(ipPortTargets, target)
+				services[target] = port.Service
 			}
 		}
 	}
@@ -77,58 +178,175 @@ func RunHTTPProbe(ctx context.Context, hosts []models.Host, cfg HTTPProbeConfig)
 
 	for _, host := range hosts {
 		for _, subdomain := range host.Subdomains {
+			if excludedNames[subdomain] {
+				continue
+			}
 			for _, port := range host.Ports {
+				if port.Protocol == "udp" {
+					continue
+				}
 				target := fmt.Sprintf("%s:%d", subdomain, port.Number)
+				if !subPortSeen[target] && !cfg.Filter.Match(filter.TargetRecord(subdomain, host, port)) {
+					subPortSeen[target] = true
+					result.FilteredOut++
+					continue
+				}
 				if !subPortSeen[target] {
 					subPortSeen[target] = true
 					subPortTargets = append(subPortTargets, target)
+					services[target] = port.Service
 				}
 			}
 		}
 	}
 
-	// Step 3: Combine target lists (IP:port first, then subdomain:port)
+	// Step 3: Combine target lists (IP:port first, then subdomain:port), or
+	// take the URL list in their place
 	allTargets := append(ipPortTargets, subPortTargets...)
+	if len(cfg.URLs) > 0 {
+		allTargets = nil
+		result.FilteredOut = 0
+		for _, raw := range cfg.URLs {
+			t, err := urllist.Parse(raw)
+			if err == nil && (excludedNames[t.Host] || cfg.Exclude.Contains(t.Host)) {
+				continue
+			}
+			if err == nil && !cfg.Filter.Match(listedRecord(t, hosts)) {
+				result.FilteredOut++
+				continue
+			}
+			allTargets = append(allTargets, raw)
+		}
+	}
+	if cfg.Filter != nil {
+		fmt.Printf("[*] Filter dropped %d targets: %s\n", result.FilteredOut, cfg.Filter)
+	}
 
+	if len(excludedNames) > 0 {
+		fmt.Printf("[*] Not probing %d hostnames that resolve into excluded networks\n", len(excludedNames))
+	}
 	if len(allTargets) == 0 {
 		fmt.Println("[*] No HTTP probe targets derived from hosts")
 		return result, nil
 	}
 
 	// Step 4: Run httpx against all targets
-	fmt.Printf("[*] Running httpx against %d targets (%d IP:port, %d subdomain:port)...\n",
-		len(allTargets), len(ipPortTargets), len(subPortTargets))
+	if len(cfg.URLs) > 0 {
+		fmt.Printf("[*] Running httpx against %d listed URLs...\n", len(allTargets))
+	} else {
+		fmt.Printf("[*] Running httpx against %d targets (%d IP:port, %d subdomain:port)...\n",
+			len(allTargets), len(ipPortTargets), len(subPortTargets))
+	}
 
-	httpxResults, err := tools.RunHttpx(ctx, allTargets, cfg.HttpxThreads, cfg.HttpxPath)
-	if err != nil {
-		return nil, fmt.Errorf("httpx execution failed: %w", err)
+	httpxOpts := tools.HttpxOptions{
+		Threads:     cfg.HttpxThreads,
+		IncludeBody: cfg.BodyScan.Enabled || !cfg.SkipClones,
+		Favicon:     !cfg.SkipOriginCheck,
 	}
 
-	fmt.Printf("[*] httpx complete, processing %d results...\n", len(httpxResults))
+	// Each result is reduced to its probe as it arrives (Steps 6 and 7), so
+	// response bodies never accumulate, however many targets there are
+	answered := make(map[string]bool)
+	var rawProbes []models.HTTPProbe
+	var rules []BodyRule
+	if cfg.BodyScan.Enabled {
+		rules = append(append(rules, DefaultBodyRules...), cfg.BodyScan.Rules...)
+	}
+	scanned := make(map[string]bool)
+	collect := func(r tools.HttpxResult) {
+		answered[r.Input] = true
+		rawProbes = append(rawProbes, toProbe(r, cfg))
+		if cfg.BodyScan.Enabled && r.Body != "" && !scanned[r.URL] {
+			scanned[r.URL] = true
+			result.BodyMatches = append(result.BodyMatches, ScanBody(r.URL, r.Body, rules)...)
+		}
+	}
 
-	// Step 5: Convert HttpxResult to models.HTTPProbe
-	rawProbes := make([]models.HTTPProbe, 0, len(httpxResults))
-	for _, r := range httpxResults {
-		port, err := strconv.Atoi(r.Port)
-		if err != nil {
-			port = 0
+	if chunks := chunkTargets(allTargets, cfg.ChunkSize); len(chunks) > 1 {
+		fmt.Printf("[*] Probing in %d batches of up to %d targets\n", len(chunks), cfg.ChunkSize)
+	}
+	providerOf := provider.Index(hosts)
+	if err := streamByProvider(ctx, allTargets, httpxOpts, cfg, providerOf, collect); err != nil {
+		return nil, fmt.Errorf("httpx execution failed: %w", err)
+	}
+
+	// Step 5: Retry the HTTP-looking targets httpx returned nothing for,
+	// once, at reduced concurrency (optional). At full thread count httpx
+	// silently drops targets that rate-limit or time out; by the end of the
+	// first pass their limits have usually reset.
+	if !cfg.SkipRetry {
+		var retry []string
+		for _, target := range unanswered(allTargets, answered) {
+			if mayServeHTTP(services[target]) {
+				retry = append(retry, target)
+			}
 		}
+		if len(retry) > 0 {
+			threads := cfg.RetryThreads
+			if threads <= 0 {
+				threads = max(cfg.HttpxThreads/4, 1)
+			}
+			fmt.Printf("[*] Retrying %d targets httpx got no response from (%d threads)...\n", len(retry), threads)
+			retryOpts := httpxOpts
+			retryOpts.Threads, retryOpts.RateLimit = threads, cfg.RetryRateLimit
+			if retryErr := streamByProvider(ctx, retry, retryOpts, cfg, providerOf, collect); retryErr != nil {
+				// The first pass's results stand on their own
+				fmt.Printf("[!] Warning: httpx retry failed: %v\n", retryErr)
+			} else {
+				fmt.Printf("[+] Retry recovered %d of %d targets\n", len(retry)-len(unanswered(retry, answered)), len(retry))
+			}
+		}
+	}
 
-		probe := models.HTTPProbe{
-			URL:           r.URL,
-			StatusCode:    r.StatusCode,
-			Title:         r.Title,
-			ContentLength: r.ContentLength,
-			WebServer:     r.WebServer,
-			Technologies:  r.Technologies,
-			Host:          r.Input,
-			IP:            r.HostIP,
-			Port:          port,
+	fmt.Printf("[*] httpx complete, processed %d results\n", len(rawProbes))
+	if cfg.BodyScan.Enabled {
+		fmt.Printf("[+] Body scan: %d matches across %d responses\n", len(result.BodyMatches), len(scanned))
+	}
+
+	// Step 8: gRPC servers speak only HTTP/2, so httpx gets no answer from
+	// them; look for one behind each target it found nothing on (optional).
+	// Listed URLs name an HTTP resource, so they are not checked.
+	silent := unanswered(allTargets, answered)
+	found := make(map[string]string)
+	if !cfg.SkipProtocols && len(cfg.URLs) == 0 {
+		hostIP := make(map[string]string)
+		for _, host := range hosts {
+			for _, sub := range host.Subdomains {
+				hostIP[sub] = host.IP
+			}
+		}
+		found = netprobe.DetectGRPC(ctx, silent, cfg.Protocols)
+		for _, target := range silent {
+			u, ok := found[target]
+			if !ok {
+				continue
+			}
+			name, portStr, _ := strings.Cut(target, ":")
+			port, _ := strconv.Atoi(portStr)
+			ip, ok := hostIP[name]
+			if !ok {
+				ip = name
+			}
+			// gRPC answers every call with HTTP 200; the outcome is in grpc-status
+			rawProbes = append(rawProbes, models.HTTPProbe{
+				URL: u, StatusCode: 200, Host: target, IP: ip, Port: port, HTTP2: true, GRPC: true,
+			})
+		}
+		if len(found) > 0 {
+			fmt.Printf("[+] Found %d gRPC services httpx could not reach\n", len(found))
+		}
+	}
+	for _, target := range silent {
+		// Without the retry a silent target may just have been dropped
+		if _, ok := found[target]; !ok && !cfg.SkipRetry && mayServeHTTP(services[target]) {
+			result.Unreachable = append(result.Unreachable, target)
 		}
-		rawProbes = append(rawProbes, probe)
+	}
+	if len(result.Unreachable) > 0 {
+		fmt.Printf("[!] %d targets gave no HTTP response\n", len(result.Unreachable))
 	}
 
-	// Step 6: Deduplicate probes by URL — httpx may return duplicate URLs
+	// Step 9: Deduplicate probes by URL — httpx may return duplicate URLs
 	// when the same service is reached via multiple target forms.
 	urlSeen := make(map[string]bool)
 	var probes []models.HTTPProbe
@@ -140,7 +358,7 @@ func RunHTTPProbe(ctx context.Context, hosts []models.Host, cfg HTTPProbeConfig)
 		probes = append(probes, probe)
 	}
 
-	// Step 7: CDN post-tagging — build a lookup map of IP -> CDN info from
+	// Step 10: CDN post-tagging — build a lookup map of IP -> CDN info from
 	// the input hosts, then stamp matching probes with CDN metadata.
 	type cdnInfo struct {
 		isCDN       bool
@@ -161,32 +379,189 @@ func RunHTTPProbe(ctx context.Context, hosts []models.Host, cfg HTTPProbeConfig)
 		}
 	}
 
-	// Step 8: Run gowitness for screenshots of 2xx responses (optional)
-	if !cfg.SkipScreenshots {
-		var liveURLs []string
-		for _, probe := range probes {
-			if probe.StatusCode >= 200 && probe.StatusCode < 300 {
-				liveURLs = append(liveURLs, probe.URL)
-			}
+	// Step 11: Detect HTTP/2, HTTP/3, gRPC and WebSocket support (optional)
+	if !cfg.SkipProtocols && len(probes) > 0 {
+		urls := make([]string, len(probes))
+		for i, probe := range probes {
+			urls[i] = probe.URL
 		}
-
-		if len(liveURLs) > 0 {
-			fmt.Printf("[*] Running gowitness for %d live services (2xx)...\n", len(liveURLs))
-			if err := tools.RunGowitness(ctx, liveURLs, cfg.ScreenshotDir, cfg.GowitnessThreads, cfg.GowitnessPath); err != nil {
-				// Screenshots are best-effort — warn but do not fail the pipeline
-				fmt.Printf("[!] Warning: gowitness failed: %v\n", err)
-			} else {
-				fmt.Printf("[+] Screenshots saved to %s\n", cfg.ScreenshotDir)
+		support := netprobe.CheckProtocols(ctx, urls, cfg.Protocols)
+		h2, h3, grpc, ws := 0, 0, 0, 0
+		for i := range probes {
+			s := support[probes[i].URL]
+			// Probes found by gRPC detection keep what it established
+			probes[i].HTTP2 = probes[i].HTTP2 || s.HTTP2
+			probes[i].GRPC = probes[i].GRPC || s.GRPC
+			probes[i].HTTP3, probes[i].WebSocket = s.HTTP3, s.WebSocket
+			probes[i].AltSvc, probes[i].QUICVersions = s.AltSvc, s.QUICVersions
+			if probes[i].HTTP2 {
+				h2++
+			}
+			if probes[i].HTTP3 {
+				h3++
+			}
+			if probes[i].GRPC {
+				grpc++
+			}
+			if probes[i].WebSocket != "" {
+				ws++
 			}
 		}
+		fmt.Printf("[*] Protocol check: %d services speak HTTP/2, %d HTTP/3, %d gRPC, %d accept WebSockets\n", h2, h3, grpc, ws)
+	}
+
+	// Step 12: Record allowed methods, TRACE and soft-404 behavior (optional)
+	if cfg.CheckMethods && len(probes) > 0 {
+		checkMethods(ctx, probes, cfg.Methods)
+	}
+
+	// Step 13: Capture screenshots of matching responses (optional)
+	var captures []tools.ChromeCapture
+	if !cfg.SkipScreenshots && cfg.Screenshots.Engine == EngineChromedp {
+		captures = captureWithChrome(ctx, probes, cfg)
+	} else if !cfg.SkipScreenshots {
+		captureWithGowitness(ctx, probes, cfg)
 	}
 
-	// Step 9: Populate result and return
+	// Step 14: Rank the captures for manual review (optional)
+	if !cfg.SkipScreenshots && !cfg.Screenshots.Triage.Skip {
+		result.Triage = triageScreenshots(ctx, probes, captures, cfg)
+	}
+
+	// Step 15: Populate result and return
+	consolidateRedirects(probes)
+	live := make(map[string]bool, len(probes))
+	for _, probe := range probes {
+		if IsLive(probe, cfg.LiveStatus) {
+			live[probe.URL] = true
+		}
+	}
+	for _, probe := range probes {
+		if !live[probe.URL] {
+			continue
+		}
+		result.LiveCount++
+		// A redirect only folds into its target when the target is live too
+		if probe.RedirectsTo == "" || !live[probe.RedirectsTo] {
+			result.UniqueApps++
+		}
+	}
 	result.Probes = probes
-	result.LiveCount = len(probes)
+	result.StatusCounts = CountStatusClasses(probes)
+	result.Clones = findClones(probes)
+	if len(result.Clones) > 0 {
+		fmt.Printf("[+] Content clones: %d pages served identically on different hosts\n", len(result.Clones))
+	}
+	if !cfg.SkipOriginCheck {
+		result.Origins, result.OriginBypass = checkOrigins(ctx, probes, hosts, cfg)
+		if len(result.OriginBypass) > 0 {
+			fmt.Printf("[+] CDN bypass: %d origins serve CDN-fronted hostnames directly\n", len(result.OriginBypass))
+		}
+	}
 	result.ScreenshotDir = cfg.ScreenshotDir
 
-	fmt.Printf("[+] HTTP probe complete: %d live services found\n", result.LiveCount)
+	fmt.Printf("[+] HTTP probe complete: %d live services found (%d unique applications, %d responses)\n",
+		result.LiveCount, result.UniqueApps, len(probes))
 
 	return result, nil
 }
+
+// toProbe converts one httpx result to a probe (Step 6). The response body
+// feeds the content hash and auth classification and is then dropped.
+func toProbe(r tools.HttpxResult, cfg HTTPProbeConfig) models.HTTPProbe {
+	port, err := strconv.Atoi(r.Port)
+	if err != nil {
+		port = 0
+	}
+
+	probe := models.HTTPProbe{
+		URL:           r.URL,
+		StatusCode:    r.StatusCode,
+		Title:         r.Title,
+		ContentLength: r.ContentLength,
+		WebServer:     r.WebServer,
+		Technologies:  r.Technologies,
+		Host:          r.Input,
+		IP:            r.HostIP,
+		Port:          port,
+		Location:      r.Location,
+		Favicon:       r.Favicon,
+	}
+	if !cfg.SkipClones {
+		probe.ContentHash = ContentHash(r.URL, r.HostIP, r.Body)
+	}
+	probe.AuthSurface, probe.AuthEvidence = ClassifyAuth(r.Title, r.Body, r.Location, r.StatusCode)
+	if r.TLS != nil {
+		probe.TLS = &models.TLSCert{
+			SubjectCN: r.TLS.SubjectCN,
+			SubjectDN: r.TLS.SubjectDN,
+			SANs:      r.TLS.SubjectAN,
+			IssuerCN:  r.TLS.IssuerCN,
+			IssuerDN:  r.TLS.IssuerDN,
+			Serial:    r.TLS.Serial,
+			NotBefore: r.TLS.NotBefore,
+			NotAfter:  r.TLS.NotAfter,
+			SHA256:    r.TLS.FingerprintHash.SHA256,
+		}
+	}
+	return probe
+}
+
+// listedRecord returns the filter record of a listed URL: its host and
+// port, with the address and CDN data of the host it resolved to. A URL
+// without a port or scheme, tried on both 80 and 443, has no port field.
+func listedRecord(t urllist.Target, hosts []models.Host) filter.Record {
+	var host models.Host
+	for _, h := range hosts {
+		if h.IP == t.Host || slices.Contains(h.Subdomains, t.Host) {
+			host = h
+			break
+		}
+	}
+	var port models.Port
+	if len(t.Ports) == 1 {
+		port = models.Port{Number: t.Ports[0]}
+		for _, p := range host.Ports {
+			if p.Number == port.Number && p.Protocol != "udp" {
+				port = p
+			}
+		}
+	}
+	r := filter.TargetRecord(t.Host, host, port)
+	if host.IP == "" {
+		delete(r, "ip")
+	}
+	return r
+}
+
+// chunkTargets splits targets into runs of at most size. size <= 0 keeps
+// them in one run.
+func chunkTargets(targets []string, size int) [][]string {
+	if size <= 0 || len(targets) <= size {
+		return [][]string{targets}
+	}
+	var chunks [][]string
+	for start := 0; start < len(targets); start += size {
+		chunks = append(chunks, targets[start:min(start+size, len(targets))])
+	}
+	return chunks
+}
+
+// unanswered returns the targets, in order, that httpx returned no result
+// for, answered being the inputs it did.
+func unanswered(targets []string, answered map[string]bool) []string {
+	var missing []string
+	for _, target := range targets {
+		if !answered[target] {
+			missing = append(missing, target)
+		}
+	}
+	return missing
+}
+
+// mayServeHTTP reports whether a port nmap identified as service could
+// answer httpx. A port nmap could not name, or only saw TLS on, might.
+func mayServeHTTP(service string) bool {
+	s := strings.ToLower(service)
+	return s == "" || s == "unknown" || s == "tcpwrapped" || s == "ssl/unknown" || strings.Contains(s, "http")
+}
@@ -0,0 +1,39 @@
+package httpprobe
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hakim/reconpipe/internal/tools"
+)
+
+// streamByProvider runs httpx over targets in runs of at most cfg.ChunkSize,
+// the targets of each provider with a profile under that profile's limits,
+// and passes every result to fn.
+func streamByProvider(ctx context.Context, targets []string, opts tools.HttpxOptions, cfg HTTPProbeConfig, providerOf func(string) string, fn func(tools.HttpxResult)) error {
+	for _, group := range cfg.Profiles.Split(targets, providerOf) {
+		groupOpts := opts
+		if group.Provider != "" {
+			groupOpts = cfg.Profiles.Httpx(group.Provider, opts)
+			fmt.Printf("[*] Provider profile %s: %d targets at %s\n", group.Provider, len(group.Targets), describeLimits(groupOpts))
+		}
+		for _, chunk := range chunkTargets(group.Targets, cfg.ChunkSize) {
+			if err := tools.StreamHttpx(ctx, chunk, groupOpts, cfg.HttpxPath, fn); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// describeLimits renders the threads, rate and timeout of an httpx run.
+func describeLimits(opts tools.HttpxOptions) string {
+	s := fmt.Sprintf("%d threads", opts.Threads)
+	if opts.RateLimit > 0 {
+		s += fmt.Sprintf(", %d req/s", opts.RateLimit)
+	}
+	if opts.Timeout > 0 {
+		s += fmt.Sprintf(", %s timeout", opts.Timeout)
+	}
+	return s
+}
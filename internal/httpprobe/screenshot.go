@@ -0,0 +1,257 @@
+package httpprobe
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hakim/reconpipe/internal/tools"
+	"github.com/hakim/reconpipe/pkg/models"
+)
+
+// Screenshot engines selectable via ScreenshotOptions.Engine.
+const (
+	EngineGowitness = "gowitness"
+	EngineChromedp  = "chromedp"
+)
+
+// DefaultScreenshotStatus is captured when no status patterns are configured.
+var DefaultScreenshotStatus = []string{"2xx"}
+
+// ScreenshotOptions selects which probes are screenshotted and how. Zero
+// values fall back to the capture engine's defaults.
+type ScreenshotOptions struct {
+	// Engine is EngineGowitness (default) or EngineChromedp.
+	Engine string
+	// StatusCodes lists response codes to capture: exact codes ("403"),
+	// classes ("2xx") or ranges ("300-399"). Empty = DefaultScreenshotStatus.
+	StatusCodes []string
+	FullPage    bool
+	Width       int
+	Height      int
+	Delay       time.Duration
+	// SaveDOM writes the rendered DOM next to each screenshot (chromedp only).
+	SaveDOM bool
+	// BatchSize and Parallel split gowitness captures into runs of
+	// BatchSize URLs, Parallel at a time (gowitness only).
+	BatchSize int
+	Parallel  int
+	// Triage ranks the captures by keywords found on each page.
+	Triage TriageOptions
+}
+
+// DefaultLiveStatus decides which responses count as live services when no
+// patterns are configured. 401/403 are included: an auth wall is still an
+// application worth testing.
+var DefaultLiveStatus = []string{"2xx", "3xx", "401", "403"}
+
+// IsLive reports whether probe counts as a live service under the live
+// status patterns, DefaultLiveStatus when there are none. An API endpoint
+// is live even when its plain HTTP answer is a 404.
+func IsLive(probe models.HTTPProbe, patterns []string) bool {
+	if len(patterns) == 0 {
+		patterns = DefaultLiveStatus
+	}
+	return MatchStatus(probe.StatusCode, patterns) || probe.IsAPIEndpoint()
+}
+
+// StatusClass returns "2xx"-style class names, or "error" for probes without
+// a status code.
+func StatusClass(code int) string {
+	if code < 100 || code > 599 {
+		return "error"
+	}
+	return fmt.Sprintf("%dxx", code/100)
+}
+
+// CountStatusClasses tallies probes by StatusClass.
+func CountStatusClasses(probes []models.HTTPProbe) map[string]int {
+	counts := make(map[string]int)
+	for _, p := range probes {
+		counts[StatusClass(p.StatusCode)]++
+	}
+	return counts
+}
+
+// MatchStatus reports whether code matches any of the patterns. Malformed
+// patterns never match; config validation rejects them up front.
+func MatchStatus(code int, patterns []string) bool {
+	for _, p := range patterns {
+		lo, hi, ok := ParseStatusPattern(p)
+		if ok && code >= lo && code <= hi {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseStatusPattern converts "403", "4xx" or "400-403" into an inclusive
+// code range.
+func ParseStatusPattern(p string) (lo, hi int, ok bool) {
+	p = strings.ToLower(strings.TrimSpace(p))
+
+	if len(p) == 3 && strings.HasSuffix(p, "xx") {
+		class, err := strconv.Atoi(p[:1])
+		if err != nil || class < 1 || class > 5 {
+			return 0, 0, false
+		}
+		return class * 100, class*100 + 99, true
+	}
+
+	if from, to, isRange := strings.Cut(p, "-"); isRange {
+		a, err1 := strconv.Atoi(from)
+		b, err2 := strconv.Atoi(to)
+		if err1 != nil || err2 != nil || a < 100 || b > 599 || a > b {
+			return 0, 0, false
+		}
+		return a, b, true
+	}
+
+	code, err := strconv.Atoi(p)
+	if err != nil || code < 100 || code > 599 {
+		return 0, 0, false
+	}
+	return code, code, true
+}
+
+// shouldCapture reports whether probe gets a screenshot: its status matches
+// statusCodes and it has a page to render. A gRPC service with an empty body
+// only answers API calls.
+func shouldCapture(probe models.HTTPProbe, statusCodes []string) bool {
+	return MatchStatus(probe.StatusCode, statusCodes) && !(probe.GRPC && probe.ContentLength == 0)
+}
+
+// captureWithChrome screenshots matching probes with the native chromedp
+// engine. Each probe's ScreenshotPath is set to its deterministic file, and
+// a title missing from httpx (e.g. set by JavaScript) is filled in from the
+// rendered page. The captures are returned for triage.
+func captureWithChrome(ctx context.Context, probes []models.HTTPProbe, cfg HTTPProbeConfig) []tools.ChromeCapture {
+	statusCodes := cfg.Screenshots.StatusCodes
+	if len(statusCodes) == 0 {
+		statusCodes = DefaultScreenshotStatus
+	}
+
+	var urls []string
+	for _, probe := range probes {
+		if shouldCapture(probe, statusCodes) {
+			urls = append(urls, probe.URL)
+		}
+	}
+	if len(urls) == 0 {
+		return nil
+	}
+
+	fmt.Printf("[*] Capturing %d live services with chromedp (%s)...\n", len(urls), strings.Join(statusCodes, ", "))
+	captures, err := tools.CaptureWithChrome(ctx, urls, cfg.ScreenshotDir, tools.ChromeCaptureOptions{
+		Concurrency: cfg.GowitnessThreads,
+		FullPage:    cfg.Screenshots.FullPage,
+		Width:       cfg.Screenshots.Width,
+		Height:      cfg.Screenshots.Height,
+		Delay:       cfg.Screenshots.Delay,
+		SaveDOM:     cfg.Screenshots.SaveDOM,
+	})
+	if err != nil {
+		// Screenshots are best-effort — keep whatever was captured
+		fmt.Printf("[!] Warning: chromedp capture failed: %v\n", err)
+	}
+
+	byURL := make(map[string]tools.ChromeCapture, len(captures))
+	captured := 0
+	for _, c := range captures {
+		byURL[c.URL] = c
+		if c.Path != "" {
+			captured++
+		}
+	}
+	for i := range probes {
+		c, ok := byURL[probes[i].URL]
+		if !ok {
+			continue
+		}
+		if c.Path != "" {
+			probes[i].ScreenshotPath = c.Path
+			probes[i].ScreenshotStatus = models.ScreenshotCaptured
+		} else {
+			probes[i].ScreenshotStatus = models.ScreenshotFailed
+			probes[i].ScreenshotError = c.Error
+		}
+		if probes[i].Title == "" && c.Title != "" {
+			probes[i].Title = c.Title
+		}
+	}
+
+	fmt.Printf("[+] %d/%d screenshots saved to %s\n", captured, len(urls), cfg.ScreenshotDir)
+	return captures
+}
+
+// captureWithGowitness screenshots matching probes with gowitness and
+// records each probe's screenshot file and capture status.
+func captureWithGowitness(ctx context.Context, probes []models.HTTPProbe, cfg HTTPProbeConfig) {
+	statusCodes := cfg.Screenshots.StatusCodes
+	if len(statusCodes) == 0 {
+		statusCodes = DefaultScreenshotStatus
+	}
+
+	var urls []string
+	for _, probe := range probes {
+		if shouldCapture(probe, statusCodes) {
+			urls = append(urls, probe.URL)
+		}
+	}
+	if len(urls) == 0 {
+		return
+	}
+
+	fmt.Printf("[*] Running gowitness for %d live services (%s)...\n", len(urls), strings.Join(statusCodes, ", "))
+	captures, err := tools.RunGowitness(ctx, urls, cfg.ScreenshotDir, tools.GowitnessOptions{
+		Threads:   cfg.GowitnessThreads,
+		FullPage:  cfg.Screenshots.FullPage,
+		Width:     cfg.Screenshots.Width,
+		Height:    cfg.Screenshots.Height,
+		Delay:     int(cfg.Screenshots.Delay.Round(time.Second) / time.Second),
+		BatchSize: cfg.Screenshots.BatchSize,
+		Parallel:  cfg.Screenshots.Parallel,
+	}, cfg.GowitnessPath)
+	if err != nil {
+		// Screenshots are best-effort — keep whatever was captured
+		fmt.Printf("[!] Warning: gowitness failed: %v\n", err)
+	}
+
+	byURL := make(map[string]tools.GowitnessCapture, len(captures))
+	for _, c := range captures {
+		byURL[c.URL] = c
+	}
+	captured, retried, failed := 0, 0, 0
+	for i := range probes {
+		c, ok := byURL[probes[i].URL]
+		if !ok || c.Attempts == 0 {
+			continue
+		}
+		switch {
+		case c.Path == "":
+			probes[i].ScreenshotStatus = models.ScreenshotFailed
+			probes[i].ScreenshotError = c.Error
+			failed++
+		case c.Attempts > 1:
+			probes[i].ScreenshotPath = c.Path
+			probes[i].ScreenshotStatus = models.ScreenshotRetried
+			captured++
+			retried++
+		default:
+			probes[i].ScreenshotPath = c.Path
+			probes[i].ScreenshotStatus = models.ScreenshotCaptured
+			captured++
+		}
+	}
+
+	fmt.Printf("[+] %d/%d screenshots saved to %s", captured, len(urls), cfg.ScreenshotDir)
+	if retried > 0 {
+		fmt.Printf(" (%d on retry)", retried)
+	}
+	fmt.Println()
+	if failed > 0 {
+		fmt.Printf("[!] %d page(s) could not be captured after a retry; see Failed Screenshots in http-probes.md\n", failed)
+	}
+}
@@ -0,0 +1,120 @@
+package httpprobe
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sort"
+
+	"github.com/hakim/reconpipe/internal/tools"
+	"github.com/hakim/reconpipe/internal/triage"
+	"github.com/hakim/reconpipe/pkg/models"
+)
+
+// TriageOptions controls keyword triage of captured screenshots.
+type TriageOptions struct {
+	// Skip disables triage entirely.
+	Skip bool
+	// SkipOCR stops tesseract from being run on screenshots that have no
+	// DOM text. OCR is otherwise used whenever tesseract is installed.
+	SkipOCR bool
+	// Rules are matched in addition to triage.DefaultRules.
+	Rules []triage.Rule
+}
+
+// triageScreenshots builds a triage list over this run's captures. Chromedp
+// captures carry their rendered text; gowitness only leaves PNGs behind, so
+// those pages are triaged on title alone unless tesseract can read them.
+func triageScreenshots(ctx context.Context, probes []models.HTTPProbe, captures []tools.ChromeCapture, cfg HTTPProbeConfig) []triage.Entry {
+	opts := cfg.Screenshots.Triage
+	ocr := !opts.SkipOCR && ocrAvailable()
+
+	var pages []triage.Page
+	if cfg.Screenshots.Engine == EngineChromedp {
+		byURL := make(map[string]models.HTTPProbe, len(probes))
+		for _, p := range probes {
+			byURL[p.URL] = p
+		}
+		for _, c := range captures {
+			if c.Path == "" {
+				continue
+			}
+			probe := byURL[c.URL]
+			page := triage.Page{
+				URL:        c.URL,
+				StatusCode: probe.StatusCode,
+				Title:      probe.Title,
+				Text:       c.Text,
+				Screenshot: filepath.Base(c.Path),
+			}
+			if page.Text != "" {
+				page.TextSource = "dom"
+			}
+			pages = append(pages, page)
+		}
+	} else {
+		// gowitness names files itself ("https---host-443.png" style); match
+		// them back to probes ignoring punctuation
+		byFile := make(map[string]models.HTTPProbe, len(probes))
+		for _, p := range probes {
+			byFile[tools.ScreenshotKey(tools.ScreenshotFilename(p.URL))] = p
+		}
+		files, _ := filepath.Glob(filepath.Join(cfg.ScreenshotDir, "*.png"))
+		sort.Strings(files)
+		for _, f := range files {
+			name := filepath.Base(f)
+			page := triage.Page{URL: name, Screenshot: name}
+			if probe, ok := byFile[tools.ScreenshotKey(name)]; ok {
+				page.URL = probe.URL
+				page.StatusCode = probe.StatusCode
+				page.Title = probe.Title
+			}
+			pages = append(pages, page)
+		}
+	}
+	if len(pages) == 0 {
+		return nil
+	}
+
+	ocrCount := 0
+	for i := range pages {
+		if pages[i].Text != "" || !ocr || ctx.Err() != nil {
+			continue
+		}
+		text, err := tools.RunTesseract(ctx, filepath.Join(cfg.ScreenshotDir, pages[i].Screenshot), "")
+		if err != nil {
+			fmt.Printf("Warning: OCR failed for %s: %v\n", pages[i].Screenshot, err)
+			continue
+		}
+		if text != "" {
+			pages[i].Text = text
+			pages[i].TextSource = "ocr"
+			ocrCount++
+		}
+	}
+
+	rules := append(append([]triage.Rule{}, triage.DefaultRules...), opts.Rules...)
+	entries := triage.Analyze(pages, rules)
+
+	flagged := 0
+	for _, e := range entries {
+		if len(e.Tags) > 0 {
+			flagged++
+		}
+	}
+	if ocrCount > 0 {
+		fmt.Printf("[+] Screenshot triage: %d/%d pages flagged (%d read by OCR)\n", flagged, len(entries), ocrCount)
+	} else {
+		fmt.Printf("[+] Screenshot triage: %d/%d pages flagged\n", flagged, len(entries))
+	}
+	return entries
+}
+
+// ocrAvailable reports whether tesseract can be run. Fake-tools mode has no
+// OCR fixture, so it is treated as unavailable there.
+func ocrAvailable() bool {
+	if tools.FakeToolsEnabled() {
+		return false
+	}
+	return tools.CheckTool(tools.ToolRequirement{Name: "tesseract", Binary: "tesseract"}).Found
+}
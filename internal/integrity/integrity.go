@@ -0,0 +1,679 @@
+// Package integrity checks a scan directory for completeness and consistency:
+// stage output present and parseable, reports agreeing with the raw data they
+// were built from, screenshots they reference on disk, and the database
+// record in step with the directory. Repair fixes what can be rebuilt from
+// what is left, and leaves a stage whose output is lost to 'scan --resume'.
+package integrity
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/hakim/reconpipe/internal/discovery"
+	"github.com/hakim/reconpipe/internal/httpprobe"
+	"github.com/hakim/reconpipe/internal/pipeline"
+	"github.com/hakim/reconpipe/internal/portscan"
+	"github.com/hakim/reconpipe/internal/report"
+	"github.com/hakim/reconpipe/internal/storage"
+	"github.com/hakim/reconpipe/internal/triage"
+	"github.com/hakim/reconpipe/internal/vulnscan"
+	"github.com/hakim/reconpipe/pkg/models"
+)
+
+// Kind says what an issue concerns.
+type Kind string
+
+const (
+	KindRaw        Kind = "raw"        // stage output missing or unreadable
+	KindReport     Kind = "report"     // report missing or out of step with its raw data
+	KindScreenshot Kind = "screenshot" // screenshot referenced but not on disk
+	KindRecord     Kind = "record"     // database record out of step with the directory
+	KindTempFile   Kind = "temp"       // leftover of an interrupted atomic write
+)
+
+// Issue is one problem found in a scan directory.
+type Issue struct {
+	Kind Kind
+	// Path is the file concerned, relative to the scan directory; empty for
+	// the database record.
+	Path    string
+	Problem string
+	// Repair says what Repair does about the issue; empty when it can't.
+	Repair string
+
+	fix fix
+	arg string // stage name, or target for fixRecord
+}
+
+// fix is the repair applied to an issue.
+type fix int
+
+const (
+	fixNone        fix = iota
+	fixRemoveFile      // delete Path
+	fixSetAside        // rename Path to Path.corrupt and take arg out of StagesRun
+	fixRunConfig       // rewrite run-config.json from the record
+	fixScreenshots     // drop references to Path from http-probes.json
+	fixReports         // regenerate the reports
+	fixDropStage       // take arg out of StagesRun so resume reruns it
+	fixAddStage        // add arg to StagesRun
+	fixInterrupted     // mark a stale running scan interrupted
+	fixRecord          // create a record for the directory
+)
+
+// Store is the part of the scan database Repair uses.
+type Store interface {
+	GetScan(id string) (*models.ScanMeta, error)
+	SaveScan(meta *models.ScanMeta) error
+}
+
+// stageOutputs are the raw files stages write. vulnscan without nuclei and
+// diff without a baseline succeed without writing theirs, so those are not
+// required of a stage recorded as run.
+var stageOutputs = []struct {
+	stage, file string
+	required    bool
+}{
+	{"discover", "subdomains.json", true},
+	{"portscan", "ports.json", true},
+	{"probe", "http-probes.json", true},
+	{"vulnscan", "vulns.json", false},
+	{"vulnscan", "nuclei-output.jsonl", false},
+	{"diff", "diff.json", false},
+}
+
+// stageOf returns the stage that writes the raw file name, or "".
+func stageOf(name string) string {
+	for _, o := range stageOutputs {
+		if o.file == name {
+			return o.stage
+		}
+	}
+	return ""
+}
+
+// reportCounts are the headline counts of reports, each checked against the
+// raw file the report was built from.
+var reportCounts = []struct {
+	report, raw, label string
+	count              func(data []byte) (int, error)
+}{
+	{"subdomains.md", "subdomains.json", "Total discovered", func(data []byte) (int, error) {
+		var r discovery.DiscoveryResult
+		err := json.Unmarshal(data, &r)
+		return r.TotalFound, err
+	}},
+	{"ports.md", "ports.json", "Total hosts", func(data []byte) (int, error) {
+		var r portscan.PortScanResult
+		err := json.Unmarshal(data, &r)
+		return len(r.Hosts), err
+	}},
+	{"ports.md", "ports.json", "Open ports", func(data []byte) (int, error) {
+		var r portscan.PortScanResult
+		err := json.Unmarshal(data, &r)
+		return r.TotalPorts, err
+	}},
+	{"http-probes.md", "http-probes.json", "Live services", func(data []byte) (int, error) {
+		var r httpprobe.HTTPProbeResult
+		err := json.Unmarshal(data, &r)
+		return r.LiveCount, err
+	}},
+	{"vulns.md", "vulns.json", "Total findings", func(data []byte) (int, error) {
+		var r vulnscan.VulnScanResult
+		err := json.Unmarshal(data, &r)
+		return r.TotalCount, err
+	}},
+}
+
+// checker accumulates the issues of one scan directory.
+type checker struct {
+	scanDir string
+	meta    *models.ScanMeta
+	issues  []Issue
+	present map[string]bool // raw files found, by name
+	corrupt map[string]bool // raw files that do not parse, by name
+}
+
+// Check inspects scanDir against meta, its database record (nil when it has
+// none), and returns the issues found, most fundamental first.
+func Check(scanDir string, meta *models.ScanMeta) ([]Issue, error) {
+	info, err := os.Stat(scanDir)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", scanDir)
+	}
+
+	c := &checker{
+		scanDir: scanDir,
+		meta:    meta,
+		present: make(map[string]bool),
+		corrupt: make(map[string]bool),
+	}
+	if err := c.checkTempFiles(); err != nil {
+		return nil, err
+	}
+	if err := c.checkRawFiles(); err != nil {
+		return nil, err
+	}
+	c.checkRecord()
+	if err := c.checkReports(); err != nil {
+		return nil, err
+	}
+	if err := c.checkScreenshots(); err != nil {
+		return nil, err
+	}
+	return c.issues, nil
+}
+
+// add records an issue. Repairs that regenerate the reports wait until no
+// raw file they are built from is corrupt, since regenerating would fail.
+func (c *checker) add(issue Issue) {
+	if issue.fix == fixReports || issue.fix == fixScreenshots {
+		if blocker := c.corruptReportInput(); blocker != "" {
+			issue.Repair = ""
+			issue.Problem += fmt.Sprintf(" (repairable once %s is fixed)", blocker)
+			issue.fix = fixNone
+		}
+	}
+	c.issues = append(c.issues, issue)
+}
+
+// corruptReportInput returns a corrupt raw file reports are built from, or "".
+func (c *checker) corruptReportInput() string {
+	for _, name := range slices.Sorted(maps.Keys(c.corrupt)) {
+		if name != "checksums.json" && name != storage.RunConfigFile && strings.HasSuffix(name, ".json") && c.present[name] {
+			return name
+		}
+	}
+	return ""
+}
+
+// rel returns path relative to the scan directory, for display.
+func (c *checker) rel(path string) string {
+	if r, err := filepath.Rel(c.scanDir, path); err == nil {
+		return filepath.ToSlash(r)
+	}
+	return path
+}
+
+// checkTempFiles finds the temporary files of atomic writes that never
+// finished.
+func (c *checker) checkTempFiles() error {
+	return filepath.WalkDir(c.scanDir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() && strings.HasPrefix(d.Name(), ".") && strings.HasSuffix(d.Name(), ".tmp") {
+			c.add(Issue{Kind: KindTempFile, Path: c.rel(p), Problem: "left by an interrupted write", Repair: "remove it", fix: fixRemoveFile})
+		}
+		return nil
+	})
+}
+
+// checkRawFiles parses every raw file, and summary.json.
+func (c *checker) checkRawFiles() error {
+	var paths []string
+	for _, pattern := range []string{"*.json", "*.jsonl"} {
+		matches, err := filepath.Glob(filepath.Join(storage.RawDir(c.scanDir), pattern))
+		if err != nil {
+			return err
+		}
+		paths = append(paths, matches...)
+	}
+	if _, err := os.Stat(filepath.Join(c.scanDir, pipeline.SummaryFile)); err == nil {
+		paths = append(paths, filepath.Join(c.scanDir, pipeline.SummaryFile))
+	}
+
+	for _, path := range paths {
+		name := filepath.Base(path)
+		if filepath.Dir(path) == storage.RawDir(c.scanDir) {
+			c.present[name] = true
+		}
+		parseErr, err := parseFile(path)
+		if err != nil {
+			return err
+		}
+		if parseErr == nil {
+			continue
+		}
+		c.corrupt[name] = true
+
+		issue := Issue{Kind: KindRaw, Path: c.rel(path), Problem: "does not parse: " + parseErr.Error()}
+		stage := stageOf(name)
+		switch {
+		case stage != "" && c.meta != nil && slices.Contains(c.meta.StagesRun, stage):
+			issue.Repair = fmt.Sprintf("set it aside as %s.corrupt and mark %s not run, so 'scan --resume' reruns it", name, stage)
+			issue.fix, issue.arg = fixSetAside, stage
+		case stage != "":
+			issue.Repair = fmt.Sprintf("set it aside as %s.corrupt; running %s again rewrites it", name, stage)
+			issue.fix, issue.arg = fixSetAside, stage
+		case name == "checksums.json":
+			issue.Repair = "remove it; the next 'report' rebuilds every report"
+			issue.fix = fixRemoveFile
+		case name == storage.RunConfigFile && c.meta != nil && c.meta.RunConfig != nil:
+			issue.Repair = "rewrite it from the database record"
+			issue.fix = fixRunConfig
+		}
+		c.add(issue)
+	}
+	return nil
+}
+
+// parseFile returns the error decoding the JSON (or JSON lines) file at
+// path, or nil when it parses. err is set when it can't be read at all.
+func parseFile(path string) (parseErr, err error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasSuffix(path, ".jsonl") {
+		var v any
+		return json.Unmarshal(data, &v), nil
+	}
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	sc.Buffer(make([]byte, 0, 64*1024), len(data)+1)
+	for line := 1; sc.Scan(); line++ {
+		if len(bytes.TrimSpace(sc.Bytes())) == 0 {
+			continue
+		}
+		var v any
+		if err := json.Unmarshal(sc.Bytes(), &v); err != nil {
+			return fmt.Errorf("line %d: %w", line, err), nil
+		}
+	}
+	return sc.Err(), nil
+}
+
+// checkRecord compares the database record with the stage output on disk.
+func (c *checker) checkRecord() {
+	if c.meta == nil {
+		issue := Issue{Kind: KindRecord, Problem: "no database record for this directory"}
+		if target := c.target(); target != "" {
+			issue.Repair = fmt.Sprintf("recreate it from the directory, as a scan of %s", target)
+			issue.fix, issue.arg = fixRecord, target
+		}
+		c.add(issue)
+		return
+	}
+
+	// A stage's first output is the one that shows it ran; vulnscan writes
+	// nuclei-output.jsonl after vulns.json
+	checked := make(map[string]bool)
+	for _, o := range stageOutputs {
+		recorded := slices.Contains(c.meta.StagesRun, o.stage)
+		path := storage.RawPath(c.scanDir, o.file)
+		first := !checked[o.stage]
+		checked[o.stage] = true
+		switch {
+		case recorded && o.required && !c.present[o.file]:
+			c.add(Issue{
+				Kind:    KindRaw,
+				Path:    c.rel(path),
+				Problem: fmt.Sprintf("missing, though the record says %s ran", o.stage),
+				Repair:  fmt.Sprintf("mark %s not run, so 'scan --resume' reruns it", o.stage),
+				fix:     fixDropStage,
+				arg:     o.stage,
+			})
+		case first && !recorded && c.present[o.file] && !c.corrupt[o.file]:
+			c.add(Issue{
+				Kind:    KindRecord,
+				Problem: fmt.Sprintf("%s is not recorded as run, though %s holds its output", o.stage, c.rel(path)),
+				Repair:  fmt.Sprintf("record %s as run", o.stage),
+				fix:     fixAddStage,
+				arg:     o.stage,
+			})
+		}
+	}
+
+	// The database can't be opened while a scan holds it, so a scan still
+	// marked running here died without saying so.
+	if c.meta.Status == models.StatusRunning {
+		c.add(Issue{Kind: KindRecord, Problem: "marked running, but no scan holds the database", Repair: "mark it interrupted", fix: fixInterrupted})
+	}
+}
+
+// target returns the target named in the scan's stage output, or "".
+func (c *checker) target() string {
+	for _, o := range stageOutputs {
+		if c.corrupt[o.file] || !c.present[o.file] || strings.HasSuffix(o.file, ".jsonl") {
+			continue
+		}
+		data, err := os.ReadFile(storage.RawPath(c.scanDir, o.file))
+		if err != nil {
+			continue
+		}
+		var v struct {
+			Target string `json:"target"`
+		}
+		if json.Unmarshal(data, &v) == nil && v.Target != "" {
+			return v.Target
+		}
+	}
+	return ""
+}
+
+// checkReports finds missing reports and reports whose headline counts
+// disagree with their raw data.
+func (c *checker) checkReports() error {
+	for _, name := range report.ExpectedReports(c.scanDir) {
+		path := storage.ReportPath(c.scanDir, name)
+		if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+			c.add(Issue{Kind: KindReport, Path: c.rel(path), Problem: "missing", Repair: "regenerate the reports", fix: fixReports})
+		}
+	}
+
+	for _, rc := range reportCounts {
+		if c.corrupt[rc.raw] || !c.present[rc.raw] {
+			continue
+		}
+		path := storage.ReportPath(c.scanDir, rc.report)
+		md, err := os.ReadFile(path)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		m := regexp.MustCompile(`\*\*` + regexp.QuoteMeta(rc.label) + `:\*\* (\d+)`).FindSubmatch(md)
+		if m == nil {
+			continue // written before the count was reported
+		}
+		shown, _ := strconv.Atoi(string(m[1]))
+
+		data, err := os.ReadFile(storage.RawPath(c.scanDir, rc.raw))
+		if err != nil {
+			return err
+		}
+		want, err := rc.count(data)
+		if err != nil {
+			continue // the raw file does not have the expected shape; nothing to compare
+		}
+		if shown != want {
+			c.add(Issue{
+				Kind:    KindReport,
+				Path:    c.rel(path),
+				Problem: fmt.Sprintf("shows %s %d, but %s has %d", strings.ToLower(rc.label), shown, c.rel(storage.RawPath(c.scanDir, rc.raw)), want),
+				Repair:  "regenerate the reports",
+				fix:     fixReports,
+			})
+		}
+	}
+	return nil
+}
+
+// screenshotLink matches links from a report to a screenshot file.
+var screenshotLink = regexp.MustCompile(`\]\(([^)\s]+)\)`)
+
+// checkScreenshots finds screenshots referenced by http-probes.json or
+// linked from a report that are not in the screenshots directory.
+func (c *checker) checkScreenshots() error {
+	shotsDir := storage.ScreenshotsDir(c.scanDir)
+	onDisk := func(name string) bool {
+		_, err := os.Stat(filepath.Join(shotsDir, name))
+		return err == nil
+	}
+	flagged := make(map[string]bool)
+
+	// Step 1: References in the probe results, dropped on repair
+	if c.present["http-probes.json"] && !c.corrupt["http-probes.json"] {
+		var r httpprobe.HTTPProbeResult
+		data, err := os.ReadFile(storage.RawPath(c.scanDir, "http-probes.json"))
+		if err != nil {
+			return err
+		}
+		if json.Unmarshal(data, &r) == nil {
+			var names []string
+			for _, p := range r.Probes {
+				if p.ScreenshotPath != "" {
+					names = append(names, filepath.Base(p.ScreenshotPath))
+				}
+			}
+			for _, e := range r.Triage {
+				if e.Screenshot != "" {
+					names = append(names, e.Screenshot)
+				}
+			}
+			for _, name := range names {
+				if flagged[name] || onDisk(name) {
+					continue
+				}
+				flagged[name] = true
+				c.add(Issue{
+					Kind:    KindScreenshot,
+					Path:    c.rel(filepath.Join(shotsDir, name)),
+					Problem: "referenced by http-probes.json but not on disk",
+					Repair:  "drop the reference and regenerate the reports",
+					fix:     fixScreenshots,
+					arg:     name,
+				})
+			}
+		}
+	}
+
+	// Step 2: Links left in reports, which regenerating drops when the raw
+	// data no longer references them
+	prefix := storage.ScreenshotLink("")
+	reports, err := filepath.Glob(filepath.Join(storage.ReportsDir(c.scanDir), "*.md"))
+	if err != nil {
+		return err
+	}
+	for _, path := range reports {
+		md, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		for _, m := range screenshotLink.FindAllSubmatch(md, -1) {
+			name, ok := strings.CutPrefix(string(m[1]), prefix)
+			if !ok || flagged[name] || onDisk(name) {
+				continue
+			}
+			flagged[name] = true
+			c.add(Issue{
+				Kind:    KindScreenshot,
+				Path:    c.rel(filepath.Join(shotsDir, name)),
+				Problem: fmt.Sprintf("linked from %s but not on disk", c.rel(path)),
+				Repair:  "regenerate the reports",
+				fix:     fixReports,
+			})
+		}
+	}
+	return nil
+}
+
+// Repair fixes the repairable issues of scanDir, found by Check against
+// meta, and saves the record to store when it changed. It returns the
+// record, which is new when the directory had none, and the issues fixed.
+// version is the running reconpipe version, for the report checksums.
+func Repair(scanDir string, meta *models.ScanMeta, issues []Issue, store Store, version string) (*models.ScanMeta, []Issue, error) {
+	var fixed []Issue
+	var missingShots []string
+	regenerate, recordChanged := false, false
+
+	// Step 1: Files
+	for _, issue := range issues {
+		switch issue.fix {
+		case fixRemoveFile:
+			if err := os.Remove(filepath.Join(scanDir, filepath.FromSlash(issue.Path))); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return meta, fixed, err
+			}
+			fixed = append(fixed, issue)
+		case fixSetAside:
+			path := filepath.Join(scanDir, filepath.FromSlash(issue.Path))
+			if err := os.Rename(path, path+".corrupt"); err != nil {
+				return meta, fixed, err
+			}
+			fixed = append(fixed, issue)
+		case fixRunConfig:
+			if err := storage.WriteRunConfig(scanDir, meta.RunConfig); err != nil {
+				return meta, fixed, err
+			}
+			fixed = append(fixed, issue)
+		case fixScreenshots:
+			missingShots = append(missingShots, issue.arg)
+			regenerate = true
+		case fixReports:
+			regenerate = true
+		}
+	}
+
+	// Step 2: Raw data, then the reports built from it
+	if len(missingShots) > 0 {
+		if err := dropScreenshots(scanDir, missingShots); err != nil {
+			return meta, fixed, fmt.Errorf("updating http-probes.json: %w", err)
+		}
+	}
+	if regenerate {
+		written, err := report.RegenerateReports(scanDir)
+		if err != nil {
+			return meta, fixed, fmt.Errorf("regenerating reports: %w", err)
+		}
+		names := make([]string, len(written))
+		for i, path := range written {
+			names[i] = filepath.Base(path)
+		}
+		if err := report.RecordReports(scanDir, version, names...); err != nil {
+			return meta, fixed, fmt.Errorf("recording report checksums: %w", err)
+		}
+		for _, issue := range issues {
+			if issue.fix == fixScreenshots || issue.fix == fixReports {
+				fixed = append(fixed, issue)
+			}
+		}
+	}
+
+	// Step 3: The database record
+	for _, issue := range issues {
+		switch issue.fix {
+		case fixRecord:
+			var err error
+			if meta, err = recoverRecord(scanDir, issue.arg, store); err != nil {
+				return nil, fixed, err
+			}
+		case fixSetAside:
+			// Already counted with the files; the record changes only when
+			// the stage was recorded as run
+			if meta != nil && slices.Contains(meta.StagesRun, issue.arg) {
+				meta.StagesRun = slices.DeleteFunc(meta.StagesRun, func(s string) bool { return s == issue.arg })
+				meta.Status = models.StatusInterrupted
+				recordChanged = true
+			}
+			continue
+		case fixDropStage:
+			meta.StagesRun = slices.DeleteFunc(meta.StagesRun, func(s string) bool { return s == issue.arg })
+			meta.Status = models.StatusInterrupted
+		case fixAddStage:
+			if !slices.Contains(meta.StagesRun, issue.arg) {
+				meta.StagesRun = append(meta.StagesRun, issue.arg)
+			}
+		case fixInterrupted:
+			meta.Status = models.StatusInterrupted
+		default:
+			continue
+		}
+		recordChanged = true
+		fixed = append(fixed, issue)
+	}
+	if recordChanged {
+		if err := store.SaveScan(meta); err != nil {
+			return meta, fixed, fmt.Errorf("saving scan record: %w", err)
+		}
+	}
+	return meta, fixed, nil
+}
+
+// dropScreenshots removes the references to the named screenshot files
+// from http-probes.json.
+func dropScreenshots(scanDir string, names []string) error {
+	path := storage.RawPath(scanDir, "http-probes.json")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var r httpprobe.HTTPProbeResult
+	if err := json.Unmarshal(data, &r); err != nil {
+		return err
+	}
+	for i := range r.Probes {
+		if p := r.Probes[i].ScreenshotPath; p != "" && slices.Contains(names, filepath.Base(p)) {
+			r.Probes[i].ScreenshotPath = ""
+			r.Probes[i].ScreenshotStatus = models.ScreenshotFailed
+			r.Probes[i].ScreenshotError = "screenshot file missing"
+		}
+	}
+	r.Triage = slices.DeleteFunc(r.Triage, func(e triage.Entry) bool {
+		return slices.Contains(names, e.Screenshot)
+	})
+	data, err = json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	return storage.WriteFileAtomic(path, data, 0644)
+}
+
+// recoverRecord builds a record for a scan directory that has none. The
+// run's summary.json, when the pipeline got that far, supplies its ID,
+// status and times; otherwise it is an interrupted scan of target with the
+// stages whose output the directory holds.
+func recoverRecord(scanDir, target string, store Store) (*models.ScanMeta, error) {
+	meta := models.NewScan(target).ScanMeta
+	meta.ScanDir = scanDir
+	meta.Status = models.StatusInterrupted
+	if rc, err := storage.ReadRunConfig(scanDir); err == nil {
+		meta.RunConfig = rc
+	}
+	// run-config.json is written as the scan starts
+	if info, err := os.Stat(storage.RawPath(scanDir, storage.RunConfigFile)); err == nil {
+		meta.StartedAt = info.ModTime()
+	} else if info, err := os.Stat(scanDir); err == nil {
+		meta.StartedAt = info.ModTime()
+	}
+
+	var summary pipeline.ScanSummary
+	if data, err := os.ReadFile(filepath.Join(scanDir, pipeline.SummaryFile)); err == nil && json.Unmarshal(data, &summary) == nil {
+		// Keep the scan's ID unless another record has taken it
+		if summary.ScanID != "" {
+			existing, err := store.GetScan(summary.ScanID)
+			if err != nil {
+				return nil, err
+			}
+			if existing == nil {
+				meta.ID = summary.ScanID
+			}
+		}
+		meta.Operator = summary.Operator
+		if !summary.StartedAt.IsZero() {
+			meta.StartedAt = summary.StartedAt
+		}
+		switch summary.Status {
+		case "complete":
+			meta.Status = models.StatusComplete
+			finished := summary.FinishedAt
+			meta.CompletedAt = &finished
+		case "partial":
+			meta.Status = models.StatusFailed
+		}
+	}
+
+	for _, o := range stageOutputs {
+		if slices.Contains(meta.StagesRun, o.stage) {
+			continue
+		}
+		if parseErr, err := parseFile(storage.RawPath(scanDir, o.file)); err == nil && parseErr == nil {
+			meta.StagesRun = append(meta.StagesRun, o.stage)
+		}
+	}
+	return &meta, nil
+}
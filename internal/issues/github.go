@@ -0,0 +1,157 @@
+package issues
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// maxPages caps how many pages of open issues a tracker lists.
+const maxPages = 20
+
+// GitHub files issues in a GitHub or GitHub Enterprise repository through the
+// REST API.
+type GitHub struct {
+	URL    string // API base, default https://api.github.com
+	Repo   string // owner/name
+	Token  string
+	Client *http.Client
+}
+
+// Name implements Tracker.
+func (g *GitHub) Name() string { return "github:" + g.Repo }
+
+// githubIssue is the part of a GitHub issue this tracker reads.
+type githubIssue struct {
+	Number      int             `json:"number"`
+	Title       string          `json:"title"`
+	Body        string          `json:"body"`
+	HTMLURL     string          `json:"html_url"`
+	PullRequest json.RawMessage `json:"pull_request"`
+}
+
+func (i githubIssue) issue() Issue {
+	return Issue{Number: i.Number, Title: i.Title, Body: i.Body, URL: i.HTMLURL}
+}
+
+// ListOpen implements Tracker. The issues endpoint also returns pull
+// requests, which are skipped.
+func (g *GitHub) ListOpen(ctx context.Context, labels []string) ([]Issue, error) {
+	var out []Issue
+	for page := 1; page <= maxPages; page++ {
+		q := url.Values{
+			"state":    {"open"},
+			"labels":   {strings.Join(labels, ",")},
+			"per_page": {"100"},
+			"page":     {fmt.Sprint(page)},
+		}
+		var batch []githubIssue
+		if err := g.do(ctx, http.MethodGet, "/issues?"+q.Encode(), nil, &batch); err != nil {
+			return nil, err
+		}
+		for _, i := range batch {
+			if len(i.PullRequest) == 0 {
+				out = append(out, i.issue())
+			}
+		}
+		if len(batch) < 100 {
+			break
+		}
+	}
+	return out, nil
+}
+
+// Create implements Tracker.
+func (g *GitHub) Create(ctx context.Context, title, body string, labels []string) (Issue, error) {
+	var created githubIssue
+	err := g.do(ctx, http.MethodPost, "/issues", map[string]any{
+		"title":  title,
+		"body":   body,
+		"labels": labels,
+	}, &created)
+	return created.issue(), err
+}
+
+// Close implements Tracker.
+func (g *GitHub) Close(ctx context.Context, issue Issue, comment string) error {
+	path := fmt.Sprintf("/issues/%d", issue.Number)
+	if err := g.do(ctx, http.MethodPost, path+"/comments", map[string]string{"body": comment}, nil); err != nil {
+		return err
+	}
+	return g.do(ctx, http.MethodPatch, path, map[string]string{"state": "closed", "state_reason": "completed"}, nil)
+}
+
+// do sends one request for a path under the repository.
+func (g *GitHub) do(ctx context.Context, method, path string, in, out any) error {
+	base := g.URL
+	if base == "" {
+		base = "https://api.github.com"
+	}
+	endpoint := strings.TrimRight(base, "/") + "/repos/" + g.Repo + path
+
+	req, err := newJSONRequest(ctx, method, endpoint, in)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+	req.Header.Set("Authorization", "Bearer "+g.Token)
+	return send(g.Client, req, out)
+}
+
+// newJSONRequest builds a request with in encoded as its JSON body, if any.
+func newJSONRequest(ctx context.Context, method, endpoint string, in any) (*http.Request, error) {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return nil, fmt.Errorf("encoding request: %w", err)
+		}
+		body = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, body)
+	if err != nil {
+		return nil, fmt.Errorf("building request: %w", err)
+	}
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	return req, nil
+}
+
+// send performs req, failing on non-2xx statuses, and decodes the response
+// into out when it is non-nil.
+func send(client *http.Client, req *http.Request, out any) error {
+	if client == nil {
+		client = &http.Client{Timeout: 30 * time.Second}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s %s: %w", req.Method, req.URL.Redacted(), err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 10<<20))
+	if err != nil {
+		return fmt.Errorf("reading response: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg := strings.TrimSpace(string(data))
+		if len(msg) > 512 {
+			msg = msg[:512] + "..."
+		}
+		return fmt.Errorf("%s %s returned %s: %s", req.Method, req.URL.Path, resp.Status, msg)
+	}
+	if out != nil {
+		if err := json.Unmarshal(data, out); err != nil {
+			return fmt.Errorf("parsing response: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package issues
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// GitLab files issues in a GitLab.com or self-managed GitLab project through
+// the v4 REST API.
+type GitLab struct {
+	URL     string // instance URL, default https://gitlab.com
+	Project string // path such as group/project, or the numeric project ID
+	Token   string // personal, group or project access token with api scope
+	Client  *http.Client
+}
+
+// Name implements Tracker.
+func (g *GitLab) Name() string { return "gitlab:" + g.Project }
+
+// gitlabIssue is the part of a GitLab issue this tracker reads.
+type gitlabIssue struct {
+	IID         int    `json:"iid"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	WebURL      string `json:"web_url"`
+}
+
+func (i gitlabIssue) issue() Issue {
+	return Issue{Number: i.IID, Title: i.Title, Body: i.Description, URL: i.WebURL}
+}
+
+// ListOpen implements Tracker.
+func (g *GitLab) ListOpen(ctx context.Context, labels []string) ([]Issue, error) {
+	var out []Issue
+	for page := 1; page <= maxPages; page++ {
+		q := url.Values{
+			"state":    {"opened"},
+			"labels":   {strings.Join(labels, ",")},
+			"per_page": {"100"},
+			"page":     {fmt.Sprint(page)},
+		}
+		var batch []gitlabIssue
+		if err := g.do(ctx, http.MethodGet, "/issues?"+q.Encode(), nil, &batch); err != nil {
+			return nil, err
+		}
+		for _, i := range batch {
+			out = append(out, i.issue())
+		}
+		if len(batch) < 100 {
+			break
+		}
+	}
+	return out, nil
+}
+
+// Create implements Tracker.
+func (g *GitLab) Create(ctx context.Context, title, body string, labels []string) (Issue, error) {
+	var created gitlabIssue
+	err := g.do(ctx, http.MethodPost, "/issues", map[string]string{
+		"title":       title,
+		"description": body,
+		"labels":      strings.Join(labels, ","),
+	}, &created)
+	return created.issue(), err
+}
+
+// Close implements Tracker.
+func (g *GitLab) Close(ctx context.Context, issue Issue, comment string) error {
+	path := fmt.Sprintf("/issues/%d", issue.Number)
+	if err := g.do(ctx, http.MethodPost, path+"/notes", map[string]string{"body": comment}, nil); err != nil {
+		return err
+	}
+	return g.do(ctx, http.MethodPut, path, map[string]string{"state_event": "close"}, nil)
+}
+
+// do sends one request for a path under the project.
+func (g *GitLab) do(ctx context.Context, method, path string, in, out any) error {
+	base := g.URL
+	if base == "" {
+		base = "https://gitlab.com"
+	}
+	endpoint := strings.TrimRight(base, "/") + "/api/v4/projects/" + url.PathEscape(g.Project) + path
+
+	req, err := newJSONRequest(ctx, method, endpoint, in)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("PRIVATE-TOKEN", g.Token)
+	return send(g.Client, req, out)
+}
@@ -0,0 +1,210 @@
+// Package issues keeps an issue tracker in step with dangling DNS findings.
+// Each newly dangling subdomain gets one issue in a GitHub or GitLab project;
+// when a later diff reports the subdomain resolved, its issue is closed with
+// a comment. Issues carry a hidden marker naming the subdomain, so running
+// the diff again never opens duplicates.
+package issues
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/hakim/reconpipe/internal/config"
+	"github.com/hakim/reconpipe/internal/diff"
+	"github.com/hakim/reconpipe/internal/takeover"
+	"github.com/hakim/reconpipe/pkg/models"
+)
+
+// DefaultLabels are applied to new issues, and used to find open ones, when
+// the config sets no labels.
+var DefaultLabels = []string{"reconpipe", "dangling-dns"}
+
+// Issue is an issue as returned by a Tracker.
+type Issue struct {
+	Number int // GitHub number or GitLab iid
+	Title  string
+	Body   string
+	URL    string
+}
+
+// Tracker is an issue tracker project.
+type Tracker interface {
+	Name() string
+	// ListOpen returns the open issues carrying all of labels.
+	ListOpen(ctx context.Context, labels []string) ([]Issue, error)
+	Create(ctx context.Context, title, body string, labels []string) (Issue, error)
+	// Close comments on the issue, then closes it.
+	Close(ctx context.Context, issue Issue, comment string) error
+}
+
+// FromConfig builds the configured tracker, or returns nil when the
+// integration is disabled.
+func FromConfig(c config.IssuesConfig) (Tracker, error) {
+	timeout := 30 * time.Second
+	if c.Timeout != "" {
+		d, err := time.ParseDuration(c.Timeout)
+		if err != nil {
+			return nil, fmt.Errorf("issues: invalid timeout %q: %w", c.Timeout, err)
+		}
+		timeout = d
+	}
+	client := &http.Client{Timeout: timeout}
+	token := os.ExpandEnv(c.Token)
+
+	switch c.Provider {
+	case "":
+		return nil, nil
+	case "github":
+		return &GitHub{URL: c.URL, Repo: c.Repo, Token: token, Client: client}, nil
+	case "gitlab":
+		return &GitLab{URL: c.URL, Project: c.Repo, Token: token, Client: client}, nil
+	default:
+		return nil, fmt.Errorf("issues: unknown provider %q", c.Provider)
+	}
+}
+
+// SyncResult reports what SyncDangling changed.
+type SyncResult struct {
+	Opened  []Issue
+	Closed  []Issue
+	Skipped int // newly dangling subdomains that already had an open issue
+}
+
+// markerPattern finds the subdomain marker in an issue body.
+var markerPattern = regexp.MustCompile(`<!-- reconpipe:dangling-dns:([^ ]+) -->`)
+
+// marker is the hidden comment identifying the subdomain an issue tracks.
+func marker(subdomain string) string {
+	return "<!-- reconpipe:dangling-dns:" + subdomain + " -->"
+}
+
+// Title is the title of the issue tracking a dangling subdomain.
+func Title(subdomain string) string {
+	return "Dangling DNS: " + subdomain
+}
+
+// SyncDangling opens an issue for each subdomain in result.NewlyDangling that
+// has none open yet, and closes the open issue of each subdomain in
+// result.ResolvedDangling. Every subdomain is attempted; the returned error
+// joins the failures.
+func SyncDangling(ctx context.Context, t Tracker, labels []string, scan *models.ScanMeta, result *diff.DiffResult) (*SyncResult, error) {
+	if len(labels) == 0 {
+		labels = DefaultLabels
+	}
+	out := &SyncResult{}
+	if len(result.NewlyDangling) == 0 && len(result.ResolvedDangling) == 0 {
+		return out, nil
+	}
+
+	open, err := t.ListOpen(ctx, labels)
+	if err != nil {
+		return out, fmt.Errorf("listing open issues: %w", err)
+	}
+	bySubdomain := make(map[string]Issue, len(open))
+	for _, issue := range open {
+		if m := markerPattern.FindStringSubmatch(issue.Body); m != nil {
+			bySubdomain[m[1]] = issue
+		} else if name, ok := strings.CutPrefix(issue.Title, Title("")); ok {
+			bySubdomain[name] = issue
+		}
+	}
+
+	var errs []error
+	for _, sub := range result.NewlyDangling {
+		if _, ok := bySubdomain[sub.Name]; ok {
+			out.Skipped++
+			continue
+		}
+		issue, err := t.Create(ctx, Title(sub.Name), issueBody(sub, scan), labels)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("opening issue for %s: %w", sub.Name, err))
+			continue
+		}
+		bySubdomain[sub.Name] = issue
+		out.Opened = append(out.Opened, issue)
+	}
+
+	removed := make(map[string]bool, len(result.RemovedSubdomains))
+	for _, sub := range result.RemovedSubdomains {
+		removed[sub.Name] = true
+	}
+	for _, sub := range result.ResolvedDangling {
+		issue, ok := bySubdomain[sub.Name]
+		if !ok {
+			continue
+		}
+		if err := t.Close(ctx, issue, closeComment(sub.Name, removed[sub.Name], scan)); err != nil {
+			errs = append(errs, fmt.Errorf("closing issue for %s: %w", sub.Name, err))
+			continue
+		}
+		out.Closed = append(out.Closed, issue)
+	}
+
+	return out, errors.Join(errs...)
+}
+
+// issueBody describes a dangling subdomain and what to do about it.
+func issueBody(sub models.Subdomain, scan *models.ScanMeta) string {
+	var cnames []string
+	for _, r := range sub.DNSRecords {
+		if r.Type == models.DNSRecordCNAME {
+			cnames = append(cnames, r.Value)
+		}
+	}
+	assessment := takeover.Assess(sub)
+
+	var b strings.Builder
+	b.WriteString(marker(sub.Name) + "\n\n")
+	fmt.Fprintf(&b, "`%s` no longer resolves to a live service.\n\n", sub.Name)
+	b.WriteString("| | |\n|---|---|\n")
+	fmt.Fprintf(&b, "| Target | %s |\n", scan.Target)
+	fmt.Fprintf(&b, "| Detected | %s |\n", scan.StartedAt.UTC().Format("2006-01-02 15:04 UTC"))
+	if scan.ID != "" {
+		fmt.Fprintf(&b, "| Scan ID | `%s` |\n", scan.ID)
+	}
+	if len(cnames) > 0 {
+		fmt.Fprintf(&b, "| CNAME | `%s` |\n", strings.Join(cnames, "`, `"))
+		fmt.Fprintf(&b, "| Provider | %s |\n", assessment.Risk())
+	}
+	fmt.Fprintf(&b, "| Severity | %s |\n", assessment.Severity)
+	b.WriteString("\n")
+
+	if len(cnames) > 0 {
+		switch assessment.Claimability {
+		case takeover.Claimable:
+			b.WriteString("**Takeover risk:** the CNAME points at a resource on a provider where anyone can claim it. " +
+				"Remove the record now, or reclaim the resource it points to.\n")
+		case takeover.NotClaimable:
+			b.WriteString("The CNAME points at a provider that verifies ownership, so a takeover is unlikely. " +
+				"Remove the record to clean up.\n")
+		default:
+			b.WriteString("**Takeover risk:** the CNAME points at a resource that may be claimable by anyone. " +
+				"Remove the record, or reclaim the resource it points to.\n")
+		}
+	} else {
+		b.WriteString("Stale DNS entry with no CNAME. Remove the record if the host is gone.\n")
+	}
+	b.WriteString("\nThis issue is closed automatically once a scan no longer finds the subdomain dangling.\n")
+	return b.String()
+}
+
+// closeComment explains why an issue is being closed. removed means the
+// subdomain was not discovered at all, typically because its record was
+// deleted.
+func closeComment(subdomain string, removed bool, scan *models.ScanMeta) string {
+	state := "no longer dangling"
+	if removed {
+		state = "no longer discovered"
+	}
+	when := scan.StartedAt.UTC().Format("2006-01-02 15:04 UTC")
+	if scan.ID != "" {
+		when += " (`" + scan.ID + "`)"
+	}
+	return fmt.Sprintf("`%s` is %s as of the scan of %s. Closing.", subdomain, state, when)
+}
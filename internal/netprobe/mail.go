@@ -0,0 +1,469 @@
+package netprobe
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hakim/reconpipe/internal/tools"
+	"github.com/hakim/reconpipe/pkg/models"
+)
+
+// MailPorts maps well-known mail ports to the protocol spoken on them.
+var MailPorts = map[int]string{
+	25:   "smtp",
+	465:  "smtps",
+	587:  "submission",
+	2525: "smtp",
+	110:  "pop3",
+	995:  "pop3s",
+	143:  "imap",
+	993:  "imaps",
+}
+
+// mailServices maps nmap service names to a protocol, for mail daemons
+// listening on non-standard ports.
+var mailServices = map[string]string{
+	"smtp":       "smtp",
+	"smtps":      "smtps",
+	"submission": "submission",
+	"pop3":       "pop3",
+	"pop3s":      "pop3s",
+	"imap":       "imap",
+	"imaps":      "imaps",
+}
+
+// Relay probe addresses use RFC 2606 reserved domains so nothing can ever be
+// delivered, and the test stops at RCPT TO — no DATA is sent.
+const (
+	relayProbeFrom = "reconpipe-relay-probe@example.org"
+	relayProbeTo   = "reconpipe-relay-probe@example.net"
+	heloName       = "reconpipe.invalid"
+)
+
+// defaultMailTimeout bounds one complete protocol exchange.
+const defaultMailTimeout = 10 * time.Second
+
+// MailCheckConfig controls the mail service checks
+type MailCheckConfig struct {
+	Timeout       time.Duration // per service, 0 = 10s
+	SkipRelayTest bool
+}
+
+// MailCheck is the outcome of probing one mail service
+type MailCheck struct {
+	IP          string          `json:"ip"`
+	Hostname    string          `json:"hostname,omitempty"`
+	Port        int             `json:"port"`
+	Protocol    string          `json:"protocol"`
+	Banner      string          `json:"banner,omitempty"`
+	ImplicitTLS bool            `json:"implicit_tls"`
+	STARTTLS    bool            `json:"starttls"`
+	TLSVersion  string          `json:"tls_version,omitempty"`
+	Cert        *models.TLSCert `json:"cert,omitempty"`
+	CertError   string          `json:"cert_error,omitempty"` // empty = chain and name valid
+	RelayTested bool            `json:"relay_tested,omitempty"`
+	OpenRelay   bool            `json:"open_relay,omitempty"`
+	Error       string          `json:"error,omitempty"`
+}
+
+// TLSAvailable reports whether the service offered TLS in either form.
+func (c MailCheck) TLSAvailable() bool {
+	return c.ImplicitTLS || c.STARTTLS
+}
+
+// mailProtocol returns the protocol for an open port, or "" when the port
+// does not look like a mail service.
+func mailProtocol(port models.Port) string {
+	if proto, ok := mailServices[strings.ToLower(port.Service)]; ok {
+		return proto
+	}
+	return MailPorts[port.Number]
+}
+
+// RunMailChecks probes every mail service among the hosts' open ports for
+// STARTTLS support, certificate validity and (SMTP only) open relaying.
+// Checks run sequentially; each one is bounded by cfg.Timeout.
+func RunMailChecks(ctx context.Context, hosts []models.Host, cfg MailCheckConfig) []MailCheck {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaultMailTimeout
+	}
+
+	var checks []MailCheck
+	for _, host := range hosts {
+		if host.IsCDN {
+			continue
+		}
+		hostname := ""
+		if len(host.Subdomains) > 0 {
+			hostname = host.Subdomains[0]
+		}
+
+		for _, port := range host.Ports {
+			if port.Protocol != "" && port.Protocol != "tcp" {
+				continue
+			}
+			proto := mailProtocol(port)
+			if proto == "" {
+				continue
+			}
+			if ctx.Err() != nil {
+				return checks
+			}
+
+			check := MailCheck{IP: host.IP, Hostname: hostname, Port: port.Number, Protocol: proto}
+			if tools.FakeToolsEnabled() {
+				fakeMailCheck(&check)
+			} else {
+				checkMailService(ctx, &check, cfg)
+			}
+			checks = append(checks, check)
+		}
+	}
+	return checks
+}
+
+// checkMailService runs the protocol dialogue for one service and records
+// the outcome in check. Failures are recorded in check.Error.
+func checkMailService(ctx context.Context, check *MailCheck, cfg MailCheckConfig) {
+	addr := net.JoinHostPort(check.IP, strconv.Itoa(check.Port))
+
+	dialer := net.Dialer{Timeout: cfg.Timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		check.Error = err.Error()
+		return
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(cfg.Timeout))
+
+	var rw net.Conn = conn
+	switch check.Protocol {
+	case "smtps", "imaps", "pop3s":
+		check.ImplicitTLS = true
+		tlsConn, err := handshake(conn, check)
+		if err != nil {
+			check.Error = err.Error()
+			return
+		}
+		rw = tlsConn
+	}
+
+	switch check.Protocol {
+	case "smtp", "smtps", "submission":
+		err = checkSMTP(conn, rw, check, cfg)
+	case "imap", "imaps":
+		err = checkIMAP(conn, rw, check)
+	case "pop3", "pop3s":
+		err = checkPOP3(conn, rw, check)
+	}
+	if err != nil {
+		check.Error = err.Error()
+	}
+}
+
+// handshake upgrades conn to TLS and records the negotiated version and
+// certificate. Verification is done separately so an invalid certificate
+// is reported rather than aborting the check.
+func handshake(conn net.Conn, check *MailCheck) (*tls.Conn, error) {
+	tlsConn := tls.Client(conn, &tls.Config{
+		ServerName:         check.Hostname,
+		InsecureSkipVerify: true,
+	})
+	if err := tlsConn.Handshake(); err != nil {
+		return nil, fmt.Errorf("TLS handshake: %w", err)
+	}
+
+	state := tlsConn.ConnectionState()
+	check.TLSVersion = tlsVersionName(state.Version)
+	if len(state.PeerCertificates) > 0 {
+		check.Cert = certInfo(state.PeerCertificates[0])
+	}
+	check.CertError = verifyChain(state, check.Hostname)
+	return tlsConn, nil
+}
+
+// checkSMTP reads the greeting, inspects EHLO extensions, optionally runs the
+// relay test, then upgrades via STARTTLS when offered on a plaintext port.
+func checkSMTP(raw net.Conn, rw net.Conn, check *MailCheck, cfg MailCheckConfig) error {
+	text := textproto.NewConn(rw)
+
+	_, banner, err := text.ReadResponse(220)
+	if err != nil {
+		return fmt.Errorf("SMTP greeting: %w", err)
+	}
+	check.Banner = firstLine(banner)
+
+	if err := text.PrintfLine("EHLO %s", heloName); err != nil {
+		return err
+	}
+	_, ext, err := text.ReadResponse(250)
+	if err != nil {
+		return fmt.Errorf("EHLO: %w", err)
+	}
+	starttls := false
+	for _, line := range strings.Split(ext, "\n") {
+		if strings.EqualFold(strings.TrimSpace(line), "STARTTLS") {
+			starttls = true
+		}
+	}
+
+	if !cfg.SkipRelayTest {
+		check.RelayTested = true
+		open, err := relayTest(text)
+		if err != nil {
+			return fmt.Errorf("relay test: %w", err)
+		}
+		check.OpenRelay = open
+	}
+
+	if !check.ImplicitTLS && starttls {
+		if err := text.PrintfLine("STARTTLS"); err != nil {
+			return err
+		}
+		if _, _, err := text.ReadResponse(220); err != nil {
+			return fmt.Errorf("STARTTLS: %w", err)
+		}
+		check.STARTTLS = true
+		tlsConn, err := handshake(raw, check)
+		if err != nil {
+			return err
+		}
+		text = textproto.NewConn(tlsConn)
+	}
+
+	text.PrintfLine("QUIT")
+	return nil
+}
+
+// relayTest asks the server to accept a message between two external
+// addresses. Acceptance of RCPT TO means it relays for anyone. The
+// transaction is always reset before DATA.
+func relayTest(text *textproto.Conn) (bool, error) {
+	if err := text.PrintfLine("MAIL FROM:<%s>", relayProbeFrom); err != nil {
+		return false, err
+	}
+	if _, _, err := text.ReadResponse(250); err != nil {
+		// Sender rejected: the server is not relaying for us
+		return false, resetTransaction(text)
+	}
+
+	if err := text.PrintfLine("RCPT TO:<%s>", relayProbeTo); err != nil {
+		return false, err
+	}
+	_, _, rcptErr := text.ReadResponse(25) // 250 or 251
+
+	return rcptErr == nil, resetTransaction(text)
+}
+
+// resetTransaction sends RSET so the session can continue cleanly.
+func resetTransaction(text *textproto.Conn) error {
+	if err := text.PrintfLine("RSET"); err != nil {
+		return err
+	}
+	_, _, err := text.ReadResponse(250)
+	return err
+}
+
+// checkIMAP reads the greeting and CAPABILITY list, then upgrades via
+// STARTTLS when offered on a plaintext port.
+func checkIMAP(raw net.Conn, rw net.Conn, check *MailCheck) error {
+	text := textproto.NewConn(rw)
+
+	greeting, err := text.ReadLine()
+	if err != nil {
+		return fmt.Errorf("IMAP greeting: %w", err)
+	}
+	if !strings.HasPrefix(greeting, "* OK") {
+		return fmt.Errorf("unexpected IMAP greeting %q", greeting)
+	}
+	check.Banner = strings.TrimSpace(strings.TrimPrefix(greeting, "* OK"))
+
+	lines, err := imapCommand(text, "a1", "CAPABILITY")
+	if err != nil {
+		return err
+	}
+	starttls := false
+	for _, line := range lines {
+		for _, capability := range strings.Fields(strings.ToUpper(line)) {
+			if capability == "STARTTLS" {
+				starttls = true
+			}
+		}
+	}
+
+	if !check.ImplicitTLS && starttls {
+		if _, err := imapCommand(text, "a2", "STARTTLS"); err != nil {
+			return err
+		}
+		check.STARTTLS = true
+		tlsConn, err := handshake(raw, check)
+		if err != nil {
+			return err
+		}
+		text = textproto.NewConn(tlsConn)
+	}
+
+	text.PrintfLine("a3 LOGOUT")
+	return nil
+}
+
+// imapCommand sends a tagged command and returns the untagged lines that
+// preceded an OK completion.
+func imapCommand(text *textproto.Conn, tag, command string) ([]string, error) {
+	if err := text.PrintfLine("%s %s", tag, command); err != nil {
+		return nil, err
+	}
+	var lines []string
+	for {
+		line, err := text.ReadLine()
+		if err != nil {
+			return nil, fmt.Errorf("IMAP %s: %w", command, err)
+		}
+		if rest, ok := strings.CutPrefix(line, tag+" "); ok {
+			if !strings.HasPrefix(strings.ToUpper(rest), "OK") {
+				return nil, fmt.Errorf("IMAP %s: %s", command, rest)
+			}
+			return lines, nil
+		}
+		lines = append(lines, line)
+	}
+}
+
+// checkPOP3 reads the greeting and CAPA list, then upgrades via STLS when
+// offered on a plaintext port.
+func checkPOP3(raw net.Conn, rw net.Conn, check *MailCheck) error {
+	text := textproto.NewConn(rw)
+
+	greeting, err := text.ReadLine()
+	if err != nil {
+		return fmt.Errorf("POP3 greeting: %w", err)
+	}
+	if !strings.HasPrefix(greeting, "+OK") {
+		return fmt.Errorf("unexpected POP3 greeting %q", greeting)
+	}
+	check.Banner = strings.TrimSpace(strings.TrimPrefix(greeting, "+OK"))
+
+	// CAPA is optional (RFC 2449); a server without it has no STLS either
+	stls := false
+	if err := text.PrintfLine("CAPA"); err != nil {
+		return err
+	}
+	status, err := text.ReadLine()
+	if err != nil {
+		return fmt.Errorf("POP3 CAPA: %w", err)
+	}
+	if strings.HasPrefix(status, "+OK") {
+		capabilities, err := text.ReadDotLines()
+		if err != nil {
+			return fmt.Errorf("POP3 CAPA: %w", err)
+		}
+		for _, capability := range capabilities {
+			if strings.EqualFold(strings.TrimSpace(capability), "STLS") {
+				stls = true
+			}
+		}
+	}
+
+	if !check.ImplicitTLS && stls {
+		if err := text.PrintfLine("STLS"); err != nil {
+			return err
+		}
+		reply, err := text.ReadLine()
+		if err != nil {
+			return fmt.Errorf("POP3 STLS: %w", err)
+		}
+		if !strings.HasPrefix(reply, "+OK") {
+			return fmt.Errorf("POP3 STLS: %s", reply)
+		}
+		check.STARTTLS = true
+		tlsConn, err := handshake(raw, check)
+		if err != nil {
+			return err
+		}
+		text = textproto.NewConn(tlsConn)
+	}
+
+	text.PrintfLine("QUIT")
+	return nil
+}
+
+// fakeMailCheck fills check from mailcheck.fixture, keyed by "ip:port".
+func fakeMailCheck(check *MailCheck) {
+	key := net.JoinHostPort(check.IP, strconv.Itoa(check.Port))
+	lines, err := tools.FakeFixture("mailcheck", key)
+	if err != nil {
+		check.Error = err.Error()
+		return
+	}
+	if len(lines) == 0 {
+		check.Error = "connection refused"
+		return
+	}
+
+	recorded := *check
+	if err := json.Unmarshal([]byte(lines[0]), check); err != nil {
+		check.Error = fmt.Sprintf("parsing fixture: %v", err)
+	}
+	check.IP, check.Hostname, check.Port, check.Protocol =
+		recorded.IP, recorded.Hostname, recorded.Port, recorded.Protocol
+}
+
+// firstLine returns the first line of a multi-line server reply.
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(s, "\n")
+	return strings.TrimSpace(line)
+}
+
+// MailFindings turns mail check outcomes into findings so they appear in
+// the vulnerability report and diff alongside nuclei results.
+func MailFindings(checks []MailCheck) []models.Vulnerability {
+	var findings []models.Vulnerability
+	for _, c := range checks {
+		if c.Error != "" && c.Banner == "" {
+			continue
+		}
+		host := net.JoinHostPort(c.IP, strconv.Itoa(c.Port))
+
+		if c.OpenRelay {
+			findings = append(findings, models.Vulnerability{
+				TemplateID:  "smtp-open-relay",
+				Name:        "SMTP Open Relay",
+				Severity:    models.SeverityHigh,
+				Host:        host,
+				Port:        c.Port,
+				MatchedAt:   host,
+				Description: fmt.Sprintf("Server accepted a message from %s to %s without authentication.", relayProbeFrom, relayProbeTo),
+			})
+		}
+
+		if !c.TLSAvailable() && c.Error == "" {
+			findings = append(findings, models.Vulnerability{
+				TemplateID:  "mail-starttls-missing",
+				Name:        "Mail Service Without TLS",
+				Severity:    models.SeverityMedium,
+				Host:        host,
+				Port:        c.Port,
+				MatchedAt:   host,
+				Description: fmt.Sprintf("%s on port %d does not offer STARTTLS; credentials and mail cross the network in cleartext.", strings.ToUpper(c.Protocol), c.Port),
+			})
+		} else if c.CertError != "" {
+			findings = append(findings, models.Vulnerability{
+				TemplateID:  "mail-tls-cert-invalid",
+				Name:        "Mail Service Certificate Invalid",
+				Severity:    models.SeverityLow,
+				Host:        host,
+				Port:        c.Port,
+				MatchedAt:   host,
+				Description: c.CertError,
+			})
+		}
+	}
+	return findings
+}
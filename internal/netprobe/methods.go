@@ -0,0 +1,182 @@
+package netprobe
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hakim/reconpipe/internal/tools"
+)
+
+// MethodCheckConfig controls the HTTP method and status checks
+type MethodCheckConfig struct {
+	Timeout     time.Duration // per request, 0 = 5s
+	Concurrency int           // 0 = 10
+}
+
+// MethodCheck is how a web service answers beyond a plain GET: the methods
+// it admits to, whether it echoes TRACE, and what a path that cannot exist
+// returns, which fuzzers and nuclei need to tell a real hit from a catch-all.
+type MethodCheck struct {
+	// AllowedMethods is the Allow header of the OPTIONS answer, uppercased
+	// and sorted.
+	AllowedMethods []string `json:"allowed_methods,omitempty"`
+	// TraceEnabled is set when TRACE echoed the request back.
+	TraceEnabled bool `json:"trace_enabled,omitempty"`
+	// NotFoundStatus and NotFoundLength describe the answer to a random
+	// path; Soft404 is set when that answer was a 2xx.
+	NotFoundStatus int    `json:"not_found_status,omitempty"`
+	NotFoundLength int64  `json:"not_found_length,omitempty"`
+	Soft404        bool   `json:"soft_404,omitempty"`
+	Error          string `json:"error,omitempty"`
+}
+
+// RiskyMethods are methods a public web service rarely needs to allow.
+var RiskyMethods = []string{"PUT", "DELETE", "PATCH", "CONNECT", "PROPFIND", "MOVE", "COPY"}
+
+// traceMarkerHeader carries a random value TRACE must echo to count.
+const traceMarkerHeader = "X-Reconpipe-Trace"
+
+// CheckMethods sends each URL an OPTIONS request, a TRACE request and a GET
+// for a random path. Results are keyed by URL. Certificates are not
+// verified, and redirects are not followed: a catch-all redirect is part of
+// the answer.
+func CheckMethods(ctx context.Context, urls []string, cfg MethodCheckConfig) map[string]MethodCheck {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaultProtocolTimeout
+	}
+	if cfg.Concurrency <= 0 {
+		cfg.Concurrency = defaultProtocolConcurrency
+	}
+	results := make(map[string]MethodCheck, len(urls))
+	var mu sync.Mutex
+	forEach(ctx, urls, cfg.Concurrency, func(u string) {
+		var check MethodCheck
+		if tools.FakeToolsEnabled() {
+			check = fakeMethodCheck(u)
+		} else {
+			check = checkMethods(ctx, u, cfg.Timeout)
+		}
+		mu.Lock()
+		results[u] = check
+		mu.Unlock()
+	})
+	return results
+}
+
+// checkMethods checks a single URL. A failed request is recorded in Error
+// and does not stop the other two.
+func checkMethods(ctx context.Context, rawURL string, timeout time.Duration) MethodCheck {
+	var check MethodCheck
+	var errs []string
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		check.Error = err.Error()
+		return check
+	}
+	client := &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			TLSClientConfig:   &tls.Config{InsecureSkipVerify: true},
+			DisableKeepAlives: true,
+		},
+		CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+	}
+
+	// OPTIONS: what the server says it accepts
+	if resp, err := send(ctx, client, http.MethodOptions, rawURL, nil); err != nil {
+		errs = append(errs, "OPTIONS: "+err.Error())
+	} else {
+		resp.Body.Close()
+		check.AllowedMethods = ParseAllow(resp.Header.Values("Allow"))
+	}
+
+	// TRACE: enabled when the marker comes back in the echoed request
+	marker := randomToken()
+	if resp, err := send(ctx, client, http.MethodTrace, rawURL, http.Header{traceMarkerHeader: {marker}}); err != nil {
+		errs = append(errs, "TRACE: "+err.Error())
+	} else {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+		resp.Body.Close()
+		check.TraceEnabled = resp.StatusCode == http.StatusOK && strings.Contains(string(body), marker)
+	}
+
+	// A path that cannot exist shows what the service answers for misses
+	bogus := u.Scheme + "://" + u.Host + "/reconpipe-" + randomToken()
+	if resp, err := send(ctx, client, http.MethodGet, bogus, nil); err != nil {
+		errs = append(errs, "404 check: "+err.Error())
+	} else {
+		n, _ := io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<20))
+		resp.Body.Close()
+		check.NotFoundStatus = resp.StatusCode
+		check.NotFoundLength = n
+		check.Soft404 = resp.StatusCode >= 200 && resp.StatusCode < 300
+	}
+
+	check.Error = strings.Join(errs, "; ")
+	return check
+}
+
+// send makes one request with header set on it.
+func send(ctx context.Context, client *http.Client, method, rawURL string, header http.Header) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	return client.Do(req)
+}
+
+// ParseAllow splits Allow header values into uppercased methods, sorted
+// and without duplicates.
+func ParseAllow(values []string) []string {
+	var methods []string
+	for _, v := range values {
+		for _, m := range strings.Split(v, ",") {
+			m = strings.ToUpper(strings.TrimSpace(m))
+			if m != "" && !slices.Contains(methods, m) {
+				methods = append(methods, m)
+			}
+		}
+	}
+	slices.Sort(methods)
+	return methods
+}
+
+// randomToken returns 16 random hex characters.
+func randomToken() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// fakeMethodCheck reads a URL's result from methods.fixture. URLs without
+// an entry allow GET, HEAD and OPTIONS and answer misses with a 404.
+func fakeMethodCheck(u string) MethodCheck {
+	check := MethodCheck{AllowedMethods: []string{"GET", "HEAD", "OPTIONS"}, NotFoundStatus: http.StatusNotFound}
+	lines, err := tools.FakeFixture("methods", u)
+	if err != nil {
+		check.Error = err.Error()
+		return check
+	}
+	if len(lines) > 0 && lines[0] != "" {
+		check = MethodCheck{}
+		if err := json.Unmarshal([]byte(lines[0]), &check); err != nil {
+			check.Error = fmt.Sprintf("parsing fixture: %v", err)
+		}
+	}
+	return check
+}
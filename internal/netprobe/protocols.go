@@ -0,0 +1,425 @@
+package netprobe
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hakim/reconpipe/internal/tools"
+)
+
+// defaultProtocolTimeout bounds the HTTP request and the QUIC probe of one
+// service, each.
+const defaultProtocolTimeout = 5 * time.Second
+
+// defaultProtocolConcurrency is how many services are checked at once.
+const defaultProtocolConcurrency = 10
+
+// ProtocolCheckConfig controls HTTP/2, HTTP/3, gRPC and WebSocket detection
+type ProtocolCheckConfig struct {
+	Timeout     time.Duration // per service, 0 = 5s
+	Concurrency int           // 0 = 10
+}
+
+// ProtocolSupport is what a web service speaks besides HTTP/1.1
+type ProtocolSupport struct {
+	HTTP2  bool   `json:"http2,omitempty"`   // h2 negotiated over TLS (ALPN)
+	AltSvc string `json:"alt_svc,omitempty"` // Alt-Svc response header, as sent
+	// HTTP3 is set when Alt-Svc advertises h3 and the advertised UDP port
+	// answers a QUIC version negotiation probe; QUICVersions lists what it
+	// offered.
+	HTTP3        bool     `json:"http3,omitempty"`
+	QUICVersions []string `json:"quic_versions,omitempty"`
+	// GRPC is set when a gRPC call got a gRPC answer (application/grpc or a
+	// grpc-status), even an error such as UNIMPLEMENTED.
+	GRPC bool `json:"grpc,omitempty"`
+	// WebSocket is the URL that accepted a WebSocket upgrade, if any.
+	WebSocket string `json:"websocket,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// grpcProbePath is the standard health check method. Servers without it
+// still answer in gRPC terms (UNIMPLEMENTED), which is all detection needs.
+const grpcProbePath = "/grpc.health.v1.Health/Check"
+
+// WebSocketPaths are tried, after the probed URL's own path, for a
+// WebSocket upgrade.
+var WebSocketPaths = []string{"/ws", "/websocket", "/socket.io/?EIO=4&transport=websocket"}
+
+// CheckProtocols requests each URL to learn whether it negotiates HTTP/2,
+// advertises HTTP/3, answers gRPC calls and accepts WebSocket upgrades.
+// Advertised HTTP/3 endpoints are confirmed with a QUIC version negotiation
+// probe. Results are keyed by URL. Certificates are not verified: the point
+// is what the server speaks.
+func CheckProtocols(ctx context.Context, urls []string, cfg ProtocolCheckConfig) map[string]ProtocolSupport {
+	cfg = cfg.withDefaults()
+	results := make(map[string]ProtocolSupport, len(urls))
+	var mu sync.Mutex
+	forEach(ctx, urls, cfg.Concurrency, func(u string) {
+		var support ProtocolSupport
+		if tools.FakeToolsEnabled() {
+			support = fakeProtocolCheck(u)
+		} else {
+			support = checkProtocols(ctx, u, cfg.Timeout)
+		}
+		mu.Lock()
+		results[u] = support
+		mu.Unlock()
+	})
+	return results
+}
+
+// DetectGRPC looks for gRPC servers among "host:port" targets that gave no
+// HTTP/1.1 answer, as a server speaking only HTTP/2 does not. Each target is
+// tried over TLS, then as cleartext HTTP/2. Results map each target where a
+// gRPC server answered to its URL.
+func DetectGRPC(ctx context.Context, targets []string, cfg ProtocolCheckConfig) map[string]string {
+	cfg = cfg.withDefaults()
+	found := make(map[string]string)
+	var mu sync.Mutex
+	forEach(ctx, targets, cfg.Concurrency, func(target string) {
+		for _, scheme := range []string{"https", "http"} {
+			u := scheme + "://" + target
+			var ok bool
+			if tools.FakeToolsEnabled() {
+				ok = fakeProtocolCheck(u).GRPC
+			} else {
+				ok, _ = grpcCall(ctx, u, cfg.Timeout)
+			}
+			if ok {
+				mu.Lock()
+				found[target] = u
+				mu.Unlock()
+				return
+			}
+		}
+	})
+	return found
+}
+
+// withDefaults fills in the zero fields.
+func (cfg ProtocolCheckConfig) withDefaults() ProtocolCheckConfig {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaultProtocolTimeout
+	}
+	if cfg.Concurrency <= 0 {
+		cfg.Concurrency = defaultProtocolConcurrency
+	}
+	return cfg
+}
+
+// forEach calls fn for every item, at most concurrency at a time, and stops
+// starting new calls once ctx is done.
+func forEach(ctx context.Context, items []string, concurrency int, fn func(string)) {
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, concurrency)
+	for _, item := range items {
+		if ctx.Err() != nil {
+			break
+		}
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(item string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			fn(item)
+		}(item)
+	}
+	wg.Wait()
+}
+
+// checkProtocols checks a single URL.
+func checkProtocols(ctx context.Context, rawURL string, timeout time.Duration) ProtocolSupport {
+	var support ProtocolSupport
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		support.Error = err.Error()
+		return support
+	}
+
+	client := &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			TLSClientConfig:   &tls.Config{InsecureSkipVerify: true},
+			ForceAttemptHTTP2: true,
+			DisableKeepAlives: true,
+		},
+		CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		support.Error = err.Error()
+		return support
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		support.Error = err.Error()
+		return support
+	}
+	resp.Body.Close()
+
+	support.HTTP2 = resp.ProtoMajor == 2
+	support.AltSvc = strings.Join(resp.Header.Values("Alt-Svc"), ", ")
+
+	// gRPC needs HTTP/2: negotiated over TLS, or spoken in cleartext
+	if support.HTTP2 || u.Scheme == "http" {
+		support.GRPC, _ = grpcCall(ctx, rawURL, timeout)
+	}
+
+	paths := append([]string{u.RequestURI()}, WebSocketPaths...)
+	for _, path := range paths {
+		wsURL := u.Scheme + "://" + u.Host + path
+		if ok, _ := webSocketUpgrade(ctx, wsURL, timeout); ok {
+			support.WebSocket = wsURL
+			break
+		}
+	}
+
+	if port, ok := HTTP3Port(support.AltSvc, u.Port()); ok {
+		versions, err := quicVersions(ctx, net.JoinHostPort(u.Hostname(), strconv.Itoa(port)), timeout)
+		if err != nil {
+			// Advertised but unreachable (UDP filtered, or a stale header)
+			support.Error = fmt.Sprintf("QUIC probe: %v", err)
+		} else {
+			support.HTTP3 = true
+			support.QUICVersions = versions
+		}
+	}
+	return support
+}
+
+// grpcCall sends an empty health check call to the server behind rawURL
+// over HTTP/2 (cleartext for http URLs) and reports whether the answer came
+// from a gRPC server.
+func grpcCall(ctx context.Context, rawURL string, timeout time.Duration) (bool, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return false, err
+	}
+	u.Path, u.RawQuery = grpcProbePath, ""
+
+	protocols := new(http.Protocols)
+	if u.Scheme == "https" {
+		protocols.SetHTTP2(true)
+	} else {
+		protocols.SetUnencryptedHTTP2(true)
+	}
+	client := &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			TLSClientConfig:   &tls.Config{InsecureSkipVerify: true},
+			Protocols:         protocols,
+			DisableKeepAlives: true,
+		},
+		CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+	}
+
+	// One length-prefixed message: not compressed, zero bytes long
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u.String(), bytes.NewReader(make([]byte, 5)))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/grpc")
+	req.Header.Set("TE", "trailers")
+	resp, err := client.Do(req)
+	if err != nil {
+		return false, err
+	}
+	// Trailers arrive after the body
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	resp.Body.Close()
+
+	return strings.HasPrefix(resp.Header.Get("Content-Type"), "application/grpc") ||
+		resp.Header.Get("Grpc-Status") != "" || resp.Trailer.Get("Grpc-Status") != "", nil
+}
+
+// webSocketUpgrade asks rawURL to switch to the WebSocket protocol and
+// reports whether it agreed. The connection is closed right after the
+// handshake.
+func webSocketUpgrade(ctx context.Context, rawURL string, timeout time.Duration) (bool, error) {
+	// The upgrade handshake only exists in HTTP/1.1
+	protocols := new(http.Protocols)
+	protocols.SetHTTP1(true)
+	client := &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			TLSClientConfig:   &tls.Config{InsecureSkipVerify: true},
+			Protocols:         protocols,
+			DisableKeepAlives: true,
+		},
+		CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+	}
+
+	key := make([]byte, 16)
+	if _, err := rand.Read(key); err != nil {
+		return false, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	req.Header.Set("Sec-WebSocket-Key", base64.StdEncoding.EncodeToString(key))
+	resp, err := client.Do(req)
+	if err != nil {
+		return false, err
+	}
+	resp.Body.Close()
+
+	return resp.StatusCode == http.StatusSwitchingProtocols &&
+		strings.EqualFold(resp.Header.Get("Upgrade"), "websocket"), nil
+}
+
+// HTTP3Port returns the UDP port an Alt-Svc header advertises HTTP/3 on
+// (h3 or a draft h3-NN) for the same host. An authority without a port uses
+// defaultPort, which is the origin's own port ("" = 443). Alternatives on
+// another host are ignored: they may be out of scope.
+func HTTP3Port(altSvc, defaultPort string) (int, bool) {
+	if altSvc == "" || strings.EqualFold(strings.TrimSpace(altSvc), "clear") {
+		return 0, false
+	}
+	for _, entry := range strings.Split(altSvc, ",") {
+		alternative, _, _ := strings.Cut(entry, ";")
+		protocol, authority, ok := strings.Cut(strings.TrimSpace(alternative), "=")
+		if !ok || (protocol != "h3" && !strings.HasPrefix(protocol, "h3-")) {
+			continue
+		}
+		host, port, err := net.SplitHostPort(strings.Trim(authority, `"`))
+		if err != nil || host != "" {
+			continue
+		}
+		if port == "" {
+			port = defaultPort
+		}
+		if port == "" {
+			port = "443"
+		}
+		n, err := strconv.Atoi(port)
+		if err != nil || n <= 0 || n > 65535 {
+			continue
+		}
+		return n, true
+	}
+	return 0, false
+}
+
+// quicProbeVersion is a reserved version (RFC 9000 section 15) that no
+// server supports, so any QUIC server answers with version negotiation.
+const quicProbeVersion = 0x1a2a3a4a
+
+// quicVersions sends a QUIC Initial-sized datagram with an unsupported
+// version to addr and returns the versions listed in the server's version
+// negotiation reply. Nothing beyond that reply is exchanged.
+func quicVersions(ctx context.Context, addr string, timeout time.Duration) ([]string, error) {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	// Long header: form and fixed bits, version, DCID and SCID of 8 bytes
+	// each. Clients must pad Initial datagrams to 1200 bytes or servers
+	// drop them.
+	packet := make([]byte, 1200)
+	packet[0] = 0xc0
+	binary.BigEndian.PutUint32(packet[1:5], quicProbeVersion)
+	packet[5] = 8
+	packet[14] = 8
+	if _, err := rand.Read(packet[6:14]); err != nil {
+		return nil, err
+	}
+	if _, err := rand.Read(packet[15:23]); err != nil {
+		return nil, err
+	}
+
+	conn.SetDeadline(time.Now().Add(timeout))
+	if _, err := conn.Write(packet); err != nil {
+		return nil, err
+	}
+	reply := make([]byte, 1500)
+	n, err := conn.Read(reply)
+	if err != nil {
+		return nil, fmt.Errorf("no reply: %w", err)
+	}
+	return parseVersionNegotiation(reply[:n])
+}
+
+// parseVersionNegotiation decodes a QUIC version negotiation packet
+// (RFC 9000 section 17.2.1) into readable version names, leaving out the
+// reserved versions servers add to keep clients honest.
+func parseVersionNegotiation(b []byte) ([]string, error) {
+	if len(b) < 7 || b[0]&0x80 == 0 || binary.BigEndian.Uint32(b[1:5]) != 0 {
+		return nil, fmt.Errorf("reply is not a QUIC version negotiation packet")
+	}
+	pos := 5
+	for i := 0; i < 2; i++ { // destination, then source connection ID
+		if pos >= len(b) {
+			return nil, fmt.Errorf("truncated version negotiation packet")
+		}
+		pos += 1 + int(b[pos])
+	}
+	if pos > len(b) || (len(b)-pos)%4 != 0 {
+		return nil, fmt.Errorf("truncated version negotiation packet")
+	}
+
+	var versions []string
+	for ; pos < len(b); pos += 4 {
+		v := binary.BigEndian.Uint32(b[pos : pos+4])
+		if v&0x0f0f0f0f == 0x0a0a0a0a {
+			continue
+		}
+		versions = append(versions, quicVersionName(v))
+	}
+	if len(versions) == 0 {
+		return nil, fmt.Errorf("server listed no QUIC versions")
+	}
+	return versions, nil
+}
+
+// quicVersionName names the QUIC versions HTTP/3 runs on.
+func quicVersionName(v uint32) string {
+	switch {
+	case v == 0x00000001:
+		return "v1"
+	case v == 0x6b3343cf:
+		return "v2"
+	case v>>8 == 0xff0000:
+		return fmt.Sprintf("draft-%d", v&0xff)
+	default:
+		return fmt.Sprintf("0x%08x", v)
+	}
+}
+
+// fakeProtocolCheck reads a URL's result from protocols.fixture. URLs
+// without an entry, or with an empty one, speak HTTP/1.1 only.
+func fakeProtocolCheck(u string) ProtocolSupport {
+	var support ProtocolSupport
+	lines, err := tools.FakeFixture("protocols", u)
+	if err != nil {
+		support.Error = err.Error()
+		return support
+	}
+	if len(lines) > 0 && lines[0] != "" {
+		if err := json.Unmarshal([]byte(lines[0]), &support); err != nil {
+			support.Error = fmt.Sprintf("parsing fixture: %v", err)
+		}
+	}
+	return support
+}
@@ -0,0 +1,69 @@
+// Package netprobe implements small protocol-aware checks that talk to
+// services directly instead of shelling out to an external tool.
+package netprobe
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	"github.com/hakim/reconpipe/pkg/models"
+)
+
+// certInfo converts the leaf certificate into the model used by HTTP probes.
+func certInfo(cert *x509.Certificate) *models.TLSCert {
+	sum := sha256.Sum256(cert.Raw)
+	return &models.TLSCert{
+		SubjectCN: cert.Subject.CommonName,
+		SubjectDN: cert.Subject.String(),
+		SANs:      cert.DNSNames,
+		IssuerCN:  cert.Issuer.CommonName,
+		IssuerDN:  cert.Issuer.String(),
+		Serial:    strings.ToUpper(cert.SerialNumber.Text(16)),
+		NotBefore: cert.NotBefore.UTC().Format(time.RFC3339),
+		NotAfter:  cert.NotAfter.UTC().Format(time.RFC3339),
+		SHA256:    hex.EncodeToString(sum[:]),
+	}
+}
+
+// verifyChain validates the peer chain against the system roots. hostname
+// is checked against the leaf when non-empty. It returns "" for a valid
+// chain, otherwise a short reason.
+func verifyChain(state tls.ConnectionState, hostname string) string {
+	if len(state.PeerCertificates) == 0 {
+		return "no certificate presented"
+	}
+	leaf := state.PeerCertificates[0]
+
+	intermediates := x509.NewCertPool()
+	for _, c := range state.PeerCertificates[1:] {
+		intermediates.AddCert(c)
+	}
+	_, err := leaf.Verify(x509.VerifyOptions{
+		DNSName:       hostname,
+		Intermediates: intermediates,
+	})
+	if err != nil {
+		return err.Error()
+	}
+	return ""
+}
+
+// tlsVersionName returns the protocol name for a negotiated TLS version.
+func tlsVersionName(v uint16) string {
+	switch v {
+	case tls.VersionTLS10:
+		return "TLS 1.0"
+	case tls.VersionTLS11:
+		return "TLS 1.1"
+	case tls.VersionTLS12:
+		return "TLS 1.2"
+	case tls.VersionTLS13:
+		return "TLS 1.3"
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,140 @@
+// Package noise sorts findings into an actionable tier and an
+// informational-noise tier, from the rules under vulnscan.noise.rules:
+//
+//	vulnscan:
+//	  noise:
+//	    rules:
+//	      - severities: [info]
+//	      - tags: [tech, waf-detect]
+//	      - templates: ["http-missing-security-headers"]
+//	        matchers: ["x-frame-options", "*-policy"]
+//
+// A rule matches a finding when every field it sets matches: one of its
+// template ID patterns, one of its tags, one of its severities and one of
+// its matcher name patterns. Patterns are path.Match globs and everything
+// is compared case-insensitively. A finding matched by any rule is noise;
+// it stays in vulns.json and the counts, but reports list it in an appendix
+// and finding alerts leave it out.
+package noise
+
+import (
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/hakim/reconpipe/pkg/models"
+)
+
+// Rule is one noise rule as written in the config. Empty fields match
+// every finding, but a rule must set at least one.
+type Rule struct {
+	Templates  []string `mapstructure:"templates"`  // template ID globs
+	Tags       []string `mapstructure:"tags"`       // nuclei template tags
+	Severities []string `mapstructure:"severities"` // critical, high, medium, low or info
+	Matchers   []string `mapstructure:"matchers"`   // nuclei matcher name globs
+}
+
+// Classifier marks findings as noise. A nil *Classifier marks nothing.
+type Classifier struct {
+	rules []Rule // lowercased and trimmed
+}
+
+// severities are the values a rule's severities may hold.
+var severities = []models.Severity{
+	models.SeverityCritical,
+	models.SeverityHigh,
+	models.SeverityMedium,
+	models.SeverityLow,
+	models.SeverityInfo,
+}
+
+// New checks rules and returns a classifier for them, or nil when there
+// are none.
+func New(rules []Rule) (*Classifier, error) {
+	if len(rules) == 0 {
+		return nil, nil
+	}
+	c := &Classifier{}
+	for i, spec := range rules {
+		r := Rule{
+			Templates:  normalize(spec.Templates),
+			Tags:       normalize(spec.Tags),
+			Severities: normalize(spec.Severities),
+			Matchers:   normalize(spec.Matchers),
+		}
+		if len(r.Templates)+len(r.Tags)+len(r.Severities)+len(r.Matchers) == 0 {
+			return nil, fmt.Errorf("rule %d: set at least one of templates, tags, severities or matchers", i+1)
+		}
+		for _, pattern := range append(slices.Clone(r.Templates), r.Matchers...) {
+			if _, err := path.Match(pattern, ""); err != nil || pattern == "" {
+				return nil, fmt.Errorf("rule %d: bad pattern %q", i+1, pattern)
+			}
+		}
+		for _, s := range r.Severities {
+			if !slices.Contains(severities, models.Severity(s)) {
+				return nil, fmt.Errorf("rule %d: unknown severity %q (want critical, high, medium, low or info)", i+1, s)
+			}
+		}
+		c.rules = append(c.rules, r)
+	}
+	return c, nil
+}
+
+// normalize lowercases and trims values.
+func normalize(values []string) []string {
+	out := make([]string, 0, len(values))
+	for _, v := range values {
+		out = append(out, strings.ToLower(strings.TrimSpace(v)))
+	}
+	return out
+}
+
+// Classify sets Noise on the findings a rule matches, clears it on the
+// rest, and returns how many are noise.
+func (c *Classifier) Classify(vulns []models.Vulnerability) int {
+	n := 0
+	for i := range vulns {
+		vulns[i].Noise = c.IsNoise(vulns[i])
+		if vulns[i].Noise {
+			n++
+		}
+	}
+	return n
+}
+
+// IsNoise reports whether a rule matches v.
+func (c *Classifier) IsNoise(v models.Vulnerability) bool {
+	if c == nil {
+		return false
+	}
+	return slices.ContainsFunc(c.rules, func(r Rule) bool { return r.matches(v) })
+}
+
+// matches reports whether every field r sets matches v.
+func (r Rule) matches(v models.Vulnerability) bool {
+	if len(r.Templates) > 0 && !matchAny(r.Templates, v.TemplateID) {
+		return false
+	}
+	if len(r.Matchers) > 0 && (v.MatcherName == "" || !matchAny(r.Matchers, v.MatcherName)) {
+		return false
+	}
+	if len(r.Severities) > 0 && !slices.Contains(r.Severities, string(v.Severity)) {
+		return false
+	}
+	if len(r.Tags) > 0 && !slices.ContainsFunc(v.Tags, func(tag string) bool {
+		return slices.Contains(r.Tags, strings.ToLower(tag))
+	}) {
+		return false
+	}
+	return true
+}
+
+// matchAny reports whether value matches one of the lowercased patterns.
+func matchAny(patterns []string, value string) bool {
+	value = strings.ToLower(value)
+	return slices.ContainsFunc(patterns, func(p string) bool {
+		ok, _ := path.Match(p, value)
+		return ok
+	})
+}
@@ -0,0 +1,128 @@
+// Package notes attaches analyst notes to the scans they apply to. Notes are
+// kept per target in the database; each scan gets a copy of those whose
+// finding or asset it reports in raw/notes.json, which its reports and
+// exports read.
+package notes
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/hakim/reconpipe/internal/diff"
+	"github.com/hakim/reconpipe/internal/riskpolicy"
+	"github.com/hakim/reconpipe/internal/storage"
+	"github.com/hakim/reconpipe/pkg/models"
+)
+
+// File is the raw file holding a scan's notes.
+const File = "notes.json"
+
+// ScanNotes is the content of raw/notes.json.
+type ScanNotes struct {
+	Target    string         `json:"target"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	Notes     []AttachedNote `json:"notes"`
+}
+
+// AttachedNote is a note with what it is attached to in the scan.
+type AttachedNote struct {
+	models.Note
+	// Name and Severity describe the finding a finding note is attached
+	// to; empty for asset notes.
+	Name     string          `json:"name,omitempty"`
+	Severity models.Severity `json:"severity,omitempty"`
+}
+
+// FindingKey returns the key notes use for a vulnerability: its template ID
+// and host, as diff.VulnKey. A dangling subdomain's key is its name.
+func FindingKey(v models.Vulnerability) string {
+	return diff.VulnKey(v)
+}
+
+// Attach returns the notes that apply to snap: finding notes whose
+// finding it reports and asset notes on a hostname or IP it has, in the
+// order given. Keys match without regard to case.
+func Attach(all []*models.Note, snap *diff.ScanSnapshot) []AttachedNote {
+	findings := map[string]riskpolicy.Finding{}
+	for _, v := range snap.Vulnerabilities {
+		findings[strings.ToLower(FindingKey(v))] = riskpolicy.VulnFinding(v, false)
+	}
+	assets := map[string]bool{}
+	for _, s := range snap.Subdomains {
+		assets[strings.ToLower(s.Name)] = true
+		if s.IsDangling {
+			findings[strings.ToLower(s.Name)] = riskpolicy.DanglingFinding(s, false)
+		}
+	}
+	for _, h := range snap.Hosts {
+		assets[h.IP] = true
+		for _, name := range h.Subdomains {
+			assets[strings.ToLower(name)] = true
+		}
+	}
+	for _, p := range snap.Probes {
+		assets[p.IP] = true
+		if u, err := url.Parse(p.URL); err == nil && u.Hostname() != "" {
+			assets[strings.ToLower(u.Hostname())] = true
+		}
+	}
+
+	var out []AttachedNote
+	for _, n := range all {
+		key := strings.ToLower(n.Key)
+		switch n.Subject {
+		case models.NoteFinding:
+			if f, ok := findings[key]; ok {
+				out = append(out, AttachedNote{Note: *n, Name: f.Name, Severity: f.Severity})
+			}
+		case models.NoteAsset:
+			if assets[key] {
+				out = append(out, AttachedNote{Note: *n})
+			}
+		}
+	}
+	return out
+}
+
+// Write replaces raw/notes.json of the scan in scanDir with the notes of
+// all that apply to it, and returns what it wrote. A scan without any keeps
+// an empty list, so removed notes disappear from it too.
+func Write(scanDir, target string, all []*models.Note) (*ScanNotes, error) {
+	snap, err := diff.LoadSnapshot(scanDir)
+	if err != nil {
+		return nil, err
+	}
+	sn := &ScanNotes{Target: target, UpdatedAt: time.Now().UTC(), Notes: Attach(all, snap)}
+	if sn.Notes == nil {
+		sn.Notes = []AttachedNote{}
+	}
+	data, err := json.MarshalIndent(sn, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("marshaling notes: %w", err)
+	}
+	if err := storage.WriteFileAtomic(storage.RawPath(scanDir, File), data, 0644); err != nil {
+		return nil, fmt.Errorf("writing %s: %w", File, err)
+	}
+	return sn, nil
+}
+
+// Load reads raw/notes.json of the scan in scanDir. A scan without one has
+// no notes.
+func Load(scanDir string) ([]AttachedNote, error) {
+	data, err := os.ReadFile(storage.RawPath(scanDir, File))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var sn ScanNotes
+	if err := json.Unmarshal(data, &sn); err != nil {
+		return nil, &diff.CorruptFileError{Path: storage.RawPath(scanDir, File), Err: err}
+	}
+	return sn.Notes, nil
+}
@@ -0,0 +1,300 @@
+// Package origins looks for the real servers behind CDN-fronted hostnames.
+// Each technique proposes origin IPs from data the scan already holds, or
+// from one cheap DNS lookup: certificates and favicons seen on the scanned
+// IP space, addresses the names resolved to in earlier scans, and the
+// domain's mail servers, which often share a host with the web origin. An
+// IP proposed by several techniques gains confidence.
+package origins
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"net"
+	"net/url"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hakim/reconpipe/internal/exclude"
+	"github.com/hakim/reconpipe/internal/tools"
+	"github.com/hakim/reconpipe/pkg/models"
+)
+
+// Techniques that propose origin candidates
+const (
+	TechniqueCertificate = "certificate"
+	TechniqueFavicon     = "favicon"
+	TechniqueDNSHistory  = "dns-history"
+	TechniqueMailRecords = "mail-records"
+)
+
+// Confidence levels, lowest first
+const (
+	ConfidenceLow    = "low"
+	ConfidenceMedium = "medium"
+	ConfidenceHigh   = "high"
+)
+
+var confidenceLevels = []string{ConfidenceLow, ConfidenceMedium, ConfidenceHigh}
+
+// techniqueConfidence is how much one technique on its own says. A
+// certificate naming the host is rarely a coincidence; a shared mail server
+// often is.
+var techniqueConfidence = map[string]int{
+	TechniqueCertificate: 2,
+	TechniqueFavicon:     1,
+	TechniqueDNSHistory:  1,
+	TechniqueMailRecords: 0,
+}
+
+// maxFaviconIPs is how many non-CDN IPs may share a favicon before it counts
+// as a stock icon (a framework default, a hosting panel) rather than the
+// application's own.
+const maxFaviconIPs = 3
+
+// Sighting is an address a hostname resolved to in an earlier scan.
+type Sighting struct {
+	Name string
+	IP   string
+	Seen time.Time
+}
+
+// Inputs is the data the techniques search.
+type Inputs struct {
+	// Hosts are the scanned hosts. CDN hosts name the fronted hostnames;
+	// the others are the IP space origins are looked for in.
+	Hosts []models.Host
+	// Probes are the responses of the scanned IP space, with the
+	// certificates and favicons they served.
+	Probes []models.HTTPProbe
+	// CDNProbes are the fronted hostnames requested through the CDN.
+	CDNProbes []models.HTTPProbe
+	// History lists where the hostnames resolved in earlier scans.
+	History []Sighting
+	// MailHosts maps the domain's MX hosts to their addresses.
+	MailHosts map[string][]string
+	// Exclude lists networks never proposed as origins. Nil excludes nothing.
+	Exclude *exclude.List
+}
+
+// Find runs every technique and returns the candidates, grouped by
+// hostname, most confident first.
+func Find(in Inputs) []models.OriginCandidate {
+	f := &finder{in: in, byKey: make(map[string]*models.OriginCandidate), cdnIPs: make(map[string]bool)}
+	var names []string
+	for _, host := range in.Hosts {
+		if !host.IsCDN {
+			continue
+		}
+		f.cdnIPs[host.IP] = true
+		if host.Excluded {
+			continue
+		}
+		for _, sub := range host.Subdomains {
+			if !slices.Contains(names, sub) {
+				names = append(names, sub)
+			}
+		}
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		f.certificates(name)
+		f.favicons(name)
+		f.history(name)
+		f.mailRecords(name)
+	}
+	return f.candidates()
+}
+
+// finder collects the candidates of one Find call.
+type finder struct {
+	in     Inputs
+	byKey  map[string]*models.OriginCandidate
+	order  []string
+	cdnIPs map[string]bool
+}
+
+// add records that technique proposed ip as the origin of name.
+func (f *finder) add(name, ip, technique, evidence string) {
+	if ip == "" || f.cdnIPs[ip] || f.in.Exclude.Contains(ip) {
+		return
+	}
+	key := name + "|" + ip
+	c, ok := f.byKey[key]
+	if !ok {
+		c = &models.OriginCandidate{Hostname: name, IP: ip}
+		f.byKey[key] = c
+		f.order = append(f.order, key)
+	}
+	if !slices.Contains(c.Techniques, technique) {
+		c.Techniques = append(c.Techniques, technique)
+	}
+	if !slices.Contains(c.Evidence, evidence) {
+		c.Evidence = append(c.Evidence, evidence)
+	}
+}
+
+// candidates scores and orders what was collected.
+func (f *finder) candidates() []models.OriginCandidate {
+	out := make([]models.OriginCandidate, 0, len(f.order))
+	for _, key := range f.order {
+		c := *f.byKey[key]
+		c.Confidence = score(c.Techniques)
+		out = append(out, c)
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		if out[i].Hostname != out[j].Hostname {
+			return out[i].Hostname < out[j].Hostname
+		}
+		ri, rj := Rank(out[i].Confidence), Rank(out[j].Confidence)
+		if ri != rj {
+			return ri > rj
+		}
+		return out[i].IP < out[j].IP
+	})
+	return out
+}
+
+// score is the confidence of the strongest technique, one level higher when
+// another technique agrees.
+func score(techniques []string) string {
+	level := 0
+	for _, t := range techniques {
+		level = max(level, techniqueConfidence[t])
+	}
+	if len(techniques) > 1 {
+		level++
+	}
+	return confidenceLevels[min(level, len(confidenceLevels)-1)]
+}
+
+// Rank orders confidence levels: 0 for low up to 2 for high, -1 for
+// anything else.
+func Rank(confidence string) int {
+	return slices.Index(confidenceLevels, confidence)
+}
+
+// certificates proposes the non-CDN IPs that presented a certificate naming
+// the host, whether they were probed by IP or by another hostname.
+func (f *finder) certificates(name string) {
+	for _, p := range f.in.Probes {
+		if p.IsCDN || p.TLS == nil {
+			continue
+		}
+		if match := CertMatch(p.TLS, name); match != "" {
+			f.add(name, p.IP, TechniqueCertificate, fmt.Sprintf("certificate for %s served by %s", match, p.URL))
+		}
+	}
+}
+
+// favicons proposes the non-CDN IPs serving the favicon the host serves
+// through the CDN, unless so many do that it is a stock icon.
+func (f *finder) favicons(name string) {
+	for _, cdn := range f.in.CDNProbes {
+		if cdn.Favicon == "" || cdn.Favicon == "0" || hostnameOf(cdn.URL) != name {
+			continue
+		}
+		ips := make(map[string]string)
+		for _, p := range f.in.Probes {
+			if !p.IsCDN && p.Favicon == cdn.Favicon && ips[p.IP] == "" {
+				ips[p.IP] = p.URL
+			}
+		}
+		if len(ips) > maxFaviconIPs {
+			continue
+		}
+		for _, ip := range slices.Sorted(maps.Keys(ips)) {
+			f.add(name, ip, TechniqueFavicon, fmt.Sprintf("favicon hash %s also served by %s", cdn.Favicon, ips[ip]))
+		}
+	}
+}
+
+// history proposes the addresses outside the CDN the host resolved to in
+// earlier scans; sites are often moved behind a CDN without changing origin.
+func (f *finder) history(name string) {
+	for _, s := range f.in.History {
+		if s.Name == name {
+			f.add(name, s.IP, TechniqueDNSHistory, fmt.Sprintf("resolved to %s in the scan of %s", s.IP, s.Seen.Format("2006-01-02")))
+		}
+	}
+}
+
+// mailRecords proposes the addresses of the domain's own mail servers. Mail
+// is rarely sent through a CDN, so small sites serving both from one host
+// expose it in their MX records.
+func (f *finder) mailRecords(name string) {
+	for _, host := range slices.Sorted(maps.Keys(f.in.MailHosts)) {
+		for _, ip := range f.in.MailHosts[host] {
+			f.add(name, ip, TechniqueMailRecords, fmt.Sprintf("MX host %s is %s", host, ip))
+		}
+	}
+}
+
+// LookupMailHosts resolves the MX records of domain. Only mail servers
+// within the domain are returned: a hosted mail service says nothing about
+// where the web site runs. Mail hosts that do not resolve are left out.
+func LookupMailHosts(ctx context.Context, domain, digPath string) (map[string][]string, error) {
+	records, err := tools.LookupRecords(ctx, domain, "MX", "", false, digPath)
+	if err != nil {
+		return nil, fmt.Errorf("MX lookup: %w", err)
+	}
+	hosts := make(map[string][]string)
+	for _, record := range records {
+		// "10 mail.example.com."
+		fields := strings.Fields(record)
+		if len(fields) == 0 {
+			continue
+		}
+		host := strings.ToLower(strings.TrimSuffix(fields[len(fields)-1], "."))
+		if host != domain && !strings.HasSuffix(host, "."+domain) {
+			continue
+		}
+		ips, err := tools.LookupRecords(ctx, host, "A", "", false, digPath)
+		if err != nil {
+			continue
+		}
+		for _, ip := range ips {
+			if net.ParseIP(ip) != nil {
+				hosts[host] = append(hosts[host], ip)
+			}
+		}
+	}
+	return hosts, nil
+}
+
+// CertMatch returns the certificate name (a SAN, or the subject CN) that
+// covers host, or "" when none does. Wildcards match a single label.
+func CertMatch(cert *models.TLSCert, host string) string {
+	host = strings.ToLower(host)
+	for _, san := range cert.SANs {
+		if certNameMatches(strings.ToLower(san), host) {
+			return san
+		}
+	}
+	if certNameMatches(strings.ToLower(cert.SubjectCN), host) {
+		return cert.SubjectCN
+	}
+	return ""
+}
+
+func certNameMatches(pattern, host string) bool {
+	if pattern == "" {
+		return false
+	}
+	if suffix, ok := strings.CutPrefix(pattern, "*."); ok {
+		label, rest, found := strings.Cut(host, ".")
+		return found && label != "" && rest == suffix
+	}
+	return pattern == host
+}
+
+func hostnameOf(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(u.Hostname())
+}
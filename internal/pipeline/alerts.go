@@ -0,0 +1,201 @@
+package pipeline
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/hakim/reconpipe/internal/riskpolicy"
+	"github.com/hakim/reconpipe/internal/storage"
+	"github.com/hakim/reconpipe/pkg/models"
+	"github.com/hakim/reconpipe/pkg/scanstore"
+)
+
+// Finding alert events.
+const (
+	AlertNew       = "new"       // first seen, or back after being resolved
+	AlertEscalated = "escalated" // severity rose above what was last alerted
+	AlertResolved  = "resolved"  // no longer found by a scan that looked for it
+)
+
+// FindingAlert is one finding whose notification state changed.
+type FindingAlert struct {
+	Event            string          `json:"event"`
+	Kind             string          `json:"kind"` // vuln or dangling
+	Identity         string          `json:"identity"`
+	Name             string          `json:"name"`
+	Host             string          `json:"host"`
+	Severity         models.Severity `json:"severity"`
+	PreviousSeverity models.Severity `json:"previous_severity,omitempty"`
+}
+
+// AlertStore persists notification state between scans.
+type AlertStore = scanstore.AlertStore
+
+// alertSeverityRank orders severities for escalation checks (higher = worse).
+var alertSeverityRank = map[models.Severity]int{
+	models.SeverityInfo:     1,
+	models.SeverityLow:      2,
+	models.SeverityMedium:   3,
+	models.SeverityHigh:     4,
+	models.SeverityCritical: 5,
+}
+
+// TrackFindings compares the scan's vulnerabilities and dangling subdomains
+// with the stored notification state and passes the resulting alerts to send.
+// The state is only saved once send succeeds, so a failed delivery is
+// retried by the next scan instead of being lost.
+//
+// A kind of finding is only evaluated when the stage that produces it ran
+// without error in this scan (vulnscan for vulnerabilities, discover for
+// dangling DNS), and vulnerabilities are skipped when the deadline cut the
+// vulnscan short or a --filter narrowed it; otherwise a partial scan would
+// mark everything resolved.
+//
+// Only findings matching the policy's alert_on are alerted on (by default,
+// all but noise). The others raise no alert but still count as seen, so a
+// finding alerted before the policy excluded it is not reported resolved.
+func TrackFindings(store AlertStore, result *PipelineResult, policy *riskpolicy.Policy, send func([]FindingAlert) error) ([]FindingAlert, error) {
+	evaluated := map[string]bool{}
+	for _, stage := range result.StagesRun {
+		if _, failed := result.StageErrors[stage]; failed {
+			continue
+		}
+		switch stage {
+		case "vulnscan":
+			evaluated[models.FindingKindVuln] = true
+		case "discover":
+			evaluated[models.FindingKindDangling] = true
+		}
+	}
+	// A vulnscan cut short by the deadline or filtered didn't look at every target
+	if evaluated[models.FindingKindVuln] && vulnScanPartial(result.ScanDir) {
+		delete(evaluated, models.FindingKindVuln)
+	}
+	if len(evaluated) == 0 {
+		return nil, nil
+	}
+
+	findings, err := riskpolicy.ScanFindings(result.ScanDir)
+	if err != nil {
+		return nil, fmt.Errorf("loading results: %w", err)
+	}
+
+	states, err := store.ListNotificationStates(result.Target)
+	if err != nil {
+		return nil, fmt.Errorf("loading notification state: %w", err)
+	}
+	byKey := make(map[string]*models.NotificationState, len(states))
+	for _, st := range states {
+		byKey[st.Kind+"\x00"+st.Identity] = st
+	}
+
+	now := time.Now().UTC()
+	var alerts []FindingAlert
+	var changed []*models.NotificationState
+	seen := map[string]bool{}
+
+	for _, f := range findings {
+		if !evaluated[f.Kind] {
+			continue
+		}
+		key := f.Kind + "\x00" + f.Identity
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		if !policy.Matches(riskpolicy.Alert, f) {
+			if st := byKey[key]; st != nil && st.ResolvedAt == nil {
+				st.LastSeen, st.LastScanID = now, result.ScanID
+				changed = append(changed, st)
+			}
+			continue
+		}
+
+		alert := FindingAlert{Kind: f.Kind, Identity: f.Identity, Name: f.Name, Host: f.Host, Severity: f.Severity}
+		st := byKey[key]
+		switch {
+		case st == nil || st.ResolvedAt != nil:
+			alert.Event = AlertNew
+			if st == nil {
+				st = &models.NotificationState{Target: result.Target, Kind: f.Kind, Identity: f.Identity}
+			}
+			st.FirstSeen, st.ResolvedAt, st.Severity = now, nil, f.Severity
+			st.Expired, st.ScansMissed = false, 0
+		case alertSeverityRank[f.Severity] > alertSeverityRank[st.Severity]:
+			alert.Event = AlertEscalated
+			alert.PreviousSeverity = st.Severity
+			st.Severity = f.Severity
+		}
+		st.Name, st.Host, st.LastSeen, st.LastScanID = f.Name, f.Host, now, result.ScanID
+
+		changed = append(changed, st)
+		if alert.Event != "" {
+			alerts = append(alerts, alert)
+		}
+	}
+
+	for _, st := range states {
+		if st.ResolvedAt != nil || !evaluated[st.Kind] || seen[st.Kind+"\x00"+st.Identity] {
+			continue
+		}
+		resolved := now
+		st.ResolvedAt = &resolved
+		st.LastScanID = result.ScanID
+		changed = append(changed, st)
+		alerts = append(alerts, FindingAlert{
+			Event: AlertResolved, Kind: st.Kind, Identity: st.Identity,
+			Name: st.Name, Host: st.Host, Severity: st.Severity,
+		})
+	}
+
+	if len(alerts) > 0 {
+		if err := send(alerts); err != nil {
+			return alerts, err
+		}
+	}
+	if err := store.SaveNotificationStates(changed); err != nil {
+		return alerts, fmt.Errorf("saving notification state: %w", err)
+	}
+	return alerts, nil
+}
+
+// vulnScanPartial reports whether the scan's vulns.json is marked partial or
+// was run with a filter.
+func vulnScanPartial(scanDir string) bool {
+	data, err := os.ReadFile(storage.RawPath(scanDir, "vulns.json"))
+	if err != nil {
+		return false
+	}
+	var v struct {
+		Partial bool   `json:"partial"`
+		Filter  string `json:"filter"`
+	}
+	return json.Unmarshal(data, &v) == nil && (v.Partial || v.Filter != "")
+}
+
+// findingsPayload is the JSON body posted for finding alerts.
+type findingsPayload struct {
+	Event  string         `json:"event"` // always "findings"
+	Target string         `json:"target"`
+	ScanID string         `json:"scan_id"`
+	Alerts []FindingAlert `json:"alerts"`
+}
+
+// SendFindingAlerts posts the alerts for one scan to the webhook URL, or as
+// a Slack or Discord message. Returns nil if WebhookURL is empty (no-op).
+func (n *NotifyConfig) SendFindingAlerts(result *PipelineResult, alerts []FindingAlert) error {
+	if n == nil || n.WebhookURL == "" {
+		return nil
+	}
+
+	payload := findingsPayload{
+		Event:  "findings",
+		Target: result.Target,
+		ScanID: result.ScanID,
+		Alerts: alerts,
+	}
+	return n.send(payload, func() chatMessage { return findingsMessage(result, alerts) })
+}
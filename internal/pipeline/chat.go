@@ -0,0 +1,444 @@
+package pipeline
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hakim/reconpipe/internal/diff"
+	"github.com/hakim/reconpipe/internal/storage"
+	"github.com/hakim/reconpipe/pkg/models"
+)
+
+// Notification providers, selected by the scheme of the notify URL.
+const (
+	ProviderWebhook = "webhook" // http(s)://: the JSON payloads as they are
+	ProviderSlack   = "slack"   // slack://: a Block Kit message for a Slack incoming webhook
+	ProviderDiscord = "discord" // discord://: an embed for a Discord webhook
+)
+
+// ParseNotifyURL returns the provider raw selects and the URL to post to.
+// slack://hooks.slack.com/services/... and discord://discord.com/api/webhooks/...
+// are posted to the same address over https; http(s) URLs are posted as
+// they are, with the generic JSON payloads.
+func ParseNotifyURL(raw string) (provider, endpoint string, err error) {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return "", "", fmt.Errorf("notify URL %q must be an http(s), slack:// or discord:// URL", raw)
+	}
+	switch u.Scheme {
+	case "http", "https":
+		return ProviderWebhook, raw, nil
+	case "slack":
+		provider = ProviderSlack
+	case "discord":
+		provider = ProviderDiscord
+	default:
+		return "", "", fmt.Errorf("notify URL %q must be an http(s), slack:// or discord:// URL", raw)
+	}
+	u.Scheme = "https"
+	return provider, u.String(), nil
+}
+
+// Message colors: severities match the HTML and PDF reports.
+var severityColors = map[models.Severity]int{
+	models.SeverityCritical: 0x8b0000,
+	models.SeverityHigh:     0xd1242f,
+	models.SeverityMedium:   0xbc4c00,
+	models.SeverityLow:      0x0969da,
+	models.SeverityInfo:     0x656d76,
+}
+
+const (
+	colorFailed  = 0xd1242f
+	colorOK      = 0x1a7f37
+	colorNeutral = 0x656d76
+)
+
+// chatSeverityOrder is the order severities are counted and listed in.
+var chatSeverityOrder = []models.Severity{
+	models.SeverityCritical,
+	models.SeverityHigh,
+	models.SeverityMedium,
+	models.SeverityLow,
+	models.SeverityInfo,
+}
+
+// maxSectionLines caps the lines listed in one section of a chat message;
+// the rest are counted on a last line.
+const maxSectionLines = 10
+
+// chatMessage is a notification laid out for a chat provider, before it is
+// turned into a Slack or Discord payload. Text is plain; each provider
+// escapes it for its own markup.
+type chatMessage struct {
+	Title    string
+	Summary  string
+	Color    int
+	Facts    []chatFact    // short name/value pairs shown side by side
+	Sections []chatSection // titled lists
+}
+
+type chatFact struct{ Name, Value string }
+
+type chatSection struct {
+	Title string
+	Lines []string
+}
+
+// addSection appends a section listing lines, keeping the first
+// maxSectionLines. Sections without lines are left out.
+func (m *chatMessage) addSection(title string, lines []string) {
+	if len(lines) == 0 {
+		return
+	}
+	if len(lines) > maxSectionLines {
+		more := len(lines) - maxSectionLines
+		lines = append(lines[:maxSectionLines:maxSectionLines], fmt.Sprintf("… and %d more", more))
+	}
+	m.Sections = append(m.Sections, chatSection{Title: title, Lines: lines})
+}
+
+// completionMessage lays out the completion notification: the outcome, the
+// scan's findings per severity and, when the diff stage ran, what changed
+// since the previous scan. It is colored red when a stage failed and by the
+// most severe finding otherwise.
+func completionMessage(result *PipelineResult) chatMessage {
+	title := fmt.Sprintf("Scan of %s complete", result.Target)
+	switch result.Status {
+	case "partial":
+		title = fmt.Sprintf("Scan of %s finished with errors", result.Target)
+	case "interrupted":
+		title = fmt.Sprintf("Scan of %s interrupted", result.Target)
+	}
+	m := chatMessage{
+		Title: title,
+		Color: colorOK,
+		Facts: []chatFact{
+			{"Scan ID", result.ScanID},
+			{"Status", result.Status},
+			{"Elapsed", result.Elapsed.Round(time.Second).String()},
+			{"Stages", strings.Join(result.StagesRun, ", ")},
+		},
+	}
+
+	if counts, ok := scanSeverityCounts(result.ScanDir); ok {
+		m.Facts = append(m.Facts, chatFact{"Findings", formatSeverityCounts(counts)})
+		for _, sev := range chatSeverityOrder {
+			if counts[string(sev)] > 0 && sev != models.SeverityInfo {
+				m.Color = severityColors[sev]
+				break
+			}
+		}
+	}
+
+	if dr := scanDiff(result.ScanDir); dr != nil {
+		var changes []string
+		if n := len(dr.NewVulns); n > 0 {
+			changes = append(changes, fmt.Sprintf("%d new vulns", n))
+		}
+		if n := len(dr.NewSubdomains); n > 0 {
+			changes = append(changes, fmt.Sprintf("%d new subdomains", n))
+		}
+		if n := len(dr.NewPorts); n > 0 {
+			changes = append(changes, fmt.Sprintf("%d new open ports", n))
+		}
+		if n := len(dr.NewlyDangling); n > 0 {
+			changes = append(changes, fmt.Sprintf("%d newly dangling subdomains", n))
+		}
+		if n := len(dr.ClosedPorts); n > 0 {
+			changes = append(changes, fmt.Sprintf("%d ports closed", n))
+		}
+		if n := len(dr.ResolvedVulns); n > 0 {
+			changes = append(changes, fmt.Sprintf("%d vulns resolved", n))
+		}
+		if len(changes) == 0 {
+			changes = append(changes, "nothing new")
+		}
+		m.addSection("Changes since last scan", changes)
+		m.addSection("New vulnerabilities", vulnLines(dr.NewVulns))
+	}
+
+	if len(result.StageErrors) > 0 {
+		m.Color = colorFailed
+		stages := make([]string, 0, len(result.StageErrors))
+		for stage := range result.StageErrors {
+			stages = append(stages, stage)
+		}
+		sort.Strings(stages)
+		lines := make([]string, 0, len(stages))
+		for _, stage := range stages {
+			lines = append(lines, stage+": "+result.StageErrors[stage])
+		}
+		m.addSection("Stage errors", lines)
+	}
+	return m
+}
+
+// changesMessage lays out the monitor notification for the new entries of
+// dr, colored by the most severe new vuln.
+func changesMessage(schedule string, result *PipelineResult, dr *diff.DiffResult) chatMessage {
+	m := chatMessage{
+		Title: fmt.Sprintf("New findings on %s", result.Target),
+		Summary: fmt.Sprintf("%d new subdomains, %d new ports and %d new vulns since the last scan.",
+			len(dr.NewSubdomains), len(dr.NewPorts), len(dr.NewVulns)),
+		Color: colorNeutral,
+		Facts: []chatFact{
+			{"Schedule", schedule},
+			{"Scan ID", result.ScanID},
+			{"Status", result.Status},
+		},
+	}
+	if len(dr.NewVulns) > 0 {
+		m.Color = worstColor(dr.NewVulns)
+	}
+
+	m.addSection("New vulnerabilities", vulnLines(dr.NewVulns))
+	var ports []string
+	for _, pc := range dr.NewPorts {
+		host := pc.Host
+		if host == "" {
+			host = pc.IP
+		}
+		line := fmt.Sprintf("%s:%d/%s", host, pc.Port.Number, pc.Port.Protocol)
+		if pc.Port.Service != "" {
+			line += " (" + pc.Port.Service + ")"
+		}
+		ports = append(ports, line)
+	}
+	m.addSection("New ports", ports)
+	var subs []string
+	for _, s := range dr.NewSubdomains {
+		subs = append(subs, s.Name)
+	}
+	m.addSection("New subdomains", subs)
+	return m
+}
+
+// findingsMessage lays out the finding alerts of one scan, colored by the
+// most severe new or escalated finding, or green when findings were only
+// resolved.
+func findingsMessage(result *PipelineResult, alerts []FindingAlert) chatMessage {
+	m := chatMessage{
+		Title: fmt.Sprintf("Finding alerts for %s", result.Target),
+		Color: colorOK,
+		Facts: []chatFact{{"Scan ID", result.ScanID}},
+	}
+	worst := 0
+	byEvent := map[string][]string{}
+	counts := map[string]int{}
+	for _, a := range alerts {
+		counts[a.Event]++
+		line := fmt.Sprintf("[%s] %s on %s", a.Severity, a.Name, a.Host)
+		if a.Event == AlertEscalated {
+			line = fmt.Sprintf("[%s, was %s] %s on %s", a.Severity, a.PreviousSeverity, a.Name, a.Host)
+		}
+		if a.Kind == models.FindingKindDangling {
+			line += " (dangling DNS)"
+		}
+		byEvent[a.Event] = append(byEvent[a.Event], line)
+		if a.Event != AlertResolved && alertSeverityRank[a.Severity] > worst {
+			worst = alertSeverityRank[a.Severity]
+			m.Color = severityColors[a.Severity]
+		}
+	}
+	m.Summary = fmt.Sprintf("%d new, %d escalated, %d resolved.", counts[AlertNew], counts[AlertEscalated], counts[AlertResolved])
+	m.addSection("New", byEvent[AlertNew])
+	m.addSection("Escalated", byEvent[AlertEscalated])
+	m.addSection("Resolved", byEvent[AlertResolved])
+	return m
+}
+
+// vulnLines lists vulns most severe first, one line each.
+func vulnLines(vulns []models.Vulnerability) []string {
+	sorted := make([]models.Vulnerability, len(vulns))
+	copy(sorted, vulns)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return alertSeverityRank[sorted[i].Severity] > alertSeverityRank[sorted[j].Severity]
+	})
+	lines := make([]string, 0, len(sorted))
+	for _, v := range sorted {
+		where := v.MatchedAt
+		if where == "" {
+			where = v.Host
+		}
+		lines = append(lines, fmt.Sprintf("[%s] %s at %s", v.Severity, v.Name, where))
+	}
+	return lines
+}
+
+// worstColor is the color of the most severe of vulns.
+func worstColor(vulns []models.Vulnerability) int {
+	worst := models.SeverityInfo
+	for _, v := range vulns {
+		if alertSeverityRank[v.Severity] > alertSeverityRank[worst] {
+			worst = v.Severity
+		}
+	}
+	return severityColors[worst]
+}
+
+// formatSeverityCounts renders counts as "2 critical, 1 high", or "none".
+func formatSeverityCounts(counts map[string]int) string {
+	var parts []string
+	for _, sev := range chatSeverityOrder {
+		if n := counts[string(sev)]; n > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", n, sev))
+		}
+	}
+	if len(parts) == 0 {
+		return "none"
+	}
+	return strings.Join(parts, ", ")
+}
+
+// scanSeverityCounts reads the per-severity finding counts of the scan's
+// vulns.json; ok is false when the vulnscan stage wrote none.
+func scanSeverityCounts(scanDir string) (counts map[string]int, ok bool) {
+	data, err := os.ReadFile(storage.RawPath(scanDir, "vulns.json"))
+	if err != nil {
+		return nil, false
+	}
+	var v struct {
+		SeverityCounts map[string]int `json:"severity_counts"`
+	}
+	if json.Unmarshal(data, &v) != nil {
+		return nil, false
+	}
+	return v.SeverityCounts, true
+}
+
+// scanDiff reads the scan's diff.json, or returns nil when the diff stage
+// wrote none.
+func scanDiff(scanDir string) *diff.DiffResult {
+	data, err := os.ReadFile(storage.RawPath(scanDir, "diff.json"))
+	if err != nil {
+		return nil
+	}
+	var dr diff.DiffResult
+	if json.Unmarshal(data, &dr) != nil {
+		return nil
+	}
+	return &dr
+}
+
+// Slack limits: https://api.slack.com/reference/block-kit/blocks
+const (
+	slackHeaderMax  = 150
+	slackSectionMax = 3000
+	slackFieldsMax  = 10
+)
+
+// slackPayload renders m as a Slack incoming-webhook message: Block Kit
+// blocks inside an attachment, which carries the color bar, and the title as
+// the plain-text fallback for notifications.
+func (m chatMessage) slackPayload() map[string]any {
+	blocks := []map[string]any{{
+		"type": "header",
+		"text": map[string]any{"type": "plain_text", "text": truncate(m.Title, slackHeaderMax)},
+	}}
+	if m.Summary != "" {
+		blocks = append(blocks, slackSection(slackEscape(m.Summary)))
+	}
+	if len(m.Facts) > 0 {
+		var fields []map[string]any
+		for _, f := range m.Facts {
+			if f.Value == "" || len(fields) == slackFieldsMax {
+				continue
+			}
+			fields = append(fields, map[string]any{
+				"type": "mrkdwn",
+				"text": truncate("*"+f.Name+"*\n"+slackEscape(f.Value), 2000),
+			})
+		}
+		blocks = append(blocks, map[string]any{"type": "section", "fields": fields})
+	}
+	for _, s := range m.Sections {
+		var b strings.Builder
+		b.WriteString("*" + slackEscape(s.Title) + "*")
+		for _, line := range s.Lines {
+			b.WriteString("\n• " + slackEscape(line))
+		}
+		blocks = append(blocks, slackSection(truncate(b.String(), slackSectionMax)))
+	}
+
+	return map[string]any{
+		"text": m.Title,
+		"attachments": []map[string]any{{
+			"color":  fmt.Sprintf("#%06x", m.Color),
+			"blocks": blocks,
+		}},
+	}
+}
+
+func slackSection(mrkdwn string) map[string]any {
+	return map[string]any{"type": "section", "text": map[string]any{"type": "mrkdwn", "text": mrkdwn}}
+}
+
+// slackEscape escapes the characters Slack treats as markup.
+func slackEscape(s string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(s)
+}
+
+// Discord limits: https://discord.com/developers/docs/resources/message#embed-object-embed-limits
+const (
+	discordTitleMax       = 256
+	discordDescriptionMax = 4096
+	discordFieldValueMax  = 1024
+	discordFieldsMax      = 25
+)
+
+// discordPayload renders m as a Discord webhook message with one embed: the
+// facts as inline fields and each section as a full-width field.
+func (m chatMessage) discordPayload() map[string]any {
+	var fields []map[string]any
+	for _, f := range m.Facts {
+		if f.Value != "" {
+			fields = append(fields, map[string]any{
+				"name": f.Name, "value": truncate(discordEscape(f.Value), discordFieldValueMax), "inline": true,
+			})
+		}
+	}
+	for _, s := range m.Sections {
+		lines := make([]string, len(s.Lines))
+		for i, line := range s.Lines {
+			lines[i] = "• " + discordEscape(line)
+		}
+		fields = append(fields, map[string]any{
+			"name": s.Title, "value": truncate(strings.Join(lines, "\n"), discordFieldValueMax),
+		})
+	}
+	if len(fields) > discordFieldsMax {
+		fields = fields[:discordFieldsMax]
+	}
+
+	embed := map[string]any{
+		"title":     truncate(m.Title, discordTitleMax),
+		"color":     m.Color,
+		"fields":    fields,
+		"footer":    map[string]any{"text": "reconpipe"},
+		"timestamp": time.Now().UTC().Format(time.RFC3339),
+	}
+	if m.Summary != "" {
+		embed["description"] = truncate(discordEscape(m.Summary), discordDescriptionMax)
+	}
+	return map[string]any{"embeds": []map[string]any{embed}}
+}
+
+// discordEscape escapes the characters Discord treats as markdown.
+func discordEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "*", `\*`, "_", `\_`, "~", `\~`, "`", "\\`", "|", `\|`).Replace(s)
+}
+
+// truncate shortens s to at most max runes, ending it with an ellipsis.
+func truncate(s string, max int) string {
+	r := []rune(s)
+	if len(r) <= max {
+		return s
+	}
+	return string(r[:max-1]) + "…"
+}
@@ -0,0 +1,76 @@
+package pipeline
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/hakim/reconpipe/pkg/models"
+)
+
+// ExpireFindings closes the target's open findings that the last `after`
+// completed scans did not report. A scan normally resolves a finding itself
+// when it looks for it and finds it gone, but a finding whose stage has
+// stopped running (a preset without vulnscan, a vulnscan always cut short)
+// would otherwise stay open in the inventory forever.
+//
+// scans are the target's scans in any order; only completed ones started
+// after a finding was last seen count as missing it. Closed findings are
+// marked Expired with their ScansMissed and returned, most severe first.
+// With dryRun the store is left untouched.
+func ExpireFindings(store AlertStore, target string, scans []*models.ScanMeta, after int, dryRun bool) ([]*models.NotificationState, error) {
+	if after < 1 {
+		return nil, fmt.Errorf("after must be at least 1, got %d", after)
+	}
+
+	var completed []*models.ScanMeta
+	for _, scan := range scans {
+		if scan.Status == models.StatusComplete {
+			completed = append(completed, scan)
+		}
+	}
+	if len(completed) == 0 {
+		return nil, nil
+	}
+	sort.Slice(completed, func(i, j int) bool { return completed[i].StartedAt.After(completed[j].StartedAt) })
+
+	states, err := store.ListNotificationStates(target)
+	if err != nil {
+		return nil, fmt.Errorf("loading finding inventory: %w", err)
+	}
+
+	now := time.Now().UTC()
+	var expired []*models.NotificationState
+	for _, st := range states {
+		if st.ResolvedAt != nil {
+			continue
+		}
+		missed := 0
+		for _, scan := range completed {
+			if !scan.StartedAt.After(st.LastSeen) {
+				break
+			}
+			missed++
+		}
+		if missed < after {
+			continue
+		}
+		closed := now
+		st.ResolvedAt = &closed
+		st.Expired, st.ScansMissed = true, missed
+		st.LastScanID = completed[0].ID
+		expired = append(expired, st)
+	}
+
+	sort.SliceStable(expired, func(i, j int) bool {
+		return alertSeverityRank[expired[i].Severity] > alertSeverityRank[expired[j].Severity]
+	})
+
+	if dryRun || len(expired) == 0 {
+		return expired, nil
+	}
+	if err := store.SaveNotificationStates(expired); err != nil {
+		return nil, fmt.Errorf("saving finding inventory: %w", err)
+	}
+	return expired, nil
+}
@@ -0,0 +1,46 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hakim/reconpipe/internal/hooks"
+)
+
+// hookRunner fires the configured hooks for one pipeline run.
+type hookRunner struct {
+	commands map[string]string
+	target   string
+	scanID   string
+	scanDir  string
+}
+
+// run executes the hook for when ("pre" or "post") and point (a stage name
+// or "scan"), if one is configured. status and stageErr are only meaningful
+// for post hooks. Hook failures are reported but never fail the scan.
+func (h *hookRunner) run(ctx context.Context, when, point, status string, stageErr error, stagesRun []string) {
+	name := hooks.Name(when, point)
+	command := h.commands[name]
+	if command == "" {
+		return
+	}
+
+	data := hooks.NewData(name, point, h.target, h.scanID, h.scanDir)
+	data.StagesRun = stagesRun
+	if when == "post" {
+		data.Status = status
+		if stageErr != nil {
+			data.Error = stageErr.Error()
+		}
+	}
+	summary, err := hooks.LoadSummary(h.scanDir)
+	if err != nil {
+		fmt.Printf("[!] Warning: hook %s: could not summarise results: %v\n", name, err)
+	}
+	data.Summary = summary
+
+	fmt.Printf("[*] Running hook %s\n", name)
+	if err := hooks.Run(ctx, command, data); err != nil {
+		fmt.Printf("[!] Warning: %v\n", err)
+	}
+}
@@ -0,0 +1,39 @@
+package pipeline
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/hakim/reconpipe/internal/notes"
+	"github.com/hakim/reconpipe/internal/report"
+	"github.com/hakim/reconpipe/internal/storage"
+	"github.com/hakim/reconpipe/pkg/scanstore"
+)
+
+// NoteStore is the store contract AttachNotes needs.
+type NoteStore = scanstore.NoteStore
+
+// AttachNotes copies the target's notes that apply to the scan in scanDir
+// into its raw/notes.json and rewrites reports/notes.md, and returns how
+// many apply. Scans of a target that never had notes are left without
+// either file.
+func AttachNotes(store NoteStore, target, scanDir string) (int, error) {
+	all, err := store.ListNotes(target)
+	if err != nil {
+		return 0, fmt.Errorf("loading notes: %w", err)
+	}
+	if len(all) == 0 {
+		if _, err := os.Stat(storage.RawPath(scanDir, notes.File)); os.IsNotExist(err) {
+			return 0, nil
+		}
+	}
+
+	sn, err := notes.Write(scanDir, target, all)
+	if err != nil {
+		return 0, err
+	}
+	if err := report.WriteNotesReport(sn, storage.ReportPath(scanDir, "notes.md")); err != nil {
+		return 0, err
+	}
+	return len(sn.Notes), nil
+}
@@ -6,11 +6,15 @@ import (
 	"fmt"
 	"net/http"
 	"time"
+
+	"github.com/hakim/reconpipe/internal/diff"
+	"github.com/hakim/reconpipe/internal/riskpolicy"
 )
 
 // NotifyConfig configures where to send completion notifications.
 type NotifyConfig struct {
-	WebhookURL string // if empty, no notifications
+	WebhookURL string             // if empty, no notifications; slack:// and discord:// select a chat provider (see ParseNotifyURL)
+	Policy     *riskpolicy.Policy // decides which findings are alerted on and whether the completion is sent
 }
 
 // completionPayload is the JSON body posted to the webhook endpoint.
@@ -23,8 +27,23 @@ type completionPayload struct {
 	Errors         map[string]string `json:"errors"`
 }
 
-// SendCompletion posts a JSON payload to the webhook URL with scan results.
-// Returns nil if WebhookURL is empty (no-op). Non-fatal — errors are returned
+// CompletionDue reports whether the completion notification for result
+// should be sent: always when the policy has no notify_on rules or a stage
+// failed, otherwise only when one of the scan's findings matches them.
+func (n *NotifyConfig) CompletionDue(result *PipelineResult) (bool, error) {
+	if n == nil || !n.Policy.Configured(riskpolicy.Notify) || len(result.StageErrors) > 0 {
+		return true, nil
+	}
+	findings, err := riskpolicy.ScanFindings(result.ScanDir)
+	if err != nil {
+		return true, err
+	}
+	return len(n.Policy.Filter(riskpolicy.Notify, findings)) > 0, nil
+}
+
+// SendCompletion posts a JSON payload to the webhook URL with scan results,
+// or for Slack and Discord a message with the findings per severity and what
+// changed since the previous scan. Returns nil if WebhookURL is empty (no-op). Non-fatal — errors are returned
 // but callers should treat them as warnings.
 func (n *NotifyConfig) SendCompletion(result *PipelineResult) error {
 	if n == nil || n.WebhookURL == "" {
@@ -40,15 +59,32 @@ func (n *NotifyConfig) SendCompletion(result *PipelineResult) error {
 		Errors:         result.StageErrors,
 	}
 
+	return n.send(payload, func() chatMessage { return completionMessage(result) })
+}
+
+// send posts payload to the webhook URL, or the chat message built by
+// message when the URL selects Slack or Discord.
+func (n *NotifyConfig) send(payload any, message func() chatMessage) error {
+	provider, endpoint, err := ParseNotifyURL(n.WebhookURL)
+	if err != nil {
+		return fmt.Errorf("notify: %w", err)
+	}
+	switch provider {
+	case ProviderSlack:
+		payload = message().slackPayload()
+	case ProviderDiscord:
+		payload = message().discordPayload()
+	}
+
 	body, err := json.Marshal(payload)
 	if err != nil {
 		return fmt.Errorf("notify: marshaling payload: %w", err)
 	}
 
 	client := &http.Client{Timeout: 10 * time.Second}
-	resp, err := client.Post(n.WebhookURL, "application/json", bytes.NewReader(body))
+	resp, err := client.Post(endpoint, "application/json", bytes.NewReader(body))
 	if err != nil {
-		return fmt.Errorf("notify: posting to %s: %w", n.WebhookURL, err)
+		return fmt.Errorf("notify: posting to %s: %w", endpoint, err)
 	}
 	defer resp.Body.Close()
 
@@ -58,3 +94,81 @@ func (n *NotifyConfig) SendCompletion(result *PipelineResult) error {
 
 	return nil
 }
+
+// changesPayload is the JSON body posted when a scheduled scan's diff shows
+// something new.
+type changesPayload struct {
+	Schedule      string        `json:"schedule"`
+	Target        string        `json:"target"`
+	ScanID        string        `json:"scan_id"`
+	Status        string        `json:"status"`
+	NewSubdomains []string      `json:"new_subdomains"`
+	NewPorts      []changedPort `json:"new_ports"`
+	NewVulns      []changedVuln `json:"new_vulns"`
+}
+
+// changedPort is a new port in changesPayload.
+type changedPort struct {
+	Host     string `json:"host,omitempty"`
+	IP       string `json:"ip"`
+	Port     int    `json:"port"`
+	Protocol string `json:"protocol"`
+	Service  string `json:"service,omitempty"`
+}
+
+// changedVuln is a new vuln in changesPayload.
+type changedVuln struct {
+	TemplateID string `json:"template_id"`
+	Name       string `json:"name"`
+	Severity   string `json:"severity"`
+	Host       string `json:"host"`
+	MatchedAt  string `json:"matched_at,omitempty"`
+}
+
+// HasNew reports whether dr shows new subdomains, ports or vulns.
+func HasNew(dr *diff.DiffResult) bool {
+	return dr != nil && len(dr.NewSubdomains)+len(dr.NewPorts)+len(dr.NewVulns) > 0
+}
+
+// SendChanges posts the new subdomains, ports and vulns of dr, the diff of
+// the scan in result, to the webhook URL or as a Slack or Discord message. schedule names the monitor
+// schedule that ran the scan. Returns nil without posting if WebhookURL is
+// empty or dr has nothing new.
+func (n *NotifyConfig) SendChanges(schedule string, result *PipelineResult, dr *diff.DiffResult) error {
+	if n == nil || n.WebhookURL == "" || !HasNew(dr) {
+		return nil
+	}
+
+	payload := changesPayload{
+		Schedule:      schedule,
+		Target:        result.Target,
+		ScanID:        result.ScanID,
+		Status:        result.Status,
+		NewSubdomains: []string{},
+		NewPorts:      []changedPort{},
+		NewVulns:      []changedVuln{},
+	}
+	for _, s := range dr.NewSubdomains {
+		payload.NewSubdomains = append(payload.NewSubdomains, s.Name)
+	}
+	for _, pc := range dr.NewPorts {
+		payload.NewPorts = append(payload.NewPorts, changedPort{
+			Host:     pc.Host,
+			IP:       pc.IP,
+			Port:     pc.Port.Number,
+			Protocol: pc.Port.Protocol,
+			Service:  pc.Port.Service,
+		})
+	}
+	for _, v := range dr.NewVulns {
+		payload.NewVulns = append(payload.NewVulns, changedVuln{
+			TemplateID: v.TemplateID,
+			Name:       v.Name,
+			Severity:   string(v.Severity),
+			Host:       v.Host,
+			MatchedAt:  v.MatchedAt,
+		})
+	}
+
+	return n.send(payload, func() chatMessage { return changesMessage(schedule, result, dr) })
+}
@@ -3,20 +3,21 @@ package pipeline
 import (
 	"context"
 	"fmt"
+	"path/filepath"
 	"time"
 
 	"github.com/hakim/reconpipe/internal/config"
-	"github.com/hakim/reconpipe/internal/models"
+	"github.com/hakim/reconpipe/internal/export"
 	"github.com/hakim/reconpipe/internal/storage"
+	"github.com/hakim/reconpipe/internal/tools"
+	"github.com/hakim/reconpipe/pkg/models"
+	"github.com/hakim/reconpipe/pkg/scanstore"
 )
 
 // StoreInterface is the minimal bbolt contract required by the orchestrator.
-// Using an interface keeps the package testable without a real database.
-type StoreInterface interface {
-	SaveScan(meta *models.ScanMeta) error
-	ListScans(target string) ([]*models.ScanMeta, error)
-	UpdateScanStatus(id string, status models.ScanStatus) error
-}
+// Using an interface keeps the package testable without a real database;
+// it is defined in pkg/scanstore so that other modules can implement it.
+type StoreInterface = scanstore.Store
 
 // StageFunc is the signature each pipeline stage must satisfy.
 // ctx carries the deadline; scanDir is the root directory for all I/O.
@@ -37,6 +38,11 @@ type PipelineConfig struct {
 	// If empty, a new directory is created via storage.CreateScanDir.
 	ScanDir string
 
+	// Preset and Tag label the run; they fill the {preset} and {tag}
+	// placeholders of the scan directory template.
+	Preset string
+	Tag    string
+
 	// Stages is the ordered allow-list of stage names to run.
 	// Empty means "run all stages defined in allStages".
 	Stages []string
@@ -52,6 +58,28 @@ type PipelineConfig struct {
 	// Zero means no timeout beyond the caller's context.
 	Timeout time.Duration
 
+	// Operator is who launched the run. It is stored on new scan records and
+	// in the audit log.
+	Operator string
+
+	// RunConfig records the settings this run was started with. It is stored
+	// on the scan record and in raw/run-config.json for new scans; resumed
+	// scans keep the record of their original run.
+	RunConfig *models.RunConfig
+
+	// Policy limits how often and how concurrently Target is scanned. Nil
+	// applies no limits, as when the operator overrides the policy.
+	Policy *TargetPolicy
+
+	// KeepToolOutput retains the raw stdout and stderr of every tool run
+	// under raw/tool-logs/, filed by stage, for debugging.
+	KeepToolOutput bool
+
+	// Stop, when closed, ends the run at the next stage boundary instead of
+	// cancelling the stage in progress. The scan is recorded as interrupted
+	// and can be resumed later. Nil never stops.
+	Stop <-chan struct{}
+
 	// OnStageStart is called immediately before each stage executes.
 	// index is 0-based; total is the count of stages selected to run.
 	OnStageStart func(name string, index, total int)
@@ -82,8 +110,12 @@ type PipelineResult struct {
 	// Elapsed is the total wall time from the first stage to the last.
 	Elapsed time.Duration
 
+	// StageTimes is how long each attempted stage took.
+	StageTimes map[string]time.Duration
+
 	// Status is "complete" when every selected stage succeeded, "partial" when
-	// at least one stage failed but execution continued past it.
+	// at least one stage failed but execution continued past it, and
+	// "interrupted" when Stop ended the run before every stage was attempted.
 	Status string
 }
 
@@ -104,7 +136,7 @@ type PipelineResult struct {
 //
 // The bbolt record is created (StatusRunning) before the first stage and
 // updated to StatusComplete or StatusFailed once all stages have been
-// attempted.
+// attempted, or to StatusInterrupted when cfg.Stop ends the run early.
 func RunPipeline(
 	ctx context.Context,
 	cfg PipelineConfig,
@@ -127,7 +159,24 @@ func RunPipeline(
 		return nil, fmt.Errorf("pipeline: no stages remain after filtering")
 	}
 
-	// ── 3. Apply optional timeout ─────────────────────────────────────────────
+	// ── 3. Enforce the target policy ──────────────────────────────────────────
+	if cfg.Policy != nil {
+		// A resumed scan continues an earlier run, so only the lock applies
+		if !cfg.Resume {
+			if err := CheckCooldown(store, cfg.Target, cfg.Policy.Cooldown, time.Now()); err != nil {
+				return nil, err
+			}
+		}
+		if !cfg.Policy.AllowConcurrent {
+			release, err := lockTarget(appCfg.ScanDir, cfg.Target)
+			if err != nil {
+				return nil, err
+			}
+			defer release()
+		}
+	}
+
+	// ── 4. Apply optional timeout ─────────────────────────────────────────────
 	runCtx := ctx
 	if cfg.Timeout > 0 {
 		var cancel context.CancelFunc
@@ -135,20 +184,25 @@ func RunPipeline(
 		defer cancel()
 	}
 
-	// ── 4. Resolve or create the scan directory ───────────────────────────────
+	// ── 5. Resolve or create the scan directory ───────────────────────────────
 	scanDir := cfg.ScanDir
 	startedAt := time.Now()
 
 	if scanDir == "" {
 		var err error
-		scanDir, err = storage.CreateScanDir(appCfg.ScanDir, cfg.Target, startedAt)
+		scanDir, err = storage.CreateScanDir(appCfg.ScanDir, storage.DirVars{
+			Target:    cfg.Target,
+			StartedAt: startedAt,
+			Preset:    cfg.Preset,
+			Tag:       cfg.Tag,
+		})
 		if err != nil {
 			return nil, fmt.Errorf("pipeline: creating scan directory: %w", err)
 		}
 		fmt.Printf("[*] Created scan directory: %s\n", scanDir)
 	}
 
-	// ── 5. Resume: find prior scan and determine already-completed stages ──────
+	// ── 6. Resume: find prior scan and determine already-completed stages ──────
 	alreadyDone := map[string]bool{}
 	var meta *models.ScanMeta
 
@@ -166,16 +220,23 @@ func RunPipeline(
 		}
 	}
 
-	// ── 6. Create or reuse the bbolt scan record ──────────────────────────────
+	// ── 7. Create or reuse the bbolt scan record ──────────────────────────────
 	if meta == nil {
 		scan := models.NewScan(cfg.Target)
 		scan.ScanDir = scanDir
 		scan.Status = models.StatusRunning
+		scan.RunConfig = cfg.RunConfig
+		scan.Operator = cfg.Operator
 		if err := store.SaveScan(&scan.ScanMeta); err != nil {
 			return nil, fmt.Errorf("pipeline: saving initial scan record: %w", err)
 		}
 		meta = &scan.ScanMeta
 		fmt.Printf("[*] Scan ID: %s\n", meta.ID)
+		if cfg.RunConfig != nil {
+			if err := storage.WriteRunConfig(scanDir, cfg.RunConfig); err != nil {
+				fmt.Printf("[!] Warning: could not record run config: %v\n", err)
+			}
+		}
 	} else {
 		// Re-mark a previously failed/complete scan as running again.
 		if err := store.UpdateScanStatus(meta.ID, models.StatusRunning); err != nil {
@@ -184,17 +245,39 @@ func RunPipeline(
 		}
 	}
 
-	// ── 7. Execute stages ─────────────────────────────────────────────────────
+	action := "scan.start"
+	if len(alreadyDone) > 0 {
+		action = "scan.resume"
+	}
+	audit(store, models.AuditEntry{
+		Operator: cfg.Operator,
+		Action:   action,
+		Target:   cfg.Target,
+		ScanID:   meta.ID,
+		Detail:   scanDir,
+	})
+
+	// ── 8. Execute stages ─────────────────────────────────────────────────────
 	result := &PipelineResult{
 		Target:      cfg.Target,
 		ScanDir:     scanDir,
 		ScanID:      meta.ID,
 		StageErrors: make(map[string]string),
+		StageTimes:  make(map[string]time.Duration),
+	}
+
+	hook := &hookRunner{
+		commands: appCfg.Hooks,
+		target:   cfg.Target,
+		scanID:   meta.ID,
+		scanDir:  scanDir,
 	}
+	hook.run(runCtx, "pre", "scan", "", nil, nil)
 
 	pipelineStart := time.Now()
 	total := len(selected)
 
+	interrupted := false
 	for i, stage := range selected {
 		// Skip stages already completed in a prior run.
 		if alreadyDone[stage.Name] {
@@ -202,15 +285,29 @@ func RunPipeline(
 			continue
 		}
 
+		// Stage boundaries are the safe point to stop: everything finished
+		// so far is already saved for a resume.
+		if stopRequested(cfg.Stop) {
+			fmt.Printf("[*] Stop requested — ending scan %s before stage %q\n", meta.ID, stage.Name)
+			interrupted = true
+			break
+		}
+
 		if cfg.OnStageStart != nil {
 			cfg.OnStageStart(stage.Name, i, total)
 		}
+		hook.run(runCtx, "pre", stage.Name, "", nil, result.StagesRun)
 
 		stageStart := time.Now()
-		stageErr := runStageIsolated(runCtx, stage, scanDir)
+		stageCtx := runCtx
+		if cfg.KeepToolOutput {
+			stageCtx = tools.WithToolLog(runCtx, filepath.Join(storage.RawDir(scanDir), "tool-logs"), stage.Name)
+		}
+		stageErr := runStageIsolated(stageCtx, stage, scanDir)
 		stageElapsed := time.Since(stageStart)
 
 		result.StagesRun = append(result.StagesRun, stage.Name)
+		result.StageTimes[stage.Name] = stageElapsed
 
 		if stageErr != nil {
 			result.StageErrors[stage.Name] = stageErr.Error()
@@ -222,6 +319,11 @@ func RunPipeline(
 		if cfg.OnStageDone != nil {
 			cfg.OnStageDone(stage.Name, i, total, stageErr, stageElapsed)
 		}
+		stageStatus := string(models.StatusComplete)
+		if stageErr != nil {
+			stageStatus = string(models.StatusFailed)
+		}
+		hook.run(runCtx, "post", stage.Name, stageStatus, stageErr, result.StagesRun)
 
 		// Persist the updated StagesRun list after each successful stage so that
 		// a crash mid-pipeline leaves a recoverable state in bbolt.
@@ -236,22 +338,88 @@ func RunPipeline(
 
 	result.Elapsed = time.Since(pipelineStart)
 
-	// ── 8. Determine final status and persist ─────────────────────────────────
+	// ── 9. Determine final status and persist ─────────────────────────────────
+	// A stage cut short because the caller gave up waiting for Stop counts
+	// as interrupted too; it runs again on resume.
+	interrupted = interrupted || (stopRequested(cfg.Stop) && ctx.Err() != nil)
 	finalStatus, resultStatus := resolveFinalStatus(result.StagesRun, result.StageErrors, selected)
+	if interrupted {
+		finalStatus, resultStatus = models.StatusInterrupted, "interrupted"
+	}
 	result.Status = resultStatus
 
 	if err := store.UpdateScanStatus(meta.ID, finalStatus); err != nil {
 		fmt.Printf("[!] Warning: could not update final scan status: %v\n", err)
 	}
 
+	// Point {target}.latest at this run so scripts and later commands find it
+	if finalStatus == models.StatusComplete {
+		if err := storage.MarkLatestScan(appCfg.ScanDir, cfg.Target, scanDir); err != nil {
+			fmt.Printf("[!] Warning: could not update latest scan pointer: %v\n", err)
+		}
+	}
+
+	// Carry the target's analyst notes over to this scan, before the sinks
+	// publish it
+	if ns, ok := store.(NoteStore); ok {
+		if n, err := AttachNotes(ns, cfg.Target, scanDir); err != nil {
+			fmt.Printf("[!] Warning: could not attach analyst notes: %v\n", err)
+		} else if n > 0 {
+			fmt.Printf("[+] %d analyst note(s) attached to this scan\n", n)
+		}
+	}
+
+	// Mirror results into the configured output sinks once any stage has
+	// produced something worth publishing. The scan directory already holds
+	// everything, so failures only warn; 'reconpipe export --sinks' retries.
+	if len(appCfg.OutputSinks) > 0 && !interrupted && len(result.StagesRun) > len(result.StageErrors) {
+		sinks, err := export.OutputSinksFromConfig(appCfg.OutputSinks)
+		if err == nil {
+			meta.Status = finalStatus
+			if meta.CompletedAt == nil {
+				now := time.Now()
+				meta.CompletedAt = &now
+			}
+			err = export.PublishAll(ctx, sinks, meta)
+		}
+		if err != nil {
+			fmt.Printf("[!] Warning: publishing results: %v\n", err)
+		}
+	}
+
 	fmt.Printf("[*] Pipeline finished in %s — status: %s\n",
 		result.Elapsed.Round(time.Millisecond), result.Status)
 
+	// Written before post_scan so the hook can read it
+	if err := writeSummary(result, meta, selected, alreadyDone); err != nil {
+		fmt.Printf("[!] Warning: could not write %s: %v\n", SummaryFile, err)
+	}
+
+	audit(store, models.AuditEntry{
+		Operator: cfg.Operator,
+		Action:   "scan.finish",
+		Target:   cfg.Target,
+		ScanID:   meta.ID,
+		Detail:   result.Status,
+	})
+
+	// The run's own deadline may already have passed; give post_scan the
+	// caller's context instead.
+	hook.run(ctx, "post", "scan", result.Status, nil, result.StagesRun)
+
 	return result, nil
 }
 
 // ── Helpers ───────────────────────────────────────────────────────────────────
 
+// audit appends entry to the audit log. A failed write is reported but never
+// stops the scan.
+func audit(store StoreInterface, entry models.AuditEntry) {
+	if err := store.AppendAudit(entry); err != nil {
+		fmt.Printf("[!] Warning: could not write audit log: %v\n", err)
+	}
+}
+
 // filterStages applies the allow-list (allowNames) and deny-list (skipNames)
 // to allStages, preserving the order defined in allStages.
 func filterStages(allStages []Stage, allowNames, skipNames []string) []Stage {
@@ -333,6 +501,16 @@ func resolveFinalStatus(stagesRun []string, stageErrors map[string]string, selec
 	return models.StatusFailed, "partial"
 }
 
+// stopRequested reports whether stop has been closed.
+func stopRequested(stop <-chan struct{}) bool {
+	select {
+	case <-stop:
+		return true
+	default:
+		return false
+	}
+}
+
 // appendUnique appends s to slice only if it is not already present.
 func appendUnique(slice []string, s string) []string {
 	for _, existing := range slice {
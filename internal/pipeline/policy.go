@@ -0,0 +1,157 @@
+package pipeline
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// TargetPolicy limits how often and how concurrently one target is scanned,
+// so overlapping runs don't hammer a client's infrastructure. The zero value
+// allows back-to-back scans but never two at once.
+type TargetPolicy struct {
+	// Cooldown is the minimum time between the starts of two scans of the
+	// same target. Zero means no cooldown.
+	Cooldown time.Duration
+
+	// AllowConcurrent lets more than one run scan the target at a time.
+	AllowConcurrent bool
+}
+
+// PolicyError is returned when a TargetPolicy refuses a scan.
+type PolicyError struct {
+	Target string
+	Reason string
+
+	// RetryAfter is how long until the cooldown ends; zero when the scan is
+	// refused because another one is running.
+	RetryAfter time.Duration
+}
+
+func (e *PolicyError) Error() string {
+	return "scan policy: " + e.Reason
+}
+
+// CheckCooldown returns a *PolicyError when a scan of target started less
+// than cooldown before now. Only scans that completed at least one stage
+// count; a run that failed before touching the target does not.
+func CheckCooldown(store StoreInterface, target string, cooldown time.Duration, now time.Time) error {
+	if cooldown <= 0 {
+		return nil
+	}
+	scans, err := store.ListScans(target)
+	if err != nil {
+		return fmt.Errorf("checking scan cooldown: %w", err)
+	}
+	for _, s := range scans {
+		if len(s.StagesRun) == 0 {
+			continue
+		}
+		if wait := s.StartedAt.Add(cooldown).Sub(now); wait > 0 {
+			return &PolicyError{
+				Target: target,
+				Reason: fmt.Sprintf("%s was last scanned %s ago and the cooldown is %s; next scan allowed at %s",
+					target, now.Sub(s.StartedAt).Round(time.Second), cooldown, s.StartedAt.Add(cooldown).Format("2006-01-02 15:04")),
+				RetryAfter: wait,
+			}
+		}
+		break // ListScans is newest first
+	}
+	return nil
+}
+
+// heldLocks records the lock files this process holds, so a lock carrying
+// our PID but not held by us is known to be left over from an earlier
+// process that had the same PID (as when a container restarts).
+var heldLocks sync.Map
+
+// targetLock is the content of a target's lock file.
+type targetLock struct {
+	PID       int       `json:"pid"`
+	Host      string    `json:"host"`
+	StartedAt time.Time `json:"started_at"`
+}
+
+// lockTarget marks target as being scanned by this process until release is
+// called, failing with a *PolicyError while another live process (or this
+// one) holds the lock. Locks live in scanRoot/.locks so every reconpipe
+// process sharing the scan directory sees them; a lock left behind by a
+// process that has exited is taken over.
+func lockTarget(scanRoot, target string) (release func(), err error) {
+	dir := filepath.Join(scanRoot, ".locks")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("creating lock directory: %w", err)
+	}
+	path := filepath.Join(dir, strings.NewReplacer("/", "_", `\`, "_").Replace(target)+".lock")
+	host, _ := os.Hostname()
+	data, _ := json.Marshal(targetLock{PID: os.Getpid(), Host: host, StartedAt: time.Now().UTC()})
+
+	for attempt := 0; attempt < 2; attempt++ {
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+		if err == nil {
+			_, werr := f.Write(data)
+			if cerr := f.Close(); werr == nil {
+				werr = cerr
+			}
+			if werr != nil {
+				os.Remove(path)
+				return nil, fmt.Errorf("writing lock file: %w", werr)
+			}
+			heldLocks.Store(path, true)
+			return func() {
+				heldLocks.Delete(path)
+				os.Remove(path)
+			}, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, fmt.Errorf("creating lock file: %w", err)
+		}
+
+		holder, stale := readTargetLock(path, host)
+		if !stale {
+			return nil, &PolicyError{
+				Target: target,
+				Reason: fmt.Sprintf("%s is already being scanned (%s)", target, holder),
+			}
+		}
+		fmt.Printf("[!] Removing stale scan lock for %s (%s)\n", target, holder)
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("removing stale lock file: %w", err)
+		}
+	}
+	return nil, &PolicyError{Target: target, Reason: target + " is already being scanned"}
+}
+
+// readTargetLock describes the holder of the lock at path and reports
+// whether the lock is stale: its process, on this host, no longer exists.
+// Locks from other hosts are never considered stale.
+func readTargetLock(path, host string) (string, bool) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "lock file unreadable", errors.Is(err, os.ErrNotExist)
+	}
+	var l targetLock
+	if err := json.Unmarshal(data, &l); err != nil || l.PID == 0 {
+		return "lock file " + path, false
+	}
+	holder := fmt.Sprintf("pid %d on %s since %s", l.PID, l.Host, l.StartedAt.Local().Format("2006-01-02 15:04"))
+	if l.Host != host {
+		return holder, false
+	}
+	if l.PID == os.Getpid() {
+		_, held := heldLocks.Load(path)
+		return holder, !held
+	}
+	p, err := os.FindProcess(l.PID)
+	if err != nil {
+		return holder, true
+	}
+	err = p.Signal(syscall.Signal(0))
+	return holder, errors.Is(err, os.ErrProcessDone)
+}
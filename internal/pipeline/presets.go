@@ -4,21 +4,23 @@ import "fmt"
 
 // Preset defines a named workflow template with pre-configured settings.
 type Preset struct {
-	Name        string
-	Description string
-	Stages      []string // which stages to run
-	Severity    string   // nuclei severity filter
-	SkipPDF     bool
+	Name         string
+	Description  string
+	Stages       []string // which stages to run
+	Severity     string   // nuclei severity filter
+	SkipPDF      bool
+	Permutations bool // altdns-style subdomain permutation during discovery
 }
 
 // builtinPresets is the registry of all known presets.
 var builtinPresets = map[string]Preset{
 	"bug-bounty": {
-		Name:        "bug-bounty",
-		Description: "Full pipeline tuned for bug-bounty programs — all stages, critical/high/medium findings",
-		Stages:      []string{"discover", "portscan", "probe", "vulnscan", "diff"},
-		Severity:    "critical,high,medium",
-		SkipPDF:     false,
+		Name:         "bug-bounty",
+		Description:  "Full pipeline tuned for bug-bounty programs — all stages, critical/high/medium findings",
+		Stages:       []string{"discover", "portscan", "probe", "vulnscan", "diff"},
+		Severity:     "critical,high,medium",
+		SkipPDF:      false,
+		Permutations: true,
 	},
 	"quick-recon": {
 		Name:        "quick-recon",
@@ -28,11 +30,12 @@ var builtinPresets = map[string]Preset{
 		SkipPDF:     true,
 	},
 	"internal-pentest": {
-		Name:        "internal-pentest",
-		Description: "Deep scan for internal networks — all stages, all severity levels",
-		Stages:      []string{"discover", "portscan", "probe", "vulnscan", "diff"},
-		Severity:    "critical,high,medium,low",
-		SkipPDF:     false,
+		Name:         "internal-pentest",
+		Description:  "Deep scan for internal networks — all stages, all severity levels",
+		Stages:       []string{"discover", "portscan", "probe", "vulnscan", "diff"},
+		Severity:     "critical,high,medium,low",
+		SkipPDF:      false,
+		Permutations: true,
 	},
 }
 
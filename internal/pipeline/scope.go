@@ -18,6 +18,28 @@ type ScopeConfig struct {
 	AllowedCIDRs []string
 }
 
+// ValidateDomain checks that target is a plain hostname: dot-separated
+// labels of letters, digits and hyphens, none starting or ending with a
+// hyphen, at most 63 characters each and 253 in all. Targets received over
+// the API end up in scan directory names, tool arguments and hooks, so
+// nothing else gets through.
+func ValidateDomain(target string) error {
+	if target == "" || len(target) > 253 {
+		return fmt.Errorf("target %q is not a domain name", target)
+	}
+	for _, label := range strings.Split(target, ".") {
+		if len(label) == 0 || len(label) > 63 || label[0] == '-' || label[len(label)-1] == '-' {
+			return fmt.Errorf("target %q is not a domain name", target)
+		}
+		for _, c := range label {
+			if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-') {
+				return fmt.Errorf("target %q is not a domain name", target)
+			}
+		}
+	}
+	return nil
+}
+
 // ValidateTarget checks if a domain is within scope.
 // Returns nil if allowed, error if out of scope.
 // If AllowedDomains is empty, everything is allowed.
@@ -0,0 +1,189 @@
+package pipeline
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/hakim/reconpipe/internal/diff"
+	"github.com/hakim/reconpipe/internal/storage"
+	"github.com/hakim/reconpipe/pkg/models"
+)
+
+// SummaryFile is the machine-readable outcome of a run, written to the root
+// of the scan directory when the pipeline finishes.
+const SummaryFile = "summary.json"
+
+// Stage outcomes recorded in the summary.
+const (
+	StageComplete = "complete"
+	StageFailed   = "failed"
+	StageResumed  = "resumed" // completed by an earlier run of a resumed scan
+	StageNotRun   = "not_run" // the run was interrupted before reaching it
+)
+
+// ScanSummary is the content of summary.json: the pipeline result plus
+// enough counts and paths that wrapper automation never needs to know the
+// per-stage file layout.
+type ScanSummary struct {
+	ScanID         string         `json:"scan_id"`
+	Target         string         `json:"target"`
+	ScanDir        string         `json:"scan_dir"`
+	Status         string         `json:"status"` // complete, partial or interrupted
+	Operator       string         `json:"operator,omitempty"`
+	StartedAt      time.Time      `json:"started_at"`
+	FinishedAt     time.Time      `json:"finished_at"`
+	ElapsedSeconds float64        `json:"elapsed_seconds"`
+	Stages         []StageSummary `json:"stages"`
+
+	// Counts holds headline numbers per stage, e.g.
+	// counts["portscan"]["open_ports"].
+	Counts map[string]map[string]int `json:"counts"`
+
+	// Reports and RawFiles are paths relative to ScanDir.
+	Reports  []string `json:"reports"`
+	RawFiles []string `json:"raw_files"`
+
+	// Errors maps each failed stage to its error message.
+	Errors map[string]string `json:"errors,omitempty"`
+}
+
+// StageSummary is the outcome of one selected stage.
+type StageSummary struct {
+	Name           string  `json:"name"`
+	Status         string  `json:"status"`
+	ElapsedSeconds float64 `json:"elapsed_seconds,omitempty"`
+	Error          string  `json:"error,omitempty"`
+}
+
+// writeSummary builds the summary for a finished run and writes it to
+// {scanDir}/summary.json.
+func writeSummary(result *PipelineResult, meta *models.ScanMeta, selected []Stage, resumed map[string]bool) error {
+	s := ScanSummary{
+		ScanID:         result.ScanID,
+		Target:         result.Target,
+		ScanDir:        result.ScanDir,
+		Status:         result.Status,
+		Operator:       meta.Operator,
+		StartedAt:      meta.StartedAt,
+		FinishedAt:     time.Now(),
+		ElapsedSeconds: result.Elapsed.Seconds(),
+		Counts:         map[string]map[string]int{},
+		Errors:         result.StageErrors,
+	}
+
+	attempted := toSet(result.StagesRun)
+	for _, stage := range selected {
+		st := StageSummary{Name: stage.Name}
+		switch {
+		case result.StageErrors[stage.Name] != "":
+			st.Status = StageFailed
+			st.Error = result.StageErrors[stage.Name]
+		case attempted[stage.Name]:
+			st.Status = StageComplete
+		case resumed[stage.Name]:
+			st.Status = StageResumed
+		default:
+			st.Status = StageNotRun
+		}
+		st.ElapsedSeconds = result.StageTimes[stage.Name].Seconds()
+		s.Stages = append(s.Stages, st)
+	}
+
+	snap, err := diff.LoadSnapshot(result.ScanDir)
+	if err != nil {
+		return fmt.Errorf("loading results: %w", err)
+	}
+	addSummaryCounts(s.Counts, snap, toSet(meta.StagesRun), attempted)
+
+	if s.Reports, err = listFiles(result.ScanDir, storage.ReportsDir(result.ScanDir)); err != nil {
+		return err
+	}
+	if s.RawFiles, err = listFiles(result.ScanDir, storage.RawDir(result.ScanDir)); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshaling summary: %w", err)
+	}
+	if err := storage.WriteFileAtomic(filepath.Join(result.ScanDir, SummaryFile), data, 0644); err != nil {
+		return fmt.Errorf("writing %s: %w", SummaryFile, err)
+	}
+	return nil
+}
+
+// addSummaryCounts fills counts for every stage whose output exists, from
+// this run or an earlier run of a resumed scan.
+func addSummaryCounts(counts map[string]map[string]int, snap *diff.ScanSnapshot, done, attempted map[string]bool) {
+	ran := func(stage string) bool { return done[stage] || attempted[stage] }
+
+	if ran("discover") {
+		c := map[string]int{"subdomains": len(snap.Subdomains)}
+		for _, sub := range snap.Subdomains {
+			if sub.Resolved {
+				c["resolved"]++
+			}
+			if sub.IsDangling {
+				c["dangling"]++
+			}
+		}
+		counts["discover"] = c
+	}
+
+	if ran("portscan") {
+		c := map[string]int{"hosts": len(snap.Hosts)}
+		for _, host := range snap.Hosts {
+			if host.IsCDN {
+				c["cdn"]++
+			}
+			if len(host.Ports) > 0 {
+				c["hosts_with_ports"]++
+			}
+			c["open_ports"] += len(host.Ports)
+		}
+		counts["portscan"] = c
+	}
+
+	if ran("probe") {
+		counts["probe"] = map[string]int{"probes": len(snap.Probes)}
+	}
+
+	if ran("vulnscan") {
+		c := map[string]int{"vulnerabilities": len(snap.Vulnerabilities)}
+		for _, v := range snap.Vulnerabilities {
+			c[string(v.Severity)]++
+		}
+		counts["vulnscan"] = c
+	}
+}
+
+// listFiles returns the regular files under dir, relative to root and sorted.
+// A missing dir yields an empty list.
+func listFiles(root, dir string) ([]string, error) {
+	files := []string{}
+	err := filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && path == dir {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if d.Type().IsRegular() {
+			rel, err := filepath.Rel(root, path)
+			if err != nil {
+				return err
+			}
+			files = append(files, filepath.ToSlash(rel))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("listing %s: %w", dir, err)
+	}
+	sort.Strings(files)
+	return files, nil
+}
@@ -4,8 +4,9 @@ import (
 	"context"
 	"fmt"
 
-	"github.com/hakim/reconpipe/internal/models"
+	"github.com/hakim/reconpipe/internal/provider"
 	"github.com/hakim/reconpipe/internal/tools"
+	"github.com/hakim/reconpipe/pkg/models"
 )
 
 // CDNFilterResult contains the results of CDN filtering
@@ -13,15 +14,21 @@ type CDNFilterResult struct {
 	CDNHosts        []models.Host         `json:"cdn_hosts"`
 	ScannableIPs    []string              `json:"scannable_ips"`
 	IPToSubdomains  map[string][]string   `json:"ip_to_subdomains"`
+	// Providers maps scannable IPs to the provider cdncheck identified
+	// them with, for their hosts' Provider.
+	Providers       map[string]string     `json:"providers,omitempty"`
 }
 
 // FilterCDN classifies IPs as CDN or scannable and builds the IP-to-subdomain mapping.
 // It returns CDN hosts, non-CDN IPs to scan, and the reverse mapping for later use.
-func FilterCDN(ctx context.Context, subdomains []models.Subdomain, cdncheckPath string) (*CDNFilterResult, error) {
+// An IP's provider profile, if any, decides whether it is scanned instead of its
+// CDN classification; skipped IPs are returned as CDN hosts either way.
+func FilterCDN(ctx context.Context, subdomains []models.Subdomain, cdncheckPath string, profiles *provider.Profiles) (*CDNFilterResult, error) {
 	result := &CDNFilterResult{
 		CDNHosts:       []models.Host{},
 		ScannableIPs:   []string{},
 		IPToSubdomains: make(map[string][]string),
+		Providers:      make(map[string]string),
 	}
 
 	// Step 1: Build IP-to-subdomain reverse map from resolved subdomains
@@ -66,14 +73,16 @@ func FilterCDN(ctx context.Context, subdomains []models.Subdomain, cdncheckPath
 
 	// Step 4: Separate results into CDN hosts and scannable IPs
 	for _, ip := range uniqueIPs {
-		cdnResult, found := cdnMap[ip]
+		cdnResult := cdnMap[ip]
+		name := cdnResult.Provider()
 
-		if found && cdnResult.IsCDN {
-			// IP is CDN - create Host object
+		if profiles.SkipPortscan(name, cdnResult.IsCDN) {
+			// IP is CDN or its provider's profile skips it - create Host object
 			host := models.Host{
 				IP:          ip,
 				IsCDN:       true,
-				CDNProvider: cdnResult.CDNName,
+				CDNProvider: name,
+				Provider:    name,
 				Subdomains:  result.IPToSubdomains[ip],
 				Ports:       []models.Port{}, // CDN hosts have no ports scanned
 			}
@@ -81,6 +90,9 @@ func FilterCDN(ctx context.Context, subdomains []models.Subdomain, cdncheckPath
 		} else {
 			// IP is not CDN or not found in results - add to scannable
 			result.ScannableIPs = append(result.ScannableIPs, ip)
+			if name != "" {
+				result.Providers[ip] = name
+			}
 		}
 	}
 
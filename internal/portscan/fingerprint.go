@@ -0,0 +1,78 @@
+package portscan
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/hakim/reconpipe/internal/tools"
+)
+
+// DefaultNmapParallel is how many nmap runs fingerprint hosts at once when
+// PortScanConfig.NmapMaxParallel is not set.
+const DefaultNmapParallel = 5
+
+// fingerprint runs nmap service detection on the open ports of each IP, at
+// most parallel hosts at a time, and returns the results by IP. A failed
+// run only warns; its IP is missing from the results. No new runs start
+// once ctx is done.
+func fingerprint(ctx context.Context, ipPorts map[string][]int, parallel int, nmapPath string) map[string][]tools.NmapResult {
+	var ips []string
+	for ip, ports := range ipPorts {
+		if len(ports) > 0 {
+			ips = append(ips, ip)
+		}
+	}
+	sort.Strings(ips)
+
+	if parallel <= 0 {
+		parallel = DefaultNmapParallel
+	}
+	parallel = min(parallel, len(ips))
+	fmt.Printf("[*] Running nmap for service detection on %d hosts (%d at a time)...\n", len(ips), parallel)
+
+	var (
+		mu   sync.Mutex
+		wg   sync.WaitGroup
+		done int
+	)
+	results := make(map[string][]tools.NmapResult, len(ips))
+
+	queue := make(chan string)
+	for range parallel {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for ip := range queue {
+				fmt.Printf("[*] Scanning %s (%d ports)...\n", ip, len(ipPorts[ip]))
+				nmapResults, err := tools.RunNmap(ctx, ip, ipPorts[ip], nmapPath)
+
+				mu.Lock()
+				done++
+				if err != nil {
+					// Log warning and continue - nmap failure shouldn't stop the pipeline
+					fmt.Printf("[!] Warning: nmap failed for %s: %v (%d/%d hosts)\n", ip, err, done, len(ips))
+				} else {
+					results[ip] = nmapResults
+					fmt.Printf("[+] Fingerprinted %s: %d services (%d/%d hosts)\n", ip, len(nmapResults), done, len(ips))
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+	// Stop handing out hosts once ctx is done
+feed:
+	for _, ip := range ips {
+		select {
+		case queue <- ip:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(queue)
+	wg.Wait()
+
+	return results
+}
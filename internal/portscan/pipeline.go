@@ -3,19 +3,57 @@ package portscan
 import (
 	"context"
 	"fmt"
+	"strings"
+	"time"
 
-	"github.com/hakim/reconpipe/internal/models"
+	"github.com/hakim/reconpipe/internal/exclude"
+	"github.com/hakim/reconpipe/internal/geoip"
+	"github.com/hakim/reconpipe/internal/netprobe"
+	"github.com/hakim/reconpipe/internal/provider"
 	"github.com/hakim/reconpipe/internal/tools"
+	"github.com/hakim/reconpipe/pkg/models"
 )
 
 // PortScanConfig contains configuration for the port scanning pipeline
 type PortScanConfig struct {
+	// Target is the engagement's root domain; it labels the result.
+	Target          string
 	CdncheckPath    string
 	MasscanPath     string
+	NaabuPath       string
 	NmapPath        string
 	MasscanRate     int
 	NmapMaxParallel int
 	SkipCDNCheck    bool
+	// Scanner is the port scanner finding open ports (tools.Scanners):
+	// masscan, the default when empty, or naabu, which needs no root.
+	// MasscanRate is the packet rate of either.
+	Scanner string
+	// TopPorts, when > 0, scans only that many of the most common ports.
+	// Otherwise PortRange lists the ports, e.g. "80,443,8000-8100"; empty
+	// scans all of them.
+	PortRange string
+	TopPorts  int
+	// UDP also scans UDPPorts (DefaultUDPPorts when empty) with nmap -sU,
+	// whatever Scanner is; the open ones are added to the hosts' TCP ports.
+	UDP      bool
+	UDPPorts []int
+	// SkipMailChecks disables the STARTTLS / certificate / open-relay
+	// checks run against mail ports after fingerprinting.
+	SkipMailChecks bool
+	MailCheck      netprobe.MailCheckConfig
+	// Exclude lists networks that are never scanned; its file is also
+	// passed to the port scanner. Nil excludes nothing.
+	Exclude *exclude.List
+	// GeoIPPath is an MMDB database used to locate every host. Empty skips
+	// the lookup.
+	GeoIPPath string
+	// Profiles decides by provider which IPs cdncheck identifies are left
+	// out of the scan. Nil skips CDN IPs only.
+	Profiles *provider.Profiles
+	// Shared, when set, reuses the ports of IPs already scanned for another
+	// target of the same run instead of scanning them again.
+	Shared *SharedScan
 }
 
 // PortScanResult contains the complete results of port scanning
@@ -25,19 +63,33 @@ type PortScanResult struct {
 	CDNCount     int           `json:"cdn_count"`
 	ScannedCount int           `json:"scanned_count"`
 	TotalPorts   int           `json:"total_ports"`
+	// ExcludedCount is how many IPs were skipped as on the exclusions list.
+	ExcludedCount int `json:"excluded_count,omitempty"`
+	// ReusedCount is how many IPs took their ports from another target's
+	// scan in the same run rather than being scanned again.
+	ReusedCount int `json:"reused_count,omitempty"`
+	// PortSelection describes the ports scanned when not all of them were,
+	// e.g. "top 100" or "80,443,8000-8100".
+	PortSelection string `json:"port_selection,omitempty"`
+	// UDPPorts lists the UDP ports scanned, when UDP was scanned.
+	UDPPorts []int `json:"udp_ports,omitempty"`
+
+	// MailChecks holds protocol-level results for SMTP/IMAP/POP3 ports.
+	MailChecks []netprobe.MailCheck `json:"mail_checks,omitempty"`
+
+	// CollectedAt is when the stage started scanning.
+	CollectedAt time.Time `json:"collected_at,omitzero"`
 }
 
 // RunPortScan orchestrates the full port scanning pipeline.
-// It filters CDN IPs, runs masscan for port discovery, nmap for service fingerprinting,
+// It filters CDN IPs, runs masscan or naabu for port discovery, nmap for service fingerprinting,
 // and returns structured results with all hosts (CDN and scanned).
 func RunPortScan(ctx context.Context, subdomains []models.Subdomain, cfg PortScanConfig) (*PortScanResult, error) {
 	result := &PortScanResult{
-		Hosts: []models.Host{},
-	}
-
-	// Derive target from first subdomain's domain field
-	if len(subdomains) > 0 {
-		result.Target = subdomains[0].Domain
+		Target:        cfg.Target,
+		PortSelection: cfg.portSelection(),
+		Hosts:         []models.Host{},
+		CollectedAt:   time.Now().UTC(),
 	}
 
 	var cdnFilter *CDNFilterResult
@@ -74,7 +126,7 @@ func RunPortScan(ctx context.Context, subdomains []models.Subdomain, cfg PortSca
 		fmt.Printf("[*] Found %d IPs to scan\n", len(cdnFilter.ScannableIPs))
 	} else {
 		fmt.Println("[*] Running CDN detection...")
-		cdnFilter, err = FilterCDN(ctx, subdomains, cfg.CdncheckPath)
+		cdnFilter, err = FilterCDN(ctx, subdomains, cfg.CdncheckPath, cfg.Profiles)
 		if err != nil {
 			return nil, fmt.Errorf("CDN filtering failed: %w", err)
 		}
@@ -82,24 +134,97 @@ func RunPortScan(ctx context.Context, subdomains []models.Subdomain, cfg PortSca
 
 	result.CDNCount = len(cdnFilter.CDNHosts)
 
-	// Step 2: If no scannable IPs, return result with only CDN hosts
+	// Step 2: Drop excluded networks. Their hosts stay in the result, marked
+	// excluded, so later stages know not to reach them by name either.
+	var excludedHosts []models.Host
+	if cfg.Exclude.Len() > 0 {
+		scannable := cdnFilter.ScannableIPs[:0]
+		for _, ip := range cdnFilter.ScannableIPs {
+			if !cfg.Exclude.Contains(ip) {
+				scannable = append(scannable, ip)
+				continue
+			}
+			excludedHosts = append(excludedHosts, models.Host{
+				IP:         ip,
+				Subdomains: cdnFilter.IPToSubdomains[ip],
+				Excluded:   true,
+			})
+		}
+		cdnFilter.ScannableIPs = scannable
+		for i := range cdnFilter.CDNHosts {
+			if cfg.Exclude.Contains(cdnFilter.CDNHosts[i].IP) {
+				cdnFilter.CDNHosts[i].Excluded = true
+			}
+		}
+		result.ExcludedCount = len(excludedHosts)
+		if len(excludedHosts) > 0 {
+			fmt.Printf("[*] Excluded %d IPs listed in %s\n", len(excludedHosts), cfg.Exclude.Path)
+		}
+	}
+
+	// IPs another target of this run already scanned keep their ports and
+	// skip the port scanner and nmap
+	var reusedHosts []models.Host
+	if cfg.Shared != nil {
+		cdnFilter.ScannableIPs, reusedHosts = cfg.Shared.claim(cfg.Target, cdnFilter.ScannableIPs)
+		for i := range reusedHosts {
+			reusedHosts[i].Subdomains = cdnFilter.IPToSubdomains[reusedHosts[i].IP]
+			result.TotalPorts += len(reusedHosts[i].Ports)
+		}
+		result.ReusedCount = len(reusedHosts)
+		if len(reusedHosts) > 0 {
+			fmt.Printf("[*] Reusing ports of %d IPs already scanned for another target\n", len(reusedHosts))
+		}
+	}
+
+	// Step 3: If no scannable IPs, return result with only CDN, excluded and reused hosts
 	if len(cdnFilter.ScannableIPs) == 0 {
-		fmt.Println("[*] All IPs are CDN-hosted, skipping port scan")
-		result.Hosts = cdnFilter.CDNHosts
+		fmt.Println("[*] All IPs are CDN-hosted, excluded or already scanned, skipping port scan")
+		result.Hosts = append(reusedHosts, cdnFilter.CDNHosts...)
+		result.Hosts = append(result.Hosts, excludedHosts...)
+		tagProviders(result.Hosts, cdnFilter.Providers)
+		locateHosts(result.Hosts, cfg.GeoIPPath)
+		if !cfg.SkipMailChecks && len(reusedHosts) > 0 {
+			result.MailChecks = netprobe.RunMailChecks(ctx, reusedHosts, cfg.MailCheck)
+		}
 		return result, nil
 	}
 
-	// Step 3: Run masscan
-	fmt.Printf("[*] Running masscan on %d IPs...\n", len(cdnFilter.ScannableIPs))
-	masscanResults, err := tools.RunMasscan(ctx, cdnFilter.ScannableIPs, cfg.MasscanRate, cfg.MasscanPath)
-	if err != nil {
-		return nil, fmt.Errorf("masscan execution failed: %w", err)
+	// Step 4: Run the port scanner; naabu's results come in masscan's shape
+	var masscanResults []tools.MasscanResult
+	if cfg.Scanner == tools.ScannerNaabu {
+		fmt.Printf("[*] Running naabu on %d IPs%s...\n", len(cdnFilter.ScannableIPs), portsNote(result.PortSelection))
+		masscanResults, err = tools.RunNaabu(ctx, cdnFilter.ScannableIPs, cfg.PortRange, cfg.TopPorts, cfg.MasscanRate, excludeFile(cfg.Exclude), cfg.NaabuPath)
+		if err != nil {
+			return nil, fmt.Errorf("naabu execution failed: %w", err)
+		}
+		fmt.Printf("[*] Naabu complete, processing results...\n")
+	} else {
+		fmt.Printf("[*] Running masscan on %d IPs%s...\n", len(cdnFilter.ScannableIPs), portsNote(result.PortSelection))
+		masscanResults, err = tools.RunMasscan(ctx, cdnFilter.ScannableIPs, cfg.PortRange, cfg.TopPorts, cfg.MasscanRate, excludeFile(cfg.Exclude), cfg.MasscanPath)
+		if err != nil {
+			return nil, fmt.Errorf("masscan execution failed: %w", err)
+		}
+		fmt.Printf("[*] Masscan complete, processing results...\n")
 	}
-	fmt.Printf("[*] Masscan complete, processing results...\n")
 
-	// Step 4: If no open ports found, print message and return
+	// UDP services are scanned by nmap alone and join the hosts' TCP ports
+	var udpPorts map[string][]models.Port
+	if cfg.UDP {
+		result.UDPPorts = cfg.UDPPorts
+		if len(result.UDPPorts) == 0 {
+			result.UDPPorts = DefaultUDPPorts
+		}
+		udpPorts = scanUDP(ctx, cdnFilter.ScannableIPs, result.UDPPorts, cfg.NmapPath)
+	}
+
+	// Step 5: If no open ports found, print message and return
 	if len(masscanResults) == 0 {
-		fmt.Println("[*] No open ports discovered")
+		if len(udpPorts) > 0 {
+			fmt.Println("[*] No open TCP ports discovered")
+		} else {
+			fmt.Println("[*] No open ports discovered")
+		}
 
 		// Create hosts with no ports for all scannable IPs
 		for _, ip := range cdnFilter.ScannableIPs {
@@ -112,14 +237,26 @@ func RunPortScan(ctx context.Context, subdomains []models.Subdomain, cfg PortSca
 			result.Hosts = append(result.Hosts, host)
 		}
 
-		// Add CDN hosts
+		result.TotalPorts += addUDPPorts(result.Hosts, udpPorts)
+		if cfg.Shared != nil {
+			cfg.Shared.record(cfg.Target, result.Hosts)
+		}
+
+		// Add reused, CDN and excluded hosts
+		result.Hosts = append(result.Hosts, reusedHosts...)
 		result.Hosts = append(result.Hosts, cdnFilter.CDNHosts...)
+		result.Hosts = append(result.Hosts, excludedHosts...)
 		result.ScannedCount = len(cdnFilter.ScannableIPs)
+		tagProviders(result.Hosts, cdnFilter.Providers)
+		locateHosts(result.Hosts, cfg.GeoIPPath)
+		if !cfg.SkipMailChecks && len(reusedHosts) > 0 {
+			result.MailChecks = netprobe.RunMailChecks(ctx, reusedHosts, cfg.MailCheck)
+		}
 
 		return result, nil
 	}
 
-	// Step 5: Build IP-to-ports map from masscan results
+	// Step 6: Build IP-to-ports map from masscan results
 	ipPorts := make(map[string][]int)
 	for _, masscanResult := range masscanResults {
 		for _, masscanPort := range masscanResult.Ports {
@@ -130,28 +267,10 @@ func RunPortScan(ctx context.Context, subdomains []models.Subdomain, cfg PortSca
 		}
 	}
 
-	// Step 6: Run nmap for service fingerprinting (sequential for now)
-	fmt.Printf("[*] Running nmap for service detection on %d hosts...\n", len(ipPorts))
-
-	nmapResultsMap := make(map[string][]tools.NmapResult)
+	// Step 7: Run nmap for service fingerprinting, NmapMaxParallel hosts at a time
+	nmapResultsMap := fingerprint(ctx, ipPorts, cfg.NmapMaxParallel, cfg.NmapPath)
 
-	for ip, ports := range ipPorts {
-		if len(ports) == 0 {
-			continue
-		}
-
-		fmt.Printf("[*] Scanning %s (%d ports)...\n", ip, len(ports))
-		nmapResults, err := tools.RunNmap(ctx, ip, ports, cfg.NmapPath)
-		if err != nil {
-			// Log warning and continue - nmap failure shouldn't stop the pipeline
-			fmt.Printf("[!] Warning: nmap failed for %s: %v\n", ip, err)
-			continue
-		}
-
-		nmapResultsMap[ip] = nmapResults
-	}
-
-	// Step 7: Build Host objects with port information
+	// Step 8: Build Host objects with port information
 	scannedHosts := make(map[string]bool)
 
 	for ip, nmapResults := range nmapResultsMap {
@@ -222,11 +341,84 @@ func RunPortScan(ctx context.Context, subdomains []models.Subdomain, cfg PortSca
 		result.Hosts = append(result.Hosts, host)
 	}
 
-	// Step 8: Add CDN hosts to result
+	result.TotalPorts += addUDPPorts(result.Hosts, udpPorts)
+
+	if cfg.Shared != nil {
+		cfg.Shared.record(cfg.Target, result.Hosts)
+	}
+
+	// Step 9: Add reused, CDN and excluded hosts to result
+	result.Hosts = append(result.Hosts, reusedHosts...)
 	result.Hosts = append(result.Hosts, cdnFilter.CDNHosts...)
+	result.Hosts = append(result.Hosts, excludedHosts...)
 	result.ScannedCount = len(cdnFilter.ScannableIPs)
 
+	// Step 10: Provider tagging and GeoIP enrichment (non-fatal)
+	tagProviders(result.Hosts, cdnFilter.Providers)
+	locateHosts(result.Hosts, cfg.GeoIPPath)
+
+	// Step 11: Protocol checks for mail services
+	if !cfg.SkipMailChecks {
+		result.MailChecks = netprobe.RunMailChecks(ctx, result.Hosts, cfg.MailCheck)
+		if len(result.MailChecks) > 0 {
+			fmt.Printf("[*] Checked %d mail services (STARTTLS, certificate, open relay)\n", len(result.MailChecks))
+		}
+	}
+
 	fmt.Printf("[+] Port scan complete: %d hosts scanned, %d ports found\n", result.ScannedCount, result.TotalPorts)
 
 	return result, nil
 }
+
+// portSelection describes the ports cfg scans, or "" for all of them.
+func (cfg PortScanConfig) portSelection() string {
+	switch {
+	case cfg.TopPorts > 0:
+		return fmt.Sprintf("top %d", cfg.TopPorts)
+	case cfg.PortRange == "" || cfg.PortRange == tools.AllPorts:
+		return ""
+	}
+	return strings.Join(strings.Fields(cfg.PortRange), "")
+}
+
+// portsNote formats a port selection for the progress output.
+func portsNote(ports string) string {
+	if ports == "" {
+		return ""
+	}
+	return " (ports: " + ports + ")"
+}
+
+// excludeFile returns the exclusions file to pass to the port scanner, if
+// any.
+func excludeFile(l *exclude.List) string {
+	if l.Len() == 0 {
+		return ""
+	}
+	return l.Path
+}
+
+// tagProviders records on scanned hosts the provider cdncheck identified
+// their IP with; CDN hosts already carry theirs.
+func tagProviders(hosts []models.Host, providers map[string]string) {
+	for i := range hosts {
+		if name, ok := providers[hosts[i].IP]; ok {
+			hosts[i].Provider = name
+		}
+	}
+}
+
+// locateHosts adds GeoIP locations to hosts from the database at path. A
+// database that cannot be opened only costs the locations.
+func locateHosts(hosts []models.Host, path string) {
+	if path == "" || len(hosts) == 0 {
+		return
+	}
+	db, err := geoip.Open(path)
+	if err != nil {
+		fmt.Printf("[!] Warning: skipping GeoIP lookup: %v\n", err)
+		return
+	}
+	defer db.Close()
+	fmt.Printf("[*] GeoIP: located %d of %d hosts\n", db.Enrich(hosts), len(hosts))
+}
@@ -0,0 +1,89 @@
+package portscan
+
+import (
+	"slices"
+	"sort"
+	"sync"
+
+	"github.com/hakim/reconpipe/pkg/models"
+)
+
+// SharedScan carries port scan results between the targets of a
+// multi-target run. An IP that several targets resolve to is scanned by
+// masscan and nmap for the first of them only; the others reuse its ports.
+// It is safe for concurrent use.
+type SharedScan struct {
+	mu         sync.Mutex
+	ports      map[string][]models.Port // IP -> ports found when it was scanned
+	scannedFor map[string]string        // IP -> target whose scan found them
+	owners     map[string][]string      // IP -> targets that resolved to it
+}
+
+// NewSharedScan returns an empty SharedScan.
+func NewSharedScan() *SharedScan {
+	return &SharedScan{
+		ports:      make(map[string][]models.Port),
+		scannedFor: make(map[string]string),
+		owners:     make(map[string][]string),
+	}
+}
+
+// claim records target as an owner of ips and splits them into those still
+// to be scanned and the hosts already scanned for another target, which
+// carry the ports found then.
+func (s *SharedScan) claim(target string, ips []string) (pending []string, reused []models.Host) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, ip := range ips {
+		if !slices.Contains(s.owners[ip], target) {
+			s.owners[ip] = append(s.owners[ip], target)
+		}
+		from, ok := s.scannedFor[ip]
+		if !ok || from == target {
+			pending = append(pending, ip)
+			continue
+		}
+		reused = append(reused, models.Host{
+			IP:         ip,
+			Ports:      slices.Clone(s.ports[ip]),
+			ScannedFor: from,
+		})
+	}
+	return pending, reused
+}
+
+// record stores the ports of hosts scanned for target so later targets can
+// reuse them.
+func (s *SharedScan) record(target string, hosts []models.Host) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, h := range hosts {
+		if h.IsCDN || h.Excluded || h.ScannedFor != "" {
+			continue
+		}
+		s.ports[h.IP] = slices.Clone(h.Ports)
+		s.scannedFor[h.IP] = target
+	}
+}
+
+// Attribute sets SharedWith on every host of result that other targets of
+// the run resolved to, and returns how many hosts are shared.
+func (s *SharedScan) Attribute(result *PortScanResult) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	shared := 0
+	for i := range result.Hosts {
+		host := &result.Hosts[i]
+		host.SharedWith = nil
+		for _, owner := range s.owners[host.IP] {
+			if owner != result.Target {
+				host.SharedWith = append(host.SharedWith, owner)
+			}
+		}
+		sort.Strings(host.SharedWith)
+		if len(host.SharedWith) > 0 {
+			shared++
+		}
+	}
+	return shared
+}
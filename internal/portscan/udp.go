@@ -0,0 +1,70 @@
+package portscan
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hakim/reconpipe/internal/tools"
+	"github.com/hakim/reconpipe/pkg/models"
+)
+
+// DefaultUDPPorts are the UDP services scanned when UDP scanning is enabled
+// without a port list: those that leak data or amplify traffic when exposed.
+var DefaultUDPPorts = []int{
+	53,    // DNS
+	69,    // TFTP
+	111,   // rpcbind
+	123,   // NTP
+	137,   // NetBIOS name service
+	161,   // SNMP
+	500,   // IKE
+	623,   // IPMI
+	1434,  // SQL Server browser
+	1900,  // SSDP
+	5060,  // SIP
+	5353,  // mDNS
+	11211, // memcached
+}
+
+// scanUDP runs nmap's UDP scan of ports on ips and returns the open ports
+// by IP. A failed scan only warns: the TCP results stand without it.
+func scanUDP(ctx context.Context, ips []string, ports []int, nmapPath string) map[string][]models.Port {
+	fmt.Printf("[*] Running nmap UDP scan of %d ports on %d IPs...\n", len(ports), len(ips))
+	results, err := tools.RunNmapUDP(ctx, ips, ports, nmapPath)
+	if err != nil {
+		fmt.Printf("[!] Warning: UDP scan failed: %v\n", err)
+		return nil
+	}
+
+	// open|filtered means no answer, which is what most closed UDP ports
+	// give too, so only ports that answered count
+	open := make(map[string][]models.Port)
+	count := 0
+	for _, r := range results {
+		if r.State != "open" {
+			continue
+		}
+		open[r.IP] = append(open[r.IP], models.Port{
+			Number:   r.Port,
+			Protocol: "udp",
+			Service:  r.Service,
+			Version:  r.Version,
+			State:    r.State,
+		})
+		count++
+	}
+	fmt.Printf("[*] UDP scan complete: %d open ports\n", count)
+	return open
+}
+
+// addUDPPorts appends the open UDP ports to their hosts and returns how many
+// were added.
+func addUDPPorts(hosts []models.Host, udp map[string][]models.Port) int {
+	added := 0
+	for i := range hosts {
+		ports := udp[hosts[i].IP]
+		hosts[i].Ports = append(hosts[i].Ports, ports...)
+		added += len(ports)
+	}
+	return added
+}
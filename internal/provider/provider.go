@@ -0,0 +1,244 @@
+// Package provider holds the probing profiles applied to hosts by the CDN,
+// WAF or cloud provider cdncheck identifies their IPs with. Edge networks
+// such as Cloudflare and Akamai ban or tarpit clients that probe them at
+// full speed, and their anycast addresses front thousands of unrelated
+// sites, so their hosts are skipped by the port scan and requested slower,
+// with longer timeouts, by httpx and nuclei.
+//
+// Built-in profiles cover the common edge providers; the config overrides
+// them field by field and adds profiles for other providers:
+//
+//	provider_profiles:
+//	  profiles:
+//	    cloudflare:
+//	      nuclei_rate_limit: 10
+//	    amazon:
+//	      httpx_rate_limit: 50
+package provider
+
+import (
+	"fmt"
+	"maps"
+	"net"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/hakim/reconpipe/internal/tools"
+	"github.com/hakim/reconpipe/pkg/models"
+)
+
+// Profile is how the hosts of one provider are scanned. Zero fields leave
+// the scan's own setting; rates and threads only ever lower it, and the
+// timeout only ever lengthens it.
+type Profile struct {
+	// SkipPortscan keeps the provider's IPs out of masscan and nmap; their
+	// hostnames are still probed by name, as CDN hosts are. Unset: only
+	// IPs cdncheck classes as CDN are skipped.
+	SkipPortscan    *bool  `mapstructure:"skip_portscan"`
+	HttpxThreads    int    `mapstructure:"httpx_threads"`
+	HttpxRateLimit  int    `mapstructure:"httpx_rate_limit"` // requests per second
+	NucleiThreads   int    `mapstructure:"nuclei_threads"`
+	NucleiRateLimit int    `mapstructure:"nuclei_rate_limit"` // requests per second
+	Timeout         string `mapstructure:"timeout"`           // per request, httpx and nuclei
+}
+
+// Builtin are the profiles applied without any configuration, by the
+// provider names cdncheck reports.
+var Builtin = map[string]Profile{
+	"cloudflare": edge(10, 25, "20s"),
+	"akamai":     edge(5, 10, "30s"),
+	"fastly":     edge(10, 25, "20s"),
+	"cloudfront": edge(10, 25, "20s"),
+	"incapsula":  edge(5, 10, "30s"),
+	"sucuri":     edge(5, 10, "30s"),
+}
+
+// edge returns the profile of an edge network: not port scanned, and
+// requested at threads and rate by httpx and nuclei alike.
+func edge(threads, rate int, timeout string) Profile {
+	skip := true
+	return Profile{
+		SkipPortscan:    &skip,
+		HttpxThreads:    threads,
+		HttpxRateLimit:  rate,
+		NucleiThreads:   threads,
+		NucleiRateLimit: rate,
+		Timeout:         timeout,
+	}
+}
+
+// Profiles maps provider names to their profiles. A nil *Profiles has none.
+type Profiles struct {
+	profiles map[string]Profile
+	timeouts map[string]time.Duration
+}
+
+// New returns the built-in profiles overridden by overrides, field by
+// field, or only overrides when builtin is false.
+func New(builtin bool, overrides map[string]Profile) (*Profiles, error) {
+	p := &Profiles{profiles: make(map[string]Profile), timeouts: make(map[string]time.Duration)}
+	if builtin {
+		maps.Copy(p.profiles, Builtin)
+	}
+	for name, o := range overrides {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			return nil, fmt.Errorf("empty provider name")
+		}
+		if o.HttpxThreads < 0 || o.HttpxRateLimit < 0 || o.NucleiThreads < 0 || o.NucleiRateLimit < 0 {
+			return nil, fmt.Errorf("%s: threads and rate limits must not be negative", name)
+		}
+		p.profiles[name] = merge(p.profiles[name], o)
+	}
+	for name, profile := range p.profiles {
+		if profile.Timeout == "" {
+			continue
+		}
+		d, err := time.ParseDuration(profile.Timeout)
+		if err != nil {
+			return nil, fmt.Errorf("%s.timeout %q: %w", name, profile.Timeout, err)
+		}
+		if d < time.Second {
+			return nil, fmt.Errorf("%s.timeout %q: must be at least 1s", name, profile.Timeout)
+		}
+		p.timeouts[name] = d
+	}
+	return p, nil
+}
+
+// merge returns base with the fields o sets replaced.
+func merge(base, o Profile) Profile {
+	if o.SkipPortscan != nil {
+		base.SkipPortscan = o.SkipPortscan
+	}
+	if o.HttpxThreads > 0 {
+		base.HttpxThreads = o.HttpxThreads
+	}
+	if o.HttpxRateLimit > 0 {
+		base.HttpxRateLimit = o.HttpxRateLimit
+	}
+	if o.NucleiThreads > 0 {
+		base.NucleiThreads = o.NucleiThreads
+	}
+	if o.NucleiRateLimit > 0 {
+		base.NucleiRateLimit = o.NucleiRateLimit
+	}
+	if o.Timeout != "" {
+		base.Timeout = o.Timeout
+	}
+	return base
+}
+
+// Lookup returns the profile of provider, matched without regard to case.
+func (p *Profiles) Lookup(provider string) (Profile, bool) {
+	if p == nil || provider == "" {
+		return Profile{}, false
+	}
+	profile, ok := p.profiles[strings.ToLower(provider)]
+	return profile, ok
+}
+
+// SkipPortscan reports whether an IP of provider is left out of the port
+// scan. cdn is whether cdncheck classed it as CDN, which decides when the
+// profile does not.
+func (p *Profiles) SkipPortscan(provider string, cdn bool) bool {
+	if profile, ok := p.Lookup(provider); ok && profile.SkipPortscan != nil {
+		return *profile.SkipPortscan
+	}
+	return cdn
+}
+
+// Httpx returns opts adjusted for the hosts of provider.
+func (p *Profiles) Httpx(provider string, opts tools.HttpxOptions) tools.HttpxOptions {
+	profile, ok := p.Lookup(provider)
+	if !ok {
+		return opts
+	}
+	opts.Threads = lower(opts.Threads, profile.HttpxThreads)
+	opts.RateLimit = lower(opts.RateLimit, profile.HttpxRateLimit)
+	opts.Timeout = max(opts.Timeout, p.timeouts[strings.ToLower(provider)])
+	return opts
+}
+
+// Nuclei returns threads, rateLimit and opts adjusted for the hosts of
+// provider.
+func (p *Profiles) Nuclei(provider string, threads, rateLimit int, opts tools.NucleiOptions) (int, int, tools.NucleiOptions) {
+	profile, ok := p.Lookup(provider)
+	if !ok {
+		return threads, rateLimit, opts
+	}
+	opts.Timeout = max(opts.Timeout, p.timeouts[strings.ToLower(provider)])
+	return lower(threads, profile.NucleiThreads), lower(rateLimit, profile.NucleiRateLimit), opts
+}
+
+// lower returns limit when it is set and below current, which is 0 for the
+// tool's default.
+func lower(current, limit int) int {
+	if limit > 0 && (current <= 0 || limit < current) {
+		return limit
+	}
+	return current
+}
+
+// Group is targets scanned under one profile.
+type Group struct {
+	Provider string // empty for the targets without a profile
+	Targets  []string
+}
+
+// Split groups targets by the profile of the provider providerOf returns
+// for each, keeping their order: the targets without a profile first, then
+// one group per provider by name.
+func (p *Profiles) Split(targets []string, providerOf func(target string) string) []Group {
+	byProvider := make(map[string][]string)
+	for _, t := range targets {
+		name := strings.ToLower(providerOf(t))
+		if _, ok := p.Lookup(name); !ok {
+			name = ""
+		}
+		byProvider[name] = append(byProvider[name], t)
+	}
+	var groups []Group
+	for _, name := range slices.Sorted(maps.Keys(byProvider)) {
+		groups = append(groups, Group{Provider: name, Targets: byProvider[name]})
+	}
+	return groups
+}
+
+// Index maps every IP and hostname of hosts to the provider of its host,
+// so targets (URLs, host:port or bare hosts) can be split by profile.
+func Index(hosts []models.Host) func(target string) string {
+	providers := make(map[string]string)
+	for _, host := range hosts {
+		name := host.Provider
+		if name == "" {
+			name = host.CDNProvider // ports.json written before providers were recorded
+		}
+		if name == "" {
+			continue
+		}
+		providers[host.IP] = name
+		for _, sub := range host.Subdomains {
+			providers[strings.ToLower(sub)] = name
+		}
+	}
+	return func(target string) string {
+		return providers[strings.ToLower(targetHost(target))]
+	}
+}
+
+// targetHost returns the host of a probe target: a URL, host:port or a
+// bare host.
+func targetHost(target string) string {
+	if strings.Contains(target, "://") {
+		if u, err := url.Parse(target); err == nil {
+			return u.Hostname()
+		}
+	}
+	if h, _, err := net.SplitHostPort(target); err == nil {
+		return h
+	}
+	return target
+}
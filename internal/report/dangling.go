@@ -2,44 +2,27 @@ package report
 
 import (
 	"fmt"
+	"sort"
 	"strings"
 	"time"
 
-	"github.com/hakim/reconpipe/internal/models"
+	"github.com/hakim/reconpipe/internal/takeover"
+	"github.com/hakim/reconpipe/pkg/models"
 )
 
-// knownProvider maps a CNAME suffix pattern to a human-readable provider label.
-// Entries are checked via strings.Contains so the pattern must be a unique
-// substring of the CNAME target (e.g. ".azurewebsites.net").
-type knownProvider struct {
-	suffix string
-	label  string
-}
-
-// takeoverProviders is evaluated in declaration order; the first match wins.
-var takeoverProviders = []knownProvider{
-	{".azurewebsites.net", "Azure"},
-	{".cloudfront.net", "CloudFront"},
-	{".s3.amazonaws.com", "AWS S3"},
-	{".s3-website", "AWS S3"},
-	{".herokuapp.com", "Heroku"},
-	{".github.io", "GitHub Pages"},
-	{".netlify.app", "Netlify"},
-	{".shopify.com", "Shopify"},
-	{".ghost.io", "Ghost"},
-	{".pantheon.io", "Pantheon"},
-}
-
 // WriteDanglingDNSReport generates a standalone markdown report for all
 // dangling DNS subdomains found during any scan (REPT-03).
 // It partitions subdomains into high-risk (has CNAME) and low-risk (no CNAME)
-// categories and writes the result to outputPath.
-func WriteDanglingDNSReport(subdomains []models.Subdomain, outputPath string) error {
+// categories and writes the result to outputPath. collectedAt is when
+// discovery resolved them, zero when unknown.
+func WriteDanglingDNSReport(subdomains []models.Subdomain, collectedAt time.Time, outputPath string) error {
 	dangling := filterDangling(subdomains)
 
 	var b strings.Builder
 	b.WriteString("# Dangling DNS Report\n\n")
-	b.WriteString(fmt.Sprintf("**Date:** %s\n\n", time.Now().UTC().Format("2006-01-02 15:04:05 UTC")))
+	b.WriteString(fmt.Sprintf("**Date:** %s\n", now().UTC().Format("2006-01-02 15:04:05 UTC")))
+	b.WriteString(collectedLine("Data collected", collectedAt))
+	b.WriteString("\n")
 
 	if len(dangling) == 0 {
 		b.WriteString("No dangling DNS records found.\n")
@@ -54,16 +37,34 @@ func WriteDanglingDNSReport(subdomains []models.Subdomain, outputPath string) er
 	b.WriteString(fmt.Sprintf("- With CNAME (takeover risk): %d\n", len(highRisk)))
 	b.WriteString(fmt.Sprintf("- Without CNAME (stale DNS): %d\n\n", len(lowRisk)))
 
+	bySeverity := map[models.Severity]int{}
+	for _, s := range dangling {
+		bySeverity[takeover.Assess(s).Severity]++
+	}
+	b.WriteString("| Severity | Count |\n")
+	b.WriteString("|----------|-------|\n")
+	for _, sev := range severityOrder {
+		if bySeverity[sev] > 0 {
+			b.WriteString(fmt.Sprintf("| %s | %d |\n", sev, bySeverity[sev]))
+		}
+	}
+	b.WriteString("\n")
+
 	// High-risk section
 	if len(highRisk) > 0 {
 		b.WriteString("## High Risk — Subdomain Takeover Candidates\n\n")
-		b.WriteString("These subdomains have CNAME records pointing to services that may be claimable.\n\n")
-		b.WriteString("| Subdomain | CNAME Target | Risk |\n")
-		b.WriteString("|-----------|-------------|------|\n")
-		for _, s := range highRisk {
-			cname := getCNAMETarget(s.DNSRecords)
-			risk := classifyProvider(cname)
-			b.WriteString(fmt.Sprintf("| %s | %s | %s |\n", s.Name, cname, risk))
+		b.WriteString("These subdomains have CNAME records pointing to services that may be claimable. " +
+			"Severity follows the provider: critical when anyone can claim the resource, high when it depends " +
+			"on the setup, medium for unknown providers, low when the provider verifies ownership.\n\n")
+		b.WriteString("| Severity | Subdomain | CNAME Target | Provider | Claimability |\n")
+		b.WriteString("|----------|-----------|-------------|----------|--------------|\n")
+		for _, s := range sortDanglingBySeverity(highRisk) {
+			a := takeover.Assess(s)
+			claim := string(a.Claimability)
+			if claim == "" {
+				claim = "unknown"
+			}
+			b.WriteString(fmt.Sprintf("| %s | %s | %s | %s | %s |\n", a.Severity, s.Name, a.CNAME, a.Provider, claim))
 		}
 		b.WriteString("\n")
 	}
@@ -121,14 +122,13 @@ func hasCNAMERecord(records []models.DNSRecord) bool {
 	return false
 }
 
-// classifyProvider maps a CNAME target to a known provider label.
-// Returns "Unknown" when no pattern matches.
-func classifyProvider(cnameTarget string) string {
-	lower := strings.ToLower(cnameTarget)
-	for _, p := range takeoverProviders {
-		if strings.Contains(lower, p.suffix) {
-			return p.label
-		}
-	}
-	return "Unknown"
+// sortDanglingBySeverity returns a new slice ordered by takeover severity,
+// most severe first, keeping the input order within a severity.
+func sortDanglingBySeverity(subs []models.Subdomain) []models.Subdomain {
+	sorted := make([]models.Subdomain, len(subs))
+	copy(sorted, subs)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return diffSeverityRank[takeover.Assess(sorted[i]).Severity] < diffSeverityRank[takeover.Assess(sorted[j]).Severity]
+	})
+	return sorted
 }
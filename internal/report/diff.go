@@ -2,13 +2,12 @@ package report
 
 import (
 	"fmt"
-	"os"
 	"sort"
 	"strings"
-	"time"
 
 	"github.com/hakim/reconpipe/internal/diff"
-	"github.com/hakim/reconpipe/internal/models"
+	"github.com/hakim/reconpipe/internal/takeover"
+	"github.com/hakim/reconpipe/pkg/models"
 )
 
 // WriteDiffReport generates a markdown report capturing the delta between two
@@ -17,7 +16,10 @@ func WriteDiffReport(result *diff.DiffResult, outputPath string) error {
 	var b strings.Builder
 
 	b.WriteString("# Scan Diff Report\n\n")
-	b.WriteString(fmt.Sprintf("**Date:** %s\n\n", time.Now().UTC().Format("2006-01-02 15:04:05 UTC")))
+	b.WriteString(fmt.Sprintf("**Date:** %s\n", now().UTC().Format("2006-01-02 15:04:05 UTC")))
+	b.WriteString(collectedLine("Data collected", result.CollectedAt))
+	b.WriteString(collectedLine("Compared with data collected", result.PreviousCollectedAt))
+	b.WriteString("\n")
 
 	// If there are zero changes across all categories, short-circuit.
 	if isEmptyDiff(result) {
@@ -26,12 +28,12 @@ func WriteDiffReport(result *diff.DiffResult, outputPath string) error {
 	}
 
 	writeDiffSummaryTable(&b, result)
-	writeNewSubdomains(&b, result.NewSubdomains)
-	writeRemovedSubdomains(&b, result.RemovedSubdomains)
-	writeNewPorts(&b, result.NewPorts)
-	writeClosedPorts(&b, result.ClosedPorts)
-	writeNewVulns(&b, result.NewVulns)
-	writeResolvedVulns(&b, result.ResolvedVulns)
+	writeNewSubdomains(&b, result.NewSubdomains, result.SubdomainSeen)
+	writeRemovedSubdomains(&b, result.RemovedSubdomains, result.SubdomainSeen)
+	writeNewPorts(&b, result.NewPorts, result.PortSeen)
+	writeClosedPorts(&b, result.ClosedPorts, result.PortSeen)
+	writeNewVulns(&b, result.NewVulns, result.VulnSeen)
+	writeResolvedVulns(&b, result.ResolvedVulns, result.VulnSeen)
 	writeDanglingDNSChanges(&b, result)
 
 	return writeFile(outputPath, b.String())
@@ -61,88 +63,122 @@ func writeDiffSummaryTable(b *strings.Builder, r *diff.DiffResult) {
 	b.WriteString("\n")
 }
 
-// writeNewSubdomains renders the new subdomains section. Skipped when empty.
-func writeNewSubdomains(b *strings.Builder, subs []models.Subdomain) {
+// writeNewSubdomains renders the new subdomains section, with when each was
+// first and last observed when seen is set. Skipped when empty.
+func writeNewSubdomains(b *strings.Builder, subs []models.Subdomain, seen map[string]diff.Sighting) {
 	if len(subs) == 0 {
 		return
 	}
 	b.WriteString(fmt.Sprintf("## New Subdomains (+%d)\n\n", len(subs)))
 	for _, s := range subs {
-		b.WriteString(fmt.Sprintf("- %s (%s)\n", s.Name, subdomainDNSSummary(s)))
+		b.WriteString(fmt.Sprintf("- %s (%s)%s\n", s.Name, subdomainDNSSummary(s), seenNote(seen, s.Name)))
 	}
 	b.WriteString("\n")
 }
 
-// writeRemovedSubdomains renders the removed subdomains section. Skipped when empty.
-func writeRemovedSubdomains(b *strings.Builder, subs []models.Subdomain) {
+// writeRemovedSubdomains renders the removed subdomains section, with when
+// each was first and last observed when seen is set. Skipped when empty.
+func writeRemovedSubdomains(b *strings.Builder, subs []models.Subdomain, seen map[string]diff.Sighting) {
 	if len(subs) == 0 {
 		return
 	}
 	b.WriteString(fmt.Sprintf("## Removed Subdomains (-%d)\n\n", len(subs)))
 	for _, s := range subs {
-		b.WriteString(fmt.Sprintf("- %s\n", s.Name))
+		b.WriteString(fmt.Sprintf("- %s%s\n", s.Name, seenNote(seen, s.Name)))
 	}
 	b.WriteString("\n")
 }
 
 // writeNewPorts renders the new open ports table. Skipped when empty.
-func writeNewPorts(b *strings.Builder, changes []diff.PortChange) {
+func writeNewPorts(b *strings.Builder, changes []diff.PortChange, seen map[string]diff.Sighting) {
 	if len(changes) == 0 {
 		return
 	}
 	b.WriteString(fmt.Sprintf("## New Open Ports (+%d)\n\n", len(changes)))
-	writePortChangeTable(b, changes)
+	writePortChangeTable(b, changes, seen)
 }
 
 // writeClosedPorts renders the closed ports table. Skipped when empty.
-func writeClosedPorts(b *strings.Builder, changes []diff.PortChange) {
+func writeClosedPorts(b *strings.Builder, changes []diff.PortChange, seen map[string]diff.Sighting) {
 	if len(changes) == 0 {
 		return
 	}
 	b.WriteString(fmt.Sprintf("## Closed Ports (-%d)\n\n", len(changes)))
-	writePortChangeTable(b, changes)
+	writePortChangeTable(b, changes, seen)
 }
 
 // writePortChangeTable is the shared table renderer for port change slices.
-func writePortChangeTable(b *strings.Builder, changes []diff.PortChange) {
-	b.WriteString("| Host | IP | Port | Protocol | Service |\n")
-	b.WriteString("|------|----|------|----------|---------|\n")
+// The First Seen and Last Seen columns are added when seen is set.
+func writePortChangeTable(b *strings.Builder, changes []diff.PortChange, seen map[string]diff.Sighting) {
+	if seen == nil {
+		b.WriteString("| Host | IP | Port | Protocol | Service |\n")
+		b.WriteString("|------|----|------|----------|---------|\n")
+	} else {
+		b.WriteString("| Host | IP | Port | Protocol | Service | First Seen | Last Seen |\n")
+		b.WriteString("|------|----|------|----------|---------|------------|-----------|\n")
+	}
 	for _, pc := range changes {
 		service := pc.Port.Service
 		if service == "" {
 			service = "-"
 		}
-		b.WriteString(fmt.Sprintf("| %s | %s | %d | %s | %s |\n",
+		b.WriteString(fmt.Sprintf("| %s | %s | %d | %s | %s |",
 			pc.Host, pc.IP, pc.Port.Number, pc.Port.Protocol, service))
+		if seen != nil {
+			b.WriteString(seenCells(seen, pc.Key()))
+		}
+		b.WriteString("\n")
 	}
 	b.WriteString("\n")
 }
 
-// writeNewVulns renders new vulnerabilities sorted by severity. Skipped when empty.
-func writeNewVulns(b *strings.Builder, vulns []models.Vulnerability) {
-	if len(vulns) == 0 {
-		return
+// writeNewVulns renders new vulnerabilities sorted by severity, with those
+// classed as noise in a section of their own. Skipped when empty.
+func writeNewVulns(b *strings.Builder, vulns []models.Vulnerability, seen map[string]diff.Sighting) {
+	var actionable, noise []models.Vulnerability
+	for _, v := range vulns {
+		if v.Noise {
+			noise = append(noise, v)
+		} else {
+			actionable = append(actionable, v)
+		}
+	}
+	if len(actionable) > 0 {
+		b.WriteString(fmt.Sprintf("## New Vulnerabilities (+%d)\n\n", len(actionable)))
+		writeVulnTable(b, sortVulnsBySeverity(actionable), seen)
+	}
+	if len(noise) > 0 {
+		b.WriteString(fmt.Sprintf("## New Informational Noise (+%d)\n\n", len(noise)))
+		writeVulnTable(b, sortVulnsBySeverity(noise), seen)
 	}
-	b.WriteString(fmt.Sprintf("## New Vulnerabilities (+%d)\n\n", len(vulns)))
-	writeVulnTable(b, sortVulnsBySeverity(vulns))
 }
 
 // writeResolvedVulns renders resolved vulnerabilities sorted by severity. Skipped when empty.
-func writeResolvedVulns(b *strings.Builder, vulns []models.Vulnerability) {
+func writeResolvedVulns(b *strings.Builder, vulns []models.Vulnerability, seen map[string]diff.Sighting) {
 	if len(vulns) == 0 {
 		return
 	}
 	b.WriteString(fmt.Sprintf("## Resolved Vulnerabilities (-%d)\n\n", len(vulns)))
-	writeVulnTable(b, sortVulnsBySeverity(vulns))
+	writeVulnTable(b, sortVulnsBySeverity(vulns), seen)
 }
 
 // writeVulnTable is the shared table renderer for vulnerability slices.
-func writeVulnTable(b *strings.Builder, vulns []models.Vulnerability) {
-	b.WriteString("| Severity | Template ID | Host | Name |\n")
-	b.WriteString("|----------|-------------|------|------|\n")
+// The First Seen and Last Seen columns are added when seen is set.
+func writeVulnTable(b *strings.Builder, vulns []models.Vulnerability, seen map[string]diff.Sighting) {
+	if seen == nil {
+		b.WriteString("| Severity | Template ID | Host | Name |\n")
+		b.WriteString("|----------|-------------|------|------|\n")
+	} else {
+		b.WriteString("| Severity | Template ID | Host | Name | First Seen | Last Seen |\n")
+		b.WriteString("|----------|-------------|------|------|------------|-----------|\n")
+	}
 	for _, v := range vulns {
-		b.WriteString(fmt.Sprintf("| %s | %s | %s | %s |\n",
+		b.WriteString(fmt.Sprintf("| %s | %s | %s | %s |",
 			v.Severity, v.TemplateID, v.Host, v.Name))
+		if seen != nil {
+			b.WriteString(seenCells(seen, diff.VulnKey(v)))
+		}
+		b.WriteString("\n")
 	}
 	b.WriteString("\n")
 }
@@ -158,26 +194,16 @@ func writeDanglingDNSChanges(b *strings.Builder, r *diff.DiffResult) {
 
 	if len(r.NewlyDangling) > 0 {
 		b.WriteString(fmt.Sprintf("### Newly Dangling (%d)\n\n", len(r.NewlyDangling)))
-		for _, s := range r.NewlyDangling {
-			cname := getCNAMETarget(s.DNSRecords)
-			if cname != "-" {
-				b.WriteString(fmt.Sprintf("- %s → CNAME: %s\n", s.Name, cname))
-			} else {
-				b.WriteString(fmt.Sprintf("- %s (no CNAME)\n", s.Name))
-			}
+		for _, s := range sortDanglingBySeverity(r.NewlyDangling) {
+			writeDanglingLine(b, s)
 		}
 		b.WriteString("\n")
 	}
 
 	if len(r.PersistentlyDangling) > 0 {
 		b.WriteString(fmt.Sprintf("### Persistently Dangling (%d)\n\n", len(r.PersistentlyDangling)))
-		for _, s := range r.PersistentlyDangling {
-			cname := getCNAMETarget(s.DNSRecords)
-			if cname != "-" {
-				b.WriteString(fmt.Sprintf("- %s → CNAME: %s\n", s.Name, cname))
-			} else {
-				b.WriteString(fmt.Sprintf("- %s (no CNAME)\n", s.Name))
-			}
+		for _, s := range sortDanglingBySeverity(r.PersistentlyDangling) {
+			writeDanglingLine(b, s)
 		}
 		b.WriteString("\n")
 	}
@@ -191,10 +217,60 @@ func writeDanglingDNSChanges(b *strings.Builder, r *diff.DiffResult) {
 	}
 }
 
+// writeDanglingLine renders one dangling subdomain with its takeover severity.
+func writeDanglingLine(b *strings.Builder, s models.Subdomain) {
+	a := takeover.Assess(s)
+	if a.CNAME == "" {
+		b.WriteString(fmt.Sprintf("- **%s** %s (no CNAME)\n", a.Severity, s.Name))
+		return
+	}
+	b.WriteString(fmt.Sprintf("- **%s** %s → CNAME: %s — %s\n", a.Severity, s.Name, a.CNAME, a.Risk()))
+}
+
 // ---------------------------------------------------------------------------
 // Helpers
 // ---------------------------------------------------------------------------
 
+// seenNote renders the sighting of key for a list line, e.g. " — first
+// observed 2024-05-01, last seen 3 scans ago (2024-06-01)". Empty when the
+// diff is not dated.
+func seenNote(seen map[string]diff.Sighting, key string) string {
+	s, ok := seen[key]
+	if !ok {
+		return ""
+	}
+	note := " — first observed " + s.FirstSeen.UTC().Format("2006-01-02")
+	if last := lastSeen(s); last != "" {
+		note += ", last seen " + last
+	}
+	return note
+}
+
+// seenCells renders the First Seen and Last Seen cells of key's table row.
+func seenCells(seen map[string]diff.Sighting, key string) string {
+	s, ok := seen[key]
+	if !ok {
+		return " - | - |"
+	}
+	last := lastSeen(s)
+	if last == "" {
+		last = "-"
+	}
+	return fmt.Sprintf(" %s | %s |", s.FirstSeen.UTC().Format("2006-01-02"), last)
+}
+
+// lastSeen describes the last earlier scan that had an entry, e.g. "3 scans
+// ago (2024-06-01)"; empty for an entry no earlier scan had.
+func lastSeen(s diff.Sighting) string {
+	switch {
+	case s.LastSeenPrevious.IsZero():
+		return ""
+	case s.ScansAgo == 1:
+		return "in the previous scan (" + s.LastSeenPrevious.UTC().Format("2006-01-02") + ")"
+	}
+	return fmt.Sprintf("%d scans ago (%s)", s.ScansAgo, s.LastSeenPrevious.UTC().Format("2006-01-02"))
+}
+
 // isEmptyDiff returns true when no changes exist across all categories.
 func isEmptyDiff(r *diff.DiffResult) bool {
 	return len(r.NewSubdomains) == 0 &&
@@ -274,11 +350,3 @@ func sortVulnsBySeverity(vulns []models.Vulnerability) []models.Vulnerability {
 	})
 	return sorted
 }
-
-// writeFile writes content to path, wrapping any OS error with context.
-func writeFile(outputPath, content string) error {
-	if err := os.WriteFile(outputPath, []byte(content), 0644); err != nil {
-		return fmt.Errorf("writing report to %s: %w", outputPath, err)
-	}
-	return nil
-}
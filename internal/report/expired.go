@@ -0,0 +1,64 @@
+package report
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hakim/reconpipe/pkg/models"
+)
+
+// WriteExpiredFindingsReport generates a markdown report of the findings
+// closed by prune-findings runs, newest run first, and writes it to
+// outputPath. These are findings that stopped being reported without any scan
+// confirming they were fixed, so they deserve a second look before they are
+// forgotten.
+func WriteExpiredFindingsReport(runs []models.FindingExpiry, outputPath string) error {
+	var b strings.Builder
+
+	b.WriteString("# Expired Findings Report\n\n")
+	if len(runs) > 0 {
+		b.WriteString(fmt.Sprintf("**Target:** %s\n", runs[0].Target))
+	}
+	b.WriteString(fmt.Sprintf("**Date:** %s\n\n", now().UTC().Format("2006-01-02 15:04:05 UTC")))
+
+	total := 0
+	byKind := map[string]int{}
+	for _, run := range runs {
+		total += len(run.Findings)
+		for _, f := range run.Findings {
+			byKind[f.Kind]++
+		}
+	}
+
+	// Summary section
+	b.WriteString("## Summary\n\n")
+	b.WriteString(fmt.Sprintf("- **Auto-closed findings:** %d\n", total))
+	b.WriteString(fmt.Sprintf("- **Vulnerabilities:** %d\n", byKind[models.FindingKindVuln]))
+	b.WriteString(fmt.Sprintf("- **Dangling DNS:** %d\n", byKind[models.FindingKindDangling]))
+	b.WriteString(fmt.Sprintf("- **Prune runs:** %d\n\n", len(runs)))
+
+	b.WriteString("## Auto-closed Findings\n\n")
+	if total == 0 {
+		b.WriteString("No findings were auto-closed.\n")
+		return writeFile(outputPath, b.String())
+	}
+
+	b.WriteString("Closed because they were missing from several completed scans in a row, not because a scan confirmed them fixed.\n\n")
+	b.WriteString("| Closed | Severity | Kind | Finding | Host | Last Seen | Scans Missed |\n")
+	b.WriteString("|--------|----------|------|---------|------|-----------|--------------|\n")
+	for i := len(runs) - 1; i >= 0; i-- {
+		run := runs[i]
+		closed := run.Time.UTC().Format("2006-01-02")
+		if run.Operator != "" {
+			closed += " (" + run.Operator + ")"
+		}
+		for _, f := range run.Findings {
+			b.WriteString(fmt.Sprintf("| %s | %s | %s | %s | %s | %s | %d |\n",
+				closed, f.Severity, f.Kind, f.Name, f.Host,
+				f.LastSeen.UTC().Format("2006-01-02"), f.ScansMissed))
+		}
+	}
+	b.WriteString("\n")
+
+	return writeFile(outputPath, b.String())
+}
@@ -0,0 +1,229 @@
+package report
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/hakim/reconpipe/internal/httpprobe"
+	"github.com/hakim/reconpipe/internal/portscan"
+	"github.com/hakim/reconpipe/internal/triage"
+	"github.com/hakim/reconpipe/pkg/models"
+)
+
+// exposureService is one column of the exposure matrix: a kind of service
+// that rarely belongs on the internet. A port matches when nmap named its
+// service in services, or when its number is in ports (masscan-only results
+// carry no service name).
+type exposureService struct {
+	name     string
+	ports    []int
+	services []string
+}
+
+// exposureServices are the matrix columns, in display order.
+var exposureServices = []exposureService{
+	{"SSH", []int{22}, []string{"ssh"}},
+	{"RDP", []int{3389}, []string{"ms-wbt-server", "rdp"}},
+	{"Telnet", []int{23}, []string{"telnet"}},
+	{"FTP", []int{21}, []string{"ftp"}},
+	{"SMB", []int{139, 445}, []string{"microsoft-ds", "netbios-ssn"}},
+	{"VNC", []int{5900, 5901}, []string{"vnc"}},
+	{"Database", []int{1433, 1521, 3306, 5432, 5984, 6379, 9042, 9200, 11211, 27017},
+		[]string{"ms-sql-s", "oracle", "oracle-tns", "mysql", "postgresql", "couchdb", "redis", "cassandra", "elasticsearch", "memcache", "memcached", "mongodb", "mongod"}},
+}
+
+// adminPanelColumn is the matrix column filled from HTTP probes rather than
+// port numbers.
+const adminPanelColumn = "Admin Panel"
+
+// adminPanelTags are the screenshot triage tags that mark a probed page as
+// an admin interface.
+var adminPanelTags = map[string]bool{"admin": true, "dashboard": true}
+
+// WriteExposureReport generates a matrix of subdomains against the notable
+// services each one exposes (SSH, RDP, databases, admin panels and so on)
+// and writes it to the specified output path. probes may be nil when the
+// HTTP probe has not run; the admin panel column is then left out.
+func WriteExposureReport(ports *portscan.PortScanResult, probes *httpprobe.HTTPProbeResult, outputPath string) error {
+	var b strings.Builder
+
+	// Header
+	b.WriteString("# Service Exposure Report\n\n")
+	b.WriteString(fmt.Sprintf("**Target:** %s\n", ports.Target))
+	b.WriteString(fmt.Sprintf("**Date:** %s\n", now().Format("2006-01-02 15:04:05")))
+	b.WriteString(collectedLine("Ports collected", ports.CollectedAt))
+	if probes != nil {
+		b.WriteString(collectedLine("Probes collected", probes.CollectedAt))
+	}
+	b.WriteString("\n")
+
+	matrix := exposureMatrix(ports.Hosts, probes)
+
+	// Only columns that something exposes, to keep the table narrow
+	var columns []string
+	for _, svc := range exposureServices {
+		if matrix.count[svc.name] > 0 {
+			columns = append(columns, svc.name)
+		}
+	}
+	if matrix.count[adminPanelColumn] > 0 {
+		columns = append(columns, adminPanelColumn)
+	}
+
+	b.WriteString("## Exposure Matrix\n\n")
+	if len(matrix.names) > 0 {
+		b.WriteString("Open ports per subdomain for services that should rarely face the internet.\n\n")
+		b.WriteString("| Subdomain | " + strings.Join(columns, " | ") + " |\n")
+		b.WriteString("|-----------|" + strings.Repeat("---|", len(columns)) + "\n")
+		for _, name := range matrix.names {
+			cells := make([]string, len(columns))
+			for i, col := range columns {
+				cells[i] = formatPorts(matrix.rows[name][col])
+			}
+			b.WriteString(fmt.Sprintf("| %s | %s |\n", name, strings.Join(cells, " | ")))
+		}
+	} else {
+		b.WriteString("No notable services exposed.\n")
+	}
+	b.WriteString("\n")
+
+	// Summary section
+	b.WriteString("## Summary\n\n")
+	b.WriteString(fmt.Sprintf("- **Subdomains with notable services:** %d\n", len(matrix.names)))
+	for _, col := range columns {
+		b.WriteString(fmt.Sprintf("- **%s:** %d\n", col, matrix.count[col]))
+	}
+	if probes == nil {
+		b.WriteString("- **Admin panels:** not checked (HTTP probe has not run)\n")
+	}
+
+	// Write to file
+	return writeFile(outputPath, b.String())
+}
+
+// exposure is the data behind the exposure matrix.
+type exposure struct {
+	names []string                    // rows, sorted
+	rows  map[string]map[string][]int // row -> column -> ports
+	count map[string]int              // column -> rows with that service
+}
+
+// exposureMatrix maps each subdomain (or IP, for hosts without one) to the
+// notable services it exposes. CDN and excluded hosts are left out, since
+// they were not port scanned.
+func exposureMatrix(hosts []models.Host, probes *httpprobe.HTTPProbeResult) exposure {
+	m := exposure{rows: map[string]map[string][]int{}, count: map[string]int{}}
+	add := func(name, col string, port int) {
+		row := m.rows[name]
+		if row == nil {
+			row = map[string][]int{}
+			m.rows[name] = row
+		}
+		for _, p := range row[col] {
+			if p == port {
+				return
+			}
+		}
+		if len(row[col]) == 0 {
+			m.count[col]++
+		}
+		row[col] = append(row[col], port)
+	}
+
+	namesByIP := map[string][]string{}
+	for _, host := range getNonCDNHosts(hosts) {
+		names := host.Subdomains
+		if len(names) == 0 {
+			names = []string{host.IP}
+		}
+		namesByIP[host.IP] = names
+		for _, port := range host.Ports {
+			col := exposureColumn(port)
+			if col == "" {
+				continue
+			}
+			for _, name := range names {
+				add(name, col, port.Number)
+			}
+		}
+	}
+
+	if probes != nil {
+		for _, probe := range probes.Probes {
+			if !isAdminPanel(probe) {
+				continue
+			}
+			// Probes of a bare IP stand for every subdomain on that host
+			name, _, err := net.SplitHostPort(probe.Host)
+			if err != nil {
+				name = probe.Host
+			}
+			names := []string{name}
+			if ipNames, ok := namesByIP[name]; ok {
+				names = ipNames
+			}
+			for _, n := range names {
+				add(n, adminPanelColumn, probe.Port)
+			}
+		}
+	}
+
+	for name, row := range m.rows {
+		m.names = append(m.names, name)
+		for _, ports := range row {
+			sort.Ints(ports)
+		}
+	}
+	sort.Strings(m.names)
+	return m
+}
+
+// exposureColumn returns the matrix column for port, or "" when it is not a
+// notable service. A service name from nmap wins over the port number.
+func exposureColumn(port models.Port) string {
+	for _, svc := range exposureServices {
+		for _, s := range svc.services {
+			if port.Service == s {
+				return svc.name
+			}
+		}
+	}
+	if port.Service != "" && port.Service != "unknown" {
+		return ""
+	}
+	for _, svc := range exposureServices {
+		for _, n := range svc.ports {
+			if port.Number == n {
+				return svc.name
+			}
+		}
+	}
+	return ""
+}
+
+// isAdminPanel reports whether a live probe looks like an admin interface,
+// judged by the triage rules over its title and detected technologies.
+func isAdminPanel(probe models.HTTPProbe) bool {
+	if probe.StatusCode == 0 {
+		return false
+	}
+	text := probe.Title + " " + strings.Join(probe.Technologies, " ")
+	for _, rule := range triage.DefaultRules {
+		if adminPanelTags[rule.Tag] && rule.Pattern.MatchString(text) {
+			return true
+		}
+	}
+	return false
+}
+
+// formatPorts renders a matrix cell: the ports, or blank when none.
+func formatPorts(ports []int) string {
+	parts := make([]string, len(ports))
+	for i, p := range ports {
+		parts[i] = strconv.Itoa(p)
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,65 @@
+package report
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/hakim/reconpipe/internal/storage"
+)
+
+// stageFiles are the raw files holding what a stage collected, in pipeline
+// order. Each records its collection time as collected_at.
+var stageFiles = []string{"subdomains.json", "ports.json", "http-probes.json", "vulns.json"}
+
+// collectedLine is the report header line stamping when its data was
+// collected, as opposed to the **Date:** the report was rendered. Raw output
+// written before collection times were recorded gets no line.
+func collectedLine(label string, t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return fmt.Sprintf("**%s:** %s\n", label, t.UTC().Format("2006-01-02 15:04:05 UTC"))
+}
+
+// DataAge is how old one stage's raw data is.
+type DataAge struct {
+	File        string
+	CollectedAt time.Time
+	// Estimated is set when the file predates collected_at and its
+	// modification time stands in for it.
+	Estimated bool
+}
+
+// StaleData returns the stage files of scanDir, in pipeline order, whose
+// data was collected more than maxAge ago.
+func StaleData(scanDir string, maxAge time.Duration) ([]DataAge, error) {
+	var stale []DataAge
+	for _, name := range stageFiles {
+		path := storage.RawPath(scanDir, name)
+		info, err := os.Stat(path)
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			return nil, err
+		}
+
+		var stamp struct {
+			CollectedAt time.Time `json:"collected_at"`
+		}
+		if _, err := readRaw(path, &stamp); err != nil {
+			return nil, err
+		}
+		age := DataAge{File: name, CollectedAt: stamp.CollectedAt}
+		if age.CollectedAt.IsZero() {
+			age.CollectedAt = info.ModTime()
+			age.Estimated = true
+		}
+		if time.Since(age.CollectedAt) > maxAge {
+			stale = append(stale, age)
+		}
+	}
+	return stale, nil
+}
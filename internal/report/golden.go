@@ -0,0 +1,190 @@
+package report
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hakim/reconpipe/internal/storage"
+)
+
+// GoldenEpoch is the fixed clock used when regenerating reports for golden
+// comparison, so date stamps never cause spurious mismatches.
+var GoldenEpoch = time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// GoldenMismatch describes one report whose regenerated output differs from
+// the stored golden file.
+type GoldenMismatch struct {
+	Name    string // report file name, e.g. "ports.md"
+	Missing bool   // regenerated report has no golden file
+	Stale   bool   // golden file exists but no report was regenerated for it
+	Diff    string // line diff, "-" expected / "+" actual
+}
+
+// CompareGolden regenerates every report from a copy of fixtureDir's raw/
+// output and compares each one with {goldenDir}/{name}. The fixture itself is
+// never modified. When update is true, goldenDir is rewritten to match the
+// current output instead and no mismatches are returned.
+func CompareGolden(fixtureDir, goldenDir string, update bool) ([]GoldenMismatch, error) {
+	workDir, err := os.MkdirTemp("", "reconpipe-golden-*")
+	if err != nil {
+		return nil, fmt.Errorf("creating work dir: %w", err)
+	}
+	defer os.RemoveAll(workDir)
+
+	// The checked-in fixture always uses the default raw/ directory
+	if err := copyDir(filepath.Join(fixtureDir, "raw"), storage.RawDir(workDir)); err != nil {
+		return nil, fmt.Errorf("copying fixture raw output: %w", err)
+	}
+
+	SetClock(func() time.Time { return GoldenEpoch })
+	defer SetClock(nil)
+
+	paths, err := RegenerateReports(workDir)
+	if err != nil {
+		return nil, fmt.Errorf("regenerating reports: %w", err)
+	}
+
+	if update {
+		if err := os.MkdirAll(goldenDir, 0755); err != nil {
+			return nil, fmt.Errorf("creating golden dir: %w", err)
+		}
+	}
+
+	var mismatches []GoldenMismatch
+	produced := make(map[string]bool, len(paths))
+
+	for _, path := range paths {
+		name := filepath.Base(path)
+		produced[name] = true
+
+		actual, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("reading regenerated %s: %w", name, err)
+		}
+
+		goldenPath := filepath.Join(goldenDir, name)
+		if update {
+			if err := os.WriteFile(goldenPath, actual, 0644); err != nil {
+				return nil, fmt.Errorf("updating golden %s: %w", name, err)
+			}
+			continue
+		}
+
+		expected, err := os.ReadFile(goldenPath)
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				mismatches = append(mismatches, GoldenMismatch{Name: name, Missing: true})
+				continue
+			}
+			return nil, fmt.Errorf("reading golden %s: %w", name, err)
+		}
+
+		if string(expected) != string(actual) {
+			mismatches = append(mismatches, GoldenMismatch{
+				Name: name,
+				Diff: lineDiff(string(expected), string(actual)),
+			})
+		}
+	}
+
+	// Golden files that no longer correspond to any report
+	entries, err := os.ReadDir(goldenDir)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("listing golden dir: %w", err)
+	}
+	for _, entry := range entries {
+		if entry.IsDir() || produced[entry.Name()] {
+			continue
+		}
+		if update {
+			if err := os.Remove(filepath.Join(goldenDir, entry.Name())); err != nil {
+				return nil, fmt.Errorf("removing stale golden %s: %w", entry.Name(), err)
+			}
+			continue
+		}
+		mismatches = append(mismatches, GoldenMismatch{Name: entry.Name(), Stale: true})
+	}
+
+	sort.Slice(mismatches, func(i, j int) bool { return mismatches[i].Name < mismatches[j].Name })
+	return mismatches, nil
+}
+
+// copyDir copies the regular files of src (recursively) into dst.
+func copyDir(src, dst string) error {
+	return filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		if d.IsDir() {
+			return os.MkdirAll(target, 0755)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(target, data, 0644)
+	})
+}
+
+// lineDiff renders a minimal line-based diff between expected and actual
+// using a longest-common-subsequence walk. Unchanged lines are omitted;
+// each hunk is introduced by the 1-based line number in expected.
+func lineDiff(expected, actual string) string {
+	a := strings.Split(expected, "\n")
+	b := strings.Split(actual, "\n")
+
+	// lcs[i][j] = length of the LCS of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var out strings.Builder
+	inHunk := false
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			inHunk = false
+			i++
+			j++
+			continue
+		case i < len(a) && (j == len(b) || lcs[i+1][j] >= lcs[i][j+1]):
+			if !inHunk {
+				out.WriteString(fmt.Sprintf("@@ line %d\n", i+1))
+				inHunk = true
+			}
+			out.WriteString("- " + a[i] + "\n")
+			i++
+		default:
+			if !inHunk {
+				out.WriteString(fmt.Sprintf("@@ line %d\n", i+1))
+				inHunk = true
+			}
+			out.WriteString("+ " + b[j] + "\n")
+			j++
+		}
+	}
+
+	return out.String()
+}
@@ -0,0 +1,415 @@
+package report
+
+import (
+	_ "embed"
+	"fmt"
+	"html/template"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hakim/reconpipe/internal/diff"
+	"github.com/hakim/reconpipe/internal/discovery"
+	"github.com/hakim/reconpipe/internal/httpprobe"
+	"github.com/hakim/reconpipe/internal/portscan"
+	"github.com/hakim/reconpipe/internal/takeover"
+	"github.com/hakim/reconpipe/internal/vulnscan"
+	"github.com/hakim/reconpipe/pkg/models"
+)
+
+// htmlTemplateText is the single page every HTML report is rendered into.
+// Styles and the table sorting script are inline, so a report is one file
+// that opens in any browser and can be mailed as is.
+//
+//go:embed templates/report.html.tmpl
+var htmlTemplateText string
+
+var htmlTemplate = template.Must(template.New("report").Parse(htmlTemplateText))
+
+// htmlPage is what the HTML template renders: a header, summary figures,
+// notices and a list of table sections.
+type htmlPage struct {
+	Title    string
+	Target   string
+	Date     string
+	Meta     []htmlStat // extra header fields, e.g. when the data was collected
+	Stats    []htmlStat
+	Notices  []string
+	Sections []*htmlSection
+}
+
+// htmlStat is a labelled figure. Class colors it, e.g. with a severity.
+type htmlStat struct {
+	Label string
+	Value string
+	Class string
+}
+
+// htmlSection is a headed table; Empty is shown instead when it has no rows.
+type htmlSection struct {
+	Title   string
+	Intro   string
+	Empty   string
+	Columns []string
+	Rows    [][]htmlCell
+}
+
+// htmlCell is a table cell. Sort, when set, is what the column sorts by
+// instead of the text; Severity renders the text as a colored badge.
+type htmlCell struct {
+	Text     string
+	Sort     string
+	Severity string
+}
+
+// newHTMLPage starts a report page dated now, stamped with when its data
+// was collected when that is known.
+func newHTMLPage(title, target string, collectedAt time.Time) *htmlPage {
+	p := &htmlPage{
+		Title:  title,
+		Target: target,
+		Date:   now().UTC().Format("2006-01-02 15:04:05 UTC"),
+	}
+	p.meta("Data collected", collectedAt)
+	return p
+}
+
+// meta adds a timestamp header field; zero times are left out.
+func (p *htmlPage) meta(label string, t time.Time) {
+	if !t.IsZero() {
+		p.Meta = append(p.Meta, htmlStat{Label: label, Value: t.UTC().Format("2006-01-02 15:04:05 UTC")})
+	}
+}
+
+// stat adds a summary figure.
+func (p *htmlPage) stat(label string, value int, class string) {
+	p.Stats = append(p.Stats, htmlStat{Label: label, Value: strconv.Itoa(value), Class: class})
+}
+
+// section adds a table section with columns and returns it for its rows.
+func (p *htmlPage) section(title, empty string, columns ...string) *htmlSection {
+	s := &htmlSection{Title: title, Empty: empty, Columns: columns}
+	p.Sections = append(p.Sections, s)
+	return s
+}
+
+// row adds a row of cells.
+func (s *htmlSection) row(cells ...htmlCell) {
+	s.Rows = append(s.Rows, cells)
+}
+
+// cell is a text cell, "-" when text is empty.
+func cell(text string) htmlCell {
+	if text == "" {
+		text = "-"
+	}
+	return htmlCell{Text: text}
+}
+
+// numCell is a cell holding a number, which sorts numerically.
+func numCell(n int) htmlCell {
+	return htmlCell{Text: strconv.Itoa(n), Sort: strconv.Itoa(n)}
+}
+
+// sevCell is a severity badge, which sorts most severe first.
+func sevCell(sev models.Severity) htmlCell {
+	rank, ok := diffSeverityRank[sev]
+	if !ok {
+		rank = len(diffSeverityRank)
+	}
+	return htmlCell{Text: string(sev), Sort: strconv.Itoa(rank), Severity: string(sev)}
+}
+
+// dateCell is the day of t, "-" when it is zero.
+func dateCell(t time.Time) htmlCell {
+	if t.IsZero() {
+		return cell("")
+	}
+	return cell(t.UTC().Format("2006-01-02"))
+}
+
+// writeHTML renders page and writes it to outputPath.
+func writeHTML(page *htmlPage, outputPath string) error {
+	var b strings.Builder
+	if err := htmlTemplate.Execute(&b, page); err != nil {
+		return fmt.Errorf("rendering %s: %w", filepath.Base(outputPath), err)
+	}
+	return writeFile(outputPath, b.String())
+}
+
+// WriteSubdomainHTMLReport generates an HTML report for subdomain discovery
+// results and writes it to the specified output path.
+func WriteSubdomainHTMLReport(result *discovery.DiscoveryResult, outputPath string) error {
+	p := newHTMLPage("Subdomain Discovery Report", result.Target, result.CollectedAt)
+	p.stat("Discovered", result.TotalFound, "")
+	p.stat("Unique", result.UniqueCount, "")
+	p.stat("Resolved", result.ResolvedCount, "")
+	p.stat("Dangling", result.DanglingCount, danglingClass(result.DanglingCount))
+
+	highPriority, lowPriority := discovery.ClassifyDangling(result.Subdomains)
+	s := p.section("Takeover Candidates", "None found.", "Severity", "Subdomain", "CNAME Target", "Provider", "Source")
+	s.Intro = "Subdomains whose CNAME points at a service nobody may own any more; someone who claims it serves content on the subdomain."
+	for _, sub := range sortDanglingBySeverity(highPriority) {
+		a := takeover.Assess(sub)
+		s.row(sevCell(a.Severity), cell(sub.Name), cell(a.CNAME), cell(a.Risk()), cell(sub.Source))
+	}
+
+	s = p.section("Resolved Subdomains", "None found.", "Subdomain", "IPs", "Source")
+	for _, sub := range getResolvedSubdomains(result.Subdomains) {
+		s.row(cell(sub.Name), cell(formatIPs(sub.DNSRecords)), cell(sub.Source))
+	}
+
+	s = p.section("Stale DNS", "None found.", "Subdomain", "Source")
+	for _, sub := range lowPriority {
+		s.row(cell(sub.Name), cell(sub.Source))
+	}
+
+	s = p.section("Unresolved", "None found.", "Subdomain", "Source")
+	for _, sub := range getUnresolvedSubdomains(result.Subdomains) {
+		s.row(cell(sub.Name), cell(sub.Source))
+	}
+
+	s = p.section("Sources", "None found.", "Source", "Subdomains")
+	sources := make([]string, 0, len(result.Sources))
+	for source := range result.Sources {
+		sources = append(sources, source)
+	}
+	sort.Strings(sources)
+	for _, source := range sources {
+		s.row(cell(source), numCell(result.Sources[source]))
+	}
+
+	return writeHTML(p, outputPath)
+}
+
+// WritePortHTMLReport generates an HTML report for port scan results and
+// writes it to the specified output path.
+func WritePortHTMLReport(result *portscan.PortScanResult, outputPath string) error {
+	p := newHTMLPage("Port Scan Report", result.Target, result.CollectedAt)
+	p.stat("Hosts", len(result.Hosts), "")
+	p.stat("CDN filtered", result.CDNCount, "")
+	p.stat("Scanned", result.ScannedCount, "")
+	p.stat("Open ports", result.TotalPorts, "")
+
+	s := p.section("Open Ports", "No open ports found.", "IP", "Subdomains", "Port", "Protocol", "State", "Service", "Version", "Location")
+	for _, host := range getNonCDNHosts(result.Hosts) {
+		location := ""
+		if host.Geo != nil {
+			location = host.Geo.String()
+		}
+		for _, port := range host.Ports {
+			s.row(cell(host.IP), cell(strings.Join(host.Subdomains, ", ")), numCell(port.Number),
+				cell(port.Protocol), cell(port.State), cell(port.Service), cell(port.Version), cell(location))
+		}
+	}
+
+	s = p.section("CDN Filtered Hosts", "None found.", "IP", "CDN Provider", "Subdomains")
+	s.Intro = "Served by a CDN, so the addresses belong to the CDN and were not port scanned."
+	for _, host := range getCDNHosts(result.Hosts) {
+		s.row(cell(host.IP), cell(host.CDNProvider), cell(strings.Join(host.Subdomains, ", ")))
+	}
+
+	if excluded := getExcludedHosts(result.Hosts); len(excluded) > 0 {
+		s = p.section("Excluded Hosts", "", "IP", "Subdomains")
+		s.Intro = "On the exclusions list; not port scanned, probed or vulnerability scanned."
+		for _, host := range excluded {
+			s.row(cell(host.IP), cell(strings.Join(host.Subdomains, ", ")))
+		}
+	}
+
+	return writeHTML(p, outputPath)
+}
+
+// WriteHTTPProbeHTMLReport generates an HTML report for HTTP probe results
+// and writes it to the specified output path.
+func WriteHTTPProbeHTMLReport(result *httpprobe.HTTPProbeResult, outputPath string) error {
+	p := newHTMLPage("HTTP Probe Report", result.Target, result.CollectedAt)
+	p.stat("Live services", result.LiveCount, "")
+	if result.UniqueApps > 0 && result.UniqueApps != result.LiveCount {
+		p.stat("Unique applications", result.UniqueApps, "")
+	}
+	if result.Filter != "" {
+		p.Notices = append(p.Notices, fmt.Sprintf("Filtered scan: only targets matching %s were probed; %d were left out.", result.Filter, result.FilteredOut))
+	}
+
+	s := p.section("Live HTTP Services", "No live HTTP services discovered.", "URL", "Status", "Title", "Server", "Technologies", "CDN")
+	var authProbes []models.HTTPProbe
+	for _, probe := range result.Probes {
+		title := probe.Title
+		switch {
+		case title != "":
+		case probe.GRPC:
+			title = "(gRPC service)"
+		case probe.WebSocket != "":
+			title = "(WebSocket endpoint)"
+		}
+		status := numCell(probe.StatusCode)
+		if probe.RedirectsTo != "" {
+			status.Text += " → " + probe.RedirectsTo
+		}
+		cdn := ""
+		if probe.IsCDN {
+			cdn = probe.CDNProvider
+		}
+		s.row(cell(probe.URL), status, cell(title), cell(probe.WebServer), cell(strings.Join(probe.Technologies, ", ")), cell(cdn))
+
+		if probe.AuthSurface != "" {
+			authProbes = append(authProbes, probe)
+		}
+	}
+
+	if len(authProbes) > 0 {
+		s = p.section("Authentication Surfaces", "", "Kind", "URL", "Title", "Evidence")
+		s.Intro = "Services that ask for credentials: VPN portals, SSO and identity provider endpoints, login pages and HTTP authentication prompts."
+		for _, probe := range authProbes {
+			kind := cell(authKindLabel(probe.AuthSurface))
+			kind.Sort = strconv.Itoa(authKindRank(probe.AuthSurface))
+			s.row(kind, cell(probe.URL), cell(probe.Title), cell(probe.AuthEvidence))
+		}
+	}
+
+	return writeHTML(p, outputPath)
+}
+
+// WriteVulnHTMLReport generates an HTML report for vulnerability scan
+// results and writes it to the specified output path.
+func WriteVulnHTMLReport(result *vulnscan.VulnScanResult, outputPath string) error {
+	p := newHTMLPage("Vulnerability Scan Report", result.Target, result.CollectedAt)
+	p.stat("Total findings", result.TotalCount, "")
+	for _, sev := range severityOrder {
+		p.stat(strings.Title(string(sev)), result.SeverityCounts[string(sev)], string(sev))
+	}
+	if result.Filter != "" {
+		p.Notices = append(p.Notices, fmt.Sprintf("Filtered scan: only targets matching %s were scanned; %d were left out.", result.Filter, result.FilteredOut))
+	}
+	if result.Partial {
+		p.Notices = append(p.Notices, "Partial scan: the pipeline deadline cut this scan short, so some targets were not fully checked.")
+	}
+
+	var actionable, noise []models.Vulnerability
+	for _, v := range result.Vulnerabilities {
+		if v.Noise {
+			noise = append(noise, v)
+		} else {
+			actionable = append(actionable, v)
+		}
+	}
+
+	s := p.section("Findings", "No findings.", "Severity", "Name", "Host", "Matched At", "Template ID")
+	for _, v := range sortVulnsBySeverity(actionable) {
+		name := v.Name
+		if v.OriginalSeverity != "" {
+			name += fmt.Sprintf(" (raised as %s)", v.OriginalSeverity)
+		}
+		s.row(sevCell(v.Severity), cell(name), cell(v.Host), cell(v.MatchedAt), cell(v.TemplateID))
+	}
+
+	if len(noise) > 0 {
+		s = p.section("Informational Noise", "", "Severity", "Name", "Host", "Template ID")
+		s.Intro = "Findings a noise rule matched. They are counted above but are rarely worth acting on."
+		for _, v := range sortVulnsBySeverity(noise) {
+			s.row(sevCell(v.Severity), cell(v.Name), cell(v.Host), cell(v.TemplateID))
+		}
+	}
+
+	return writeHTML(p, outputPath)
+}
+
+// WriteDiffHTMLReport generates an HTML report of the delta between two
+// consecutive scan snapshots and writes it to outputPath.
+func WriteDiffHTMLReport(result *diff.DiffResult, outputPath string) error {
+	p := newHTMLPage("Scan Diff Report", "", result.CollectedAt)
+	p.meta("Compared with data collected", result.PreviousCollectedAt)
+	p.stat("New subdomains", len(result.NewSubdomains), "")
+	p.stat("New open ports", len(result.NewPorts), "")
+	p.stat("New vulnerabilities", len(result.NewVulns), "")
+	p.stat("Resolved vulnerabilities", len(result.ResolvedVulns), "")
+	p.stat("Newly dangling", len(result.NewlyDangling), danglingClass(len(result.NewlyDangling)))
+	if isEmptyDiff(result) {
+		p.Notices = append(p.Notices, "No changes detected.")
+		return writeHTML(p, outputPath)
+	}
+
+	s := p.section("Summary", "", "Category", "Previous", "Current", "Change")
+	s.row(cell("Subdomains"), numCell(result.PreviousSubdomainCount), numCell(result.CurrentSubdomainCount),
+		cell(formatChange(result.CurrentSubdomainCount-result.PreviousSubdomainCount, len(result.NewSubdomains), len(result.RemovedSubdomains))))
+	s.row(cell("Open Ports"), numCell(result.PreviousPortCount), numCell(result.CurrentPortCount),
+		cell(formatChange(result.CurrentPortCount-result.PreviousPortCount, len(result.NewPorts), len(result.ClosedPorts))))
+	s.row(cell("Vulnerabilities"), numCell(result.PreviousVulnCount), numCell(result.CurrentVulnCount),
+		cell(formatChange(result.CurrentVulnCount-result.PreviousVulnCount, len(result.NewVulns), len(result.ResolvedVulns))))
+
+	s = p.section("New Subdomains", "None.", "Subdomain", "DNS", "First Seen", "Last Seen")
+	for _, sub := range result.NewSubdomains {
+		s.row(append([]htmlCell{cell(sub.Name), cell(subdomainDNSSummary(sub))}, seenHTMLCells(result.SubdomainSeen, sub.Name)...)...)
+	}
+	s = p.section("Removed Subdomains", "None.", "Subdomain", "First Seen", "Last Seen")
+	for _, sub := range result.RemovedSubdomains {
+		s.row(append([]htmlCell{cell(sub.Name)}, seenHTMLCells(result.SubdomainSeen, sub.Name)...)...)
+	}
+
+	portSection := func(title string, changes []diff.PortChange) {
+		s := p.section(title, "None.", "Host", "IP", "Port", "Protocol", "Service", "First Seen", "Last Seen")
+		for _, pc := range changes {
+			s.row(append([]htmlCell{cell(pc.Host), cell(pc.IP), numCell(pc.Port.Number), cell(pc.Port.Protocol), cell(pc.Port.Service)},
+				seenHTMLCells(result.PortSeen, pc.Key())...)...)
+		}
+	}
+	portSection("New Open Ports", result.NewPorts)
+	portSection("Closed Ports", result.ClosedPorts)
+
+	vulnSection := func(title string, vulns []models.Vulnerability) {
+		s := p.section(title, "None.", "Severity", "Name", "Host", "Template ID", "First Seen", "Last Seen")
+		for _, v := range sortVulnsBySeverity(vulns) {
+			s.row(append([]htmlCell{sevCell(v.Severity), cell(v.Name), cell(v.Host), cell(v.TemplateID)},
+				seenHTMLCells(result.VulnSeen, diff.VulnKey(v))...)...)
+		}
+	}
+	vulnSection("New Vulnerabilities", result.NewVulns)
+	vulnSection("Resolved Vulnerabilities", result.ResolvedVulns)
+
+	s = p.section("Dangling DNS Changes", "None.", "Change", "Severity", "Subdomain", "CNAME Target", "Provider")
+	for _, group := range []struct {
+		change string
+		subs   []models.Subdomain
+	}{
+		{"newly dangling", result.NewlyDangling},
+		{"still dangling", result.PersistentlyDangling},
+		{"resolved", result.ResolvedDangling},
+	} {
+		for _, sub := range sortDanglingBySeverity(group.subs) {
+			a := takeover.Assess(sub)
+			sev := sevCell(a.Severity)
+			if group.change == "resolved" {
+				sev = cell("")
+			}
+			s.row(cell(group.change), sev, cell(sub.Name), cell(a.CNAME), cell(a.Risk()))
+		}
+	}
+
+	return writeHTML(p, outputPath)
+}
+
+// seenHTMLCells are the First Seen and Last Seen cells of key, "-" when the
+// diff is not dated or no earlier scan had it.
+func seenHTMLCells(seen map[string]diff.Sighting, key string) []htmlCell {
+	s, ok := seen[key]
+	if !ok {
+		return []htmlCell{cell(""), cell("")}
+	}
+	last := cell(lastSeen(s))
+	if !s.LastSeenPrevious.IsZero() {
+		last.Sort = s.LastSeenPrevious.UTC().Format(time.RFC3339)
+	}
+	return []htmlCell{dateCell(s.FirstSeen), last}
+}
+
+// danglingClass colors a count of dangling subdomains: high when there are
+// any.
+func danglingClass(n int) string {
+	if n > 0 {
+		return string(models.SeverityHigh)
+	}
+	return ""
+}
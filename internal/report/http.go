@@ -2,11 +2,15 @@ package report
 
 import (
 	"fmt"
-	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
-	"time"
 
 	"github.com/hakim/reconpipe/internal/httpprobe"
+	"github.com/hakim/reconpipe/internal/netprobe"
+	"github.com/hakim/reconpipe/internal/storage"
+	"github.com/hakim/reconpipe/pkg/models"
 )
 
 // WriteHTTPProbeReport generates a markdown report for HTTP probe results
@@ -17,8 +21,16 @@ func WriteHTTPProbeReport(result *httpprobe.HTTPProbeResult, outputPath string)
 	// Header
 	b.WriteString("# HTTP Probe Report\n\n")
 	b.WriteString(fmt.Sprintf("**Target:** %s\n", result.Target))
-	b.WriteString(fmt.Sprintf("**Date:** %s\n", time.Now().UTC().Format("2006-01-02 15:04:05")))
-	b.WriteString(fmt.Sprintf("**Live services:** %d\n\n", result.LiveCount))
+	b.WriteString(fmt.Sprintf("**Date:** %s\n", now().UTC().Format("2006-01-02 15:04:05")))
+	b.WriteString(collectedLine("Data collected", result.CollectedAt))
+	if result.Filter != "" {
+		b.WriteString(fmt.Sprintf("**Filter:** `%s` (%d targets left out)\n", result.Filter, result.FilteredOut))
+	}
+	if result.UniqueApps > 0 && result.UniqueApps != result.LiveCount {
+		b.WriteString(fmt.Sprintf("**Live services:** %d | **Unique applications:** %d\n\n", result.LiveCount, result.UniqueApps))
+	} else {
+		b.WriteString(fmt.Sprintf("**Live services:** %d\n\n", result.LiveCount))
+	}
 
 	// Live HTTP Services table
 	b.WriteString("## Live HTTP Services\n\n")
@@ -27,7 +39,14 @@ func WriteHTTPProbeReport(result *httpprobe.HTTPProbeResult, outputPath string)
 		b.WriteString("|-----|--------|-------|--------|-------------|-----|\n")
 		for _, probe := range result.Probes {
 			title := probe.Title
-			if title == "" {
+			switch {
+			case title != "":
+			case probe.GRPC:
+				// API endpoints have no page; say what answered instead
+				title = "(gRPC service)"
+			case probe.WebSocket != "":
+				title = "(WebSocket endpoint)"
+			default:
 				title = "-"
 			}
 
@@ -46,14 +65,251 @@ func WriteHTTPProbeReport(result *httpprobe.HTTPProbeResult, outputPath string)
 				cdn = probe.CDNProvider
 			}
 
-			b.WriteString(fmt.Sprintf("| %s | %d | %s | %s | %s | %s |\n",
-				probe.URL, probe.StatusCode, title, server, tech, cdn))
+			status := fmt.Sprintf("%d", probe.StatusCode)
+			if probe.RedirectsTo != "" {
+				status += " → " + probe.RedirectsTo
+			}
+
+			b.WriteString(fmt.Sprintf("| %s | %s | %s | %s | %s | %s |\n",
+				probe.URL, status, title, server, tech, cdn))
 		}
 	} else {
 		b.WriteString("No live HTTP services discovered.\n")
 	}
 	b.WriteString("\n")
 
+	// Login pages, SSO endpoints and VPN portals, most specific kind first
+	var authProbes []models.HTTPProbe
+	for _, probe := range result.Probes {
+		if probe.AuthSurface != "" {
+			authProbes = append(authProbes, probe)
+		}
+	}
+	if len(authProbes) > 0 {
+		sort.SliceStable(authProbes, func(i, j int) bool {
+			return authKindRank(authProbes[i].AuthSurface) < authKindRank(authProbes[j].AuthSurface)
+		})
+		b.WriteString("## Authentication Surfaces\n\n")
+		b.WriteString("Services that ask for credentials: VPN portals, SSO and identity provider endpoints, login pages and HTTP authentication prompts.\n\n")
+		b.WriteString("| Kind | URL | Title | Evidence |\n")
+		b.WriteString("|------|-----|-------|----------|\n")
+		for _, probe := range authProbes {
+			title := probe.Title
+			if title == "" {
+				title = "-"
+			}
+			b.WriteString(fmt.Sprintf("| %s | %s | %s | %s |\n",
+				authKindLabel(probe.AuthSurface), probe.URL, title, strings.ReplaceAll(probe.AuthEvidence, "|", "\\|")))
+		}
+		b.WriteString("\n")
+	}
+
+	// Newer protocols, only when the protocol check found any
+	var h2, h3, grpc, ws int
+	var protoRows []string
+	for _, probe := range result.Probes {
+		if probe.HTTP2 {
+			h2++
+		}
+		if probe.HTTP3 {
+			h3++
+		}
+		if probe.GRPC {
+			grpc++
+		}
+		if probe.WebSocket != "" {
+			ws++
+		}
+		if !probe.HTTP2 && !probe.HTTP3 && probe.AltSvc == "" && !probe.IsAPIEndpoint() {
+			continue
+		}
+		http2 := "-"
+		if probe.HTTP2 {
+			http2 = "yes"
+		}
+		http3 := "-"
+		if probe.HTTP3 {
+			http3 = "yes (QUIC " + strings.Join(probe.QUICVersions, ", ") + ")"
+		} else if _, ok := netprobe.HTTP3Port(probe.AltSvc, strconv.Itoa(probe.Port)); ok {
+			http3 = "advertised, no QUIC answer"
+		}
+		altSvc := "-"
+		if probe.AltSvc != "" {
+			altSvc = "`" + strings.ReplaceAll(probe.AltSvc, "|", "\\|") + "`"
+		}
+		grpcCol := "-"
+		if probe.GRPC {
+			grpcCol = "yes"
+		}
+		wsCol := "-"
+		if probe.WebSocket != "" {
+			wsCol = probe.WebSocket
+		}
+		protoRows = append(protoRows, fmt.Sprintf("| %s | %s | %s | %s | %s | %s |\n", probe.URL, http2, http3, grpcCol, wsCol, altSvc))
+	}
+	if len(protoRows) > 0 {
+		b.WriteString("## Protocol Support\n\n")
+		b.WriteString("Services that negotiate HTTP/2 or offer HTTP/3 may route, cache or parse requests differently than over HTTP/1.1; test them on each protocol. gRPC services and WebSocket endpoints need an API client rather than a browser.\n\n")
+		b.WriteString("| URL | HTTP/2 | HTTP/3 | gRPC | WebSocket | Alt-Svc |\n")
+		b.WriteString("|-----|--------|--------|------|-----------|---------|\n")
+		for _, row := range protoRows {
+			b.WriteString(row)
+		}
+		b.WriteString("\n")
+	}
+
+	// Method check results (probe.method_checks), for services that echo
+	// TRACE, allow risky methods or answer any path with a 2xx
+	var methodRows []string
+	soft404 := 0
+	for _, probe := range result.Probes {
+		risky := httpprobe.RiskyMethods(probe)
+		if !probe.TraceEnabled && len(risky) == 0 && !probe.Soft404 {
+			continue
+		}
+		trace := "-"
+		if probe.TraceEnabled {
+			trace = "yes"
+		}
+		riskyCol := "-"
+		if len(risky) > 0 {
+			riskyCol = strings.Join(risky, ", ")
+		}
+		miss := fmt.Sprintf("%d", probe.NotFoundStatus)
+		if probe.Soft404 {
+			miss = fmt.Sprintf("**%d** (soft 404, %d bytes)", probe.NotFoundStatus, probe.NotFoundLength)
+			soft404++
+		}
+		methodRows = append(methodRows, fmt.Sprintf("| %s | %s | %s | %s |\n", probe.URL, trace, riskyCol, miss))
+	}
+	if len(methodRows) > 0 {
+		b.WriteString("## HTTP Method Anomalies\n\n")
+		b.WriteString("TRACE and risky methods are also reported as findings. A soft 404 answers paths that do not exist with a 2xx: filter fuzzer and nuclei hits on it by the status and length shown.\n\n")
+		b.WriteString("| URL | TRACE | Risky Methods | Random Path |\n")
+		b.WriteString("|-----|-------|---------------|-------------|\n")
+		for _, row := range methodRows {
+			b.WriteString(row)
+		}
+		b.WriteString("\n")
+	}
+
+	// Screenshots recorded on their probes by either engine
+	var shots []string
+	for _, probe := range result.Probes {
+		if probe.ScreenshotPath != "" {
+			name := filepath.Base(probe.ScreenshotPath)
+			shots = append(shots, fmt.Sprintf("| %s | %d | [%s](%s) |\n", probe.URL, probe.StatusCode, name, storage.ScreenshotLink(name)))
+		}
+	}
+	if len(result.Triage) > 0 {
+		// The triage list covers every capture, so it replaces the plain index
+		b.WriteString("## Screenshot Triage\n\n")
+		b.WriteString("Most interesting first. Tags come from the page title and visible text.\n\n")
+		b.WriteString("| # | URL | Status | Tags | Score | Screenshot |\n")
+		b.WriteString("|---|-----|--------|------|-------|------------|\n")
+		for i, e := range result.Triage {
+			tags := "-"
+			if len(e.Tags) > 0 {
+				tags = strings.Join(e.Tags, ", ")
+			}
+			if e.TextSource == "ocr" {
+				tags += " (OCR)"
+			}
+			status := "-"
+			if e.StatusCode != 0 {
+				status = fmt.Sprintf("%d", e.StatusCode)
+			}
+			b.WriteString(fmt.Sprintf("| %d | %s | %s | %s | %d | [%s](%s) |\n",
+				i+1, e.URL, status, tags, e.Score, e.Screenshot, storage.ScreenshotLink(e.Screenshot)))
+		}
+		b.WriteString("\n")
+	} else if len(shots) > 0 {
+		b.WriteString("## Screenshots\n\n")
+		b.WriteString("| URL | Status | Screenshot |\n")
+		b.WriteString("|-----|--------|------------|\n")
+		for _, row := range shots {
+			b.WriteString(row)
+		}
+		b.WriteString("\n")
+	}
+
+	// Pages that should have a screenshot but have none
+	var failedShots []string
+	for _, probe := range result.Probes {
+		if probe.ScreenshotStatus == models.ScreenshotFailed {
+			reason := probe.ScreenshotError
+			if reason == "" {
+				reason = "-"
+			}
+			failedShots = append(failedShots, fmt.Sprintf("| %s | %d | %s |\n", probe.URL, probe.StatusCode, reason))
+		}
+	}
+	if len(failedShots) > 0 {
+		b.WriteString("## Failed Screenshots\n\n")
+		b.WriteString("These pages matched the screenshot filter but have no screenshot: the capture failed on retry too, or the file is gone. Open them in a browser.\n\n")
+		b.WriteString("| URL | Status | Reason |\n")
+		b.WriteString("|-----|--------|--------|\n")
+		for _, row := range failedShots {
+			b.WriteString(row)
+		}
+		b.WriteString("\n")
+	}
+
+	// Pages served identically on different hosts
+	if len(result.Clones) > 0 {
+		b.WriteString("## Content Clones\n\n")
+		b.WriteString("Each row is one page served on several hosts with the same content once hostnames, timestamps and tokens are masked. A copy outside production (staging, preprod, an old server) often lags on patches and access control; test it first.\n\n")
+		b.WriteString("| Title | Services | Content hash |\n")
+		b.WriteString("|-------|----------|--------------|\n")
+		for _, c := range result.Clones {
+			title := c.Title
+			if title == "" {
+				title = "-"
+			}
+			b.WriteString(fmt.Sprintf("| %s | %s | `%s` |\n", title, strings.Join(c.URLs, ", "), c.Hash[:min(12, len(c.Hash))]))
+		}
+		b.WriteString("\n")
+	}
+
+	// Origins answering for CDN-fronted names without the CDN
+	if len(result.OriginBypass) > 0 {
+		b.WriteString("## CDN-Bypassable Origins\n\n")
+		b.WriteString("These hostnames are served through a CDN, but an origin candidate (see **Origin Candidates** in `ports.md`) also serves them to anyone who connects to its IP with the right Host header. Requests sent there skip the CDN's WAF, rate limits and access rules. Restrict the origin to the CDN's address ranges.\n\n")
+		b.WriteString("| Hostname | CDN | Origin | Status | Match | Evidence |\n")
+		b.WriteString("|----------|-----|--------|--------|-------|----------|\n")
+		for _, o := range result.OriginBypass {
+			provider := o.CDNProvider
+			if provider == "" {
+				provider = "-"
+			}
+			b.WriteString(fmt.Sprintf("| %s | %s | %s | %d | %s | %s |\n",
+				o.Hostname, provider, o.OriginURL, o.StatusCode, o.Match, o.Evidence))
+		}
+		b.WriteString("\n")
+	}
+
+	// Targets that stayed silent through the retry
+	if len(result.Unreachable) > 0 {
+		b.WriteString("## Unreachable Targets\n\n")
+		b.WriteString("These open ports may serve HTTP but answered neither httpx pass (the second at reduced concurrency) nor a gRPC call. Check them by hand: a WAF or rate limit may be dropping the scanner.\n\n")
+		for _, target := range result.Unreachable {
+			b.WriteString(fmt.Sprintf("- %s\n", target))
+		}
+		b.WriteString("\n")
+	}
+
+	// Response body scan hits, only when body scanning was enabled
+	if len(result.BodyMatches) > 0 {
+		b.WriteString("## Response Body Matches\n\n")
+		b.WriteString("| Severity | Finding | URL | Matches |\n")
+		b.WriteString("|----------|---------|-----|---------|\n")
+		for _, m := range result.BodyMatches {
+			b.WriteString(fmt.Sprintf("| %s | %s | %s | `%s` |\n",
+				m.Severity, m.Name, m.URL, strings.ReplaceAll(strings.Join(m.Matches, "`, `"), "|", "\\|")))
+		}
+		b.WriteString("\n")
+	}
+
 	// Summary section
 	screenshotDisplay := "disabled"
 	if result.ScreenshotDir != "" {
@@ -62,13 +318,80 @@ func WriteHTTPProbeReport(result *httpprobe.HTTPProbeResult, outputPath string)
 
 	b.WriteString("## Summary\n\n")
 	b.WriteString(fmt.Sprintf("- **Total probes:** %d\n", len(result.Probes)))
+	if len(result.Probes) > 0 {
+		// Recomputed from the probes so older results without status_counts
+		// render the same way
+		counts := httpprobe.CountStatusClasses(result.Probes)
+		var classes []string
+		for _, class := range []string{"1xx", "2xx", "3xx", "4xx", "5xx", "error"} {
+			if counts[class] > 0 {
+				classes = append(classes, fmt.Sprintf("%s: %d", class, counts[class]))
+			}
+		}
+		b.WriteString(fmt.Sprintf("- **By status class:** %s\n", strings.Join(classes, ", ")))
+	}
 	b.WriteString(fmt.Sprintf("- **Live services:** %d\n", result.LiveCount))
+	if result.UniqueApps > 0 && result.UniqueApps != result.LiveCount {
+		b.WriteString(fmt.Sprintf("- **Unique applications:** %d (%d redirect to another service)\n",
+			result.UniqueApps, result.LiveCount-result.UniqueApps))
+	}
+	if len(protoRows) > 0 {
+		b.WriteString(fmt.Sprintf("- **HTTP/2:** %d | **HTTP/3:** %d\n", h2, h3))
+	}
+	if len(authProbes) > 0 {
+		b.WriteString(fmt.Sprintf("- **Authentication surfaces:** %d\n", len(authProbes)))
+	}
+	if len(methodRows) > 0 {
+		b.WriteString(fmt.Sprintf("- **Method anomalies:** %d (%d soft 404)\n", len(methodRows), soft404))
+	}
+	if len(result.Clones) > 0 {
+		b.WriteString(fmt.Sprintf("- **Content clones:** %d\n", len(result.Clones)))
+	}
+	if len(result.Origins) > 0 {
+		b.WriteString(fmt.Sprintf("- **Origin candidates:** %d (see ports.md)\n", len(result.Origins)))
+	}
+	if len(result.OriginBypass) > 0 {
+		b.WriteString(fmt.Sprintf("- **CDN-bypassable origins:** %d\n", len(result.OriginBypass)))
+	}
+	if len(result.Unreachable) > 0 {
+		b.WriteString(fmt.Sprintf("- **Unreachable targets:** %d\n", len(result.Unreachable)))
+	}
+	if grpc+ws > 0 {
+		b.WriteString(fmt.Sprintf("- **API endpoints:** %d gRPC | %d WebSocket\n", grpc, ws))
+	}
 	b.WriteString(fmt.Sprintf("- **Screenshots:** %s\n", screenshotDisplay))
 
 	// Write to file
-	if err := os.WriteFile(outputPath, []byte(b.String()), 0644); err != nil {
-		return fmt.Errorf("writing report to %s: %w", outputPath, err)
+	return writeFile(outputPath, b.String())
+}
+
+// authKindRank orders authentication surfaces by how much a red team cares:
+// remote access first, bare HTTP auth prompts last.
+func authKindRank(kind string) int {
+	switch kind {
+	case httpprobe.AuthVPN:
+		return 0
+	case httpprobe.AuthSSO:
+		return 1
+	case httpprobe.AuthLoginForm:
+		return 2
+	default:
+		return 3
 	}
+}
 
-	return nil
+// authKindLabel names an authentication surface kind in reports.
+func authKindLabel(kind string) string {
+	switch kind {
+	case httpprobe.AuthVPN:
+		return "VPN portal"
+	case httpprobe.AuthSSO:
+		return "SSO"
+	case httpprobe.AuthLoginForm:
+		return "Login form"
+	case httpprobe.AuthHTTP:
+		return "HTTP auth"
+	default:
+		return kind
+	}
 }
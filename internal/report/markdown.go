@@ -2,12 +2,12 @@ package report
 
 import (
 	"fmt"
-	"os"
+	"sort"
 	"strings"
-	"time"
 
 	"github.com/hakim/reconpipe/internal/discovery"
-	"github.com/hakim/reconpipe/internal/models"
+	"github.com/hakim/reconpipe/internal/takeover"
+	"github.com/hakim/reconpipe/pkg/models"
 )
 
 // WriteSubdomainReport generates a markdown report for subdomain discovery results
@@ -18,7 +18,8 @@ func WriteSubdomainReport(result *discovery.DiscoveryResult, outputPath string)
 	// Header
 	b.WriteString("# Subdomain Discovery Report\n\n")
 	b.WriteString(fmt.Sprintf("**Target:** %s\n", result.Target))
-	b.WriteString(fmt.Sprintf("**Date:** %s\n", time.Now().Format("2006-01-02 15:04:05")))
+	b.WriteString(fmt.Sprintf("**Date:** %s\n", now().Format("2006-01-02 15:04:05")))
+	b.WriteString(collectedLine("Data collected", result.CollectedAt))
 	b.WriteString(fmt.Sprintf("**Total discovered:** %d | **Unique:** %d | **Resolved:** %d | **Dangling:** %d\n\n",
 		result.TotalFound, result.UniqueCount, result.ResolvedCount, result.DanglingCount))
 
@@ -27,14 +28,51 @@ func WriteSubdomainReport(result *discovery.DiscoveryResult, outputPath string)
 	if len(result.Sources) > 0 {
 		b.WriteString("| Source | Count |\n")
 		b.WriteString("|--------|-------|\n")
-		for source, count := range result.Sources {
-			b.WriteString(fmt.Sprintf("| %s | %d |\n", source, count))
+		// Sort source names so the table is stable across runs
+		sources := make([]string, 0, len(result.Sources))
+		for source := range result.Sources {
+			sources = append(sources, source)
+		}
+		sort.Strings(sources)
+		for _, source := range sources {
+			b.WriteString(fmt.Sprintf("| %s | %d |\n", source, result.Sources[source]))
 		}
 	} else {
 		b.WriteString("None found.\n")
 	}
 	b.WriteString("\n")
 
+	// Zone transfer outcome, only when AXFR was attempted for this scan
+	if _, ok := result.Sources[discovery.ZoneTransferSource]; ok {
+		b.WriteString("## Zone Transfer (AXFR)\n\n")
+		if axfr := discovery.ZoneTransferFindings(result.Findings); len(axfr) > 0 {
+			b.WriteString("| Nameserver | Severity | Details |\n")
+			b.WriteString("|------------|----------|---------|\n")
+			for _, f := range axfr {
+				b.WriteString(fmt.Sprintf("| %s | %s | %s |\n", f.Host, f.Severity, f.Description))
+			}
+		} else {
+			b.WriteString("Refused by every authoritative nameserver.\n")
+		}
+		b.WriteString("\n")
+	}
+
+	// DNSSEC and CAA state, only when the DNS health checks ran
+	if len(result.DNSHealth) > 0 {
+		writeDNSHealthSection(&b, result)
+	}
+
+	// Names that may exist only because of wildcard DNS, only when the
+	// target has it
+	if result.Wildcard != nil {
+		writeWildcardSection(&b, result)
+	}
+
+	// Answers below the resolver quorum, only when --dns-consensus was used
+	if result.Consensus != nil {
+		writeConsensusSection(&b, result)
+	}
+
 	// Resolved subdomains
 	b.WriteString("## Resolved Subdomains\n\n")
 	resolvedSubdomains := getResolvedSubdomains(result.Subdomains)
@@ -56,11 +94,11 @@ func WriteSubdomainReport(result *discovery.DiscoveryResult, outputPath string)
 	// High priority dangling DNS (CNAME takeover candidates)
 	b.WriteString("## Dangling DNS - High Priority (Takeover Candidates)\n\n")
 	if len(highPriority) > 0 {
-		b.WriteString("| Subdomain | CNAME Target | Source |\n")
-		b.WriteString("|-----------|-------------|--------|\n")
-		for _, sub := range highPriority {
-			target := getCNAMETarget(sub.DNSRecords)
-			b.WriteString(fmt.Sprintf("| %s | %s | %s |\n", sub.Name, target, sub.Source))
+		b.WriteString("| Severity | Subdomain | CNAME Target | Provider | Source |\n")
+		b.WriteString("|----------|-----------|-------------|----------|--------|\n")
+		for _, sub := range sortDanglingBySeverity(highPriority) {
+			a := takeover.Assess(sub)
+			b.WriteString(fmt.Sprintf("| %s | %s | %s | %s | %s |\n", a.Severity, sub.Name, a.CNAME, a.Risk(), sub.Source))
 		}
 	} else {
 		b.WriteString("None found.\n")
@@ -94,12 +132,126 @@ func WriteSubdomainReport(result *discovery.DiscoveryResult, outputPath string)
 	}
 	b.WriteString("\n")
 
+	// Client-supplied subdomains missed by every passive source. Only shown
+	// when a known-subdomains list was used for this scan.
+	if _, ok := result.Sources[discovery.ProvidedSource]; ok {
+		b.WriteString("## Provided but Not Discovered\n\n")
+		if len(result.ProvidedNotDiscovered) > 0 {
+			resolved := make(map[string]bool, len(result.Subdomains))
+			for _, sub := range result.Subdomains {
+				resolved[sub.Name] = sub.Resolved
+			}
+			b.WriteString("| Subdomain | Resolves |\n")
+			b.WriteString("|-----------|----------|\n")
+			for _, name := range result.ProvidedNotDiscovered {
+				status := "no"
+				if resolved[name] {
+					status = "yes"
+				}
+				b.WriteString(fmt.Sprintf("| %s | %s |\n", name, status))
+			}
+		} else {
+			b.WriteString("None — passive sources found every provided subdomain.\n")
+		}
+		b.WriteString("\n")
+	}
+
 	// Write to file
-	if err := os.WriteFile(outputPath, []byte(b.String()), 0644); err != nil {
-		return fmt.Errorf("writing report to %s: %w", outputPath, err)
+	return writeFile(outputPath, b.String())
+}
+
+// writeDNSHealthSection writes the DNSSEC and CAA state of each checked name,
+// followed by the problems found and how to fix them.
+func writeDNSHealthSection(b *strings.Builder, result *discovery.DiscoveryResult) {
+	b.WriteString("## DNS Health (DNSSEC / CAA)\n\n")
+	b.WriteString("| Name | DNSSEC | CAA |\n")
+	b.WriteString("|------|--------|-----|\n")
+	for _, c := range result.DNSHealth {
+		dnssec := c.DNSSEC
+		caa := "none"
+		switch {
+		case c.Error != "":
+			dnssec, caa = "error", c.Error
+		case c.CAASource == c.Name:
+			caa = strings.Join(c.CAA, ", ")
+		case c.CAASource != "":
+			caa = "inherited from " + c.CAASource
+		}
+		b.WriteString(fmt.Sprintf("| %s | %s | %s |\n", c.Name, dnssec, caa))
+	}
+	b.WriteString("\n")
+
+	var issues []models.Vulnerability
+	for _, f := range result.Findings {
+		if discovery.IsDNSHealthFinding(f.TemplateID) {
+			issues = append(issues, f)
+		}
 	}
+	if len(issues) == 0 {
+		b.WriteString("No DNSSEC or CAA problems found.\n\n")
+		return
+	}
+	sort.SliceStable(issues, func(i, j int) bool {
+		return diffSeverityRank[issues[i].Severity] < diffSeverityRank[issues[j].Severity]
+	})
+	b.WriteString("### Remediation\n\n")
+	for _, f := range issues {
+		b.WriteString(fmt.Sprintf("- **%s** %s — %s: %s\n", f.Severity, f.Host, f.Name, f.Description))
+	}
+	b.WriteString("\n")
+}
+
+// writeConsensusSection writes the resolvers the names were checked against
+// and every answer too few of them returned to be used.
+func writeConsensusSection(b *strings.Builder, result *discovery.DiscoveryResult) {
+	b.WriteString("## DNS Consensus\n\n")
+	b.WriteString(fmt.Sprintf("**Resolvers:** %s | **Quorum:** %d\n\n",
+		strings.Join(result.Consensus.Resolvers, ", "), result.Consensus.Quorum))
+
+	unconfirmed := discovery.UnconfirmedSubdomains(result.Subdomains)
+	if len(unconfirmed) == 0 {
+		b.WriteString("Every answer was returned by a quorum of resolvers.\n\n")
+		return
+	}
+	b.WriteString("Answers returned by too few resolvers, not used by the scan:\n\n")
+	b.WriteString("| Subdomain | Answer | Returned by | Resolved |\n")
+	b.WriteString("|-----------|--------|-------------|----------|\n")
+	for _, sub := range unconfirmed {
+		resolved := "no"
+		if sub.Resolved {
+			resolved = "yes"
+		}
+		for _, r := range sub.Unconfirmed {
+			b.WriteString(fmt.Sprintf("| %s | %s | %s | %s |\n", sub.Name, r.Value, strings.Join(r.Resolvers, ", "), resolved))
+		}
+	}
+	b.WriteString("\n")
+}
+
+// writeWildcardSection writes the wildcard addresses of the target and the
+// subdomains that resolve only to them, flagged or dropped.
+func writeWildcardSection(b *strings.Builder, result *discovery.DiscoveryResult) {
+	b.WriteString("## Wildcard DNS\n\n")
+	b.WriteString(fmt.Sprintf("Random names under %s resolve to **%s**. Subdomains resolving only to these addresses may not exist at all.\n\n",
+		result.Target, strings.Join(result.Wildcard.IPs, ", ")))
 
-	return nil
+	flagged := discovery.WildcardSubdomains(result.Subdomains)
+	if len(flagged) == 0 && len(result.Wildcard.Filtered) == 0 {
+		b.WriteString("Every resolved subdomain has an address of its own.\n\n")
+		return
+	}
+	if len(flagged) > 0 {
+		b.WriteString("| Subdomain | Source |\n")
+		b.WriteString("|-----------|--------|\n")
+		for _, sub := range flagged {
+			b.WriteString(fmt.Sprintf("| %s | %s |\n", sub.Name, sub.Source))
+		}
+		b.WriteString("\n")
+	}
+	if len(result.Wildcard.Filtered) > 0 {
+		b.WriteString(fmt.Sprintf("Dropped by wildcard_filter (%d): %s\n\n",
+			len(result.Wildcard.Filtered), strings.Join(result.Wildcard.Filtered, ", ")))
+	}
 }
 
 // getResolvedSubdomains returns subdomains that have DNS records with IPs
@@ -113,11 +265,12 @@ func getResolvedSubdomains(subdomains []models.Subdomain) []models.Subdomain {
 	return resolved
 }
 
-// getUnresolvedSubdomains returns subdomains with no DNS records at all
+// getUnresolvedSubdomains returns subdomains with neither address nor CNAME
+// records (MX and TXT records, which dnsx also collects, do not count)
 func getUnresolvedSubdomains(subdomains []models.Subdomain) []models.Subdomain {
 	var unresolved []models.Subdomain
 	for _, sub := range subdomains {
-		if len(sub.DNSRecords) == 0 {
+		if !hasIPRecords(sub.DNSRecords) && getCNAMETarget(sub.DNSRecords) == "-" {
 			unresolved = append(unresolved, sub)
 		}
 	}
@@ -0,0 +1,77 @@
+package report
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hakim/reconpipe/internal/notes"
+	"github.com/hakim/reconpipe/pkg/models"
+)
+
+// WriteNotesReport generates a markdown report of the analyst notes attached
+// to a scan's findings and assets, oldest first, and writes it to outputPath.
+func WriteNotesReport(sn *notes.ScanNotes, outputPath string) error {
+	var b strings.Builder
+
+	b.WriteString("# Analyst Notes Report\n\n")
+	b.WriteString(fmt.Sprintf("**Target:** %s\n", sn.Target))
+	b.WriteString(fmt.Sprintf("**Date:** %s\n\n", now().UTC().Format("2006-01-02 15:04:05 UTC")))
+
+	var findings, assets []notes.AttachedNote
+	for _, n := range sn.Notes {
+		if n.Subject == models.NoteFinding {
+			findings = append(findings, n)
+		} else {
+			assets = append(assets, n)
+		}
+	}
+
+	// Summary section
+	b.WriteString("## Summary\n\n")
+	b.WriteString(fmt.Sprintf("- **Notes:** %d\n", len(sn.Notes)))
+	b.WriteString(fmt.Sprintf("- **On findings:** %d\n", len(findings)))
+	b.WriteString(fmt.Sprintf("- **On assets:** %d\n\n", len(assets)))
+
+	if len(sn.Notes) == 0 {
+		b.WriteString("No notes apply to this scan. Add one with `reconpipe note add`.\n")
+		return writeFile(outputPath, b.String())
+	}
+
+	if len(findings) > 0 {
+		b.WriteString("## Finding Notes\n\n")
+		b.WriteString("| Added | Severity | Finding | Key | Note |\n")
+		b.WriteString("|-------|----------|---------|-----|------|\n")
+		for _, n := range findings {
+			b.WriteString(fmt.Sprintf("| %s | %s | %s | `%s` | %s |\n",
+				noteAdded(n.Note), n.Severity, n.Name, n.Key, noteCell(n.Text)))
+		}
+		b.WriteString("\n")
+	}
+
+	if len(assets) > 0 {
+		b.WriteString("## Asset Notes\n\n")
+		b.WriteString("| Added | Asset | Note |\n")
+		b.WriteString("|-------|-------|------|\n")
+		for _, n := range assets {
+			b.WriteString(fmt.Sprintf("| %s | %s | %s |\n", noteAdded(n.Note), n.Key, noteCell(n.Text)))
+		}
+		b.WriteString("\n")
+	}
+
+	return writeFile(outputPath, b.String())
+}
+
+// noteAdded renders when and by whom a note was added.
+func noteAdded(n models.Note) string {
+	added := n.CreatedAt.UTC().Format("2006-01-02")
+	if n.Author != "" {
+		added += " (" + n.Author + ")"
+	}
+	return added
+}
+
+// noteCell makes note text fit a table cell: one line, pipes escaped.
+func noteCell(text string) string {
+	text = strings.ReplaceAll(text, "|", "\\|")
+	return strings.Join(strings.Fields(text), " ")
+}
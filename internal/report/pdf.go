@@ -0,0 +1,18 @@
+package report
+
+import (
+	"fmt"
+
+	"github.com/hakim/reconpipe/internal/report/pdf"
+	"github.com/hakim/reconpipe/internal/vulnscan"
+)
+
+// WriteVulnPDFReport renders the vulnerability report as a PDF titled title
+// and writes it to the specified output path.
+func WriteVulnPDFReport(result *vulnscan.VulnScanResult, title, outputPath string) error {
+	data, err := pdf.VulnReport(result, title, now())
+	if err != nil {
+		return fmt.Errorf("generating %s: %w", outputPath, err)
+	}
+	return writeFile(outputPath, string(data))
+}
@@ -0,0 +1,287 @@
+// Package pdf renders the vulnerability report as a PDF in pure Go, so
+// scans can always produce vulns.pdf without python3 or Nuc-pdf installed.
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hakim/reconpipe/internal/vulnscan"
+	"github.com/hakim/reconpipe/pkg/models"
+	"github.com/jung-kurt/gofpdf"
+)
+
+// severityOrder is the order severities are summarised and listed in.
+var severityOrder = []models.Severity{
+	models.SeverityCritical,
+	models.SeverityHigh,
+	models.SeverityMedium,
+	models.SeverityLow,
+	models.SeverityInfo,
+}
+
+// rgb is a fill or text color.
+type rgb struct{ r, g, b int }
+
+// severityColors match the HTML reports.
+var severityColors = map[models.Severity]rgb{
+	models.SeverityCritical: {0x8b, 0x00, 0x00},
+	models.SeverityHigh:     {0xd1, 0x24, 0x2f},
+	models.SeverityMedium:   {0xbc, 0x4c, 0x00},
+	models.SeverityLow:      {0x09, 0x69, 0xda},
+	models.SeverityInfo:     {0x65, 0x6d, 0x76},
+}
+
+var (
+	textColor   = rgb{0x1f, 0x23, 0x28}
+	mutedColor  = rgb{0x65, 0x6d, 0x76}
+	borderColor = rgb{0xd0, 0xd7, 0xde}
+	shadeColor  = rgb{0xf6, 0xf8, 0xfa}
+	noticeColor = rgb{0xff, 0xf8, 0xc5}
+)
+
+const (
+	font       = "Helvetica"
+	margin     = 15.0 // mm
+	lineHeight = 5.0  // mm
+	labelWidth = 28.0 // mm, of the field names in a finding
+)
+
+// doc wraps the PDF being built with the UTF-8 to cp1252 translation the
+// core fonts need.
+type doc struct {
+	*gofpdf.Fpdf
+	tr func(string) string
+}
+
+// VulnReport renders the vulnerability scan result as a PDF titled title and
+// dated date, and returns the document.
+func VulnReport(result *vulnscan.VulnScanResult, title string, date time.Time) ([]byte, error) {
+	f := gofpdf.New("P", "mm", "A4", "")
+	d := &doc{Fpdf: f, tr: f.UnicodeTranslatorFromDescriptor("")}
+	f.SetMargins(margin, margin, margin)
+	f.SetAutoPageBreak(true, margin)
+	f.SetTitle(title, true)
+	f.SetCreator("reconpipe", true)
+	f.SetCreationDate(date)
+	f.SetCatalogSort(true)
+	f.AliasNbPages("")
+	f.SetFooterFunc(func() {
+		f.SetY(-margin + 3)
+		d.font("", 8, mutedColor)
+		f.CellFormat((d.pageWidth()-2*margin)/2, lineHeight, d.tr(title), "", 0, "L", false, 0, "")
+		f.CellFormat(0, lineHeight, fmt.Sprintf("Page %d of {nb}", f.PageNo()), "", 0, "R", false, 0, "")
+	})
+	f.AddPage()
+
+	// Title block
+	d.font("B", 20, textColor)
+	f.MultiCell(0, 9, d.tr(title), "", "L", false)
+	d.font("", 10, mutedColor)
+	d.line(fmt.Sprintf("Target: %s", result.Target))
+	d.line(fmt.Sprintf("Date: %s", date.UTC().Format("2006-01-02 15:04:05 UTC")))
+	if !result.CollectedAt.IsZero() {
+		d.line(fmt.Sprintf("Data collected: %s", result.CollectedAt.UTC().Format("2006-01-02 15:04:05 UTC")))
+	}
+	f.Ln(4)
+
+	if result.Filter != "" {
+		d.notice(fmt.Sprintf("Filtered scan: only targets matching %s were scanned; %d were left out.", result.Filter, result.FilteredOut))
+	}
+	if result.Partial {
+		d.notice(partialNotice(result))
+	}
+
+	var actionable, noise []models.Vulnerability
+	for _, v := range result.Vulnerabilities {
+		if v.Noise {
+			noise = append(noise, v)
+		} else {
+			actionable = append(actionable, v)
+		}
+	}
+
+	d.heading("Summary")
+	d.summary(result)
+
+	d.heading("Findings")
+	if len(actionable) == 0 {
+		d.font("I", 10, mutedColor)
+		d.line("No findings.")
+	}
+	for i, v := range sortBySeverity(actionable) {
+		d.finding(i+1, v)
+	}
+
+	if len(noise) > 0 {
+		d.heading("Informational Noise")
+		d.font("", 10, mutedColor)
+		f.MultiCell(0, lineHeight, "Findings a noise rule matched. They are counted above but are rarely worth acting on.", "", "L", false)
+		f.Ln(2)
+		for _, v := range sortBySeverity(noise) {
+			d.font("", 9, textColor)
+			f.MultiCell(0, lineHeight, d.tr(fmt.Sprintf("%s  %s  (%s, %s)", strings.ToUpper(string(v.Severity)), v.Name, v.Host, v.TemplateID)), "", "L", false)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := f.Output(&buf); err != nil {
+		return nil, fmt.Errorf("rendering PDF: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// partialNotice says what a scan cut short by the pipeline deadline left out.
+func partialNotice(result *vulnscan.VulnScanResult) string {
+	var missed []string
+	if n := len(result.SkippedTargets); n > 0 {
+		missed = append(missed, fmt.Sprintf("%d targets were not scanned", n))
+	}
+	if result.NarrowedSeverity != "" {
+		missed = append(missed, "later targets were only checked for "+result.NarrowedSeverity)
+	}
+	if len(missed) == 0 {
+		missed = append(missed, "some nuclei runs were stopped before they finished")
+	}
+	return fmt.Sprintf("Partial scan: the pipeline deadline cut this scan short; %s.", strings.Join(missed, ", "))
+}
+
+// summary draws the table of finding counts per severity.
+func (d *doc) summary(result *vulnscan.VulnScanResult) {
+	const sevWidth, countWidth, rowHeight = 40.0, 25.0, 7.0
+	d.setDraw(borderColor)
+	d.setFill(shadeColor)
+	d.font("B", 10, textColor)
+	d.CellFormat(sevWidth, rowHeight, "Severity", "1", 0, "L", true, 0, "")
+	d.CellFormat(countWidth, rowHeight, "Findings", "1", 1, "R", true, 0, "")
+	for _, sev := range severityOrder {
+		d.setFill(severityColors[sev])
+		d.font("B", 9, rgb{0xff, 0xff, 0xff})
+		d.CellFormat(sevWidth, rowHeight, strings.ToUpper(string(sev)), "1", 0, "L", true, 0, "")
+		d.font("", 10, textColor)
+		d.CellFormat(countWidth, rowHeight, fmt.Sprint(result.SeverityCounts[string(sev)]), "1", 1, "R", false, 0, "")
+	}
+	d.font("B", 10, textColor)
+	d.CellFormat(sevWidth, rowHeight, "Total", "1", 0, "L", false, 0, "")
+	d.CellFormat(countWidth, rowHeight, fmt.Sprint(result.TotalCount), "1", 1, "R", false, 0, "")
+}
+
+// finding draws one finding: a severity badge and its name, then its fields.
+func (d *doc) finding(n int, v models.Vulnerability) {
+	// Keep the heading of a finding on the page with its first fields
+	_, pageHeight := d.GetPageSize()
+	if d.GetY() > pageHeight-margin-30 {
+		d.AddPage()
+	}
+	d.Ln(3)
+
+	badge := strings.ToUpper(string(v.Severity))
+	d.font("B", 8, rgb{0xff, 0xff, 0xff})
+	color, ok := severityColors[v.Severity]
+	if !ok {
+		color = mutedColor
+	}
+	d.setFill(color)
+	d.CellFormat(d.GetStringWidth(badge)+4, 6, badge, "", 0, "C", true, 0, "")
+	d.SetX(d.GetX() + 2)
+	d.font("B", 11, textColor)
+	d.MultiCell(0, 6, d.tr(fmt.Sprintf("%d. %s", n, v.Name)), "", "L", false)
+	d.Ln(1)
+
+	d.field("Host", v.Host)
+	d.field("Matched at", v.MatchedAt)
+	d.field("Template", v.TemplateID)
+	if v.OriginalSeverity != "" {
+		d.field("Raised as", string(v.OriginalSeverity))
+	}
+	d.field("Tags", strings.Join(v.Tags, ", "))
+	if len(v.Compliance) > 0 {
+		frameworks := make([]string, 0, len(v.Compliance))
+		for fw := range v.Compliance {
+			frameworks = append(frameworks, fw)
+		}
+		sort.Strings(frameworks)
+		var refs []string
+		for _, fw := range frameworks {
+			refs = append(refs, fmt.Sprintf("%s %s", fw, strings.Join(v.Compliance[fw], ", ")))
+		}
+		d.field("Compliance", strings.Join(refs, "; "))
+	}
+	d.field("Description", v.Description)
+
+	d.setDraw(borderColor)
+	y := d.GetY() + 1
+	d.Line(margin, y, d.pageWidth()-margin, y)
+	d.SetY(y)
+}
+
+// field draws a labelled field of a finding; empty values are left out.
+func (d *doc) field(label, value string) {
+	if value == "" {
+		return
+	}
+	d.font("B", 9, mutedColor)
+	d.CellFormat(labelWidth, lineHeight, label, "", 0, "L", false, 0, "")
+	d.font("", 9, textColor)
+	d.MultiCell(0, lineHeight, d.tr(value), "", "L", false)
+}
+
+// heading starts a report section.
+func (d *doc) heading(text string) {
+	d.Ln(4)
+	d.font("B", 14, textColor)
+	d.CellFormat(0, 8, d.tr(text), "B", 1, "L", false, 0, "")
+	d.Ln(2)
+}
+
+// notice draws a highlighted paragraph.
+func (d *doc) notice(text string) {
+	d.setFill(noticeColor)
+	d.setDraw(rgb{0xd4, 0xa7, 0x2c})
+	d.font("", 10, textColor)
+	d.MultiCell(0, lineHeight+1, d.tr(text), "1", "L", true)
+	d.Ln(2)
+}
+
+// line writes a single line of text in the current font.
+func (d *doc) line(text string) {
+	d.CellFormat(0, lineHeight, d.tr(text), "", 1, "L", false, 0, "")
+}
+
+// font sets the font style, size and text color.
+func (d *doc) font(style string, size float64, c rgb) {
+	d.SetFont(font, style, size)
+	d.SetTextColor(c.r, c.g, c.b)
+}
+
+func (d *doc) setFill(c rgb) { d.SetFillColor(c.r, c.g, c.b) }
+func (d *doc) setDraw(c rgb) { d.SetDrawColor(c.r, c.g, c.b) }
+
+func (d *doc) pageWidth() float64 {
+	w, _ := d.GetPageSize()
+	return w
+}
+
+// sortBySeverity returns vulns ordered most severe first, keeping the scan
+// order within a severity.
+func sortBySeverity(vulns []models.Vulnerability) []models.Vulnerability {
+	rank := make(map[models.Severity]int, len(severityOrder))
+	for i, sev := range severityOrder {
+		rank[sev] = i
+	}
+	rankOf := func(sev models.Severity) int {
+		if r, ok := rank[sev]; ok {
+			return r
+		}
+		return len(severityOrder)
+	}
+	sorted := make([]models.Vulnerability, len(vulns))
+	copy(sorted, vulns)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return rankOf(sorted[i].Severity) < rankOf(sorted[j].Severity)
+	})
+	return sorted
+}
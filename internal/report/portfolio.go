@@ -0,0 +1,89 @@
+package report
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hakim/reconpipe/internal/stats"
+	"github.com/hakim/reconpipe/pkg/models"
+)
+
+// WritePortfolioReport generates a markdown report summarizing the latest
+// scan of every target in p and writes it to outputPath. top caps the worst
+// targets table; zero lists every target.
+func WritePortfolioReport(p *stats.Portfolio, top int, outputPath string) error {
+	var b strings.Builder
+
+	b.WriteString("# Portfolio Report\n\n")
+	b.WriteString(fmt.Sprintf("**Targets:** %d\n", len(p.Targets)))
+	b.WriteString(fmt.Sprintf("**New since:** %s\n", p.Since.UTC().Format("2006-01-02")))
+	b.WriteString(fmt.Sprintf("**Date:** %s\n\n", now().UTC().Format("2006-01-02 15:04:05")))
+
+	// Summary section
+	b.WriteString("## Summary\n\n")
+	b.WriteString(fmt.Sprintf("- **Assets:** %d (%d subdomains, %d hosts)\n", p.Assets, p.Subdomains, p.Hosts))
+	b.WriteString(fmt.Sprintf("- **Open ports:** %d\n", p.OpenPorts))
+	b.WriteString(fmt.Sprintf("- **Web services:** %d\n", p.WebServices))
+	b.WriteString(fmt.Sprintf("- **Findings:** %s\n", severityLine(p.Severities)))
+	b.WriteString(fmt.Sprintf("- **New assets:** %d across existing targets, plus %d new targets\n\n", len(p.NewAssets), len(p.NewTargets)))
+
+	// Worst targets, already ordered by the severity of their findings
+	b.WriteString("## Worst Targets\n\n")
+	targets := p.Targets
+	if top > 0 && len(targets) > top {
+		targets = targets[:top]
+		b.WriteString(fmt.Sprintf("The %d targets with the most severe findings, of %d.\n\n", top, len(p.Targets)))
+	}
+	if len(targets) == 0 {
+		b.WriteString("No scanned targets.\n\n")
+	} else {
+		b.WriteString("| Target | Critical | High | Medium | Low | Subdomains | Hosts | Open Ports | New Assets | Latest Scan |\n")
+		b.WriteString("|--------|----------|------|--------|-----|------------|-------|------------|------------|-------------|\n")
+		for _, t := range targets {
+			b.WriteString(fmt.Sprintf("| %s | %d | %d | %d | %d | %d | %d | %d | %d | %s |\n",
+				t.Target, t.Severities[models.SeverityCritical], t.Severities[models.SeverityHigh],
+				t.Severities[models.SeverityMedium], t.Severities[models.SeverityLow],
+				t.Subdomains, t.Hosts, t.OpenPorts, t.NewAssets, t.ScanTime.UTC().Format("2006-01-02")))
+		}
+		b.WriteString("\n")
+	}
+
+	// New assets on targets that were already being scanned
+	b.WriteString("## New Assets\n\n")
+	if len(p.NewAssets) == 0 {
+		b.WriteString("No new subdomains or open ports on previously scanned targets.\n\n")
+	} else {
+		b.WriteString("| Target | Kind | Asset |\n")
+		b.WriteString("|--------|------|-------|\n")
+		for _, a := range p.NewAssets {
+			b.WriteString(fmt.Sprintf("| %s | %s | %s |\n", a.Target, a.Kind, a.Name))
+		}
+		b.WriteString("\n")
+	}
+
+	if len(p.NewTargets) > 0 {
+		b.WriteString("## New Targets\n\n")
+		b.WriteString("First scanned after the cut-off; every asset they have is new.\n\n")
+		for _, t := range p.NewTargets {
+			b.WriteString(fmt.Sprintf("- %s\n", t))
+		}
+		b.WriteString("\n")
+	}
+
+	return writeFile(outputPath, b.String())
+}
+
+// severityLine renders a severity distribution as "critical: 1, high: 3, ...",
+// most severe first, or "none".
+func severityLine(counts map[models.Severity]int) string {
+	var parts []string
+	for _, sev := range stats.Severities {
+		if counts[sev] > 0 {
+			parts = append(parts, fmt.Sprintf("%s: %d", sev, counts[sev]))
+		}
+	}
+	if len(parts) == 0 {
+		return "none"
+	}
+	return strings.Join(parts, ", ")
+}
@@ -2,12 +2,12 @@ package report
 
 import (
 	"fmt"
-	"os"
+	"sort"
 	"strings"
-	"time"
 
-	"github.com/hakim/reconpipe/internal/models"
+	"github.com/hakim/reconpipe/internal/netprobe"
 	"github.com/hakim/reconpipe/internal/portscan"
+	"github.com/hakim/reconpipe/pkg/models"
 )
 
 // WritePortReport generates a markdown report for port scan results
@@ -18,9 +18,23 @@ func WritePortReport(result *portscan.PortScanResult, outputPath string) error {
 	// Header
 	b.WriteString("# Port Scan Report\n\n")
 	b.WriteString(fmt.Sprintf("**Target:** %s\n", result.Target))
-	b.WriteString(fmt.Sprintf("**Date:** %s\n", time.Now().Format("2006-01-02 15:04:05")))
+	b.WriteString(fmt.Sprintf("**Date:** %s\n", now().Format("2006-01-02 15:04:05")))
+	b.WriteString(collectedLine("Data collected", result.CollectedAt))
 	b.WriteString(fmt.Sprintf("**Total hosts:** %d | **CDN filtered:** %d | **Scanned:** %d | **Open ports:** %d\n\n",
 		len(result.Hosts), result.CDNCount, result.ScannedCount, result.TotalPorts))
+	if result.PortSelection != "" {
+		b.WriteString(fmt.Sprintf("**Ports scanned:** %s (not the full range; ports outside it were not checked)\n\n", result.PortSelection))
+	}
+	if len(result.UDPPorts) > 0 {
+		udp := make([]string, len(result.UDPPorts))
+		for i, port := range result.UDPPorts {
+			udp[i] = fmt.Sprint(port)
+		}
+		b.WriteString(fmt.Sprintf("**UDP ports scanned:** %s\n\n", strings.Join(udp, ", ")))
+	}
+	if result.ReusedCount > 0 {
+		b.WriteString(fmt.Sprintf("**Reused from other targets of this run:** %d IPs (ports scanned once for every target that resolves to them)\n\n", result.ReusedCount))
+	}
 
 	// CDN Filtered Hosts section
 	b.WriteString("## CDN Filtered Hosts\n\n")
@@ -40,6 +54,34 @@ func WritePortReport(result *portscan.PortScanResult, outputPath string) error {
 	}
 	b.WriteString("\n")
 
+	// Origin Candidates section, once the probe stage has looked for them
+	if rows := originRows(cdnHosts); len(rows) > 0 {
+		b.WriteString("## Origin Candidates\n\n")
+		b.WriteString("Servers that may be the origin behind a CDN-fronted hostname. Verified origins served the hostname's CDN content when asked for it directly, so the CDN can be bypassed.\n\n")
+		b.WriteString("| Hostname | Origin IP | Confidence | Techniques | Verified | Evidence |\n")
+		b.WriteString("|----------|-----------|------------|------------|----------|----------|\n")
+		for _, row := range rows {
+			b.WriteString(row)
+		}
+		b.WriteString("\n")
+	}
+
+	// Excluded Hosts section, only when the exclusions list matched anything
+	if excluded := getExcludedHosts(result.Hosts); len(excluded) > 0 {
+		b.WriteString("## Excluded Hosts\n\n")
+		b.WriteString("On the exclusions list (`exclude_file`); not port scanned, probed or vulnerability scanned.\n\n")
+		b.WriteString("| IP | Subdomains |\n")
+		b.WriteString("|----|------------|\n")
+		for _, host := range excluded {
+			subdomains := strings.Join(host.Subdomains, ", ")
+			if subdomains == "" {
+				subdomains = "-"
+			}
+			b.WriteString(fmt.Sprintf("| %s | %s |\n", host.IP, subdomains))
+		}
+		b.WriteString("\n")
+	}
+
 	// Open Ports by Host section
 	b.WriteString("## Open Ports by Host\n\n")
 	hostsWithPorts := getNonCDNHosts(result.Hosts)
@@ -51,6 +93,15 @@ func WritePortReport(result *portscan.PortScanResult, outputPath string) error {
 				subdomains = "unknown"
 			}
 			b.WriteString(fmt.Sprintf("### %s (%s)\n\n", host.IP, subdomains))
+			if host.Geo != nil {
+				b.WriteString(fmt.Sprintf("**Location:** %s\n\n", host.Geo))
+			}
+			if len(host.SharedWith) > 0 {
+				b.WriteString(fmt.Sprintf("**Shared with:** %s\n\n", strings.Join(host.SharedWith, ", ")))
+			}
+			if host.ScannedFor != "" {
+				b.WriteString(fmt.Sprintf("**Ports from the scan of:** %s\n\n", host.ScannedFor))
+			}
 
 			if len(host.Ports) > 0 {
 				b.WriteString("| Port | Protocol | State | Service | Version |\n")
@@ -76,6 +127,37 @@ func WritePortReport(result *portscan.PortScanResult, outputPath string) error {
 		b.WriteString("No hosts with open ports found.\n\n")
 	}
 
+	// Mail services section, only when mail checks ran against something
+	if len(result.MailChecks) > 0 {
+		b.WriteString("## Mail Services\n\n")
+		b.WriteString("| Host | Port | Protocol | TLS | Certificate | Open Relay |\n")
+		b.WriteString("|------|------|----------|-----|-------------|------------|\n")
+		for _, c := range result.MailChecks {
+			host := c.IP
+			if c.Hostname != "" {
+				host = fmt.Sprintf("%s (%s)", c.IP, c.Hostname)
+			}
+			if c.Error != "" && c.Banner == "" {
+				b.WriteString(fmt.Sprintf("| %s | %d | %s | - | - | - (%s) |\n", host, c.Port, c.Protocol, c.Error))
+				continue
+			}
+			b.WriteString(fmt.Sprintf("| %s | %d | %s | %s | %s | %s |\n",
+				host, c.Port, c.Protocol, mailTLSStatus(c), mailCertStatus(c), mailRelayStatus(c)))
+		}
+		b.WriteString("\n")
+	}
+
+	// Hosting locations section, only when GeoIP enrichment ran
+	if locations := hostLocations(result.Hosts); len(locations) > 0 {
+		b.WriteString("## Hosting Locations\n\n")
+		b.WriteString("| Country | Hosts | IPs |\n")
+		b.WriteString("|---------|-------|-----|\n")
+		for _, loc := range locations {
+			b.WriteString(fmt.Sprintf("| %s | %d | %s |\n", loc.country, len(loc.ips), strings.Join(loc.ips, ", ")))
+		}
+		b.WriteString("\n")
+	}
+
 	// Summary section
 	b.WriteString("## Summary\n\n")
 	b.WriteString(fmt.Sprintf("- **Total IPs checked:** %d\n", len(result.Hosts)))
@@ -85,11 +167,117 @@ func WritePortReport(result *portscan.PortScanResult, outputPath string) error {
 	b.WriteString(fmt.Sprintf("- **Total unique ports found:** %d\n", result.TotalPorts))
 
 	// Write to file
-	if err := os.WriteFile(outputPath, []byte(b.String()), 0644); err != nil {
-		return fmt.Errorf("writing report to %s: %w", outputPath, err)
+	return writeFile(outputPath, b.String())
+}
+
+// mailTLSStatus describes how (and whether) a mail service offers TLS
+func mailTLSStatus(c netprobe.MailCheck) string {
+	var status string
+	switch {
+	case c.ImplicitTLS:
+		status = "implicit"
+	case c.STARTTLS:
+		status = "STARTTLS"
+	default:
+		return "**none**"
+	}
+	if c.TLSVersion != "" {
+		status += " (" + c.TLSVersion + ")"
 	}
+	return status
+}
 
-	return nil
+// mailCertStatus summarises certificate validation for a mail service
+func mailCertStatus(c netprobe.MailCheck) string {
+	switch {
+	case c.Cert == nil:
+		return "-"
+	case c.CertError != "":
+		return "**invalid:** " + c.CertError
+	default:
+		return "valid, expires " + strings.SplitN(c.Cert.NotAfter, "T", 2)[0]
+	}
+}
+
+// mailRelayStatus reports the open-relay test outcome
+func mailRelayStatus(c netprobe.MailCheck) string {
+	switch {
+	case !c.RelayTested:
+		return "-"
+	case c.OpenRelay:
+		return "**YES**"
+	default:
+		return "no"
+	}
+}
+
+// hostLocation groups the hosts GeoIP placed in one country.
+type hostLocation struct {
+	country string
+	ips     []string
+}
+
+// hostLocations groups hosts by country, most hosts first, with hosts the
+// database could not place last as "Unknown". It returns nil when no host
+// has a location, i.e. GeoIP enrichment is off.
+func hostLocations(hosts []models.Host) []hostLocation {
+	byCountry := make(map[string]*hostLocation)
+	var order []*hostLocation
+	var unknown []string
+	for _, host := range hosts {
+		if host.Geo == nil {
+			unknown = append(unknown, host.IP)
+			continue
+		}
+		country := host.Geo.CountryLabel()
+		loc, ok := byCountry[country]
+		if !ok {
+			loc = &hostLocation{country: country}
+			byCountry[country] = loc
+			order = append(order, loc)
+		}
+		loc.ips = append(loc.ips, host.IP)
+	}
+	if len(order) == 0 {
+		return nil
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		if len(order[i].ips) != len(order[j].ips) {
+			return len(order[i].ips) > len(order[j].ips)
+		}
+		return order[i].country < order[j].country
+	})
+	out := make([]hostLocation, 0, len(order)+1)
+	for _, loc := range order {
+		out = append(out, *loc)
+	}
+	if len(unknown) > 0 {
+		out = append(out, hostLocation{country: "Unknown", ips: unknown})
+	}
+	return out
+}
+
+// originRows renders the origin candidates of hosts as table rows, one per
+// hostname and IP.
+func originRows(hosts []models.Host) []string {
+	var rows []string
+	seen := make(map[string]bool)
+	for _, host := range hosts {
+		for _, c := range host.Origins {
+			if seen[c.Hostname+"|"+c.IP] {
+				continue
+			}
+			seen[c.Hostname+"|"+c.IP] = true
+			verified := "no"
+			if c.Verified {
+				verified = "yes"
+			}
+			rows = append(rows, fmt.Sprintf("| %s | %s | %s | %s | %s | %s |\n",
+				c.Hostname, c.IP, c.Confidence, strings.Join(c.Techniques, ", "), verified,
+				strings.ReplaceAll(strings.Join(c.Evidence, "; "), "|", "\\|")))
+		}
+	}
+	return rows
 }
 
 // getCDNHosts returns hosts that are classified as CDN
@@ -103,11 +291,23 @@ func getCDNHosts(hosts []models.Host) []models.Host {
 	return cdnHosts
 }
 
-// getNonCDNHosts returns non-CDN hosts
+// getExcludedHosts returns hosts skipped because of the exclusions list,
+// CDN or not
+func getExcludedHosts(hosts []models.Host) []models.Host {
+	var excluded []models.Host
+	for _, host := range hosts {
+		if host.Excluded {
+			excluded = append(excluded, host)
+		}
+	}
+	return excluded
+}
+
+// getNonCDNHosts returns non-CDN hosts that were scanned
 func getNonCDNHosts(hosts []models.Host) []models.Host {
 	var nonCDNHosts []models.Host
 	for _, host := range hosts {
-		if !host.IsCDN {
+		if !host.IsCDN && !host.Excluded {
 			nonCDNHosts = append(nonCDNHosts, host)
 		}
 	}
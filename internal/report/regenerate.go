@@ -0,0 +1,281 @@
+package report
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/hakim/reconpipe/internal/diff"
+	"github.com/hakim/reconpipe/internal/discovery"
+	"github.com/hakim/reconpipe/internal/httpprobe"
+	"github.com/hakim/reconpipe/internal/notes"
+	"github.com/hakim/reconpipe/internal/portscan"
+	"github.com/hakim/reconpipe/internal/storage"
+	"github.com/hakim/reconpipe/internal/vulnscan"
+	"github.com/hakim/reconpipe/pkg/models"
+)
+
+// now is the clock used for report date stamps. It is swapped out by
+// SetClock so regenerated reports can be compared byte-for-byte.
+var now = time.Now
+
+// SetClock overrides the clock used for report date stamps. Passing nil
+// restores time.Now.
+func SetClock(fn func() time.Time) {
+	if fn == nil {
+		fn = time.Now
+	}
+	now = fn
+}
+
+// rawReport is a report rebuilt from raw JSON. inputs are the raw files it
+// reads; it is only built when the first exists.
+type rawReport struct {
+	name   string
+	inputs []string
+	write  func(rawDir, path string) error
+}
+
+// rawReports lists every report RegenerateReports rebuilds, in order.
+var rawReports = []rawReport{
+	{"subdomains.md", []string{"subdomains.json"}, func(rawDir, path string) error {
+		var r discovery.DiscoveryResult
+		if err := readRequired(filepath.Join(rawDir, "subdomains.json"), &r); err != nil {
+			return err
+		}
+		return WriteSubdomainReport(&r, path)
+	}},
+	{"subdomains.html", []string{"subdomains.json"}, func(rawDir, path string) error {
+		var r discovery.DiscoveryResult
+		if err := readRequired(filepath.Join(rawDir, "subdomains.json"), &r); err != nil {
+			return err
+		}
+		return WriteSubdomainHTMLReport(&r, path)
+	}},
+	{"dangling-dns.md", []string{"subdomains.json"}, func(rawDir, path string) error {
+		var r discovery.DiscoveryResult
+		if err := readRequired(filepath.Join(rawDir, "subdomains.json"), &r); err != nil {
+			return err
+		}
+		return WriteDanglingDNSReport(r.Subdomains, r.CollectedAt, path)
+	}},
+	{"ports.md", []string{"ports.json"}, func(rawDir, path string) error {
+		var r portscan.PortScanResult
+		if err := readRequired(filepath.Join(rawDir, "ports.json"), &r); err != nil {
+			return err
+		}
+		return WritePortReport(&r, path)
+	}},
+	{"ports.html", []string{"ports.json"}, func(rawDir, path string) error {
+		var r portscan.PortScanResult
+		if err := readRequired(filepath.Join(rawDir, "ports.json"), &r); err != nil {
+			return err
+		}
+		return WritePortHTMLReport(&r, path)
+	}},
+	{"http-probes.md", []string{"http-probes.json"}, func(rawDir, path string) error {
+		var r httpprobe.HTTPProbeResult
+		if err := readRequired(filepath.Join(rawDir, "http-probes.json"), &r); err != nil {
+			return err
+		}
+		return WriteHTTPProbeReport(&r, path)
+	}},
+	{"http-probes.html", []string{"http-probes.json"}, func(rawDir, path string) error {
+		var r httpprobe.HTTPProbeResult
+		if err := readRequired(filepath.Join(rawDir, "http-probes.json"), &r); err != nil {
+			return err
+		}
+		return WriteHTTPProbeHTMLReport(&r, path)
+	}},
+	// The exposure matrix joins the port scan with the probes, if any
+	{"exposure.md", []string{"ports.json", "http-probes.json"}, func(rawDir, path string) error {
+		var ports portscan.PortScanResult
+		if err := readRequired(filepath.Join(rawDir, "ports.json"), &ports); err != nil {
+			return err
+		}
+		var probes httpprobe.HTTPProbeResult
+		found, err := readRaw(filepath.Join(rawDir, "http-probes.json"), &probes)
+		if err != nil {
+			return err
+		}
+		if !found {
+			return WriteExposureReport(&ports, nil, path)
+		}
+		return WriteExposureReport(&ports, &probes, path)
+	}},
+	{"vulns.md", []string{"vulns.json"}, func(rawDir, path string) error {
+		var r vulnscan.VulnScanResult
+		if err := readRequired(filepath.Join(rawDir, "vulns.json"), &r); err != nil {
+			return err
+		}
+		return WriteVulnReport(&r, path)
+	}},
+	{"vulns.html", []string{"vulns.json"}, func(rawDir, path string) error {
+		var r vulnscan.VulnScanResult
+		if err := readRequired(filepath.Join(rawDir, "vulns.json"), &r); err != nil {
+			return err
+		}
+		return WriteVulnHTMLReport(&r, path)
+	}},
+	{"diff.md", []string{"diff.json"}, func(rawDir, path string) error {
+		var r diff.DiffResult
+		if err := readRequired(filepath.Join(rawDir, "diff.json"), &r); err != nil {
+			return err
+		}
+		return WriteDiffReport(&r, path)
+	}},
+	{"diff.html", []string{"diff.json"}, func(rawDir, path string) error {
+		var r diff.DiffResult
+		if err := readRequired(filepath.Join(rawDir, "diff.json"), &r); err != nil {
+			return err
+		}
+		return WriteDiffHTMLReport(&r, path)
+	}},
+	{"expired-findings.md", []string{"expired-findings.json"}, func(rawDir, path string) error {
+		var r []models.FindingExpiry
+		if err := readRequired(filepath.Join(rawDir, "expired-findings.json"), &r); err != nil {
+			return err
+		}
+		return WriteExpiredFindingsReport(r, path)
+	}},
+	{"notes.md", []string{notes.File}, func(rawDir, path string) error {
+		var r notes.ScanNotes
+		if err := readRequired(filepath.Join(rawDir, notes.File), &r); err != nil {
+			return err
+		}
+		return WriteNotesReport(&r, path)
+	}},
+}
+
+// RegenerateReports rebuilds every markdown and HTML report in {scanDir}/reports/ from
+// the structured JSON in {scanDir}/raw/. Reports whose raw input is absent are
+// skipped. Returns the paths of the reports written.
+func RegenerateReports(scanDir string) ([]string, error) {
+	written, _, err := regenerate(scanDir, nil)
+	return written, err
+}
+
+// ExpectedReports lists the names of the reports RegenerateReports builds
+// for scanDir: those whose raw input exists.
+func ExpectedReports(scanDir string) []string {
+	var names []string
+	for _, r := range rawReports {
+		if _, err := os.Stat(storage.RawPath(scanDir, r.inputs[0])); err == nil {
+			names = append(names, r.name)
+		}
+	}
+	return names
+}
+
+// RegenerateChanged is RegenerateReports for the reports whose raw inputs
+// changed since they were last written, per the checksums recorded in the
+// scan directory, and for missing ones. version is the running reconpipe
+// version; reports recorded by another version are all rebuilt. Returns the
+// paths written and those left as they were, and records the new checksums.
+func RegenerateChanged(scanDir, version string) (written, unchanged []string, err error) {
+	sums, err := storage.LoadChecksums(scanDir, version)
+	if err != nil {
+		// A damaged manifest only costs the skipping
+		fmt.Printf("[!] Warning: %v; regenerating every report\n", err)
+	}
+	written, unchanged, err = regenerate(scanDir, sums)
+	if saveErr := sums.Save(scanDir); saveErr != nil && err == nil {
+		err = saveErr
+	}
+	return written, unchanged, err
+}
+
+// RecordReports records the current checksums of the raw inputs of the named
+// reports, after a stage wrote them, so RegenerateChanged can skip them.
+func RecordReports(scanDir, version string, names ...string) error {
+	sums, err := storage.LoadChecksums(scanDir, version)
+	if err != nil {
+		return err
+	}
+	for _, r := range rawReports {
+		if !slices.Contains(names, r.name) {
+			continue
+		}
+		if _, err := os.Stat(storage.ReportPath(scanDir, r.name)); err != nil {
+			continue
+		}
+		inputs, err := storage.HashRawFiles(scanDir, r.inputs...)
+		if err != nil {
+			return err
+		}
+		sums.Record(r.name, inputs)
+	}
+	return sums.Save(scanDir)
+}
+
+// regenerate rebuilds the reports of scanDir. With sums, reports built from
+// unchanged inputs that still exist are skipped, and the inputs of those
+// rebuilt are recorded in sums.
+func regenerate(scanDir string, sums *storage.Checksums) (written, unchanged []string, err error) {
+	rawDir := storage.RawDir(scanDir)
+	reportsDir := storage.ReportsDir(scanDir)
+
+	if err := os.MkdirAll(reportsDir, 0755); err != nil {
+		return nil, nil, fmt.Errorf("creating reports dir: %w", err)
+	}
+
+	for _, r := range rawReports {
+		if _, err := os.Stat(filepath.Join(rawDir, r.inputs[0])); err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			return written, unchanged, fmt.Errorf("reading %s: %w", r.inputs[0], err)
+		}
+
+		path := filepath.Join(reportsDir, r.name)
+		var inputs map[string]string
+		if sums != nil {
+			if inputs, err = storage.HashRawFiles(scanDir, r.inputs...); err != nil {
+				return written, unchanged, err
+			}
+			if _, statErr := os.Stat(path); statErr == nil && sums.Unchanged(r.name, inputs) {
+				unchanged = append(unchanged, path)
+				continue
+			}
+		}
+
+		if err := r.write(rawDir, path); err != nil {
+			return written, unchanged, err
+		}
+		written = append(written, path)
+		if sums != nil {
+			sums.Record(r.name, inputs)
+		}
+	}
+
+	return written, unchanged, nil
+}
+
+// readRequired is readRaw for a file already known to exist.
+func readRequired(path string, v any) error {
+	found, err := readRaw(path, v)
+	if err == nil && !found {
+		err = fmt.Errorf("reading %s: %w", path, os.ErrNotExist)
+	}
+	return err
+}
+
+// readRaw unmarshals a raw JSON stage output into v. found is false when the
+// file does not exist.
+func readRaw(path string, v any) (found bool, err error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return false, nil
+		}
+		return false, fmt.Errorf("reading %s: %w", path, err)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return false, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	return true, nil
+}
@@ -0,0 +1,287 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hakim/reconpipe/internal/diff"
+	"github.com/hakim/reconpipe/internal/vulnscan"
+	"github.com/hakim/reconpipe/pkg/models"
+)
+
+// SARIF 2.1.0 document types. Only the fields reconpipe populates are
+// modelled.
+
+// SARIFLog is the top-level SARIF document.
+type SARIFLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []SARIFRun `json:"runs"`
+}
+
+// SARIFRun is one scan of one target.
+type SARIFRun struct {
+	Tool              SARIFTool               `json:"tool"`
+	AutomationDetails *SARIFAutomationDetails `json:"automationDetails,omitempty"`
+	Results           []SARIFResult           `json:"results"`
+	Properties        map[string]any          `json:"properties,omitempty"`
+}
+
+// SARIFTool names the tool that produced the run and the rules it checked.
+type SARIFTool struct {
+	Driver SARIFDriver `json:"driver"`
+}
+
+// SARIFDriver is the tool component: reconpipe, with one rule per nuclei
+// template that matched.
+type SARIFDriver struct {
+	Name           string      `json:"name"`
+	Version        string      `json:"version,omitempty"`
+	InformationURI string      `json:"informationUri,omitempty"`
+	Rules          []SARIFRule `json:"rules"`
+}
+
+// SARIFRule describes a nuclei template.
+type SARIFRule struct {
+	ID                   string              `json:"id"`
+	Name                 string              `json:"name,omitempty"`
+	ShortDescription     SARIFMessage        `json:"shortDescription"`
+	FullDescription      *SARIFMessage       `json:"fullDescription,omitempty"`
+	DefaultConfiguration SARIFConfiguration  `json:"defaultConfiguration"`
+	Properties           SARIFRuleProperties `json:"properties"`
+}
+
+// SARIFConfiguration is a rule's default reporting level.
+type SARIFConfiguration struct {
+	Level string `json:"level"`
+}
+
+// SARIFRuleProperties carries the tags and the numeric severity GitHub Code
+// Scanning ranks security alerts by.
+type SARIFRuleProperties struct {
+	Tags             []string `json:"tags,omitempty"`
+	SecuritySeverity string   `json:"security-severity,omitempty"`
+}
+
+// SARIFResult is one finding.
+type SARIFResult struct {
+	RuleID              string             `json:"ruleId"`
+	RuleIndex           int                `json:"ruleIndex"`
+	Level               string             `json:"level"`
+	Message             SARIFMessage       `json:"message"`
+	Locations           []SARIFLocation    `json:"locations"`
+	PartialFingerprints map[string]string  `json:"partialFingerprints"`
+	Suppressions        []SARIFSuppression `json:"suppressions,omitempty"`
+	Properties          map[string]any     `json:"properties,omitempty"`
+}
+
+// SARIFMessage is a plain-text message.
+type SARIFMessage struct {
+	Text string `json:"text"`
+}
+
+// SARIFLocation is where a finding was matched: the URL or host:port nuclei
+// reported.
+type SARIFLocation struct {
+	PhysicalLocation SARIFPhysicalLocation `json:"physicalLocation"`
+}
+
+// SARIFPhysicalLocation wraps the location's URI.
+type SARIFPhysicalLocation struct {
+	ArtifactLocation SARIFArtifactLocation `json:"artifactLocation"`
+}
+
+// SARIFArtifactLocation is a URI.
+type SARIFArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+// SARIFSuppression marks a result as not needing action.
+type SARIFSuppression struct {
+	Kind          string `json:"kind"`
+	Justification string `json:"justification,omitempty"`
+}
+
+// SARIFAutomationDetails identifies the run's category, so uploads for
+// different targets do not replace each other's alerts.
+type SARIFAutomationDetails struct {
+	ID string `json:"id"`
+}
+
+const (
+	sarifSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+	sarifVersion = "2.1.0"
+
+	// sarifFingerprint keys the identity of a finding across scans, the
+	// template and host its diff entry is keyed by.
+	sarifFingerprint = "reconpipeFinding/v1"
+)
+
+// sarifLevels maps a severity to the SARIF level it is reported at.
+var sarifLevels = map[models.Severity]string{
+	models.SeverityCritical: "error",
+	models.SeverityHigh:     "error",
+	models.SeverityMedium:   "warning",
+	models.SeverityLow:      "note",
+	models.SeverityInfo:     "note",
+}
+
+// sarifSecuritySeverity maps a severity to a score in the band GitHub Code
+// Scanning shows it as: 9.0+ critical, 7.0+ high, 4.0+ medium, above 0 low.
+// Info findings get none and are shown by their level alone.
+var sarifSecuritySeverity = map[models.Severity]string{
+	models.SeverityCritical: "9.5",
+	models.SeverityHigh:     "8.0",
+	models.SeverityMedium:   "5.5",
+	models.SeverityLow:      "2.0",
+}
+
+// BuildSARIF turns a vulnerability scan result into a SARIF log with one
+// run, for GitHub Code Scanning and other SARIF dashboards. Each nuclei
+// template that matched becomes a rule and each finding a result located at
+// the URL or address it matched, most severe first. Noise findings are kept
+// as suppressed results. version is the reconpipe version that ran the scan.
+func BuildSARIF(result *vulnscan.VulnScanResult, version string) *SARIFLog {
+	run := SARIFRun{
+		Tool: SARIFTool{Driver: SARIFDriver{
+			Name:           "reconpipe",
+			Version:        version,
+			InformationURI: "https://github.com/Wakiki93/recon-pipeline",
+			Rules:          []SARIFRule{},
+		}},
+		Results: []SARIFResult{},
+	}
+	if result.Target != "" {
+		run.AutomationDetails = &SARIFAutomationDetails{ID: "reconpipe/" + result.Target + "/"}
+	}
+	props := map[string]any{}
+	if result.Target != "" {
+		props["target"] = result.Target
+	}
+	if !result.CollectedAt.IsZero() {
+		props["collectedAt"] = result.CollectedAt.UTC().Format("2006-01-02T15:04:05Z")
+	}
+	if result.Partial {
+		props["partial"] = true
+	}
+	if result.Filter != "" {
+		props["filter"] = result.Filter
+	}
+	if len(props) > 0 {
+		run.Properties = props
+	}
+
+	ruleIndex := make(map[string]int)
+	for _, v := range sortVulnsBySeverity(result.Vulnerabilities) {
+		idx, ok := ruleIndex[v.TemplateID]
+		if !ok {
+			idx = len(run.Tool.Driver.Rules)
+			ruleIndex[v.TemplateID] = idx
+			run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, sarifRule(v))
+		}
+		run.Results = append(run.Results, sarifResult(v, idx))
+	}
+
+	return &SARIFLog{Schema: sarifSchema, Version: sarifVersion, Runs: []SARIFRun{run}}
+}
+
+// WriteSARIF converts a vulnerability scan result to SARIF 2.1.0 and writes it
+// to the specified output path.
+func WriteSARIF(result *vulnscan.VulnScanResult, version, outputPath string) error {
+	data, err := json.MarshalIndent(BuildSARIF(result, version), "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshaling SARIF: %w", err)
+	}
+	return writeFile(outputPath, string(data)+"\n")
+}
+
+// sarifRule describes the template of v, at the severity of the first
+// finding of it, which is its most severe.
+func sarifRule(v models.Vulnerability) SARIFRule {
+	rule := SARIFRule{
+		ID:                   v.TemplateID,
+		Name:                 v.Name,
+		ShortDescription:     SARIFMessage{Text: v.Name},
+		DefaultConfiguration: SARIFConfiguration{Level: sarifLevel(v.Severity)},
+		Properties: SARIFRuleProperties{
+			Tags:             append([]string{"security"}, v.Tags...),
+			SecuritySeverity: sarifSecuritySeverity[v.Severity],
+		},
+	}
+	if v.Description != "" {
+		rule.FullDescription = &SARIFMessage{Text: v.Description}
+	}
+	return rule
+}
+
+// sarifResult reports v against the rule at ruleIndex.
+func sarifResult(v models.Vulnerability, ruleIndex int) SARIFResult {
+	where := v.MatchedAt
+	if where == "" {
+		where = v.Host
+	}
+	r := SARIFResult{
+		RuleID:    v.TemplateID,
+		RuleIndex: ruleIndex,
+		Level:     sarifLevel(v.Severity),
+		Message:   SARIFMessage{Text: fmt.Sprintf("%s (%s) at %s", v.Name, v.Severity, where)},
+		Locations: []SARIFLocation{{PhysicalLocation: SARIFPhysicalLocation{
+			ArtifactLocation: SARIFArtifactLocation{URI: sarifURI(where)},
+		}}},
+		PartialFingerprints: map[string]string{sarifFingerprint: diff.VulnKey(v)},
+	}
+	if v.Noise {
+		r.Suppressions = []SARIFSuppression{{Kind: "external", Justification: "matched a vulnscan.noise rule"}}
+	}
+
+	props := map[string]any{"severity": string(v.Severity), "host": v.Host}
+	if v.OriginalSeverity != "" {
+		props["originalSeverity"] = string(v.OriginalSeverity)
+	}
+	if len(v.Compliance) > 0 {
+		frameworks := make([]string, 0, len(v.Compliance))
+		for fw := range v.Compliance {
+			frameworks = append(frameworks, fw)
+		}
+		sort.Strings(frameworks)
+		var refs []string
+		for _, fw := range frameworks {
+			for _, ref := range v.Compliance[fw] {
+				refs = append(refs, fw+" "+ref)
+			}
+		}
+		props["compliance"] = refs
+	}
+	r.Properties = props
+	return r
+}
+
+// sarifURI makes where a valid URI reference: an address without a scheme,
+// such as the host:port of a network template, becomes a network-path
+// reference (//host:port), and characters a URI cannot hold, such as the
+// braces of an injection payload, are percent-encoded.
+func sarifURI(where string) string {
+	if !strings.Contains(where, "://") {
+		where = "//" + where
+	}
+	var b strings.Builder
+	for i := 0; i < len(where); i++ {
+		c := where[i]
+		if c > ' ' && c < 0x7f && !strings.ContainsRune("\"<>\\^`{|}", rune(c)) {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+// sarifLevel is the SARIF level of sev; unknown severities are warnings.
+func sarifLevel(sev models.Severity) string {
+	if level, ok := sarifLevels[sev]; ok {
+		return level
+	}
+	return "warning"
+}
@@ -0,0 +1,207 @@
+package report
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/hakim/reconpipe/internal/config"
+	"github.com/hakim/reconpipe/internal/storage"
+)
+
+// Report is one generated report handed to a Sink.
+type Report struct {
+	Scan    string // scan directory name, e.g. "example.com_20260101_120000"
+	Name    string // report file name, e.g. "ports.md"
+	Path    string // path the report was written to inside the scan directory
+	Content []byte
+}
+
+// Sink receives a copy of every report after it has been written to the
+// scan directory.
+type Sink interface {
+	Name() string
+	Write(r Report) error
+}
+
+// sinks are the extra destinations every report fans out to.
+var sinks []Sink
+
+// SetSinks replaces the extra report destinations. Passing nil leaves only
+// the scan directory.
+func SetSinks(s []Sink) {
+	sinks = s
+}
+
+// SinksFromConfig builds sinks from the report_sinks config block.
+func SinksFromConfig(cfgs []config.ReportSinkConfig) ([]Sink, error) {
+	var out []Sink
+	for i, c := range cfgs {
+		timeout := 10 * time.Second
+		if c.Timeout != "" {
+			d, err := time.ParseDuration(c.Timeout)
+			if err != nil {
+				return nil, fmt.Errorf("report_sinks[%d]: invalid timeout %q: %w", i, c.Timeout, err)
+			}
+			timeout = d
+		}
+
+		switch c.Type {
+		case "stdout":
+			out = append(out, &StdoutSink{Out: os.Stdout})
+		case "file":
+			out = append(out, &FileSink{Dir: c.Dir})
+		case "webhook":
+			out = append(out, &WebhookSink{
+				URL:     c.URL,
+				Headers: c.Headers,
+				Client:  &http.Client{Timeout: timeout},
+			})
+		case "s3":
+			s3, err := NewS3Sink(c.Bucket, c.Region, c.Prefix, c.Endpoint)
+			if err != nil {
+				return nil, fmt.Errorf("report_sinks[%d]: %w", i, err)
+			}
+			s3.Client = &http.Client{Timeout: timeout}
+			out = append(out, s3)
+		default:
+			return nil, fmt.Errorf("report_sinks[%d]: unknown sink type %q", i, c.Type)
+		}
+	}
+	return out, nil
+}
+
+// writeFile writes content to path, wrapping any OS error with context, then
+// fans the report out to any configured sinks. Sink failures are reported as
+// warnings since the scan directory copy is already safe on disk.
+func writeFile(outputPath, content string) error {
+	if err := storage.WriteFileAtomic(outputPath, []byte(content), 0644); err != nil {
+		return fmt.Errorf("writing report to %s: %w", outputPath, err)
+	}
+
+	if len(sinks) == 0 {
+		return nil
+	}
+
+	r := Report{
+		Scan:    scanName(outputPath),
+		Name:    filepath.Base(outputPath),
+		Path:    outputPath,
+		Content: []byte(content),
+	}
+	for _, s := range sinks {
+		if err := s.Write(r); err != nil {
+			fmt.Printf("[!] Warning: report sink %s failed for %s: %v\n", s.Name(), r.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// scanName derives the scan directory name from a report path of the form
+// {scan_dir}/{reports_dir}/{name}.
+func scanName(reportPath string) string {
+	dir := filepath.Dir(reportPath)
+	if storage.IsReportsDir(dir) {
+		dir = filepath.Dir(dir)
+	}
+	return filepath.Base(dir)
+}
+
+// contentType picks a MIME type for a report from its extension.
+func contentType(name string) string {
+	switch filepath.Ext(name) {
+	case ".md":
+		return "text/markdown; charset=utf-8"
+	case ".html":
+		return "text/html; charset=utf-8"
+	case ".json":
+		return "application/json"
+	case ".pdf":
+		return "application/pdf"
+	default:
+		return "application/octet-stream"
+	}
+}
+
+// StdoutSink prints each report to Out under a header line.
+type StdoutSink struct {
+	Out io.Writer
+}
+
+// Name implements Sink.
+func (s *StdoutSink) Name() string { return "stdout" }
+
+// Write implements Sink.
+func (s *StdoutSink) Write(r Report) error {
+	_, err := fmt.Fprintf(s.Out, "==> %s/%s <==\n%s\n", r.Scan, r.Name, r.Content)
+	return err
+}
+
+// FileSink mirrors reports into Dir/{scan}/{name}, e.g. a shared drive.
+type FileSink struct {
+	Dir string
+}
+
+// Name implements Sink.
+func (s *FileSink) Name() string { return "file:" + s.Dir }
+
+// Write implements Sink.
+func (s *FileSink) Write(r Report) error {
+	dir := filepath.Join(s.Dir, r.Scan)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("creating %s: %w", dir, err)
+	}
+	path := filepath.Join(dir, r.Name)
+	if err := os.WriteFile(path, r.Content, 0644); err != nil {
+		return fmt.Errorf("writing %s: %w", path, err)
+	}
+	return nil
+}
+
+// WebhookSink POSTs the raw report body to URL. The scan and report names
+// travel in X-Reconpipe-Scan and X-Reconpipe-Report headers.
+type WebhookSink struct {
+	URL     string
+	Headers map[string]string // values are expanded with os.ExpandEnv
+	Client  *http.Client
+}
+
+// Name implements Sink.
+func (s *WebhookSink) Name() string { return "webhook:" + s.URL }
+
+// Write implements Sink.
+func (s *WebhookSink) Write(r Report) error {
+	req, err := http.NewRequest(http.MethodPost, s.URL, bytes.NewReader(r.Content))
+	if err != nil {
+		return fmt.Errorf("building request: %w", err)
+	}
+	req.Header.Set("Content-Type", contentType(r.Name))
+	req.Header.Set("X-Reconpipe-Scan", r.Scan)
+	req.Header.Set("X-Reconpipe-Report", r.Name)
+	for k, v := range s.Headers {
+		req.Header.Set(k, os.ExpandEnv(v))
+	}
+
+	client := s.Client
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("posting to %s: %w", s.URL, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("webhook returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	return nil
+}
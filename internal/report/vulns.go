@@ -2,12 +2,13 @@ package report
 
 import (
 	"fmt"
-	"os"
+	"slices"
+	"sort"
 	"strings"
-	"time"
 
-	"github.com/hakim/reconpipe/internal/models"
+	"github.com/hakim/reconpipe/internal/compliance"
 	"github.com/hakim/reconpipe/internal/vulnscan"
+	"github.com/hakim/reconpipe/pkg/models"
 )
 
 // severityOrder defines the display order for vulnerability sections (most severe first).
@@ -27,7 +28,8 @@ func WriteVulnReport(result *vulnscan.VulnScanResult, outputPath string) error {
 	// Header
 	b.WriteString("# Vulnerability Scan Report\n\n")
 	b.WriteString(fmt.Sprintf("**Target:** %s\n", result.Target))
-	b.WriteString(fmt.Sprintf("**Date:** %s\n", time.Now().UTC().Format("2006-01-02 15:04:05 UTC")))
+	b.WriteString(fmt.Sprintf("**Date:** %s\n", now().UTC().Format("2006-01-02 15:04:05 UTC")))
+	b.WriteString(collectedLine("Data collected", result.CollectedAt))
 	b.WriteString(fmt.Sprintf(
 		"**Total findings:** %d | **Critical:** %d | **High:** %d | **Medium:** %d | **Low:** %d | **Info:** %d\n\n",
 		result.TotalCount,
@@ -38,8 +40,45 @@ func WriteVulnReport(result *vulnscan.VulnScanResult, outputPath string) error {
 		result.SeverityCounts[string(models.SeverityInfo)],
 	))
 
+	// A filtered scan did not look for findings on the targets it left out
+	if result.Filter != "" {
+		b.WriteString(fmt.Sprintf("> **Filtered scan:** only targets matching `%s` were scanned; %d were left out.\n\n", result.Filter, result.FilteredOut))
+	}
+
+	// A scan cut short by the pipeline deadline says what it left out
+	if result.Partial {
+		var missed []string
+		if n := len(result.SkippedTargets); n > 0 {
+			missed = append(missed, fmt.Sprintf("%d targets were not scanned", n))
+		}
+		if result.NarrowedSeverity != "" {
+			missed = append(missed, "later targets were only checked for "+result.NarrowedSeverity)
+		}
+		if len(missed) == 0 {
+			missed = append(missed, "some nuclei runs were stopped before they finished")
+		}
+		b.WriteString(fmt.Sprintf("> **Partial scan:** the pipeline deadline cut this scan short; %s.\n\n", strings.Join(missed, ", ")))
+	}
+
+	if result.Overridden > 0 {
+		b.WriteString(fmt.Sprintf("> **Severity overrides:** %d findings are listed at the severity configured for their template, not the one they were raised with.\n\n", result.Overridden))
+	}
+
+	// Findings a noise rule matched go to the appendix, not the sections
+	var actionable, noise []models.Vulnerability
+	for _, v := range result.Vulnerabilities {
+		if v.Noise {
+			noise = append(noise, v)
+		} else {
+			actionable = append(actionable, v)
+		}
+	}
+	if len(noise) > 0 {
+		b.WriteString(fmt.Sprintf("> **Noise:** %d findings matched a noise rule and are listed under Appendix: Informational Noise, not in the sections below.\n\n", len(noise)))
+	}
+
 	// One section per severity in priority order
-	bySeverity := vulnsBySeverity(result.Vulnerabilities)
+	bySeverity := vulnsBySeverity(actionable)
 	for _, sev := range severityOrder {
 		heading := strings.Title(string(sev))
 		b.WriteString(fmt.Sprintf("## %s Findings\n\n", heading))
@@ -57,8 +96,12 @@ func WriteVulnReport(result *vulnscan.VulnScanResult, outputPath string) error {
 			if matchedAt == "" {
 				matchedAt = "-"
 			}
+			name := v.Name
+			if v.OriginalSeverity != "" {
+				name += fmt.Sprintf(" *(raised as %s)*", v.OriginalSeverity)
+			}
 			b.WriteString(fmt.Sprintf("| %s | %s | %s | %s |\n",
-				v.Name, v.Host, matchedAt, v.TemplateID))
+				name, v.Host, matchedAt, v.TemplateID))
 		}
 		b.WriteString("\n")
 	}
@@ -71,13 +114,15 @@ func WriteVulnReport(result *vulnscan.VulnScanResult, outputPath string) error {
 	b.WriteString(fmt.Sprintf("- **Medium:** %d\n", result.SeverityCounts[string(models.SeverityMedium)]))
 	b.WriteString(fmt.Sprintf("- **Low:** %d\n", result.SeverityCounts[string(models.SeverityLow)]))
 	b.WriteString(fmt.Sprintf("- **Info:** %d\n", result.SeverityCounts[string(models.SeverityInfo)]))
-
-	// Write to file
-	if err := os.WriteFile(outputPath, []byte(b.String()), 0644); err != nil {
-		return fmt.Errorf("writing report to %s: %w", outputPath, err)
+	if len(noise) > 0 {
+		b.WriteString(fmt.Sprintf("- **Noise:** %d (counted above, listed in the appendix)\n", len(noise)))
 	}
 
-	return nil
+	writeComplianceSummary(&b, result.Vulnerabilities)
+	writeNoiseAppendix(&b, noise)
+
+	// Write to file
+	return writeFile(outputPath, b.String())
 }
 
 // vulnsBySeverity partitions a vulnerability slice into a map keyed by severity.
@@ -88,3 +133,96 @@ func vulnsBySeverity(vulns []models.Vulnerability) map[models.Severity][]models.
 	}
 	return groups
 }
+
+// writeNoiseAppendix lists the findings classed as noise, most severe
+// first. Nothing is written when there are none.
+func writeNoiseAppendix(b *strings.Builder, noise []models.Vulnerability) {
+	if len(noise) == 0 {
+		return
+	}
+	b.WriteString("\n## Appendix: Informational Noise\n\n")
+	b.WriteString("Findings matched by a `vulnscan.noise` rule. They count towards the totals; finding alerts leave them out unless `vulnscan.noise.alert` is set.\n\n")
+	b.WriteString("| Severity | Name | Host | Matched At | Template ID |\n")
+	b.WriteString("|----------|------|------|------------|-------------|\n")
+	bySeverity := vulnsBySeverity(noise)
+	for _, sev := range severityOrder {
+		for _, v := range bySeverity[sev] {
+			matchedAt := v.MatchedAt
+			if matchedAt == "" {
+				matchedAt = "-"
+			}
+			b.WriteString(fmt.Sprintf("| %s | %s | %s | %s | %s |\n",
+				strings.Title(string(sev)), v.Name, v.Host, matchedAt, v.TemplateID))
+		}
+	}
+}
+
+// complianceRow is one framework requirement in the compliance summary.
+type complianceRow struct {
+	findings  int
+	worst     models.Severity
+	templates []string
+}
+
+// writeComplianceSummary adds a section per framework listing each mapped
+// requirement with the findings that bear on it. Nothing is written when
+// no finding carries a compliance reference.
+func writeComplianceSummary(b *strings.Builder, vulns []models.Vulnerability) {
+	frameworks := make(map[string]map[string]*complianceRow)
+	untagged := 0
+	for _, v := range vulns {
+		if len(v.Compliance) == 0 {
+			untagged++
+			continue
+		}
+		for framework, refs := range v.Compliance {
+			if frameworks[framework] == nil {
+				frameworks[framework] = make(map[string]*complianceRow)
+			}
+			for _, ref := range refs {
+				row := frameworks[framework][ref]
+				if row == nil {
+					row = &complianceRow{worst: v.Severity}
+					frameworks[framework][ref] = row
+				}
+				row.findings++
+				if slices.Index(severityOrder, v.Severity) < slices.Index(severityOrder, row.worst) {
+					row.worst = v.Severity
+				}
+				if !slices.Contains(row.templates, v.TemplateID) {
+					row.templates = append(row.templates, v.TemplateID)
+				}
+			}
+		}
+	}
+	if len(frameworks) == 0 {
+		return
+	}
+
+	b.WriteString("\n## Compliance Summary\n\n")
+	b.WriteString(fmt.Sprintf("%d of %d findings map to a framework requirement.\n", len(vulns)-untagged, len(vulns)))
+
+	names := make([]string, 0, len(frameworks))
+	for name := range frameworks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		rows := frameworks[name]
+		refs := make([]string, 0, len(rows))
+		for ref := range rows {
+			refs = append(refs, ref)
+		}
+		slices.SortFunc(refs, compliance.CompareRefs)
+
+		b.WriteString(fmt.Sprintf("\n### %s\n\n", name))
+		b.WriteString("| Requirement | Findings | Worst Severity | Templates |\n")
+		b.WriteString("|-------------|----------|----------------|-----------|\n")
+		for _, ref := range refs {
+			row := rows[ref]
+			sort.Strings(row.templates)
+			b.WriteString(fmt.Sprintf("| %s | %d | %s | %s |\n",
+				ref, row.findings, strings.Title(string(row.worst)), strings.Join(row.templates, ", ")))
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package riskpolicy
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/hakim/reconpipe/internal/diff"
+	"github.com/hakim/reconpipe/internal/storage"
+	"github.com/hakim/reconpipe/internal/takeover"
+	"github.com/hakim/reconpipe/pkg/models"
+)
+
+// ScanFindings loads the vulnerabilities and dangling subdomains of the scan
+// in scanDir, most severe first. A finding is new unless the scan's diff
+// (raw/diff.json) shows the previous scan had it; a scan without a diff has
+// nothing to compare against, so all its findings are new.
+func ScanFindings(scanDir string) ([]Finding, error) {
+	snap, err := diff.LoadSnapshot(scanDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var changes *diff.DiffResult
+	if data, err := os.ReadFile(storage.RawPath(scanDir, "diff.json")); err == nil {
+		changes = new(diff.DiffResult)
+		if err := json.Unmarshal(data, changes); err != nil {
+			return nil, fmt.Errorf("parsing diff.json: %w", err)
+		}
+	}
+	newVulns := map[string]bool{}
+	newDangling := map[string]bool{}
+	if changes != nil {
+		for _, v := range changes.NewVulns {
+			newVulns[diff.VulnKey(v)] = true
+		}
+		for _, s := range changes.NewlyDangling {
+			newDangling[s.Name] = true
+		}
+	}
+
+	var out []Finding
+	for _, v := range snap.Vulnerabilities {
+		key := diff.VulnKey(v)
+		out = append(out, VulnFinding(v, changes == nil || newVulns[key]))
+	}
+	for _, s := range snap.Subdomains {
+		if s.IsDangling {
+			out = append(out, DanglingFinding(s, changes == nil || newDangling[s.Name]))
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		return severityRank[out[i].Severity] > severityRank[out[j].Severity]
+	})
+	return out, nil
+}
+
+// VulnFinding returns the finding of v. Its categories are "vuln" and its
+// nuclei tags.
+func VulnFinding(v models.Vulnerability, isNew bool) Finding {
+	name := v.Name
+	if name == "" {
+		name = v.TemplateID
+	}
+	return Finding{
+		Kind:       models.FindingKindVuln,
+		Identity:   diff.VulnKey(v),
+		Name:       name,
+		Host:       v.Host,
+		Severity:   v.Severity,
+		Categories: append([]string{models.FindingKindVuln}, v.Tags...),
+		New:        isNew,
+		Noise:      v.Noise,
+	}
+}
+
+// DanglingFinding returns the finding of a dangling subdomain, rated as in
+// the dangling DNS report. Its categories are "dangling", plus "takeover"
+// when its CNAME points at a service that allows takeovers.
+func DanglingFinding(s models.Subdomain, isNew bool) Finding {
+	a := takeover.Assess(s)
+	f := Finding{
+		Kind:       models.FindingKindDangling,
+		Identity:   s.Name,
+		Name:       "Dangling DNS (stale record)",
+		Host:       s.Name,
+		Severity:   a.Severity,
+		Categories: []string{models.FindingKindDangling},
+		New:        isNew,
+	}
+	if a.CNAME != "" {
+		f.Name = "Dangling DNS (takeover candidate: " + a.CNAME + ", " + a.Risk() + ")"
+		f.Categories = append(f.Categories, "takeover")
+	}
+	return f
+}
@@ -0,0 +1,267 @@
+// Package riskpolicy holds the threshold decisions every integration makes
+// about findings: which ones fail a run, raise a finding alert, warrant the
+// completion notification or open a ticket. They are configured once under
+// risk_policy:
+//
+//	risk_policy:
+//	  assets:
+//	    prod: ["www.example.com", "api.*", "*.prod.example.com"]
+//	  fail_on:
+//	    - min_severity: high
+//	      new: true
+//	  alert_on:
+//	    - min_severity: medium
+//	    - assets: [prod]
+//	  notify_on:
+//	    - min_severity: high
+//	  ticket_on:
+//	    - categories: [takeover]
+//
+// Each action lists rules, and a finding triggers the action when any rule
+// matches it. A rule matches when every condition it sets holds: one of its
+// severities (or at least min_severity), one of its categories, its new
+// flag, and one of its asset tags. Findings classed as noise only match
+// rules that set noise: true. An action without rules keeps the behavior
+// reconpipe had before policies: nothing fails a run, every finding but
+// noise is alerted on, and the completion notification and tickets are
+// always sent.
+package riskpolicy
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"path"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/hakim/reconpipe/pkg/models"
+)
+
+// Action is a decision the policy makes about findings.
+type Action string
+
+// Actions, named as their config keys.
+const (
+	Fail   Action = "fail_on"   // the run exits with ExitCode
+	Alert  Action = "alert_on"  // a finding alert is sent when it is new or escalated
+	Notify Action = "notify_on" // the completion notification is sent
+	Ticket Action = "ticket_on" // an issue is opened for it
+)
+
+// Actions lists every action, in config order.
+var Actions = []Action{Fail, Alert, Notify, Ticket}
+
+// ExitCode is the status scan and vulnscan exit with when a finding
+// matches fail_on, so CI can tell it from a failed run (1).
+const ExitCode = 2
+
+// Rule is one rule as written in the config. A rule must set at least one
+// condition.
+type Rule struct {
+	Severities  []string `mapstructure:"severities"`   // critical, high, medium, low or info
+	MinSeverity string   `mapstructure:"min_severity"` // this severity or worse
+	Categories  []string `mapstructure:"categories"`   // finding kind (vuln, dangling), nuclei tag or takeover
+	New         *bool    `mapstructure:"new"`          // true: only findings new since the previous scan; false: only known ones
+	Assets      []string `mapstructure:"assets"`       // asset tags from risk_policy.assets
+	Noise       bool     `mapstructure:"noise"`        // also match findings classed as noise
+}
+
+// Spec is a whole policy as written in the config.
+type Spec struct {
+	Assets map[string][]string // asset tag to hostname globs
+	Rules  map[Action][]Rule
+
+	// AlertNoise makes alert_on match noise findings too
+	// (vulnscan.noise.alert).
+	AlertNoise bool
+}
+
+// Finding is a finding reduced to what the policy looks at, plus what the
+// integrations report about it.
+type Finding struct {
+	Kind       string // models.FindingKindVuln or models.FindingKindDangling
+	Identity   string // diff.VulnKey, or the dangling subdomain
+	Name       string
+	Host       string
+	Severity   models.Severity
+	Categories []string // kind first, then tags
+	New        bool     // not in the target's previous scan
+	Noise      bool
+}
+
+// Policy decides actions for findings. A nil *Policy applies the defaults.
+type Policy struct {
+	assets     map[string][]string // lowercased
+	rules      map[Action][]Rule   // lowercased and trimmed
+	alertNoise bool
+}
+
+// severityRank orders severities for min_severity (higher = worse).
+var severityRank = map[models.Severity]int{
+	models.SeverityInfo:     1,
+	models.SeverityLow:      2,
+	models.SeverityMedium:   3,
+	models.SeverityHigh:     4,
+	models.SeverityCritical: 5,
+}
+
+// New checks spec and returns its policy.
+func New(spec Spec) (*Policy, error) {
+	p := &Policy{
+		assets:     make(map[string][]string, len(spec.Assets)),
+		rules:      make(map[Action][]Rule, len(spec.Rules)),
+		alertNoise: spec.AlertNoise,
+	}
+	for tag, patterns := range spec.Assets {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" {
+			return nil, fmt.Errorf("assets: empty tag name")
+		}
+		if len(patterns) == 0 {
+			return nil, fmt.Errorf("assets.%s: no hostname patterns", tag)
+		}
+		for _, pattern := range normalize(patterns) {
+			if _, err := path.Match(pattern, ""); err != nil || pattern == "" {
+				return nil, fmt.Errorf("assets.%s: bad pattern %q", tag, pattern)
+			}
+			p.assets[tag] = append(p.assets[tag], pattern)
+		}
+	}
+
+	for action, rules := range spec.Rules {
+		if !slices.Contains(Actions, action) {
+			return nil, fmt.Errorf("unknown action %q", action)
+		}
+		for i, spec := range rules {
+			r := Rule{
+				Severities:  normalize(spec.Severities),
+				MinSeverity: strings.ToLower(strings.TrimSpace(spec.MinSeverity)),
+				Categories:  normalize(spec.Categories),
+				New:         spec.New,
+				Assets:      normalize(spec.Assets),
+				Noise:       spec.Noise,
+			}
+			if len(r.Severities)+len(r.Categories)+len(r.Assets) == 0 && r.MinSeverity == "" && r.New == nil && !r.Noise {
+				return nil, fmt.Errorf("%s rule %d: set at least one of severities, min_severity, categories, new, assets or noise", action, i+1)
+			}
+			for _, s := range append(slices.Clone(r.Severities), r.MinSeverity) {
+				if _, ok := severityRank[models.Severity(s)]; !ok && s != "" {
+					return nil, fmt.Errorf("%s rule %d: unknown severity %q (want critical, high, medium, low or info)", action, i+1, s)
+				}
+			}
+			for _, tag := range r.Assets {
+				if _, ok := p.assets[tag]; !ok {
+					return nil, fmt.Errorf("%s rule %d: asset tag %q is not defined under assets", action, i+1, tag)
+				}
+			}
+			p.rules[action] = append(p.rules[action], r)
+		}
+	}
+	return p, nil
+}
+
+// normalize lowercases and trims values.
+func normalize(values []string) []string {
+	out := make([]string, 0, len(values))
+	for _, v := range values {
+		out = append(out, strings.ToLower(strings.TrimSpace(v)))
+	}
+	return out
+}
+
+// Configured reports whether the policy has rules for action, as opposed
+// to applying its default.
+func (p *Policy) Configured(action Action) bool {
+	return p != nil && len(p.rules[action]) > 0
+}
+
+// Matches reports whether f triggers action.
+func (p *Policy) Matches(action Action, f Finding) bool {
+	if !p.Configured(action) {
+		switch action {
+		case Alert:
+			return !f.Noise || (p != nil && p.alertNoise)
+		case Notify, Ticket:
+			return true
+		}
+		return false
+	}
+	if f.Noise && action == Alert && p.alertNoise {
+		f.Noise = false
+	}
+	tags := p.AssetTags(f.Host)
+	return slices.ContainsFunc(p.rules[action], func(r Rule) bool { return r.matches(f, tags) })
+}
+
+// Filter returns the findings that trigger action, in their order.
+func (p *Policy) Filter(action Action, findings []Finding) []Finding {
+	var out []Finding
+	for _, f := range findings {
+		if p.Matches(action, f) {
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+// matches reports whether every condition r sets holds for f, whose host
+// carries the asset tags in tags.
+func (r Rule) matches(f Finding, tags []string) bool {
+	if f.Noise && !r.Noise {
+		return false
+	}
+	if len(r.Severities) > 0 && !slices.Contains(r.Severities, string(f.Severity)) {
+		return false
+	}
+	if r.MinSeverity != "" && severityRank[f.Severity] < severityRank[models.Severity(r.MinSeverity)] {
+		return false
+	}
+	if r.New != nil && *r.New != f.New {
+		return false
+	}
+	if len(r.Categories) > 0 && !slices.ContainsFunc(f.Categories, func(c string) bool {
+		return slices.Contains(r.Categories, strings.ToLower(c))
+	}) {
+		return false
+	}
+	if len(r.Assets) > 0 && !slices.ContainsFunc(tags, func(t string) bool { return slices.Contains(r.Assets, t) }) {
+		return false
+	}
+	return true
+}
+
+// AssetTags returns the sorted asset tags whose patterns match host, which
+// may be a URL or host:port.
+func (p *Policy) AssetTags(host string) []string {
+	if p == nil || len(p.assets) == 0 {
+		return nil
+	}
+	name := strings.ToLower(Hostname(host))
+	var tags []string
+	for tag, patterns := range p.assets {
+		if slices.ContainsFunc(patterns, func(pattern string) bool {
+			ok, _ := path.Match(pattern, name)
+			return ok
+		}) {
+			tags = append(tags, tag)
+		}
+	}
+	sort.Strings(tags)
+	return tags
+}
+
+// Hostname returns the host name or IP of a finding's host, which may be a
+// URL, host:port or a bare name.
+func Hostname(host string) string {
+	if strings.Contains(host, "://") {
+		if u, err := url.Parse(host); err == nil && u.Hostname() != "" {
+			return u.Hostname()
+		}
+	}
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		return h
+	}
+	return host
+}
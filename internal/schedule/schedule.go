@@ -0,0 +1,227 @@
+// Package schedule parses the cron-like expressions 'reconpipe monitor'
+// runs its schedules on. An expression is either five cron fields
+//
+//	minute hour day-of-month month day-of-week
+//
+// each a *, a number, a range (1-5), a list (1,15) or a step (*/15, 8-18/2),
+// with month and weekday names (jan, mon) allowed, or one of the
+// descriptors @hourly, @daily (@midnight), @weekly, @monthly, @yearly
+// (@annually) and @every <duration>, e.g. @every 6h. As in cron, a day
+// matches when either day field does once both are restricted.
+package schedule
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Spec is a parsed schedule.
+type Spec struct {
+	expr string
+
+	// every is the interval of an @every schedule; the fields are unused
+	// when it is set
+	every time.Duration
+
+	minute, hour, dom, month, dow uint64 // bit n set when n matches
+	domAny, dowAny                bool   // the day field was *
+}
+
+// field is the range of one cron field.
+type field struct {
+	name     string
+	min, max int
+	names    map[string]int
+}
+
+var (
+	minuteField = field{name: "minute", min: 0, max: 59}
+	hourField   = field{name: "hour", min: 0, max: 23}
+	domField    = field{name: "day of month", min: 1, max: 31}
+	monthField  = field{name: "month", min: 1, max: 12, names: map[string]int{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}}
+	// 7 is Sunday too, as in most crons
+	dowField = field{name: "day of week", min: 0, max: 7, names: map[string]int{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}}
+)
+
+// descriptors are the @ shorthands for common schedules.
+var descriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// minEvery is the shortest @every interval accepted.
+const minEvery = time.Minute
+
+// Parse parses a schedule expression.
+func Parse(expr string) (*Spec, error) {
+	expr = strings.TrimSpace(expr)
+	s := &Spec{expr: expr}
+
+	if rest, ok := strings.CutPrefix(expr, "@every "); ok {
+		d, err := time.ParseDuration(strings.TrimSpace(rest))
+		if err != nil {
+			return nil, fmt.Errorf("schedule %q: %w", expr, err)
+		}
+		if d < minEvery {
+			return nil, fmt.Errorf("schedule %q: interval must be at least %s", expr, minEvery)
+		}
+		s.every = d
+		return s, nil
+	}
+
+	fields := expr
+	if strings.HasPrefix(expr, "@") {
+		var ok bool
+		if fields, ok = descriptors[strings.ToLower(expr)]; !ok {
+			return nil, fmt.Errorf("schedule %q: unknown descriptor (want @hourly, @daily, @weekly, @monthly, @yearly or @every <duration>)", expr)
+		}
+	}
+	parts := strings.Fields(fields)
+	if len(parts) != 5 {
+		return nil, fmt.Errorf("schedule %q: want 5 fields (minute hour day-of-month month day-of-week), got %d", expr, len(parts))
+	}
+
+	var err error
+	if s.minute, err = minuteField.parse(parts[0]); err != nil {
+		return nil, fmt.Errorf("schedule %q: %w", expr, err)
+	}
+	if s.hour, err = hourField.parse(parts[1]); err != nil {
+		return nil, fmt.Errorf("schedule %q: %w", expr, err)
+	}
+	if s.dom, err = domField.parse(parts[2]); err != nil {
+		return nil, fmt.Errorf("schedule %q: %w", expr, err)
+	}
+	if s.month, err = monthField.parse(parts[3]); err != nil {
+		return nil, fmt.Errorf("schedule %q: %w", expr, err)
+	}
+	if s.dow, err = dowField.parse(parts[4]); err != nil {
+		return nil, fmt.Errorf("schedule %q: %w", expr, err)
+	}
+	if s.dow&(1<<7) != 0 {
+		s.dow |= 1 // 7 is Sunday
+	}
+	s.domAny = parts[2] == "*" || parts[2] == "?"
+	s.dowAny = parts[4] == "*" || parts[4] == "?"
+
+	// e.g. 0 0 30 2 *
+	if s.Next(time.Now()).IsZero() {
+		return nil, fmt.Errorf("schedule %q never runs", expr)
+	}
+	return s, nil
+}
+
+// String returns the expression s was parsed from.
+func (s *Spec) String() string {
+	return s.expr
+}
+
+// Next returns the first time after after that s runs at, in after's
+// location; cron fields are matched against the wall clock there. It
+// returns the zero time when s does not run within five years.
+func (s *Spec) Next(after time.Time) time.Time {
+	if s.every > 0 {
+		return after.Add(s.every).Truncate(time.Second)
+	}
+
+	loc := after.Location()
+	t := time.Date(after.Year(), after.Month(), after.Day(), after.Hour(), after.Minute()+1, 0, 0, loc)
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		switch {
+		case s.month&(1<<uint(t.Month())) == 0:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+		case !s.dayMatches(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+		case s.hour&(1<<uint(t.Hour())) == 0:
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+		case s.minute&(1<<uint(t.Minute())) == 0:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// dayMatches reports whether the day of t matches the day fields.
+func (s *Spec) dayMatches(t time.Time) bool {
+	dom := s.dom&(1<<uint(t.Day())) != 0
+	dow := s.dow&(1<<uint(t.Weekday())) != 0
+	if s.domAny || s.dowAny {
+		return dom && dow
+	}
+	return dom || dow
+}
+
+// parse parses one field into a bit set of the values it matches.
+func (f field) parse(expr string) (uint64, error) {
+	var set uint64
+	for _, item := range strings.Split(expr, ",") {
+		rng, stepStr, hasStep := strings.Cut(item, "/")
+		step := 1
+		if hasStep {
+			n, err := strconv.Atoi(stepStr)
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("%s: invalid step %q", f.name, stepStr)
+			}
+			step = n
+		}
+
+		lo, hi := f.min, f.max
+		switch {
+		case rng == "*" || rng == "?":
+		case strings.Contains(rng, "-"):
+			a, b, _ := strings.Cut(rng, "-")
+			var err error
+			if lo, err = f.value(a); err != nil {
+				return 0, err
+			}
+			if hi, err = f.value(b); err != nil {
+				return 0, err
+			}
+			if lo > hi {
+				return 0, fmt.Errorf("%s: range %q runs backwards", f.name, rng)
+			}
+		default:
+			var err error
+			if lo, err = f.value(rng); err != nil {
+				return 0, err
+			}
+			// with a step, 5/15 runs from 5 to the end of the field
+			if !hasStep {
+				hi = lo
+			}
+		}
+		for v := lo; v <= hi; v += step {
+			set |= 1 << uint(v)
+		}
+	}
+	return set, nil
+}
+
+// value parses a single number or name of the field.
+func (f field) value(s string) (int, error) {
+	if n, ok := f.names[strings.ToLower(s)]; ok {
+		return n, nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("%s: invalid value %q", f.name, s)
+	}
+	if n < f.min || n > f.max {
+		return 0, fmt.Errorf("%s: %d out of range %d-%d", f.name, n, f.min, f.max)
+	}
+	return n, nil
+}
@@ -0,0 +1,112 @@
+package selfupdate
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ManifestName is the release asset that ties a release's binaries to its
+// tag. It is signed like any other file, so its signature is
+// ManifestName + ".sig".
+const ManifestName = "reconpipe.manifest"
+
+// manifestHeader is the first line of a manifest.
+const manifestHeader = "reconpipe-release v1"
+
+// Manifest lists the release tag and the SHA-256 digest of each binary:
+//
+//	reconpipe-release v1
+//	tag: v1.4.0
+//	sha256: 9f86d081884c7d65...  reconpipe_linux_amd64
+//
+// Signing the manifest rather than each binary stops a valid binary of one
+// release being served as another.
+type Manifest struct {
+	Tag    string
+	SHA256 map[string]string // hex digest by asset name
+}
+
+// NewManifest builds the manifest of release tag from the binaries at
+// paths, listed by their base names.
+func NewManifest(tag string, paths []string) (*Manifest, error) {
+	m := &Manifest{Tag: tag, SHA256: make(map[string]string, len(paths))}
+	for _, path := range paths {
+		sum, err := fileSHA256(path)
+		if err != nil {
+			return nil, err
+		}
+		m.SHA256[filepath.Base(path)] = sum
+	}
+	return m, nil
+}
+
+// Write saves the manifest to path.
+func (m *Manifest) Write(path string) error {
+	names := make([]string, 0, len(m.SHA256))
+	for name := range m.SHA256 {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s\ntag: %s\n", manifestHeader, m.Tag)
+	for _, name := range names {
+		fmt.Fprintf(&b, "sha256: %s  %s\n", m.SHA256[name], name)
+	}
+	return os.WriteFile(path, []byte(b.String()), 0644)
+}
+
+// ReadManifest parses the manifest at path.
+func ReadManifest(path string) (*Manifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	if !scanner.Scan() || scanner.Text() != manifestHeader {
+		return nil, fmt.Errorf("%s: not a reconpipe release manifest", filepath.Base(path))
+	}
+	m := &Manifest{SHA256: make(map[string]string)}
+	for scanner.Scan() {
+		k, v, ok := strings.Cut(scanner.Text(), ": ")
+		if !ok {
+			continue
+		}
+		switch k {
+		case "tag":
+			m.Tag = v
+		case "sha256":
+			fields := strings.Fields(v)
+			if len(fields) != 2 {
+				return nil, fmt.Errorf("%s: malformed line %q", filepath.Base(path), scanner.Text())
+			}
+			m.SHA256[fields[1]] = fields[0]
+		}
+	}
+	if m.Tag == "" {
+		return nil, fmt.Errorf("%s: no release tag", filepath.Base(path))
+	}
+	return m, nil
+}
+
+// fileSHA256 returns the hex SHA-256 digest of the file at path.
+func fileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", fmt.Errorf("hashing %s: %w", filepath.Base(path), err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,434 @@
+// Package selfupdate keeps a reconpipe binary current from the project's
+// GitHub releases. A release carries one binary per platform, named
+// reconpipe_<os>_<arch> (.exe on Windows), and a manifest of the release
+// tag and each binary's SHA-256 digest with an ed25519 signature in the
+// format package signing writes:
+//
+//	reconpipe_linux_amd64
+//	reconpipe.manifest
+//	reconpipe.manifest.sig
+//
+// A binary is only installed when the manifest verifies against the
+// configured public key, names the release being installed and lists the
+// binary's digest, and the binary reports that release's version. A
+// compromised release page or mirror can then only offer binaries the key
+// holder built for that tag; it can still withhold updates.
+package selfupdate
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hakim/reconpipe/internal/signing"
+)
+
+// DefaultRepo is the GitHub repository releases are fetched from.
+const DefaultRepo = "Wakiki93/recon-pipeline"
+
+// Release channels.
+const (
+	ChannelStable = "stable" // releases only
+	ChannelBeta   = "beta"   // prereleases too
+)
+
+// maxBinarySize caps how much of a release asset is downloaded.
+const maxBinarySize = 512 << 20
+
+// Release is a published release.
+type Release struct {
+	Tag         string
+	Name        string
+	Prerelease  bool
+	PublishedAt time.Time
+	URL         string // the release page
+	Assets      []Asset
+}
+
+// Asset is a file attached to a release.
+type Asset struct {
+	Name string
+	URL  string // download URL
+	Size int64
+}
+
+// Version returns the release's version: its tag without a leading v.
+func (r *Release) Version() string {
+	return strings.TrimPrefix(r.Tag, "v")
+}
+
+// Asset returns the asset called name.
+func (r *Release) Asset(name string) (Asset, bool) {
+	for _, a := range r.Assets {
+		if a.Name == name {
+			return a, true
+		}
+	}
+	return Asset{}, false
+}
+
+// AssetName returns the name of the binary built for goos and goarch.
+func AssetName(goos, goarch string) string {
+	name := "reconpipe_" + goos + "_" + goarch
+	if goos == "windows" {
+		name += ".exe"
+	}
+	return name
+}
+
+// Client lists and downloads releases through the GitHub REST API.
+type Client struct {
+	URL    string // API base, default https://api.github.com
+	Repo   string // owner/name, default DefaultRepo
+	Client *http.Client
+}
+
+// githubRelease is the part of a GitHub release this client reads.
+type githubRelease struct {
+	TagName     string    `json:"tag_name"`
+	Name        string    `json:"name"`
+	Draft       bool      `json:"draft"`
+	Prerelease  bool      `json:"prerelease"`
+	PublishedAt time.Time `json:"published_at"`
+	HTMLURL     string    `json:"html_url"`
+	Assets      []struct {
+		Name               string `json:"name"`
+		BrowserDownloadURL string `json:"browser_download_url"`
+		Size               int64  `json:"size"`
+	} `json:"assets"`
+}
+
+// Latest returns the newest release on channel: the highest version that
+// is not a draft, counting prereleases only on the beta channel. It
+// returns nil when the channel has no release.
+func (c *Client) Latest(ctx context.Context, channel string) (*Release, error) {
+	if channel != ChannelStable && channel != ChannelBeta {
+		return nil, fmt.Errorf("unknown channel %q (want stable or beta)", channel)
+	}
+	var releases []githubRelease
+	if err := c.get(ctx, "/releases?per_page=100", &releases); err != nil {
+		return nil, err
+	}
+	var latest *Release
+	for _, gr := range releases {
+		if gr.Draft || (gr.Prerelease && channel == ChannelStable) {
+			continue
+		}
+		rel := &Release{
+			Tag:         gr.TagName,
+			Name:        gr.Name,
+			Prerelease:  gr.Prerelease,
+			PublishedAt: gr.PublishedAt,
+			URL:         gr.HTMLURL,
+		}
+		if _, err := parseVersion(rel.Version()); err != nil {
+			continue // not a version tag
+		}
+		for _, a := range gr.Assets {
+			rel.Assets = append(rel.Assets, Asset{Name: a.Name, URL: a.BrowserDownloadURL, Size: a.Size})
+		}
+		if latest == nil || Compare(rel.Version(), latest.Version()) > 0 {
+			latest = rel
+		}
+	}
+	return latest, nil
+}
+
+// get fetches a path under the repository and decodes the JSON response
+// into out.
+func (c *Client) get(ctx context.Context, path string, out any) error {
+	base := c.URL
+	if base == "" {
+		base = "https://api.github.com"
+	}
+	repo := c.Repo
+	if repo == "" {
+		repo = DefaultRepo
+	}
+	endpoint := strings.TrimRight(base, "/") + "/repos/" + repo + path
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return fmt.Errorf("building request: %w", err)
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+	resp, err := c.client().Do(req)
+	if err != nil {
+		return fmt.Errorf("GET %s: %w", req.URL.Redacted(), err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 10<<20))
+	if err != nil {
+		return fmt.Errorf("GET %s: reading response: %w", req.URL.Redacted(), err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("GET %s: %s: %s", req.URL.Redacted(), resp.Status, strings.TrimSpace(string(data)))
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("GET %s: decoding response: %w", req.URL.Redacted(), err)
+	}
+	return nil
+}
+
+// download writes the asset at rawURL to path.
+func (c *Client) download(ctx context.Context, rawURL, path string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return fmt.Errorf("building request: %w", err)
+	}
+	req.Header.Set("Accept", "application/octet-stream")
+	resp, err := c.client().Do(req)
+	if err != nil {
+		return fmt.Errorf("GET %s: %w", req.URL.Redacted(), err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("GET %s: %s", req.URL.Redacted(), resp.Status)
+	}
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	n, err := io.Copy(f, io.LimitReader(resp.Body, maxBinarySize+1))
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return fmt.Errorf("downloading %s: %w", filepath.Base(path), err)
+	}
+	if n > maxBinarySize {
+		return fmt.Errorf("downloading %s: larger than %d MB", filepath.Base(path), maxBinarySize>>20)
+	}
+	return nil
+}
+
+// client returns the HTTP client, a default one when unset. Downloads can
+// take a while, so the default only bounds how long a response may take
+// to start.
+func (c *Client) client() *http.Client {
+	if c.Client != nil {
+		return c.Client
+	}
+	return &http.Client{Transport: &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		TLSHandshakeTimeout:   30 * time.Second,
+		ResponseHeaderTimeout: 30 * time.Second,
+	}}
+}
+
+// Install downloads the binary rel has for this platform and replaces the
+// executable at exe with it. The release's manifest must verify with
+// verifier, name rel's tag and list the binary's SHA-256 digest, and the
+// binary must report rel's version. The replaced binary is kept as exe.old
+// until the next update, so a bad release can be rolled back by hand.
+// Nothing is replaced when any step fails.
+func (c *Client) Install(ctx context.Context, rel *Release, exe string, verifier *signing.Signer) error {
+	if verifier == nil {
+		return errors.New("a public key is required to verify the release")
+	}
+	name := AssetName(runtime.GOOS, runtime.GOARCH)
+	bin, ok := rel.Asset(name)
+	if !ok {
+		return fmt.Errorf("release %s has no binary for %s/%s (%s)", rel.Tag, runtime.GOOS, runtime.GOARCH, name)
+	}
+	manifest, ok := rel.Asset(ManifestName)
+	if !ok {
+		return fmt.Errorf("release %s has no %s", rel.Tag, ManifestName)
+	}
+	sig, ok := rel.Asset(verifier.SignaturePath(ManifestName))
+	if !ok {
+		return fmt.Errorf("release %s has no signature for %s", rel.Tag, ManifestName)
+	}
+
+	// Download next to the executable, so the final rename stays on one
+	// filesystem
+	tmp := filepath.Join(filepath.Dir(exe), "."+filepath.Base(exe)+".update")
+	tmpManifest := tmp + ".manifest"
+	defer os.Remove(tmp)
+	defer os.Remove(tmpManifest)
+	defer os.Remove(verifier.SignaturePath(tmpManifest))
+
+	// Step 1: The manifest must be signed and made for this release
+	if err := c.download(ctx, manifest.URL, tmpManifest); err != nil {
+		return err
+	}
+	if err := c.download(ctx, sig.URL, verifier.SignaturePath(tmpManifest)); err != nil {
+		return err
+	}
+	if _, err := verifier.VerifyNamed(ctx, tmpManifest, ManifestName); err != nil {
+		return fmt.Errorf("verifying %s: %w", ManifestName, err)
+	}
+	m, err := ReadManifest(tmpManifest)
+	if err != nil {
+		return err
+	}
+	if m.Tag != rel.Tag {
+		return fmt.Errorf("%s is signed for release %s, not %s", ManifestName, m.Tag, rel.Tag)
+	}
+	want, ok := m.SHA256[name]
+	if !ok {
+		return fmt.Errorf("%s of release %s does not list %s", ManifestName, rel.Tag, name)
+	}
+
+	// Step 2: The binary must be the one the manifest lists
+	if err := c.download(ctx, bin.URL, tmp); err != nil {
+		return err
+	}
+	got, err := fileSHA256(tmp)
+	if err != nil {
+		return err
+	}
+	if got != want {
+		return fmt.Errorf("verifying %s: SHA-256 %s does not match the manifest", name, got)
+	}
+	if err := os.Chmod(tmp, 0755); err != nil {
+		return err
+	}
+	if err := checkVersion(ctx, tmp, rel.Version()); err != nil {
+		return err
+	}
+
+	old := exe + ".old"
+	if err := os.Remove(old); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("removing %s: %w", old, err)
+	}
+	if err := os.Rename(exe, old); err != nil {
+		return fmt.Errorf("moving current binary aside: %w", err)
+	}
+	if err := os.Rename(tmp, exe); err != nil {
+		if rerr := os.Rename(old, exe); rerr != nil {
+			return fmt.Errorf("installing %s: %w (restoring the previous binary from %s also failed: %v)", rel.Tag, err, old, rerr)
+		}
+		return fmt.Errorf("installing %s: %w", rel.Tag, err)
+	}
+	return nil
+}
+
+// checkVersion runs "bin version" and checks that it reports version.
+func checkVersion(ctx context.Context, bin, version string) error {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, bin, "version").Output()
+	if err != nil {
+		return fmt.Errorf("running the new binary: %w", err)
+	}
+	fields := strings.Fields(string(out))
+	if len(fields) < 2 || fields[0] != "reconpipe" || Compare(fields[1], version) != 0 {
+		return fmt.Errorf("the new binary reports %q, not reconpipe %s", strings.TrimSpace(string(out)), version)
+	}
+	return nil
+}
+
+// Executable returns the path of the running binary, with symlinks
+// resolved so the update replaces the real file.
+func Executable() (string, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return "", err
+	}
+	return filepath.EvalSymlinks(exe)
+}
+
+// Compare compares two semantic versions, with or without a leading v: -1
+// when a is older than b, 1 when newer, 0 when equal. A prerelease such as
+// 1.2.0-beta.1 is older than 1.2.0; build metadata is ignored. Versions
+// that do not parse compare as older than those that do.
+func Compare(a, b string) int {
+	va, erra := parseVersion(a)
+	vb, errb := parseVersion(b)
+	switch {
+	case erra != nil && errb != nil:
+		return strings.Compare(a, b)
+	case erra != nil:
+		return -1
+	case errb != nil:
+		return 1
+	}
+	for i := range 3 {
+		if va.core[i] != vb.core[i] {
+			return cmp.Compare(va.core[i], vb.core[i])
+		}
+	}
+	return comparePrerelease(va.pre, vb.pre)
+}
+
+// version is a parsed semantic version.
+type version struct {
+	core [3]int
+	pre  []string
+}
+
+// parseVersion parses MAJOR.MINOR.PATCH[-PRERELEASE][+BUILD]; MINOR and
+// PATCH may be left out.
+func parseVersion(s string) (version, error) {
+	var v version
+	s = strings.TrimPrefix(strings.TrimSpace(s), "v")
+	s, _, _ = strings.Cut(s, "+")
+	core, pre, hasPre := strings.Cut(s, "-")
+	if hasPre {
+		if pre == "" {
+			return v, fmt.Errorf("invalid version %q", s)
+		}
+		v.pre = strings.Split(pre, ".")
+	}
+	parts := strings.Split(core, ".")
+	if len(parts) > 3 {
+		return v, fmt.Errorf("invalid version %q", s)
+	}
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 0 {
+			return v, fmt.Errorf("invalid version %q", s)
+		}
+		v.core[i] = n
+	}
+	return v, nil
+}
+
+// comparePrerelease orders prerelease identifiers as semver does: none
+// ranks above any, numeric identifiers compare as numbers and below
+// alphanumeric ones, and a shorter list ranks below a longer one it
+// prefixes.
+func comparePrerelease(a, b []string) int {
+	switch {
+	case len(a) == 0 && len(b) == 0:
+		return 0
+	case len(a) == 0:
+		return 1
+	case len(b) == 0:
+		return -1
+	}
+	for i := 0; i < len(a) && i < len(b); i++ {
+		na, erra := strconv.Atoi(a[i])
+		nb, errb := strconv.Atoi(b[i])
+		switch {
+		case erra == nil && errb == nil:
+			if na != nb {
+				return cmp.Compare(na, nb)
+			}
+		case erra == nil:
+			return -1
+		case errb == nil:
+			return 1
+		default:
+			if c := strings.Compare(a[i], b[i]); c != 0 {
+				return c
+			}
+		}
+	}
+	return cmp.Compare(len(a), len(b))
+}
@@ -0,0 +1,383 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hakim/reconpipe/internal/diff"
+	"github.com/hakim/reconpipe/internal/pipeline"
+	"github.com/hakim/reconpipe/internal/storage"
+	"github.com/hakim/reconpipe/pkg/models"
+)
+
+// maxRequestBody bounds POST bodies.
+const maxRequestBody = 64 << 10
+
+// writeJSON sends v with status.
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(v)
+}
+
+// writeError sends {"error": msg} with status.
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, map[string]string{"error": msg})
+}
+
+// publicScan strips the config snapshot from a scan record: it can hold
+// sink credentials that read-only tokens must not see.
+func publicScan(m *models.ScanMeta) *models.ScanMeta {
+	out := *m
+	if m.RunConfig != nil {
+		rc := *m.RunConfig
+		rc.Config = nil
+		out.RunConfig = &rc
+	}
+	return &out
+}
+
+// findScan resolves the {id} path value, writing the error response itself
+// when it returns nil.
+func (s *Server) findScan(w http.ResponseWriter, r *http.Request) *models.ScanMeta {
+	scan, err := s.store.FindScan(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return nil
+	}
+	if scan == nil {
+		writeError(w, http.StatusNotFound, "scan not found")
+		return nil
+	}
+	return scan
+}
+
+// GET /api/v1/targets
+func (s *Server) handleTargets(w http.ResponseWriter, r *http.Request) {
+	targets, err := s.store.ListTargets()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"targets": nonNilSlice(targets)})
+}
+
+// GET /api/v1/scans?target=example.com&limit=20
+func (s *Server) handleListScans(w http.ResponseWriter, r *http.Request) {
+	limit := 0
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			writeError(w, http.StatusBadRequest, "limit must be a non-negative integer")
+			return
+		}
+		limit = n
+	}
+
+	targets := []string{r.URL.Query().Get("target")}
+	if targets[0] == "" {
+		var err error
+		if targets, err = s.store.ListTargets(); err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+
+	var scans []*models.ScanMeta
+	for _, t := range targets {
+		list, err := s.store.ListScans(t)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		for _, m := range list {
+			scans = append(scans, publicScan(m))
+		}
+	}
+	sort.Slice(scans, func(i, j int) bool {
+		return scans[i].StartedAt.After(scans[j].StartedAt)
+	})
+	if limit > 0 && len(scans) > limit {
+		scans = scans[:limit]
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"scans": nonNilSlice(scans)})
+}
+
+// GET /api/v1/scans/{id}
+func (s *Server) handleGetScan(w http.ResponseWriter, r *http.Request) {
+	if scan := s.findScan(w, r); scan != nil {
+		writeJSON(w, http.StatusOK, publicScan(scan))
+	}
+}
+
+// GET /api/v1/scans/{id}/results
+func (s *Server) handleScanResults(w http.ResponseWriter, r *http.Request) {
+	scan := s.findScan(w, r)
+	if scan == nil {
+		return
+	}
+	snap, err := diff.LoadScanSnapshot(s.store, scan)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "loading results: "+err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"scan":            publicScan(scan),
+		"subdomains":      nonNilSlice(snap.Subdomains),
+		"hosts":           nonNilSlice(snap.Hosts),
+		"http_probes":     nonNilSlice(snap.Probes),
+		"vulnerabilities": nonNilSlice(snap.Vulnerabilities),
+	})
+}
+
+// POST /api/v1/scans
+func (s *Server) handleCreateScan(w http.ResponseWriter, r *http.Request) {
+	var req ScanRequest
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBody))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body: "+err.Error())
+		return
+	}
+
+	req.Target = strings.ToLower(strings.TrimSpace(req.Target))
+	if err := pipeline.ValidateDomain(req.Target); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	_, scope, policy := s.limits()
+	if err := (&pipeline.ScopeConfig{AllowedDomains: scope}).ValidateTarget(req.Target); err != nil {
+		writeError(w, http.StatusForbidden, err.Error())
+		return
+	}
+	if req.Priority == "" {
+		req.Priority = PriorityNormal
+	}
+	if !ValidPriority(req.Priority) {
+		writeError(w, http.StatusBadRequest, "unknown priority "+strconv.Quote(req.Priority)+" (want high, normal or low)")
+		return
+	}
+	if req.Preset != "" {
+		if _, err := pipeline.GetPreset(req.Preset); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	if !req.IgnorePolicy {
+		err := pipeline.CheckCooldown(s.store, req.Target, policy.Cooldown, time.Now())
+		var pe *pipeline.PolicyError
+		if errors.As(err, &pe) {
+			w.Header().Set("Retry-After", strconv.Itoa(int(pe.RetryAfter.Seconds()+1)))
+			writeError(w, http.StatusConflict, err.Error())
+			return
+		} else if err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+
+	exclusive := !req.IgnorePolicy && !policy.AllowConcurrent
+	job, err := s.jobs.enqueue(req, "api:"+requestToken(r).Name, exclusive)
+	if errors.Is(err, errQueueFull) || errors.Is(err, errDraining) {
+		w.Header().Set("Retry-After", "60")
+		writeError(w, http.StatusServiceUnavailable, err.Error())
+		return
+	}
+	if errors.Is(err, errTargetBusy) {
+		writeError(w, http.StatusConflict, err.Error())
+		return
+	}
+	w.Header().Set("Location", "/api/v1/jobs/"+job.ID)
+	writeJSON(w, http.StatusAccepted, job)
+}
+
+// GET /api/v1/scans/{id}/diff?against={id}
+//
+// Compares the scan with another scan, by default the newest scan of the
+// same target that started before it. With no earlier scan everything
+// counts as new. New and removed entries are dated from every earlier scan
+// of the target.
+func (s *Server) handleScanDiff(w http.ResponseWriter, r *http.Request) {
+	scan := s.findScan(w, r)
+	if scan == nil {
+		return
+	}
+
+	var prev *models.ScanMeta
+	var err error
+	if against := r.URL.Query().Get("against"); against != "" {
+		prev, err = s.store.FindScan(against)
+		if err == nil && prev == nil {
+			writeError(w, http.StatusNotFound, "scan to compare against not found")
+			return
+		}
+	} else {
+		prev, err = s.previousScan(scan)
+	}
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	current, err := diff.LoadScanSnapshot(s.store, scan)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "loading results: "+err.Error())
+		return
+	}
+	previous := &diff.ScanSnapshot{}
+	var prevOut *models.ScanMeta
+	if prev != nil {
+		if previous, err = diff.LoadScanSnapshot(s.store, prev); err != nil {
+			writeError(w, http.StatusInternalServerError, "loading previous results: "+err.Error())
+			return
+		}
+		prevOut = publicScan(prev)
+	}
+
+	result := diff.ComputeDiff(current, previous)
+	if earlier, err := s.earlierScans(scan); err == nil {
+		if history, err := diff.LoadHistory(s.store, earlier); err == nil {
+			result.Date(history, scan.StartedAt)
+		}
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"scan":     publicScan(scan),
+		"previous": prevOut,
+		"diff":     result,
+	})
+}
+
+// previousScan returns the newest scan of scan's target that started before
+// it, or nil.
+func (s *Server) previousScan(scan *models.ScanMeta) (*models.ScanMeta, error) {
+	scans, err := s.store.ListScans(scan.Target)
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range scans { // newest first
+		if m.ID != scan.ID && m.StartedAt.Before(scan.StartedAt) {
+			return m, nil
+		}
+	}
+	return nil, nil
+}
+
+// earlierScans returns the scans of scan's target that started before it,
+// which date the entries of its diff.
+func (s *Server) earlierScans(scan *models.ScanMeta) ([]*models.ScanMeta, error) {
+	scans, err := s.store.ListScans(scan.Target)
+	if err != nil {
+		return nil, err
+	}
+	var earlier []*models.ScanMeta
+	for _, m := range scans {
+		if m.ID != scan.ID && m.StartedAt.Before(scan.StartedAt) {
+			earlier = append(earlier, m)
+		}
+	}
+	return earlier, nil
+}
+
+// GET /api/v1/scans/{id}/reports
+func (s *Server) handleListReports(w http.ResponseWriter, r *http.Request) {
+	scan := s.findScan(w, r)
+	if scan == nil {
+		return
+	}
+
+	type reportFile struct {
+		Name     string    `json:"name"`
+		Size     int64     `json:"size"`
+		Modified time.Time `json:"modified"`
+	}
+	reports := []reportFile{}
+	entries, err := os.ReadDir(storage.ReportsDir(scan.ScanDir))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	for _, e := range entries {
+		info, err := e.Info()
+		if err != nil || !info.Mode().IsRegular() || strings.HasPrefix(e.Name(), ".") {
+			continue
+		}
+		reports = append(reports, reportFile{Name: e.Name(), Size: info.Size(), Modified: info.ModTime()})
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"reports": reports})
+}
+
+// GET /api/v1/scans/{id}/reports/{name}
+func (s *Server) handleReportFile(w http.ResponseWriter, r *http.Request) {
+	if scan := s.findScan(w, r); scan != nil {
+		serveScanFile(w, r, storage.ReportsDir(scan.ScanDir), r.PathValue("name"))
+	}
+}
+
+// GET /api/v1/scans/{id}/screenshots/{name}
+func (s *Server) handleScreenshot(w http.ResponseWriter, r *http.Request) {
+	if scan := s.findScan(w, r); scan != nil {
+		serveScanFile(w, r, storage.ScreenshotsDir(scan.ScanDir), r.PathValue("name"))
+	}
+}
+
+// serveScanFile serves one file directly inside dir. Scan output can contain
+// attacker-controlled content (page titles, HTML reports), so it is served
+// sandboxed: it can never run script in the UI's origin.
+func serveScanFile(w http.ResponseWriter, r *http.Request, dir, name string) {
+	if name == "" || name != filepath.Base(name) || strings.HasPrefix(name, ".") {
+		writeError(w, http.StatusBadRequest, "invalid file name")
+		return
+	}
+	f, err := os.Open(filepath.Join(dir, name))
+	if err != nil {
+		writeError(w, http.StatusNotFound, "file not found")
+		return
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil || !info.Mode().IsRegular() {
+		writeError(w, http.StatusNotFound, "file not found")
+		return
+	}
+
+	if strings.EqualFold(filepath.Ext(name), ".md") {
+		w.Header().Set("Content-Type", "text/markdown; charset=utf-8")
+	}
+	w.Header().Set("Content-Security-Policy", "sandbox; default-src 'none'; img-src 'self' data:; style-src 'unsafe-inline'")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	http.ServeContent(w, r, name, info.ModTime(), f)
+}
+
+// GET /api/v1/jobs
+func (s *Server) handleListJobs(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{"jobs": s.jobs.list()})
+}
+
+// GET /api/v1/jobs/{id}
+func (s *Server) handleGetJob(w http.ResponseWriter, r *http.Request) {
+	job, ok := s.jobs.get(r.PathValue("id"))
+	if !ok {
+		writeError(w, http.StatusNotFound, "job not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, job)
+}
+
+// nonNilSlice makes empty lists encode as [] rather than null.
+func nonNilSlice[T any](s []T) []T {
+	if s == nil {
+		return []T{}
+	}
+	return s
+}
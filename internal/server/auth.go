@@ -0,0 +1,163 @@
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hakim/reconpipe/pkg/models"
+)
+
+// tokenPrefix marks reconpipe secrets so they are easy to spot in logs and
+// secret scanners.
+const tokenPrefix = "rp_"
+
+// lastUsedInterval limits how often a token's LastUsedAt is written back.
+const lastUsedInterval = time.Minute
+
+// GenerateToken creates a token record and the secret that authenticates as
+// it. Only the record is stored; the secret must be shown to the user now.
+func GenerateToken(name, scope string, rateLimit int, createdBy string) (string, *models.APIToken, error) {
+	if scope != models.TokenScopeRead && scope != models.TokenScopeScan {
+		return "", nil, fmt.Errorf("unknown scope %q (want %s or %s)", scope, models.TokenScopeRead, models.TokenScopeScan)
+	}
+	if rateLimit < 0 {
+		return "", nil, fmt.Errorf("rate limit must not be negative")
+	}
+
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", nil, fmt.Errorf("generating token: %w", err)
+	}
+	secret := tokenPrefix + hex.EncodeToString(buf)
+
+	return secret, &models.APIToken{
+		ID:        uuid.New().String(),
+		Name:      name,
+		Scope:     scope,
+		Hash:      HashToken(secret),
+		RateLimit: rateLimit,
+		CreatedAt: time.Now(),
+		CreatedBy: createdBy,
+	}, nil
+}
+
+// HashToken is the stored form of a secret.
+func HashToken(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+type tokenKey struct{}
+
+// requestToken returns the token that authenticated r.
+func requestToken(r *http.Request) *models.APIToken {
+	t, _ := r.Context().Value(tokenKey{}).(*models.APIToken)
+	return t
+}
+
+// authenticate checks the bearer token, applies its rate limit and requires
+// scope before calling next.
+func (s *Server) authenticate(scope string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		secret, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || secret == "" {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="reconpipe"`)
+			writeError(w, http.StatusUnauthorized, "missing bearer token")
+			return
+		}
+
+		token, err := s.store.FindTokenByHash(HashToken(strings.TrimSpace(secret)))
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "looking up token")
+			return
+		}
+		if token == nil || token.RevokedAt != nil {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="reconpipe", error="invalid_token"`)
+			writeError(w, http.StatusUnauthorized, "invalid or revoked token")
+			return
+		}
+
+		perMinute := token.RateLimit
+		if perMinute == 0 {
+			perMinute, _, _ = s.limits()
+		}
+		if ok, wait := s.limiter.allow(token.ID, perMinute, time.Now()); !ok {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			writeError(w, http.StatusTooManyRequests, "rate limit exceeded")
+			return
+		}
+
+		if !token.Allows(scope) {
+			writeError(w, http.StatusForbidden, fmt.Sprintf("token scope %q does not allow this request", token.Scope))
+			return
+		}
+
+		s.touch(token)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), tokenKey{}, token)))
+	})
+}
+
+// touch records that token was used, at most once per lastUsedInterval.
+// Only the stored last use is updated, so a revocation saved since the
+// token was looked up is not overwritten.
+func (s *Server) touch(token *models.APIToken) {
+	now := time.Now()
+	if token.LastUsedAt != nil && now.Sub(*token.LastUsedAt) < lastUsedInterval {
+		return
+	}
+	token.LastUsedAt = &now
+	if err := s.store.TouchToken(token.ID, now); err != nil {
+		fmt.Printf("[!] Warning: could not record token use: %v\n", err)
+	}
+}
+
+// limiter is a token bucket per API token: each holds up to perMinute
+// requests and refills at perMinute per minute, so short bursts are allowed
+// but the sustained rate is capped.
+type limiter struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+}
+
+type bucket struct {
+	available float64
+	updated   time.Time
+}
+
+func newLimiter() *limiter {
+	return &limiter{buckets: make(map[string]*bucket)}
+}
+
+// allow takes one request from id's bucket. When the bucket is empty it
+// returns false and how long until a request is available.
+func (l *limiter) allow(id string, perMinute int, now time.Time) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	capacity := float64(perMinute)
+	b, ok := l.buckets[id]
+	if !ok {
+		b = &bucket{available: capacity, updated: now}
+		l.buckets[id] = b
+	}
+
+	perSecond := capacity / 60
+	b.available = math.Min(capacity, b.available+now.Sub(b.updated).Seconds()*perSecond)
+	b.updated = now
+
+	if b.available < 1 {
+		return false, time.Duration((1 - b.available) / perSecond * float64(time.Second))
+	}
+	b.available--
+	return true, 0
+}
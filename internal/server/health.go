@@ -0,0 +1,126 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// toolCheckInterval is how long /readyz reuses the result of Options.Tools;
+// the check runs each binary for its version.
+const toolCheckInterval = time.Minute
+
+// ToolStatus is whether one external tool the scans use is installed.
+type ToolStatus struct {
+	Name     string
+	Found    bool
+	Required bool // scans refuse to start without it
+}
+
+// HealthCheck is the result of one check in a health report.
+type HealthCheck struct {
+	OK     bool   `json:"ok"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// HealthReport is the body of /healthz and /readyz.
+type HealthReport struct {
+	Status string                 `json:"status"` // ok or unavailable
+	Checks map[string]HealthCheck `json:"checks"`
+
+	// Readiness only
+	Worker       string   `json:"worker,omitempty"` // idle, running, draining or stopped
+	CurrentJob   string   `json:"current_job,omitempty"`
+	QueueDepth   *int     `json:"queue_depth,omitempty"`
+	QueueSize    int      `json:"queue_size,omitempty"`
+	MissingTools []string `json:"missing_tools,omitempty"` // optional tools included
+}
+
+// toolCache remembers the last tool check.
+type toolCache struct {
+	mu      sync.Mutex
+	checked time.Time
+	tools   []ToolStatus
+}
+
+// get returns the tool statuses from check, re-running it at most once per
+// toolCheckInterval.
+func (c *toolCache) get(check func() []ToolStatus) []ToolStatus {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.checked.IsZero() || time.Since(c.checked) > toolCheckInterval {
+		c.tools, c.checked = check(), time.Now()
+	}
+	return c.tools
+}
+
+// GET /healthz
+//
+// Liveness: the process is serving and the database is readable. It needs
+// no token, so orchestrators can probe it.
+func (s *Server) handleHealthz(w http.ResponseWriter, r *http.Request) {
+	report := &HealthReport{Checks: map[string]HealthCheck{"database": s.checkDatabase()}}
+	writeHealth(w, report)
+}
+
+// GET /readyz
+//
+// Readiness: the server can take and run scans. Besides the database it
+// fails while shutting down or when a required tool is missing. Queue depth
+// and missing optional tools are reported without failing the check.
+func (s *Server) handleReadyz(w http.ResponseWriter, r *http.Request) {
+	report := &HealthReport{Checks: map[string]HealthCheck{"database": s.checkDatabase()}}
+
+	state, current := s.jobs.state()
+	report.Worker, report.CurrentJob = state, current
+	report.Checks["worker"] = HealthCheck{
+		OK:     state == workerIdle || state == workerRunning,
+		Detail: state,
+	}
+
+	depth, size := s.jobs.depth()
+	report.QueueDepth, report.QueueSize = &depth, size
+
+	if s.tools != nil {
+		var missingRequired []string
+		for _, t := range s.toolCache.get(s.tools) {
+			if t.Found {
+				continue
+			}
+			report.MissingTools = append(report.MissingTools, t.Name)
+			if t.Required {
+				missingRequired = append(missingRequired, t.Name)
+			}
+		}
+		check := HealthCheck{OK: len(missingRequired) == 0}
+		if !check.OK {
+			check.Detail = "required tools missing: " + strings.Join(missingRequired, ", ")
+		}
+		report.Checks["tools"] = check
+	}
+
+	writeHealth(w, report)
+}
+
+// checkDatabase reports whether the database can be read.
+func (s *Server) checkDatabase() HealthCheck {
+	if err := s.store.Ping(); err != nil {
+		return HealthCheck{Detail: err.Error()}
+	}
+	return HealthCheck{OK: true}
+}
+
+// writeHealth sets the report's status from its checks and sends it, with
+// 503 when any check failed.
+func writeHealth(w http.ResponseWriter, report *HealthReport) {
+	status := http.StatusOK
+	report.Status = "ok"
+	for _, c := range report.Checks {
+		if !c.OK {
+			report.Status, status = "unavailable", http.StatusServiceUnavailable
+		}
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, status, report)
+}
@@ -0,0 +1,428 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hakim/reconpipe/pkg/models"
+)
+
+// Job states. A finished job takes the pipeline's result status (complete
+// or partial), or failed if the pipeline could not run. A job stopped by a
+// shutdown is interrupted; it is queued again when the server restarts.
+const (
+	JobQueued      = "queued"
+	JobRunning     = "running"
+	JobComplete    = "complete"
+	JobPartial     = "partial"
+	JobFailed      = "failed"
+	JobInterrupted = "interrupted"
+)
+
+// Worker states reported by /readyz.
+const (
+	workerIdle     = "idle"
+	workerRunning  = "running"
+	workerDraining = "draining" // shutting down; the running scan is finishing its stage
+	workerStopped  = "stopped"
+)
+
+var (
+	// errQueueFull is returned when no more scans can be queued.
+	errQueueFull = errors.New("scan queue is full")
+	// errDraining is returned once the server has begun shutting down.
+	errDraining = errors.New("server is shutting down and not accepting scans")
+	// errTargetBusy is returned when the target already has a queued or
+	// running job and the policy allows one at a time.
+	errTargetBusy = errors.New("target already has a queued or running scan")
+)
+
+// Job is a scan requested through the API.
+type Job struct {
+	ID         string      `json:"id"`
+	Request    ScanRequest `json:"request"`
+	Operator   string      `json:"operator"`
+	Status     string      `json:"status"`
+	ScanID     string      `json:"scan_id,omitempty"`
+	ScanDir    string      `json:"scan_dir,omitempty"`
+	Error      string      `json:"error,omitempty"`
+	QueuedAt   time.Time   `json:"queued_at"`
+	StartedAt  *time.Time  `json:"started_at,omitempty"`
+	FinishedAt *time.Time  `json:"finished_at,omitempty"`
+}
+
+// Scan priorities. Higher-priority jobs run first; jobs of equal priority
+// run in the order they were queued.
+const (
+	PriorityHigh   = "high"   // ad-hoc analyst scans
+	PriorityNormal = "normal" // the default
+	PriorityLow    = "low"    // routine monitoring
+)
+
+// priorityRank orders priorities (higher runs first).
+var priorityRank = map[string]int{PriorityLow: 1, PriorityNormal: 2, PriorityHigh: 3}
+
+// ValidPriority reports whether p is a known priority. Empty means normal.
+func ValidPriority(p string) bool {
+	return p == "" || priorityRank[p] > 0
+}
+
+// rank returns the job's priority rank, treating an empty priority as normal.
+func (j *Job) rank() int {
+	if r := priorityRank[j.Request.Priority]; r > 0 {
+		return r
+	}
+	return priorityRank[PriorityNormal]
+}
+
+// jobQueue holds API-requested scans and runs them one at a time, highest
+// priority first. With preemption on, queueing a job of higher priority than
+// the running one stops that scan at its next stage boundary; it goes back
+// to the queue and resumes in its scan directory once its turn comes again.
+//
+// Jobs are kept in memory while the server runs; on shutdown the unfinished
+// ones are saved to the database (see unfinished) and restored on the next
+// start.
+type jobQueue struct {
+	mu       sync.Mutex
+	jobs     map[string]*Job
+	queue    []*Job // waiting to run, in run order
+	size     int
+	preempt  bool
+	wake     chan struct{} // signals the worker that a job was queued
+	draining bool
+	stopped  bool          // the worker has exited
+	current  *Job          // the running job
+	runStop  chan struct{} // closed to stop the running job; nil once closed
+	stop     chan struct{} // closed by drain
+}
+
+func newJobQueue(size int, preempt bool) *jobQueue {
+	return &jobQueue{
+		jobs:    make(map[string]*Job),
+		size:    size,
+		preempt: preempt,
+		wake:    make(chan struct{}, 1),
+		stop:    make(chan struct{}),
+	}
+}
+
+// enqueue adds a job for req, or returns errQueueFull or errDraining. When
+// exclusive is set it also refuses a target that already has a queued or
+// running job, with an error wrapping errTargetBusy.
+func (q *jobQueue) enqueue(req ScanRequest, operator string, exclusive bool) (Job, error) {
+	job := &Job{
+		ID:       uuid.New().String(),
+		Request:  req,
+		Operator: operator,
+		Status:   JobQueued,
+		QueuedAt: time.Now(),
+	}
+	if err := q.add(job, exclusive); err != nil {
+		return Job{}, err
+	}
+	return *job, nil
+}
+
+// add queues job as it is, preempting the running job if it has a lower
+// priority and preemption is on. See enqueue for exclusive.
+func (q *jobQueue) add(job *Job, exclusive bool) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.draining {
+		return errDraining
+	}
+	if len(q.queue) >= q.size {
+		return errQueueFull
+	}
+	if exclusive {
+		if other := q.activeFor(job.Request.Target); other != nil {
+			return fmt.Errorf("%w (job %s is %s)", errTargetBusy, other.ID, other.Status)
+		}
+	}
+	q.jobs[job.ID] = job
+	q.insert(job)
+
+	if q.preempt && q.current != nil && q.runStop != nil && job.rank() > q.current.rank() {
+		fmt.Printf("[*] API job %s (%s priority) preempts job %s; it pauses at its next stage boundary\n",
+			job.ID, job.Request.Priority, q.current.ID)
+		q.stopRun()
+	}
+	return nil
+}
+
+// activeFor returns the running or a queued job for target, or nil. Callers
+// hold mu.
+func (q *jobQueue) activeFor(target string) *Job {
+	if q.current != nil && q.current.Request.Target == target {
+		return q.current
+	}
+	for _, j := range q.queue {
+		if j.Request.Target == target {
+			return j
+		}
+	}
+	return nil
+}
+
+// insert places job in run order and wakes the worker. Callers hold mu.
+func (q *jobQueue) insert(job *Job) {
+	q.queue = append(q.queue, job)
+	sort.SliceStable(q.queue, func(i, k int) bool {
+		a, b := q.queue[i], q.queue[k]
+		if a.rank() != b.rank() {
+			return a.rank() > b.rank()
+		}
+		return a.QueuedAt.Before(b.QueuedAt)
+	})
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+// next removes and returns the job to run next, or nil when there is none
+// or the queue is draining.
+func (q *jobQueue) next() *Job {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.draining || len(q.queue) == 0 {
+		return nil
+	}
+	job := q.queue[0]
+	q.queue = q.queue[1:]
+	return job
+}
+
+// depth returns how many jobs are waiting and how many the queue can hold.
+func (q *jobQueue) depth() (int, int) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.queue), q.size
+}
+
+// stopRun asks the running job to stop at its next stage boundary. Callers
+// hold mu.
+func (q *jobQueue) stopRun() {
+	if q.runStop != nil {
+		close(q.runStop)
+		q.runStop = nil
+	}
+}
+
+// restore queues scans saved by a previous shutdown, keeping their job IDs.
+// It returns the scans that did not fit in the queue.
+func (q *jobQueue) restore(saved []*models.QueuedScan) []*models.QueuedScan {
+	for i, sc := range saved {
+		job := &Job{
+			ID: sc.JobID,
+			Request: ScanRequest{
+				Target:       sc.Target,
+				Preset:       sc.Preset,
+				Stages:       sc.Stages,
+				Skip:         sc.Skip,
+				Severity:     sc.Severity,
+				Tag:          sc.Tag,
+				Priority:     sc.Priority,
+				IgnorePolicy: sc.IgnorePolicy,
+			},
+			Operator: sc.Operator,
+			Status:   JobQueued,
+			ScanID:   sc.ScanID,
+			ScanDir:  sc.ScanDir,
+			QueuedAt: sc.QueuedAt,
+		}
+		if err := q.add(job, false); err != nil {
+			return saved[i:]
+		}
+	}
+	return nil
+}
+
+// drain stops accepting jobs and asks the running scan to stop at its next
+// stage boundary. Queued jobs stay queued.
+func (q *jobQueue) drain() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if !q.draining {
+		q.draining = true
+		close(q.stop)
+		q.stopRun()
+	}
+}
+
+// state returns the worker state and the ID of the running job, if any.
+func (q *jobQueue) state() (string, string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	switch {
+	case q.stopped:
+		return workerStopped, ""
+	case q.draining && q.current != nil:
+		return workerDraining, q.current.ID
+	case q.draining:
+		return workerDraining, ""
+	case q.current != nil:
+		return workerRunning, q.current.ID
+	default:
+		return workerIdle, ""
+	}
+}
+
+// unfinished returns the jobs to run again after a restart: those still
+// queued and those interrupted part-way, which resume in their scan
+// directory. Call it once the worker has stopped.
+func (q *jobQueue) unfinished() []*models.QueuedScan {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	var out []*models.QueuedScan
+	for _, j := range q.jobs {
+		if j.Status != JobQueued && j.Status != JobInterrupted {
+			continue
+		}
+		out = append(out, &models.QueuedScan{
+			JobID:        j.ID,
+			Target:       j.Request.Target,
+			Preset:       j.Request.Preset,
+			Stages:       j.Request.Stages,
+			Skip:         j.Request.Skip,
+			Severity:     j.Request.Severity,
+			Tag:          j.Request.Tag,
+			Priority:     j.Request.Priority,
+			Operator:     j.Operator,
+			IgnorePolicy: j.Request.IgnorePolicy,
+			QueuedAt:     j.QueuedAt,
+			ScanID:       j.ScanID,
+			ScanDir:      j.ScanDir,
+		})
+	}
+	sort.Slice(out, func(i, k int) bool { return out[i].QueuedAt.Before(out[k].QueuedAt) })
+	return out
+}
+
+// get returns a copy of the job with id.
+func (q *jobQueue) get(id string) (Job, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	j, ok := q.jobs[id]
+	if !ok {
+		return Job{}, false
+	}
+	return *j, true
+}
+
+// list returns copies of all jobs, newest first.
+func (q *jobQueue) list() []Job {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	out := make([]Job, 0, len(q.jobs))
+	for _, j := range q.jobs {
+		out = append(out, *j)
+	}
+	sort.Slice(out, func(i, k int) bool { return out[i].QueuedAt.After(out[k].QueuedAt) })
+	return out
+}
+
+// update applies fn to the job under the lock. fn may also change the
+// queue's own fields.
+func (q *jobQueue) update(job *Job, fn func(*Job)) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	fn(job)
+}
+
+// work runs queued jobs with launch until drain is called or ctx is
+// cancelled.
+func (q *jobQueue) work(ctx context.Context, launch LaunchFunc) {
+	defer q.update(nil, func(*Job) { q.stopped = true })
+	for {
+		job := q.next()
+		if job == nil {
+			select {
+			case <-ctx.Done():
+				return
+			case <-q.stop:
+				return
+			case <-q.wake:
+			}
+			continue
+		}
+		q.run(ctx, job, launch)
+	}
+}
+
+func (q *jobQueue) run(ctx context.Context, job *Job, launch LaunchFunc) {
+	var started Job
+	stop := make(chan struct{})
+	q.update(job, func(j *Job) {
+		now := time.Now()
+		j.Status = JobRunning
+		j.StartedAt = &now
+		started = *j
+		q.current, q.runStop = j, stop
+		if q.draining { // drained between next and here
+			q.stopRun()
+		}
+	})
+	if started.ScanDir != "" {
+		fmt.Printf("[*] API job %s: resuming %s in %s\n", job.ID, job.Request.Target, job.ScanDir)
+	} else {
+		fmt.Printf("[*] API job %s: scanning %s for %s\n", job.ID, job.Request.Target, job.Operator)
+	}
+
+	result, err := launch(ctx, started, stop)
+
+	var status string
+	var requeued bool
+	q.update(job, func(j *Job) {
+		now := time.Now()
+		j.FinishedAt = &now
+		if result != nil {
+			j.ScanID = result.ScanID
+			j.ScanDir = result.ScanDir
+		}
+		stopped := q.runStop == nil
+		switch {
+		case stopped && (result != nil && result.Status == "interrupted" || err != nil && result == nil):
+			// Stopped at a stage boundary, or before the scan got going.
+			// Preempted jobs go back in the queue; on shutdown they are saved.
+			j.StartedAt, j.FinishedAt = nil, nil
+			switch {
+			case !q.draining:
+				j.Status, requeued = JobQueued, true
+				q.insert(j)
+			case result != nil:
+				j.Status = JobInterrupted
+			default:
+				j.Status = JobQueued
+			}
+		case err != nil:
+			j.Status = JobFailed
+			j.Error = err.Error()
+		case result.Status == "partial":
+			j.Status = JobPartial
+		default:
+			j.Status = JobComplete
+		}
+		status = j.Status
+		q.current, q.runStop = nil, nil
+	})
+
+	switch {
+	case requeued:
+		fmt.Printf("[*] API job %s paused for a higher-priority scan; it resumes when its turn comes\n", job.ID)
+	case status == JobQueued:
+		fmt.Printf("[*] API job %s stopped before it started; it runs when the server restarts\n", job.ID)
+	case status == JobInterrupted:
+		fmt.Printf("[*] API job %s interrupted; it resumes when the server restarts\n", job.ID)
+	case status == JobFailed:
+		fmt.Printf("[!] API job %s failed: %v\n", job.ID, err)
+	default:
+		fmt.Printf("[+] API job %s finished: %s\n", job.ID, result.Status)
+	}
+}
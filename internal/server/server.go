@@ -0,0 +1,276 @@
+// Package server implements 'reconpipe serve': a small JSON API over the
+// scan database that lets other systems read results and queue scans.
+// Every request must carry an API token (see GenerateToken); tokens are
+// scoped to reading or to reading and launching scans, and each token is
+// rate limited. The API can be served over TLS, optionally requiring client
+// certificates (see TLSOptions).
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/hakim/reconpipe/internal/pipeline"
+	"github.com/hakim/reconpipe/internal/storage"
+	"github.com/hakim/reconpipe/pkg/models"
+)
+
+// Defaults for zero Options fields.
+const (
+	DefaultListen    = "127.0.0.1:8080"
+	DefaultRateLimit = 60 // requests per minute per token
+	DefaultQueueSize = 16
+	DefaultGrace     = time.Minute // for a running scan to reach a stage boundary on shutdown
+)
+
+// ScanRequest is the body of POST /api/v1/scans. Empty fields take the same
+// defaults as 'reconpipe scan'.
+type ScanRequest struct {
+	Target   string   `json:"target"`
+	Preset   string   `json:"preset,omitempty"`
+	Stages   []string `json:"stages,omitempty"`
+	Skip     []string `json:"skip,omitempty"`
+	Severity string   `json:"severity,omitempty"`
+	Tag      string   `json:"tag,omitempty"`
+	Priority string   `json:"priority,omitempty"` // high, normal (default) or low
+
+	// IgnorePolicy skips the target's cooldown and one-scan-at-a-time
+	// limits, like --ignore-policy on the command line.
+	IgnorePolicy bool `json:"ignore_policy,omitempty"`
+}
+
+// LaunchFunc runs one queued scan to completion. job.Operator identifies the
+// token that requested it, and a job.ScanDir means the scan was interrupted
+// and resumes there. Scans run one at a time.
+//
+// When stop is closed the server is shutting down: the scan should end at
+// its next stage boundary (see pipeline.PipelineConfig.Stop). ctx is
+// cancelled if it has not ended within the grace period.
+type LaunchFunc func(ctx context.Context, job Job, stop <-chan struct{}) (*pipeline.PipelineResult, error)
+
+// Options configures a Server.
+type Options struct {
+	Store     storage.Store
+	Launch    LaunchFunc
+	RateLimit int // requests per minute for tokens without their own limit
+	QueueSize int
+	Tools     func() []ToolStatus   // installed tools, for /readyz; nil skips the check
+	Scope     []string              // domain patterns queued targets must match; empty allows any
+	Policy    pipeline.TargetPolicy // per-target cooldown and concurrency limits
+	Grace     time.Duration         // how long shutdown waits for a running scan
+	Preempt   bool                  // higher-priority jobs pause a running lower-priority scan
+	TLS       *TLSOptions           // nil or not Enabled serves plain HTTP
+}
+
+// Server serves the API. Create it with New.
+type Server struct {
+	store  storage.Store
+	launch LaunchFunc
+	grace  time.Duration
+	tls    *TLSOptions
+	tools  func() []ToolStatus
+
+	mu        sync.RWMutex // guards rateLimit, scope and policy, which SetLimits changes
+	rateLimit int
+	scope     []string
+	policy    pipeline.TargetPolicy
+
+	limiter   *limiter
+	jobs      *jobQueue
+	toolCache toolCache
+}
+
+// New returns a Server for opts, filling in defaults.
+func New(opts Options) *Server {
+	if opts.RateLimit <= 0 {
+		opts.RateLimit = DefaultRateLimit
+	}
+	if opts.QueueSize <= 0 {
+		opts.QueueSize = DefaultQueueSize
+	}
+	if opts.Grace <= 0 {
+		opts.Grace = DefaultGrace
+	}
+	return &Server{
+		store:     opts.Store,
+		launch:    opts.Launch,
+		grace:     opts.Grace,
+		tools:     opts.Tools,
+		rateLimit: opts.RateLimit,
+		scope:     opts.Scope,
+		policy:    opts.Policy,
+		tls:       opts.TLS,
+		limiter:   newLimiter(),
+		jobs:      newJobQueue(opts.QueueSize, opts.Preempt),
+	}
+}
+
+// SetLimits replaces the default per-token rate limit, the scan scope and
+// the target policy while the server runs, as when its config is reloaded.
+// A rateLimit of zero restores DefaultRateLimit. Scans already queued keep
+// running.
+func (s *Server) SetLimits(rateLimit int, scope []string, policy pipeline.TargetPolicy) {
+	if rateLimit <= 0 {
+		rateLimit = DefaultRateLimit
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rateLimit = rateLimit
+	s.scope = scope
+	s.policy = policy
+}
+
+// limits returns the current default rate limit, scan scope and target
+// policy.
+func (s *Server) limits() (int, []string, pipeline.TargetPolicy) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.rateLimit, s.scope, s.policy
+}
+
+// Handler returns the API's routes, all behind token authentication, the
+// web UI, whose static files are public but which signs in with a token too,
+// and the unauthenticated /healthz and /readyz probes.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	read := func(h http.HandlerFunc) http.Handler { return s.authenticate(models.TokenScopeRead, h) }
+	scan := func(h http.HandlerFunc) http.Handler { return s.authenticate(models.TokenScopeScan, h) }
+
+	mux.Handle("GET /api/v1/targets", read(s.handleTargets))
+	mux.Handle("GET /api/v1/scans", read(s.handleListScans))
+	mux.Handle("GET /api/v1/scans/{id}", read(s.handleGetScan))
+	mux.Handle("GET /api/v1/scans/{id}/results", read(s.handleScanResults))
+	mux.Handle("GET /api/v1/scans/{id}/diff", read(s.handleScanDiff))
+	mux.Handle("GET /api/v1/scans/{id}/reports", read(s.handleListReports))
+	mux.Handle("GET /api/v1/scans/{id}/reports/{name}", read(s.handleReportFile))
+	mux.Handle("GET /api/v1/scans/{id}/screenshots/{name}", read(s.handleScreenshot))
+	mux.Handle("POST /api/v1/scans", scan(s.handleCreateScan))
+	mux.Handle("GET /api/v1/jobs", read(s.handleListJobs))
+	mux.Handle("GET /api/v1/jobs/{id}", read(s.handleGetJob))
+	mux.HandleFunc("GET /healthz", s.handleHealthz)
+	mux.HandleFunc("GET /readyz", s.handleReadyz)
+	mux.Handle("GET /api/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeError(w, http.StatusNotFound, "no such endpoint")
+	}))
+	mux.Handle("GET /", uiHandler())
+	return mux
+}
+
+// Run serves on addr until ctx is cancelled, running queued scans in the
+// background. Scans left unfinished by the previous shutdown are queued
+// again first.
+//
+// On shutdown new scans are refused while the API keeps answering; the
+// running scan stops at its next stage boundary, or is cancelled after the
+// grace period. The queued and interrupted scans are then saved to the
+// database, and in-flight requests get a few seconds to finish.
+func (s *Server) Run(ctx context.Context, addr string) error {
+	if addr == "" {
+		addr = DefaultListen
+	}
+	var tlsConf *tls.Config
+	if s.tls.Enabled() {
+		var err error
+		if tlsConf, err = s.tls.Config(addr); err != nil {
+			return err
+		}
+	}
+
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("listening on %s: %w", addr, err)
+	}
+	scheme := "http"
+	if tlsConf != nil {
+		ln = tls.NewListener(ln, tlsConf)
+		scheme = "https"
+	}
+
+	srv := &http.Server{
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	if err := s.restoreQueue(); err != nil {
+		fmt.Printf("[!] Warning: could not restore saved scan queue: %v\n", err)
+	}
+
+	// The worker outlives ctx so the running scan can reach a stage boundary
+	workerCtx, stopWorker := context.WithCancel(context.WithoutCancel(ctx))
+	defer stopWorker()
+	workerDone := make(chan struct{})
+	go func() {
+		defer close(workerDone)
+		s.jobs.work(workerCtx, s.launch)
+	}()
+
+	errc := make(chan error, 1)
+	go func() { errc <- srv.Serve(ln) }()
+	fmt.Printf("[+] API listening on %s://%s\n", scheme, ln.Addr())
+	if tlsConf != nil {
+		fmt.Printf("[*] Certificate SHA-256 fingerprint: %s\n", fingerprint(tlsConf.Certificates[0]))
+		if tlsConf.ClientAuth == tls.RequireAndVerifyClientCert {
+			fmt.Println("[*] Mutual TLS: client certificates are required")
+		}
+	}
+
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+	}
+
+	fmt.Println("[*] Shutting down: no longer accepting scans")
+	s.jobs.drain()
+	select {
+	case <-workerDone:
+	case <-time.After(s.grace):
+		fmt.Printf("[!] Running scan did not stop within %s; cancelling it\n", s.grace)
+		stopWorker()
+		<-workerDone
+	}
+	if err := s.saveQueue(); err != nil {
+		fmt.Printf("[!] Warning: could not save the scan queue: %v\n", err)
+	}
+
+	fmt.Println("[*] Shutting down API server")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// restoreQueue queues the scans saved by the last shutdown.
+func (s *Server) restoreQueue() error {
+	saved, err := s.store.TakeQueuedScans()
+	if err != nil || len(saved) == 0 {
+		return err
+	}
+	dropped := s.jobs.restore(saved)
+	fmt.Printf("[*] Restored %d scan(s) queued before the last shutdown\n", len(saved)-len(dropped))
+	for _, sc := range dropped {
+		fmt.Printf("[!] Dropped saved scan of %s (job %s): the queue is full\n", sc.Target, sc.JobID)
+	}
+	return nil
+}
+
+// saveQueue saves the scans that have yet to run or finish for the next
+// start.
+func (s *Server) saveQueue() error {
+	unfinished := s.jobs.unfinished()
+	if err := s.store.SaveQueuedScans(unfinished); err != nil {
+		return err
+	}
+	if len(unfinished) > 0 {
+		fmt.Printf("[*] Saved %d unfinished scan(s); they run when the server starts again\n", len(unfinished))
+	}
+	return nil
+}
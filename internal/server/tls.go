@@ -0,0 +1,195 @@
+package server
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"strings"
+	"time"
+)
+
+// selfSignedValidity is how long a generated certificate is valid.
+const selfSignedValidity = 365 * 24 * time.Hour
+
+// TLSOptions configures HTTPS for the API. TLS is on when CertFile is set or
+// SelfSigned is true.
+type TLSOptions struct {
+	CertFile string
+	KeyFile  string
+
+	// SelfSigned generates a certificate when CertFile doesn't exist yet. If
+	// CertFile and KeyFile are set, the certificate is saved there and reused
+	// on later starts (until it expires), so clients can pin it.
+	SelfSigned bool
+	Hosts      []string // extra DNS names and IPs for a generated certificate
+
+	// ClientCAFile enables mutual TLS: clients must present a certificate
+	// signed by one of the CAs in this PEM file.
+	ClientCAFile string
+}
+
+// Enabled reports whether the options turn TLS on.
+func (o *TLSOptions) Enabled() bool {
+	return o != nil && (o.CertFile != "" || o.SelfSigned)
+}
+
+// Config builds the server TLS configuration. listenAddr supplies a host name
+// for generated certificates.
+func (o *TLSOptions) Config(listenAddr string) (*tls.Config, error) {
+	cert, err := o.certificate(listenAddr)
+	if err != nil {
+		return nil, err
+	}
+
+	conf := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+
+	if o.ClientCAFile != "" {
+		data, err := os.ReadFile(o.ClientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("reading client CA: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("client CA %s: no PEM certificates found", o.ClientCAFile)
+		}
+		conf.ClientCAs = pool
+		conf.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	return conf, nil
+}
+
+// certificate loads the configured key pair, or generates one for SelfSigned.
+func (o *TLSOptions) certificate(listenAddr string) (tls.Certificate, error) {
+	if !o.SelfSigned {
+		cert, err := tls.LoadX509KeyPair(o.CertFile, o.KeyFile)
+		if err != nil {
+			return tls.Certificate{}, fmt.Errorf("loading TLS certificate: %w", err)
+		}
+		return cert, nil
+	}
+
+	if o.CertFile != "" {
+		if cert, err := tls.LoadX509KeyPair(o.CertFile, o.KeyFile); err == nil {
+			if cert.Leaf != nil && time.Now().Before(cert.Leaf.NotAfter) {
+				return cert, nil
+			}
+			fmt.Printf("[*] Self-signed certificate %s has expired; generating a new one\n", o.CertFile)
+		} else if !errors.Is(err, os.ErrNotExist) {
+			return tls.Certificate{}, fmt.Errorf("loading TLS certificate: %w", err)
+		}
+	}
+
+	certPEM, keyPEM, err := generateSelfSigned(selfSignedHosts(listenAddr, o.Hosts))
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	if o.CertFile != "" {
+		if err := os.WriteFile(o.CertFile, certPEM, 0644); err != nil {
+			return tls.Certificate{}, fmt.Errorf("saving certificate: %w", err)
+		}
+		if err := os.WriteFile(o.KeyFile, keyPEM, 0600); err != nil {
+			return tls.Certificate{}, fmt.Errorf("saving private key: %w", err)
+		}
+		fmt.Printf("[+] Generated self-signed certificate %s\n", o.CertFile)
+	}
+	return tls.X509KeyPair(certPEM, keyPEM)
+}
+
+// selfSignedHosts lists the names a generated certificate is valid for: the
+// listen host (or this machine's names when listening on all interfaces)
+// plus any configured extras.
+func selfSignedHosts(listenAddr string, extra []string) []string {
+	host, _, err := net.SplitHostPort(listenAddr)
+	if err != nil {
+		host = listenAddr
+	}
+
+	hosts := []string{"localhost", "127.0.0.1", "::1"}
+	if host == "" || host == "0.0.0.0" || host == "::" {
+		if name, err := os.Hostname(); err == nil {
+			hosts = append(hosts, name)
+		}
+	} else {
+		hosts = append(hosts, host)
+	}
+	return append(hosts, extra...)
+}
+
+// generateSelfSigned creates an ECDSA P-256 certificate for hosts and returns
+// it and its key in PEM form.
+func generateSelfSigned(hosts []string) (certPEM, keyPEM []byte, err error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, fmt.Errorf("generating key: %w", err)
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, nil, fmt.Errorf("generating serial number: %w", err)
+	}
+
+	now := time.Now()
+	tmpl := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{Organization: []string{"reconpipe"}, CommonName: "reconpipe serve"},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(selfSignedValidity),
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+	}
+	seen := map[string]bool{}
+	for _, h := range hosts {
+		h = strings.TrimSpace(h)
+		if h == "" || seen[h] {
+			continue
+		}
+		seen[h] = true
+		if ip := net.ParseIP(h); ip != nil {
+			tmpl.IPAddresses = append(tmpl.IPAddresses, ip)
+		} else {
+			tmpl.DNSNames = append(tmpl.DNSNames, h)
+		}
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("creating certificate: %w", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("encoding private key: %w", err)
+	}
+
+	certPEM = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM = pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	return certPEM, keyPEM, nil
+}
+
+// fingerprint is the SHA-256 fingerprint of a certificate, as shown by
+// 'openssl x509 -fingerprint -sha256'.
+func fingerprint(cert tls.Certificate) string {
+	if len(cert.Certificate) == 0 {
+		return ""
+	}
+	sum := sha256.Sum256(cert.Certificate[0])
+	parts := make([]string, len(sum))
+	for i, b := range sum {
+		parts[i] = strings.ToUpper(hex.EncodeToString([]byte{b}))
+	}
+	return strings.Join(parts, ":")
+}
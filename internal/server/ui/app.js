@@ -0,0 +1,447 @@
+// ReconPipe web UI. Plain DOM code with no dependencies: every value from the
+// API is inserted as text, never as HTML, because scan results contain
+// attacker-controlled strings (page titles, DNS records, template output).
+"use strict";
+
+const TOKEN_KEY = "reconpipe.token";
+const app = document.getElementById("app");
+const crumbs = document.getElementById("crumbs");
+const signout = document.getElementById("signout");
+
+// ── Helpers ─────────────────────────────────────────────────────────────────
+
+// h builds an element. attrs may hold class, href, on<event> handlers and
+// plain attributes; children are nodes or strings (added as text).
+function h(tag, attrs, ...children) {
+  const el = document.createElement(tag);
+  for (const [k, v] of Object.entries(attrs || {})) {
+    if (v === undefined || v === null || v === false) continue;
+    if (k === "class") el.className = v;
+    else if (k.startsWith("on")) el.addEventListener(k.slice(2), v);
+    else el.setAttribute(k, v);
+  }
+  for (const c of children.flat()) {
+    if (c === undefined || c === null || c === false) continue;
+    el.append(c instanceof Node ? c : String(c));
+  }
+  return el;
+}
+
+function fmtTime(s) {
+  if (!s) return "-";
+  const d = new Date(s);
+  return isNaN(d) ? s : d.toISOString().slice(0, 16).replace("T", " ");
+}
+
+function shortID(id) {
+  return id && id.length > 8 ? id.slice(0, 8) : id;
+}
+
+function badge(text, cls) {
+  return h("span", { class: "badge " + (cls || "") }, text);
+}
+
+function table(headers, rows) {
+  if (!rows.length) return h("p", { class: "muted" }, "None.");
+  return h("table", {},
+    h("thead", {}, h("tr", {}, headers.map((x) => h("th", {}, x)))),
+    h("tbody", {}, rows));
+}
+
+function show(...nodes) {
+  app.replaceChildren(...nodes);
+}
+
+function setCrumbs(...parts) {
+  const nodes = [];
+  parts.forEach((p, i) => {
+    if (i) nodes.push(" / ");
+    nodes.push(p.href ? h("a", { href: p.href }, p.text) : p.text);
+  });
+  crumbs.replaceChildren(...nodes);
+}
+
+// ── API ─────────────────────────────────────────────────────────────────────
+
+class AuthError extends Error {}
+
+async function api(path, as) {
+  const res = await fetch("/api/v1" + path, {
+    headers: { Authorization: "Bearer " + localStorage.getItem(TOKEN_KEY) },
+  });
+  if (res.status === 401) throw new AuthError("token rejected");
+  if (!res.ok) {
+    let msg = res.statusText;
+    try { msg = (await res.json()).error || msg; } catch (_) { /* not JSON */ }
+    throw new Error(res.status + ": " + msg);
+  }
+  if (as === "blob") return res.blob();
+  if (as === "text") return res.text();
+  return res.json();
+}
+
+// screenshot returns an <img> whose data is fetched with the token, since
+// image requests can't carry an Authorization header.
+function screenshot(scanID, path, alt) {
+  const name = path.split(/[\\/]/).pop();
+  const img = h("img", { alt: alt || name, loading: "lazy" });
+  api("/scans/" + encodeURIComponent(scanID) + "/screenshots/" + encodeURIComponent(name), "blob")
+    .then((b) => { img.src = URL.createObjectURL(b); })
+    .catch(() => { img.alt = "screenshot unavailable"; });
+  return img;
+}
+
+// ── Views ───────────────────────────────────────────────────────────────────
+
+function signIn(message) {
+  signout.hidden = true;
+  setCrumbs();
+  const form = document.getElementById("signin").content.firstElementChild.cloneNode(true);
+  const err = form.querySelector(".error");
+  if (message) { err.textContent = message; err.hidden = false; }
+  form.addEventListener("submit", (e) => {
+    e.preventDefault();
+    localStorage.setItem(TOKEN_KEY, form.token.value.trim());
+    route();
+  });
+  show(form);
+  form.token.focus();
+}
+
+async function targetsView() {
+  setCrumbs({ text: "Targets" });
+  const [{ targets }, { scans }] = await Promise.all([api("/targets"), api("/scans")]);
+  const latest = {};
+  for (const s of scans) if (!latest[s.target]) latest[s.target] = s;
+
+  const rows = targets.map((t) => {
+    const s = latest[t] || {};
+    const count = scans.filter((x) => x.target === t).length;
+    return h("tr", { class: "clickable", onclick: () => { location.hash = "#/target/" + encodeURIComponent(t); } },
+      h("td", {}, h("a", { href: "#/target/" + encodeURIComponent(t) }, t)),
+      h("td", {}, count),
+      h("td", {}, fmtTime(s.started_at)),
+      h("td", {}, s.status ? badge(s.status, "status-" + s.status) : "-"));
+  });
+  show(h("h1", {}, "Targets"), table(["Target", "Scans", "Last scan", "Status"], rows));
+}
+
+async function targetView(target) {
+  setCrumbs({ text: "Targets", href: "#/" }, { text: target });
+  const { scans } = await api("/scans?target=" + encodeURIComponent(target));
+  const rows = scans.map((s) =>
+    h("tr", { class: "clickable", onclick: () => { location.hash = "#/scan/" + s.id; } },
+      h("td", {}, h("a", { href: "#/scan/" + s.id }, h("code", {}, shortID(s.id)))),
+      h("td", {}, fmtTime(s.started_at)),
+      h("td", {}, s.operator || "-"),
+      h("td", {}, badge(s.status, "status-" + s.status)),
+      h("td", {}, (s.stages_run || []).join(", ") || "-"),
+      h("td", {}, (s.run_config && (s.run_config.tag || s.run_config.preset)) || "")));
+  show(h("h1", {}, "Scan history for " + target),
+    table(["Scan", "Started", "Operator", "Status", "Stages", "Preset / tag"], rows));
+}
+
+async function scanView(id, tab) {
+  const [results, d] = await Promise.all([
+    api("/scans/" + encodeURIComponent(id) + "/results"),
+    api("/scans/" + encodeURIComponent(id) + "/diff"),
+  ]);
+  const scan = results.scan;
+  normalizeDiff(d);
+  setCrumbs({ text: "Targets", href: "#/" },
+    { text: scan.target, href: "#/target/" + encodeURIComponent(scan.target) },
+    { text: shortID(scan.id) });
+
+  const tabs = [
+    ["changes", "Changes"],
+    ["subdomains", "Subdomains"],
+    ["ports", "Ports"],
+    ["http", "HTTP"],
+    ["vulns", "Vulnerabilities"],
+    ["screenshots", "Screenshots"],
+    ["reports", "Reports"],
+  ];
+  tab = tab || "changes";
+  const body = h("div");
+  const bar = h("div", { class: "tabs" }, tabs.map(([key, label]) =>
+    h("button", {
+      class: key === tab ? "active" : "",
+      onclick: () => { location.hash = "#/scan/" + scan.id + "/" + key; },
+    }, label)));
+
+  show(
+    h("h1", {}, scan.target, " ", badge(scan.status, "status-" + scan.status)),
+    h("p", { class: "muted" },
+      "Started " + fmtTime(scan.started_at),
+      scan.operator ? " by " + scan.operator : "",
+      " · stages: " + ((scan.stages_run || []).join(", ") || "none"),
+      d.previous ? " · compared with the scan of " + fmtTime(d.previous.started_at) : " · no earlier scan to compare with"),
+    summaryCards(results, d.diff),
+    bar,
+    body);
+
+  const render = {
+    changes: () => changesTab(d),
+    subdomains: () => subdomainsTab(results, d.diff),
+    ports: () => portsTab(results, d.diff),
+    http: () => httpTab(results),
+    vulns: () => vulnsTab(results, d.diff),
+    screenshots: () => screenshotsTab(results),
+    reports: () => reportsTab(scan),
+  }[tab];
+  body.append(await (render ? render() : changesTab(d)));
+}
+
+// normalizeDiff replaces null lists (Go nil slices) with empty ones. A scan
+// with no predecessor is shown without changes rather than as all-new.
+function normalizeDiff(d) {
+  for (const k of ["NewSubdomains", "RemovedSubdomains", "NewPorts", "ClosedPorts", "NewVulns",
+    "ResolvedVulns", "NewlyDangling", "PersistentlyDangling", "ResolvedDangling"]) {
+    d.diff[k] = (d.previous && d.diff[k]) || [];
+  }
+}
+
+function summaryCards(r, diff) {
+  const ports = r.hosts.reduce((n, x) => n + (x.ports || []).length, 0);
+  const card = (label, value, added, removed) => h("div", { class: "card" },
+    h("div", { class: "muted" }, label),
+    h("div", { class: "value" }, value),
+    added !== undefined && h("div", {},
+      h("span", { class: "delta up" }, "+" + added), " ",
+      h("span", { class: "delta down" }, "−" + removed)));
+  return h("div", { class: "cards" },
+    card("Subdomains", r.subdomains.length, diff.NewSubdomains.length, diff.RemovedSubdomains.length),
+    card("Open ports", ports, diff.NewPorts.length, diff.ClosedPorts.length),
+    card("HTTP services", r.http_probes.length),
+    card("Vulnerabilities", r.vulnerabilities.length, diff.NewVulns.length, diff.ResolvedVulns.length),
+    card("Dangling DNS", r.subdomains.filter((s) => s.is_dangling).length, diff.NewlyDangling.length, diff.ResolvedDangling.length));
+}
+
+function changesTab(d) {
+  if (!d.previous) return h("p", { class: "muted" }, "This is the first scan of " + d.scan.target + ", so there is nothing to compare with yet.");
+  const diff = d.diff;
+  const sub = (list, cls, label) => list.map((s) =>
+    h("tr", { class: cls }, h("td", {}, badge(label, cls)), h("td", {}, s.name), h("td", {}, (s.ips || []).join(", "))));
+  const port = (list, cls, label) => list.map((c) =>
+    h("tr", { class: cls }, h("td", {}, badge(label, cls)), h("td", {}, c.Host || c.IP),
+      h("td", {}, c.Port.number + "/" + c.Port.protocol), h("td", {}, c.Port.service || "")));
+  const vuln = (list, cls, label) => list.map((v) =>
+    h("tr", { class: cls }, h("td", {}, badge(label, cls)), h("td", {}, badge(v.severity, v.severity)),
+      h("td", {}, v.name || v.template_id), h("td", {}, v.matched_at || v.host)));
+
+  return h("div", {},
+    h("h2", {}, "Vulnerabilities"),
+    table(["", "Severity", "Finding", "Where"],
+      [...vuln(diff.NewVulns, "new", "new"), ...vuln(diff.ResolvedVulns, "gone", "resolved")]),
+    h("h2", {}, "Dangling DNS"),
+    table(["", "Subdomain", "IPs"],
+      [...sub(diff.NewlyDangling, "new", "new"), ...sub(diff.ResolvedDangling, "gone", "resolved")]),
+    h("h2", {}, "Subdomains"),
+    table(["", "Subdomain", "IPs"],
+      [...sub(diff.NewSubdomains, "new", "new"), ...sub(diff.RemovedSubdomains, "gone", "removed")]),
+    h("h2", {}, "Ports"),
+    table(["", "Host", "Port", "Service"],
+      [...port(diff.NewPorts, "new", "opened"), ...port(diff.ClosedPorts, "gone", "closed")]));
+}
+
+function subdomainsTab(r, diff) {
+  const isNew = new Set(diff.NewSubdomains.map((s) => s.name));
+  const rows = r.subdomains.map((s) => h("tr", { class: isNew.has(s.name) ? "new" : "" },
+    h("td", {}, s.name),
+    h("td", {}, (s.ips || []).join(", ") || "-"),
+    h("td", {}, s.source),
+    h("td", {}, s.is_cdn ? s.cdn_provider || "yes" : ""),
+    h("td", {}, s.is_dangling ? badge("dangling", "high") : "")));
+  const gone = diff.RemovedSubdomains.map((s) => h("tr", { class: "gone" },
+    h("td", {}, s.name), h("td", {}, (s.ips || []).join(", ")), h("td", {}, s.source), h("td"), h("td")));
+  return table(["Subdomain", "IPs", "Source", "CDN", ""], [...rows, ...gone]);
+}
+
+function portsTab(r, diff) {
+  const key = (ip, p) => ip + "|" + p.number + "/" + p.protocol;
+  const isNew = new Set(diff.NewPorts.map((c) => key(c.IP, c.Port)));
+  const rows = [];
+  for (const host of r.hosts) {
+    for (const p of host.ports || []) {
+      rows.push(h("tr", { class: isNew.has(key(host.ip, p)) ? "new" : "" },
+        h("td", {}, host.ip),
+        h("td", {}, (host.subdomains || []).join(", ")),
+        h("td", {}, p.number + "/" + p.protocol),
+        h("td", {}, p.service || ""),
+        h("td", {}, p.version || "")));
+    }
+  }
+  for (const c of diff.ClosedPorts) {
+    rows.push(h("tr", { class: "gone" }, h("td", {}, c.IP), h("td", {}, c.Host),
+      h("td", {}, c.Port.number + "/" + c.Port.protocol), h("td", {}, c.Port.service || ""), h("td")));
+  }
+  return table(["IP", "Hostnames", "Port", "Service", "Version"], rows);
+}
+
+function httpTab(r) {
+  const rows = r.http_probes.map((p) => h("tr", {},
+    h("td", {}, p.url),
+    h("td", {}, p.status_code),
+    h("td", {}, p.title || ""),
+    h("td", {}, p.webserver || ""),
+    h("td", {}, (p.technologies || []).join(", "))));
+  return table(["URL", "Status", "Title", "Server", "Technologies"], rows);
+}
+
+function vulnsTab(r, diff) {
+  const order = { critical: 0, high: 1, medium: 2, low: 3, info: 4 };
+  const key = (v) => [v.template_id, v.host, v.port, v.matched_at].join("|");
+  const isNew = new Set(diff.NewVulns.map(key));
+  const vulns = [...r.vulnerabilities].sort((a, b) => (order[a.severity] ?? 9) - (order[b.severity] ?? 9));
+  const rows = vulns.map((v) => h("tr", { class: isNew.has(key(v)) ? "new" : "" },
+    h("td", {}, badge(v.severity, v.severity)),
+    h("td", {}, v.name || v.template_id, h("div", { class: "muted" }, v.template_id)),
+    h("td", {}, v.matched_at || v.url || v.host),
+    h("td", {}, v.description || "")));
+  return table(["Severity", "Finding", "Matched at", "Description"], rows);
+}
+
+function screenshotsTab(r) {
+  const shots = r.http_probes.filter((p) => p.screenshot_path);
+  if (!shots.length) return h("p", { class: "muted" }, "No screenshots in this scan.");
+  return h("div", { class: "shots" }, shots.map((p) => h("div", { class: "shot" },
+    screenshot(r.scan.id, p.screenshot_path, p.url),
+    h("div", { class: "caption" }, h("strong", {}, p.status_code), " ", p.url, h("div", { class: "muted" }, p.title || "")))));
+}
+
+async function reportsTab(scan) {
+  const { reports } = await api("/scans/" + encodeURIComponent(scan.id) + "/reports");
+  if (!reports.length) return h("p", { class: "muted" }, "No reports in this scan.");
+
+  const view = h("div");
+  const list = h("div", { class: "report-list" });
+  const open = async (name, button) => {
+    for (const b of list.children) b.classList.toggle("active", b === button);
+    const path = "/scans/" + encodeURIComponent(scan.id) + "/reports/" + encodeURIComponent(name);
+    if (!name.toLowerCase().endsWith(".md")) {
+      const blob = await api(path, "blob");
+      view.replaceChildren(h("p", {}, h("a", { href: URL.createObjectURL(blob), download: name }, "Download " + name)));
+      return;
+    }
+    view.replaceChildren(renderMarkdown(await api(path, "text"), scan.id));
+  };
+  for (const r of reports) {
+    const b = h("button", { onclick: () => open(r.name, b) }, r.name);
+    list.append(b);
+  }
+  const first = reports.find((r) => r.name.endsWith(".md")) || reports[0];
+  open(first.name, [...list.children][reports.indexOf(first)]);
+  return h("div", {}, list, view);
+}
+
+// ── Markdown ────────────────────────────────────────────────────────────────
+
+// renderMarkdown handles the subset the report generators write: headings,
+// tables, lists, code blocks, quotes, rules, emphasis, links and images.
+// Images pointing into the screenshots directory are loaded through the API.
+function renderMarkdown(src, scanID) {
+  const root = h("div", { class: "markdown" });
+  const lines = src.replace(/\r\n/g, "\n").split("\n");
+  let i = 0;
+  const isTableRow = (l) => /^\s*\|.*\|\s*$/.test(l);
+  const cells = (l) => l.trim().replace(/^\||\|$/g, "").split("|").map((c) => c.trim());
+
+  while (i < lines.length) {
+    const line = lines[i];
+    let m;
+    if (/^```/.test(line)) {
+      const code = [];
+      for (i++; i < lines.length && !/^```/.test(lines[i]); i++) code.push(lines[i]);
+      i++;
+      root.append(h("pre", {}, h("code", {}, code.join("\n"))));
+    } else if ((m = /^(#{1,6})\s+(.*)$/.exec(line))) {
+      root.append(h("h" + m[1].length, {}, inline(m[2], scanID)));
+      i++;
+    } else if (/^\s*(-{3,}|\*{3,})\s*$/.test(line)) {
+      root.append(h("hr"));
+      i++;
+    } else if (isTableRow(line)) {
+      const rows = [];
+      for (; i < lines.length && isTableRow(lines[i]); i++) rows.push(lines[i]);
+      const body = rows.filter((r, n) => !(n === 1 && /^[\s|:-]+$/.test(r)));
+      root.append(h("table", {},
+        h("thead", {}, h("tr", {}, cells(body[0]).map((c) => h("th", {}, inline(c, scanID))))),
+        h("tbody", {}, body.slice(1).map((r) => h("tr", {}, cells(r).map((c) => h("td", {}, inline(c, scanID))))))));
+    } else if (/^\s*([-*+]|\d+\.)\s+/.test(line)) {
+      const ordered = /^\s*\d+\./.test(line);
+      const list = h(ordered ? "ol" : "ul");
+      for (; i < lines.length && /^\s*([-*+]|\d+\.)\s+/.test(lines[i]); i++) {
+        list.append(h("li", {}, inline(lines[i].replace(/^\s*([-*+]|\d+\.)\s+/, ""), scanID)));
+      }
+      root.append(list);
+    } else if (/^>\s?/.test(line)) {
+      const quote = [];
+      for (; i < lines.length && /^>\s?/.test(lines[i]); i++) quote.push(lines[i].replace(/^>\s?/, ""));
+      root.append(h("blockquote", {}, inline(quote.join(" "), scanID)));
+    } else if (line.trim() === "") {
+      i++;
+    } else {
+      const para = [];
+      for (; i < lines.length && lines[i].trim() !== "" && !/^(#|```|>|\s*\|)/.test(lines[i]); i++) para.push(lines[i]);
+      if (!para.length) para.push(lines[i++]);
+      root.append(h("p", {}, inline(para.join(" "), scanID)));
+    }
+  }
+  return root;
+}
+
+// inline converts code spans, images, links, bold and italics to nodes.
+function inline(text, scanID) {
+  const out = [];
+  const re = /(`[^`]+`)|!\[([^\]]*)\]\(([^)\s]+)\)|\[([^\]]+)\]\(([^)\s]+)\)|\*\*([^*]+)\*\*|(?<![\w*])\*([^*\s][^*]*)\*(?![\w*])/g;
+  let last = 0;
+  let m;
+  while ((m = re.exec(text))) {
+    if (m.index > last) out.push(text.slice(last, m.index));
+    if (m[1]) out.push(h("code", {}, m[1].slice(1, -1)));
+    else if (m[3] !== undefined) out.push(mdImage(m[2], m[3], scanID));
+    else if (m[5] !== undefined) out.push(mdLink(m[4], m[5]));
+    else if (m[6] !== undefined) out.push(h("strong", {}, m[6]));
+    else out.push(h("em", {}, m[7]));
+    last = re.lastIndex;
+  }
+  if (last < text.length) out.push(text.slice(last));
+  return out;
+}
+
+function mdLink(text, href) {
+  if (/^https?:\/\//i.test(href)) return h("a", { href, target: "_blank", rel: "noopener noreferrer" }, text);
+  return text; // relative links point at files the UI doesn't serve directly
+}
+
+function mdImage(alt, src, scanID) {
+  if (/^(\.\.\/)?[^/]*screenshots?\//.test(src) || !/[/:]/.test(src)) return screenshot(scanID, src, alt);
+  return h("span", { class: "muted" }, "[image: " + alt + "]");
+}
+
+// ── Router ──────────────────────────────────────────────────────────────────
+
+async function route() {
+  if (!localStorage.getItem(TOKEN_KEY)) return signIn();
+  signout.hidden = false;
+
+  const parts = location.hash.replace(/^#\/?/, "").split("/").map(decodeURIComponent);
+  try {
+    if (parts[0] === "target" && parts[1]) await targetView(parts[1]);
+    else if (parts[0] === "scan" && parts[1]) await scanView(parts[1], parts[2]);
+    else await targetsView();
+  } catch (e) {
+    if (e instanceof AuthError) {
+      localStorage.removeItem(TOKEN_KEY);
+      return signIn("That token was rejected. It may have been revoked.");
+    }
+    show(h("p", { class: "error" }, "Error: " + e.message));
+  }
+}
+
+signout.addEventListener("click", () => {
+  localStorage.removeItem(TOKEN_KEY);
+  signIn();
+});
+window.addEventListener("hashchange", route);
+route();
@@ -0,0 +1,296 @@
+// Package signing produces and checks detached signatures for scan output,
+// so a change report can be shown to be exactly what reconpipe wrote. Two
+// methods are supported: gpg, which shells out to the gpg binary, and
+// ed25519, which needs no external tool and reads PEM keys such as those made
+// by "openssl genpkey -algorithm ed25519".
+package signing
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/hakim/reconpipe/internal/tools"
+)
+
+// Signing methods.
+const (
+	MethodGPG     = "gpg"
+	MethodEd25519 = "ed25519"
+)
+
+// ErrNoSignature is returned by Verify when the file has no signature next
+// to it.
+var ErrNoSignature = errors.New("no signature")
+
+// Signer signs and verifies files with one key.
+type Signer struct {
+	Method string
+
+	// Key is the gpg key to sign with (ID, fingerprint or email; empty uses
+	// gpg's default key), or the path of the ed25519 PEM private key.
+	Key string
+
+	// PublicKey is the path of the ed25519 PEM public key used to verify.
+	// When empty it is derived from Key.
+	PublicKey string
+
+	// GPGPath overrides the gpg binary.
+	GPGPath string
+
+	priv ed25519.PrivateKey
+	pub  ed25519.PublicKey
+}
+
+// New checks the settings and, for ed25519, loads the keys. A signer with
+// only a public key can verify but not sign.
+func New(method, key, publicKey, gpgPath string) (*Signer, error) {
+	s := &Signer{Method: method, Key: key, PublicKey: publicKey, GPGPath: gpgPath}
+	switch method {
+	case MethodGPG:
+		return s, nil
+	case MethodEd25519:
+	default:
+		return nil, fmt.Errorf("unknown signing method %q (want gpg or ed25519)", method)
+	}
+
+	if key == "" && publicKey == "" {
+		return nil, errors.New("ed25519 signing needs key or public_key")
+	}
+	if key != "" {
+		priv, err := readPrivateKey(key)
+		if err != nil {
+			return nil, err
+		}
+		s.priv = priv
+		s.pub = priv.Public().(ed25519.PublicKey)
+	}
+	if publicKey != "" {
+		pub, err := readPublicKey(publicKey)
+		if err != nil {
+			return nil, err
+		}
+		if s.priv != nil && !pub.Equal(s.pub) {
+			return nil, fmt.Errorf("%s is not the public half of %s", publicKey, key)
+		}
+		s.pub = pub
+	}
+	return s, nil
+}
+
+// SignaturePath is where the detached signature for path is kept: path.asc
+// for gpg (ASCII-armored) and path.sig for ed25519.
+func (s *Signer) SignaturePath(path string) string {
+	if s.Method == MethodGPG {
+		return path + ".asc"
+	}
+	return path + ".sig"
+}
+
+// Sign writes a detached signature for path and returns its location.
+func (s *Signer) Sign(ctx context.Context, path string) (string, error) {
+	sigPath := s.SignaturePath(path)
+	if s.Method == MethodGPG {
+		args := []string{"--batch", "--yes", "--armor", "--detach-sign", "--output", sigPath}
+		if s.Key != "" {
+			args = append(args, "--local-user", s.Key)
+		}
+		if res, err := tools.RunTool(ctx, s.gpg(), append(args, path)...); err != nil {
+			return "", fmt.Errorf("gpg signing %s: %w%s", path, err, stderrSuffix(res))
+		}
+		return sigPath, nil
+	}
+
+	if s.priv == nil {
+		return "", errors.New("ed25519 signing needs the private key (key)")
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	sig := ed25519.Sign(s.priv, data)
+	content := fmt.Sprintf("reconpipe-signature v1\nalgorithm: ed25519\nkey: %s\nfile: %s\nsignature: %s\n",
+		fingerprint(s.pub), filepath.Base(path), base64.StdEncoding.EncodeToString(sig))
+	if err := os.WriteFile(sigPath, []byte(content), 0644); err != nil {
+		return "", fmt.Errorf("writing signature: %w", err)
+	}
+	return sigPath, nil
+}
+
+// Verify checks the detached signature next to path. It returns a short
+// description of the signer on success, ErrNoSignature when there is no
+// signature file, and an error describing the mismatch otherwise.
+// The file name recorded in the signature is not checked, so a signed
+// report still verifies after it is copied or renamed.
+func (s *Signer) Verify(ctx context.Context, path string) (string, error) {
+	return s.verify(ctx, path, "")
+}
+
+// VerifyNamed is Verify that also requires an ed25519 signature to have
+// been made for a file called name, such as the release asset a download
+// was saved from. The name is not covered by the signature itself, so this
+// only catches a signature paired with the wrong file; gpg signatures carry
+// no name and are not checked for one.
+func (s *Signer) VerifyNamed(ctx context.Context, path, name string) (string, error) {
+	return s.verify(ctx, path, name)
+}
+
+// verify implements Verify and VerifyNamed; an empty name is not checked.
+func (s *Signer) verify(ctx context.Context, path, name string) (string, error) {
+	sigPath := s.SignaturePath(path)
+	if _, err := os.Stat(sigPath); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return "", ErrNoSignature
+		}
+		return "", err
+	}
+
+	if s.Method == MethodGPG {
+		res, err := tools.RunTool(ctx, s.gpg(), "--batch", "--verify", sigPath, path)
+		if err != nil {
+			return "", fmt.Errorf("bad signature%s", stderrSuffix(res))
+		}
+		return gpgSigner(res.Stderr), nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	sig, keyID, file, err := readSignature(sigPath)
+	if err != nil {
+		return "", err
+	}
+	if name != "" && file != name {
+		return "", fmt.Errorf("signature is for %q, not %q", file, name)
+	}
+	if keyID != fingerprint(s.pub) {
+		return "", fmt.Errorf("signed with key %s, not %s", keyID, fingerprint(s.pub))
+	}
+	if !ed25519.Verify(s.pub, data, sig) {
+		return "", errors.New("bad signature: file was modified after signing")
+	}
+	return "ed25519 key " + keyID, nil
+}
+
+func (s *Signer) gpg() string {
+	if s.GPGPath != "" {
+		return s.GPGPath
+	}
+	return "gpg"
+}
+
+// readSignature parses an ed25519 signature file.
+func readSignature(path string) (sig []byte, keyID, file string, err error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, "", "", err
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		k, v, ok := strings.Cut(scanner.Text(), ": ")
+		if !ok {
+			continue
+		}
+		switch k {
+		case "key":
+			keyID = v
+		case "file":
+			file = v
+		case "signature":
+			if sig, err = base64.StdEncoding.DecodeString(v); err != nil {
+				return nil, "", "", fmt.Errorf("%s: malformed signature: %w", path, err)
+			}
+		}
+	}
+	if sig == nil {
+		return nil, "", "", fmt.Errorf("%s: not a reconpipe signature", path)
+	}
+	return sig, keyID, file, nil
+}
+
+// readPrivateKey loads a PKCS#8 PEM ed25519 private key.
+func readPrivateKey(path string) (ed25519.PrivateKey, error) {
+	der, err := readPEM(path, "PRIVATE KEY")
+	if err != nil {
+		return nil, err
+	}
+	key, err := x509.ParsePKCS8PrivateKey(der)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	priv, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("%s: not an ed25519 key", path)
+	}
+	return priv, nil
+}
+
+// readPublicKey loads a PKIX PEM ed25519 public key.
+func readPublicKey(path string) (ed25519.PublicKey, error) {
+	der, err := readPEM(path, "PUBLIC KEY")
+	if err != nil {
+		return nil, err
+	}
+	key, err := x509.ParsePKIXPublicKey(der)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	pub, ok := key.(ed25519.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("%s: not an ed25519 key", path)
+	}
+	return pub, nil
+}
+
+func readPEM(path, blockType string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != blockType {
+		return nil, fmt.Errorf("%s: no %s PEM block", path, blockType)
+	}
+	return block.Bytes, nil
+}
+
+// fingerprint identifies a public key as SHA256:<base64>, like ssh-keygen.
+func fingerprint(pub ed25519.PublicKey) string {
+	sum := sha256.Sum256(pub)
+	return "SHA256:" + base64.RawStdEncoding.EncodeToString(sum[:])
+}
+
+// gpgSigner picks the "Good signature from" line out of gpg's output.
+func gpgSigner(stderr string) string {
+	for _, line := range strings.Split(stderr, "\n") {
+		if i := strings.Index(line, "Good signature from"); i >= 0 {
+			return "gpg: " + strings.TrimSpace(line[i:])
+		}
+	}
+	return "gpg: good signature"
+}
+
+// stderrSuffix returns ": " plus the last line gpg printed, which carries the
+// verdict ("BAD signature from ...", "No public key"), or "" when it printed
+// nothing.
+func stderrSuffix(res *tools.ToolResult) string {
+	if res == nil {
+		return ""
+	}
+	lines := strings.Split(strings.TrimSpace(res.Stderr), "\n")
+	if last := strings.TrimSpace(lines[len(lines)-1]); last != "" {
+		return ": " + last
+	}
+	return ""
+}
@@ -0,0 +1,196 @@
+package stats
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/hakim/reconpipe/internal/diff"
+	"github.com/hakim/reconpipe/pkg/models"
+)
+
+// Portfolio summarizes the latest scan of many targets for reporting across
+// programs: how much is exposed, how bad it is, which targets are worst off
+// and what appeared since a cut-off.
+type Portfolio struct {
+	GeneratedAt time.Time `json:"generated_at"`
+	// Since is the cut-off for new assets: each target's latest scan is
+	// compared with its last scan started before it.
+	Since time.Time `json:"since"`
+
+	// Assets counts resolved subdomains plus hosts with open ports.
+	Assets      int                     `json:"assets"`
+	Subdomains  int                     `json:"subdomains"`
+	Hosts       int                     `json:"hosts"`
+	OpenPorts   int                     `json:"open_ports"`
+	WebServices int                     `json:"web_services"`
+	Severities  map[models.Severity]int `json:"severity_distribution"`
+
+	// Targets is ordered worst first: most criticals, then highs, and so on.
+	Targets []TargetSummary `json:"targets"`
+	// NewAssets lists what the latest scans found that the scans before
+	// Since did not, for targets scanned before Since.
+	NewAssets []NewAsset `json:"new_assets"`
+	// NewTargets were first scanned after Since; all their assets are new.
+	NewTargets []string `json:"new_targets"`
+}
+
+// TargetSummary is one target as of its latest scan.
+type TargetSummary struct {
+	Target      string                  `json:"target"`
+	ScanID      string                  `json:"scan_id"`
+	ScanTime    time.Time               `json:"scan_time"`
+	Subdomains  int                     `json:"subdomains"` // resolved only
+	Hosts       int                     `json:"hosts"`      // with open ports
+	OpenPorts   int                     `json:"open_ports"`
+	WebServices int                     `json:"web_services"`
+	Severities  map[models.Severity]int `json:"severity_distribution"`
+	NewAssets   int                     `json:"new_assets"`
+}
+
+// NewAsset is a subdomain or open port that appeared since the cut-off.
+type NewAsset struct {
+	Target string `json:"target"`
+	Kind   string `json:"kind"` // "subdomain" or "port"
+	Name   string `json:"name"`
+}
+
+// ComputePortfolio summarizes the latest scan of each target in history,
+// which maps a target to its scans newest first, as ListScans returns them.
+// The latest complete scan is used, or the latest scan when none completed.
+// Targets without scans are skipped. Scans whose directory is gone are read
+// from the stage results saved in store.
+func ComputePortfolio(store diff.ResultStore, history map[string][]*models.ScanMeta, since time.Time) (*Portfolio, error) {
+	p := &Portfolio{
+		GeneratedAt: time.Now().UTC(),
+		Since:       since.UTC(),
+		Severities:  map[models.Severity]int{},
+		Targets:     []TargetSummary{},
+		NewAssets:   []NewAsset{},
+		NewTargets:  []string{},
+	}
+
+	targets := make([]string, 0, len(history))
+	for target := range history {
+		targets = append(targets, target)
+	}
+	sort.Strings(targets)
+
+	for _, target := range targets {
+		scans := history[target]
+		latest := latestScan(scans)
+		if latest == nil {
+			continue
+		}
+		snap, err := diff.LoadScanSnapshot(store, latest)
+		if err != nil {
+			return nil, fmt.Errorf("loading scan %s of %s: %w", latest.ID, target, err)
+		}
+
+		ts := TargetSummary{
+			Target:      target,
+			ScanID:      latest.ID,
+			ScanTime:    latest.StartedAt,
+			WebServices: len(snap.Probes),
+			Severities:  map[models.Severity]int{},
+		}
+		for _, sub := range snap.Subdomains {
+			if sub.Resolved {
+				ts.Subdomains++
+			}
+		}
+		for _, host := range snap.Hosts {
+			if len(host.Ports) > 0 {
+				ts.Hosts++
+			}
+			ts.OpenPorts += len(host.Ports)
+		}
+		seen := map[string]bool{}
+		for _, v := range snap.Vulnerabilities {
+			if key := diff.VulnKey(v); !seen[key] {
+				seen[key] = true
+				ts.Severities[v.Severity]++
+			}
+		}
+
+		baseline := baselineScan(scans, latest, since)
+		switch {
+		case latest.StartedAt.Before(since):
+			// Not scanned since the cut-off, so nothing is new
+		case baseline == nil:
+			p.NewTargets = append(p.NewTargets, target)
+		default:
+			prev, err := diff.LoadScanSnapshot(store, baseline)
+			if err != nil {
+				return nil, fmt.Errorf("loading scan %s of %s: %w", baseline.ID, target, err)
+			}
+			d := diff.ComputeDiff(snap, prev)
+			for _, sub := range d.NewSubdomains {
+				p.NewAssets = append(p.NewAssets, NewAsset{Target: target, Kind: "subdomain", Name: sub.Name})
+			}
+			for _, pc := range d.NewPorts {
+				name := fmt.Sprintf("%s:%d/%s", pc.IP, pc.Port.Number, pc.Port.Protocol)
+				if pc.Host != "" && pc.Host != pc.IP {
+					name += " (" + pc.Host + ")"
+				}
+				p.NewAssets = append(p.NewAssets, NewAsset{Target: target, Kind: "port", Name: name})
+			}
+			ts.NewAssets = len(d.NewSubdomains) + len(d.NewPorts)
+		}
+
+		p.Subdomains += ts.Subdomains
+		p.Hosts += ts.Hosts
+		p.OpenPorts += ts.OpenPorts
+		p.WebServices += ts.WebServices
+		for sev, n := range ts.Severities {
+			p.Severities[sev] += n
+		}
+		p.Targets = append(p.Targets, ts)
+	}
+	p.Assets = p.Subdomains + p.Hosts
+
+	sort.SliceStable(p.Targets, func(i, j int) bool {
+		a, b := p.Targets[i].Severities, p.Targets[j].Severities
+		for _, sev := range Severities {
+			if a[sev] != b[sev] {
+				return a[sev] > b[sev]
+			}
+		}
+		return false
+	})
+
+	return p, nil
+}
+
+// latestScan returns the newest complete scan, or the newest scan when none
+// completed.
+func latestScan(scans []*models.ScanMeta) *models.ScanMeta {
+	for _, scan := range scans {
+		if scan.Status == models.StatusComplete {
+			return scan
+		}
+	}
+	if len(scans) > 0 {
+		return scans[0]
+	}
+	return nil
+}
+
+// baselineScan returns the newest scan older than latest that started before
+// since, preferring complete ones, or nil when the target was first scanned
+// after since.
+func baselineScan(scans []*models.ScanMeta, latest *models.ScanMeta, since time.Time) *models.ScanMeta {
+	var fallback *models.ScanMeta
+	for _, scan := range scans {
+		if scan.ID == latest.ID || !scan.StartedAt.Before(since) || !scan.StartedAt.Before(latest.StartedAt) {
+			continue
+		}
+		if scan.Status == models.StatusComplete {
+			return scan
+		}
+		if fallback == nil {
+			fallback = scan
+		}
+	}
+	return fallback
+}
@@ -0,0 +1,225 @@
+// Package stats aggregates scan results into the headline numbers used for
+// management reporting: what runs where, what is exposed, how bad the
+// findings are, and which discovery sources pull their weight, for one
+// target or across every target in the store.
+package stats
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hakim/reconpipe/internal/diff"
+	"github.com/hakim/reconpipe/pkg/models"
+)
+
+// Severities lists the severity levels, most severe first.
+var Severities = []models.Severity{
+	models.SeverityCritical,
+	models.SeverityHigh,
+	models.SeverityMedium,
+	models.SeverityLow,
+	models.SeverityInfo,
+}
+
+// Stats summarizes one or more scans of a target. When several scans are
+// aggregated, each subdomain, open port, technology and finding is counted
+// once however many scans saw it.
+type Stats struct {
+	Target    string    `json:"target"`
+	Scans     int       `json:"scans"`
+	FirstScan time.Time `json:"first_scan"`
+	LastScan  time.Time `json:"last_scan"`
+
+	// CompletedScans is the number of scans AvgDuration is averaged over.
+	CompletedScans int           `json:"completed_scans"`
+	AvgDuration    time.Duration `json:"-"`
+	AvgDurationSec float64       `json:"avg_duration_seconds"`
+
+	Subdomains int `json:"subdomains"`
+	Hosts      int `json:"hosts"`
+
+	Technologies []Count                 `json:"top_technologies"`
+	Ports        []PortCount             `json:"top_ports"`
+	Severities   map[models.Severity]int `json:"severity_distribution"`
+	Sources      []SourceStats           `json:"discovery_sources"`
+}
+
+// Count is a name and how many times it was seen.
+type Count struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+// PortCount is an open port and the number of hosts it is open on.
+type PortCount struct {
+	Port    int    `json:"port"`
+	Service string `json:"service,omitempty"`
+	Hosts   int    `json:"hosts"`
+}
+
+// SourceStats measures a discovery source by the subdomains credited to it
+// (the first source to report a name gets the credit) and how many of those
+// resolved.
+type SourceStats struct {
+	Source     string `json:"source"`
+	Subdomains int    `json:"subdomains"`
+	Resolved   int    `json:"resolved"`
+}
+
+// ResolveRate is the share of the source's subdomains that resolved, 0-100.
+func (s SourceStats) ResolveRate() float64 {
+	if s.Subdomains == 0 {
+		return 0
+	}
+	return float64(s.Resolved) * 100 / float64(s.Subdomains)
+}
+
+// Compute loads the results of each scan from its scan directory, or from
+// store when the directory is gone, and aggregates them. scans must be newest first, as ListScans returns them,
+// so a finding is counted at its latest severity. top caps the technology
+// and port lists; zero keeps all.
+func Compute(store diff.ResultStore, target string, scans []*models.ScanMeta, top int) (*Stats, error) {
+	st := &Stats{Target: target, Scans: len(scans), Severities: map[models.Severity]int{}}
+
+	subdomains := map[string]bool{}
+	hosts := map[string]bool{}
+	techs := map[string]map[string]bool{}    // technology -> URLs
+	ports := map[int]map[string]bool{}       // port -> IPs
+	services := map[int]map[string]int{}     // port -> service name -> times seen
+	vulns := map[string]models.Severity{}    // finding key -> severity
+	credited := map[string]map[string]bool{} // source -> subdomains
+	resolved := map[string]map[string]bool{} // source -> resolved subdomains
+
+	var total time.Duration
+	for _, scan := range scans {
+		if st.FirstScan.IsZero() || scan.StartedAt.Before(st.FirstScan) {
+			st.FirstScan = scan.StartedAt
+		}
+		if scan.StartedAt.After(st.LastScan) {
+			st.LastScan = scan.StartedAt
+		}
+		if scan.Status == models.StatusComplete && scan.CompletedAt != nil {
+			total += scan.CompletedAt.Sub(scan.StartedAt)
+			st.CompletedScans++
+		}
+
+		snap, err := diff.LoadScanSnapshot(store, scan)
+		if err != nil {
+			return nil, fmt.Errorf("loading scan %s: %w", scan.ID, err)
+		}
+
+		for _, sub := range snap.Subdomains {
+			subdomains[sub.Name] = true
+			addTo(credited, sub.Source, sub.Name)
+			if sub.Resolved {
+				addTo(resolved, sub.Source, sub.Name)
+			}
+		}
+
+		for _, host := range snap.Hosts {
+			if len(host.Ports) > 0 {
+				hosts[host.IP] = true
+			}
+			for _, port := range host.Ports {
+				addTo(ports, port.Number, host.IP)
+				if port.Service != "" {
+					if services[port.Number] == nil {
+						services[port.Number] = map[string]int{}
+					}
+					services[port.Number][port.Service]++
+				}
+			}
+		}
+
+		for _, probe := range snap.Probes {
+			for _, tech := range probe.Technologies {
+				name, _, _ := strings.Cut(tech, ":") // drop the version
+				addTo(techs, name, probe.URL)
+			}
+		}
+
+		for _, v := range snap.Vulnerabilities {
+			key := diff.VulnKey(v)
+			if _, seen := vulns[key]; !seen { // scans are newest first
+				vulns[key] = v.Severity
+			}
+		}
+	}
+
+	if st.CompletedScans > 0 {
+		st.AvgDuration = (total / time.Duration(st.CompletedScans)).Round(time.Second)
+		st.AvgDurationSec = st.AvgDuration.Seconds()
+	}
+	st.Subdomains = len(subdomains)
+	st.Hosts = len(hosts)
+
+	for name, urls := range techs {
+		st.Technologies = append(st.Technologies, Count{Name: name, Count: len(urls)})
+	}
+	sort.Slice(st.Technologies, func(i, j int) bool {
+		a, b := st.Technologies[i], st.Technologies[j]
+		if a.Count != b.Count {
+			return a.Count > b.Count
+		}
+		return a.Name < b.Name
+	})
+	st.Technologies = truncate(st.Technologies, top)
+
+	for port, ips := range ports {
+		st.Ports = append(st.Ports, PortCount{Port: port, Service: mostCommon(services[port]), Hosts: len(ips)})
+	}
+	sort.Slice(st.Ports, func(i, j int) bool {
+		a, b := st.Ports[i], st.Ports[j]
+		if a.Hosts != b.Hosts {
+			return a.Hosts > b.Hosts
+		}
+		return a.Port < b.Port
+	})
+	st.Ports = truncate(st.Ports, top)
+
+	for _, sev := range vulns {
+		st.Severities[sev]++
+	}
+
+	for source, names := range credited {
+		st.Sources = append(st.Sources, SourceStats{Source: source, Subdomains: len(names), Resolved: len(resolved[source])})
+	}
+	sort.Slice(st.Sources, func(i, j int) bool {
+		a, b := st.Sources[i], st.Sources[j]
+		if a.Subdomains != b.Subdomains {
+			return a.Subdomains > b.Subdomains
+		}
+		return a.Source < b.Source
+	})
+
+	return st, nil
+}
+
+// addTo records value under key in a set-of-sets.
+func addTo[K comparable](m map[K]map[string]bool, key K, value string) {
+	if m[key] == nil {
+		m[key] = map[string]bool{}
+	}
+	m[key][value] = true
+}
+
+// mostCommon returns the name seen most often, breaking ties alphabetically.
+func mostCommon(counts map[string]int) string {
+	best := ""
+	for name, n := range counts {
+		if best == "" || n > counts[best] || (n == counts[best] && name < best) {
+			best = name
+		}
+	}
+	return best
+}
+
+// truncate keeps the first n elements of s; n <= 0 keeps all.
+func truncate[T any](s []T, n int) []T {
+	if n > 0 && len(s) > n {
+		return s[:n]
+	}
+	return s
+}
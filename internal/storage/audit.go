@@ -0,0 +1,65 @@
+package storage
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"os"
+	"time"
+
+	"github.com/hakim/reconpipe/pkg/models"
+	"go.etcd.io/bbolt"
+)
+
+// AppendAudit adds an entry to the audit log. Time and Host are filled in
+// when empty. Keys are the bucket's sequence numbers, so entries keep their
+// insertion order.
+func (s *boltStore) AppendAudit(entry models.AuditEntry) error {
+	if entry.Time.IsZero() {
+		entry.Time = time.Now()
+	}
+	if entry.Host == "" {
+		entry.Host, _ = os.Hostname()
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	return s.db.Update(func(tx *bbolt.Tx) error {
+		audit := tx.Bucket([]byte(bucketAudit))
+		seq, err := audit.NextSequence()
+		if err != nil {
+			return err
+		}
+		key := make([]byte, 8)
+		binary.BigEndian.PutUint64(key, seq)
+		return audit.Put(key, data)
+	})
+}
+
+// ListAudit returns audit entries newest-first, optionally only those for
+// target, and at most limit entries when limit is positive.
+func (s *boltStore) ListAudit(target string, limit int) ([]models.AuditEntry, error) {
+	var entries []models.AuditEntry
+
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		c := tx.Bucket([]byte(bucketAudit)).Cursor()
+		for k, v := c.Last(); k != nil; k, v = c.Prev() {
+			var e models.AuditEntry
+			if err := json.Unmarshal(v, &e); err != nil {
+				return err
+			}
+			if target != "" && e.Target != target {
+				continue
+			}
+			entries = append(entries, e)
+			if limit > 0 && len(entries) >= limit {
+				break
+			}
+		}
+		return nil
+	})
+
+	return entries, err
+}
@@ -1,35 +1,80 @@
 package storage
 
 import (
+	"fmt"
 	"time"
 
 	"go.etcd.io/bbolt"
 )
 
 const (
-	bucketScans     = "scans"
-	bucketScanIndex = "scan_index"
+	bucketScans         = "scans"
+	bucketScanIndex     = "scan_index"
+	bucketAudit         = "audit"
+	bucketTokens        = "api_tokens"
+	bucketNotifications = "notification_state"
+	bucketQueue         = "scan_queue"
+	bucketNotes         = "notes"
+	bucketMonitor       = "monitor_runs"
+	bucketResults       = "scan_results"
 )
 
-// Store wraps a bbolt database for scan metadata persistence
-type Store struct {
+// buckets are created by openBolt and expected by every boltStore method.
+var buckets = []string{bucketScans, bucketScanIndex, bucketAudit, bucketTokens, bucketNotifications, bucketQueue, bucketNotes, bucketMonitor, bucketResults}
+
+// boltStore is the Store kept in a bbolt database
+type boltStore struct {
 	db *bbolt.DB
 }
 
-// NewStore opens a bbolt database at the given path and initializes required buckets
-func NewStore(path string) (*Store, error) {
-	db, err := bbolt.Open(path, 0600, &bbolt.Options{Timeout: 1 * time.Second})
+// openBolt opens a bbolt database at the given path and initializes required buckets
+func openBolt(path string) (Store, error) {
+	return openBoltTimeout(path, 1*time.Second)
+}
+
+// openBoltTimeout is openBolt waiting up to timeout for another process to
+// close the database.
+func openBoltTimeout(path string, timeout time.Duration) (Store, error) {
+	if readOnly {
+		return openBoltReadOnly(path, timeout)
+	}
+
+	db, err := bbolt.Open(path, 0600, &bbolt.Options{Timeout: timeout})
 	if err != nil {
 		return nil, err
 	}
 
 	// Create required buckets
 	err = db.Update(func(tx *bbolt.Tx) error {
-		if _, err := tx.CreateBucketIfNotExists([]byte(bucketScans)); err != nil {
-			return err
+		for _, name := range buckets {
+			if _, err := tx.CreateBucketIfNotExists([]byte(name)); err != nil {
+				return err
+			}
 		}
-		if _, err := tx.CreateBucketIfNotExists([]byte(bucketScanIndex)); err != nil {
-			return err
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &boltStore{db: db}, nil
+}
+
+// openBoltReadOnly opens an existing database without write access. Buckets
+// can't be created, so a database that predates one of them is rejected
+// rather than failing later on a missing bucket.
+func openBoltReadOnly(path string, timeout time.Duration) (Store, error) {
+	db, err := bbolt.Open(path, 0600, &bbolt.Options{Timeout: timeout, ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.View(func(tx *bbolt.Tx) error {
+		for _, name := range buckets {
+			if tx.Bucket([]byte(name)) == nil {
+				return fmt.Errorf("database %s has no %s bucket; open it once without read-only mode to upgrade it", path, name)
+			}
 		}
 		return nil
 	})
@@ -38,10 +83,20 @@ func NewStore(path string) (*Store, error) {
 		return nil, err
 	}
 
-	return &Store{db: db}, nil
+	return &boltStore{db: db}, nil
+}
+
+// Ping checks that the database is open and readable
+func (s *boltStore) Ping() error {
+	return s.db.View(func(tx *bbolt.Tx) error {
+		if tx.Bucket([]byte(bucketScans)) == nil {
+			return fmt.Errorf("database has no %s bucket", bucketScans)
+		}
+		return nil
+	})
 }
 
 // Close closes the bbolt database
-func (s *Store) Close() error {
+func (s *boltStore) Close() error {
 	return s.db.Close()
 }
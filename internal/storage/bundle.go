@@ -0,0 +1,282 @@
+package storage
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/hakim/reconpipe/pkg/models"
+)
+
+// bundleVersion is the scan bundle layout written by WriteBundle. Bundles
+// from a newer layout are refused rather than half-imported.
+const bundleVersion = 1
+
+// bundleManifestName is the first entry of every bundle.
+const bundleManifestName = "bundle.json"
+
+// BundleManifest describes a scan bundle: a gzipped tar of scan directories
+// plus their database records, for moving a scan (finished or not) to
+// another machine.
+type BundleManifest struct {
+	Version          int       `json:"version"`
+	ReconpipeVersion string    `json:"reconpipe_version"`
+	CreatedAt        time.Time `json:"created_at"`
+	Host             string    `json:"host,omitempty"` // machine the bundle was made on
+
+	// Scans are the bundled scans, the one being moved first and then any
+	// baseline its diff stage compares against.
+	Scans []BundledScan `json:"scans"`
+}
+
+// BundledScan is one scan in a bundle. Its files are under scans/{Dir}/.
+type BundledScan struct {
+	Dir  string           `json:"dir"`
+	Meta *models.ScanMeta `json:"meta"`
+}
+
+// WriteBundle writes a bundle of scans to w: the manifest, then every file
+// under each scan's directory. Leftover temporary files of interrupted
+// atomic writes are not included.
+func WriteBundle(w io.Writer, version string, scans []*models.ScanMeta) error {
+	manifest := BundleManifest{
+		Version:          bundleVersion,
+		ReconpipeVersion: version,
+		CreatedAt:        time.Now().UTC(),
+	}
+	manifest.Host, _ = os.Hostname()
+	seen := make(map[string]bool)
+	for _, meta := range scans {
+		dir := filepath.Base(meta.ScanDir)
+		if seen[dir] {
+			return fmt.Errorf("two bundled scans share the directory name %s", dir)
+		}
+		seen[dir] = true
+		manifest.Scans = append(manifest.Scans, BundledScan{Dir: dir, Meta: meta})
+	}
+
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshaling bundle manifest: %w", err)
+	}
+	hdr := &tar.Header{Name: bundleManifestName, Mode: 0644, Size: int64(len(data)), ModTime: manifest.CreatedAt}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	if _, err := tw.Write(data); err != nil {
+		return err
+	}
+
+	for _, s := range manifest.Scans {
+		if err := addScanDir(tw, s.Meta.ScanDir, path.Join("scans", s.Dir)); err != nil {
+			return fmt.Errorf("bundling %s: %w", s.Meta.ScanDir, err)
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// addScanDir adds the directories and regular files under root to tw,
+// named under prefix. Symlinks and other special files are skipped.
+func addScanDir(tw *tar.Writer, root, prefix string) error {
+	return filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() && !d.Type().IsRegular() {
+			return nil
+		}
+		if strings.HasPrefix(d.Name(), ".") && strings.HasSuffix(d.Name(), ".tmp") {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+
+		hdr, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+		hdr.Name = path.Join(prefix, filepath.ToSlash(rel))
+		if d.IsDir() {
+			hdr.Name += "/"
+		}
+		hdr.Uname, hdr.Gname = "", ""
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+
+		f, err := os.Open(p)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(tw, f)
+		return err
+	})
+}
+
+// ImportBundle unpacks a bundle read from r into baseDir and records its
+// scans in store, pointing them at their new directories. It refuses a
+// bundle whose scans are already in store or whose directories already
+// exist under baseDir, and removes what it unpacked if it fails part way.
+// The returned manifest's scans point at their new directories.
+func ImportBundle(r io.Reader, store Store, baseDir string) (*BundleManifest, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("reading bundle: %w", err)
+	}
+	defer gz.Close()
+	tr := tar.NewReader(gz)
+
+	// Step 1: The manifest comes first
+	hdr, err := tr.Next()
+	if err != nil {
+		return nil, fmt.Errorf("reading bundle: %w", err)
+	}
+	if hdr.Name != bundleManifestName {
+		return nil, fmt.Errorf("not a scan bundle: first entry is %q, not %s", hdr.Name, bundleManifestName)
+	}
+	var manifest BundleManifest
+	if err := json.NewDecoder(tr).Decode(&manifest); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", bundleManifestName, err)
+	}
+	if manifest.Version > bundleVersion {
+		return nil, fmt.Errorf("bundle layout %d is newer than this reconpipe supports (%d); upgrade reconpipe", manifest.Version, bundleVersion)
+	}
+
+	if len(manifest.Scans) == 0 {
+		return nil, errors.New("bundle holds no scans")
+	}
+
+	// Step 2: Check nothing would be overwritten
+	dirs := make(map[string]string, len(manifest.Scans))
+	for _, s := range manifest.Scans {
+		if s.Meta == nil || s.Meta.ID == "" || !validBundleDir(s.Dir) || dirs[s.Dir] != "" {
+			return nil, fmt.Errorf("bundle manifest has an invalid scan entry %q", s.Dir)
+		}
+		existing, err := store.GetScan(s.Meta.ID)
+		if err != nil {
+			return nil, err
+		}
+		if existing != nil {
+			return nil, fmt.Errorf("scan %s is already in the database (directory %s)", s.Meta.ID, existing.ScanDir)
+		}
+		dest := filepath.Join(baseDir, s.Dir)
+		if _, err := os.Stat(dest); err == nil {
+			return nil, fmt.Errorf("%s already exists", dest)
+		}
+		dirs[s.Dir] = dest
+	}
+
+	// Step 3: Unpack, removing the new directories on failure
+	succeeded := false
+	defer func() {
+		if !succeeded {
+			for _, dest := range dirs {
+				os.RemoveAll(dest)
+			}
+		}
+	}()
+	for _, dest := range dirs {
+		if err := os.MkdirAll(dest, 0755); err != nil {
+			return nil, err
+		}
+	}
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("reading bundle: %w", err)
+		}
+		if err := extractBundleEntry(tr, hdr, dirs); err != nil {
+			return nil, err
+		}
+	}
+
+	// Step 4: Record the scans at their new location
+	for _, s := range manifest.Scans {
+		s.Meta.ScanDir = dirs[s.Dir]
+		if err := store.SaveScan(s.Meta); err != nil {
+			return nil, fmt.Errorf("saving scan record %s: %w", s.Meta.ID, err)
+		}
+	}
+	succeeded = true
+	return &manifest, nil
+}
+
+// validBundleDir reports whether dir is a plain directory name.
+func validBundleDir(dir string) bool {
+	return dir != "" && dir != "." && dir != ".." && !strings.ContainsAny(dir, `/\`)
+}
+
+// extractBundleEntry writes one tar entry, named scans/{dir}/{path}, into
+// the destination of its scan directory. Names that would escape it are
+// refused.
+func extractBundleEntry(tr *tar.Reader, hdr *tar.Header, dirs map[string]string) error {
+	name := path.Clean(hdr.Name)
+	rest, ok := strings.CutPrefix(name, "scans/")
+	if !ok {
+		return fmt.Errorf("unexpected bundle entry %q", hdr.Name)
+	}
+	dir, rel, _ := strings.Cut(rest, "/")
+	dest, ok := dirs[dir]
+	if !ok {
+		return fmt.Errorf("bundle entry %q is not under a bundled scan", hdr.Name)
+	}
+	if rel == "" {
+		return nil
+	}
+	if !filepath.IsLocal(filepath.FromSlash(rel)) {
+		return fmt.Errorf("bundle entry %q escapes its scan directory", hdr.Name)
+	}
+	target := filepath.Join(dest, filepath.FromSlash(rel))
+
+	switch hdr.Typeflag {
+	case tar.TypeDir:
+		return os.MkdirAll(target, 0755)
+	case tar.TypeReg:
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return err
+		}
+		f, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_EXCL, fs.FileMode(hdr.Mode)&0777)
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(f, tr); err != nil {
+			f.Close()
+			return fmt.Errorf("unpacking %s: %w", hdr.Name, err)
+		}
+		if err := f.Close(); err != nil {
+			return err
+		}
+		return os.Chtimes(target, hdr.ModTime, hdr.ModTime)
+	default:
+		return fmt.Errorf("bundle entry %q is not a file or directory", hdr.Name)
+	}
+}
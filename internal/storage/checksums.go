@@ -0,0 +1,108 @@
+package storage
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+)
+
+// ChecksumsFile is the raw file that records which raw artifacts each
+// derived output was last built from.
+const ChecksumsFile = "checksums.json"
+
+// Checksums maps every output derived from raw artifacts (a report, a
+// section of the diff) to the SHA-256 of each input it was last built from.
+// An output whose inputs hash the same can be reused instead of rebuilt.
+type Checksums struct {
+	// Version is the reconpipe version that built the outputs. Another
+	// version may format them differently, so its entries are dropped.
+	Version string                       `json:"version"`
+	Outputs map[string]map[string]string `json:"outputs"`
+}
+
+// LoadChecksums reads the checksums recorded in scanDir. A missing file, or
+// one written by a different version, yields an empty set.
+func LoadChecksums(scanDir, version string) (*Checksums, error) {
+	sums := &Checksums{Version: version, Outputs: make(map[string]map[string]string)}
+	data, err := os.ReadFile(RawPath(scanDir, ChecksumsFile))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return sums, nil
+		}
+		return sums, fmt.Errorf("reading %s: %w", ChecksumsFile, err)
+	}
+
+	var recorded Checksums
+	if err := json.Unmarshal(data, &recorded); err != nil {
+		return sums, fmt.Errorf("parsing %s: %w", ChecksumsFile, err)
+	}
+	if recorded.Version == version && recorded.Outputs != nil {
+		sums.Outputs = recorded.Outputs
+	}
+	return sums, nil
+}
+
+// Save writes the checksums to scanDir.
+func (c *Checksums) Save(scanDir string) error {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshaling checksums: %w", err)
+	}
+	if err := EnsureDir(RawDir(scanDir)); err != nil {
+		return err
+	}
+	if err := WriteFileAtomic(RawPath(scanDir, ChecksumsFile), data, 0644); err != nil {
+		return fmt.Errorf("writing %s: %w", ChecksumsFile, err)
+	}
+	return nil
+}
+
+// Unchanged reports whether output was last built from exactly inputs.
+func (c *Checksums) Unchanged(output string, inputs map[string]string) bool {
+	recorded, ok := c.Outputs[output]
+	return ok && maps.Equal(recorded, inputs)
+}
+
+// Record notes that output was built from inputs.
+func (c *Checksums) Record(output string, inputs map[string]string) {
+	c.Outputs[output] = inputs
+}
+
+// HashRawFiles returns the SHA-256 of each named file in scanDir's raw
+// directory, keyed by name. A missing file hashes to "".
+func HashRawFiles(scanDir string, names ...string) (map[string]string, error) {
+	hashes := make(map[string]string, len(names))
+	for _, name := range names {
+		h, err := hashFile(RawPath(scanDir, name))
+		if err != nil {
+			return nil, err
+		}
+		hashes[name] = h
+	}
+	return hashes, nil
+}
+
+// hashFile returns the hex SHA-256 of the file at path, or "" when it does
+// not exist.
+func hashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return "", nil
+		}
+		return "", fmt.Errorf("hashing %s: %w", filepath.Base(path), err)
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", fmt.Errorf("hashing %s: %w", filepath.Base(path), err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
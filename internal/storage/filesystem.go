@@ -5,7 +5,8 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
-	"time"
+	"sort"
+	"strings"
 )
 
 // SanitizeTarget replaces characters unsafe for filesystem paths
@@ -15,18 +16,15 @@ func SanitizeTarget(target string) string {
 	return re.ReplaceAllString(target, "_")
 }
 
-// ScanDirPath generates a consistent directory path for a scan
-// Format: {baseDir}/{target}_{YYYYMMDD}_{HHMMSS}
-func ScanDirPath(baseDir string, target string, startedAt time.Time) string {
-	sanitized := SanitizeTarget(target)
-	timestamp := startedAt.Format("20060102_150405")
-	dirName := fmt.Sprintf("%s_%s", sanitized, timestamp)
-	return filepath.Join(baseDir, dirName)
+// ScanDirPath generates a consistent directory path for a scan, named by the
+// current layout's DirTemplate (default {baseDir}/{target}_{YYYYMMDD}_{HHMMSS}).
+func ScanDirPath(baseDir string, vars DirVars) string {
+	return filepath.Join(baseDir, CurrentLayout().DirName(vars))
 }
 
 // CreateScanDir creates a scan directory with subdirectories for reports and raw output
-func CreateScanDir(baseDir string, target string, startedAt time.Time) (string, error) {
-	scanPath := ScanDirPath(baseDir, target, startedAt)
+func CreateScanDir(baseDir string, vars DirVars) (string, error) {
+	scanPath := ScanDirPath(baseDir, vars)
 
 	// Create main scan directory
 	if err := EnsureDir(scanPath); err != nil {
@@ -34,19 +32,170 @@ func CreateScanDir(baseDir string, target string, startedAt time.Time) (string,
 	}
 
 	// Create subdirectories
-	reportsDir := filepath.Join(scanPath, "reports")
-	if err := EnsureDir(reportsDir); err != nil {
+	if err := EnsureDir(ReportsDir(scanPath)); err != nil {
 		return "", err
 	}
 
-	rawDir := filepath.Join(scanPath, "raw")
-	if err := EnsureDir(rawDir); err != nil {
+	if err := EnsureDir(RawDir(scanPath)); err != nil {
 		return "", err
 	}
 
 	return scanPath, nil
 }
 
+// LatestLinkPath returns the path of a target's "latest" pointer:
+// {baseDir}/{target}.latest.
+func LatestLinkPath(baseDir, target string) string {
+	return filepath.Join(baseDir, SanitizeTarget(target)+".latest")
+}
+
+// MarkLatestScan points the target's "latest" entry at scanDir. It is a
+// relative symlink when scanDir lives under baseDir (an absolute one
+// otherwise); where symlinks are unavailable (e.g. Windows without developer
+// mode) a plain file holding the path is written instead. The swap is done
+// via rename so readers never see a missing entry.
+func MarkLatestScan(baseDir, target, scanDir string) error {
+	linkPath := LatestLinkPath(baseDir, target)
+
+	dest, err := filepath.Rel(baseDir, scanDir)
+	if err != nil || dest == ".." || strings.HasPrefix(dest, ".."+string(filepath.Separator)) {
+		if dest, err = filepath.Abs(scanDir); err != nil {
+			return fmt.Errorf("resolving scan directory: %w", err)
+		}
+	}
+
+	tmp := linkPath + ".tmp"
+	os.Remove(tmp)
+	if err := os.Symlink(dest, tmp); err != nil {
+		if err := os.WriteFile(tmp, []byte(dest+"\n"), 0644); err != nil {
+			return fmt.Errorf("writing latest pointer: %w", err)
+		}
+	}
+	if err := os.Rename(tmp, linkPath); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("updating latest pointer: %w", err)
+	}
+	return nil
+}
+
+// WriteFileAtomic writes data to path through a temporary file in the same
+// directory that is synced and then renamed over path. A crash mid-write
+// leaves the previous file (or none), never a truncated one, and concurrent
+// writers each replace the file whole.
+func WriteFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // no-op once renamed
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), perm); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// readLatestScan resolves the target's "latest" entry, returning "" when it
+// is missing or points at a directory that no longer exists.
+func readLatestScan(baseDir, target string) string {
+	linkPath := LatestLinkPath(baseDir, target)
+
+	info, err := os.Lstat(linkPath)
+	if err != nil {
+		return ""
+	}
+	var dest string
+	if info.Mode()&os.ModeSymlink != 0 {
+		dest, err = os.Readlink(linkPath)
+	} else {
+		var data []byte
+		data, err = os.ReadFile(linkPath)
+		dest = strings.TrimSpace(string(data))
+	}
+	if err != nil || dest == "" {
+		return ""
+	}
+	if !filepath.IsAbs(dest) {
+		dest = filepath.Join(baseDir, dest)
+	}
+	if st, err := os.Stat(dest); err != nil || !st.IsDir() {
+		return ""
+	}
+	return dest
+}
+
+// FindLatestScanDir finds the most recent scan directory for a target under
+// baseDir. The target's "latest" pointer is used when it resolves; otherwise
+// directory names are matched against the current layout's DirTemplate and
+// ordered by the timestamp embedded in them.
+func FindLatestScanDir(baseDir, target string) (string, error) {
+	if latest := readLatestScan(baseDir, target); latest != "" {
+		return latest, nil
+	}
+
+	entries, err := os.ReadDir(baseDir)
+	if err != nil {
+		return "", fmt.Errorf("reading scan directory: %w", err)
+	}
+
+	re := CurrentLayout().dirPattern(target)
+
+	// Collect matching directories with their sort key
+	type match struct {
+		name, stamp string
+	}
+	var matches []match
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		m := re.FindStringSubmatch(entry.Name())
+		if m == nil {
+			continue
+		}
+		var stamp, date, clock string
+		for i, group := range re.SubexpNames() {
+			switch group {
+			case "timestamp":
+				stamp = m[i]
+			case "date":
+				date = m[i]
+			case "time":
+				clock = m[i]
+			}
+		}
+		if stamp == "" {
+			stamp = date + "_" + clock
+		}
+		matches = append(matches, match{entry.Name(), stamp})
+	}
+
+	if len(matches) == 0 {
+		return "", fmt.Errorf("no scan directories found for domain %s", target)
+	}
+
+	// Newest first; names break ties so the order is stable
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].stamp != matches[j].stamp {
+			return matches[i].stamp > matches[j].stamp
+		}
+		return strings.Compare(matches[i].name, matches[j].name) > 0
+	})
+
+	return filepath.Join(baseDir, matches[0].name), nil
+}
+
 // EnsureDir creates a directory and all parent directories if they don't exist
 func EnsureDir(path string) error {
 	return os.MkdirAll(path, 0755)
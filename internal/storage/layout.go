@@ -0,0 +1,208 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// DefaultDirTemplate names scan directories when no template is configured.
+const DefaultDirTemplate = "{target}_{timestamp}"
+
+// Layout describes how scan directories are named and organised. Every
+// stage, report and loader builds its paths through the helpers below so a
+// customised layout is honoured everywhere. Zero fields use the defaults.
+type Layout struct {
+	// DirTemplate names each scan directory. Placeholders: {target},
+	// {timestamp} (20060102_150405), {date}, {time}, {preset} and {tag}.
+	DirTemplate    string
+	RawDir         string // structured JSON per stage, default "raw"
+	ReportsDir     string // markdown/PDF reports, default "reports"
+	ScreenshotsDir string // default "screenshots"
+}
+
+// DirVars are the values substituted into Layout.DirTemplate.
+type DirVars struct {
+	Target    string
+	StartedAt time.Time
+	Preset    string
+	Tag       string
+}
+
+// layout is the process-wide layout. It is set once at startup from config
+// before any scan directory is created or read.
+var layout Layout
+
+// SetLayout replaces the process-wide layout.
+func SetLayout(l Layout) {
+	layout = l
+}
+
+// CurrentLayout returns the process-wide layout with defaults filled in.
+func CurrentLayout() Layout {
+	return layout.withDefaults()
+}
+
+// withDefaults fills in empty fields.
+func (l Layout) withDefaults() Layout {
+	if l.DirTemplate == "" {
+		l.DirTemplate = DefaultDirTemplate
+	}
+	if l.RawDir == "" {
+		l.RawDir = "raw"
+	}
+	if l.ReportsDir == "" {
+		l.ReportsDir = "reports"
+	}
+	if l.ScreenshotsDir == "" {
+		l.ScreenshotsDir = "screenshots"
+	}
+	return l
+}
+
+// placeholderRe matches a {name} placeholder in a directory template.
+var placeholderRe = regexp.MustCompile(`\{([a-z]+)\}`)
+
+// separatorRunRe matches the runs of separators left around empty values.
+var separatorRunRe = regexp.MustCompile(`[_-]{2,}`)
+
+// Validate checks that the template can both create unique directories and
+// find them again, and that subdirectory names are single path elements.
+func (l Layout) Validate() error {
+	l = l.withDefaults()
+
+	seen := map[string]bool{}
+	for _, m := range placeholderRe.FindAllStringSubmatch(l.DirTemplate, -1) {
+		switch m[1] {
+		case "target", "timestamp", "date", "time", "preset", "tag":
+			seen[m[1]] = true
+		default:
+			return fmt.Errorf("dir_template: unknown placeholder {%s}", m[1])
+		}
+	}
+	if !seen["target"] {
+		return errors.New("dir_template must contain {target}")
+	}
+	if !seen["timestamp"] && !(seen["date"] && seen["time"]) {
+		return errors.New("dir_template must contain {timestamp} or both {date} and {time}")
+	}
+	if strings.ContainsAny(placeholderRe.ReplaceAllString(l.DirTemplate, ""), `/\`) {
+		return errors.New("dir_template must not contain path separators")
+	}
+
+	for name, dir := range map[string]string{"raw_dir": l.RawDir, "reports_dir": l.ReportsDir, "screenshots_dir": l.ScreenshotsDir} {
+		if dir == "." || dir == ".." || strings.ContainsAny(dir, `/\`) {
+			return fmt.Errorf("%s %q must be a single directory name", name, dir)
+		}
+	}
+	return nil
+}
+
+// DirName renders the directory template. Empty values (e.g. no preset)
+// drop out along with the duplicate separator they would leave behind.
+func (l Layout) DirName(v DirVars) string {
+	values := map[string]string{
+		"target":    SanitizeTarget(v.Target),
+		"timestamp": v.StartedAt.Format("20060102_150405"),
+		"date":      v.StartedAt.Format("20060102"),
+		"time":      v.StartedAt.Format("150405"),
+		"preset":    sanitizeOptional(v.Preset),
+		"tag":       sanitizeOptional(v.Tag),
+	}
+	name := placeholderRe.ReplaceAllStringFunc(l.DirTemplate, func(p string) string {
+		return values[p[1:len(p)-1]]
+	})
+	name = separatorRunRe.ReplaceAllStringFunc(name, func(run string) string {
+		return run[:1]
+	})
+	return strings.Trim(name, "_-")
+}
+
+// dirPattern turns the template into a regexp matching directory names for
+// target. Timestamp parts are captured for ordering; separators are matched
+// loosely because empty values collapse them.
+func (l Layout) dirPattern(target string) *regexp.Regexp {
+	var b strings.Builder
+	b.WriteString("^")
+	rest := l.DirTemplate
+	for {
+		loc := placeholderRe.FindStringIndex(rest)
+		literal := rest
+		if loc != nil {
+			literal = rest[:loc[0]]
+		}
+		parts := separatorSplitRe.Split(literal, -1)
+		for i := range parts {
+			parts[i] = regexp.QuoteMeta(parts[i])
+		}
+		b.WriteString(strings.Join(parts, "[_-]*"))
+		if loc == nil {
+			break
+		}
+		switch rest[loc[0]+1 : loc[1]-1] {
+		case "target":
+			b.WriteString(regexp.QuoteMeta(SanitizeTarget(target)))
+		case "timestamp":
+			b.WriteString(`(?P<timestamp>\d{8}_\d{6})`)
+		case "date":
+			b.WriteString(`(?P<date>\d{8})`)
+		case "time":
+			b.WriteString(`(?P<time>\d{6})`)
+		default:
+			b.WriteString(`[A-Za-z0-9.-]*`)
+		}
+		rest = rest[loc[1]:]
+	}
+	b.WriteString("$")
+	return regexp.MustCompile(b.String())
+}
+
+// separatorSplitRe splits template literals on separator runs.
+var separatorSplitRe = regexp.MustCompile(`[_-]+`)
+
+// sanitizeOptional is SanitizeTarget for optional values, which may be empty.
+func sanitizeOptional(s string) string {
+	if s == "" {
+		return ""
+	}
+	return SanitizeTarget(s)
+}
+
+// RawDir returns the directory holding a scan's structured JSON.
+func RawDir(scanDir string) string {
+	return filepath.Join(scanDir, CurrentLayout().RawDir)
+}
+
+// RawPath returns the path of a raw output file, e.g. RawPath(dir, "ports.json").
+func RawPath(scanDir, name string) string {
+	return filepath.Join(RawDir(scanDir), name)
+}
+
+// ReportsDir returns the directory holding a scan's reports.
+func ReportsDir(scanDir string) string {
+	return filepath.Join(scanDir, CurrentLayout().ReportsDir)
+}
+
+// ReportPath returns the path of a report file, e.g. ReportPath(dir, "vulns.md").
+func ReportPath(scanDir, name string) string {
+	return filepath.Join(ReportsDir(scanDir), name)
+}
+
+// ScreenshotsDir returns the directory holding a scan's screenshots.
+func ScreenshotsDir(scanDir string) string {
+	return filepath.Join(scanDir, CurrentLayout().ScreenshotsDir)
+}
+
+// ScreenshotLink returns the link to a screenshot file from inside the
+// reports directory, for use in markdown reports.
+func ScreenshotLink(name string) string {
+	return "../" + CurrentLayout().ScreenshotsDir + "/" + name
+}
+
+// IsReportsDir reports whether dir is named like a scan's reports directory.
+func IsReportsDir(dir string) bool {
+	return filepath.Base(dir) == CurrentLayout().ReportsDir
+}
@@ -0,0 +1,76 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/json"
+	"slices"
+
+	"github.com/hakim/reconpipe/pkg/models"
+	"go.etcd.io/bbolt"
+)
+
+// SaveMonitorRun creates or replaces a monitor run
+func (s *boltStore) SaveMonitorRun(run *models.MonitorRun) error {
+	data, err := json.Marshal(run)
+	if err != nil {
+		return err
+	}
+	return s.db.Update(func(tx *bbolt.Tx) error {
+		return tx.Bucket([]byte(bucketMonitor)).Put([]byte(run.Key()), data)
+	})
+}
+
+// ListMonitorRuns returns the runs of schedule, newest first, or of every
+// schedule when it is empty
+func (s *boltStore) ListMonitorRuns(schedule string) ([]*models.MonitorRun, error) {
+	var runs []*models.MonitorRun
+	var prefix []byte
+	if schedule != "" {
+		prefix = []byte(schedule + "\x00")
+	}
+
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		c := tx.Bucket([]byte(bucketMonitor)).Cursor()
+		for k, v := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+			var run models.MonitorRun
+			if err := json.Unmarshal(v, &run); err != nil {
+				return err
+			}
+			runs = append(runs, &run)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Keys sort each schedule's runs oldest first
+	slices.SortStableFunc(runs, func(a, b *models.MonitorRun) int {
+		return b.DueAt.Compare(a.DueAt)
+	})
+	return runs, nil
+}
+
+// LastMonitorRun returns the newest run of schedule, or nil when it has
+// never run
+func (s *boltStore) LastMonitorRun(schedule string) (*models.MonitorRun, error) {
+	var run *models.MonitorRun
+	prefix := []byte(schedule + "\x00")
+
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		c := tx.Bucket([]byte(bucketMonitor)).Cursor()
+		var last []byte
+		for k, v := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+			last = v
+		}
+		if last == nil {
+			return nil
+		}
+		run = new(models.MonitorRun)
+		return json.Unmarshal(last, run)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return run, nil
+}
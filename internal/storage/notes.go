@@ -0,0 +1,85 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hakim/reconpipe/pkg/models"
+	"go.etcd.io/bbolt"
+)
+
+// SaveNote creates or replaces a note
+func (s *boltStore) SaveNote(n *models.Note) error {
+	data, err := json.Marshal(n)
+	if err != nil {
+		return err
+	}
+	return s.db.Update(func(tx *bbolt.Tx) error {
+		return tx.Bucket([]byte(bucketNotes)).Put([]byte(n.ID), data)
+	})
+}
+
+// ListNotes returns the notes of target, oldest first. Notes are few, so the
+// whole bucket is read.
+func (s *boltStore) ListNotes(target string) ([]*models.Note, error) {
+	var notes []*models.Note
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		return tx.Bucket([]byte(bucketNotes)).ForEach(func(_, v []byte) error {
+			var n models.Note
+			if err := json.Unmarshal(v, &n); err != nil {
+				return err
+			}
+			if n.Target == target {
+				notes = append(notes, &n)
+			}
+			return nil
+		})
+	})
+	sort.SliceStable(notes, func(i, j int) bool { return notes[i].CreatedAt.Before(notes[j].CreatedAt) })
+	return notes, err
+}
+
+// FindNote retrieves a note by its full ID or a unique ID prefix. It returns
+// nil if nothing matches and an error if the prefix is ambiguous.
+func (s *boltStore) FindNote(idOrPrefix string) (*models.Note, error) {
+	idOrPrefix = strings.TrimSuffix(idOrPrefix, "...")
+	if idOrPrefix == "" {
+		return nil, nil
+	}
+
+	var found []byte
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		prefix := []byte(idOrPrefix)
+		c := tx.Bucket([]byte(bucketNotes)).Cursor()
+		for k, v := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+			if string(k) == idOrPrefix {
+				found = v
+				return nil
+			}
+			if found != nil {
+				return fmt.Errorf("note ID prefix %q is ambiguous", idOrPrefix)
+			}
+			found = v
+		}
+		return nil
+	})
+	if err != nil || found == nil {
+		return nil, err
+	}
+
+	var n models.Note
+	if err := json.Unmarshal(found, &n); err != nil {
+		return nil, err
+	}
+	return &n, nil
+}
+
+// DeleteNote removes the note with the given ID
+func (s *boltStore) DeleteNote(id string) error {
+	return s.db.Update(func(tx *bbolt.Tx) error {
+		return tx.Bucket([]byte(bucketNotes)).Delete([]byte(id))
+	})
+}
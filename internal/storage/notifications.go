@@ -0,0 +1,95 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+
+	"github.com/hakim/reconpipe/pkg/models"
+	"go.etcd.io/bbolt"
+)
+
+// ListNotificationStates returns the notification state of every finding
+// ever alerted for target, resolved ones included
+func (s *boltStore) ListNotificationStates(target string) ([]*models.NotificationState, error) {
+	var states []*models.NotificationState
+	prefix := []byte(target + "\x00")
+
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		c := tx.Bucket([]byte(bucketNotifications)).Cursor()
+		for k, v := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+			var st models.NotificationState
+			if err := json.Unmarshal(v, &st); err != nil {
+				return err
+			}
+			states = append(states, &st)
+		}
+		return nil
+	})
+	return states, err
+}
+
+// SaveNotificationStates creates or replaces states in a single transaction
+func (s *boltStore) SaveNotificationStates(states []*models.NotificationState) error {
+	return s.db.Update(func(tx *bbolt.Tx) error {
+		b := tx.Bucket([]byte(bucketNotifications))
+		for _, st := range states {
+			data, err := json.Marshal(st)
+			if err != nil {
+				return err
+			}
+			if err := b.Put([]byte(st.Key()), data); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// ListFindings returns the findings q selects across every target, most
+// severe first, then by target and name. The whole bucket is read.
+func (s *boltStore) ListFindings(q FindingQuery) ([]*models.NotificationState, error) {
+	var states []*models.NotificationState
+	var prefix []byte
+	if q.Target != "" {
+		prefix = []byte(q.Target + "\x00")
+	}
+
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		c := tx.Bucket([]byte(bucketNotifications)).Cursor()
+		for k, v := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+			var st models.NotificationState
+			if err := json.Unmarshal(v, &st); err != nil {
+				return err
+			}
+			if q.Matches(&st) {
+				states = append(states, &st)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sortFindings(states)
+	return states, nil
+}
+
+// sortFindings orders states most severe first, then by target, name and
+// host, the order ListFindings returns.
+func sortFindings(states []*models.NotificationState) {
+	sort.SliceStable(states, func(i, j int) bool {
+		a, b := states[i], states[j]
+		if ra, rb := findingSeverityRank[a.Severity], findingSeverityRank[b.Severity]; ra != rb {
+			return ra > rb
+		}
+		if a.Target != b.Target {
+			return a.Target < b.Target
+		}
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		return a.Host < b.Host
+	})
+}
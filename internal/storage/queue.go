@@ -0,0 +1,64 @@
+package storage
+
+import (
+	"encoding/json"
+	"sort"
+
+	"github.com/hakim/reconpipe/pkg/models"
+	"go.etcd.io/bbolt"
+)
+
+// SaveQueuedScans replaces the saved scan queue with scans
+func (s *boltStore) SaveQueuedScans(scans []*models.QueuedScan) error {
+	return s.db.Update(func(tx *bbolt.Tx) error {
+		if err := tx.DeleteBucket([]byte(bucketQueue)); err != nil {
+			return err
+		}
+		b, err := tx.CreateBucket([]byte(bucketQueue))
+		if err != nil {
+			return err
+		}
+		for _, q := range scans {
+			data, err := json.Marshal(q)
+			if err != nil {
+				return err
+			}
+			if err := b.Put([]byte(q.JobID), data); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// TakeQueuedScans returns the saved scan queue, oldest first, and empties it
+func (s *boltStore) TakeQueuedScans() ([]*models.QueuedScan, error) {
+	var scans []*models.QueuedScan
+
+	err := s.db.Update(func(tx *bbolt.Tx) error {
+		err := tx.Bucket([]byte(bucketQueue)).ForEach(func(_, v []byte) error {
+			var q models.QueuedScan
+			if err := json.Unmarshal(v, &q); err != nil {
+				return err
+			}
+			scans = append(scans, &q)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		if err := tx.DeleteBucket([]byte(bucketQueue)); err != nil {
+			return err
+		}
+		_, err = tx.CreateBucket([]byte(bucketQueue))
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(scans, func(i, j int) bool {
+		return scans[i].QueuedAt.Before(scans[j].QueuedAt)
+	})
+	return scans, nil
+}
@@ -0,0 +1,141 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/json"
+	"time"
+
+	"github.com/hakim/reconpipe/pkg/models"
+	"go.etcd.io/bbolt"
+)
+
+// Stage results are saved under the name of the raw file the stage writes
+// them to.
+const (
+	ResultSubdomains = "subdomains.json"
+	ResultHosts      = "ports.json"
+	ResultProbes     = "http-probes.json"
+	ResultVulns      = "vulns.json"
+)
+
+// ScanResults are the structured results of a scan's stages as saved in the
+// database, which outlive the scan directory. Results a stage never saved
+// are nil and absent from Saved.
+type ScanResults struct {
+	Subdomains      []models.Subdomain
+	Hosts           []models.Host
+	Probes          []models.HTTPProbe
+	Vulnerabilities []models.Vulnerability
+
+	// Saved holds the result names that were saved and CollectedAt when
+	// each was collected, when the stage recorded it.
+	Saved       map[string]bool
+	CollectedAt map[string]time.Time
+}
+
+// storedResult is how a stage result is kept: its items and when they were
+// collected.
+type storedResult struct {
+	Items       json.RawMessage `json:"items"`
+	CollectedAt time.Time       `json:"collected_at,omitzero"`
+}
+
+// add decodes the items of the result called name into r.
+func (r *ScanResults) add(name string, items []byte, collectedAt time.Time) error {
+	var err error
+	switch name {
+	case ResultSubdomains:
+		err = json.Unmarshal(items, &r.Subdomains)
+	case ResultHosts:
+		err = json.Unmarshal(items, &r.Hosts)
+	case ResultProbes:
+		err = json.Unmarshal(items, &r.Probes)
+	case ResultVulns:
+		err = json.Unmarshal(items, &r.Vulnerabilities)
+	default:
+		return nil // written by a newer version
+	}
+	if err != nil {
+		return err
+	}
+	if r.Saved == nil {
+		r.Saved = make(map[string]bool)
+	}
+	r.Saved[name] = true
+	if !collectedAt.IsZero() {
+		if r.CollectedAt == nil {
+			r.CollectedAt = make(map[string]time.Time)
+		}
+		r.CollectedAt[name] = collectedAt
+	}
+	return nil
+}
+
+// resultKey is the scan_results key of a result: the scan ID, a NUL and
+// the result name, so a scan's results are adjacent.
+func resultKey(scanID, name string) []byte {
+	return []byte(scanID + "\x00" + name)
+}
+
+// SaveSubdomains saves the subdomains the discover stage of a scan found
+func (s *boltStore) SaveSubdomains(scanID string, subs []models.Subdomain, collectedAt time.Time) error {
+	return s.saveResult(scanID, ResultSubdomains, subs, collectedAt)
+}
+
+// SaveHosts saves the hosts and open ports the portscan stage of a scan found
+func (s *boltStore) SaveHosts(scanID string, hosts []models.Host, collectedAt time.Time) error {
+	return s.saveResult(scanID, ResultHosts, hosts, collectedAt)
+}
+
+// SaveProbes saves the HTTP services the probe stage of a scan found
+func (s *boltStore) SaveProbes(scanID string, probes []models.HTTPProbe, collectedAt time.Time) error {
+	return s.saveResult(scanID, ResultProbes, probes, collectedAt)
+}
+
+// SaveVulns saves the findings the vulnscan stage of a scan found
+func (s *boltStore) SaveVulns(scanID string, vulns []models.Vulnerability, collectedAt time.Time) error {
+	return s.saveResult(scanID, ResultVulns, vulns, collectedAt)
+}
+
+// saveResult creates or replaces the result called name of a scan
+func (s *boltStore) saveResult(scanID, name string, items any, collectedAt time.Time) error {
+	raw, err := json.Marshal(items)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(storedResult{Items: raw, CollectedAt: collectedAt})
+	if err != nil {
+		return err
+	}
+	return s.db.Update(func(tx *bbolt.Tx) error {
+		return tx.Bucket([]byte(bucketResults)).Put(resultKey(scanID, name), data)
+	})
+}
+
+// GetScanResults returns the stage results saved for a scan, or nil when
+// none were
+func (s *boltStore) GetScanResults(scanID string) (*ScanResults, error) {
+	var results *ScanResults
+	prefix := resultKey(scanID, "")
+
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		c := tx.Bucket([]byte(bucketResults)).Cursor()
+		for k, v := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+			var stored storedResult
+			if err := json.Unmarshal(v, &stored); err != nil {
+				return err
+			}
+			if results == nil {
+				results = &ScanResults{}
+			}
+			if err := results.add(string(k[len(prefix):]), stored.Items, stored.CollectedAt); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
@@ -0,0 +1,41 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/hakim/reconpipe/pkg/models"
+)
+
+// RunConfigFile is the raw file that records how a scan was produced.
+const RunConfigFile = "run-config.json"
+
+// WriteRunConfig saves rc to {scanDir}/{raw}/run-config.json.
+func WriteRunConfig(scanDir string, rc *models.RunConfig) error {
+	data, err := json.MarshalIndent(rc, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshaling run config: %w", err)
+	}
+	if err := EnsureDir(RawDir(scanDir)); err != nil {
+		return err
+	}
+	if err := WriteFileAtomic(RawPath(scanDir, RunConfigFile), data, 0644); err != nil {
+		return fmt.Errorf("writing run config: %w", err)
+	}
+	return nil
+}
+
+// ReadRunConfig loads the run config recorded in scanDir. Scans made before
+// run configs were recorded return an os.ErrNotExist error.
+func ReadRunConfig(scanDir string) (*models.RunConfig, error) {
+	data, err := os.ReadFile(RawPath(scanDir, RunConfigFile))
+	if err != nil {
+		return nil, err
+	}
+	var rc models.RunConfig
+	if err := json.Unmarshal(data, &rc); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", RunConfigFile, err)
+	}
+	return &rc, nil
+}
@@ -1,16 +1,19 @@
 package storage
 
 import (
+	"bytes"
 	"encoding/json"
+	"fmt"
 	"sort"
+	"strings"
 	"time"
 
-	"github.com/hakim/reconpipe/internal/models"
+	"github.com/hakim/reconpipe/pkg/models"
 	"go.etcd.io/bbolt"
 )
 
 // SaveScan persists a scan metadata record to the database
-func (s *Store) SaveScan(meta *models.ScanMeta) error {
+func (s *boltStore) SaveScan(meta *models.ScanMeta) error {
 	return s.db.Update(func(tx *bbolt.Tx) error {
 		// Marshal scan metadata to JSON
 		data, err := json.Marshal(meta)
@@ -58,7 +61,7 @@ func (s *Store) SaveScan(meta *models.ScanMeta) error {
 }
 
 // GetScan retrieves a scan metadata record by ID
-func (s *Store) GetScan(id string) (*models.ScanMeta, error) {
+func (s *boltStore) GetScan(id string) (*models.ScanMeta, error) {
 	var meta *models.ScanMeta
 
 	err := s.db.View(func(tx *bbolt.Tx) error {
@@ -75,8 +78,46 @@ func (s *Store) GetScan(id string) (*models.ScanMeta, error) {
 	return meta, err
 }
 
+// FindScan retrieves a scan by its full ID or by a unique ID prefix, such as
+// the shortened IDs shown by the history command. It returns nil if nothing
+// matches and an error if the prefix matches more than one scan.
+func (s *boltStore) FindScan(idOrPrefix string) (*models.ScanMeta, error) {
+	idOrPrefix = strings.TrimSuffix(idOrPrefix, "...")
+	if idOrPrefix == "" {
+		return nil, nil
+	}
+
+	var meta *models.ScanMeta
+
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		scans := tx.Bucket([]byte(bucketScans))
+		if data := scans.Get([]byte(idOrPrefix)); data != nil {
+			meta = &models.ScanMeta{}
+			return json.Unmarshal(data, meta)
+		}
+
+		prefix := []byte(idOrPrefix)
+		var found []byte
+		c := scans.Cursor()
+		for k, v := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+			if found != nil {
+				return fmt.Errorf("scan ID prefix %q is ambiguous", idOrPrefix)
+			}
+			found = v
+		}
+		if found == nil {
+			return nil // Not found
+		}
+
+		meta = &models.ScanMeta{}
+		return json.Unmarshal(found, meta)
+	})
+
+	return meta, err
+}
+
 // ListScans retrieves all scan metadata records for a target, sorted by StartedAt descending
-func (s *Store) ListScans(target string) ([]*models.ScanMeta, error) {
+func (s *boltStore) ListScans(target string) ([]*models.ScanMeta, error) {
 	var scans []*models.ScanMeta
 
 	err := s.db.View(func(tx *bbolt.Tx) error {
@@ -120,8 +161,22 @@ func (s *Store) ListScans(target string) ([]*models.ScanMeta, error) {
 	return scans, nil
 }
 
+// ListTargets returns every target with at least one scan, sorted by name
+func (s *boltStore) ListTargets() ([]string, error) {
+	var targets []string
+
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		return tx.Bucket([]byte(bucketScanIndex)).ForEach(func(k, _ []byte) error {
+			targets = append(targets, string(k))
+			return nil
+		})
+	})
+
+	return targets, err
+}
+
 // GetLatestScan retrieves the most recent scan for a target
-func (s *Store) GetLatestScan(target string) (*models.ScanMeta, error) {
+func (s *boltStore) GetLatestScan(target string) (*models.ScanMeta, error) {
 	scans, err := s.ListScans(target)
 	if err != nil {
 		return nil, err
@@ -133,7 +188,7 @@ func (s *Store) GetLatestScan(target string) (*models.ScanMeta, error) {
 }
 
 // UpdateScanStatus updates the status of a scan and sets CompletedAt if applicable
-func (s *Store) UpdateScanStatus(id string, status models.ScanStatus) error {
+func (s *boltStore) UpdateScanStatus(id string, status models.ScanStatus) error {
 	return s.db.Update(func(tx *bbolt.Tx) error {
 		scans := tx.Bucket([]byte(bucketScans))
 
@@ -0,0 +1,197 @@
+package storage
+
+import (
+	"sync"
+	"time"
+
+	"github.com/hakim/reconpipe/pkg/models"
+)
+
+// sharedOpenTimeout is how long a shared bbolt store waits for another
+// process to release the database before a call fails.
+const sharedOpenTimeout = 10 * time.Second
+
+// NewSharedStore opens the database for a long-running process such as
+// serve or monitor. With bbolt, which lets one process at a time have the
+// file open, the database is opened for each call and closed straight
+// after, so CLI commands (token revoke, history, show...) can use it in
+// between. SQLite is shared already and is opened once, as by NewStore.
+func NewSharedStore(path string) (Store, error) {
+	if driver == DriverSQLite {
+		return openSQLite(path)
+	}
+	s := &sharedStore{path: path}
+	// Create the file and its buckets, and fail now if it can't be opened
+	if err := s.Ping(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// sharedStore is a bbolt Store that holds the database only for the length
+// of each call. Calls are serialised: a second open from this process would
+// wait on its own file lock.
+type sharedStore struct {
+	path string
+	mu   sync.Mutex
+}
+
+// with opens the database, runs fn on it and closes it again.
+func (s *sharedStore) with(fn func(Store) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	st, err := openBoltTimeout(s.path, sharedOpenTimeout)
+	if err != nil {
+		return err
+	}
+	defer st.Close()
+	return fn(st)
+}
+
+// withResult is with for calls that return a value.
+func withResult[T any](s *sharedStore, fn func(Store) (T, error)) (T, error) {
+	var result T
+	err := s.with(func(st Store) error {
+		var err error
+		result, err = fn(st)
+		return err
+	})
+	return result, err
+}
+
+// Ping opens the database and checks it is readable.
+func (s *sharedStore) Ping() error {
+	return s.with(func(st Store) error { return st.Ping() })
+}
+
+// Close does nothing: the database is only open during a call.
+func (s *sharedStore) Close() error {
+	return nil
+}
+
+// The remaining methods call the bbolt method of the same name on a freshly
+// opened database.
+
+func (s *sharedStore) SaveScan(meta *models.ScanMeta) error {
+	return s.with(func(st Store) error { return st.SaveScan(meta) })
+}
+
+func (s *sharedStore) GetScan(id string) (*models.ScanMeta, error) {
+	return withResult(s, func(st Store) (*models.ScanMeta, error) { return st.GetScan(id) })
+}
+
+func (s *sharedStore) FindScan(idOrPrefix string) (*models.ScanMeta, error) {
+	return withResult(s, func(st Store) (*models.ScanMeta, error) { return st.FindScan(idOrPrefix) })
+}
+
+func (s *sharedStore) ListScans(target string) ([]*models.ScanMeta, error) {
+	return withResult(s, func(st Store) ([]*models.ScanMeta, error) { return st.ListScans(target) })
+}
+
+func (s *sharedStore) ListTargets() ([]string, error) {
+	return withResult(s, func(st Store) ([]string, error) { return st.ListTargets() })
+}
+
+func (s *sharedStore) GetLatestScan(target string) (*models.ScanMeta, error) {
+	return withResult(s, func(st Store) (*models.ScanMeta, error) { return st.GetLatestScan(target) })
+}
+
+func (s *sharedStore) UpdateScanStatus(id string, status models.ScanStatus) error {
+	return s.with(func(st Store) error { return st.UpdateScanStatus(id, status) })
+}
+
+func (s *sharedStore) SaveSubdomains(scanID string, subs []models.Subdomain, collectedAt time.Time) error {
+	return s.with(func(st Store) error { return st.SaveSubdomains(scanID, subs, collectedAt) })
+}
+
+func (s *sharedStore) SaveHosts(scanID string, hosts []models.Host, collectedAt time.Time) error {
+	return s.with(func(st Store) error { return st.SaveHosts(scanID, hosts, collectedAt) })
+}
+
+func (s *sharedStore) SaveProbes(scanID string, probes []models.HTTPProbe, collectedAt time.Time) error {
+	return s.with(func(st Store) error { return st.SaveProbes(scanID, probes, collectedAt) })
+}
+
+func (s *sharedStore) SaveVulns(scanID string, vulns []models.Vulnerability, collectedAt time.Time) error {
+	return s.with(func(st Store) error { return st.SaveVulns(scanID, vulns, collectedAt) })
+}
+
+func (s *sharedStore) GetScanResults(scanID string) (*ScanResults, error) {
+	return withResult(s, func(st Store) (*ScanResults, error) { return st.GetScanResults(scanID) })
+}
+
+func (s *sharedStore) AppendAudit(entry models.AuditEntry) error {
+	return s.with(func(st Store) error { return st.AppendAudit(entry) })
+}
+
+func (s *sharedStore) ListAudit(target string, limit int) ([]models.AuditEntry, error) {
+	return withResult(s, func(st Store) ([]models.AuditEntry, error) { return st.ListAudit(target, limit) })
+}
+
+func (s *sharedStore) SaveToken(t *models.APIToken) error {
+	return s.with(func(st Store) error { return st.SaveToken(t) })
+}
+
+func (s *sharedStore) TouchToken(id string, at time.Time) error {
+	return s.with(func(st Store) error { return st.TouchToken(id, at) })
+}
+
+func (s *sharedStore) ListTokens() ([]*models.APIToken, error) {
+	return withResult(s, func(st Store) ([]*models.APIToken, error) { return st.ListTokens() })
+}
+
+func (s *sharedStore) FindTokenByHash(hash string) (*models.APIToken, error) {
+	return withResult(s, func(st Store) (*models.APIToken, error) { return st.FindTokenByHash(hash) })
+}
+
+func (s *sharedStore) FindToken(idOrPrefix string) (*models.APIToken, error) {
+	return withResult(s, func(st Store) (*models.APIToken, error) { return st.FindToken(idOrPrefix) })
+}
+
+func (s *sharedStore) ListNotificationStates(target string) ([]*models.NotificationState, error) {
+	return withResult(s, func(st Store) ([]*models.NotificationState, error) { return st.ListNotificationStates(target) })
+}
+
+func (s *sharedStore) SaveNotificationStates(states []*models.NotificationState) error {
+	return s.with(func(st Store) error { return st.SaveNotificationStates(states) })
+}
+
+func (s *sharedStore) ListFindings(q FindingQuery) ([]*models.NotificationState, error) {
+	return withResult(s, func(st Store) ([]*models.NotificationState, error) { return st.ListFindings(q) })
+}
+
+func (s *sharedStore) SaveQueuedScans(scans []*models.QueuedScan) error {
+	return s.with(func(st Store) error { return st.SaveQueuedScans(scans) })
+}
+
+func (s *sharedStore) TakeQueuedScans() ([]*models.QueuedScan, error) {
+	return withResult(s, func(st Store) ([]*models.QueuedScan, error) { return st.TakeQueuedScans() })
+}
+
+func (s *sharedStore) SaveNote(n *models.Note) error {
+	return s.with(func(st Store) error { return st.SaveNote(n) })
+}
+
+func (s *sharedStore) ListNotes(target string) ([]*models.Note, error) {
+	return withResult(s, func(st Store) ([]*models.Note, error) { return st.ListNotes(target) })
+}
+
+func (s *sharedStore) FindNote(idOrPrefix string) (*models.Note, error) {
+	return withResult(s, func(st Store) (*models.Note, error) { return st.FindNote(idOrPrefix) })
+}
+
+func (s *sharedStore) DeleteNote(id string) error {
+	return s.with(func(st Store) error { return st.DeleteNote(id) })
+}
+
+func (s *sharedStore) SaveMonitorRun(run *models.MonitorRun) error {
+	return s.with(func(st Store) error { return st.SaveMonitorRun(run) })
+}
+
+func (s *sharedStore) ListMonitorRuns(schedule string) ([]*models.MonitorRun, error) {
+	return withResult(s, func(st Store) ([]*models.MonitorRun, error) { return st.ListMonitorRuns(schedule) })
+}
+
+func (s *sharedStore) LastMonitorRun(schedule string) (*models.MonitorRun, error) {
+	return withResult(s, func(st Store) (*models.MonitorRun, error) { return st.LastMonitorRun(schedule) })
+}
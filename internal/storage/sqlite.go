@@ -0,0 +1,673 @@
+package storage
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/hakim/reconpipe/pkg/models"
+	_ "github.com/mattn/go-sqlite3" // registers the sqlite3 database/sql driver
+)
+
+// sqliteSchema creates the tables of a SQLite database. Each record is kept
+// whole as JSON in its data column, as in bbolt; the other columns copy the
+// fields it is looked up and sorted by, so the history can also be queried
+// with SQL. Times are stored as UTC RFC 3339 with nanoseconds, which sort
+// as text.
+var sqliteSchema = []string{
+	`CREATE TABLE IF NOT EXISTS scans (
+		id           TEXT PRIMARY KEY,
+		target       TEXT NOT NULL,
+		status       TEXT NOT NULL,
+		started_at   TEXT NOT NULL,
+		completed_at TEXT,
+		scan_dir     TEXT NOT NULL,
+		operator     TEXT NOT NULL,
+		data         TEXT NOT NULL
+	)`,
+	`CREATE INDEX IF NOT EXISTS scans_target ON scans (target, started_at)`,
+	`CREATE TABLE IF NOT EXISTS audit (
+		seq      INTEGER PRIMARY KEY AUTOINCREMENT,
+		time     TEXT NOT NULL,
+		operator TEXT NOT NULL,
+		action   TEXT NOT NULL,
+		target   TEXT NOT NULL,
+		scan_id  TEXT NOT NULL,
+		data     TEXT NOT NULL
+	)`,
+	`CREATE INDEX IF NOT EXISTS audit_target ON audit (target, seq)`,
+	`CREATE TABLE IF NOT EXISTS api_tokens (
+		id         TEXT PRIMARY KEY,
+		hash       TEXT NOT NULL,
+		created_at TEXT NOT NULL,
+		data       TEXT NOT NULL
+	)`,
+	`CREATE INDEX IF NOT EXISTS api_tokens_hash ON api_tokens (hash)`,
+	`CREATE TABLE IF NOT EXISTS findings (
+		target        TEXT NOT NULL,
+		kind          TEXT NOT NULL,
+		identity      TEXT NOT NULL,
+		name          TEXT NOT NULL,
+		host          TEXT NOT NULL,
+		severity      TEXT NOT NULL,
+		severity_rank INTEGER NOT NULL,
+		first_seen    TEXT NOT NULL,
+		last_seen     TEXT NOT NULL,
+		resolved_at   TEXT,
+		data          TEXT NOT NULL,
+		PRIMARY KEY (target, kind, identity)
+	)`,
+	`CREATE TABLE IF NOT EXISTS scan_queue (
+		job_id    TEXT PRIMARY KEY,
+		queued_at TEXT NOT NULL,
+		data      TEXT NOT NULL
+	)`,
+	`CREATE TABLE IF NOT EXISTS notes (
+		id         TEXT PRIMARY KEY,
+		target     TEXT NOT NULL,
+		created_at TEXT NOT NULL,
+		data       TEXT NOT NULL
+	)`,
+	`CREATE INDEX IF NOT EXISTS notes_target ON notes (target, created_at)`,
+	`CREATE TABLE IF NOT EXISTS monitor_runs (
+		schedule TEXT NOT NULL,
+		due_at   TEXT NOT NULL,
+		data     TEXT NOT NULL,
+		PRIMARY KEY (schedule, due_at)
+	)`,
+	`CREATE TABLE IF NOT EXISTS scan_results (
+		scan_id      TEXT NOT NULL,
+		name         TEXT NOT NULL,
+		collected_at TEXT,
+		data         TEXT NOT NULL,
+		PRIMARY KEY (scan_id, name)
+	)`,
+}
+
+// sqliteTables are created by openSQLite and expected by every sqliteStore
+// method.
+var sqliteTables = []string{"scans", "audit", "api_tokens", "findings", "scan_queue", "notes", "monitor_runs", "scan_results"}
+
+// sqliteStore is the Store kept in a SQLite database. The database runs in
+// WAL mode with a busy timeout, so several reconpipe processes (a monitor,
+// serve and one-off commands) can use it at once; writes wait for each other
+// instead of failing.
+type sqliteStore struct {
+	db *sql.DB
+}
+
+// openSQLite opens the SQLite database at path, creating it and its tables
+// when needed. In read-only mode the database must already exist with every
+// table.
+func openSQLite(path string) (Store, error) {
+	params := url.Values{}
+	params.Set("_busy_timeout", "5000")
+	if readOnly {
+		params.Set("mode", "ro")
+	} else {
+		params.Set("_journal_mode", "WAL")
+		params.Set("_txlock", "immediate")
+	}
+	db, err := sql.Open("sqlite3", "file:"+path+"?"+params.Encode())
+	if err != nil {
+		return nil, err
+	}
+
+	if readOnly {
+		err = checkSQLiteTables(db, path)
+	} else {
+		err = createSQLiteTables(db)
+		if err == nil {
+			os.Chmod(path, 0600)
+		}
+	}
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &sqliteStore{db: db}, nil
+}
+
+func createSQLiteTables(db *sql.DB) error {
+	for _, stmt := range sqliteSchema {
+		if _, err := db.Exec(stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkSQLiteTables rejects a database that predates one of the tables,
+// since read-only mode can't create it.
+func checkSQLiteTables(db *sql.DB, path string) error {
+	for _, name := range sqliteTables {
+		var n int
+		if err := db.QueryRow(`SELECT count(*) FROM sqlite_master WHERE type = 'table' AND name = ?`, name).Scan(&n); err != nil {
+			return err
+		}
+		if n == 0 {
+			return fmt.Errorf("database %s has no %s table; open it once without read-only mode to upgrade it", path, name)
+		}
+	}
+	return nil
+}
+
+// Ping checks that the database is open and readable
+func (s *sqliteStore) Ping() error {
+	_, err := s.db.Exec(`SELECT 1 FROM scans LIMIT 1`)
+	return err
+}
+
+// Close closes the SQLite database
+func (s *sqliteStore) Close() error {
+	return s.db.Close()
+}
+
+// sqlTime formats t for a time column.
+func sqlTime(t time.Time) string {
+	return t.UTC().Format("2006-01-02T15:04:05.000000000Z")
+}
+
+// sqlNullTime formats t for a nullable time column.
+func sqlNullTime(t *time.Time) any {
+	if t == nil {
+		return nil
+	}
+	return sqlTime(*t)
+}
+
+// queryJSON decodes the data column of every row query returns.
+func queryJSON[T any](db *sql.DB, query string, args ...any) ([]*T, error) {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []*T
+	for rows.Next() {
+		var data []byte
+		if err := rows.Scan(&data); err != nil {
+			return nil, err
+		}
+		v := new(T)
+		if err := json.Unmarshal(data, v); err != nil {
+			return nil, err
+		}
+		out = append(out, v)
+	}
+	return out, rows.Err()
+}
+
+// getJSON decodes the data column of the first row query returns, or
+// returns nil when it returns none.
+func getJSON[T any](db *sql.DB, query string, args ...any) (*T, error) {
+	var data []byte
+	err := db.QueryRow(query, args...).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	v := new(T)
+	if err := json.Unmarshal(data, v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// findByPrefix returns the record of table whose id is idOrPrefix, or the
+// only one it prefixes. It returns nil if nothing matches and an error
+// naming what if the prefix matches more than one.
+func findByPrefix[T any](db *sql.DB, table, what, idOrPrefix string) (*T, error) {
+	idOrPrefix = strings.TrimSuffix(idOrPrefix, "...")
+	if idOrPrefix == "" {
+		return nil, nil
+	}
+	if v, err := getJSON[T](db, `SELECT data FROM `+table+` WHERE id = ?`, idOrPrefix); v != nil || err != nil {
+		return v, err
+	}
+	found, err := queryJSON[T](db, `SELECT data FROM `+table+` WHERE substr(id, 1, length(?1)) = ?1 ORDER BY id LIMIT 2`, idOrPrefix)
+	switch {
+	case err != nil:
+		return nil, err
+	case len(found) > 1:
+		return nil, fmt.Errorf("%s ID prefix %q is ambiguous", what, idOrPrefix)
+	case len(found) == 1:
+		return found[0], nil
+	}
+	return nil, nil
+}
+
+// inTx runs fn in a transaction, committing when it returns nil.
+func (s *sqliteStore) inTx(fn func(tx *sql.Tx) error) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// SaveScan persists a scan metadata record to the database
+func (s *sqliteStore) SaveScan(meta *models.ScanMeta) error {
+	data, err := json.Marshal(meta)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec(`INSERT OR REPLACE INTO scans (id, target, status, started_at, completed_at, scan_dir, operator, data)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		meta.ID, meta.Target, string(meta.Status), sqlTime(meta.StartedAt), sqlNullTime(meta.CompletedAt),
+		meta.ScanDir, meta.Operator, data)
+	return err
+}
+
+// GetScan retrieves a scan metadata record by ID
+func (s *sqliteStore) GetScan(id string) (*models.ScanMeta, error) {
+	return getJSON[models.ScanMeta](s.db, `SELECT data FROM scans WHERE id = ?`, id)
+}
+
+// FindScan retrieves a scan by its full ID or by a unique ID prefix
+func (s *sqliteStore) FindScan(idOrPrefix string) (*models.ScanMeta, error) {
+	return findByPrefix[models.ScanMeta](s.db, "scans", "scan", idOrPrefix)
+}
+
+// ListScans retrieves all scan metadata records for a target, sorted by StartedAt descending
+func (s *sqliteStore) ListScans(target string) ([]*models.ScanMeta, error) {
+	return queryJSON[models.ScanMeta](s.db, `SELECT data FROM scans WHERE target = ? ORDER BY started_at DESC`, target)
+}
+
+// ListTargets returns every target with at least one scan, sorted by name
+func (s *sqliteStore) ListTargets() ([]string, error) {
+	rows, err := s.db.Query(`SELECT DISTINCT target FROM scans ORDER BY target`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var targets []string
+	for rows.Next() {
+		var t string
+		if err := rows.Scan(&t); err != nil {
+			return nil, err
+		}
+		targets = append(targets, t)
+	}
+	return targets, rows.Err()
+}
+
+// GetLatestScan retrieves the most recent scan for a target
+func (s *sqliteStore) GetLatestScan(target string) (*models.ScanMeta, error) {
+	return getJSON[models.ScanMeta](s.db, `SELECT data FROM scans WHERE target = ? ORDER BY started_at DESC LIMIT 1`, target)
+}
+
+// UpdateScanStatus updates the status of a scan and sets CompletedAt if applicable
+func (s *sqliteStore) UpdateScanStatus(id string, status models.ScanStatus) error {
+	return s.inTx(func(tx *sql.Tx) error {
+		var data []byte
+		err := tx.QueryRow(`SELECT data FROM scans WHERE id = ?`, id).Scan(&data)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil // Not found, no-op
+		}
+		if err != nil {
+			return err
+		}
+
+		var meta models.ScanMeta
+		if err := json.Unmarshal(data, &meta); err != nil {
+			return err
+		}
+		meta.Status = status
+		if (status == models.StatusComplete || status == models.StatusFailed) && meta.CompletedAt == nil {
+			now := time.Now()
+			meta.CompletedAt = &now
+		}
+
+		updated, err := json.Marshal(&meta)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(`UPDATE scans SET status = ?, completed_at = ?, data = ? WHERE id = ?`,
+			string(meta.Status), sqlNullTime(meta.CompletedAt), updated, id)
+		return err
+	})
+}
+
+// SaveSubdomains saves the subdomains the discover stage of a scan found
+func (s *sqliteStore) SaveSubdomains(scanID string, subs []models.Subdomain, collectedAt time.Time) error {
+	return s.saveResult(scanID, ResultSubdomains, subs, collectedAt)
+}
+
+// SaveHosts saves the hosts and open ports the portscan stage of a scan found
+func (s *sqliteStore) SaveHosts(scanID string, hosts []models.Host, collectedAt time.Time) error {
+	return s.saveResult(scanID, ResultHosts, hosts, collectedAt)
+}
+
+// SaveProbes saves the HTTP services the probe stage of a scan found
+func (s *sqliteStore) SaveProbes(scanID string, probes []models.HTTPProbe, collectedAt time.Time) error {
+	return s.saveResult(scanID, ResultProbes, probes, collectedAt)
+}
+
+// SaveVulns saves the findings the vulnscan stage of a scan found
+func (s *sqliteStore) SaveVulns(scanID string, vulns []models.Vulnerability, collectedAt time.Time) error {
+	return s.saveResult(scanID, ResultVulns, vulns, collectedAt)
+}
+
+// saveResult creates or replaces the result called name of a scan. The data
+// column holds the items as a JSON array, for json_each.
+func (s *sqliteStore) saveResult(scanID, name string, items any, collectedAt time.Time) error {
+	data, err := json.Marshal(items)
+	if err != nil {
+		return err
+	}
+	var collected any
+	if !collectedAt.IsZero() {
+		collected = sqlTime(collectedAt)
+	}
+	_, err = s.db.Exec(`INSERT OR REPLACE INTO scan_results (scan_id, name, collected_at, data) VALUES (?, ?, ?, ?)`,
+		scanID, name, collected, data)
+	return err
+}
+
+// GetScanResults returns the stage results saved for a scan, or nil when
+// none were
+func (s *sqliteStore) GetScanResults(scanID string) (*ScanResults, error) {
+	rows, err := s.db.Query(`SELECT name, collected_at, data FROM scan_results WHERE scan_id = ?`, scanID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results *ScanResults
+	for rows.Next() {
+		var name string
+		var collected sql.NullString
+		var data []byte
+		if err := rows.Scan(&name, &collected, &data); err != nil {
+			return nil, err
+		}
+		var collectedAt time.Time
+		if collected.Valid {
+			if collectedAt, err = time.Parse(time.RFC3339Nano, collected.String); err != nil {
+				return nil, err
+			}
+		}
+		if results == nil {
+			results = &ScanResults{}
+		}
+		if err := results.add(name, data, collectedAt); err != nil {
+			return nil, err
+		}
+	}
+	return results, rows.Err()
+}
+
+// AppendAudit adds an entry to the audit log. Time and Host are filled in
+// when empty.
+func (s *sqliteStore) AppendAudit(entry models.AuditEntry) error {
+	if entry.Time.IsZero() {
+		entry.Time = time.Now()
+	}
+	if entry.Host == "" {
+		entry.Host, _ = os.Hostname()
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec(`INSERT INTO audit (time, operator, action, target, scan_id, data) VALUES (?, ?, ?, ?, ?, ?)`,
+		sqlTime(entry.Time), entry.Operator, entry.Action, entry.Target, entry.ScanID, data)
+	return err
+}
+
+// ListAudit returns audit entries newest-first, optionally only those for
+// target, and at most limit entries when limit is positive.
+func (s *sqliteStore) ListAudit(target string, limit int) ([]models.AuditEntry, error) {
+	if limit <= 0 {
+		limit = -1 // no limit
+	}
+	found, err := queryJSON[models.AuditEntry](s.db,
+		`SELECT data FROM audit WHERE ?1 = '' OR target = ?1 ORDER BY seq DESC LIMIT ?2`, target, limit)
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]models.AuditEntry, 0, len(found))
+	for _, e := range found {
+		entries = append(entries, *e)
+	}
+	return entries, nil
+}
+
+// SaveToken creates or replaces an API token record, keyed by its ID
+func (s *sqliteStore) SaveToken(t *models.APIToken) error {
+	data, err := json.Marshal(t)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec(`INSERT OR REPLACE INTO api_tokens (id, hash, created_at, data) VALUES (?, ?, ?, ?)`,
+		t.ID, t.Hash, sqlTime(t.CreatedAt), data)
+	return err
+}
+
+// TouchToken sets the last use of token id to at, re-reading the record in
+// the same transaction; revoked and deleted tokens are left alone
+func (s *sqliteStore) TouchToken(id string, at time.Time) error {
+	return s.inTx(func(tx *sql.Tx) error {
+		var data []byte
+		err := tx.QueryRow(`SELECT data FROM api_tokens WHERE id = ?`, id).Scan(&data)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		var t models.APIToken
+		if err := json.Unmarshal(data, &t); err != nil {
+			return err
+		}
+		if t.RevokedAt != nil {
+			return nil
+		}
+		t.LastUsedAt = &at
+		if data, err = json.Marshal(&t); err != nil {
+			return err
+		}
+		_, err = tx.Exec(`UPDATE api_tokens SET data = ? WHERE id = ?`, data, id)
+		return err
+	})
+}
+
+// ListTokens returns all API tokens, revoked ones included, oldest first
+func (s *sqliteStore) ListTokens() ([]*models.APIToken, error) {
+	return queryJSON[models.APIToken](s.db, `SELECT data FROM api_tokens ORDER BY created_at, id`)
+}
+
+// FindTokenByHash returns the token whose secret hashes to hash, or nil
+func (s *sqliteStore) FindTokenByHash(hash string) (*models.APIToken, error) {
+	return getJSON[models.APIToken](s.db, `SELECT data FROM api_tokens WHERE hash = ? ORDER BY id LIMIT 1`, hash)
+}
+
+// FindToken retrieves a token by its full ID or a unique ID prefix
+func (s *sqliteStore) FindToken(idOrPrefix string) (*models.APIToken, error) {
+	return findByPrefix[models.APIToken](s.db, "api_tokens", "token", idOrPrefix)
+}
+
+// ListNotificationStates returns the notification state of every finding
+// ever alerted for target, resolved ones included
+func (s *sqliteStore) ListNotificationStates(target string) ([]*models.NotificationState, error) {
+	return queryJSON[models.NotificationState](s.db,
+		`SELECT data FROM findings WHERE target = ? ORDER BY kind, identity`, target)
+}
+
+// SaveNotificationStates creates or replaces states in a single transaction
+func (s *sqliteStore) SaveNotificationStates(states []*models.NotificationState) error {
+	return s.inTx(func(tx *sql.Tx) error {
+		stmt, err := tx.Prepare(`INSERT OR REPLACE INTO findings
+			(target, kind, identity, name, host, severity, severity_rank, first_seen, last_seen, resolved_at, data)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`)
+		if err != nil {
+			return err
+		}
+		defer stmt.Close()
+
+		for _, st := range states {
+			data, err := json.Marshal(st)
+			if err != nil {
+				return err
+			}
+			_, err = stmt.Exec(st.Target, st.Kind, st.Identity, st.Name, st.Host,
+				string(st.Severity), findingSeverityRank[st.Severity],
+				sqlTime(st.FirstSeen), sqlTime(st.LastSeen), sqlNullTime(st.ResolvedAt), data)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// ListFindings returns the findings q selects across every target, most
+// severe first, then by target and name
+func (s *sqliteStore) ListFindings(q FindingQuery) ([]*models.NotificationState, error) {
+	var where []string
+	var args []any
+	if q.Target != "" {
+		where, args = append(where, "target = ?"), append(args, q.Target)
+	}
+	if q.Kind != "" {
+		where, args = append(where, "kind = ?"), append(args, q.Kind)
+	}
+	if q.MinSeverity != "" {
+		where, args = append(where, "severity_rank >= ?"), append(args, findingSeverityRank[q.MinSeverity])
+	}
+	if q.Open {
+		where = append(where, "resolved_at IS NULL")
+	}
+	if !q.Since.IsZero() {
+		where, args = append(where, "last_seen >= ?"), append(args, sqlTime(q.Since))
+	}
+
+	query := `SELECT data FROM findings`
+	if len(where) > 0 {
+		query += ` WHERE ` + strings.Join(where, " AND ")
+	}
+	query += ` ORDER BY severity_rank DESC, target, name, host`
+	return queryJSON[models.NotificationState](s.db, query, args...)
+}
+
+// SaveQueuedScans replaces the saved scan queue with scans
+func (s *sqliteStore) SaveQueuedScans(scans []*models.QueuedScan) error {
+	return s.inTx(func(tx *sql.Tx) error {
+		if _, err := tx.Exec(`DELETE FROM scan_queue`); err != nil {
+			return err
+		}
+		for _, q := range scans {
+			data, err := json.Marshal(q)
+			if err != nil {
+				return err
+			}
+			if _, err := tx.Exec(`INSERT OR REPLACE INTO scan_queue (job_id, queued_at, data) VALUES (?, ?, ?)`,
+				q.JobID, sqlTime(q.QueuedAt), data); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// TakeQueuedScans returns the saved scan queue, oldest first, and empties it
+func (s *sqliteStore) TakeQueuedScans() ([]*models.QueuedScan, error) {
+	var scans []*models.QueuedScan
+	err := s.inTx(func(tx *sql.Tx) error {
+		rows, err := tx.Query(`SELECT data FROM scan_queue ORDER BY queued_at, job_id`)
+		if err != nil {
+			return err
+		}
+		for rows.Next() {
+			var data []byte
+			if err := rows.Scan(&data); err != nil {
+				rows.Close()
+				return err
+			}
+			var q models.QueuedScan
+			if err := json.Unmarshal(data, &q); err != nil {
+				rows.Close()
+				return err
+			}
+			scans = append(scans, &q)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		_, err = tx.Exec(`DELETE FROM scan_queue`)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return scans, nil
+}
+
+// SaveNote creates or replaces a note
+func (s *sqliteStore) SaveNote(n *models.Note) error {
+	data, err := json.Marshal(n)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec(`INSERT OR REPLACE INTO notes (id, target, created_at, data) VALUES (?, ?, ?, ?)`,
+		n.ID, n.Target, sqlTime(n.CreatedAt), data)
+	return err
+}
+
+// ListNotes returns the notes of target, oldest first
+func (s *sqliteStore) ListNotes(target string) ([]*models.Note, error) {
+	return queryJSON[models.Note](s.db, `SELECT data FROM notes WHERE target = ? ORDER BY created_at, id`, target)
+}
+
+// FindNote retrieves a note by its full ID or a unique ID prefix
+func (s *sqliteStore) FindNote(idOrPrefix string) (*models.Note, error) {
+	return findByPrefix[models.Note](s.db, "notes", "note", idOrPrefix)
+}
+
+// DeleteNote removes the note with the given ID
+func (s *sqliteStore) DeleteNote(id string) error {
+	_, err := s.db.Exec(`DELETE FROM notes WHERE id = ?`, id)
+	return err
+}
+
+// SaveMonitorRun creates or replaces a monitor run
+func (s *sqliteStore) SaveMonitorRun(run *models.MonitorRun) error {
+	data, err := json.Marshal(run)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec(`INSERT OR REPLACE INTO monitor_runs (schedule, due_at, data) VALUES (?, ?, ?)`,
+		run.Schedule, run.DueAt.UTC().Format(time.RFC3339), data)
+	return err
+}
+
+// ListMonitorRuns returns the runs of schedule, newest first, or of every
+// schedule when it is empty
+func (s *sqliteStore) ListMonitorRuns(schedule string) ([]*models.MonitorRun, error) {
+	return queryJSON[models.MonitorRun](s.db,
+		`SELECT data FROM monitor_runs WHERE ?1 = '' OR schedule = ?1 ORDER BY due_at DESC, schedule`, schedule)
+}
+
+// LastMonitorRun returns the newest run of schedule, or nil when it has
+// never run
+func (s *sqliteStore) LastMonitorRun(schedule string) (*models.MonitorRun, error) {
+	return getJSON[models.MonitorRun](s.db,
+		`SELECT data FROM monitor_runs WHERE schedule = ? ORDER BY due_at DESC LIMIT 1`, schedule)
+}
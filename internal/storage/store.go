@@ -0,0 +1,139 @@
+package storage
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/hakim/reconpipe/pkg/models"
+)
+
+// Database drivers, selected by the db_driver config setting.
+const (
+	DriverBbolt  = "bbolt"  // single-file key/value store; one process at a time
+	DriverSQLite = "sqlite" // SQLite in WAL mode; queryable with SQL and shared by several processes
+)
+
+// Store is the scan database: scan records and their stage results, the
+// audit log, API tokens, the finding inventory, the saved scan queue, notes
+// and monitor runs. NewStore opens the backend chosen with SetDriver.
+type Store interface {
+	// Ping checks that the database is open and readable
+	Ping() error
+	// Close closes the database
+	Close() error
+
+	SaveScan(meta *models.ScanMeta) error
+	GetScan(id string) (*models.ScanMeta, error)
+	FindScan(idOrPrefix string) (*models.ScanMeta, error)
+	ListScans(target string) ([]*models.ScanMeta, error)
+	ListTargets() ([]string, error)
+	GetLatestScan(target string) (*models.ScanMeta, error)
+	UpdateScanStatus(id string, status models.ScanStatus) error
+
+	// Stage results of a scan, keyed by scan ID, so history and diffs
+	// survive the scan directory
+	SaveSubdomains(scanID string, subs []models.Subdomain, collectedAt time.Time) error
+	SaveHosts(scanID string, hosts []models.Host, collectedAt time.Time) error
+	SaveProbes(scanID string, probes []models.HTTPProbe, collectedAt time.Time) error
+	SaveVulns(scanID string, vulns []models.Vulnerability, collectedAt time.Time) error
+	GetScanResults(scanID string) (*ScanResults, error)
+
+	AppendAudit(entry models.AuditEntry) error
+	ListAudit(target string, limit int) ([]models.AuditEntry, error)
+
+	SaveToken(t *models.APIToken) error
+	TouchToken(id string, at time.Time) error
+	ListTokens() ([]*models.APIToken, error)
+	FindTokenByHash(hash string) (*models.APIToken, error)
+	FindToken(idOrPrefix string) (*models.APIToken, error)
+
+	ListNotificationStates(target string) ([]*models.NotificationState, error)
+	SaveNotificationStates(states []*models.NotificationState) error
+	ListFindings(q FindingQuery) ([]*models.NotificationState, error)
+
+	SaveQueuedScans(scans []*models.QueuedScan) error
+	TakeQueuedScans() ([]*models.QueuedScan, error)
+
+	SaveNote(n *models.Note) error
+	ListNotes(target string) ([]*models.Note, error)
+	FindNote(idOrPrefix string) (*models.Note, error)
+	DeleteNote(id string) error
+
+	SaveMonitorRun(run *models.MonitorRun) error
+	ListMonitorRuns(schedule string) ([]*models.MonitorRun, error)
+	LastMonitorRun(schedule string) (*models.MonitorRun, error)
+}
+
+// FindingQuery selects findings from the finding inventory, the
+// vulnerabilities and dangling subdomains tracked for alerting. Zero fields
+// match everything.
+type FindingQuery struct {
+	Target      string
+	Kind        string          // vuln or dangling
+	MinSeverity models.Severity // at least this severe
+	Open        bool            // only findings that are not resolved
+	Since       time.Time       // last seen at or after
+}
+
+// findingSeverityRank orders severities for FindingQuery.MinSeverity
+// (higher = worse).
+var findingSeverityRank = map[models.Severity]int{
+	models.SeverityInfo:     1,
+	models.SeverityLow:      2,
+	models.SeverityMedium:   3,
+	models.SeverityHigh:     4,
+	models.SeverityCritical: 5,
+}
+
+// Matches reports whether st is selected by q.
+func (q FindingQuery) Matches(st *models.NotificationState) bool {
+	switch {
+	case q.Target != "" && st.Target != q.Target:
+		return false
+	case q.Kind != "" && st.Kind != q.Kind:
+		return false
+	case q.MinSeverity != "" && findingSeverityRank[st.Severity] < findingSeverityRank[q.MinSeverity]:
+		return false
+	case q.Open && st.ResolvedAt != nil:
+		return false
+	case !q.Since.IsZero() && st.LastSeen.Before(q.Since):
+		return false
+	}
+	return true
+}
+
+// readOnly makes NewStore open databases read-only. It is set once at startup
+// (reconpipe --read-only) before any store is opened.
+var readOnly bool
+
+// SetReadOnly switches every store opened afterwards to read-only mode, in
+// which writes fail.
+func SetReadOnly(ro bool) {
+	readOnly = ro
+}
+
+// driver is the backend NewStore opens, set once at startup from db_driver.
+var driver = DriverBbolt
+
+// SetDriver selects the backend every store opened afterwards uses; empty
+// selects bbolt.
+func SetDriver(name string) error {
+	switch name {
+	case "", DriverBbolt:
+		driver = DriverBbolt
+	case DriverSQLite:
+		driver = DriverSQLite
+	default:
+		return fmt.Errorf("unknown database driver %q (want bbolt or sqlite)", name)
+	}
+	return nil
+}
+
+// NewStore opens the database at path with the driver chosen by SetDriver,
+// creating it when it does not exist.
+func NewStore(path string) (Store, error) {
+	if driver == DriverSQLite {
+		return openSQLite(path)
+	}
+	return openBolt(path)
+}
@@ -0,0 +1,134 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hakim/reconpipe/pkg/models"
+	"go.etcd.io/bbolt"
+)
+
+// SaveToken creates or replaces an API token record, keyed by its ID
+func (s *boltStore) SaveToken(t *models.APIToken) error {
+	data, err := json.Marshal(t)
+	if err != nil {
+		return err
+	}
+
+	return s.db.Update(func(tx *bbolt.Tx) error {
+		return tx.Bucket([]byte(bucketTokens)).Put([]byte(t.ID), data)
+	})
+}
+
+// TouchToken sets the last use of token id to at. The stored record is
+// re-read in the same transaction, so a revocation saved since the token was
+// looked up is kept; revoked and deleted tokens are left alone.
+func (s *boltStore) TouchToken(id string, at time.Time) error {
+	return s.db.Update(func(tx *bbolt.Tx) error {
+		b := tx.Bucket([]byte(bucketTokens))
+		v := b.Get([]byte(id))
+		if v == nil {
+			return nil
+		}
+		var t models.APIToken
+		if err := json.Unmarshal(v, &t); err != nil {
+			return err
+		}
+		if t.RevokedAt != nil {
+			return nil
+		}
+		t.LastUsedAt = &at
+		data, err := json.Marshal(&t)
+		if err != nil {
+			return err
+		}
+		return b.Put([]byte(id), data)
+	})
+}
+
+// ListTokens returns all API tokens, revoked ones included, oldest first
+func (s *boltStore) ListTokens() ([]*models.APIToken, error) {
+	var tokens []*models.APIToken
+
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		return tx.Bucket([]byte(bucketTokens)).ForEach(func(_, v []byte) error {
+			var t models.APIToken
+			if err := json.Unmarshal(v, &t); err != nil {
+				return err
+			}
+			tokens = append(tokens, &t)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(tokens, func(i, j int) bool {
+		return tokens[i].CreatedAt.Before(tokens[j].CreatedAt)
+	})
+	return tokens, nil
+}
+
+// FindTokenByHash returns the token whose secret hashes to hash, or nil.
+// Tokens are few, so a scan of the bucket is cheap enough for every request.
+func (s *boltStore) FindTokenByHash(hash string) (*models.APIToken, error) {
+	var found *models.APIToken
+
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		c := tx.Bucket([]byte(bucketTokens)).Cursor()
+		for k, v := c.First(); k != nil; k, v = c.Next() {
+			var t models.APIToken
+			if err := json.Unmarshal(v, &t); err != nil {
+				return err
+			}
+			if t.Hash == hash {
+				found = &t
+				return nil
+			}
+		}
+		return nil
+	})
+
+	return found, err
+}
+
+// FindToken retrieves a token by its full ID or a unique ID prefix. It returns
+// nil if nothing matches and an error if the prefix is ambiguous.
+func (s *boltStore) FindToken(idOrPrefix string) (*models.APIToken, error) {
+	idOrPrefix = strings.TrimSuffix(idOrPrefix, "...")
+	if idOrPrefix == "" {
+		return nil, nil
+	}
+
+	var found []byte
+
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		prefix := []byte(idOrPrefix)
+		c := tx.Bucket([]byte(bucketTokens)).Cursor()
+		for k, v := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+			if string(k) == idOrPrefix {
+				found = v
+				return nil
+			}
+			if found != nil {
+				return fmt.Errorf("token ID prefix %q is ambiguous", idOrPrefix)
+			}
+			found = v
+		}
+		return nil
+	})
+	if err != nil || found == nil {
+		return nil, err
+	}
+
+	var t models.APIToken
+	if err := json.Unmarshal(found, &t); err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
@@ -0,0 +1,120 @@
+// Package takeover rates dangling DNS records by how easily someone else
+// could claim the resource they point at. A CNAME to a deleted S3 bucket can
+// be taken over by anyone who registers the bucket name; one to a service
+// that verifies domain ownership cannot.
+package takeover
+
+import (
+	"strings"
+
+	"github.com/hakim/reconpipe/pkg/models"
+)
+
+// Claimability is whether a provider's abandoned resources can be registered
+// by a third party.
+type Claimability string
+
+const (
+	// Claimable providers hand out the abandoned name to whoever asks.
+	Claimable Claimability = "claimable"
+	// NeedsVerification providers are claimable in some setups only (e.g.
+	// without domain verification); someone has to check by hand.
+	NeedsVerification Claimability = "needs-verification"
+	// NotClaimable providers verify ownership or reserve released names.
+	NotClaimable Claimability = "not-claimable"
+)
+
+// Provider is a hosting service recognized by the suffix of a CNAME target.
+type Provider struct {
+	Name         string
+	Suffixes     []string // matched as substrings of the lowercased target
+	Claimability Claimability
+}
+
+// Providers is checked in order; the first match wins. Ratings follow the
+// community-maintained can-i-take-over-xyz list.
+var Providers = []Provider{
+	{"Azure", []string{".azurewebsites.net", ".cloudapp.net", ".cloudapp.azure.com", ".trafficmanager.net", ".blob.core.windows.net"}, Claimable},
+	{"AWS S3", []string{".s3.amazonaws.com", ".s3-website"}, Claimable},
+	{"AWS Elastic Beanstalk", []string{".elasticbeanstalk.com"}, Claimable},
+	{"Bitbucket", []string{".bitbucket.io"}, Claimable},
+	{"Ghost", []string{".ghost.io"}, Claimable},
+	{"Pantheon", []string{".pantheon.io", ".pantheonsite.io"}, Claimable},
+	{"Surge", []string{".surge.sh"}, Claimable},
+	{"WordPress.com", []string{".wordpress.com"}, Claimable},
+	{"CloudFront", []string{".cloudfront.net"}, NeedsVerification},
+	{"Fastly", []string{".fastly.net"}, NeedsVerification},
+	{"GitHub Pages", []string{".github.io"}, NeedsVerification},
+	{"Heroku", []string{".herokuapp.com", ".herokudns.com"}, NeedsVerification},
+	{"Netlify", []string{".netlify.app", ".netlify.com"}, NeedsVerification},
+	{"Shopify", []string{".myshopify.com", ".shopify.com"}, NeedsVerification},
+	{"Freshdesk", []string{".freshdesk.com"}, NotClaimable},
+	{"Squarespace", []string{".squarespace.com"}, NotClaimable},
+	{"Zendesk", []string{".zendesk.com"}, NotClaimable},
+}
+
+// Classify returns the provider a CNAME target belongs to, or false when it
+// is not a known one.
+func Classify(cname string) (Provider, bool) {
+	lower := strings.ToLower(cname)
+	for _, p := range Providers {
+		for _, suffix := range p.Suffixes {
+			if strings.Contains(lower, suffix) {
+				return p, true
+			}
+		}
+	}
+	return Provider{}, false
+}
+
+// Assessment is the takeover rating of one dangling subdomain.
+type Assessment struct {
+	CNAME        string       // empty for a stale record with no CNAME
+	Provider     string       // "Unknown" for an unrecognized CNAME target
+	Claimability Claimability // empty without a CNAME or for an unknown provider
+	Severity     models.Severity
+}
+
+// Assess rates a dangling subdomain. A CNAME to a claimable provider is
+// critical, one that needs verification high, an unknown target medium, and a
+// non-claimable provider or a stale record without a CNAME low.
+func Assess(sub models.Subdomain) Assessment {
+	var cname string
+	for _, r := range sub.DNSRecords {
+		if r.Type == models.DNSRecordCNAME {
+			cname = r.Value
+			break
+		}
+	}
+	if cname == "" {
+		return Assessment{Severity: models.SeverityLow}
+	}
+
+	p, ok := Classify(cname)
+	if !ok {
+		return Assessment{CNAME: cname, Provider: "Unknown", Severity: models.SeverityMedium}
+	}
+	a := Assessment{CNAME: cname, Provider: p.Name, Claimability: p.Claimability}
+	switch p.Claimability {
+	case Claimable:
+		a.Severity = models.SeverityCritical
+	case NeedsVerification:
+		a.Severity = models.SeverityHigh
+	default:
+		a.Severity = models.SeverityLow
+	}
+	return a
+}
+
+// Risk describes the assessment in a few words for reports, e.g.
+// "AWS S3 (claimable)" or "Unknown provider".
+func (a Assessment) Risk() string {
+	switch {
+	case a.CNAME == "":
+		return "stale record"
+	case a.Claimability == "":
+		return "Unknown provider"
+	default:
+		return a.Provider + " (" + string(a.Claimability) + ")"
+	}
+}
@@ -0,0 +1,82 @@
+package tools
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Subdomain enumeration engines, selected by the discovery.engine config
+// setting.
+const (
+	EngineSubfinder = "subfinder" // subfinder only (the default)
+	EngineAmass     = "amass"     // amass only
+	EngineBoth      = "both"      // both, results merged
+)
+
+// Engines lists the valid discovery.engine values.
+var Engines = []string{EngineSubfinder, EngineAmass, EngineBoth}
+
+// EngineTools reports which enumeration tools engine runs; empty selects
+// subfinder.
+func EngineTools(engine string) (subfinder, amass bool) {
+	switch engine {
+	case EngineAmass:
+		return false, true
+	case EngineBoth:
+		return true, true
+	}
+	return true, false
+}
+
+// RunAmass executes amass enum for the given domain and returns the
+// discovered names under it, deduplicated.
+//
+// amass v3 prints one name per line while v4 prints graph edges such as
+// "www.example.com (FQDN) --> a_record --> 192.0.2.1 (IPAddress)", so every
+// field of every line is considered and those in scope are kept.
+func RunAmass(ctx context.Context, domain string, binaryPath string) ([]string, error) {
+	// Use provided binary path or fall back to tool name
+	binary := "amass"
+	if binaryPath != "" {
+		binary = binaryPath
+	}
+
+	// Build arguments: enum -d domain -nocolor
+	args := []string{
+		"enum",
+		"-d", domain,
+		"-nocolor", // No ANSI colour codes in the output
+	}
+
+	// Execute via RunTool
+	result, err := RunTool(ctx, binary, args...)
+	if err != nil {
+		return nil, fmt.Errorf("amass execution failed: %w", err)
+	}
+
+	// Parse output and extract subdomains
+	seen := make(map[string]bool)
+	var names []string
+	scanner := bufio.NewScanner(bytes.NewReader(result.Stdout))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	for scanner.Scan() {
+		for _, field := range strings.Fields(scanner.Text()) {
+			name := strings.ToLower(strings.TrimSuffix(field, "."))
+			if seen[name] || !strings.HasSuffix(name, "."+domain) {
+				continue
+			}
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read amass output: %w", err)
+	}
+
+	return names, nil
+}
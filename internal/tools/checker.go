@@ -33,6 +33,13 @@ func DefaultTools() []ToolRequirement {
 			InstallCmd: "go install -v github.com/projectdiscovery/subfinder/v2/cmd/subfinder@latest",
 			Purpose:    "Subdomain discovery",
 		},
+		{
+			Name:       "amass",
+			Binary:     "amass",
+			Required:   false,
+			InstallCmd: "go install -v github.com/owasp-amass/amass/v4/...@master",
+			Purpose:    "Subdomain discovery (discovery.engine: amass or both)",
+		},
 		{
 			Name:       "tlsx",
 			Binary:     "tlsx",
@@ -43,10 +50,17 @@ func DefaultTools() []ToolRequirement {
 		{
 			Name:       "dig",
 			Binary:     "dig",
-			Required:   true,
+			Required:   !NativeDNS(),
 			InstallCmd: "apt install dnsutils (or brew install bind on macOS)",
 			Purpose:    "DNS resolution",
 		},
+		{
+			Name:       "dnsx",
+			Binary:     "dnsx",
+			Required:   BulkDNS(),
+			InstallCmd: "go install -v github.com/projectdiscovery/dnsx/cmd/dnsx@latest",
+			Purpose:    "Bulk DNS resolution (discovery.dns_resolver.mode: dnsx)",
+		},
 		{
 			Name:       "cdncheck",
 			Binary:     "cdncheck",
@@ -61,6 +75,13 @@ func DefaultTools() []ToolRequirement {
 			InstallCmd: "apt install masscan (or brew install masscan on macOS)",
 			Purpose:    "Fast port scanning",
 		},
+		{
+			Name:       "naabu",
+			Binary:     "naabu",
+			Required:   false,
+			InstallCmd: "go install -v github.com/projectdiscovery/naabu/v2/cmd/naabu@latest",
+			Purpose:    "Port scanning without root (portscan.scanner: naabu)",
+		},
 		{
 			Name:       "nmap",
 			Binary:     "nmap",
@@ -82,6 +103,13 @@ func DefaultTools() []ToolRequirement {
 			InstallCmd: "go install -v github.com/sensepost/gowitness@latest",
 			Purpose:    "Screenshot capture",
 		},
+		{
+			Name:       "tesseract",
+			Binary:     "tesseract",
+			Required:   false,
+			InstallCmd: "apt install tesseract-ocr (or brew install tesseract on macOS)",
+			Purpose:    "OCR for screenshot triage",
+		},
 		{
 			Name:       "nuclei",
 			Binary:     "nuclei",
@@ -108,6 +136,14 @@ func CheckTool(tool ToolRequirement) CheckResult {
 		Found: false,
 	}
 
+	// In fake-tools mode the fixture stands in for the binary
+	if isFakeTool(tool.Binary) {
+		result.Found = true
+		result.Path = "(fixture)"
+		result.Version = "fake"
+		return result
+	}
+
 	// Try to find the binary in PATH
 	path, err := exec.LookPath(tool.Binary)
 	if err != nil {
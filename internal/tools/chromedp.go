@@ -0,0 +1,274 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chromedp/chromedp"
+)
+
+// chromeBinaries are the Chrome/Chromium executable names searched in PATH.
+var chromeBinaries = []string{
+	"google-chrome",
+	"google-chrome-stable",
+	"chromium",
+	"chromium-browser",
+	"chrome",
+	"headless-shell",
+}
+
+// ChromeCaptureOptions controls the native chromedp screenshot engine.
+type ChromeCaptureOptions struct {
+	Concurrency int           // parallel tabs, default 4
+	FullPage    bool          // capture the full scrollable page
+	Width       int           // viewport width, default 1920
+	Height      int           // viewport height, default 1080
+	Delay       time.Duration // wait after load before capturing
+	Timeout     time.Duration // per page, default 60s
+	SaveDOM     bool          // also write the rendered DOM next to the screenshot
+}
+
+// ChromeCapture is the outcome of rendering one URL.
+type ChromeCapture struct {
+	URL     string
+	Path    string // screenshot file; empty when the capture failed
+	DOMPath string // rendered DOM file when SaveDOM was set
+	Title   string // document.title after rendering
+	Text    string // visible text (body.innerText) after rendering
+	Error   string
+}
+
+// FindChrome returns the path of a Chrome/Chromium binary, or "" if none is
+// installed.
+func FindChrome() string {
+	for _, name := range chromeBinaries {
+		if path, err := exec.LookPath(name); err == nil {
+			return path
+		}
+	}
+	for _, path := range []string{
+		"/Applications/Google Chrome.app/Contents/MacOS/Google Chrome",
+		"/Applications/Chromium.app/Contents/MacOS/Chromium",
+	} {
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	return ""
+}
+
+// ChromeAvailable reports whether the chromedp engine can run. Fake-tools
+// mode always has a (simulated) browser.
+func ChromeAvailable() bool {
+	return FakeToolsEnabled() || FindChrome() != ""
+}
+
+// ScreenshotFilename derives a stable file name from a URL, e.g.
+// https://www.example.com/ → "https-www.example.com-443.png". The same URL
+// always maps to the same file so reruns overwrite rather than accumulate.
+func ScreenshotFilename(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return sanitizeFilename(rawURL) + ".png"
+	}
+
+	port := u.Port()
+	if port == "" {
+		port = "80"
+		if u.Scheme == "https" {
+			port = "443"
+		}
+	}
+	name := u.Scheme + "-" + u.Hostname() + "-" + port
+	if path := strings.Trim(u.EscapedPath(), "/"); path != "" {
+		name += "-" + path
+	}
+	return sanitizeFilename(name) + ".png"
+}
+
+// sanitizeFilename replaces anything outside [A-Za-z0-9.-] with '-'.
+func sanitizeFilename(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.', r == '-':
+			return r
+		default:
+			return '-'
+		}
+	}, s)
+}
+
+// CaptureWithChrome renders each URL in headless Chrome and writes a PNG per
+// URL into dir, named by ScreenshotFilename. Results are returned in input
+// order; a page that fails to render has Error set but does not fail the
+// batch. Certificate errors are ignored so self-signed hosts still render.
+func CaptureWithChrome(ctx context.Context, urls []string, dir string, opts ChromeCaptureOptions) ([]ChromeCapture, error) {
+	if len(urls) == 0 {
+		return nil, nil
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create screenshot directory %q: %w", dir, err)
+	}
+
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = 4
+	}
+	if opts.Width <= 0 {
+		opts.Width = 1920
+	}
+	if opts.Height <= 0 {
+		opts.Height = 1080
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = 60 * time.Second
+	}
+
+	if FakeToolsEnabled() {
+		return fakeChromeCaptures(urls, dir)
+	}
+
+	chromePath := FindChrome()
+	if chromePath == "" {
+		return nil, fmt.Errorf("no Chrome or Chromium binary found in PATH")
+	}
+
+	allocOpts := append(chromedp.DefaultExecAllocatorOptions[:],
+		chromedp.ExecPath(chromePath),
+		chromedp.WindowSize(opts.Width, opts.Height),
+		chromedp.Flag("ignore-certificate-errors", true),
+	)
+	allocCtx, cancelAlloc := chromedp.NewExecAllocator(ctx, allocOpts...)
+	defer cancelAlloc()
+
+	browserCtx, cancelBrowser := chromedp.NewContext(allocCtx)
+	defer cancelBrowser()
+
+	// Start the browser once up front so a launch failure is reported as
+	// such rather than as N page failures.
+	if err := chromedp.Run(browserCtx); err != nil {
+		return nil, fmt.Errorf("starting chrome: %w", err)
+	}
+
+	captures := make([]ChromeCapture, len(urls))
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, opts.Concurrency)
+	for i, u := range urls {
+		if ctx.Err() != nil {
+			break
+		}
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, u string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			captures[i] = capturePage(browserCtx, u, dir, opts)
+		}(i, u)
+	}
+	wg.Wait()
+
+	if ctx.Err() != nil {
+		return captures, fmt.Errorf("chrome capture cancelled: %w", ctx.Err())
+	}
+	return captures, nil
+}
+
+// capturePage renders a single URL in its own tab.
+func capturePage(browserCtx context.Context, pageURL, dir string, opts ChromeCaptureOptions) ChromeCapture {
+	capture := ChromeCapture{URL: pageURL}
+
+	tabCtx, cancelTab := chromedp.NewContext(browserCtx)
+	defer cancelTab()
+	tabCtx, cancelTimeout := context.WithTimeout(tabCtx, opts.Timeout)
+	defer cancelTimeout()
+
+	var buf []byte
+	var html string
+	actions := []chromedp.Action{chromedp.Navigate(pageURL)}
+	if opts.Delay > 0 {
+		actions = append(actions, chromedp.Sleep(opts.Delay))
+	}
+	actions = append(actions,
+		chromedp.Title(&capture.Title),
+		chromedp.Evaluate(`document.body ? document.body.innerText : ""`, &capture.Text),
+	)
+	if opts.SaveDOM {
+		actions = append(actions, chromedp.OuterHTML("html", &html, chromedp.ByQuery))
+	}
+	if opts.FullPage {
+		// Quality 100 keeps the output PNG
+		actions = append(actions, chromedp.FullScreenshot(&buf, 100))
+	} else {
+		actions = append(actions, chromedp.CaptureScreenshot(&buf))
+	}
+
+	if err := chromedp.Run(tabCtx, actions...); err != nil {
+		capture.Error = err.Error()
+		return capture
+	}
+
+	path := filepath.Join(dir, ScreenshotFilename(pageURL))
+	if err := os.WriteFile(path, buf, 0644); err != nil {
+		capture.Error = fmt.Sprintf("writing screenshot: %v", err)
+		return capture
+	}
+	capture.Path = path
+
+	if opts.SaveDOM {
+		domPath := strings.TrimSuffix(path, ".png") + ".html"
+		if err := os.WriteFile(domPath, []byte(html), 0644); err != nil {
+			capture.Error = fmt.Sprintf("writing DOM: %v", err)
+		} else {
+			capture.DOMPath = domPath
+		}
+	}
+
+	return capture
+}
+
+// writePlaceholderPNG writes the blank image fake-tools runs use in place of
+// a screenshot.
+func writePlaceholderPNG(path string) error {
+	img := image.NewGray(image.Rect(0, 0, 16, 9))
+	for i := range img.Pix {
+		img.Pix[i] = color.Gray{Y: 0xee}.Y
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("writing placeholder screenshot: %w", err)
+	}
+	err = png.Encode(f, img)
+	f.Close()
+	if err != nil {
+		return fmt.Errorf("encoding placeholder screenshot: %w", err)
+	}
+	return nil
+}
+
+// fakeChromeCaptures writes a blank placeholder PNG per URL so that fake-tools
+// runs exercise the same file naming and ScreenshotPath plumbing. Visible
+// text comes from chrome.fixture, keyed by URL.
+func fakeChromeCaptures(urls []string, dir string) ([]ChromeCapture, error) {
+	captures := make([]ChromeCapture, len(urls))
+	for i, u := range urls {
+		path := filepath.Join(dir, ScreenshotFilename(u))
+		if err := writePlaceholderPNG(path); err != nil {
+			return nil, err
+		}
+		text, err := FakeFixture("chrome", u)
+		if err != nil {
+			return nil, err
+		}
+		captures[i] = ChromeCapture{URL: u, Path: path, Text: strings.Join(text, "\n")}
+	}
+	return captures, nil
+}
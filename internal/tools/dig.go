@@ -5,6 +5,7 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"strconv"
 	"strings"
 )
 
@@ -20,6 +21,16 @@ type DNSResult struct {
 // ResolveSubdomains resolves DNS A/AAAA records for the given subdomains.
 // It returns a slice of DNSResult containing resolution status and IPs.
 func ResolveSubdomains(ctx context.Context, subdomains []string, binaryPath string) ([]DNSResult, error) {
+	return ResolveSubdomainsVia(ctx, subdomains, "", binaryPath)
+}
+
+// ResolveSubdomainsVia is ResolveSubdomains against a specific resolver.
+// An empty server uses the system resolver.
+func ResolveSubdomainsVia(ctx context.Context, subdomains []string, server string, binaryPath string) ([]DNSResult, error) {
+	if useNative() {
+		return nativeResolve(ctx, subdomains, server), nil
+	}
+
 	// Use provided binary path or fall back to tool name
 	binary := "dig"
 	if binaryPath != "" {
@@ -31,6 +42,9 @@ func ResolveSubdomains(ctx context.Context, subdomains []string, binaryPath stri
 	for _, subdomain := range subdomains {
 		// Run dig +short for A/AAAA records
 		args := []string{"+short", subdomain}
+		if server != "" {
+			args = append(args, "@"+server)
+		}
 
 		result, err := RunTool(ctx, binary, args...)
 		dnsResult := DNSResult{
@@ -78,6 +92,10 @@ func ResolveSubdomains(ctx context.Context, subdomains []string, binaryPath stri
 // CheckCNAME checks if a subdomain has a CNAME record.
 // Returns the CNAME target or empty string if no CNAME exists.
 func CheckCNAME(ctx context.Context, subdomain string, binaryPath string) (string, error) {
+	if useNative() {
+		return nativeCNAME(ctx, subdomain)
+	}
+
 	// Use provided binary path or fall back to tool name
 	binary := "dig"
 	if binaryPath != "" {
@@ -105,3 +123,189 @@ func CheckCNAME(ctx context.Context, subdomain string, binaryPath string) (strin
 	// No CNAME found
 	return "", nil
 }
+
+// ZoneRecord is a single resource record returned by a zone transfer
+type ZoneRecord struct {
+	Name  string
+	TTL   int
+	Type  string
+	Value string
+}
+
+// LookupNS returns the authoritative nameservers for domain, without
+// trailing dots.
+func LookupNS(ctx context.Context, domain string, binaryPath string) ([]string, error) {
+	if useNative() {
+		nameservers, _, err := nativeRecords(ctx, domain, "NS", "")
+		if err != nil && !notFound(err) {
+			return nil, fmt.Errorf("NS lookup failed: %w", err)
+		}
+		for i, ns := range nameservers {
+			nameservers[i] = strings.ToLower(ns)
+		}
+		return nameservers, nil
+	}
+
+	binary := "dig"
+	if binaryPath != "" {
+		binary = binaryPath
+	}
+
+	result, err := RunTool(ctx, binary, "+short", "NS", domain)
+	if err != nil {
+		return nil, fmt.Errorf("NS lookup failed: %w", err)
+	}
+
+	var nameservers []string
+	scanner := bufio.NewScanner(bytes.NewReader(result.Stdout))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, ";") {
+			continue
+		}
+		nameservers = append(nameservers, strings.ToLower(strings.TrimSuffix(line, ".")))
+	}
+
+	return nameservers, nil
+}
+
+// ZoneTransfer attempts an AXFR of domain from nameserver. A refused or
+// failed transfer is not an error: dig reports it as a comment, and the
+// result is simply empty.
+func ZoneTransfer(ctx context.Context, domain, nameserver string, binaryPath string) ([]ZoneRecord, error) {
+	binary := "dig"
+	if binaryPath != "" {
+		binary = binaryPath
+	}
+
+	args := []string{"AXFR", domain, "@" + nameserver, "+noall", "+answer", "+time=5", "+tries=1"}
+
+	result, err := RunTool(ctx, binary, args...)
+	if err != nil {
+		return nil, fmt.Errorf("zone transfer from %s failed: %w", nameserver, err)
+	}
+
+	// Answer lines: "<name> <ttl> <class> <type> <rdata...>"
+	var records []ZoneRecord
+	scanner := bufio.NewScanner(bytes.NewReader(result.Stdout))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, ";") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) < 5 {
+			continue
+		}
+		ttl, err := strconv.Atoi(fields[1])
+		if err != nil {
+			continue
+		}
+
+		records = append(records, ZoneRecord{
+			Name:  strings.ToLower(strings.TrimSuffix(fields[0], ".")),
+			TTL:   ttl,
+			Type:  strings.ToUpper(fields[3]),
+			Value: strings.Join(fields[4:], " "),
+		})
+	}
+
+	return records, nil
+}
+
+// LookupRecords returns the rdata of every rrtype record for name, without
+// trailing dots. When server is non-empty the query goes to that resolver;
+// with checkingDisabled the resolver returns records even when they fail
+// DNSSEC validation (+cd).
+func LookupRecords(ctx context.Context, name, rrtype, server string, checkingDisabled bool, binaryPath string) ([]string, error) {
+	if useNative() && !checkingDisabled {
+		if values, ok, err := nativeRecords(ctx, name, rrtype, server); ok {
+			if err != nil && !notFound(err) {
+				return nil, fmt.Errorf("%s lookup failed: %w", rrtype, err)
+			}
+			return values, nil
+		}
+	}
+
+	binary := "dig"
+	if binaryPath != "" {
+		binary = binaryPath
+	}
+
+	args := []string{"+short"}
+	if checkingDisabled {
+		args = append(args, "+cd")
+	}
+	args = append(args, rrtype, name)
+	if server != "" {
+		args = append(args, "@"+server)
+	}
+
+	result, err := RunTool(ctx, binary, args...)
+	if err != nil {
+		return nil, fmt.Errorf("%s lookup failed: %w", rrtype, err)
+	}
+
+	var values []string
+	scanner := bufio.NewScanner(bytes.NewReader(result.Stdout))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, ";") {
+			continue
+		}
+		values = append(values, strings.TrimSuffix(line, "."))
+	}
+
+	return values, nil
+}
+
+// DNSStatus is the outcome of a query as reported in dig's header.
+type DNSStatus struct {
+	Rcode         string // NOERROR, NXDOMAIN, SERVFAIL...; empty when dig printed no header
+	Authenticated bool   // the resolver validated the answer (AD flag)
+}
+
+// QueryStatus sends an rrtype query for name with the AD bit set and returns
+// the response code and whether the resolver vouched for the answer. Only a
+// validating resolver sets AD, so Authenticated is false for every name when
+// the resolver does not check DNSSEC.
+func QueryStatus(ctx context.Context, name, rrtype, server string, binaryPath string) (DNSStatus, error) {
+	binary := "dig"
+	if binaryPath != "" {
+		binary = binaryPath
+	}
+
+	args := []string{"+noall", "+comments", "+adflag", "+time=5", "+tries=1", rrtype, name}
+	if server != "" {
+		args = append(args, "@"+server)
+	}
+
+	result, err := RunTool(ctx, binary, args...)
+	if err != nil {
+		return DNSStatus{}, fmt.Errorf("%s query for %s failed: %w", rrtype, name, err)
+	}
+
+	// Header lines:
+	//   ;; ->>HEADER<<- opcode: QUERY, status: NOERROR, id: 4242
+	//   ;; flags: qr rd ra ad; QUERY: 1, ANSWER: 1, AUTHORITY: 0, ADDITIONAL: 1
+	var status DNSStatus
+	scanner := bufio.NewScanner(bytes.NewReader(result.Stdout))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if _, rest, ok := strings.Cut(line, "status: "); ok && strings.Contains(line, "HEADER") {
+			status.Rcode, _, _ = strings.Cut(rest, ",")
+			continue
+		}
+		if rest, ok := strings.CutPrefix(line, ";; flags:"); ok {
+			flags, _, _ := strings.Cut(rest, ";")
+			for _, f := range strings.Fields(flags) {
+				if f == "ad" {
+					status.Authenticated = true
+				}
+			}
+		}
+	}
+
+	return status, nil
+}
@@ -0,0 +1,127 @@
+package tools
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hakim/reconpipe/pkg/models"
+)
+
+// DnsxResult is one line of dnsx's JSON output: the answers for one host.
+// With -rcode dnsx prints every host that got a response, with or without
+// records; a host whose queries all went unanswered is left out.
+type DnsxResult struct {
+	Host       string   `json:"host"`
+	Resolver   []string `json:"resolver"`    // e.g. "1.1.1.1:53"
+	StatusCode string   `json:"status_code"` // response code, e.g. NOERROR or NXDOMAIN
+	A          []string `json:"a"`
+	AAAA       []string `json:"aaaa"`
+	CNAME      []string `json:"cname"`
+	MX         []string `json:"mx"`
+	TXT        []string `json:"txt"`
+}
+
+// Records returns the answers of r as DNS records: addresses first, then
+// the CNAME chain in order, MX and TXT. Each notes the resolvers that
+// answered, without their port.
+func (r DnsxResult) Records() []models.DNSRecord {
+	var resolvers []string
+	for _, res := range r.Resolver {
+		resolvers = append(resolvers, strings.TrimSuffix(res, ":53"))
+	}
+
+	var records []models.DNSRecord
+	add := func(typ models.DNSRecordType, values []string) {
+		for _, v := range values {
+			v = strings.TrimSuffix(strings.TrimSpace(v), ".")
+			if v != "" {
+				records = append(records, models.DNSRecord{Type: typ, Value: v, Resolvers: resolvers})
+			}
+		}
+	}
+	add(models.DNSRecordA, r.A)
+	add(models.DNSRecordAAAA, r.AAAA)
+	add(models.DNSRecordCNAME, r.CNAME)
+	add(models.DNSRecordMX, r.MX)
+	add(models.DNSRecordTXT, r.TXT)
+	return records
+}
+
+// RunDnsx resolves hosts in bulk with dnsx, querying A, AAAA, CNAME, MX
+// and TXT records, and returns its parsed results. Hosts are piped to
+// stdin. If threads > 0, it sets the concurrency (-t flag), and if
+// timeout > 0 how long each query waits for an answer (-timeout flag).
+// resolvers, when given, replace dnsx's built-in resolver list (-r flag).
+func RunDnsx(ctx context.Context, hosts []string, binaryPath string, threads int, timeout time.Duration, resolvers []string) ([]DnsxResult, error) {
+	// Return early if no hosts provided
+	if len(hosts) == 0 {
+		return []DnsxResult{}, nil
+	}
+
+	// Use provided binary path or fall back to tool name
+	binary := "dnsx"
+	if binaryPath != "" {
+		binary = binaryPath
+	}
+
+	// Build arguments: -silent -json -a -aaaa -cname -mx -txt -rcode ...
+	args := []string{
+		"-silent",
+		"-json", // JSONL output
+		"-a", "-aaaa", "-cname", "-mx", "-txt",
+		// Print hosts without records too, so NXDOMAIN can be told apart
+		// from no answer at all
+		"-rcode", "noerror,nxdomain,servfail,refused",
+	}
+
+	// Add thread count if specified
+	if threads > 0 {
+		args = append(args, "-t", strconv.Itoa(threads))
+	}
+	if timeout > 0 {
+		args = append(args, "-timeout", timeout.String())
+	}
+	if len(resolvers) > 0 {
+		args = append(args, "-r", strings.Join(resolvers, ","))
+	}
+
+	// Pipe hosts to stdin (one per line) and collect JSONL output
+	result, err := RunToolWithInput(ctx, binary, hosts, args...)
+	if err != nil {
+		return nil, fmt.Errorf("dnsx execution failed: %w", err)
+	}
+
+	// Parse JSONL output (one JSON object per line)
+	var results []DnsxResult
+	scanner := bufio.NewScanner(bytes.NewReader(result.Stdout))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+
+		var dnsxResult DnsxResult
+		if err := json.Unmarshal(line, &dnsxResult); err != nil {
+			// Log warning and continue - some lines may not be valid JSON
+			fmt.Printf("Warning: failed to parse dnsx JSON line: %v\n", err)
+			continue
+		}
+		dnsxResult.Host = strings.ToLower(strings.TrimSuffix(dnsxResult.Host, "."))
+
+		results = append(results, dnsxResult)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read dnsx output: %w", err)
+	}
+
+	return results, nil
+}
@@ -0,0 +1,471 @@
+package tools
+
+import (
+	"bufio"
+	"bytes"
+	"embed"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Fake-tools mode replaces every external binary with canned fixture output so
+// the whole pipeline can run end-to-end on a machine with none of the tools
+// installed (CI of the pipeline itself, demos, report development).
+//
+// Fixtures are plain-text files named {tool}.fixture. Each non-comment line is
+// "<key>\t<output line>". The key is matched against the tool's input — the
+// domain for subfinder/amass/tlsx, "<TYPE> <name>" for dig, the IP for masscan,
+// "<port>/<proto>" for nmap, each URL of gowitness's input file, and each
+// stdin line for httpx, cdncheck, dnsx, naabu and nuclei; an httpx run
+// sending a Host header uses "<host>@<target>". Keys may contain a single
+// {{domain}} placeholder; the captured value is substituted into the output
+// so one fixture set works for any target.
+// When several keys match, only the lines of the most specific key are used.
+// Native probes that run no binary read their own fixture through
+// FakeFixture: the mail checks keyed by "<ip>:<port>", the chromedp engine
+// (page text) and the HTTP/2, HTTP/3, gRPC and WebSocket checks keyed by URL,
+// and discovery's passive sources (response body) keyed by "<source> <domain>".
+
+//go:embed fixtures/*.fixture
+var embeddedFixtures embed.FS
+
+// fakeTools holds the process-wide fake-tools switch. It is set once at
+// startup (from --fake-tools) before any stage runs.
+var fakeTools struct {
+	enabled bool
+	dir     string
+}
+
+// fakeToolNames lists the binaries fake mode stands in for. Anything else
+// (e.g. python3 for PDF generation) is still looked up and executed normally.
+var fakeToolNames = map[string]bool{
+	"subfinder": true,
+	"amass":     true,
+	"tlsx":      true,
+	"dig":       true,
+	"dnsx":      true,
+	"cdncheck":  true,
+	"masscan":   true,
+	"naabu":     true,
+	"nmap":      true,
+	"httpx":     true,
+	"gowitness": true,
+	"nuclei":    true,
+}
+
+// EnableFakeTools switches the tools package into fixture mode. When dir is
+// non-empty, {dir}/{tool}.fixture overrides the embedded fixture for that tool.
+func EnableFakeTools(dir string) {
+	fakeTools.enabled = true
+	fakeTools.dir = dir
+}
+
+// FakeToolsEnabled reports whether fixture mode is active.
+func FakeToolsEnabled() bool {
+	return fakeTools.enabled
+}
+
+// isFakeTool reports whether binary is served from fixtures in fake mode.
+func isFakeTool(binary string) bool {
+	return FakeToolsEnabled() && fakeToolNames[toolName(binary)]
+}
+
+// toolName reduces a binary path to the bare tool name used for fixture lookup.
+func toolName(binary string) string {
+	return strings.TrimSuffix(filepath.Base(binary), ".exe")
+}
+
+// fixtureLine is one "<key>\t<output>" record from a fixture file.
+type fixtureLine struct {
+	key    string
+	output string
+}
+
+// loadFixture reads the fixture for a tool, preferring the override directory.
+// A tool without any fixture yields an empty set rather than an error.
+func loadFixture(tool string) ([]fixtureLine, error) {
+	name := tool + ".fixture"
+
+	var data []byte
+	var err error
+	if fakeTools.dir != "" {
+		data, err = os.ReadFile(filepath.Join(fakeTools.dir, name))
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("reading fixture %s: %w", name, err)
+		}
+	}
+	if data == nil {
+		data, err = embeddedFixtures.ReadFile("fixtures/" + name)
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil, nil
+			}
+			return nil, fmt.Errorf("reading embedded fixture %s: %w", name, err)
+		}
+	}
+
+	var lines []fixtureLine
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		text := scanner.Text()
+		if strings.TrimSpace(text) == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		key, output, ok := strings.Cut(text, "\t")
+		if !ok {
+			return nil, fmt.Errorf("fixture %s line %d: missing tab between key and output", name, lineNo)
+		}
+		lines = append(lines, fixtureLine{key: key, output: output})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading fixture %s: %w", name, err)
+	}
+
+	return lines, nil
+}
+
+// matchKey reports whether s matches a fixture key. The key may contain one
+// {{domain}} placeholder that captures a non-empty substring of s. score is the
+// number of literal characters matched and is used to pick the most specific key.
+func matchKey(key, s string) (domain string, score int, ok bool) {
+	prefix, suffix, hasPlaceholder := strings.Cut(key, "{{domain}}")
+	if !hasPlaceholder {
+		return "", len(key), key == s
+	}
+	if len(s) <= len(prefix)+len(suffix) || !strings.HasPrefix(s, prefix) || !strings.HasSuffix(s, suffix) {
+		return "", 0, false
+	}
+	return s[len(prefix) : len(s)-len(suffix)], len(prefix) + len(suffix), true
+}
+
+// fixtureOutput returns the output lines recorded for a single input, with
+// {{domain}} and {{input}} substituted.
+func fixtureOutput(lines []fixtureLine, input string) []string {
+	bestKey := ""
+	bestScore := -1
+	bestDomain := ""
+	for _, l := range lines {
+		domain, score, ok := matchKey(l.key, input)
+		if ok && score > bestScore {
+			bestKey, bestScore, bestDomain = l.key, score, domain
+		}
+	}
+	if bestScore < 0 {
+		return nil
+	}
+
+	var out []string
+	for _, l := range lines {
+		if l.key != bestKey {
+			continue
+		}
+		line := strings.ReplaceAll(l.output, "{{domain}}", bestDomain)
+		line = strings.ReplaceAll(line, "{{input}}", input)
+		out = append(out, line)
+	}
+	return out
+}
+
+// FakeFixture returns the fixture lines recorded for key in {name}.fixture.
+// It serves native probes (which run no binary) in fake-tools mode; a
+// missing fixture or key yields no lines.
+func FakeFixture(name, key string) ([]string, error) {
+	lines, err := loadFixture(name)
+	if err != nil {
+		return nil, err
+	}
+	return fixtureOutput(lines, key), nil
+}
+
+// runFakeTool stands in for RunTool/RunToolWithInput in fake mode.
+func runFakeTool(binary string, args []string, input []string) (*ToolResult, error) {
+	tool := toolName(binary)
+	if !fakeToolNames[tool] {
+		return nil, fmt.Errorf("fake tools: no fixture support for %q", tool)
+	}
+
+	lines, err := loadFixture(tool)
+	if err != nil {
+		return nil, err
+	}
+
+	var stdout bytes.Buffer
+	emit := func(out []string) {
+		for _, line := range out {
+			stdout.WriteString(line)
+			stdout.WriteByte('\n')
+		}
+	}
+
+	switch tool {
+	case "subfinder", "amass":
+		emit(fixtureOutput(lines, argValue(args, "-d")))
+
+	case "tlsx":
+		emit(fixtureOutput(lines, argValue(args, "-host")))
+
+	case "dig":
+		emit(fixtureOutput(lines, digQuery(args)))
+
+	case "masscan":
+		if err := fakeMasscan(lines, args); err != nil {
+			return nil, err
+		}
+
+	case "nmap":
+		if err := fakeNmap(lines, args); err != nil {
+			return nil, err
+		}
+
+	case "gowitness":
+		if err := fakeGowitness(lines, args); err != nil {
+			return nil, err
+		}
+
+	default:
+		// stdin-driven tools: httpx, cdncheck, dnsx, naabu, nuclei (which may
+		// read a -list file instead)
+		if list := argValue(args, "-list"); list != "" {
+			data, err := os.ReadFile(list)
+			if err != nil {
+				return nil, fmt.Errorf("reading %s input: %w", tool, err)
+			}
+			input = strings.Fields(string(data))
+		}
+		prefix := ""
+		if host, ok := strings.CutPrefix(argValue(args, "-H"), "Host: "); ok {
+			prefix = host + "@"
+		}
+		for _, in := range input {
+			emit(fixtureOutput(lines, prefix+strings.TrimSpace(in)))
+		}
+	}
+
+	return &ToolResult{Stdout: stdout.Bytes()}, nil
+}
+
+// fakeGowitnessSeen counts the gowitness runs each URL was in, for
+// gowitness.fixture's fail-once.
+var fakeGowitnessSeen = struct {
+	sync.Mutex
+	runs map[string]int
+}{runs: make(map[string]int)}
+
+// fakeGowitness writes a placeholder screenshot, named the way gowitness
+// names its files, for each URL in the -f file into the -s directory. URLs
+// whose gowitness.fixture output is "fail" are never captured, those with
+// "fail-once" only on their second run.
+func fakeGowitness(lines []fixtureLine, args []string) error {
+	data, err := os.ReadFile(argValue(args, "-f"))
+	if err != nil {
+		return fmt.Errorf("reading gowitness input: %w", err)
+	}
+	for _, u := range strings.Fields(string(data)) {
+		fakeGowitnessSeen.Lock()
+		fakeGowitnessSeen.runs[u]++
+		runs := fakeGowitnessSeen.runs[u]
+		fakeGowitnessSeen.Unlock()
+
+		switch strings.Join(fixtureOutput(lines, u), "") {
+		case "fail":
+			continue
+		case "fail-once":
+			if runs == 1 {
+				continue
+			}
+		}
+		// gowitness v3 style: https://host:8443/a → https---host-8443-a.png
+		name := u
+		if parsed, err := url.Parse(u); err == nil && parsed.Port() == "" {
+			port := "80"
+			if parsed.Scheme == "https" {
+				port = "443"
+			}
+			parsed.Host += ":" + port
+			name = parsed.String()
+		}
+		if err := writePlaceholderPNG(filepath.Join(argValue(args, "-s"), sanitizeFilename(strings.TrimSuffix(name, "/"))+".png")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// argValue returns the value following flag in args, or "" when absent.
+func argValue(args []string, flag string) string {
+	for i := 0; i < len(args)-1; i++ {
+		if args[i] == flag {
+			return args[i+1]
+		}
+	}
+	return ""
+}
+
+// digQuery turns "dig +short [TYPE] name [@server]" arguments into the
+// "<TYPE> <name>" fixture key, with " @<server>" appended when a server is
+// given. The record type defaults to A, as it does for dig itself.
+func digQuery(args []string) string {
+	var positional []string
+	server := ""
+	for _, a := range args {
+		if strings.HasPrefix(a, "@") {
+			server = " " + a
+			continue
+		}
+		if strings.HasPrefix(a, "+") {
+			continue
+		}
+		positional = append(positional, a)
+	}
+
+	switch len(positional) {
+	case 0:
+		return ""
+	case 1:
+		return "A " + positional[0] + server
+	default:
+		return strings.ToUpper(positional[0]) + " " + positional[1] + server
+	}
+}
+
+// fakeMasscan writes the fixture records for every IP in the -iL input file to
+// the -oJ output file, in masscan's JSON array format.
+func fakeMasscan(lines []fixtureLine, args []string) error {
+	inputPath := argValue(args, "-iL")
+	outputPath := argValue(args, "-oJ")
+	if inputPath == "" || outputPath == "" {
+		return fmt.Errorf("fake masscan: -iL and -oJ are required")
+	}
+
+	data, err := os.ReadFile(inputPath)
+	if err != nil {
+		return fmt.Errorf("fake masscan: reading input: %w", err)
+	}
+
+	var records []string
+	for _, ip := range strings.Fields(string(data)) {
+		records = append(records, fixtureOutput(lines, ip)...)
+	}
+
+	out := "[\n" + strings.Join(records, ",\n") + "\n]\n"
+	if len(records) == 0 {
+		out = ""
+	}
+	return os.WriteFile(outputPath, []byte(out), 0644)
+}
+
+// fakeNmap renders an nmap XML report for the target IP and requested ports,
+// taking service details from "<port>/<proto>\t<service>|<product>|<version>"
+// fixture records. Ports without a record are reported open with no service.
+// A UDP scan (-sU) of the IPs in an -iL file reports only the ports with a
+// "<ip> <port>/udp" record, as a UDP scan is not narrowed to open ports
+// beforehand.
+func fakeNmap(lines []fixtureLine, args []string) error {
+	outputPath := argValue(args, "-oX")
+	portList := argValue(args, "-p")
+	if outputPath == "" || len(args) == 0 {
+		return fmt.Errorf("fake nmap: -oX and a target are required")
+	}
+	if slices.Contains(args, "-sU") {
+		return fakeNmapUDP(lines, args, outputPath, portList)
+	}
+	ip := args[len(args)-1]
+
+	host := nmapHost{
+		Addresses: []nmapAddress{fakeNmapAddress(ip)},
+	}
+
+	for _, p := range strings.Split(portList, ",") {
+		portID, err := strconv.Atoi(strings.TrimSpace(p))
+		if err != nil {
+			continue
+		}
+
+		port := nmapPort{
+			Protocol: "tcp",
+			PortID:   portID,
+			State:    nmapState{State: "open"},
+		}
+		if out := fixtureOutput(lines, fmt.Sprintf("%d/tcp", portID)); len(out) > 0 {
+			port.Service = fakeNmapService(out[0])
+		}
+		host.Ports.Ports = append(host.Ports.Ports, port)
+	}
+
+	return writeFakeNmapReport(outputPath, []nmapHost{host})
+}
+
+// fakeNmapUDP renders the report of a UDP scan of the IPs in the -iL file.
+func fakeNmapUDP(lines []fixtureLine, args []string, outputPath, portList string) error {
+	data, err := os.ReadFile(argValue(args, "-iL"))
+	if err != nil {
+		return fmt.Errorf("fake nmap: reading input: %w", err)
+	}
+
+	var hosts []nmapHost
+	for _, ip := range strings.Fields(string(data)) {
+		host := nmapHost{Addresses: []nmapAddress{fakeNmapAddress(ip)}}
+		for _, p := range strings.Split(portList, ",") {
+			portID, err := strconv.Atoi(strings.TrimSpace(p))
+			if err != nil {
+				continue
+			}
+			if out := fixtureOutput(lines, fmt.Sprintf("%s %d/udp", ip, portID)); len(out) > 0 {
+				host.Ports.Ports = append(host.Ports.Ports, nmapPort{
+					Protocol: "udp",
+					PortID:   portID,
+					State:    nmapState{State: "open"},
+					Service:  fakeNmapService(out[0]),
+				})
+			}
+		}
+		hosts = append(hosts, host)
+	}
+
+	return writeFakeNmapReport(outputPath, hosts)
+}
+
+// fakeNmapAddress returns the address element of ip.
+func fakeNmapAddress(ip string) nmapAddress {
+	addrType := "ipv4"
+	if strings.Contains(ip, ":") {
+		addrType = "ipv6"
+	}
+	return nmapAddress{Addr: ip, AddrType: addrType}
+}
+
+// fakeNmapService parses a "<service>|<product>|<version>" fixture record.
+func fakeNmapService(record string) nmapService {
+	var svc nmapService
+	fields := strings.SplitN(record, "|", 3)
+	svc.Name = fields[0]
+	if len(fields) > 1 {
+		svc.Product = fields[1]
+	}
+	if len(fields) > 2 {
+		svc.Version = fields[2]
+	}
+	return svc
+}
+
+// writeFakeNmapReport writes hosts to path as an nmap -oX report.
+func writeFakeNmapReport(path string, hosts []nmapHost) error {
+	data, err := xml.MarshalIndent(nmapRun{Hosts: hosts}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("fake nmap: rendering XML: %w", err)
+	}
+
+	return os.WriteFile(path, append([]byte(xml.Header), data...), 0644)
+}
@@ -2,71 +2,239 @@ package tools
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 )
 
-// RunGowitness executes gowitness to capture screenshots for the given URLs.
-// It writes URLs to a temp file, creates the screenshot directory, then runs
-// gowitness in file-scan mode. Screenshot filenames are managed by gowitness itself.
-// Returns an error only — gowitness is fire-and-forget for screenshot capture.
-func RunGowitness(ctx context.Context, urls []string, screenshotDir string, threads int, binaryPath string) error {
+// GowitnessOptions controls how gowitness renders each page. Zero values
+// leave gowitness's own defaults in place.
+type GowitnessOptions struct {
+	Threads  int  // concurrent captures per gowitness run, default 4
+	FullPage bool // capture the whole scrollable page instead of the viewport
+	Width    int  // browser window width in pixels
+	Height   int  // browser window height in pixels
+	Delay    int  // seconds to wait after load before capturing
+
+	BatchSize int // URLs per gowitness run, default 25
+	Parallel  int // gowitness runs at once, default 2
+}
+
+// Default batching for RunGowitness.
+const (
+	defaultGowitnessBatch    = 25
+	defaultGowitnessParallel = 2
+)
+
+// gowitnessPageTimeout is gowitness's per-page timeout (-T). A batch is
+// given enough of these for every page plus one spare before it is killed.
+const gowitnessPageTimeout = 60 * time.Second
+
+// GowitnessCapture is the outcome of capturing one URL with gowitness.
+type GowitnessCapture struct {
+	URL      string
+	Path     string // screenshot file; empty when no attempt produced one
+	Attempts int    // gowitness runs the URL was in: 1, or 2 when retried
+	Error    string // why the last attempt left no screenshot
+}
+
+// RunGowitness captures screenshots of urls into screenshotDir. The URLs are
+// split into batches of opts.BatchSize, each a gowitness run of its own with
+// a deadline, opts.Parallel at a time, so a page that hangs or crashes
+// gowitness only costs its batch. URLs left without a screenshot are retried
+// once in fresh batches. Results are returned in input order; the error is
+// only set when capturing could not start or ctx ended.
+func RunGowitness(ctx context.Context, urls []string, screenshotDir string, opts GowitnessOptions, binaryPath string) ([]GowitnessCapture, error) {
 	// Return early if no URLs provided
 	if len(urls) == 0 {
-		return nil
+		return nil, nil
 	}
 
-	// Use provided binary path or fall back to tool name
-	binary := "gowitness"
-	if binaryPath != "" {
-		binary = binaryPath
+	// Ensure the screenshot directory exists before invoking gowitness
+	if err := os.MkdirAll(screenshotDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create screenshot directory %q: %w", screenshotDir, err)
 	}
 
-	// Default threads to 4 if not specified
-	if threads <= 0 {
-		threads = 4
+	if opts.Threads <= 0 {
+		opts.Threads = 4
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = defaultGowitnessBatch
+	}
+	if opts.Parallel <= 0 {
+		opts.Parallel = defaultGowitnessParallel
 	}
 
-	// Ensure the screenshot directory exists before invoking gowitness
-	if err := os.MkdirAll(screenshotDir, 0755); err != nil {
-		return fmt.Errorf("failed to create screenshot directory %q: %w", screenshotDir, err)
+	captures := make([]GowitnessCapture, len(urls))
+	pending := make([]int, len(urls))
+	for i, u := range urls {
+		captures[i].URL = u
+		pending[i] = i
 	}
 
-	// Create temp file for input URLs
-	inputFile, err := os.CreateTemp("", "gowitness-input-*.txt")
-	if err != nil {
-		return fmt.Errorf("failed to create input temp file: %w", err)
+	// First pass over every URL, then one retry of the failures
+	for attempt := 1; attempt <= 2 && len(pending) > 0; attempt++ {
+		if ctx.Err() != nil {
+			break
+		}
+		runGowitnessBatches(ctx, captures, pending, screenshotDir, opts, binaryPath)
+		var failed []int
+		for _, i := range pending {
+			captures[i].Attempts = attempt
+			if captures[i].Path == "" {
+				failed = append(failed, i)
+			}
+		}
+		pending = failed
+	}
+
+	if ctx.Err() != nil {
+		return captures, fmt.Errorf("gowitness cancelled: %w", ctx.Err())
 	}
-	defer os.Remove(inputFile.Name())
+	return captures, nil
+}
 
-	// Write URLs to temp file (one per line)
-	for _, url := range urls {
-		if _, err := fmt.Fprintln(inputFile, url); err != nil {
-			inputFile.Close()
-			return fmt.Errorf("failed to write URL to temp file: %w", err)
+// runGowitnessBatches captures the URLs of captures at the indexes in
+// pending, in batches run opts.Parallel at a time, and fills in their Path
+// or Error.
+func runGowitnessBatches(ctx context.Context, captures []GowitnessCapture, pending []int, dir string, opts GowitnessOptions, binaryPath string) {
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, opts.Parallel)
+	for start := 0; start < len(pending); start += opts.BatchSize {
+		if ctx.Err() != nil {
+			break
 		}
+		batch := pending[start:min(start+opts.BatchSize, len(pending))]
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(batch []int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			urls := make([]string, len(batch))
+			for j, i := range batch {
+				urls[j] = captures[i].URL
+			}
+			paths, err := runGowitnessBatch(ctx, urls, dir, opts, binaryPath)
+			// Each batch owns its indexes, so no locking is needed
+			for _, i := range batch {
+				captures[i].Path = paths[captures[i].URL]
+				switch {
+				case captures[i].Path != "":
+					captures[i].Error = ""
+				case err != nil:
+					captures[i].Error = err.Error()
+				default:
+					captures[i].Error = "gowitness produced no screenshot"
+				}
+			}
+		}(batch)
+	}
+	wg.Wait()
+}
+
+// runGowitnessBatch runs gowitness once over urls, into a directory of its
+// own under dir so batches running together never see each other's files.
+// The screenshots it wrote are moved into dir and returned by URL, also
+// when gowitness failed part way.
+func runGowitnessBatch(ctx context.Context, urls []string, dir string, opts GowitnessOptions, binaryPath string) (map[string]string, error) {
+	// Use provided binary path or fall back to tool name
+	binary := "gowitness"
+	if binaryPath != "" {
+		binary = binaryPath
+	}
+
+	batchDir, err := os.MkdirTemp(dir, ".gowitness-batch-*")
+	if err != nil {
+		return nil, fmt.Errorf("creating batch directory: %w", err)
+	}
+	defer os.RemoveAll(batchDir)
+
+	// Write URLs to the input file (one per line)
+	inputPath := filepath.Join(batchDir, "urls.txt")
+	if err := os.WriteFile(inputPath, []byte(strings.Join(urls, "\n")+"\n"), 0644); err != nil {
+		return nil, fmt.Errorf("failed to write URL file: %w", err)
+	}
+	shotDir := filepath.Join(batchDir, "shots")
+	if err := os.Mkdir(shotDir, 0755); err != nil {
+		return nil, fmt.Errorf("creating batch directory: %w", err)
 	}
-	inputFile.Close()
 
 	// Build arguments for gowitness file-scan mode
 	args := []string{
 		"scan", "file",
-		"-f", inputFile.Name(),           // Input file of URLs
-		"-s", screenshotDir,              // Screenshot output directory
-		"-t", fmt.Sprintf("%d", threads), // Concurrent thread count
-		"-T", "60",                       // Per-page timeout in seconds
-		"--screenshot-format", "png",     // Output format
+		"-f", inputPath, // Input file of URLs
+		"-s", shotDir, // Screenshot output directory
+		"-t", strconv.Itoa(opts.Threads), // Concurrent thread count
+		"-T", strconv.Itoa(int(gowitnessPageTimeout / time.Second)), // Per-page timeout in seconds
+		"--screenshot-format", "png", // Output format
+	}
+	if opts.FullPage {
+		args = append(args, "--screenshot-fullpage")
+	}
+	if opts.Width > 0 {
+		args = append(args, "--chrome-window-x", strconv.Itoa(opts.Width))
+	}
+	if opts.Height > 0 {
+		args = append(args, "--chrome-window-y", strconv.Itoa(opts.Height))
+	}
+	if opts.Delay > 0 {
+		args = append(args, "--delay", strconv.Itoa(opts.Delay))
 	}
 
-	// Execute via RunTool (no stdin piping needed)
-	_, err = RunTool(ctx, binary, args...)
-	if err != nil {
-		// Context cancellation propagates as-is
-		if ctx.Err() != nil {
-			return fmt.Errorf("gowitness cancelled: %w", ctx.Err())
+	// A hung browser must not hold the batch past what its pages can take
+	rounds := (len(urls)+opts.Threads-1)/opts.Threads + 1
+	perPage := gowitnessPageTimeout + time.Duration(opts.Delay)*time.Second
+	batchCtx, cancel := context.WithTimeout(ctx, time.Duration(rounds)*perPage)
+	defer cancel()
+
+	_, runErr := RunTool(batchCtx, binary, args...)
+	switch {
+	case runErr == nil:
+	case ctx.Err() != nil:
+		runErr = fmt.Errorf("gowitness cancelled: %w", ctx.Err())
+	case errors.Is(batchCtx.Err(), context.DeadlineExceeded):
+		runErr = fmt.Errorf("gowitness batch timed out after %s", time.Duration(rounds)*perPage)
+	default:
+		runErr = fmt.Errorf("gowitness execution failed: %w", runErr)
+	}
+
+	// gowitness names files itself ("https---host-443.png" style); match
+	// them back to URLs ignoring punctuation
+	byKey := make(map[string]string, len(urls))
+	for _, u := range urls {
+		byKey[ScreenshotKey(ScreenshotFilename(u))] = u
+	}
+	files, _ := filepath.Glob(filepath.Join(shotDir, "*.png"))
+	paths := make(map[string]string, len(files))
+	for _, f := range files {
+		u, ok := byKey[ScreenshotKey(filepath.Base(f))]
+		if !ok {
+			continue
 		}
-		return fmt.Errorf("gowitness execution failed: %w", err)
+		dest := filepath.Join(dir, filepath.Base(f))
+		if err := os.Rename(f, dest); err != nil {
+			continue
+		}
+		paths[u] = dest
 	}
+	return paths, runErr
+}
 
-	return nil
+// ScreenshotKey reduces a screenshot file name to lowercase letters and
+// digits, so a gowitness file name and ScreenshotFilename of the same URL
+// compare equal.
+func ScreenshotKey(name string) string {
+	name = strings.TrimSuffix(strings.ToLower(name), ".png")
+	return strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			return r
+		}
+		return -1
+	}, name)
 }
@@ -1,38 +1,84 @@
 package tools
 
 import (
-	"bufio"
-	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
-	"os/exec"
 	"time"
 )
 
 // HttpxResult represents the probed HTTP endpoint data returned by httpx
 type HttpxResult struct {
-	URL           string   `json:"url"`
-	Input         string   `json:"input"`
-	StatusCode    int      `json:"status_code"`
-	Title         string   `json:"title"`
-	ContentLength int64    `json:"content_length"`
-	WebServer     string   `json:"webserver"`
-	Technologies  []string `json:"tech"`
-	HostIP        string   `json:"host"`
-	Port          string   `json:"port"`
-	CDN           bool     `json:"cdn"`
-	CDNName       string   `json:"cdn_name"`
+	URL           string    `json:"url"`
+	Input         string    `json:"input"`
+	StatusCode    int       `json:"status_code"`
+	Title         string    `json:"title"`
+	ContentLength int64     `json:"content_length"`
+	WebServer     string    `json:"webserver"`
+	Technologies  []string  `json:"tech"`
+	HostIP        string    `json:"host"`
+	Port          string    `json:"port"`
+	CDN           bool      `json:"cdn"`
+	CDNName       string    `json:"cdn_name"`
+	TLS           *HttpxTLS `json:"tls"`
+	Location      string    `json:"location"`
+	Body          string    `json:"body"`    // only with HttpxOptions.IncludeBody
+	Favicon       string    `json:"favicon"` // only with HttpxOptions.Favicon
+}
+
+// HttpxOptions tunes an httpx run.
+type HttpxOptions struct {
+	Threads     int           // default 50
+	RateLimit   int           // requests per second, 0 = httpx default (150)
+	Timeout     time.Duration // per request (-timeout, whole seconds), 0 = httpx default (10s)
+	IncludeBody bool          // add response bodies to the JSON output (-irr)
+	Favicon     bool          // hash /favicon.ico (-favicon)
+	// Host is sent as the Host header and TLS SNI name of every request,
+	// to ask an IP directly for a virtual host. Empty = the target's own.
+	Host string
+}
+
+// HttpxTLS represents the certificate data httpx returns with -tls-grab
+type HttpxTLS struct {
+	SubjectCN       string   `json:"subject_cn"`
+	SubjectDN       string   `json:"subject_dn"`
+	SubjectAN       []string `json:"subject_an"`
+	IssuerCN        string   `json:"issuer_cn"`
+	IssuerDN        string   `json:"issuer_dn"`
+	Serial          string   `json:"serial"`
+	NotBefore       string   `json:"not_before"`
+	NotAfter        string   `json:"not_after"`
+	FingerprintHash struct {
+		SHA256 string `json:"sha256"`
+	} `json:"fingerprint_hash"`
 }
 
 // RunHttpx executes httpx for the given targets and returns parsed results.
 // It pipes targets to stdin line by line and parses JSONL output.
-func RunHttpx(ctx context.Context, targets []string, threads int, binaryPath string) ([]HttpxResult, error) {
+func RunHttpx(ctx context.Context, targets []string, opts HttpxOptions, binaryPath string) ([]HttpxResult, error) {
 	// Return early if no targets provided
 	if len(targets) == 0 {
 		return []HttpxResult{}, nil
 	}
 
+	var results []HttpxResult
+	err := StreamHttpx(ctx, targets, opts, binaryPath, func(r HttpxResult) {
+		results = append(results, r)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// StreamHttpx executes httpx like RunHttpx, but hands each result to fn as
+// httpx reports it instead of collecting them. A caller that keeps only part
+// of each result (not the response body, say) never holds the whole run.
+func StreamHttpx(ctx context.Context, targets []string, opts HttpxOptions, binaryPath string, fn func(HttpxResult)) error {
+	if len(targets) == 0 {
+		return nil
+	}
+
 	// Use provided binary path or fall back to tool name
 	binary := "httpx"
 	if binaryPath != "" {
@@ -40,11 +86,12 @@ func RunHttpx(ctx context.Context, targets []string, threads int, binaryPath str
 	}
 
 	// Default threads to 50 if not specified
+	threads := opts.Threads
 	if threads <= 0 {
 		threads = 50
 	}
 
-	// Build arguments: JSON output, status code, title, server, tech detection, CDN, IP
+	// Build arguments: JSON output, status code, title, server, tech detection, CDN, IP, TLS
 	args := []string{
 		"-json",                           // JSON output (JSONL, one object per line)
 		"-silent",                         // Suppress banner and non-essential output
@@ -54,111 +101,54 @@ func RunHttpx(ctx context.Context, targets []string, threads int, binaryPath str
 		"-td",                             // Enable technology detection
 		"-cdn",                            // Include CDN detection
 		"-ip",                             // Include resolved IP
+		"-tls-grab",                       // Include TLS certificate data
+		"-location",                       // Include redirect location
 		"-t", fmt.Sprintf("%d", threads),  // Thread count
 	}
-
-	// Create command with context
-	cmd := exec.CommandContext(ctx, binary, args...)
-
-	// Set WaitDelay for subprocess cleanup after context cancellation
-	cmd.WaitDelay = 5 * time.Second
-
-	// Create pipes for stdin, stdout, and stderr
-	stdinPipe, err := cmd.StdinPipe()
-	if err != nil {
-		return nil, fmt.Errorf("failed to create stdin pipe: %w", err)
+	if opts.RateLimit > 0 {
+		args = append(args, "-rl", fmt.Sprintf("%d", opts.RateLimit)) // Requests per second
 	}
-
-	stdoutPipe, err := cmd.StdoutPipe()
-	if err != nil {
-		return nil, fmt.Errorf("failed to create stdout pipe: %w", err)
+	if opts.Timeout > 0 {
+		secs := int(opts.Timeout.Round(time.Second) / time.Second)
+		args = append(args, "-timeout", fmt.Sprintf("%d", max(secs, 1))) // Seconds per request
 	}
-
-	stderrPipe, err := cmd.StderrPipe()
-	if err != nil {
-		return nil, fmt.Errorf("failed to create stderr pipe: %w", err)
+	if opts.IncludeBody {
+		args = append(args, "-irr") // Include request/response, which adds "body"
 	}
-
-	// Start the command
-	if err := cmd.Start(); err != nil {
-		return nil, fmt.Errorf("failed to start command: %w", err)
+	if opts.Favicon {
+		args = append(args, "-favicon") // MurmurHash3 of /favicon.ico
 	}
-
-	// Write targets to stdin and close
-	go func() {
-		defer stdinPipe.Close()
-		for _, target := range targets {
-			fmt.Fprintln(stdinPipe, target)
-		}
-	}()
-
-	// Read stdout and stderr concurrently to prevent deadlocks
-	var stdoutBuf bytes.Buffer
-	var stderrBuf bytes.Buffer
-
-	stdoutDone := make(chan error, 1)
-	stderrDone := make(chan error, 1)
-
-	// Read stdout using bufio.Scanner for line-by-line processing
-	go func() {
-		scanner := bufio.NewScanner(stdoutPipe)
-		for scanner.Scan() {
-			stdoutBuf.Write(scanner.Bytes())
-			stdoutBuf.WriteByte('\n')
-		}
-		stdoutDone <- scanner.Err()
-	}()
-
-	// Read stderr
-	go func() {
-		scanner := bufio.NewScanner(stderrPipe)
-		for scanner.Scan() {
-			stderrBuf.Write(scanner.Bytes())
-			stderrBuf.WriteByte('\n')
-		}
-		stderrDone <- scanner.Err()
-	}()
-
-	// Wait for both readers to finish
-	<-stdoutDone
-	<-stderrDone
-
-	// Wait for the command to complete
-	err = cmd.Wait()
-
-	if err != nil {
-		// Context cancellation is expected, return error
-		if ctx.Err() != nil {
-			return nil, fmt.Errorf("command cancelled: %w", ctx.Err())
-		}
-		// Non-zero exit code
-		exitCode := cmd.ProcessState.ExitCode()
-		return nil, fmt.Errorf("httpx failed with exit code %d: %w\nstderr: %s", exitCode, err, stderrBuf.String())
+	if opts.Host != "" {
+		args = append(args, "-H", "Host: "+opts.Host, "-sni", opts.Host) // Virtual host to request
 	}
 
-	// Parse JSONL output (one JSON object per line)
-	var results []HttpxResult
-	scanner := bufio.NewScanner(bytes.NewReader(stdoutBuf.Bytes()))
-
-	for scanner.Scan() {
-		line := scanner.Bytes()
+	// Pipe targets to stdin (one per line) and parse each JSONL line as it arrives
+	onLine := func(line []byte) {
 		if len(line) == 0 {
-			continue
+			return
 		}
 
 		var httpxResult HttpxResult
 		if err := json.Unmarshal(line, &httpxResult); err != nil {
 			// Log warning and continue - some lines may not be valid JSON
 			fmt.Printf("Warning: failed to parse httpx JSON line: %v\n", err)
-			continue
+			return
 		}
 
-		results = append(results, httpxResult)
+		fn(httpxResult)
 	}
-
-	if err := scanner.Err(); err != nil {
-		return nil, fmt.Errorf("failed to read httpx output: %w", err)
+	toolResult, err := RunToolStream(ctx, binary, targets, onLine, args...)
+	if err != nil {
+		// Context cancellation is expected, return error
+		if ctx.Err() != nil {
+			return fmt.Errorf("command cancelled: %w", ctx.Err())
+		}
+		stderr := ""
+		if toolResult != nil {
+			stderr = toolResult.Stderr
+		}
+		return fmt.Errorf("httpx failed: %w\nstderr: %s", err, stderr)
 	}
 
-	return results, nil
+	return nil
 }
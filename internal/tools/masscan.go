@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"strconv"
 	"strings"
 )
 
@@ -23,8 +24,11 @@ type MasscanResult struct {
 
 // RunMasscan executes masscan for the given IPs and returns parsed results.
 // It writes IPs to a temp file and parses JSON output.
-// If rate <= 0, defaults to 1000 packets/second.
-func RunMasscan(ctx context.Context, ips []string, rate int, binaryPath string) ([]MasscanResult, error) {
+// If topPorts > 0, only that many of the most common ports are scanned;
+// otherwise ports (a list like "80,443,8000-8100", empty = all ports).
+// If rate <= 0, defaults to 1000 packets/second. A non-empty excludeFile is
+// passed as --excludefile so masscan itself refuses those networks.
+func RunMasscan(ctx context.Context, ips []string, ports string, topPorts int, rate int, excludeFile string, binaryPath string) ([]MasscanResult, error) {
 	// Return early if no IPs provided
 	if len(ips) == 0 {
 		return []MasscanResult{}, nil
@@ -68,11 +72,21 @@ func RunMasscan(ctx context.Context, ips []string, rate int, binaryPath string)
 	// Build arguments
 	args := []string{
 		"-iL", inputFile.Name(),
-		"-p1-65535",
 		fmt.Sprintf("--rate=%d", rate),
 		"-oJ", outputFile.Name(),
 		"--wait", "2",
 	}
+	if topPorts > 0 {
+		args = append(args, "--top-ports", strconv.Itoa(topPorts))
+	} else {
+		if ports == "" {
+			ports = AllPorts
+		}
+		args = append(args, "-p"+normalizePortRange(ports))
+	}
+	if excludeFile != "" {
+		args = append(args, "--excludefile", excludeFile)
+	}
 
 	// Execute via RunTool
 	_, err = RunTool(ctx, binary, args...)
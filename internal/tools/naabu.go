@@ -0,0 +1,121 @@
+package tools
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+// Port scanners, selected by the portscan.scanner config setting.
+const (
+	ScannerMasscan = "masscan" // raw-socket SYN scan; needs root (the default)
+	ScannerNaabu   = "naabu"   // runs unprivileged with TCP connect scans
+)
+
+// Scanners lists the valid portscan.scanner values.
+var Scanners = []string{ScannerMasscan, ScannerNaabu}
+
+// naabuResult is one line of naabu's JSON output: one open port.
+type naabuResult struct {
+	IP       string `json:"ip"`
+	Host     string `json:"host"`
+	Port     int    `json:"port"`
+	Protocol string `json:"protocol"`
+}
+
+// RunNaabu executes naabu against the given IPs and returns the open ports
+// in masscan's result shape, one MasscanResult per IP. IPs are piped to
+// stdin. Without root naabu falls back to TCP connect scans, so it needs no
+// raw sockets. Ports are selected as for RunMasscan, except that topPorts
+// must be one of NaabuTopPorts. If rate <= 0, defaults to 1000
+// packets/second. A non-empty excludeFile is passed as -exclude-file.
+func RunNaabu(ctx context.Context, ips []string, ports string, topPorts int, rate int, excludeFile string, binaryPath string) ([]MasscanResult, error) {
+	// Return early if no IPs provided
+	if len(ips) == 0 {
+		return []MasscanResult{}, nil
+	}
+
+	// Use provided binary path or fall back to tool name
+	binary := "naabu"
+	if binaryPath != "" {
+		binary = binaryPath
+	}
+
+	// Default rate to 1000 if not specified
+	if rate <= 0 {
+		rate = 1000
+	}
+
+	// Build arguments
+	args := []string{
+		"-rate", strconv.Itoa(rate),
+		"-json",
+		"-silent",
+	}
+	if topPorts > 0 {
+		args = append(args, "-top-ports", strconv.Itoa(topPorts))
+	} else {
+		if ports == "" {
+			ports = AllPorts
+		}
+		args = append(args, "-p", normalizePortRange(ports))
+	}
+	if excludeFile != "" {
+		args = append(args, "-exclude-file", excludeFile)
+	}
+
+	// Pipe IPs to stdin (one per line) and collect JSONL output
+	result, err := RunToolWithInput(ctx, binary, ips, args...)
+	if err != nil {
+		return nil, fmt.Errorf("naabu execution failed: %w", err)
+	}
+
+	// Group the open ports by IP
+	byIP := make(map[string][]MasscanPort)
+	seen := make(map[string]bool)
+	scanner := bufio.NewScanner(bytes.NewReader(result.Stdout))
+
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+
+		var nr naabuResult
+		if err := json.Unmarshal(line, &nr); err != nil {
+			// Log warning and continue - some lines may not be valid JSON
+			fmt.Printf("Warning: failed to parse naabu JSON line: %v\n", err)
+			continue
+		}
+		ip := nr.IP
+		if ip == "" {
+			ip = nr.Host
+		}
+		proto := nr.Protocol
+		if proto == "" {
+			proto = "tcp"
+		}
+		key := fmt.Sprintf("%s:%d/%s", ip, nr.Port, proto)
+		if ip == "" || nr.Port <= 0 || seen[key] {
+			continue
+		}
+		seen[key] = true
+		byIP[ip] = append(byIP[ip], MasscanPort{Port: nr.Port, Proto: proto, Status: "open"})
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read naabu output: %w", err)
+	}
+
+	results := make([]MasscanResult, 0, len(byIP))
+	for ip, p := range byIP {
+		sort.Slice(p, func(i, j int) bool { return p[i].Port < p[j].Port })
+		results = append(results, MasscanResult{IP: ip, Ports: p})
+	}
+	sort.Slice(results, func(i, j int) bool { return results[i].IP < results[j].IP })
+	return results, nil
+}
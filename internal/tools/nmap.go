@@ -100,8 +100,76 @@ func RunNmap(ctx context.Context, ip string, ports []int, binaryPath string) ([]
 		return nil, fmt.Errorf("nmap execution failed: %w", err)
 	}
 
+	return readNmapResults(outputFile.Name())
+}
+
+// RunNmapUDP executes a UDP scan (-sU) with version detection of the given
+// ports on all ips at once and returns the ports nmap reports, in every
+// state. The version probes are what turn silent "open|filtered" ports into
+// "open" ones, so callers should keep only those. Like any -sU scan it needs
+// root.
+func RunNmapUDP(ctx context.Context, ips []string, ports []int, binaryPath string) ([]NmapResult, error) {
+	// Return early if nothing to scan
+	if len(ips) == 0 || len(ports) == 0 {
+		return []NmapResult{}, nil
+	}
+
+	// Use provided binary path or fall back to tool name
+	binary := "nmap"
+	if binaryPath != "" {
+		binary = binaryPath
+	}
+
+	portStrings := make([]string, len(ports))
+	for i, port := range ports {
+		portStrings[i] = strconv.Itoa(port)
+	}
+
+	// Create temp file for input IPs
+	inputFile, err := os.CreateTemp("", "nmap-input-*.txt")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create input temp file: %w", err)
+	}
+	defer os.Remove(inputFile.Name())
+	for _, ip := range ips {
+		if _, err := fmt.Fprintln(inputFile, ip); err != nil {
+			inputFile.Close()
+			return nil, fmt.Errorf("failed to write IP to temp file: %w", err)
+		}
+	}
+	inputFile.Close()
+
+	// Create temp file for XML output
+	outputFile, err := os.CreateTemp("", "nmap-output-*.xml")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create output temp file: %w", err)
+	}
+	outputFile.Close()
+	defer os.Remove(outputFile.Name())
+
+	// Build arguments: -sU -sV -Pn -p ports -iL input -oX output
+	args := []string{
+		"-sU", // UDP scan
+		"-sV", // Version detection; sends protocol payloads
+		"-Pn", // Skip ping (treat hosts as online)
+		"-p", strings.Join(portStrings, ","),
+		"-iL", inputFile.Name(),
+		"-oX", outputFile.Name(),
+	}
+
+	// Execute via RunTool
+	if _, err := RunTool(ctx, binary, args...); err != nil {
+		return nil, fmt.Errorf("nmap UDP scan failed: %w", err)
+	}
+
+	return readNmapResults(outputFile.Name())
+}
+
+// readNmapResults parses the -oX report at path into one NmapResult per
+// port of every host.
+func readNmapResults(path string) ([]NmapResult, error) {
 	// Read the XML output file
-	data, err := os.ReadFile(outputFile.Name())
+	data, err := os.ReadFile(path)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read nmap output: %w", err)
 	}
@@ -1,17 +1,14 @@
 package tools
 
 import (
-	"bufio"
-	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
 	"net/url"
-	"os/exec"
 	"strconv"
 	"time"
 
-	"github.com/hakim/reconpipe/internal/models"
+	"github.com/hakim/reconpipe/pkg/models"
 )
 
 // NucleiClassification holds CVE/CWE and CVSS metadata for a finding.
@@ -44,16 +41,40 @@ type NucleiResult struct {
 	IP            string           `json:"ip"`
 	Timestamp     string           `json:"timestamp"`
 	MatcherStatus bool             `json:"matcher-status"`
+	MatcherName   string           `json:"matcher-name"`
+}
+
+// DefaultNucleiSeverity is the severity filter used when none is given.
+const DefaultNucleiSeverity = "critical,high,medium"
+
+// NucleiOptions bounds how long nuclei spends on slow or failing hosts. Zero
+// values leave nuclei's own defaults in place.
+type NucleiOptions struct {
+	Timeout      time.Duration // per request (-timeout, whole seconds); nuclei default 10s
+	MaxHostError int           // errors before a host is skipped (-max-host-error); nuclei default 30
+	ScanStrategy string        // auto, host-spray or template-spray (-scan-strategy)
 }
 
 // RunNuclei executes nuclei against the given targets and returns parsed findings.
 // Targets are piped via stdin (one per line). Findings are returned as a slice of
-// NucleiResult parsed from nuclei's JSONL output stream.
-func RunNuclei(ctx context.Context, targets []string, severity string, threads int, rateLimit int, binaryPath string) ([]NucleiResult, error) {
+// NucleiResult parsed from nuclei's JSONL output stream. When ctx ends before
+// nuclei finishes, the findings reported so far are returned with the error.
+func RunNuclei(ctx context.Context, targets []string, severity string, threads int, rateLimit int, opts NucleiOptions, binaryPath string) ([]NucleiResult, error) {
 	if len(targets) == 0 {
 		return []NucleiResult{}, nil
 	}
+	return runNuclei(ctx, targets, nucleiArgs(severity, threads, rateLimit, opts), binaryPath)
+}
+
+// RunNucleiList executes nuclei like RunNuclei, but has it read its targets
+// from listPath (-list), a file of one target per line, instead of stdin.
+func RunNucleiList(ctx context.Context, listPath string, severity string, threads int, rateLimit int, opts NucleiOptions, binaryPath string) ([]NucleiResult, error) {
+	args := append(nucleiArgs(severity, threads, rateLimit, opts), "-list", listPath)
+	return runNuclei(ctx, nil, args, binaryPath)
+}
 
+// nucleiArgs returns the nuclei arguments for the given limits.
+func nucleiArgs(severity string, threads int, rateLimit int, opts NucleiOptions) []string {
 	// Apply defaults for optional parameters
 	if threads <= 0 {
 		threads = 25
@@ -62,12 +83,7 @@ func RunNuclei(ctx context.Context, targets []string, severity string, threads i
 		rateLimit = 150
 	}
 	if severity == "" {
-		severity = "critical,high,medium"
-	}
-
-	binary := "nuclei"
-	if binaryPath != "" {
-		binary = binaryPath
+		severity = DefaultNucleiSeverity
 	}
 
 	args := []string{
@@ -77,94 +93,55 @@ func RunNuclei(ctx context.Context, targets []string, severity string, threads i
 		"-t", strconv.Itoa(threads),
 		"-rl", strconv.Itoa(rateLimit),
 	}
-
-	cmd := exec.CommandContext(ctx, binary, args...)
-	cmd.WaitDelay = 5 * time.Second
-
-	stdinPipe, err := cmd.StdinPipe()
-	if err != nil {
-		return nil, fmt.Errorf("failed to create stdin pipe: %w", err)
-	}
-
-	stdoutPipe, err := cmd.StdoutPipe()
-	if err != nil {
-		return nil, fmt.Errorf("failed to create stdout pipe: %w", err)
+	if opts.Timeout > 0 {
+		secs := int(opts.Timeout.Round(time.Second) / time.Second)
+		args = append(args, "-timeout", strconv.Itoa(max(secs, 1)))
 	}
-
-	stderrPipe, err := cmd.StderrPipe()
-	if err != nil {
-		return nil, fmt.Errorf("failed to create stderr pipe: %w", err)
+	if opts.MaxHostError > 0 {
+		args = append(args, "-max-host-error", strconv.Itoa(opts.MaxHostError))
 	}
-
-	if err := cmd.Start(); err != nil {
-		return nil, fmt.Errorf("failed to start command: %w", err)
+	if opts.ScanStrategy != "" {
+		args = append(args, "-scan-strategy", opts.ScanStrategy)
 	}
+	return args
+}
 
-	// Write targets to stdin and close so nuclei knows input is done
-	go func() {
-		defer stdinPipe.Close()
-		for _, target := range targets {
-			fmt.Fprintln(stdinPipe, target)
-		}
-	}()
-
-	var stdoutBuf bytes.Buffer
-	var stderrBuf bytes.Buffer
-
-	stdoutDone := make(chan error, 1)
-	stderrDone := make(chan error, 1)
-
-	go func() {
-		scanner := bufio.NewScanner(stdoutPipe)
-		for scanner.Scan() {
-			stdoutBuf.Write(scanner.Bytes())
-			stdoutBuf.WriteByte('\n')
-		}
-		stdoutDone <- scanner.Err()
-	}()
-
-	go func() {
-		scanner := bufio.NewScanner(stderrPipe)
-		for scanner.Scan() {
-			stderrBuf.Write(scanner.Bytes())
-			stderrBuf.WriteByte('\n')
-		}
-		stderrDone <- scanner.Err()
-	}()
-
-	<-stdoutDone
-	<-stderrDone
-
-	err = cmd.Wait()
-	if err != nil {
-		if ctx.Err() != nil {
-			return nil, fmt.Errorf("command cancelled: %w", ctx.Err())
-		}
-		exitCode := cmd.ProcessState.ExitCode()
-		return nil, fmt.Errorf("nuclei failed with exit code %d: %w\nstderr: %s", exitCode, err, stderrBuf.String())
+// runNuclei runs nuclei with args, piping targets to stdin when there are
+// any, and parses its findings.
+func runNuclei(ctx context.Context, targets []string, args []string, binaryPath string) ([]NucleiResult, error) {
+	binary := "nuclei"
+	if binaryPath != "" {
+		binary = binaryPath
 	}
 
-	// Parse JSONL output — one finding per line
+	// Pipe any targets to stdin (one per line) and parse each finding as it
+	// arrives; nuclei lines carry the full request and response, which are
+	// not kept
 	var results []NucleiResult
-	scanner := bufio.NewScanner(bytes.NewReader(stdoutBuf.Bytes()))
-
-	for scanner.Scan() {
-		line := scanner.Bytes()
+	onLine := func(line []byte) {
 		if len(line) == 0 {
-			continue
+			return
 		}
 
 		var result NucleiResult
 		if err := json.Unmarshal(line, &result); err != nil {
 			fmt.Printf("Warning: failed to parse nuclei JSON line: %v\n", err)
-			continue
+			return
 		}
 
 		results = append(results, result)
 	}
-
-	if err := scanner.Err(); err != nil {
-		return nil, fmt.Errorf("failed to read nuclei output: %w", err)
+	toolResult, err := RunToolStream(ctx, binary, targets, onLine, args...)
+	if err != nil {
+		// Context cancellation is expected; keep what nuclei reported so far
+		if ctx.Err() != nil {
+			return results, fmt.Errorf("command cancelled: %w", ctx.Err())
+		}
+		stderr := ""
+		if toolResult != nil {
+			stderr = toolResult.Stderr
+		}
+		return nil, fmt.Errorf("nuclei failed: %w\nstderr: %s", err, stderr)
 	}
 
 	return results, nil
@@ -183,6 +160,8 @@ func NucleiResultToVulnerability(nr NucleiResult) models.Vulnerability {
 		URL:         nr.MatchedAt,
 		Description: nr.Info.Description,
 		MatchedAt:   nr.MatchedAt,
+		Tags:        nr.Info.Tags,
+		MatcherName: nr.MatcherName,
 	}
 }
 
@@ -0,0 +1,57 @@
+package tools
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// AllPorts is the port range scanned when no ports or top ports are given.
+const AllPorts = "1-65535"
+
+// NaabuTopPorts lists the top-ports counts naabu accepts; masscan takes any
+// positive count.
+var NaabuTopPorts = []int{100, 1000}
+
+// CheckPortRange reports whether spec is a valid port list for masscan and
+// naabu: comma-separated ports and low-high ranges within 1-65535, e.g.
+// "22,80,443,8000-8100".
+func CheckPortRange(spec string) error {
+	if strings.TrimSpace(spec) == "" {
+		return fmt.Errorf("empty port list")
+	}
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		low, high, isRange := strings.Cut(part, "-")
+		lo, err := parsePort(low)
+		if err != nil {
+			return err
+		}
+		if !isRange {
+			continue
+		}
+		hi, err := parsePort(high)
+		if err != nil {
+			return err
+		}
+		if lo > hi {
+			return fmt.Errorf("port range %q ends before it starts", part)
+		}
+	}
+	return nil
+}
+
+// parsePort parses one port number within 1-65535.
+func parsePort(s string) (int, error) {
+	port, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil || port < 1 || port > 65535 {
+		return 0, fmt.Errorf("invalid port %q (want 1-65535)", s)
+	}
+	return port, nil
+}
+
+// normalizePortRange strips the spaces CheckPortRange tolerates, which the
+// scanners do not.
+func normalizePortRange(spec string) string {
+	return strings.Join(strings.Fields(spec), "")
+}
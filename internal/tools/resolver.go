@@ -0,0 +1,183 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync/atomic"
+)
+
+// DNS resolver modes, selected by the discovery.dns_resolver.mode config
+// setting.
+const (
+	ResolverDig    = "dig"    // run dig for every lookup
+	ResolverNative = "native" // Go's own resolver for A/AAAA/CNAME/NS/MX; no dnsutils needed
+	ResolverDnsx   = "dnsx"   // dnsx resolves discovered names in bulk; dig for the rest
+)
+
+// dnsResolver holds the process-wide resolver mode. It is set once at
+// startup from the config before any stage runs.
+var dnsResolver struct {
+	native      bool
+	bulk        bool     // dnsx
+	nameservers []string // empty = the system's resolv.conf
+	next        atomic.Uint64
+}
+
+// SetDNSResolver selects how DNS lookups are made; empty selects dig. In
+// native mode nameservers, when given, answer the lookups that would
+// otherwise go to the system resolver, taken in turn so a retry goes to the
+// next one. Nameservers may carry a port (1.1.1.1:5353); the default is 53.
+//
+// Native mode covers A/AAAA resolution, CNAME checks and NS and MX lookups.
+// Zone transfers, the DNSSEC and CAA queries of the DNS health checks, and
+// lookups with checking disabled still run dig. In fake-tools mode dig's
+// fixtures answer every lookup whatever the mode.
+//
+// dnsx mode hands the batches of discovered names to dnsx (see RunDnsx),
+// with nameservers as its resolvers; every other lookup runs dig.
+func SetDNSResolver(mode string, nameservers []string) error {
+	dnsResolver.native, dnsResolver.bulk = false, false
+	switch mode {
+	case "", ResolverDig:
+	case ResolverNative:
+		dnsResolver.native = true
+	case ResolverDnsx:
+		dnsResolver.bulk = true
+	default:
+		return fmt.Errorf("unknown DNS resolver %q (want dig, native or dnsx)", mode)
+	}
+	dnsResolver.nameservers = nameservers
+	return nil
+}
+
+// NativeDNS reports whether A/AAAA/CNAME lookups are answered by Go's
+// resolver instead of dig, so dig is not required to resolve names.
+func NativeDNS() bool {
+	return dnsResolver.native
+}
+
+// BulkDNS reports whether batches of names are resolved with dnsx, which is
+// then required.
+func BulkDNS() bool {
+	return dnsResolver.bulk
+}
+
+// DNSNameservers returns the configured nameservers; empty means the
+// system's (or, for dnsx, its built-in) resolvers.
+func DNSNameservers() []string {
+	return dnsResolver.nameservers
+}
+
+// useNative reports whether a lookup skips dig.
+func useNative() bool {
+	return dnsResolver.native && !FakeToolsEnabled()
+}
+
+// nativeResolver returns a resolver sending its queries to server, or to
+// the configured nameservers in turn when server is empty, or else to the
+// system's resolvers.
+func nativeResolver(server string) *net.Resolver {
+	servers := dnsResolver.nameservers
+	if server != "" {
+		servers = []string{server}
+	}
+	if len(servers) == 0 {
+		return &net.Resolver{PreferGo: true}
+	}
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			addr := servers[dnsResolver.next.Add(1)%uint64(len(servers))]
+			if _, _, err := net.SplitHostPort(addr); err != nil {
+				addr = net.JoinHostPort(addr, "53")
+			}
+			var d net.Dialer
+			return d.DialContext(ctx, network, addr)
+		},
+	}
+}
+
+// notFound reports whether err says the name or record does not exist,
+// which dig prints as empty output rather than failing.
+func notFound(err error) bool {
+	var dnsErr *net.DNSError
+	return errors.As(err, &dnsErr) && dnsErr.IsNotFound
+}
+
+// nativeResolve is ResolveSubdomainsVia through Go's resolver.
+func nativeResolve(ctx context.Context, subdomains []string, server string) []DNSResult {
+	r := nativeResolver(server)
+	var results []DNSResult
+	for _, subdomain := range subdomains {
+		dnsResult := DNSResult{Subdomain: subdomain}
+		ips, err := r.LookupIP(ctx, "ip", subdomain)
+		if err != nil && !notFound(err) {
+			dnsResult.Error = err.Error()
+		}
+		for _, ip := range ips {
+			dnsResult.IPs = append(dnsResult.IPs, ip.String())
+		}
+		dnsResult.Resolved = len(dnsResult.IPs) > 0
+		results = append(results, dnsResult)
+	}
+	return results
+}
+
+// nativeCNAME is CheckCNAME through Go's resolver. A name without a CNAME
+// yields "", as it does from dig.
+func nativeCNAME(ctx context.Context, subdomain string) (string, error) {
+	cname, err := nativeResolver("").LookupCNAME(ctx, subdomain)
+	if notFound(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("CNAME check failed: %w", err)
+	}
+	cname = strings.TrimSuffix(cname, ".")
+	if strings.EqualFold(cname, strings.TrimSuffix(subdomain, ".")) {
+		// Go returns the name itself when it has only address records
+		return "", nil
+	}
+	return cname, nil
+}
+
+// nativeRecords is LookupRecords through Go's resolver for the record types
+// it can query. ok is false for other types, which need dig.
+func nativeRecords(ctx context.Context, name, rrtype, server string) (values []string, ok bool, err error) {
+	r := nativeResolver(server)
+	switch strings.ToUpper(rrtype) {
+	case "A", "AAAA":
+		network := "ip4"
+		if strings.EqualFold(rrtype, "AAAA") {
+			network = "ip6"
+		}
+		ips, err := r.LookupIP(ctx, network, name)
+		for _, ip := range ips {
+			values = append(values, ip.String())
+		}
+		return values, true, err
+	case "CNAME":
+		cname, err := r.LookupCNAME(ctx, name)
+		if cname = strings.TrimSuffix(cname, "."); err == nil && !strings.EqualFold(cname, name) {
+			values = append(values, cname)
+		}
+		return values, true, err
+	case "NS":
+		nss, err := r.LookupNS(ctx, name)
+		for _, ns := range nss {
+			values = append(values, strings.TrimSuffix(ns.Host, "."))
+		}
+		return values, true, err
+	case "MX":
+		// In dig's +short form: "10 mail.example.com"
+		mxs, err := r.LookupMX(ctx, name)
+		for _, mx := range mxs {
+			values = append(values, fmt.Sprintf("%d %s", mx.Pref, strings.TrimSuffix(mx.Host, ".")))
+		}
+		return values, true, err
+	}
+	return nil, false, nil
+}
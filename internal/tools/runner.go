@@ -19,8 +19,20 @@ type ToolResult struct {
 
 // RunTool executes a tool binary with the given arguments and returns the result.
 // It handles concurrent pipe reading to prevent buffer deadlocks and enforces
-// context timeout with proper subprocess cleanup.
+// context timeout with proper subprocess cleanup. The output is retained when
+// ctx carries a tool log (see WithToolLog).
 func RunTool(ctx context.Context, binary string, args ...string) (*ToolResult, error) {
+	started := time.Now()
+	result, err := runTool(ctx, binary, args)
+	retainRun(ctx, binary, args, nil, started, result, err)
+	return result, err
+}
+
+func runTool(ctx context.Context, binary string, args []string) (*ToolResult, error) {
+	if isFakeTool(binary) {
+		return runFakeTool(binary, args, nil)
+	}
+
 	cmd := exec.CommandContext(ctx, binary, args...)
 
 	// Set WaitDelay for subprocess cleanup after context cancellation
@@ -89,3 +101,158 @@ func RunTool(ctx context.Context, binary string, args ...string) (*ToolResult, e
 
 	return result, nil
 }
+
+// RunToolWithInput executes a tool binary like RunTool, but streams input to
+// its stdin one line at a time. stdin is closed once all lines are written so
+// tools that read until EOF (httpx, cdncheck, nuclei) know input is complete.
+func RunToolWithInput(ctx context.Context, binary string, input []string, args ...string) (*ToolResult, error) {
+	started := time.Now()
+	result, err := runToolWithInput(ctx, binary, input, args)
+	retainRun(ctx, binary, args, input, started, result, err)
+	return result, err
+}
+
+func runToolWithInput(ctx context.Context, binary string, input []string, args []string) (*ToolResult, error) {
+	if isFakeTool(binary) {
+		return runFakeTool(binary, args, input)
+	}
+
+	var stdoutBuf bytes.Buffer
+	result, err := runToolPiped(ctx, binary, input, args, func(line []byte) {
+		stdoutBuf.Write(line)
+		stdoutBuf.WriteByte('\n')
+	})
+	if result != nil {
+		result.Stdout = stdoutBuf.Bytes()
+	}
+	return result, err
+}
+
+// RunToolStream executes a tool binary like RunToolWithInput, but hands each
+// line of stdout to onLine as it is read instead of buffering the output.
+// The tool blocks on its stdout pipe while onLine runs, so a slow consumer
+// slows the tool down rather than growing memory. The line is only valid
+// until onLine returns. The result's Stdout is empty unless ctx carries a
+// tool log, which keeps a copy.
+func RunToolStream(ctx context.Context, binary string, input []string, onLine func([]byte), args ...string) (*ToolResult, error) {
+	started := time.Now()
+	_, keep := ctx.Value(toolLogKey{}).(*toolLog)
+
+	var result *ToolResult
+	var err error
+	if isFakeTool(binary) {
+		result, err = runFakeTool(binary, args, input)
+		if result != nil {
+			scanner := bufio.NewScanner(bytes.NewReader(result.Stdout))
+			scanner.Buffer(nil, maxLineSize)
+			for scanner.Scan() {
+				onLine(scanner.Bytes())
+			}
+			if !keep {
+				result.Stdout = nil
+			}
+		}
+	} else {
+		var kept bytes.Buffer
+		result, err = runToolPiped(ctx, binary, input, args, func(line []byte) {
+			if keep {
+				kept.Write(line)
+				kept.WriteByte('\n')
+			}
+			onLine(line)
+		})
+		if result != nil {
+			result.Stdout = kept.Bytes()
+		}
+	}
+
+	retainRun(ctx, binary, args, input, started, result, err)
+	return result, err
+}
+
+// maxLineSize bounds one line of tool output. JSON lines carrying a whole
+// response body (httpx -irr) run far past bufio's 64KB default.
+const maxLineSize = 64 << 20
+
+// runToolPiped runs binary with input on stdin and passes each stdout line
+// to onLine. The returned result carries stderr and the exit code only.
+func runToolPiped(ctx context.Context, binary string, input []string, args []string, onLine func([]byte)) (*ToolResult, error) {
+	cmd := exec.CommandContext(ctx, binary, args...)
+
+	// Set WaitDelay for subprocess cleanup after context cancellation
+	cmd.WaitDelay = 5 * time.Second
+
+	// Create pipes for stdin, stdout, and stderr
+	stdinPipe, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create stdin pipe: %w", err)
+	}
+
+	stdoutPipe, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create stdout pipe: %w", err)
+	}
+
+	stderrPipe, err := cmd.StderrPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create stderr pipe: %w", err)
+	}
+
+	// Start the command
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start command: %w", err)
+	}
+
+	// Write input to stdin and close
+	go func() {
+		defer stdinPipe.Close()
+		for _, line := range input {
+			fmt.Fprintln(stdinPipe, line)
+		}
+	}()
+
+	// Read stdout and stderr concurrently to prevent deadlocks
+	var stderrBuf bytes.Buffer
+
+	stdoutDone := make(chan error, 1)
+	stderrDone := make(chan error, 1)
+
+	go func() {
+		scanner := bufio.NewScanner(stdoutPipe)
+		scanner.Buffer(nil, maxLineSize)
+		for scanner.Scan() {
+			onLine(scanner.Bytes())
+		}
+		err := scanner.Err()
+		// Drain the rest so the tool is not left blocked on a full pipe
+		io.Copy(io.Discard, stdoutPipe)
+		stdoutDone <- err
+	}()
+
+	go func() {
+		_, err := io.Copy(&stderrBuf, stderrPipe)
+		stderrDone <- err
+	}()
+
+	stdoutErr := <-stdoutDone
+	<-stderrDone
+
+	err = cmd.Wait()
+
+	result := &ToolResult{
+		Stderr:   stderrBuf.String(),
+		ExitCode: cmd.ProcessState.ExitCode(),
+	}
+	if err == nil && stdoutErr != nil {
+		return result, fmt.Errorf("reading output: %w", stdoutErr)
+	}
+
+	if err != nil {
+		if ctx.Err() != nil {
+			return result, fmt.Errorf("command cancelled: %w", ctx.Err())
+		}
+		return result, fmt.Errorf("command failed with exit code %d: %w", result.ExitCode, err)
+	}
+
+	return result, nil
+}
@@ -0,0 +1,25 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// RunTesseract extracts text from an image with the tesseract OCR engine.
+// It is used to triage screenshots when no DOM text is available.
+func RunTesseract(ctx context.Context, imagePath string, binaryPath string) (string, error) {
+	// Use provided binary path or fall back to tool name
+	binary := "tesseract"
+	if binaryPath != "" {
+		binary = binaryPath
+	}
+
+	// "stdout" as the output base makes tesseract print instead of writing a file
+	result, err := RunTool(ctx, binary, imagePath, "stdout")
+	if err != nil {
+		return "", fmt.Errorf("tesseract failed for %s: %w", imagePath, err)
+	}
+
+	return strings.TrimSpace(string(result.Stdout)), nil
+}
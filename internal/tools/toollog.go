@@ -0,0 +1,170 @@
+package tools
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Tool output retention keeps the raw stdout and stderr of every tool run so
+// a parser bug or a surprising result can be investigated after the scan.
+// It is off unless a stage's context carries a log from WithToolLog, which
+// the orchestrator attaches per stage for --keep-tool-output. Each run is
+// written to {dir}/{stage}/{NNN}-{tool}.stdout.gz and .stderr.gz (streams
+// that stayed empty are skipped), and described by a line of
+// {dir}/index.jsonl.
+
+// MaxToolOutput caps each retained stream before compression. Longer output
+// is cut and the entry marked truncated; the stage itself still sees all of it.
+const MaxToolOutput = 16 << 20
+
+// ToolLogIndex is the file listing every retained invocation.
+const ToolLogIndex = "index.jsonl"
+
+// ToolLogEntry describes one retained tool invocation.
+type ToolLogEntry struct {
+	Stage      string    `json:"stage"`
+	Invocation string    `json:"invocation"` // file prefix within the stage directory
+	Tool       string    `json:"tool"`
+	Args       []string  `json:"args"`
+	InputLines int       `json:"input_lines,omitempty"` // lines written to stdin
+	StartedAt  time.Time `json:"started_at"`
+	Duration   string    `json:"duration"`
+	ExitCode   int       `json:"exit_code"`
+	Error      string    `json:"error,omitempty"`
+	StdoutSize int       `json:"stdout_bytes"`
+	StderrSize int       `json:"stderr_bytes"`
+	Truncated  bool      `json:"truncated,omitempty"`
+}
+
+// toolLog retains the runs of one stage. Tools may run concurrently within
+// a stage, so the numbering is guarded by mu.
+type toolLog struct {
+	dir   string
+	stage string
+
+	mu   sync.Mutex
+	next int
+}
+
+type toolLogKey struct{}
+
+// indexMu serializes appends to the index, which every stage shares.
+var indexMu sync.Mutex
+
+// WithToolLog returns a context under which RunTool and RunToolWithInput
+// retain each run's output in dir, filed under stage. Numbering continues
+// after any runs already retained for stage, so a resumed stage keeps the
+// output of the attempt that failed.
+func WithToolLog(ctx context.Context, dir, stage string) context.Context {
+	l := &toolLog{dir: dir, stage: stage, next: 1}
+	if entries, err := os.ReadDir(filepath.Join(dir, stage)); err == nil {
+		for _, e := range entries {
+			var n int
+			if _, err := fmt.Sscanf(e.Name(), "%d-", &n); err == nil && n >= l.next {
+				l.next = n + 1
+			}
+		}
+	}
+	return context.WithValue(ctx, toolLogKey{}, l)
+}
+
+// retainRun writes a finished run to the context's tool log, if any. A
+// failed write only warns: losing debugging output must not fail the stage.
+func retainRun(ctx context.Context, binary string, args []string, input []string, started time.Time, result *ToolResult, runErr error) {
+	l, ok := ctx.Value(toolLogKey{}).(*toolLog)
+	if !ok || result == nil {
+		return
+	}
+	if err := l.write(binary, args, input, started, result, runErr); err != nil {
+		fmt.Printf("    [!] Warning: could not keep %s output: %v\n", toolName(binary), err)
+	}
+}
+
+func (l *toolLog) write(binary string, args []string, input []string, started time.Time, result *ToolResult, runErr error) error {
+	tool := toolName(binary)
+	l.mu.Lock()
+	invocation := fmt.Sprintf("%03d-%s", l.next, tool)
+	l.next++
+	l.mu.Unlock()
+
+	stageDir := filepath.Join(l.dir, l.stage)
+	if err := os.MkdirAll(stageDir, 0755); err != nil {
+		return err
+	}
+
+	entry := ToolLogEntry{
+		Stage:      l.stage,
+		Invocation: invocation,
+		Tool:       tool,
+		Args:       args,
+		InputLines: len(input),
+		StartedAt:  started.UTC(),
+		Duration:   time.Since(started).Round(time.Millisecond).String(),
+		ExitCode:   result.ExitCode,
+		StdoutSize: len(result.Stdout),
+		StderrSize: len(result.Stderr),
+		Truncated:  len(result.Stdout) > MaxToolOutput || len(result.Stderr) > MaxToolOutput,
+	}
+	if runErr != nil {
+		entry.Error = runErr.Error()
+	}
+
+	base := filepath.Join(stageDir, invocation)
+	if err := writeGzip(base+".stdout.gz", result.Stdout); err != nil {
+		return err
+	}
+	if err := writeGzip(base+".stderr.gz", []byte(result.Stderr)); err != nil {
+		return err
+	}
+	return appendIndex(filepath.Join(l.dir, ToolLogIndex), entry)
+}
+
+// writeGzip writes at most MaxToolOutput bytes of data to path, compressed.
+// Nothing is written for empty data; the index records the size.
+func writeGzip(path string, data []byte) error {
+	if len(data) == 0 {
+		return nil
+	}
+	if len(data) > MaxToolOutput {
+		data = data[:MaxToolOutput]
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	zw := gzip.NewWriter(f)
+	if _, err := zw.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// appendIndex adds entry as one JSON line to the index at path.
+func appendIndex(path string, entry ToolLogEntry) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	indexMu.Lock()
+	defer indexMu.Unlock()
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
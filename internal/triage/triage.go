@@ -0,0 +1,121 @@
+// Package triage ranks captured web pages by how interesting they look, using
+// keyword rules over the page title and visible text.
+package triage
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Rule tags a page when Pattern matches its title or text. Score orders the
+// triage list; a page's score is the sum of its matched rules.
+type Rule struct {
+	Tag     string
+	Score   int
+	Pattern *regexp.Regexp
+}
+
+// DefaultRules cover the pages worth a human look first.
+var DefaultRules = []Rule{
+	{"directory-listing", 50, regexp.MustCompile(`(?i)\bindex of /|directory listing for`)},
+	{"stack-trace", 45, regexp.MustCompile(`(?i)traceback \(most recent call last\)|exception in thread|stack ?trace|\bat [a-z0-9_.$]+\([a-z0-9_]+\.java:\d+\)|fatal error:|uncaught exception`)},
+	{"debug", 45, regexp.MustCompile(`(?i)werkzeug debugger|debug mode|whoops! there was an error|phpinfo\(\)|django debug|laravel.*exception`)},
+	{"admin", 35, regexp.MustCompile(`(?i)\badmin(istrator|istration)?\b|control panel|phpmyadmin|\bcpanel\b`)},
+	{"dashboard", 30, regexp.MustCompile(`(?i)\bdashboard\b|grafana|kibana|jenkins|prometheus`)},
+	{"login", 20, regexp.MustCompile(`(?i)\blog ?in\b|\bsign ?in\b|\bpassword\b|\bsso\b`)},
+	{"default-page", 15, regexp.MustCompile(`(?i)welcome to nginx|apache2 (ubuntu|debian) default page|it works!|iis windows server|test page for|successfully installed tomcat`)},
+	{"error", 10, regexp.MustCompile(`(?i)\b(internal server error|bad gateway|service unavailable|forbidden|not found)\b`)},
+}
+
+// RuleSpec is an uncompiled rule, as written in config.
+type RuleSpec struct {
+	Tag     string
+	Pattern string
+	Score   int
+}
+
+// CompileRules compiles configured rules. A zero score defaults to 25.
+func CompileRules(specs []RuleSpec) ([]Rule, error) {
+	rules := make([]Rule, 0, len(specs))
+	for _, spec := range specs {
+		re, err := regexp.Compile(spec.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("triage rule %q: %w", spec.Tag, err)
+		}
+		score := spec.Score
+		if score == 0 {
+			score = 25
+		}
+		rules = append(rules, Rule{Tag: spec.Tag, Score: score, Pattern: re})
+	}
+	return rules, nil
+}
+
+// Page is one capture to be triaged.
+type Page struct {
+	URL        string
+	StatusCode int
+	Title      string
+	Text       string // visible text from the DOM or OCR
+	TextSource string // "dom", "ocr" or "" when only the title is known
+	Screenshot string
+}
+
+// Entry is a triaged page.
+type Entry struct {
+	URL        string   `json:"url"`
+	StatusCode int      `json:"status_code,omitempty"`
+	Title      string   `json:"title,omitempty"`
+	Screenshot string   `json:"screenshot,omitempty"`
+	TextSource string   `json:"text_source,omitempty"`
+	Tags       []string `json:"tags,omitempty"`
+	Score      int      `json:"score"`
+}
+
+// Analyze matches rules against each page and returns entries ordered by
+// score (highest first), then URL. Pages matching nothing are kept at the
+// bottom so the list still covers every capture.
+func Analyze(pages []Page, rules []Rule) []Entry {
+	entries := make([]Entry, 0, len(pages))
+	for _, p := range pages {
+		haystack := p.Title + "\n" + p.Text
+		e := Entry{
+			URL:        p.URL,
+			StatusCode: p.StatusCode,
+			Title:      p.Title,
+			Screenshot: p.Screenshot,
+			TextSource: p.TextSource,
+		}
+		for _, r := range rules {
+			if r.Pattern.MatchString(haystack) {
+				e.Tags = append(e.Tags, r.Tag)
+				e.Score += r.Score
+			}
+		}
+		// Auth walls are login pages even when the body says little
+		if (p.StatusCode == 401 || p.StatusCode == 403) && !contains(e.Tags, "login") {
+			e.Tags = append(e.Tags, "auth-required")
+			e.Score += 15
+		}
+		entries = append(entries, e)
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].Score != entries[j].Score {
+			return entries[i].Score > entries[j].Score
+		}
+		return entries[i].URL < entries[j].URL
+	})
+	return entries
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,230 @@
+// Package urllist scans a fixed list of URLs supplied by a client instead of
+// a discovered attack surface. Discovery and port scanning are replaced by
+// records built from the list itself, written in the same raw formats so
+// probing, vulnerability scanning, reporting and diff work unchanged.
+package urllist
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hakim/reconpipe/internal/discovery"
+	"github.com/hakim/reconpipe/internal/exclude"
+	"github.com/hakim/reconpipe/internal/portscan"
+	"github.com/hakim/reconpipe/pkg/models"
+)
+
+// Source is the Source recorded for hostnames taken from a URL list.
+const Source = "targets-file"
+
+// Target is one entry of a URL list.
+type Target struct {
+	URL   string // as given; httpx probes it verbatim
+	Host  string // lowercased hostname or IP
+	Ports []int  // from the port or scheme; 80 and 443 when neither is given
+}
+
+// Parse reads one entry: a URL with an http or https scheme, or a bare
+// host or host:port, which httpx tries over both schemes.
+func Parse(raw string) (Target, error) {
+	s := strings.TrimSpace(raw)
+	withScheme := strings.Contains(s, "://")
+	if !withScheme {
+		s = "//" + s
+	}
+	u, err := url.Parse(s)
+	if err != nil {
+		return Target{}, fmt.Errorf("invalid URL %q: %w", raw, err)
+	}
+	if withScheme && u.Scheme != "http" && u.Scheme != "https" {
+		return Target{}, fmt.Errorf("invalid URL %q: scheme must be http or https", raw)
+	}
+	if u.Hostname() == "" {
+		return Target{}, fmt.Errorf("invalid URL %q: no host", raw)
+	}
+
+	t := Target{URL: strings.TrimSpace(raw), Host: strings.ToLower(u.Hostname())}
+	switch {
+	case u.Port() != "":
+		port, err := strconv.Atoi(u.Port())
+		if err != nil || port < 1 || port > 65535 {
+			return Target{}, fmt.Errorf("invalid URL %q: bad port", raw)
+		}
+		t.Ports = []int{port}
+	case u.Scheme == "https":
+		t.Ports = []int{443}
+	case u.Scheme == "http":
+		t.Ports = []int{80}
+	default:
+		t.Ports = []int{80, 443}
+	}
+	return t, nil
+}
+
+// Load reads a URL list, one entry per line. Blank lines and lines starting
+// with '#' are ignored, and duplicates are dropped. An invalid entry is an
+// error naming its line, so a typo cannot silently shrink the scope.
+func Load(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("opening targets file: %w", err)
+	}
+	defer f.Close()
+
+	var urls []string
+	seen := make(map[string]bool)
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if _, err := Parse(line); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, n, err)
+		}
+		if !seen[line] {
+			seen[line] = true
+			urls = append(urls, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading targets file: %w", err)
+	}
+	if len(urls) == 0 {
+		return nil, fmt.Errorf("targets file %s lists no URLs", path)
+	}
+	return urls, nil
+}
+
+// Hostnames returns the distinct hostnames of urls, IP addresses excluded.
+func Hostnames(urls []string) []string {
+	seen := make(map[string]bool)
+	var names []string
+	for _, raw := range urls {
+		t, err := Parse(raw)
+		if err != nil || net.ParseIP(t.Host) != nil || seen[t.Host] {
+			continue
+		}
+		seen[t.Host] = true
+		names = append(names, t.Host)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Discovery stands in for subdomain discovery: it resolves the hostnames of
+// urls and records them as the subdomains of domain, without querying any
+// passive source. consensus and resolve are applied as in
+// discovery.ResolveBatch.
+func Discovery(ctx context.Context, domain string, urls []string, digPath string, consensus discovery.Consensus, resolve discovery.ResolveOptions) (*discovery.DiscoveryResult, error) {
+	collectedAt := time.Now().UTC()
+	names := Hostnames(urls)
+	subdomains := make([]models.Subdomain, len(names))
+	for i, name := range names {
+		subdomains[i] = models.Subdomain{Name: name, Domain: domain, Source: Source}
+	}
+
+	subdomains, err := discovery.ResolveBatch(ctx, subdomains, digPath, consensus, resolve)
+	if err != nil {
+		return nil, fmt.Errorf("resolving target hosts: %w", err)
+	}
+
+	result := &discovery.DiscoveryResult{
+		Target:      domain,
+		Subdomains:  subdomains,
+		TotalFound:  len(subdomains),
+		UniqueCount: len(subdomains),
+		Sources:     map[string]int{Source: len(subdomains)},
+		Consensus:   consensus.Summary(),
+		CollectedAt: collectedAt,
+	}
+	for _, sub := range subdomains {
+		if sub.Resolved {
+			result.ResolvedCount++
+		}
+		if sub.IsDangling {
+			result.DanglingCount++
+		}
+	}
+	return result, nil
+}
+
+// PortScan stands in for port scanning: every host named in urls gets the
+// ports of its URLs as open, under each address it resolved to in
+// subdomains. Hosts on the exclusions list are recorded as excluded with no
+// ports, as the port scan records them, so nothing probes them.
+func PortScan(domain string, urls []string, subdomains []models.Subdomain, excl *exclude.List) *portscan.PortScanResult {
+	ipsOf := make(map[string][]string)
+	for _, sub := range subdomains {
+		if sub.Resolved {
+			ipsOf[sub.Name] = sub.IPs
+		}
+	}
+
+	byIP := make(map[string]*models.Host)
+	var order []string
+	for _, raw := range urls {
+		t, err := Parse(raw)
+		if err != nil {
+			continue
+		}
+		ips, name := ipsOf[t.Host], t.Host
+		if net.ParseIP(t.Host) != nil {
+			ips, name = []string{t.Host}, ""
+		}
+		for _, ip := range ips {
+			host, ok := byIP[ip]
+			if !ok {
+				host = &models.Host{IP: ip}
+				byIP[ip] = host
+				order = append(order, ip)
+			}
+			if name != "" && !slices.Contains(host.Subdomains, name) {
+				host.Subdomains = append(host.Subdomains, name)
+			}
+			for _, port := range t.Ports {
+				if !slices.ContainsFunc(host.Ports, func(p models.Port) bool { return p.Number == port }) {
+					host.Ports = append(host.Ports, models.Port{
+						Number: port, Protocol: "tcp", Service: serviceFor(raw, port), State: "open",
+					})
+				}
+			}
+		}
+	}
+
+	result := &portscan.PortScanResult{Target: domain, Hosts: []models.Host{}}
+	for _, ip := range order {
+		host := byIP[ip]
+		if excl.Contains(ip) {
+			host.Excluded, host.Ports = true, nil
+			result.ExcludedCount++
+		} else {
+			result.ScannedCount++
+			result.TotalPorts += len(host.Ports)
+		}
+		sort.Slice(host.Ports, func(i, j int) bool { return host.Ports[i].Number < host.Ports[j].Number })
+		result.Hosts = append(result.Hosts, *host)
+	}
+	return result
+}
+
+// serviceFor names the service a URL implies on port, or "" when the entry
+// has no scheme and the port is not a standard web port.
+func serviceFor(raw string, port int) string {
+	switch {
+	case strings.HasPrefix(strings.ToLower(raw), "https://"), port == 443:
+		return "https"
+	case strings.HasPrefix(strings.ToLower(raw), "http://"), port == 80:
+		return "http"
+	}
+	return ""
+}
@@ -1,22 +1,71 @@
 package vulnscan
 
 import (
+	"bufio"
 	"context"
+	"errors"
 	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"time"
 
-	"github.com/hakim/reconpipe/internal/models"
+	"github.com/hakim/reconpipe/internal/exclude"
+	"github.com/hakim/reconpipe/internal/filter"
+	"github.com/hakim/reconpipe/internal/provider"
 	"github.com/hakim/reconpipe/internal/tools"
+	"github.com/hakim/reconpipe/pkg/models"
 )
 
 // VulnScanConfig contains configuration for the vulnerability scanning pipeline
 type VulnScanConfig struct {
+	// Target is the engagement's root domain; it labels the result.
+	Target     string
 	NucleiPath string
 	Severity   string // comma-separated: "critical,high,medium"
 	Threads    int
 	RateLimit  int
 	SkipNuclei bool
+	// Nuclei bounds per-request time and per-host errors, so a host that
+	// hangs or errors is skipped instead of stretching the stage.
+	Nuclei tools.NucleiOptions
+	// Exclude lists networks nuclei must not reach; hosts in them, names
+	// resolving to them and probes answered from them are left out.
+	Exclude *exclude.List
+	// BatchSize is how many targets each nuclei run gets when ctx has a
+	// deadline (default 50). Without a deadline nuclei runs once per
+	// ChunkSize targets.
+	BatchSize int
+	// ChunkSize caps the targets of one nuclei run, so a scan of tens of
+	// thousands of endpoints runs as several nuclei processes in turn.
+	// 0 runs them all at once (or in batches under a deadline).
+	ChunkSize int
+	// ListFile writes each run's targets to a temporary file passed as
+	// -list instead of piping them to nuclei's stdin.
+	ListFile bool
+	// Filter selects the targets nuclei gets, from filter.ProbeFields: probe
+	// URLs by their probe, subdomains and IPs when any of their ports
+	// matches. Nil scans every target.
+	Filter *filter.Expr
+	// Profiles lowers the nuclei rate and lengthens its timeout for the
+	// targets of hosts whose provider has a profile. Nil scans every target
+	// alike.
+	Profiles *provider.Profiles
+	// SeverityOverrides replaces the severity of findings by template ID
+	// (lowercased, see ParseSeverityOverrides) before they are counted.
+	SeverityOverrides map[string]models.Severity
 }
 
+// defaultBatchSize is the number of targets per nuclei run under a deadline.
+const defaultBatchSize = 50
+
+// deadlineReserve is held back from the context deadline so a scan cut short
+// still has time to write its results.
+const deadlineReserve = 15 * time.Second
+
+// minBatchTime is the least time worth starting another nuclei run for.
+const minBatchTime = 30 * time.Second
+
 // VulnScanResult contains the complete results of vulnerability scanning
 type VulnScanResult struct {
 	Target          string                 `json:"target"`
@@ -24,6 +73,28 @@ type VulnScanResult struct {
 	TotalCount      int                    `json:"total_count"`
 	SeverityCounts  map[string]int         `json:"severity_counts"`
 	RawJSONLPath    string                 `json:"raw_jsonl_path,omitempty"`
+
+	// Partial is set when the context deadline cut the scan short: targets
+	// were skipped, scanned at a narrower severity, or their nuclei run was
+	// stopped before it finished.
+	Partial          bool     `json:"partial,omitempty"`
+	SkippedTargets   []string `json:"skipped_targets,omitempty"`
+	NarrowedSeverity string   `json:"narrowed_severity,omitempty"` // severity used once time ran short
+
+	// Filter is the --filter expression the targets were selected with;
+	// FilteredOut counts the targets it dropped. Findings on those are
+	// not looked for, so none of them count as resolved.
+	Filter      string `json:"filter,omitempty"`
+	FilteredOut int    `json:"filtered_out,omitempty"`
+
+	// SeverityOverrides are the configured overrides, kept so findings
+	// merged by AddFindings get them too; Overridden counts the findings
+	// whose severity they changed.
+	SeverityOverrides map[string]models.Severity `json:"severity_overrides,omitempty"`
+	Overridden        int                        `json:"overridden,omitempty"`
+
+	// CollectedAt is when the stage started scanning.
+	CollectedAt time.Time `json:"collected_at,omitzero"`
 }
 
 // RunVulnScan orchestrates the full vulnerability scanning pipeline.
@@ -31,41 +102,74 @@ type VulnScanResult struct {
 // deduplicates findings, and returns structured results with severity counts.
 func RunVulnScan(ctx context.Context, hosts []models.Host, probes []models.HTTPProbe, cfg VulnScanConfig) (*VulnScanResult, error) {
 	result := &VulnScanResult{
-		Vulnerabilities: []models.Vulnerability{},
-		SeverityCounts:  make(map[string]int),
-	}
-
-	// Derive target from first host's subdomain
-	if len(hosts) > 0 && len(hosts[0].Subdomains) > 0 {
-		result.Target = hosts[0].Subdomains[0]
+		Target:            cfg.Target,
+		Vulnerabilities:   []models.Vulnerability{},
+		SeverityCounts:    make(map[string]int),
+		Filter:            cfg.Filter.String(),
+		SeverityOverrides: cfg.SeverityOverrides,
+		CollectedAt:       time.Now().UTC(),
 	}
 
 	// Build deduplicated target list from all available sources
 	seen := make(map[string]bool)
 	var targets []string
 
+	excludedNames := cfg.Exclude.Names(hosts)
 	addTarget := func(t string) {
-		if t != "" && !seen[t] {
+		if t != "" && !seen[t] && !excludedNames[t] && !cfg.Exclude.Contains(t) {
 			seen[t] = true
 			targets = append(targets, t)
 		}
 	}
 
+	// Targets the filter drops, counted once each
+	filtered := make(map[string]bool)
+	keep := func(t string, matched bool) bool {
+		if !matched {
+			filtered[t] = true
+		}
+		return matched
+	}
+
 	// HTTP probe URLs (for web-specific nuclei templates)
+	ports := portsByAddress(hosts)
 	for _, probe := range probes {
-		addTarget(probe.URL)
+		if cfg.Exclude.Contains(probe.IP) {
+			continue
+		}
+		if u, err := url.Parse(probe.URL); err == nil && (excludedNames[u.Hostname()] || cfg.Exclude.Contains(u.Hostname())) {
+			continue
+		}
+		record := filter.ProbeRecord(probe, ports[fmt.Sprintf("%s:%d", probe.IP, probe.Port)])
+		if keep(probe.URL, cfg.Filter.Match(record)) {
+			addTarget(probe.URL)
+		}
 	}
 
 	// Subdomain names from hosts (for non-HTTP nuclei templates)
 	for _, host := range hosts {
 		for _, sub := range host.Subdomains {
-			addTarget(sub)
+			if keep(sub, anyPortMatches(cfg.Filter, sub, host)) {
+				addTarget(sub)
+			}
 		}
 	}
 
 	// IP addresses from hosts
 	for _, host := range hosts {
-		addTarget(host.IP)
+		if cfg.Exclude.Allows(host) && keep(host.IP, anyPortMatches(cfg.Filter, host.IP, host)) {
+			addTarget(host.IP)
+		}
+	}
+
+	// A target dropped in one form may have been kept in another
+	for t := range filtered {
+		if !seen[t] {
+			result.FilteredOut++
+		}
+	}
+	if cfg.Filter != nil {
+		fmt.Printf("[*] Filter dropped %d targets: %s\n", result.FilteredOut, cfg.Filter)
 	}
 
 	if len(targets) == 0 {
@@ -74,7 +178,7 @@ func RunVulnScan(ctx context.Context, hosts []models.Host, probes []models.HTTPP
 
 	fmt.Printf("[*] Running nuclei against %d targets...\n", len(targets))
 
-	nucleiResults, err := tools.RunNuclei(ctx, targets, cfg.Severity, cfg.Threads, cfg.RateLimit, cfg.NucleiPath)
+	nucleiResults, err := runBatches(ctx, targets, provider.Index(hosts), cfg, result)
 	if err != nil {
 		return nil, fmt.Errorf("nuclei execution failed: %w", err)
 	}
@@ -99,13 +203,283 @@ func RunVulnScan(ctx context.Context, hosts []models.Host, probes []models.HTTPP
 		}
 		seenVulns[key] = true
 
+		result.overrideSeverity(&vuln)
 		result.Vulnerabilities = append(result.Vulnerabilities, vuln)
 		result.SeverityCounts[string(vuln.Severity)]++
 	}
 
 	result.TotalCount = len(result.Vulnerabilities)
 
+	if result.Overridden > 0 {
+		fmt.Printf("[*] Severity overridden on %d findings\n", result.Overridden)
+	}
 	fmt.Printf("[+] Vulnerability scan complete: %d findings\n", result.TotalCount)
 
 	return result, nil
 }
+
+// portsByAddress indexes the TCP ports of hosts, which HTTP probes reach,
+// by "ip:port".
+func portsByAddress(hosts []models.Host) map[string]models.Port {
+	ports := make(map[string]models.Port)
+	for _, host := range hosts {
+		for _, port := range host.Ports {
+			if port.Protocol == "udp" {
+				continue
+			}
+			ports[fmt.Sprintf("%s:%d", host.IP, port.Number)] = port
+		}
+	}
+	return ports
+}
+
+// anyPortMatches reports whether expr matches one of host's ports reached
+// as hostname, or the host itself when it has none.
+func anyPortMatches(expr *filter.Expr, hostname string, host models.Host) bool {
+	if len(host.Ports) == 0 {
+		return expr.Match(filter.TargetRecord(hostname, host, models.Port{}))
+	}
+	for _, port := range host.Ports {
+		if expr.Match(filter.TargetRecord(hostname, host, port)) {
+			return true
+		}
+	}
+	return false
+}
+
+// runBatches runs nuclei over targets, the targets of each provider with a
+// profile under that profile's limits. When ctx has a deadline the targets
+// are split into batches and each batch gets an equal share of the time
+// left, so a slow batch is stopped (keeping what it found) rather than
+// starving the rest. If the batches so far ran slower than the remaining
+// time allows, the least severe level is dropped from the filter; once too
+// little time is left the remaining targets are skipped. Either way result
+// is marked partial instead of the stage ending with no output.
+func runBatches(ctx context.Context, targets []string, providerOf func(string) string, cfg VulnScanConfig, result *VulnScanResult) ([]tools.NucleiResult, error) {
+	groups := cfg.Profiles.Split(targets, providerOf)
+	for _, g := range groups {
+		if g.Provider != "" {
+			threads, rateLimit, opts := cfg.limits(g.Provider)
+			limits := fmt.Sprintf("%d threads, %d req/s", threads, rateLimit)
+			if opts.Timeout > 0 {
+				limits += fmt.Sprintf(", %s timeout", opts.Timeout)
+			}
+			fmt.Printf("[*] Provider profile %s: %d targets at %s\n", g.Provider, len(g.Targets), limits)
+		}
+	}
+
+	deadline, hasDeadline := ctx.Deadline()
+	if !hasDeadline {
+		var all []tools.NucleiResult
+		for _, g := range groups {
+			threads, rateLimit, opts := cfg.limits(g.Provider)
+			chunks := chunk(g.Targets, cfg.ChunkSize)
+			for i, targets := range chunks {
+				if len(chunks) > 1 {
+					fmt.Printf("[*] nuclei chunk %d/%d: %d targets\n", i+1, len(chunks), len(targets))
+				}
+				found, err := cfg.runNuclei(ctx, targets, cfg.Severity, threads, rateLimit, opts)
+				if err != nil {
+					return nil, err
+				}
+				all = append(all, found...)
+			}
+		}
+		return all, nil
+	}
+
+	size := cfg.BatchSize
+	if size <= 0 {
+		size = defaultBatchSize
+	}
+	if cfg.ChunkSize > 0 {
+		size = min(size, cfg.ChunkSize)
+	}
+	type batch struct {
+		provider string
+		targets  []string
+	}
+	var batches []batch
+	for _, g := range groups {
+		for _, targets := range chunk(g.Targets, size) {
+			batches = append(batches, batch{g.Provider, targets})
+		}
+	}
+
+	severity := cfg.Severity
+	if severity == "" {
+		severity = tools.DefaultNucleiSeverity
+	}
+
+	var all []tools.NucleiResult
+	var spent time.Duration
+	for i, b := range batches {
+		left := len(batches) - i
+		remaining := time.Until(deadline) - deadlineReserve
+		if remaining < minBatchTime {
+			for _, skipped := range batches[i:] {
+				result.SkippedTargets = append(result.SkippedTargets, skipped.targets...)
+			}
+			result.Partial = true
+			fmt.Printf("[!] Deadline near: skipping the last %d targets\n", len(result.SkippedTargets))
+			break
+		}
+
+		// Projected from the batches so far, the rest won't fit: narrow the scope
+		if i > 0 && spent/time.Duration(i)*time.Duration(left) > remaining {
+			if narrowed := narrowSeverity(severity); narrowed != severity {
+				severity = narrowed
+				result.NarrowedSeverity = narrowed
+				result.Partial = true
+				fmt.Printf("[!] Deadline near: narrowing severity to %s for the last %d batches\n", narrowed, left)
+			}
+		}
+
+		batchCtx, cancel := context.WithTimeout(ctx, remaining/time.Duration(left))
+		start := time.Now()
+		threads, rateLimit, opts := cfg.limits(b.provider)
+		found, err := cfg.runNuclei(batchCtx, b.targets, severity, threads, rateLimit, opts)
+		cancel()
+		spent += time.Since(start)
+		all = append(all, found...)
+
+		if err != nil {
+			// Only a deadline keeps partial output; a cancelled scan or a
+			// nuclei failure still fails the stage
+			if batchCtx.Err() == nil || (ctx.Err() != nil && !errors.Is(ctx.Err(), context.DeadlineExceeded)) {
+				return nil, err
+			}
+			result.Partial = true
+			fmt.Printf("[!] nuclei batch %d/%d ran out of time; keeping its %d findings\n", i+1, len(batches), len(found))
+			if ctx.Err() != nil {
+				for _, skipped := range batches[i+1:] {
+					result.SkippedTargets = append(result.SkippedTargets, skipped.targets...)
+				}
+				break
+			}
+		}
+	}
+	return all, nil
+}
+
+// chunk splits targets into runs of at most size, or one run when size is
+// 0.
+func chunk(targets []string, size int) [][]string {
+	if size <= 0 || len(targets) <= size {
+		return [][]string{targets}
+	}
+	var chunks [][]string
+	for start := 0; start < len(targets); start += size {
+		chunks = append(chunks, targets[start:min(start+size, len(targets))])
+	}
+	return chunks
+}
+
+// runNuclei runs nuclei once over targets, on stdin or from a list file
+// as cfg.ListFile says. The list file is removed when nuclei exits.
+func (cfg VulnScanConfig) runNuclei(ctx context.Context, targets []string, severity string, threads, rateLimit int, opts tools.NucleiOptions) ([]tools.NucleiResult, error) {
+	if !cfg.ListFile || len(targets) == 0 {
+		return tools.RunNuclei(ctx, targets, severity, threads, rateLimit, opts, cfg.NucleiPath)
+	}
+
+	listFile, err := os.CreateTemp("", "nuclei-targets-*.txt")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create nuclei target list: %w", err)
+	}
+	defer os.Remove(listFile.Name())
+
+	w := bufio.NewWriter(listFile)
+	for _, t := range targets {
+		w.WriteString(t)
+		w.WriteByte('\n')
+	}
+	if err := w.Flush(); err != nil {
+		listFile.Close()
+		return nil, fmt.Errorf("failed to write nuclei target list: %w", err)
+	}
+	if err := listFile.Close(); err != nil {
+		return nil, fmt.Errorf("failed to write nuclei target list: %w", err)
+	}
+
+	return tools.RunNucleiList(ctx, listFile.Name(), severity, threads, rateLimit, opts, cfg.NucleiPath)
+}
+
+// limits returns the nuclei threads, rate limit and options for the targets
+// of the named provider: the configured ones, adjusted by its profile if it
+// has one.
+func (cfg VulnScanConfig) limits(name string) (int, int, tools.NucleiOptions) {
+	return cfg.Profiles.Nuclei(name, cfg.Threads, cfg.RateLimit, cfg.Nuclei)
+}
+
+// narrowSeverity drops the least severe level from a comma-separated
+// severity filter, keeping at least one.
+func narrowSeverity(severity string) string {
+	levels := strings.Split(severity, ",")
+	if len(levels) < 2 {
+		return severity
+	}
+	least := 0
+	for i, l := range levels {
+		if severityRank[strings.TrimSpace(l)] < severityRank[strings.TrimSpace(levels[least])] {
+			least = i
+		}
+	}
+	return strings.Join(append(levels[:least:least], levels[least+1:]...), ",")
+}
+
+// severityRank orders nuclei severity names (higher = worse).
+var severityRank = map[string]int{"info": 1, "low": 2, "medium": 3, "high": 4, "critical": 5}
+
+// AddFindings merges findings raised outside nuclei (e.g. a zone transfer
+// seen during discovery) into the result, using the same TemplateID + Host
+// deduplication and severity overrides, and refreshes the counts.
+func (r *VulnScanResult) AddFindings(findings []models.Vulnerability) {
+	if r.SeverityCounts == nil {
+		r.SeverityCounts = make(map[string]int)
+	}
+	for _, f := range findings {
+		duplicate := false
+		for _, v := range r.Vulnerabilities {
+			if v.TemplateID == f.TemplateID && v.Host == f.Host {
+				duplicate = true
+				break
+			}
+		}
+		if duplicate {
+			continue
+		}
+		r.overrideSeverity(&f)
+		r.Vulnerabilities = append(r.Vulnerabilities, f)
+		r.SeverityCounts[string(f.Severity)]++
+	}
+	r.TotalCount = len(r.Vulnerabilities)
+}
+
+// overrideSeverity applies the configured override for v's template, if
+// any, keeping the severity it replaces.
+func (r *VulnScanResult) overrideSeverity(v *models.Vulnerability) {
+	sev, ok := r.SeverityOverrides[strings.ToLower(v.TemplateID)]
+	if !ok || sev == v.Severity {
+		return
+	}
+	v.OriginalSeverity = v.Severity
+	v.Severity = sev
+	r.Overridden++
+}
+
+// ParseSeverityOverrides checks a template ID to severity map from config.
+// Keys are lowercased, as config keys are case-insensitive.
+func ParseSeverityOverrides(overrides map[string]string) (map[string]models.Severity, error) {
+	if len(overrides) == 0 {
+		return nil, nil
+	}
+	parsed := make(map[string]models.Severity, len(overrides))
+	for id, s := range overrides {
+		sev := models.Severity(strings.ToLower(strings.TrimSpace(s)))
+		if _, ok := severityRank[string(sev)]; !ok {
+			return nil, fmt.Errorf("template %q: unknown severity %q (want critical, high, medium, low or info)", id, s)
+		}
+		parsed[strings.ToLower(id)] = sev
+	}
+	return parsed, nil
+}
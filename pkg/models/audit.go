@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// AuditEntry records who did what on a shared recon box. Entries are only
+// ever appended.
+type AuditEntry struct {
+	Time     time.Time `json:"time"`
+	Operator string    `json:"operator"`
+	Host     string    `json:"host,omitempty"`
+	Action   string    `json:"action"` // e.g. scan.start, scan.finish, discover
+	Target   string    `json:"target,omitempty"`
+	ScanID   string    `json:"scan_id,omitempty"`
+	Detail   string    `json:"detail,omitempty"`
+}
@@ -0,0 +1,213 @@
+package models
+
+// Subdomain represents a discovered subdomain
+type Subdomain struct {
+	Name        string      `json:"name"`
+	Domain      string      `json:"domain"`
+	Source      string      `json:"source"`
+	Resolved    bool        `json:"resolved"`
+	IPs         []string    `json:"ips,omitempty"`
+	DNSRecords  []DNSRecord `json:"dns_records,omitempty"`
+	IsCDN       bool        `json:"is_cdn"`
+	CDNProvider string      `json:"cdn_provider,omitempty"`
+	IsDangling  bool        `json:"is_dangling"`
+	// Unconfirmed are answers that fewer resolvers than the quorum returned
+	// under --dns-consensus. They are kept for review but not used.
+	Unconfirmed []DNSRecord `json:"unconfirmed_records,omitempty"`
+	// Wildcard is set when every address of the name is also returned for
+	// random names under the target: it may exist only because of
+	// wildcard DNS.
+	Wildcard bool `json:"wildcard,omitempty"`
+}
+
+// DNSRecord represents a DNS record entry
+type DNSRecord struct {
+	Type  DNSRecordType `json:"type"`
+	Value string        `json:"value"`
+	// Resolvers that returned this answer; "system" is the host's own
+	// resolver.
+	Resolvers []string `json:"resolvers,omitempty"`
+}
+
+// Host represents a discovered host/IP with its services
+type Host struct {
+	IP          string       `json:"ip"`
+	Subdomains  []string     `json:"subdomains,omitempty"`
+	Ports       []Port       `json:"ports,omitempty"`
+	IsCDN       bool         `json:"is_cdn"`
+	CDNProvider string       `json:"cdn_provider,omitempty"`
+	Excluded    bool         `json:"excluded,omitempty"` // on the never-scan list; not contacted
+	Geo         *GeoLocation `json:"geo,omitempty"`      // from the GeoIP database, when configured
+	// Provider is the CDN, WAF or cloud provider cdncheck identified the IP
+	// with, in that order of preference. It picks the provider profile the
+	// host is probed under.
+	Provider string `json:"provider,omitempty"`
+	// Origins lists the servers that may sit behind a CDN host's names,
+	// found by the probe stage
+	Origins []OriginCandidate `json:"origin_candidates,omitempty"`
+	// SharedWith lists the other targets of a multi-target run that
+	// resolved to this IP. ScannedFor is the target whose port scan
+	// produced Ports, set when they were reused from it.
+	SharedWith []string `json:"shared_with,omitempty"`
+	ScannedFor string   `json:"scanned_for,omitempty"`
+}
+
+// OriginCandidate is an IP address that may be the origin server behind a
+// CDN-fronted hostname.
+type OriginCandidate struct {
+	Hostname   string   `json:"hostname"`
+	IP         string   `json:"ip"`
+	Confidence string   `json:"confidence"` // low, medium or high
+	Techniques []string `json:"techniques"` // what proposed it: certificate, favicon, dns-history, mail-records
+	Evidence   []string `json:"evidence"`
+	// Verified is set when the IP served the hostname's CDN content when
+	// asked for it directly
+	Verified bool `json:"verified,omitempty"`
+}
+
+// GeoLocation is where a GeoIP database places an IP address.
+type GeoLocation struct {
+	CountryCode string `json:"country_code"` // ISO 3166-1 alpha-2
+	Country     string `json:"country,omitempty"`
+	City        string `json:"city,omitempty"`
+}
+
+// CountryLabel formats the country as "Name (CC)", or just the code when
+// the database has no name for it.
+func (g *GeoLocation) CountryLabel() string {
+	if g.Country == "" {
+		return g.CountryCode
+	}
+	return g.Country + " (" + g.CountryCode + ")"
+}
+
+// String formats the location as "City, Name (CC)", leaving out the city
+// when it is unknown.
+func (g *GeoLocation) String() string {
+	if g == nil {
+		return ""
+	}
+	if g.City != "" {
+		return g.City + ", " + g.CountryLabel()
+	}
+	return g.CountryLabel()
+}
+
+// Port represents an open port with service information
+type Port struct {
+	Number   int    `json:"number"`
+	Protocol string `json:"protocol"`
+	Service  string `json:"service,omitempty"`
+	Version  string `json:"version,omitempty"`
+	State    string `json:"state"`
+}
+
+// Vulnerability represents a discovered security issue
+type Vulnerability struct {
+	TemplateID  string   `json:"template_id"`
+	Name        string   `json:"name"`
+	Severity    Severity `json:"severity"`
+	Host        string   `json:"host"`
+	Port        int      `json:"port,omitempty"`
+	URL         string   `json:"url,omitempty"`
+	Description string   `json:"description,omitempty"`
+	MatchedAt   string   `json:"matched_at,omitempty"`
+
+	// OriginalSeverity is the severity the finding was raised with when a
+	// configured override replaced it.
+	OriginalSeverity Severity `json:"original_severity,omitempty"`
+
+	// Compliance maps a framework name to the requirements the finding
+	// bears on, from the compliance mapping file.
+	Compliance map[string][]string `json:"compliance,omitempty"`
+
+	// Tags are the nuclei template's tags and MatcherName the matcher that
+	// fired, for noise rules.
+	Tags        []string `json:"tags,omitempty"`
+	MatcherName string   `json:"matcher_name,omitempty"`
+
+	// Noise is set when a vulnscan.noise rule classed the finding as
+	// informational noise: reported in an appendix and not alerted on.
+	Noise bool `json:"noise,omitempty"`
+}
+
+// Screenshot capture outcomes recorded on a probe.
+const (
+	ScreenshotCaptured = "captured" // captured on the first attempt
+	ScreenshotRetried  = "retried"  // captured when retried
+	ScreenshotFailed   = "failed"   // no attempt produced a screenshot
+)
+
+// HTTPProbe represents HTTP probe results for a discovered endpoint
+type HTTPProbe struct {
+	URL            string   `json:"url"`
+	StatusCode     int      `json:"status_code"`
+	Title          string   `json:"title,omitempty"`
+	ContentLength  int64    `json:"content_length"`
+	Technologies   []string `json:"technologies,omitempty"`
+	Host           string   `json:"host"`
+	IP             string   `json:"ip"`
+	Port           int      `json:"port"`
+	ScreenshotPath string   `json:"screenshot_path,omitempty"`
+	IsCDN          bool     `json:"is_cdn"`
+	CDNProvider    string   `json:"cdn_provider,omitempty"`
+	WebServer      string   `json:"webserver,omitempty"`
+	TLS            *TLSCert `json:"tls,omitempty"`
+	Location       string   `json:"location,omitempty"`      // redirect target from a 3xx response
+	RedirectsTo    string   `json:"redirects_to,omitempty"`  // URL of the probe this one redirects to, if probed
+	ContentHash    string   `json:"content_hash,omitempty"`  // SHA-256 of the normalized body, for clone detection
+	AuthSurface    string   `json:"auth_surface,omitempty"`  // login-form, sso, vpn-portal or http-auth
+	AuthEvidence   string   `json:"auth_evidence,omitempty"` // what marked the probe as an authentication surface
+
+	// Protocols beyond HTTP/1.1. HTTP3 is only set once the QUIC port
+	// advertised in AltSvc has answered; AltSvc alone is an advertisement.
+	HTTP2        bool     `json:"http2,omitempty"`
+	HTTP3        bool     `json:"http3,omitempty"`
+	AltSvc       string   `json:"alt_svc,omitempty"`
+	QUICVersions []string `json:"quic_versions,omitempty"`
+
+	// API endpoints that rarely have a page of their own: GRPC is set when
+	// the service answered a gRPC call, WebSocket is the URL that accepted a
+	// WebSocket upgrade.
+	GRPC      bool   `json:"grpc,omitempty"`
+	WebSocket string `json:"websocket,omitempty"`
+
+	// Favicon is httpx's MurmurHash3 of /favicon.ico, which identifies an
+	// application across hosts; requested for origin discovery.
+	Favicon string `json:"favicon,omitempty"`
+
+	// ScreenshotStatus is the outcome of the capture, one of the
+	// Screenshot* values, and ScreenshotError why it failed. Both are
+	// empty when no capture was attempted.
+	ScreenshotStatus string `json:"screenshot_status,omitempty"`
+	ScreenshotError  string `json:"screenshot_error,omitempty"`
+
+	// Method and status checks (probe.method_checks): the methods OPTIONS
+	// listed, whether TRACE echoed the request, and the answer to a random
+	// path. Soft404 marks a service answering misses with a 2xx, so a 200
+	// from it proves nothing about the path.
+	AllowedMethods []string `json:"allowed_methods,omitempty"`
+	TraceEnabled   bool     `json:"trace_enabled,omitempty"`
+	NotFoundStatus int      `json:"not_found_status,omitempty"`
+	NotFoundLength int64    `json:"not_found_length,omitempty"`
+	Soft404        bool     `json:"soft_404,omitempty"`
+}
+
+// IsAPIEndpoint reports whether the probe answered as a gRPC or WebSocket
+// endpoint, whatever its plain HTTP status.
+func (p HTTPProbe) IsAPIEndpoint() bool {
+	return p.GRPC || p.WebSocket != ""
+}
+
+// TLSCert represents the leaf certificate presented by an HTTPS endpoint
+type TLSCert struct {
+	SubjectCN string   `json:"subject_cn,omitempty"`
+	SubjectDN string   `json:"subject_dn,omitempty"`
+	SANs      []string `json:"sans,omitempty"`
+	IssuerCN  string   `json:"issuer_cn,omitempty"`
+	IssuerDN  string   `json:"issuer_dn,omitempty"`
+	Serial    string   `json:"serial,omitempty"`
+	NotBefore string   `json:"not_before,omitempty"`
+	NotAfter  string   `json:"not_after,omitempty"`
+	SHA256    string   `json:"sha256,omitempty"`
+}
@@ -0,0 +1,35 @@
+package models
+
+import "time"
+
+// MonitorRun records one run of a 'reconpipe monitor' schedule: a scan of
+// each of its targets and what the scans' diffs found.
+type MonitorRun struct {
+	Schedule   string          `json:"schedule"`
+	DueAt      time.Time       `json:"due_at"` // the scheduled time the run was for
+	StartedAt  time.Time       `json:"started_at"`
+	FinishedAt time.Time       `json:"finished_at"`
+	Targets    []MonitorTarget `json:"targets"`
+}
+
+// MonitorTarget is the scan of one target in a monitor run.
+type MonitorTarget struct {
+	Target string `json:"target"`
+	ScanID string `json:"scan_id,omitempty"`
+	Status string `json:"status"` // complete, partial or interrupted as the scan ended; failed or skipped (refused by the target policy) when it never ran
+	Error  string `json:"error,omitempty"`
+
+	// Counts from the scan's diff against the previous scan of the target;
+	// zero for the first scan, which has nothing to diff against.
+	NewSubdomains int `json:"new_subdomains,omitempty"`
+	NewPorts      int `json:"new_ports,omitempty"`
+	NewVulns      int `json:"new_vulns,omitempty"`
+
+	Notified bool `json:"notified,omitempty"` // the change notification was delivered
+}
+
+// Key is the run's database key: schedule and due time, which sorts a
+// schedule's runs oldest first.
+func (r *MonitorRun) Key() string {
+	return r.Schedule + "\x00" + r.DueAt.UTC().Format(time.RFC3339)
+}
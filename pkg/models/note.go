@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// What a note is attached to.
+const (
+	NoteFinding = "finding" // a vulnerability or dangling subdomain, by finding key
+	NoteAsset   = "asset"   // a hostname or IP
+)
+
+// Note is an analyst's annotation on a finding or asset of a target. Notes
+// belong to the target rather than a scan, so they carry over to every
+// later scan that reports the same finding or asset.
+type Note struct {
+	ID        string    `json:"id"`
+	Target    string    `json:"target"`
+	Subject   string    `json:"subject"` // finding or asset
+	Key       string    `json:"key"`     // template::host or dangling subdomain; asset hostname or IP
+	Text      string    `json:"text"`
+	Author    string    `json:"author,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
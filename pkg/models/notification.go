@@ -0,0 +1,47 @@
+package models
+
+import "time"
+
+// Kinds of finding tracked for notifications.
+const (
+	FindingKindVuln     = "vuln"
+	FindingKindDangling = "dangling"
+)
+
+// NotificationState records what has been alerted about one finding of a
+// target, so each finding is announced once, again only when its severity
+// rises, and once more when it disappears.
+type NotificationState struct {
+	Target     string     `json:"target"`
+	Kind       string     `json:"kind"`     // vuln or dangling
+	Identity   string     `json:"identity"` // stable key within the target, e.g. template|host|port|matched_at
+	Name       string     `json:"name"`
+	Host       string     `json:"host"`
+	Severity   Severity   `json:"severity"` // highest severity alerted while open
+	FirstSeen  time.Time  `json:"first_seen"`
+	LastSeen   time.Time  `json:"last_seen"`
+	LastScanID string     `json:"last_scan_id"`
+	ResolvedAt *time.Time `json:"resolved_at,omitempty"`
+
+	// Expired marks a finding closed by prune-findings because ScansMissed
+	// completed scans since LastSeen did not report it, rather than by a
+	// scan that looked for it and found it gone.
+	Expired     bool `json:"expired,omitempty"`
+	ScansMissed int  `json:"scans_missed,omitempty"`
+}
+
+// FindingExpiry records one prune-findings run that closed findings. The
+// records of a target are kept in raw/expired-findings.json of the latest
+// scan at the time.
+type FindingExpiry struct {
+	Target   string              `json:"target"`
+	Time     time.Time           `json:"time"`
+	Operator string              `json:"operator,omitempty"`
+	After    int                 `json:"after"` // scans a finding had to be missing from
+	Findings []NotificationState `json:"findings"`
+}
+
+// Key is the state's database key: target, kind and identity.
+func (n *NotificationState) Key() string {
+	return n.Target + "\x00" + n.Kind + "\x00" + n.Identity
+}
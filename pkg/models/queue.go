@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+// QueuedScan is a scan queued through the API that had not finished when
+// 'reconpipe serve' shut down. It is queued again on the next start.
+type QueuedScan struct {
+	JobID    string    `json:"job_id"`
+	Target   string    `json:"target"`
+	Preset   string    `json:"preset,omitempty"`
+	Stages   []string  `json:"stages,omitempty"`
+	Skip     []string  `json:"skip,omitempty"`
+	Severity string    `json:"severity,omitempty"`
+	Tag      string    `json:"tag,omitempty"`
+	Priority string    `json:"priority,omitempty"`
+	Operator string    `json:"operator"`
+	QueuedAt time.Time `json:"queued_at"`
+
+	IgnorePolicy bool `json:"ignore_policy,omitempty"` // the request overrode the target policy
+
+	// ScanID and ScanDir are set when the scan was stopped part-way; it
+	// resumes in ScanDir, skipping the stages already done.
+	ScanID  string `json:"scan_id,omitempty"`
+	ScanDir string `json:"scan_dir,omitempty"`
+}
@@ -0,0 +1,77 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ScanMeta contains metadata about a scan
+type ScanMeta struct {
+	ID           string            `json:"id"`
+	Target       string            `json:"target"`
+	StartedAt    time.Time         `json:"started_at"`
+	CompletedAt  *time.Time        `json:"completed_at,omitempty"`
+	Status       ScanStatus        `json:"status"`
+	ScanDir      string            `json:"scan_dir"`
+	ToolVersions map[string]string `json:"tool_versions,omitempty"`
+	StagesRun    []string          `json:"stages_run,omitempty"`
+	Operator     string            `json:"operator,omitempty"` // who launched the scan
+	RunConfig    *RunConfig        `json:"run_config,omitempty"`
+}
+
+// RunConfig records how a scan was produced: the resolved command-line
+// options plus a snapshot of the loaded configuration file. It holds enough
+// to rerun the scan with identical settings.
+type RunConfig struct {
+	Version         string          `json:"reconpipe_version"`
+	Preset          string          `json:"preset,omitempty"`
+	Tag             string          `json:"tag,omitempty"`
+	Stages          []string        `json:"stages,omitempty"`
+	Skip            []string        `json:"skip,omitempty"`
+	Severity        string          `json:"severity"`
+	Timeout         string          `json:"timeout"`
+	ScopeDomains    []string        `json:"scope_domains,omitempty"`
+	SkipPDF         bool            `json:"skip_pdf"`
+	PDFEngine       string          `json:"pdf_engine,omitempty"`
+	Permutations    bool            `json:"permutations"`
+	Bruteforce      bool            `json:"bruteforce,omitempty"`
+	ZoneTransfer    bool            `json:"zone_transfer"`
+	DNSConsensus    bool            `json:"dns_consensus,omitempty"`
+	Ports           string          `json:"ports,omitempty"`     // --ports
+	TopPorts        int             `json:"top_ports,omitempty"` // --top-ports
+	UDP             bool            `json:"udp,omitempty"`       // --udp
+	ProbeFilter     string          `json:"probe_filter,omitempty"`
+	VulnscanFilter  string          `json:"vulnscan_filter,omitempty"`
+	KnownSubdomains []string        `json:"known_subdomains,omitempty"` // contents of --known-subdomains, not the path
+	TargetURLs      []string        `json:"target_urls,omitempty"`      // contents of --targets-file, not the path
+	FakeTools       bool            `json:"fake_tools,omitempty"`
+	ReplayOf        string          `json:"replay_of,omitempty"` // scan ID this run replayed
+	Config          json.RawMessage `json:"config"`
+}
+
+// Scan represents a complete scan with all discovered data
+type Scan struct {
+	ScanMeta
+	Subdomains      []Subdomain     `json:"subdomains,omitempty"`
+	Hosts           []Host          `json:"hosts,omitempty"`
+	Vulnerabilities []Vulnerability `json:"vulnerabilities,omitempty"`
+}
+
+// NewScan creates a new scan instance with initialized metadata
+func NewScan(target string) *Scan {
+	return &Scan{
+		ScanMeta: ScanMeta{
+			ID:           uuid.New().String(),
+			Target:       target,
+			StartedAt:    time.Now(),
+			Status:       StatusPending,
+			ToolVersions: make(map[string]string),
+			StagesRun:    []string{},
+		},
+		Subdomains:      []Subdomain{},
+		Hosts:           []Host{},
+		Vulnerabilities: []Vulnerability{},
+	}
+}
@@ -0,0 +1,41 @@
+package models
+
+import "time"
+
+// ScanSnapshot holds all structured data loaded from a single scan's raw output
+// directory. Fields are empty (nil) when the corresponding JSON file is absent.
+type ScanSnapshot struct {
+	ScanDir         string
+	Subdomains      []Subdomain
+	Hosts           []Host
+	Vulnerabilities []Vulnerability
+	Probes          []HTTPProbe
+
+	// CollectedAt holds when each stage collected its data, by raw file
+	// name. Files written before collection times were recorded are absent.
+	CollectedAt map[string]time.Time
+}
+
+// Collected returns when the newest of the snapshot's data was collected,
+// or the zero time when none of its files records it.
+func (s *ScanSnapshot) Collected() time.Time {
+	var latest time.Time
+	for _, t := range s.CollectedAt {
+		if t.After(latest) {
+			latest = t
+		}
+	}
+	return latest
+}
+
+// SetCollected records t as the collection time of the named raw file. A
+// zero t is ignored.
+func (s *ScanSnapshot) SetCollected(name string, t time.Time) {
+	if t.IsZero() {
+		return
+	}
+	if s.CollectedAt == nil {
+		s.CollectedAt = make(map[string]time.Time)
+	}
+	s.CollectedAt[name] = t
+}
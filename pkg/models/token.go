@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+// API token scopes. A scan token may also read.
+const (
+	TokenScopeRead = "read"
+	TokenScopeScan = "scan"
+)
+
+// APIToken is a credential for the serve API. Only a SHA-256 hash of the
+// secret is stored; the secret itself is shown once, when it is created.
+type APIToken struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	Scope      string     `json:"scope"` // read or scan
+	Hash       string     `json:"hash"`
+	RateLimit  int        `json:"rate_limit,omitempty"` // requests per minute; 0 = server default
+	CreatedAt  time.Time  `json:"created_at"`
+	CreatedBy  string     `json:"created_by,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
+
+// Allows reports whether the token grants scope.
+func (t *APIToken) Allows(scope string) bool {
+	if t.RevokedAt != nil {
+		return false
+	}
+	return t.Scope == scope || (t.Scope == TokenScopeScan && scope == TokenScopeRead)
+}
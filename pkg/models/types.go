@@ -4,10 +4,11 @@ package models
 type ScanStatus string
 
 const (
-	StatusPending  ScanStatus = "pending"
-	StatusRunning  ScanStatus = "running"
-	StatusComplete ScanStatus = "complete"
-	StatusFailed   ScanStatus = "failed"
+	StatusPending     ScanStatus = "pending"
+	StatusRunning     ScanStatus = "running"
+	StatusComplete    ScanStatus = "complete"
+	StatusFailed      ScanStatus = "failed"
+	StatusInterrupted ScanStatus = "interrupted" // stopped between stages by a shutdown; resumable
 )
 
 // Severity represents the severity level of a vulnerability
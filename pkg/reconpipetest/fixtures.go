@@ -0,0 +1,272 @@
+package reconpipetest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/hakim/reconpipe/internal/storage"
+	"github.com/hakim/reconpipe/pkg/models"
+)
+
+// ScanBuilder builds a scan record.
+type ScanBuilder struct {
+	meta models.ScanMeta
+}
+
+// Scan starts a scan record of target as models.NewScan does: a new ID,
+// started now, pending, no stages run.
+func Scan(target string) *ScanBuilder {
+	return &ScanBuilder{meta: models.NewScan(target).ScanMeta}
+}
+
+// ID sets the scan ID.
+func (b *ScanBuilder) ID(id string) *ScanBuilder {
+	b.meta.ID = id
+	return b
+}
+
+// StartedAt sets when the scan started.
+func (b *ScanBuilder) StartedAt(t time.Time) *ScanBuilder {
+	b.meta.StartedAt = t
+	return b
+}
+
+// Dir sets the scan directory.
+func (b *ScanBuilder) Dir(scanDir string) *ScanBuilder {
+	b.meta.ScanDir = scanDir
+	return b
+}
+
+// Operator sets who ran the scan.
+func (b *ScanBuilder) Operator(name string) *ScanBuilder {
+	b.meta.Operator = name
+	return b
+}
+
+// Stages records the stages as run.
+func (b *ScanBuilder) Stages(names ...string) *ScanBuilder {
+	b.meta.StagesRun = append(b.meta.StagesRun, names...)
+	return b
+}
+
+// Status sets the status, and CompletedAt to the start time for a scan
+// that completed or failed.
+func (b *ScanBuilder) Status(status models.ScanStatus) *ScanBuilder {
+	b.meta.Status = status
+	b.meta.CompletedAt = nil
+	if status == models.StatusComplete || status == models.StatusFailed {
+		at := b.meta.StartedAt
+		b.meta.CompletedAt = &at
+	}
+	return b
+}
+
+// Complete marks the scan complete.
+func (b *ScanBuilder) Complete() *ScanBuilder {
+	return b.Status(models.StatusComplete)
+}
+
+// Meta returns the scan record. Each call returns a new copy.
+func (b *ScanBuilder) Meta() *models.ScanMeta {
+	meta := b.meta
+	meta.StagesRun = append([]string{}, b.meta.StagesRun...)
+	if b.meta.CompletedAt != nil {
+		at := *b.meta.CompletedAt
+		meta.CompletedAt = &at
+	}
+	return &meta
+}
+
+// SnapshotBuilder builds a scan snapshot: what the discovery, portscan,
+// probe and vulnscan stages of one scan found.
+type SnapshotBuilder struct {
+	target string
+	at     time.Time
+	snap   models.ScanSnapshot
+}
+
+// Snapshot starts an empty snapshot of target, collected now.
+func Snapshot(target string) *SnapshotBuilder {
+	return &SnapshotBuilder{target: target, at: time.Now().UTC()}
+}
+
+// CollectedAt sets when every stage collected its data.
+func (b *SnapshotBuilder) CollectedAt(t time.Time) *SnapshotBuilder {
+	b.at = t
+	return b
+}
+
+// Subdomain adds a subdomain resolving to ips, or an unresolved one
+// without any.
+func (b *SnapshotBuilder) Subdomain(name string, ips ...string) *SnapshotBuilder {
+	s := models.Subdomain{Name: name, Domain: b.target, Source: "fixture", Resolved: len(ips) > 0, IPs: ips}
+	for _, ip := range ips {
+		s.DNSRecords = append(s.DNSRecords, models.DNSRecord{Type: models.DNSRecordA, Value: ip})
+	}
+	b.snap.Subdomains = append(b.snap.Subdomains, s)
+	return b
+}
+
+// Dangling adds a dangling subdomain, a CNAME to cname when it is set.
+func (b *SnapshotBuilder) Dangling(name, cname string) *SnapshotBuilder {
+	s := models.Subdomain{Name: name, Domain: b.target, Source: "fixture", IsDangling: true}
+	if cname != "" {
+		s.DNSRecords = []models.DNSRecord{{Type: models.DNSRecordCNAME, Value: cname}}
+	}
+	b.snap.Subdomains = append(b.snap.Subdomains, s)
+	return b
+}
+
+// Port adds an open TCP port to the host with ip, adding the host when it
+// is new. A new host gets the subdomains added so far that resolve to ip.
+func (b *SnapshotBuilder) Port(ip string, number int, service string) *SnapshotBuilder {
+	h := b.host(ip)
+	h.Ports = append(h.Ports, models.Port{Number: number, Protocol: "tcp", Service: service, State: "open"})
+	return b
+}
+
+// CDN adds the host with ip as fronted by provider: not port scanned.
+func (b *SnapshotBuilder) CDN(ip, provider string) *SnapshotBuilder {
+	h := b.host(ip)
+	h.IsCDN, h.CDNProvider, h.Provider = true, provider, provider
+	return b
+}
+
+// host returns the host with ip, adding it when it is new.
+func (b *SnapshotBuilder) host(ip string) *models.Host {
+	for i := range b.snap.Hosts {
+		if b.snap.Hosts[i].IP == ip {
+			return &b.snap.Hosts[i]
+		}
+	}
+	h := models.Host{IP: ip, Ports: []models.Port{}}
+	for _, s := range b.snap.Subdomains {
+		for _, sip := range s.IPs {
+			if sip == ip {
+				h.Subdomains = append(h.Subdomains, s.Name)
+			}
+		}
+	}
+	b.snap.Hosts = append(b.snap.Hosts, h)
+	return &b.snap.Hosts[len(b.snap.Hosts)-1]
+}
+
+// Probe adds an HTTP probe of rawURL that answered with status.
+func (b *SnapshotBuilder) Probe(rawURL string, status int) *SnapshotBuilder {
+	p := models.HTTPProbe{URL: rawURL, StatusCode: status}
+	if u, err := url.Parse(rawURL); err == nil {
+		p.Host = u.Hostname()
+		p.Port, _ = strconv.Atoi(u.Port())
+		if p.Port == 0 && u.Scheme == "https" {
+			p.Port = 443
+		} else if p.Port == 0 {
+			p.Port = 80
+		}
+	}
+	b.snap.Probes = append(b.snap.Probes, p)
+	return b
+}
+
+// Vuln adds a finding of templateID on host, a URL as nuclei reports it.
+func (b *SnapshotBuilder) Vuln(templateID, host string, severity models.Severity) *SnapshotBuilder {
+	b.snap.Vulnerabilities = append(b.snap.Vulnerabilities, models.Vulnerability{
+		TemplateID: templateID,
+		Name:       templateID,
+		Severity:   severity,
+		Host:       host,
+		URL:        host,
+	})
+	return b
+}
+
+// Build returns the snapshot, as diff.LoadSnapshot would load it from a
+// scan directory Write wrote it to.
+func (b *SnapshotBuilder) Build() *models.ScanSnapshot {
+	// A deep copy, so adding to the builder does not change it
+	var snap models.ScanSnapshot
+	data, _ := json.Marshal(b.snap)
+	json.Unmarshal(data, &snap)
+	snap.CollectedAt = make(map[string]time.Time)
+	for _, name := range b.files() {
+		snap.CollectedAt[name] = b.at
+	}
+	return &snap
+}
+
+// Write writes the snapshot's raw files to scanDir, as the stages do, and
+// returns the snapshot with ScanDir set. Only the files of the data added
+// are written, so a snapshot without vulns leaves the vulnscan stage
+// unrun.
+func (b *SnapshotBuilder) Write(scanDir string) (*models.ScanSnapshot, error) {
+	if err := storage.EnsureDir(storage.RawDir(scanDir)); err != nil {
+		return nil, err
+	}
+	for _, name := range b.files() {
+		var records any
+		key := ""
+		switch name {
+		case "subdomains.json":
+			key, records = "subdomains", b.snap.Subdomains
+		case "ports.json":
+			key, records = "hosts", b.snap.Hosts
+		case "http-probes.json":
+			key, records = "probes", b.snap.Probes
+		case "vulns.json":
+			key, records = "vulnerabilities", b.snap.Vulnerabilities
+		}
+		data, err := json.MarshalIndent(map[string]any{
+			"target":       b.target,
+			key:            records,
+			"collected_at": b.at,
+		}, "", "  ")
+		if err != nil {
+			return nil, fmt.Errorf("marshaling %s: %w", name, err)
+		}
+		if err := storage.WriteFileAtomic(storage.RawPath(scanDir, name), data, 0644); err != nil {
+			return nil, fmt.Errorf("writing %s: %w", name, err)
+		}
+	}
+	snap := b.Build()
+	snap.ScanDir = scanDir
+	return snap, nil
+}
+
+// files returns the raw files of the data added.
+func (b *SnapshotBuilder) files() []string {
+	var files []string
+	if b.snap.Subdomains != nil {
+		files = append(files, "subdomains.json")
+	}
+	if b.snap.Hosts != nil {
+		files = append(files, "ports.json")
+	}
+	if b.snap.Probes != nil {
+		files = append(files, "http-probes.json")
+	}
+	if b.snap.Vulnerabilities != nil {
+		files = append(files, "vulns.json")
+	}
+	return files
+}
+
+// Recorded returns the record of a complete scan of target that started
+// at at and wrote snap, as returned by SnapshotBuilder.Write: its
+// directory, and the stages whose raw files snap has as run. Stores need
+// it to find the snapshot, e.g. as the previous scan of a diff.
+func Recorded(target string, at time.Time, snap *models.ScanSnapshot) *models.ScanMeta {
+	b := Scan(target).Dir(snap.ScanDir).StartedAt(at)
+	for _, stage := range []struct{ name, file string }{
+		{"discover", "subdomains.json"},
+		{"portscan", "ports.json"},
+		{"probe", "http-probes.json"},
+		{"vulnscan", "vulns.json"},
+	} {
+		if _, ok := snap.CollectedAt[stage.file]; ok {
+			b.Stages(stage.name)
+		}
+	}
+	return b.Complete().Meta()
+}
@@ -0,0 +1,289 @@
+// Package reconpipetest provides test doubles for code that drives scans:
+// an in-memory Store that satisfies the scanstore interfaces the
+// orchestrator runs against, and fixture builders for scan records and the
+// raw files stages read. Both this module and downstream automation and
+// plugins can test against them without a bbolt database on disk:
+//
+//	store := reconpipetest.NewStore(reconpipetest.Scan("example.com").Complete().Meta())
+//	err := plugin.Run(ctx, store) // takes a scanstore.Store
+//	latest, _ := store.Latest("example.com")
+package reconpipetest
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hakim/reconpipe/pkg/models"
+	"github.com/hakim/reconpipe/pkg/scanstore"
+)
+
+// Store is an in-memory stand-in for storage.Store. Records are kept as
+// JSON, as bbolt keeps them, so callers get copies: changing a returned
+// record does not change the store until it is saved. It is safe for
+// concurrent use. The zero value is not usable; call NewStore.
+type Store struct {
+	mu     sync.Mutex
+	scans  map[string][]byte
+	audit  [][]byte
+	states map[string][]byte
+	notes  map[string][]byte
+	fail   map[string]error
+}
+
+var (
+	_ scanstore.Store      = (*Store)(nil)
+	_ scanstore.AlertStore = (*Store)(nil)
+	_ scanstore.NoteStore  = (*Store)(nil)
+)
+
+// NewStore returns a store holding scans.
+func NewStore(scans ...*models.ScanMeta) *Store {
+	s := &Store{
+		scans:  make(map[string][]byte),
+		states: make(map[string][]byte),
+		notes:  make(map[string][]byte),
+		fail:   make(map[string]error),
+	}
+	for _, meta := range scans {
+		if err := s.SaveScan(meta); err != nil {
+			panic(fmt.Sprintf("reconpipetest: %v", err))
+		}
+	}
+	return s
+}
+
+// FailOn makes every later call of the named method, e.g. "SaveScan",
+// return err. A nil err makes the method work again.
+func (s *Store) FailOn(method string, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err == nil {
+		delete(s.fail, method)
+		return
+	}
+	s.fail[method] = err
+}
+
+// SaveScan creates or replaces a scan record.
+func (s *Store) SaveScan(meta *models.ScanMeta) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.fail["SaveScan"]; err != nil {
+		return err
+	}
+	data, err := json.Marshal(meta)
+	if err != nil {
+		return err
+	}
+	s.scans[meta.ID] = data
+	return nil
+}
+
+// GetScan returns the scan with id, or nil.
+func (s *Store) GetScan(id string) (*models.ScanMeta, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.fail["GetScan"]; err != nil {
+		return nil, err
+	}
+	data, ok := s.scans[id]
+	if !ok {
+		return nil, nil
+	}
+	return decode[models.ScanMeta](data)
+}
+
+// ListScans returns the scans of target, newest first.
+func (s *Store) ListScans(target string) ([]*models.ScanMeta, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.fail["ListScans"]; err != nil {
+		return nil, err
+	}
+	var scans []*models.ScanMeta
+	for _, data := range s.scans {
+		meta, err := decode[models.ScanMeta](data)
+		if err != nil {
+			return nil, err
+		}
+		if meta.Target == target {
+			scans = append(scans, meta)
+		}
+	}
+	sort.Slice(scans, func(i, j int) bool {
+		return scans[i].StartedAt.After(scans[j].StartedAt)
+	})
+	return scans, nil
+}
+
+// Latest returns the newest scan of target, or nil.
+func (s *Store) Latest(target string) (*models.ScanMeta, error) {
+	scans, err := s.ListScans(target)
+	if err != nil || len(scans) == 0 {
+		return nil, err
+	}
+	return scans[0], nil
+}
+
+// UpdateScanStatus sets the status of the scan with id, and its
+// CompletedAt once it completes or fails, as storage.Store does. An
+// unknown id is a no-op.
+func (s *Store) UpdateScanStatus(id string, status models.ScanStatus) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.fail["UpdateScanStatus"]; err != nil {
+		return err
+	}
+	data, ok := s.scans[id]
+	if !ok {
+		return nil
+	}
+	meta, err := decode[models.ScanMeta](data)
+	if err != nil {
+		return err
+	}
+	meta.Status = status
+	if (status == models.StatusComplete || status == models.StatusFailed) && meta.CompletedAt == nil {
+		now := time.Now()
+		meta.CompletedAt = &now
+	}
+	if s.scans[id], err = json.Marshal(meta); err != nil {
+		return err
+	}
+	return nil
+}
+
+// AppendAudit adds an entry to the audit log. Time and Host are filled in
+// when empty.
+func (s *Store) AppendAudit(entry models.AuditEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.fail["AppendAudit"]; err != nil {
+		return err
+	}
+	if entry.Time.IsZero() {
+		entry.Time = time.Now()
+	}
+	if entry.Host == "" {
+		entry.Host, _ = os.Hostname()
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	s.audit = append(s.audit, data)
+	return nil
+}
+
+// Audit returns the audit entries, oldest first.
+func (s *Store) Audit() []models.AuditEntry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var entries []models.AuditEntry
+	for _, data := range s.audit {
+		if e, err := decode[models.AuditEntry](data); err == nil {
+			entries = append(entries, *e)
+		}
+	}
+	return entries
+}
+
+// AuditActions returns the Action of every audit entry, oldest first, e.g.
+// [scan.start scan.complete].
+func (s *Store) AuditActions() []string {
+	var actions []string
+	for _, e := range s.Audit() {
+		actions = append(actions, e.Action)
+	}
+	return actions
+}
+
+// ListNotificationStates returns the notification states of target.
+func (s *Store) ListNotificationStates(target string) ([]*models.NotificationState, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.fail["ListNotificationStates"]; err != nil {
+		return nil, err
+	}
+	var states []*models.NotificationState
+	for _, key := range slices.Sorted(maps.Keys(s.states)) {
+		if !strings.HasPrefix(key, target+"\x00") {
+			continue
+		}
+		st, err := decode[models.NotificationState](s.states[key])
+		if err != nil {
+			return nil, err
+		}
+		states = append(states, st)
+	}
+	return states, nil
+}
+
+// SaveNotificationStates creates or replaces states.
+func (s *Store) SaveNotificationStates(states []*models.NotificationState) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.fail["SaveNotificationStates"]; err != nil {
+		return err
+	}
+	for _, st := range states {
+		data, err := json.Marshal(st)
+		if err != nil {
+			return err
+		}
+		s.states[st.Key()] = data
+	}
+	return nil
+}
+
+// SaveNote creates or replaces a note.
+func (s *Store) SaveNote(n *models.Note) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.fail["SaveNote"]; err != nil {
+		return err
+	}
+	data, err := json.Marshal(n)
+	if err != nil {
+		return err
+	}
+	s.notes[n.ID] = data
+	return nil
+}
+
+// ListNotes returns the notes of target, oldest first.
+func (s *Store) ListNotes(target string) ([]*models.Note, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.fail["ListNotes"]; err != nil {
+		return nil, err
+	}
+	var notes []*models.Note
+	for _, key := range slices.Sorted(maps.Keys(s.notes)) {
+		n, err := decode[models.Note](s.notes[key])
+		if err != nil {
+			return nil, err
+		}
+		if n.Target == target {
+			notes = append(notes, n)
+		}
+	}
+	sort.SliceStable(notes, func(i, j int) bool { return notes[i].CreatedAt.Before(notes[j].CreatedAt) })
+	return notes, nil
+}
+
+// decode unmarshals a stored record.
+func decode[T any](data []byte) (*T, error) {
+	v := new(T)
+	if err := json.Unmarshal(data, v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
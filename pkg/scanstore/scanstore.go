@@ -0,0 +1,25 @@
+// Package scanstore defines the store contracts the scan orchestrator runs
+// against. storage.Store satisfies all of them with bbolt or SQLite;
+// reconpipetest.Store satisfies them in memory.
+package scanstore
+
+import "github.com/hakim/reconpipe/pkg/models"
+
+// Store is the minimal contract required by the orchestrator.
+type Store interface {
+	SaveScan(meta *models.ScanMeta) error
+	ListScans(target string) ([]*models.ScanMeta, error)
+	UpdateScanStatus(id string, status models.ScanStatus) error
+	AppendAudit(entry models.AuditEntry) error
+}
+
+// AlertStore persists notification state between scans.
+type AlertStore interface {
+	ListNotificationStates(target string) ([]*models.NotificationState, error)
+	SaveNotificationStates(states []*models.NotificationState) error
+}
+
+// NoteStore is the store contract attaching notes to a scan needs.
+type NoteStore interface {
+	ListNotes(target string) ([]*models.Note, error)
+}